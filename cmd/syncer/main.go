@@ -39,6 +39,7 @@ import (
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/node"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/utils"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
@@ -144,6 +145,7 @@ func main() {
 			if sig == syscall.SIGTERM {
 				log.Info("SIGTERM signal received")
 				utils.LogoutAllvCenterSessions(ctx)
+				tracing.Shutdown(ctx)
 				os.Exit(0)
 			}
 		}
@@ -296,6 +298,16 @@ func initSyncerComponents(ctx context.Context, clusterFlavor cnstypes.CnsCluster
 				os.Exit(1)
 			}
 		}
+		// Resolve the credentials referenced by the config from their Secrets
+		// before the sessions are created from it.
+		if err := utils.StartCredentialsWatcher(ctx, configInfo.Cfg); err != nil {
+			log.Errorf("failed to start the credentials watcher. Err: %+v", err)
+			os.Exit(1)
+		}
+		if err := tracing.Init(ctx, "vsphere-syncer", syncer.Version, &configInfo.Cfg.Tracing); err != nil {
+			log.Errorf("failed to initialize tracing. Err: %+v", err)
+			os.Exit(1)
+		}
 
 		// Initialize CNS Operator for Supervisor clusters.
 		if clusterFlavor == cnstypes.CnsClusterFlavorWorkload {
@@ -65,6 +65,13 @@ type CnsRegisterVolumeSpec struct {
 	// SparseVer2BackingInfo, RawDiskMappingVer1BackingInfo, SeSparseBackingInfo,
 	// LocalPMemBackingInfo, or empty string.
 	BackingType string `json:"backingType,omitempty"`
+
+	// StorageClassName is the name of the StorageClass of the PV and PVC
+	// created for the volume. It is only used on vanilla Kubernetes clusters,
+	// where the StorageClass cannot be derived from the storage policy of the
+	// volume. If not specified, the PV and PVC are created without a
+	// StorageClass.
+	StorageClassName string `json:"storageClassName,omitempty"`
 }
 
 // CnsRegisterVolumeStatus defines the observed state of CnsRegisterVolume
@@ -0,0 +1,199 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CnsVolumeImportSpec defines the desired state of CnsVolumeImport.
+// +k8s:openapi-gen=true
+//
+// CnsVolumeImport imports the disks selected on a datastore as statically
+// provisioned PVs of a vanilla Kubernetes cluster. The disks are selected by
+// either FolderPath, for virtual disks which are not First Class Disks (FCDs)
+// yet, or Tag, for FCDs. If neither is set, all the FCDs of the datastore are
+// imported.
+type CnsVolumeImportSpec struct {
+	// DatastoreURL is the URL of the datastore of the disks to import, such as
+	// "ds:///vmfs/volumes/vsan:52d6f7c1d8e2e4a5-9a6f3d1c5b2e8f70/".
+	// +kubebuilder:validation:Required
+	DatastoreURL string `json:"datastoreURL"`
+
+	// FolderPath is the path of a folder of the datastore, such as
+	// "import/app". All the virtual disks of the folder and its sub-folders are
+	// registered as FCDs and imported.
+	// FolderPath and Tag cannot be specified together.
+	// +optional
+	FolderPath string `json:"folderPath,omitempty"`
+
+	// TagCategory is the category of Tag.
+	// +optional
+	TagCategory string `json:"tagCategory,omitempty"`
+
+	// Tag is the name of a vSphere tag. All the FCDs of the datastore attached
+	// to the tag are imported. TagCategory must be specified with Tag.
+	// FolderPath and Tag cannot be specified together.
+	// +optional
+	Tag string `json:"tag,omitempty"`
+
+	// StorageClassName is the name of the StorageClass of the PVs created for
+	// the disks. If specified, the StorageClass must be provisioned by the
+	// vSphere CSI driver. Otherwise, the PVs are created without a StorageClass.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// ReclaimPolicy is the reclaim policy of the PVs created for the disks.
+	// Default value is Retain.
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +optional
+	ReclaimPolicy v1.PersistentVolumeReclaimPolicy `json:"reclaimPolicy,omitempty"`
+
+	// AccessMode is the access mode of the PVs created for the disks.
+	// Default value is ReadWriteOnce.
+	// +optional
+	AccessMode v1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+
+	// VolumeMode can either be Block (for raw block volume) or
+	// Filesystem. Default value is Filesystem.
+	// +optional
+	VolumeMode v1.PersistentVolumeMode `json:"volumeMode,omitempty"`
+
+	// FsType is the filesystem type of the PVs created for the disks, when
+	// VolumeMode is Filesystem. Default value is ext4.
+	// +optional
+	FsType string `json:"fsType,omitempty"`
+}
+
+// CnsVolumeImportPhase is the lifecycle phase of a CnsVolumeImport.
+type CnsVolumeImportPhase string
+
+const (
+	// CnsVolumeImportPhaseInProgress is the phase while the disks are imported.
+	CnsVolumeImportPhaseInProgress CnsVolumeImportPhase = "InProgress"
+	// CnsVolumeImportPhaseCompleted is the phase once all the disks are imported.
+	CnsVolumeImportPhaseCompleted CnsVolumeImportPhase = "Completed"
+	// CnsVolumeImportPhaseFailed is the phase when the disks could not be
+	// listed, or the import of at least one disk failed. The import of the
+	// failed disks is retried.
+	CnsVolumeImportPhaseFailed CnsVolumeImportPhase = "Failed"
+)
+
+// CnsVolumeImportDiskState is the import state of a disk.
+type CnsVolumeImportDiskState string
+
+const (
+	// CnsVolumeImportDiskStateImported means that a PV was created for the disk.
+	CnsVolumeImportDiskStateImported CnsVolumeImportDiskState = "Imported"
+	// CnsVolumeImportDiskStateSkipped means that the disk already had a PV, or
+	// was already registered with CNS.
+	CnsVolumeImportDiskStateSkipped CnsVolumeImportDiskState = "Skipped"
+	// CnsVolumeImportDiskStateFailed means that the import of the disk failed.
+	CnsVolumeImportDiskStateFailed CnsVolumeImportDiskState = "Failed"
+)
+
+// CnsVolumeImportDiskStatus is the import status of a disk.
+type CnsVolumeImportDiskStatus struct {
+	// Path is the datastore path of the virtual disk, when the disks are
+	// selected by FolderPath.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// VolumeID is the ID of the FCD.
+	// +optional
+	VolumeID string `json:"volumeID,omitempty"`
+
+	// PVName is the name of the PV of the disk.
+	// +optional
+	PVName string `json:"pvName,omitempty"`
+
+	// State is the import state of the disk.
+	State CnsVolumeImportDiskState `json:"state"`
+
+	// Message describes the failure, or the reason the disk was skipped.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CnsVolumeImportStatus defines the observed state of CnsVolumeImport.
+// +k8s:openapi-gen=true
+type CnsVolumeImportStatus struct {
+	// Phase is the current lifecycle phase.
+	// +optional
+	Phase CnsVolumeImportPhase `json:"phase,omitempty"`
+
+	// ObservedGeneration is the generation of the spec last processed.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Total is the number of disks selected.
+	// +optional
+	Total int `json:"total,omitempty"`
+
+	// Imported is the number of disks imported.
+	// +optional
+	Imported int `json:"imported,omitempty"`
+
+	// Skipped is the number of disks skipped.
+	// +optional
+	Skipped int `json:"skipped,omitempty"`
+
+	// Failed is the number of disks whose import failed.
+	// +optional
+	Failed int `json:"failed,omitempty"`
+
+	// Disks is the import status of each disk processed so far.
+	// +optional
+	Disks []CnsVolumeImportDiskStatus `json:"disks,omitempty"`
+
+	// Error is the last error encountered, if any.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=cnsvolimp
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Total",type=integer,JSONPath=`.status.total`
+// +kubebuilder:printcolumn:name="Imported",type=integer,JSONPath=`.status.imported`
+// +kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CnsVolumeImport is the Schema for the cnsvolumeimports API.
+type CnsVolumeImport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CnsVolumeImportSpec   `json:"spec,omitempty"`
+	Status CnsVolumeImportStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+
+// CnsVolumeImportList contains a list of CnsVolumeImport.
+type CnsVolumeImportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CnsVolumeImport `json:"items"`
+}
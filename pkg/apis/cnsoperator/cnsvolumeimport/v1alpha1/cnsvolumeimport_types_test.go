@@ -0,0 +1,53 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+)
+
+// TestDeepCopyStatus verifies CnsVolumeImportStatus.DeepCopy copies the disks instead of sharing them.
+func TestDeepCopyStatus(t *testing.T) {
+	orig := &CnsVolumeImportStatus{
+		Phase: CnsVolumeImportPhaseInProgress,
+		Total: 1,
+		Disks: []CnsVolumeImportDiskStatus{
+			{VolumeID: "fcd-1", PVName: "static-pv-fcd-1", State: CnsVolumeImportDiskStateImported},
+		},
+	}
+	cp := orig.DeepCopy()
+	if cp.Phase != orig.Phase || cp.Total != orig.Total || len(cp.Disks) != 1 || cp.Disks[0] != orig.Disks[0] {
+		t.Fatalf("DeepCopy content mismatch: got %+v, want %+v", *cp, *orig)
+	}
+	cp.Disks[0].State = CnsVolumeImportDiskStateFailed
+	if orig.Disks[0].State != CnsVolumeImportDiskStateImported {
+		t.Errorf("mutation of copy affected original: orig.Disks[0].State = %q", orig.Disks[0].State)
+	}
+}
+
+// TestDeepCopyObject verifies that CnsVolumeImport and its list implement runtime.Object.
+func TestDeepCopyObject(t *testing.T) {
+	list := &CnsVolumeImportList{Items: []CnsVolumeImport{{Spec: CnsVolumeImportSpec{FolderPath: "import"}}}}
+	cp, ok := list.DeepCopyObject().(*CnsVolumeImportList)
+	if !ok {
+		t.Fatalf("DeepCopyObject returned %T", list.DeepCopyObject())
+	}
+	cp.Items[0].Spec.FolderPath = "changed"
+	if list.Items[0].Spec.FolderPath != "import" {
+		t.Errorf("mutation of copy affected original: FolderPath = %q", list.Items[0].Spec.FolderPath)
+	}
+}
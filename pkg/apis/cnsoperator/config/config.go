@@ -61,3 +61,8 @@ const EmbedStoragePolicyInfoCRFileName = "cns.vmware.com_storagepolicyinfos.yaml
 var EmbedVKSRegisterVolumeCRFile embed.FS
 
 const EmbedVKSRegisterVolumeCRFileName = "cns.vmware.com_vksregistervolumes.yaml"
+
+//go:embed cns.vmware.com_cnsvolumeimports.yaml
+var EmbedCnsVolumeImportCRFile embed.FS
+
+const EmbedCnsVolumeImportCRFileName = "cns.vmware.com_cnsvolumeimports.yaml"
@@ -31,6 +31,7 @@ import (
 	cnsnodevmbatchattachmentv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/cnsnodevmbatchattachment/v1alpha1"
 	cnsregistervolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/cnsregistervolume/v1alpha1"
 	cnsunregistervolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/cnsunregistervolume/v1alpha1"
+	cnsvolumeimportv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/cnsvolumeimport/v1alpha1"
 	cnsvolumemetadatav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
 	infrastoragepolicyinfov1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/infrastoragepolicyinfo/v1alpha1"
 	storagepolicyv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/storagepolicy/v1alpha1"
@@ -98,6 +99,8 @@ var (
 	StoragePolicyInfoPlural = "storagepolicyinfos"
 	// VKSRegisterVolumePlural is plural of VKSRegisterVolume
 	VKSRegisterVolumePlural = "vksregistervolumes"
+	// CnsVolumeImportPlural is plural of CnsVolumeImport
+	CnsVolumeImportPlural = "cnsvolumeimports"
 )
 
 var (
@@ -228,6 +231,12 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&vksregistervolumev1alpha1.VKSRegisterVolumeList{},
 	)
 
+	scheme.AddKnownTypes(
+		SchemeGroupVersion,
+		&cnsvolumeimportv1alpha1.CnsVolumeImport{},
+		&cnsvolumeimportv1alpha1.CnsVolumeImportList{},
+	)
+
 	scheme.AddKnownTypes(
 		SchemeGroupVersion,
 		&metav1.Status{},
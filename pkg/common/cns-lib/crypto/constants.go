@@ -24,6 +24,24 @@ const (
 	// PVCEncryptionClassAnnotationName is a PVC annotation indicating the associated EncryptionClass
 	PVCEncryptionClassAnnotationName = "csi.vsphere.encryption-class"
 
+	// PVCLastRekeyTimeAnnotationName is a PVC annotation recording the time,
+	// in RFC 3339 format, of the last rekey of the volume.
+	PVCLastRekeyTimeAnnotationName = "csi.vsphere.last-rekey-time"
+
+	// PVCLastRekeyKeyIDAnnotationName is a PVC annotation recording the ID of
+	// the key the volume was encrypted with by its last rekey.
+	PVCLastRekeyKeyIDAnnotationName = "csi.vsphere.last-rekey-key-id"
+
+	// EncryptionClassRekeyIntervalAnnotationName is an EncryptionClass
+	// annotation setting the number of days between the rekeys of the volumes
+	// of the class. The volumes are not rekeyed without it.
+	EncryptionClassRekeyIntervalAnnotationName = "csi.vsphere.rekey-interval-days"
+
+	// EncryptionClassRekeyMaxConcurrencyAnnotationName is an EncryptionClass
+	// annotation setting the maximum number of volumes of the class rekeyed
+	// at the same time.
+	EncryptionClassRekeyMaxConcurrencyAnnotationName = "csi.vsphere.rekey-max-concurrency"
+
 	// DefaultEncryptionClassLabelName is the name of the label that identifies
 	// the default EncryptionClass in a given namespace.
 	DefaultEncryptionClassLabelName = "encryption.vmware.com/default"
@@ -13,8 +13,10 @@ import (
 	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
+	"go.opentelemetry.io/otel/attribute"
 
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
 )
 
@@ -52,6 +54,10 @@ type ListViewImpl struct {
 	mu sync.RWMutex
 	// isReady defines the ready state of the listview + property collector mechanism
 	isReady bool
+	// migrateView is set when the session of the VC is replaced with new credentials.
+	// the listView is then re-created on the new session with the pending tasks,
+	// instead of reporting an error for them
+	migrateView bool
 }
 
 // TaskDetails is used to hold state for a task
@@ -83,6 +89,7 @@ func NewListViewImpl(ctx context.Context, virtualCenter *cnsvsphere.VirtualCente
 	if err != nil {
 		return nil, logger.LogNewErrorf(log, "failed to create a ListView. error: %+v", err)
 	}
+	virtualCenter.AddSessionChangeHandler(t.onSessionChange)
 	go t.listenToTaskUpdates()
 	go t.restartContainer()
 	return t, nil
@@ -149,9 +156,46 @@ func (l *ListViewImpl) ResetVirtualCenter(ctx context.Context, virtualCenter *cn
 	defer l.mu.Unlock()
 	log.Info("acquired lock before updating vc object")
 	l.virtualCenter = virtualCenter
+	virtualCenter.AddSessionChangeHandler(l.onSessionChange)
 	log.Info("updated VirtualCenter object reference in ListView")
 }
 
+// onSessionChange is called when the session of the VC is replaced with new credentials.
+// it cancels the WaitForUpdates loop so that the listView is re-created on the new session
+// with the pending tasks. the tasks in flight are not failed.
+func (l *ListViewImpl) onSessionChange(ctx context.Context, virtualCenter *cnsvsphere.VirtualCenter) {
+	log := logger.GetLogger(ctx)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if virtualCenter != l.virtualCenter {
+		// the listview has moved to another VC object since the handler was registered
+		return
+	}
+	log.Infof("session of vc %s changed. migrating listview to the new session", virtualCenter.Config.Host)
+	l.migrateView = true
+	if l.waitForUpdatesCancelFunc != nil {
+		l.waitForUpdatesCancelFunc()
+	}
+}
+
+// isMigratingView returns whether the WaitForUpdates loop was cancelled by onSessionChange, and resets it
+func (l *ListViewImpl) isMigratingView() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	migrateView := l.migrateView
+	l.migrateView = false
+	return migrateView
+}
+
+// getPendingTasks returns the references of the tasks in the map
+func (l *ListViewImpl) getPendingTasks() []types.ManagedObjectReference {
+	var tasks []types.ManagedObjectReference
+	for _, taskDetails := range l.taskMap.GetAll() {
+		tasks = append(tasks, taskDetails.Reference)
+	}
+	return tasks
+}
+
 func getListViewWaitFilter(listView *view.ListView) *property.WaitFilter {
 	ts := types.TraversalSpec{
 		Type: "ListView",
@@ -195,7 +239,12 @@ func (l *ListViewImpl) IsListViewReady() bool {
 }
 
 // AddTask adds task to listView and the internal map
-func (l *ListViewImpl) AddTask(ctx context.Context, taskMoRef types.ManagedObjectReference, ch chan TaskResult) error {
+func (l *ListViewImpl) AddTask(ctx context.Context, taskMoRef types.ManagedObjectReference,
+	ch chan TaskResult) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "ListView.AddTask", attribute.String("vsphere.task", taskMoRef.Value))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	log := logger.GetLogger(ctx)
 	log.Infof("AddTask called for %+v", taskMoRef)
 
@@ -217,7 +266,10 @@ func (l *ListViewImpl) AddTask(ctx context.Context, taskMoRef types.ManagedObjec
 	log.Debugf("task %+v added to map", taskMoRef)
 	log.Infof("client is valid. trying to add task to listview object")
 
-	response, err := l.listView.Add(l.ctx, []types.ManagedObjectReference{taskMoRef})
+	l.mu.RLock()
+	listView := l.listView
+	l.mu.RUnlock()
+	response, err := listView.Add(l.ctx, []types.ManagedObjectReference{taskMoRef})
 	if err != nil {
 		l.taskMap.Delete(taskMoRef)
 		l.SetListViewNotReady(ctx)
@@ -310,6 +362,8 @@ func (l *ListViewImpl) listenToTaskUpdates() {
 	// we need to recreate the listView and the wait filter after any error from vc
 	// for the first iteration we already have the listView and filter initialized
 	recreateView := false
+	// when the listView is migrated to a new session, the pending tasks are added to the re-created listView
+	migrateView := false
 	for {
 		// calling Connect at the beginning to ensure the current session is neither nil nor NotAuthenticated
 		if err := l.connect(); err != nil {
@@ -334,13 +388,22 @@ func (l *ListViewImpl) listenToTaskUpdates() {
 				}
 			}
 			log.Info("re-creating the listView object")
-			err := l.createListView(l.ctx, nil)
+			var pendingTasks []types.ManagedObjectReference
+			if migrateView {
+				// AddTask adds the task to the map before reading the listView under the lock,
+				// so the tasks added concurrently are either in the map or added to the new listView
+				pendingTasks = l.getPendingTasks()
+			}
+			err := l.createListView(l.ctx, pendingTasks)
 			if err != nil {
 				log.Errorf("failed to create a ListView. error: %+v", err)
 				l.mu.Unlock()
 				continue
 			}
-			log.Info("successfully created listview")
+			log.Infof("successfully created listview with %d pending tasks", len(pendingTasks))
+			// the listView is created on the current session, so a pending migration is done
+			migrateView = false
+			l.migrateView = false
 
 			filter = getListViewWaitFilter(l.listView)
 			l.waitForUpdatesContext, l.waitForUpdatesCancelFunc = context.WithCancel(context.Background())
@@ -381,7 +444,13 @@ func (l *ListViewImpl) listenToTaskUpdates() {
 		// if property collector returns any errors,
 		// we want to immediately return a fault for all the pending tasks in the map
 		// note: this is not a task error but an error from the vc
-		if err != nil {
+		if err != nil && l.isMigratingView() {
+			// the tasks are tracked on the new session from their current state,
+			// so a task completed during the migration is reported by the first update
+			log.Infof("migrating listview to the new session of vc: %+v", l.virtualCenter.Config.Host)
+			recreateView = true
+			migrateView = true
+		} else if err != nil {
 			log.Errorf("WaitForUpdates returned err: %v for vc: %+v", err,
 				l.virtualCenter.Config.Host)
 			recreateView = true
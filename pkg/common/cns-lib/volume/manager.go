@@ -35,6 +35,7 @@ import (
 	"github.com/vmware/govmomi/vslm"
 	vslmmethods "github.com/vmware/govmomi/vslm/methods"
 	vslmtypes "github.com/vmware/govmomi/vslm/types"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc/codes"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -43,6 +44,7 @@ import (
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
 	csifault "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/fault"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsvolumeoperationrequest"
 )
@@ -864,7 +866,12 @@ func IsTaskPending(volumeOperationDetails *cnsvolumeoperationrequest.VolumeOpera
 }
 
 func (m *defaultManager) waitOnTask(csiOpContext context.Context,
-	taskMoRef vim25types.ManagedObjectReference) (*vim25types.TaskInfo, error) {
+	taskMoRef vim25types.ManagedObjectReference) (taskInfo *vim25types.TaskInfo, err error) {
+	csiOpContext, span := tracing.StartSpan(csiOpContext, "cns.WaitOnTask",
+		attribute.String("vsphere.task", taskMoRef.Value))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	log := logger.GetLogger(csiOpContext)
 	if m.listViewIf == nil {
 		err := m.initListView(context.Background())
@@ -873,7 +880,7 @@ func (m *defaultManager) waitOnTask(csiOpContext context.Context,
 		}
 	}
 	ch := make(chan TaskResult, 1)
-	err := m.listViewIf.AddTask(csiOpContext, taskMoRef, ch)
+	err = m.listViewIf.AddTask(csiOpContext, taskMoRef, ch)
 	if errors.Is(err, ErrListViewTaskAddition) {
 		return nil, logger.LogNewErrorf(log, "%s. err: %v", listviewAdditionError, err)
 	} else if err != nil {
@@ -3828,7 +3835,7 @@ func (m *defaultManager) ReRegisterVolume(ctx context.Context, volumeID string)
 	log.Infof("ReRegisterVolume: Attempting to re-register volume %q to CNS", volumeID)
 
 	containerCluster := cnsvsphere.GetContainerCluster(m.clusterId,
-		m.virtualCenter.Config.Username,
+		m.virtualCenter.GetUsername(),
 		m.clusterFlavor, m.clusterDistribution)
 	containerClusterArray := []cnstypes.CnsContainerCluster{containerCluster}
 
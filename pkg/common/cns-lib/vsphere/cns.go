@@ -44,11 +44,15 @@ func (vc *VirtualCenter) ConnectCns(ctx context.Context) error {
 		log.Errorf("failed to connect to Virtual Center host %q with err: %v", vc.Config.Host, err)
 		return err
 	}
+	// UpdateCredentials replaces the clients under ClientMutex.
+	vc.ClientMutex.Lock()
+	defer vc.ClientMutex.Unlock()
 	if vc.CnsClient == nil {
 		if vc.CnsClient, err = NewCnsClient(ctx, vc.Client.Client); err != nil {
 			log.Errorf("failed to create CNS client on vCenter host %q with err: %v", vc.Config.Host, err)
 			return err
 		}
+		vc.CnsClient.RoundTripper = vc.newMetricRoundTripper("cns", vc.CnsClient.RoundTripper)
 	}
 	return nil
 }
@@ -56,6 +60,8 @@ func (vc *VirtualCenter) ConnectCns(ctx context.Context) error {
 // DisconnectCns destroys the CNS client for the virtual center.
 func (vc *VirtualCenter) DisconnectCns(ctx context.Context) {
 	log := logger.GetLogger(ctx)
+	vc.ClientMutex.Lock()
+	defer vc.ClientMutex.Unlock()
 	if vc.CnsClient == nil {
 		log.Info("CnsClient wasn't connected, ignoring")
 	} else {
@@ -19,11 +19,14 @@ package vsphere
 import (
 	"context"
 	"fmt"
+	"path"
+	"sort"
 
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/govmomi/vslm"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
 )
 
@@ -58,3 +61,59 @@ func (ds *Datastore) GetDatastoreURLAndType(ctx context.Context) (string, string
 	}
 	return dsMo.Summary.Url, dsMo.Summary.Type, nil
 }
+
+// ListVirtualDisks returns the paths, relative to the root of the datastore,
+// of the virtual disks in the given folder of the datastore and its subfolders.
+func (ds *Datastore) ListVirtualDisks(ctx context.Context, folderPath string) ([]string, error) {
+	log := logger.GetLogger(ctx)
+	browser, err := ds.Browser(ctx)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to get the browser of datastore %q. Error: %+v",
+			ds.Reference().Value, err)
+	}
+	searchSpec := &types.HostDatastoreBrowserSearchSpec{
+		Query:        []types.BaseFileQuery{&types.VmDiskFileQuery{}},
+		MatchPattern: []string{"*.vmdk"},
+	}
+	task, err := browser.SearchDatastoreSubFolders(ctx, ds.Path(folderPath), searchSpec)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to search folder %q of datastore %q. Error: %+v",
+			folderPath, ds.Reference().Value, err)
+	}
+	taskInfo, err := task.WaitForResult(ctx)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to search folder %q of datastore %q. Error: %+v",
+			folderPath, ds.Reference().Value, err)
+	}
+	results, ok := taskInfo.Result.(types.ArrayOfHostDatastoreBrowserSearchResults)
+	if !ok {
+		return nil, logger.LogNewErrorf(log, "unexpected result %T of the search of folder %q of datastore %q",
+			taskInfo.Result, folderPath, ds.Reference().Value)
+	}
+	var diskPaths []string
+	for _, result := range results.HostDatastoreBrowserSearchResults {
+		var folder object.DatastorePath
+		folder.FromString(result.FolderPath)
+		for _, file := range result.File {
+			diskPaths = append(diskPaths, path.Join(folder.Path, file.GetFileInfo().Path))
+		}
+	}
+	sort.Strings(diskPaths)
+	return diskPaths, nil
+}
+
+// ListVStorageObjects returns the IDs of the First Class Disks of the datastore.
+func (ds *Datastore) ListVStorageObjects(ctx context.Context) ([]string, error) {
+	log := logger.GetLogger(ctx)
+	ids, err := vslm.NewObjectManager(ds.Client()).List(ctx, ds.Datastore)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to list the First Class Disks of datastore %q. Error: %+v",
+			ds.Reference().Value, err)
+	}
+	volumeIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		volumeIDs = append(volumeIDs, id.Id)
+	}
+	sort.Strings(volumeIDs)
+	return volumeIDs, nil
+}
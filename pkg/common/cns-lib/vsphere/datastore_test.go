@@ -0,0 +1,106 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/govmomi/vslm"
+)
+
+func TestDatastoreListVirtualDisksAndVStorageObjects(t *testing.T) {
+	simulator.Test(func(ctx context.Context, c *vim25.Client) {
+		finder := find.NewFinder(c)
+		dc, err := finder.DefaultDatacenter(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		finder.SetDatacenter(dc)
+		govmomiDs, err := finder.DefaultDatastore(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ds := &Datastore{Datastore: govmomiDs}
+
+		err = object.NewFileManager(c).MakeDirectory(ctx, ds.Path("import/app"), dc, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		diskManager := object.NewVirtualDiskManager(c)
+		for _, diskPath := range []string{"import/disk-1.vmdk", "import/app/disk-2.vmdk"} {
+			task, err := diskManager.CreateVirtualDisk(ctx, ds.Path(diskPath), dc, &types.FileBackedVirtualDiskSpec{
+				VirtualDiskSpec: types.VirtualDiskSpec{
+					DiskType:    string(types.VirtualDiskTypeThin),
+					AdapterType: string(types.VirtualDiskAdapterTypeLsiLogic),
+				},
+				CapacityKb: 1024,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = task.Wait(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		diskPaths, err := ds.ListVirtualDisks(ctx, "import")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"import/app/disk-2.vmdk", "import/disk-1.vmdk"}
+		if len(diskPaths) != len(expected) {
+			t.Fatalf("expected virtual disks %v, got %v", expected, diskPaths)
+		}
+		for i := range expected {
+			if diskPaths[i] != expected[i] {
+				t.Fatalf("expected virtual disks %v, got %v", expected, diskPaths)
+			}
+		}
+
+		task, err := vslm.NewObjectManager(c).CreateDisk(ctx, types.VslmCreateSpec{
+			Name:         "fcd-1",
+			CapacityInMB: 1,
+			BackingSpec: &types.VslmCreateSpecDiskFileBackingSpec{
+				VslmCreateSpecBackingSpec: types.VslmCreateSpecBackingSpec{
+					Datastore: ds.Reference(),
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		taskInfo, err := task.WaitForResult(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fcd := taskInfo.Result.(types.VStorageObject)
+
+		volumeIDs, err := ds.ListVStorageObjects(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(volumeIDs) != 1 || volumeIDs[0] != fcd.Config.Id.Id {
+			t.Fatalf("expected First Class Disks [%s], got %v", fcd.Config.Id.Id, volumeIDs)
+		}
+	})
+}
@@ -71,11 +71,15 @@ func (vc *VirtualCenter) ConnectPbm(ctx context.Context) error {
 		log.Errorf("failed to connect to Virtual Center %q with err: %v", vc.Config.Host, err)
 		return err
 	}
+	// UpdateCredentials replaces the clients under ClientMutex.
+	vc.ClientMutex.Lock()
+	defer vc.ClientMutex.Unlock()
 	if vc.PbmClient == nil {
 		if vc.PbmClient, err = pbm.NewClient(ctx, vc.Client.Client); err != nil {
 			log.Errorf("failed to create pbm client with err: %v", err)
 			return err
 		}
+		vc.PbmClient.RoundTripper = vc.newMetricRoundTripper("pbm", vc.PbmClient.RoundTripper)
 	}
 	return nil
 }
@@ -83,6 +87,8 @@ func (vc *VirtualCenter) ConnectPbm(ctx context.Context) error {
 // DisconnectPbm destroys the PBM client for the virtual center.
 func (vc *VirtualCenter) DisconnectPbm(ctx context.Context) error {
 	log := logger.GetLogger(ctx)
+	vc.ClientMutex.Lock()
+	defer vc.ClientMutex.Unlock()
 	if vc.PbmClient == nil {
 		log.Info("PbmClient wasn't connected, ignoring")
 	} else {
@@ -0,0 +1,236 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+)
+
+// RequestPriority is the priority class of a vCenter API call. When the calls
+// to a vCenter are rate limited, the waiting calls of a higher priority class
+// are made first.
+type RequestPriority int
+
+const (
+	// PriorityBackground is the priority of the background syncs, such as the
+	// full sync, the CBT sync and the storage pool listeners. It is the
+	// priority of the calls made with a context holding no priority.
+	PriorityBackground RequestPriority = iota
+	// PriorityAttachDetach is the priority of the attach and detach of volumes.
+	PriorityAttachDetach
+	// PriorityUserFacing is the priority of the user-facing CSI RPCs, such as
+	// CreateVolume and DeleteVolume.
+	PriorityUserFacing
+
+	// numRequestPriorities is the number of priority classes.
+	numRequestPriorities
+)
+
+// String returns the name of the priority class, used as a metric label.
+func (p RequestPriority) String() string {
+	switch p {
+	case PriorityBackground:
+		return "background"
+	case PriorityAttachDetach:
+		return "attach-detach"
+	case PriorityUserFacing:
+		return "user-facing"
+	}
+	return fmt.Sprintf("RequestPriority(%d)", int(p))
+}
+
+// ErrAPIRateLimited is returned for a vCenter API call which waited longer than
+// the maximum wait of the rate limiter of the vCenter.
+var ErrAPIRateLimited = errors.New("vCenter API call rejected by the rate limiter")
+
+// requestPriorityKey is the context key of the RequestPriority of the calls.
+type requestPriorityKey struct{}
+
+// WithRequestPriority returns a context in which the vCenter API calls are
+// made with priority.
+func WithRequestPriority(ctx context.Context, priority RequestPriority) context.Context {
+	return context.WithValue(ctx, requestPriorityKey{}, priority)
+}
+
+// GetRequestPriority returns the priority of the vCenter API calls made with
+// ctx, PriorityBackground if it holds no priority.
+func GetRequestPriority(ctx context.Context) RequestPriority {
+	if priority, ok := ctx.Value(requestPriorityKey{}).(RequestPriority); ok &&
+		priority >= 0 && priority < numRequestPriorities {
+		return priority
+	}
+	return PriorityBackground
+}
+
+// APIRateLimiter is a token bucket limiting the rate of the API calls to a
+// vCenter. The calls waiting for a token are let through in order of priority,
+// and in order of arrival within a priority class.
+type APIRateLimiter struct {
+	// host is the vCenter of the limiter, used as a metric label.
+	host string
+	// rate is the number of tokens added to the bucket per second.
+	rate float64
+	// burst is the capacity of the bucket.
+	burst float64
+	// maxWait is the maximum wait of a call for a token, unlimited if 0.
+	maxWait time.Duration
+
+	lock    sync.Mutex
+	tokens  float64
+	last    time.Time
+	waiters [numRequestPriorities][]*apiRateLimitWaiter
+	// timer dispatches the tokens to the waiters when they are added to the
+	// bucket, nil if no dispatch is scheduled.
+	timer *time.Timer
+}
+
+// apiRateLimitWaiter is a call waiting for a token.
+type apiRateLimitWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// NewAPIRateLimiter returns an APIRateLimiter of host allowing rate calls per
+// second, with bursts of burst calls, and rejecting the calls waiting longer
+// than maxWait.
+func NewAPIRateLimiter(host string, rate float64, burst int, maxWait time.Duration) *APIRateLimiter {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &APIRateLimiter{
+		host:    host,
+		rate:    rate,
+		burst:   float64(burst),
+		maxWait: maxWait,
+		tokens:  float64(burst),
+		last:    time.Now(),
+	}
+}
+
+// Wait blocks until a call of priority may be made. It returns
+// ErrAPIRateLimited if the call waited longer than the maximum wait, or the
+// error of ctx if it is done first.
+func (l *APIRateLimiter) Wait(ctx context.Context, priority RequestPriority) error {
+	if priority < 0 || priority >= numRequestPriorities {
+		priority = PriorityBackground
+	}
+	start := time.Now()
+	waiter := &apiRateLimitWaiter{ready: make(chan struct{})}
+	l.lock.Lock()
+	l.waiters[priority] = append(l.waiters[priority], waiter)
+	l.dispatchLocked()
+	l.lock.Unlock()
+
+	var timeout <-chan time.Time
+	if l.maxWait > 0 {
+		timer := time.NewTimer(l.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var err error
+	select {
+	case <-waiter.ready:
+	case <-timeout:
+		err = fmt.Errorf("%w: waited %v for a %s call to vCenter %q", ErrAPIRateLimited, l.maxWait, priority, l.host)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		l.lock.Lock()
+		if waiter.granted {
+			// The token was granted while the wait ended.
+			err = nil
+		} else {
+			l.removeLocked(priority, waiter)
+		}
+		l.lock.Unlock()
+	}
+	prometheus.VCenterAPIRateLimitWaitHistVec.WithLabelValues(l.host, priority.String()).Observe(
+		time.Since(start).Seconds())
+	if errors.Is(err, ErrAPIRateLimited) {
+		prometheus.VCenterAPIRateLimitRejectionsCounterVec.WithLabelValues(l.host, priority.String()).Inc()
+	}
+	return err
+}
+
+// dispatchLocked grants the tokens of the bucket to the waiters of the highest
+// priority, and schedules the next dispatch if waiters are left. It must be
+// called with the lock held.
+func (l *APIRateLimiter) dispatchLocked() {
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	for l.tokens >= 1 {
+		waiter := l.popLocked()
+		if waiter == nil {
+			return
+		}
+		l.tokens--
+		waiter.granted = true
+		close(waiter.ready)
+	}
+	if l.timer != nil || !l.hasWaitersLocked() {
+		return
+	}
+	delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	l.timer = time.AfterFunc(delay, func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		l.timer = nil
+		l.dispatchLocked()
+	})
+}
+
+// popLocked removes and returns the first waiter of the highest priority, nil
+// if no call is waiting.
+func (l *APIRateLimiter) popLocked() *apiRateLimitWaiter {
+	for priority := numRequestPriorities - 1; priority >= 0; priority-- {
+		if len(l.waiters[priority]) > 0 {
+			waiter := l.waiters[priority][0]
+			l.waiters[priority] = l.waiters[priority][1:]
+			return waiter
+		}
+	}
+	return nil
+}
+
+// hasWaitersLocked returns true if calls are waiting for a token.
+func (l *APIRateLimiter) hasWaitersLocked() bool {
+	for _, waiters := range l.waiters {
+		if len(waiters) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// removeLocked removes waiter from the waiters of priority.
+func (l *APIRateLimiter) removeLocked(priority RequestPriority, waiter *apiRateLimitWaiter) {
+	for i, w := range l.waiters[priority] {
+		if w == waiter {
+			l.waiters[priority] = append(l.waiters[priority][:i], l.waiters[priority][i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAPIRateLimiterPriority(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewAPIRateLimiter("vc", 20, 1, 0)
+	// The burst lets the first call through.
+	if err := limiter.Wait(ctx, PriorityBackground); err != nil {
+		t.Fatal(err)
+	}
+
+	// The calls queued while the bucket is empty are made by priority.
+	order := make(chan RequestPriority, 3)
+	for _, priority := range []RequestPriority{PriorityBackground, PriorityAttachDetach, PriorityUserFacing} {
+		go func() {
+			if err := limiter.Wait(ctx, priority); err != nil {
+				t.Error(err)
+			}
+			order <- priority
+		}()
+		// Let the call be queued before the next one.
+		for {
+			limiter.lock.Lock()
+			queued := len(limiter.waiters[priority]) == 1
+			limiter.lock.Unlock()
+			if queued {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for _, expected := range []RequestPriority{PriorityUserFacing, PriorityAttachDetach, PriorityBackground} {
+		select {
+		case priority := <-order:
+			if priority != expected {
+				t.Fatalf("expected the %s call to be made, got the %s call", expected, priority)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the %s call", expected)
+		}
+	}
+}
+
+func TestAPIRateLimiterRejection(t *testing.T) {
+	limiter := NewAPIRateLimiter("vc", 0.01, 1, 10*time.Millisecond)
+	if err := limiter.Wait(context.Background(), PriorityUserFacing); err != nil {
+		t.Fatal(err)
+	}
+	err := limiter.Wait(context.Background(), PriorityUserFacing)
+	if !errors.Is(err, ErrAPIRateLimited) {
+		t.Fatalf("expected the call to be rejected after the maximum wait, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx, PriorityBackground); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the error of the context, got %v", err)
+	}
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+	if limiter.hasWaitersLocked() {
+		t.Error("expected the rejected calls to be removed from the queue")
+	}
+}
+
+func TestGetRequestPriority(t *testing.T) {
+	ctx := context.Background()
+	if priority := GetRequestPriority(ctx); priority != PriorityBackground {
+		t.Errorf("expected the calls without priority to be background calls, got %s", priority)
+	}
+	ctx = WithRequestPriority(ctx, PriorityAttachDetach)
+	if priority := GetRequestPriority(ctx); priority != PriorityAttachDetach {
+		t.Errorf("expected the priority of the context, got %s", priority)
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/vim25"
+
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+)
+
+const (
+	// tokenExchangePath is the path of the vCenter token exchange API.
+	tokenExchangePath = "/api/vcenter/tokenservice/token-exchange"
+	// tokenExchangeGrantType is the grant type of an OAuth 2.0 token exchange.
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	// jwtTokenType is the type of the service account token exchanged.
+	jwtTokenType = "urn:ietf:params:oauth:token-type:jwt"
+	// saml2TokenType is the type of the token requested from vCenter.
+	saml2TokenType = "urn:ietf:params:oauth:token-type:saml2"
+	// tokenRefreshRatio is the part of the lifetime of an exchanged token after
+	// which it is refreshed.
+	tokenRefreshRatio = 0.8
+)
+
+var (
+	// timeNow returns the current time. Overridden in unit tests.
+	timeNow = time.Now
+
+	// serviceAccountTokenProviders are the ServiceAccountTokenProviders of the
+	// configuration, by path of the service account token.
+	serviceAccountTokenProviders     = make(map[string]*ServiceAccountTokenProvider)
+	serviceAccountTokenProvidersLock = &sync.Mutex{}
+)
+
+// CredentialProvider provides the SAML bearer token used to log in to vCenter,
+// instead of the username and password of the vCenter configuration.
+type CredentialProvider interface {
+	// GetToken returns a SAML bearer token valid for a login to the vCenter of
+	// client.
+	GetToken(ctx context.Context, client *vim25.Client) (string, error)
+}
+
+// FileTokenProvider is a CredentialProvider reading the SAML bearer token from
+// a file, which is read again on each login so that it can be rotated by an
+// external agent.
+type FileTokenProvider struct {
+	// Path is the path of the file holding the SAML bearer token.
+	Path string
+}
+
+// NewFileTokenProvider returns a FileTokenProvider reading the SAML bearer
+// token from path.
+func NewFileTokenProvider(path string) *FileTokenProvider {
+	return &FileTokenProvider{Path: path}
+}
+
+// GetToken returns the SAML bearer token read from the file.
+func (p *FileTokenProvider) GetToken(ctx context.Context, client *vim25.Client) (string, error) {
+	log := logger.GetLogger(ctx)
+	token, err := os.ReadFile(p.Path)
+	if err != nil {
+		return "", logger.LogNewErrorf(log, "failed to read the SAML token file %q. Err: %v", p.Path, err)
+	}
+	if len(bytes.TrimSpace(token)) == 0 {
+		return "", logger.LogNewErrorf(log, "SAML token file %q is empty", p.Path)
+	}
+	return string(bytes.TrimSpace(token)), nil
+}
+
+// ServiceAccountTokenProvider is a CredentialProvider exchanging a projected
+// Kubernetes service account token for a SAML bearer token through the vCenter
+// token exchange API. The SAML token is cached, and exchanged again once
+// tokenRefreshRatio of its lifetime elapsed, so that a login never uses a token
+// about to expire.
+type ServiceAccountTokenProvider struct {
+	// Path is the path of the projected service account token, which is read
+	// again on each exchange as the kubelet rotates it.
+	Path string
+
+	lock      sync.Mutex
+	token     string
+	expiresAt time.Time
+	refreshAt time.Time
+}
+
+// NewServiceAccountTokenProvider returns a ServiceAccountTokenProvider
+// exchanging the service account token of path.
+func NewServiceAccountTokenProvider(path string) *ServiceAccountTokenProvider {
+	return &ServiceAccountTokenProvider{Path: path}
+}
+
+// tokenExchangeRequest is the body of a vCenter token exchange request.
+type tokenExchangeRequest struct {
+	GrantType          string `json:"grant_type"`
+	SubjectToken       string `json:"subject_token"`
+	SubjectTokenType   string `json:"subject_token_type"`
+	RequestedTokenType string `json:"requested_token_type"`
+}
+
+// tokenExchangeResponse is the body of a vCenter token exchange response.
+type tokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+// GetToken returns the cached SAML bearer token, or exchanges the service
+// account token for a new one if the cached token is due for a refresh. If the
+// exchange fails, the cached token is returned until it expires.
+func (p *ServiceAccountTokenProvider) GetToken(ctx context.Context, client *vim25.Client) (string, error) {
+	log := logger.GetLogger(ctx)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := timeNow()
+	if p.token != "" && now.Before(p.refreshAt) {
+		return p.token, nil
+	}
+	token, lifetime, err := p.exchange(ctx, client)
+	if err != nil {
+		if p.token != "" && now.Before(p.expiresAt) {
+			log.Warnf("failed to refresh the SAML token of service account token %q, using the cached token "+
+				"expiring at %v. Err: %v", p.Path, p.expiresAt, err)
+			return p.token, nil
+		}
+		return "", err
+	}
+	p.token = token
+	p.expiresAt = now.Add(lifetime)
+	p.refreshAt = now.Add(time.Duration(float64(lifetime) * tokenRefreshRatio))
+	log.Infof("Exchanged service account token %q for a SAML token expiring at %v", p.Path, p.expiresAt)
+	return p.token, nil
+}
+
+// exchange exchanges the service account token for a SAML bearer token, and
+// returns it with its lifetime.
+func (p *ServiceAccountTokenProvider) exchange(ctx context.Context,
+	client *vim25.Client) (string, time.Duration, error) {
+	log := logger.GetLogger(ctx)
+	subjectToken, err := os.ReadFile(p.Path)
+	if err != nil {
+		return "", 0, logger.LogNewErrorf(log, "failed to read the service account token file %q. Err: %v",
+			p.Path, err)
+	}
+	body, err := json.Marshal(tokenExchangeRequest{
+		GrantType:          tokenExchangeGrantType,
+		SubjectToken:       strings.TrimSpace(string(subjectToken)),
+		SubjectTokenType:   jwtTokenType,
+		RequestedTokenType: saml2TokenType,
+	})
+	if err != nil {
+		return "", 0, logger.LogNewErrorf(log, "failed to marshal the token exchange request. Err: %v", err)
+	}
+	url := *client.URL()
+	url.Path = tokenExchangePath
+	url.RawQuery = ""
+	req, err := http.NewRequest(http.MethodPost, url.String(), bytes.NewReader(body))
+	if err != nil {
+		return "", 0, logger.LogNewErrorf(log, "failed to create the token exchange request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	var res tokenExchangeResponse
+	err = client.Client.Do(ctx, req, func(httpRes *http.Response) error {
+		if httpRes.StatusCode != http.StatusOK && httpRes.StatusCode != http.StatusCreated {
+			msg, _ := io.ReadAll(io.LimitReader(httpRes.Body, 1024))
+			return fmt.Errorf("%s: %s", httpRes.Status, strings.TrimSpace(string(msg)))
+		}
+		return json.NewDecoder(httpRes.Body).Decode(&res)
+	})
+	if err != nil {
+		return "", 0, logger.LogNewErrorf(log, "failed to exchange service account token %q at %q. Err: %v",
+			p.Path, url.String(), err)
+	}
+	if res.IssuedTokenType != "" && res.IssuedTokenType != saml2TokenType {
+		return "", 0, logger.LogNewErrorf(log, "token exchange returned a token of type %q instead of %q",
+			res.IssuedTokenType, saml2TokenType)
+	}
+	if res.AccessToken == "" || res.ExpiresIn <= 0 {
+		return "", 0, logger.LogNewErrorf(log, "token exchange returned an empty or expired token")
+	}
+	token, err := decodeSAMLToken(res.AccessToken)
+	if err != nil {
+		return "", 0, logger.LogNewErrorf(log, "failed to decode the SAML token. Err: %v", err)
+	}
+	return token, time.Duration(res.ExpiresIn) * time.Second, nil
+}
+
+// decodeSAMLToken returns the SAML assertion of an access token, which vCenter
+// returns base64 encoded.
+func decodeSAMLToken(accessToken string) (string, error) {
+	if strings.HasPrefix(strings.TrimSpace(accessToken), "<") {
+		return strings.TrimSpace(accessToken), nil
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding,
+		base64.RawStdEncoding, base64.RawURLEncoding} {
+		if token, err := encoding.DecodeString(accessToken); err == nil {
+			return string(token), nil
+		}
+	}
+	return "", fmt.Errorf("access token is neither a SAML assertion nor base64 encoded")
+}
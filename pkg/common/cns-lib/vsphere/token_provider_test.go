@@ -0,0 +1,198 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi/simulator"
+
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+)
+
+const testSAMLToken = `<saml2:Assertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion">` +
+	`<saml2:Subject><saml2:NameID>csi@vsphere.local</saml2:NameID></saml2:Subject></saml2:Assertion>`
+
+// newTokenProviderTestVC returns a VirtualCenter of a vCenter simulator logging
+// in with provider.
+func newTokenProviderTestVC(t *testing.T, provider CredentialProvider) (*VirtualCenter, *simulator.Service) {
+	model := simulator.VPX()
+	t.Cleanup(model.Remove)
+	if err := model.Create(); err != nil {
+		t.Fatal(err)
+	}
+	model.Service.TLS = new(tls.Config)
+	s := model.Service.NewServer()
+	t.Cleanup(s.Close)
+
+	// Write values to test_vsphere.conf, read for the useragent of the sessions.
+	os.Setenv("VSPHERE_CSI_CONFIG", "test_vsphere.conf")
+	conf := []byte(fmt.Sprintf("[Global]\ninsecure-flag = \"true\"\ncluster-id = \"test-cluster\"\n"+
+		"[VirtualCenter \"%s\"]\nsaml-token-file = \"token\"\nport = \"%s\"", s.URL.Hostname(), s.URL.Port()))
+	if err := os.WriteFile("test_vsphere.conf", conf, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Unsetenv("VSPHERE_CSI_CONFIG")
+		os.Remove("test_vsphere.conf")
+	})
+
+	port, err := strconv.Atoi(s.URL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	vc := &VirtualCenter{
+		Config: &VirtualCenterConfig{
+			Host:               s.URL.Hostname(),
+			Port:               port,
+			CredentialProvider: provider,
+			Insecure:           true,
+		},
+		ClientMutex: &sync.Mutex{},
+	}
+	return vc, model.Service
+}
+
+func TestFileTokenProvider(t *testing.T) {
+	ctx := context.Background()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(testSAMLToken+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	vc, _ := newTokenProviderTestVC(t, NewFileTokenProvider(tokenFile))
+	if err := vc.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if vc.GetUsername() != "csi@vsphere.local" {
+		t.Errorf("expected the user of the session to be recorded, got %q", vc.GetUsername())
+	}
+	if vc.Config.Username != "" {
+		t.Errorf("expected the configured username not to be changed, got %q", vc.Config.Username)
+	}
+
+	// An empty token file fails the login.
+	if err := os.WriteFile(tokenFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileTokenProvider(tokenFile).GetToken(ctx, vc.Client.Client); err == nil {
+		t.Error("expected an empty token file to fail")
+	}
+}
+
+func TestServiceAccountTokenProvider(t *testing.T) {
+	ctx := context.Background()
+	saTokenFile := filepath.Join(t.TempDir(), "sa-token")
+	if err := os.WriteFile(saTokenFile, []byte("sa-jwt\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	provider := NewServiceAccountTokenProvider(saTokenFile)
+	vc, service := newTokenProviderTestVC(t, provider)
+
+	origTimeNow := timeNow
+	defer func() {
+		timeNow = origTimeNow
+	}()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	// Stand-in for the vCenter token exchange API.
+	var exchanges int
+	failExchange := false
+	service.Handle(tokenExchangePath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req tokenExchangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SubjectToken != "sa-jwt" ||
+			req.GrantType != tokenExchangeGrantType || req.RequestedTokenType != saml2TokenType {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if failExchange {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		exchanges++
+		_ = json.NewEncoder(w).Encode(tokenExchangeResponse{
+			AccessToken:     base64.StdEncoding.EncodeToString([]byte(testSAMLToken)),
+			IssuedTokenType: saml2TokenType,
+			TokenType:       "Bearer",
+			ExpiresIn:       600,
+		})
+	}))
+
+	if err := vc.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if vc.GetUsername() != "csi@vsphere.local" || exchanges != 1 {
+		t.Fatalf("expected a login with the exchanged token, got user %q after %d exchanges",
+			vc.GetUsername(), exchanges)
+	}
+
+	// The token is cached until 80% of its lifetime elapsed.
+	now = now.Add(7 * time.Minute)
+	if token, err := provider.GetToken(ctx, vc.Client.Client); err != nil || token != testSAMLToken {
+		t.Fatalf("expected the cached token, got err %v", err)
+	}
+	if exchanges != 1 {
+		t.Fatalf("expected the cached token to be used, got %d exchanges", exchanges)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := provider.GetToken(ctx, vc.Client.Client); err != nil {
+		t.Fatal(err)
+	}
+	if exchanges != 2 {
+		t.Fatalf("expected the token to be refreshed before it expires, got %d exchanges", exchanges)
+	}
+
+	// A failed refresh returns the cached token until it expires.
+	failExchange = true
+	now = now.Add(9 * time.Minute)
+	if token, err := provider.GetToken(ctx, vc.Client.Client); err != nil || token != testSAMLToken {
+		t.Fatalf("expected the cached token on a failed refresh, got err %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := provider.GetToken(ctx, vc.Client.Client); err == nil {
+		t.Fatal("expected a failed refresh of an expired token to fail")
+	}
+}
+
+func TestNewCredentialProviderIsShared(t *testing.T) {
+	saTokenFile := filepath.Join(t.TempDir(), "sa-token")
+	vcConfig := &config.VirtualCenterConfig{ServiceAccountTokenFile: saTokenFile}
+	// The configuration is read again on each reload.
+	provider := newCredentialProvider(vcConfig)
+	if reloaded := newCredentialProvider(&config.VirtualCenterConfig{
+		ServiceAccountTokenFile: saTokenFile}); reloaded != provider {
+		t.Error("expected the provider of the token file to be shared by the reloaded configuration")
+	}
+	if other := newCredentialProvider(&config.VirtualCenterConfig{
+		ServiceAccountTokenFile: saTokenFile + "-other"}); other == provider {
+		t.Error("expected another token file to have its own provider")
+	}
+	if newCredentialProvider(&config.VirtualCenterConfig{User: "user", Password: "pass"}) != nil {
+		t.Error("expected no provider for a username and password")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/vmware/govmomi/cns"
@@ -195,12 +196,16 @@ func GetVirtualCenterConfig(ctx context.Context, cfg *config.Config) (*VirtualCe
 		Thumbprint:                  vcThumbprint,
 		Username:                    cfg.VirtualCenter[host].User,
 		Password:                    cfg.VirtualCenter[host].Password,
+		CredentialProvider:          newCredentialProvider(cfg.VirtualCenter[host]),
 		Insecure:                    cfg.VirtualCenter[host].InsecureFlag,
 		TargetvSANFileShareClusters: targetvSANClustersForFile,
 		QueryLimit:                  cfg.Global.QueryLimit,
 		ListVolumeThreshold:         cfg.Global.ListVolumeThreshold,
 		MigrationDataStoreURL:       cfg.VirtualCenter[host].MigrationDataStoreURL,
 		FileVolumeActivated:         cfg.VirtualCenter[host].FileVolumeActivated,
+		APIRateLimit:                cfg.VirtualCenter[host].APIRateLimit,
+		APIBurst:                    cfg.VirtualCenter[host].APIBurst,
+		APIMaxWait:                  time.Duration(cfg.VirtualCenter[host].APIMaxWaitSeconds) * time.Second,
 	}
 
 	log.Debugf("Setting the queryLimit = %v, ListVolumeThreshold = %v", vcConfig.QueryLimit, vcConfig.ListVolumeThreshold)
@@ -242,11 +247,15 @@ func GetVirtualCenterConfigs(ctx context.Context, cfg *config.Config) ([]*Virtua
 			Thumbprint:                  cfg.VirtualCenter[vCenterIP].Thumbprint,
 			Username:                    cfg.VirtualCenter[vCenterIP].User,
 			Password:                    cfg.VirtualCenter[vCenterIP].Password,
+			CredentialProvider:          newCredentialProvider(cfg.VirtualCenter[vCenterIP]),
 			Insecure:                    cfg.VirtualCenter[vCenterIP].InsecureFlag,
 			TargetvSANFileShareClusters: targetvSANClustersForFile,
 			QueryLimit:                  cfg.Global.QueryLimit,
 			ListVolumeThreshold:         cfg.Global.ListVolumeThreshold,
 			FileVolumeActivated:         cfg.VirtualCenter[vCenterIP].FileVolumeActivated,
+			APIRateLimit:                cfg.VirtualCenter[vCenterIP].APIRateLimit,
+			APIBurst:                    cfg.VirtualCenter[vCenterIP].APIBurst,
+			APIMaxWait:                  time.Duration(cfg.VirtualCenter[vCenterIP].APIMaxWaitSeconds) * time.Second,
 		}
 		if vcConfig.CAFile == "" {
 			vcConfig.CAFile = cfg.Global.CAFile
@@ -266,6 +275,28 @@ func GetVirtualCenterConfigs(ctx context.Context, cfg *config.Config) ([]*Virtua
 	return VirtualCenterConfigs, nil
 }
 
+// newCredentialProvider returns the CredentialProvider of the token file of the
+// vCenter configuration, or nil if it logs in with a username and password.
+// The ServiceAccountTokenProvider of a token file is created once and shared by
+// the configurations read on reloads, so that its exchanged token is cached
+// until it is due for a refresh.
+func newCredentialProvider(vcConfig *config.VirtualCenterConfig) CredentialProvider {
+	if vcConfig.ServiceAccountTokenFile != "" {
+		serviceAccountTokenProvidersLock.Lock()
+		defer serviceAccountTokenProvidersLock.Unlock()
+		provider, ok := serviceAccountTokenProviders[vcConfig.ServiceAccountTokenFile]
+		if !ok {
+			provider = NewServiceAccountTokenProvider(vcConfig.ServiceAccountTokenFile)
+			serviceAccountTokenProviders[vcConfig.ServiceAccountTokenFile] = provider
+		}
+		return provider
+	}
+	if vcConfig.SAMLTokenFile != "" {
+		return NewFileTokenProvider(vcConfig.SAMLTokenFile)
+	}
+	return nil
+}
+
 // GetVcenterIPs returns list of vCenter IPs from VSphereConfig.
 func GetVcenterIPs(cfg *config.Config) ([]string, error) {
 	var err error
@@ -307,9 +338,9 @@ func CompareKubernetesMetadata(ctx context.Context, k8sMetaData *cnstypes.CnsKub
 	return labelsMatch
 }
 
-// Signer decodes the certificate and private key and returns SAML token needed
-// for authentication.
-func signer(ctx context.Context, client *vim25.Client, username string, password string) (*sts.Signer, error) {
+// newSigner decodes the certificate and private key and returns SAML token
+// needed for authentication.
+func newSigner(ctx context.Context, client *vim25.Client, username string, password string) (*sts.Signer, error) {
 	pemBlock, _ := pem.Decode([]byte(username))
 	if pemBlock == nil {
 		return nil, nil
@@ -342,9 +373,19 @@ func GetTagManager(ctx context.Context, vc *VirtualCenter) (*tags.Manager, error
 	}
 
 	restClient := rest.NewClient(vc.Client.Client)
-	signer, err := signer(ctx, vc.Client.Client, vc.Config.Username, vc.Config.Password)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create the Signer. Error: %v", err)
+	var signer *sts.Signer
+	var err error
+	if vc.Config.CredentialProvider != nil {
+		token, err := vc.Config.CredentialProvider.GetToken(ctx, vc.Client.Client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the SAML token from the credential provider. Error: %v", err)
+		}
+		signer = &sts.Signer{Token: token}
+	} else {
+		signer, err = newSigner(ctx, vc.Client.Client, vc.Config.Username, vc.Config.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the Signer. Error: %v", err)
+		}
 	}
 	if signer == nil {
 		user := url.UserPassword(vc.Config.Username, vc.Config.Password)
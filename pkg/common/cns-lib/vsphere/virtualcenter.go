@@ -43,8 +43,10 @@ import (
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/govmomi/vsan"
 	"github.com/vmware/govmomi/vslm"
+	"go.opentelemetry.io/otel/attribute"
 
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
 
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
@@ -72,6 +74,11 @@ const (
 	retryDelay      = 3 * time.Minute
 )
 
+// sessionLogoutDelay is the delay after which the session replaced by
+// UpdateCredentials is logged out, so that the calls in flight on the
+// previous session can complete.
+var sessionLogoutDelay = 2 * time.Minute
+
 // VirtualCenter holds details of a virtual center instance.
 type VirtualCenter struct {
 	// Config represents the virtual center configuration.
@@ -86,13 +93,31 @@ type VirtualCenter struct {
 	VsanClient *vsan.Client
 	// VslmClient represents the Vslm client instance.
 	VslmClient *vslm.Client
-	// ClientMutex is used for exclusive connection creation.
+	// ClientMutex is used for exclusive connection creation. It also guards
+	// the replacement of the clients by UpdateCredentials and
+	// sessionChangeHandlers.
 	ClientMutex *sync.Mutex
+	// sessionChangeHandlers are called after the session is replaced by
+	// UpdateCredentials.
+	sessionChangeHandlers []SessionChangeHandler
+	// sessionUsername is the user of the session logged in with the token of
+	// Config.CredentialProvider, as Config.Username is not set in that case.
+	sessionUsername string
+	// apiRateLimiter limits the rate of the API calls of all the clients of
+	// the virtual center, nil if the rate is not limited.
+	apiRateLimiter *APIRateLimiter
 }
 
+// SessionChangeHandler is called after the session of a VirtualCenter is
+// replaced by a session logged in with new credentials. The clients of the
+// VirtualCenter are already recreated on the new session when it is called.
+type SessionChangeHandler func(ctx context.Context, vc *VirtualCenter)
+
 type MetricRoundTripper struct {
 	roundTripper soap.RoundTripper
 	clientName   string
+	// rateLimiter limits the rate of the calls, if not nil.
+	rateLimiter *APIRateLimiter
 }
 
 var (
@@ -124,6 +149,9 @@ type VirtualCenterConfig struct {
 	Username string
 	// Password represents the virtual center password in clear text.
 	Password string
+	// CredentialProvider provides the SAML token used to log in instead of
+	// Username and Password, if set.
+	CredentialProvider CredentialProvider
 	// Specifies the path to a CA certificate in PEM format. This has no effect
 	// if Insecure is enabled. Optional; if not configured, the system's CA
 	// certificates will be used.
@@ -158,6 +186,14 @@ type VirtualCenterConfig struct {
 	ReloadVCConfigForNewClient bool
 	// FileVolumeActivated indicates whether file service has been enabled on any vSAN cluster or not
 	FileVolumeActivated bool
+	// APIRateLimit is the maximum number of API calls per second to the
+	// virtual center. The calls are not limited if 0.
+	APIRateLimit float64
+	// APIBurst is the number of API calls allowed at once above APIRateLimit.
+	APIBurst int
+	// APIMaxWait is the maximum time an API call waits for the rate limiter
+	// before it is rejected.
+	APIMaxWait time.Duration
 }
 
 // NewClient creates a new govmomi Client instance.
@@ -225,21 +261,55 @@ func (vc *VirtualCenter) NewClient(ctx context.Context, useragent string) (*govm
 		return nil, errors.New("nil session obtained from session manager")
 	}
 	log.Infof("New session ID for '%s' = %s", s.UserName, s.Key)
+	if vc.Config.CredentialProvider != nil {
+		// The user of the token is used to check the privileges of the
+		// session, and in the metadata of the volumes. It is not recorded in
+		// Config.Username, which is compared with the reloaded configuration.
+		vc.sessionUsername = s.UserName
+	}
 
 	if vc.Config.RoundTripperCount == 0 {
 		vc.Config.RoundTripperCount = DefaultRoundTripperCount
 	}
 	rt := vim25.Retry(client.RoundTripper, vim25.TemporaryNetworkError(vc.Config.RoundTripperCount))
-	client.RoundTripper = &MetricRoundTripper{clientName: "soap", roundTripper: rt}
+	if vc.apiRateLimiter == nil && vc.Config.APIRateLimit > 0 {
+		log.Infof("Limiting the API calls to vCenter %q to %v per second with bursts of %d",
+			vc.Config.Host, vc.Config.APIRateLimit, vc.Config.APIBurst)
+		vc.apiRateLimiter = NewAPIRateLimiter(vc.Config.Host, vc.Config.APIRateLimit, vc.Config.APIBurst,
+			vc.Config.APIMaxWait)
+	}
+	client.RoundTripper = vc.newMetricRoundTripper("soap", rt)
 	return client, nil
 }
 
-// login calls SessionManager.LoginByToken if certificate and private key are
-// configured. Otherwise, calls SessionManager.Login with user and password.
+// newMetricRoundTripper returns the MetricRoundTripper of the client clientName
+// of vc, rate limited by the API rate limiter of vc. If rt is already a
+// MetricRoundTripper, the round tripper it wraps is wrapped instead.
+func (vc *VirtualCenter) newMetricRoundTripper(clientName string, rt soap.RoundTripper) soap.RoundTripper {
+	if mrt, ok := rt.(*MetricRoundTripper); ok {
+		rt = mrt.roundTripper
+	}
+	return &MetricRoundTripper{clientName: clientName, roundTripper: rt, rateLimiter: vc.apiRateLimiter}
+}
+
+// login calls SessionManager.LoginByToken with the token of the credential
+// provider if one is configured, or with a token issued by STS if certificate
+// and private key are configured. Otherwise, calls SessionManager.Login with
+// user and password.
 func (vc *VirtualCenter) login(ctx context.Context, client *govmomi.Client) error {
 	log := logger.GetLogger(ctx)
 	var err error
 
+	if vc.Config.CredentialProvider != nil {
+		token, err := vc.Config.CredentialProvider.GetToken(ctx, client.Client)
+		if err != nil {
+			log.Errorf("failed to get SAML token from the credential provider with err: %v", err)
+			return err
+		}
+		header := soap.Header{Security: &sts.Signer{Token: token}}
+		return client.SessionManager.LoginByToken(client.Client.WithHeader(ctx, header))
+	}
+
 	b, _ := pem.Decode([]byte(vc.Config.Username))
 	if b == nil {
 		return client.SessionManager.Login(ctx,
@@ -422,13 +492,21 @@ func (vc *VirtualCenter) connect(ctx context.Context) error {
 		}
 		return err
 	}
+	return vc.recreateDependentClients(ctx)
+}
+
+// recreateDependentClients recreates the PBM, CNS, Vslm and vSAN clients
+// which were created, on the session of vc.Client.
+func (vc *VirtualCenter) recreateDependentClients(ctx context.Context) error {
+	log := logger.GetLogger(ctx)
+	var err error
 	// Recreate PbmClient if created using timed out VC Client.
 	if vc.PbmClient != nil {
 		if vc.PbmClient, err = pbm.NewClient(ctx, vc.Client.Client); err != nil {
 			log.Errorf("failed to create pbm client with err: %v", err)
 			return err
 		}
-		vc.PbmClient.RoundTripper = &MetricRoundTripper{clientName: "pbm", roundTripper: vc.PbmClient.RoundTripper}
+		vc.PbmClient.RoundTripper = vc.newMetricRoundTripper("pbm", vc.PbmClient.RoundTripper)
 	}
 	// Recreate CNSClient if created using timed out VC Client.
 	if vc.CnsClient != nil {
@@ -437,6 +515,7 @@ func (vc *VirtualCenter) connect(ctx context.Context) error {
 				vc.Config.Host, err)
 			return err
 		}
+		vc.CnsClient.RoundTripper = vc.newMetricRoundTripper("cns", vc.CnsClient.RoundTripper)
 	}
 	// Recreate VslmClient if created using timed out VC Client.
 	if vc.VslmClient != nil {
@@ -452,11 +531,104 @@ func (vc *VirtualCenter) connect(ctx context.Context) error {
 			log.Errorf("failed to create vsan client with err: %v", err)
 			return err
 		}
-		vc.VsanClient.RoundTripper = &MetricRoundTripper{clientName: "vsan", roundTripper: vc.VsanClient.RoundTripper}
+		vc.VsanClient.RoundTripper = vc.newMetricRoundTripper("vsan", vc.VsanClient.RoundTripper)
+	}
+	return nil
+}
+
+// UpdateCredentials replaces the credentials of the virtual center. If a
+// session is established, a new session is logged in with the new
+// credentials, and the PBM, CNS, Vslm and vSAN clients are recreated on it.
+// The previous session is logged out after sessionLogoutDelay, so that the
+// calls in flight on it can complete, and the SessionChangeHandlers are
+// called to move the long running watches to the new session.
+// If the login with the new credentials fails, the current session is kept.
+func (vc *VirtualCenter) UpdateCredentials(ctx context.Context, username string, password string) error {
+	log := logger.GetLogger(ctx)
+	vc.ClientMutex.Lock()
+	if vc.Config.Username == username && vc.Config.Password == password {
+		vc.ClientMutex.Unlock()
+		return nil
+	}
+	previousUsername, previousPassword := vc.Config.Username, vc.Config.Password
+	vc.Config.Username, vc.Config.Password = username, password
+	if vc.Client == nil {
+		// The new credentials are used by the next call to Connect.
+		vc.ClientMutex.Unlock()
+		log.Infof("Updated the credentials of vCenter %q", vc.Config.Host)
+		return nil
+	}
+	useragent, err := config.GetSessionUserAgent(ctx)
+	if err != nil {
+		vc.Config.Username, vc.Config.Password = previousUsername, previousPassword
+		vc.ClientMutex.Unlock()
+		return logger.LogNewErrorf(log, "failed to get useragent for vCenter session. Err: %v", err)
 	}
+	client, err := vc.NewClient(ctx, useragent)
+	if err != nil {
+		vc.Config.Username, vc.Config.Password = previousUsername, previousPassword
+		vc.ClientMutex.Unlock()
+		return logger.LogNewErrorf(log, "failed to login to vCenter %q with the new credentials, "+
+			"keeping the current session. Err: %v", vc.Config.Host, err)
+	}
+	previousClient := vc.Client
+	vc.Client = client
+	err = vc.recreateDependentClients(ctx)
+	handlers := append([]SessionChangeHandler(nil), vc.sessionChangeHandlers...)
+	vc.ClientMutex.Unlock()
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to recreate the clients of vCenter %q on the new session. Err: %v",
+			vc.Config.Host, err)
+	}
+	log.Infof("Logged in to vCenter %q with the new credentials", vc.Config.Host)
+
+	for _, handler := range handlers {
+		handler(ctx, vc)
+	}
+
+	time.AfterFunc(sessionLogoutDelay, func() {
+		logoutCtx := logger.NewContextWithLogger(context.Background())
+		if err := previousClient.Logout(logoutCtx); err != nil {
+			logger.GetLogger(logoutCtx).Warnf("failed to logout the previous session of vCenter %q. Err: %v",
+				vc.Config.Host, err)
+		}
+	})
 	return nil
 }
 
+// GetUsername returns the user of the virtual center session, which is the
+// user of the token if a credential provider is configured.
+func (vc *VirtualCenter) GetUsername() string {
+	if vc.Config.CredentialProvider != nil {
+		return vc.sessionUsername
+	}
+	return vc.Config.Username
+}
+
+// AddSessionChangeHandler registers a handler called after the session of the
+// virtual center is replaced by UpdateCredentials.
+func (vc *VirtualCenter) AddSessionChangeHandler(handler SessionChangeHandler) {
+	vc.ClientMutex.Lock()
+	defer vc.ClientMutex.Unlock()
+	vc.sessionChangeHandlers = append(vc.sessionChangeHandlers, handler)
+}
+
+// UpdateVirtualCenterCredentials updates the credentials of the vCenter host
+// registered with the virtual center manager. It is a no-op if the vCenter
+// is not registered yet, as its credentials are then read on registration.
+func UpdateVirtualCenterCredentials(ctx context.Context, host string, username string, password string) error {
+	log := logger.GetLogger(ctx)
+	vc, err := GetVirtualCenterManager(ctx).GetVirtualCenter(ctx, host)
+	if err != nil {
+		if errors.Is(err, ErrVCNotFound) {
+			log.Infof("vCenter %q is not registered yet. Its credentials are read on registration.", host)
+			return nil
+		}
+		return err
+	}
+	return vc.UpdateCredentials(ctx, username, password)
+}
+
 // ReadVCConfigs will ensure we are always reading the latest config
 // before attempting to create a new govmomi client.
 // It works in case of both vanilla (including multi-vc) and wcp
@@ -549,6 +721,8 @@ func (vc *VirtualCenter) GetDatacenters(ctx context.Context) ([]*Datacenter, err
 // Disconnect disconnects the virtual center host connection if connected.
 func (vc *VirtualCenter) Disconnect(ctx context.Context) error {
 	log := logger.GetLogger(ctx)
+	vc.ClientMutex.Lock()
+	defer vc.ClientMutex.Unlock()
 	if vc.Client == nil {
 		log.Info("Client wasn't connected, ignoring")
 		return nil
@@ -851,8 +1025,17 @@ func (vc *VirtualCenter) GetAllVirtualMachines(ctx context.Context,
 func (mrt *MetricRoundTripper) RoundTrip(ctx context.Context, req, resp soap.HasFault) error {
 	vreq := reflect.ValueOf(req).Elem().FieldByName("Req").Elem()
 	requestName := vreq.Type().Name()
+	ctx, span := tracing.StartClientSpan(ctx, mrt.clientName+"."+requestName,
+		attribute.String("vsphere.client", mrt.clientName), attribute.String("vsphere.method", requestName))
+	if mrt.rateLimiter != nil {
+		if err := mrt.rateLimiter.Wait(ctx, GetRequestPriority(ctx)); err != nil {
+			tracing.EndSpan(span, err)
+			return err
+		}
+	}
 	requestTime := time.Now()
 	err := mrt.roundTripper.RoundTrip(ctx, req, resp)
+	tracing.EndSpan(span, err)
 	if err != nil {
 		timeTaken := time.Since(requestTime).Seconds()
 		prometheus.RequestOpsMetric.WithLabelValues(requestName, mrt.clientName, statusFailUnknown).Observe(timeTaken)
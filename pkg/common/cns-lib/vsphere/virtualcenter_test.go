@@ -0,0 +1,118 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+
+	pbmsim "github.com/vmware/govmomi/pbm/simulator"
+	"github.com/vmware/govmomi/simulator"
+)
+
+func TestUpdateCredentials(t *testing.T) {
+	ctx := context.Background()
+	model := simulator.VPX()
+	defer model.Remove()
+	if err := model.Create(); err != nil {
+		t.Fatal(err)
+	}
+	model.Service.TLS = new(tls.Config)
+	s := model.Service.NewServer()
+	defer s.Close()
+	// PBM Service simulator.
+	model.Service.RegisterSDK(pbmsim.New())
+
+	// Write values to test_vsphere.conf, read for the useragent of the sessions.
+	os.Setenv("VSPHERE_CSI_CONFIG", "test_vsphere.conf")
+	conf := []byte(fmt.Sprintf("[Global]\ninsecure-flag = \"true\"\ncluster-id = \"test-cluster\"\n"+
+		"[VirtualCenter \"%s\"]\nuser = \"user@vsphere.local\"\npassword = \"pass\"\nport = \"%s\"",
+		s.URL.Hostname(), s.URL.Port()))
+	if err := os.WriteFile("test_vsphere.conf", conf, 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.Unsetenv("VSPHERE_CSI_CONFIG")
+		os.Remove("test_vsphere.conf")
+	}()
+
+	port, err := strconv.Atoi(s.URL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	vc := &VirtualCenter{
+		Config: &VirtualCenterConfig{
+			Host:     s.URL.Hostname(),
+			Port:     port,
+			Username: "user@vsphere.local",
+			Password: "pass",
+			Insecure: true,
+		},
+		ClientMutex: &sync.Mutex{},
+	}
+	if err = vc.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = vc.ConnectPbm(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var handlerCalls int
+	vc.AddSessionChangeHandler(func(ctx context.Context, changed *VirtualCenter) {
+		if changed != vc {
+			t.Errorf("handler called with another VirtualCenter")
+		}
+		handlerCalls++
+	})
+	sessionLogoutDelay = 0
+
+	// The current session is kept if the login with the new credentials fails.
+	client, pbmClient := vc.Client, vc.PbmClient
+	if err = vc.UpdateCredentials(ctx, "user@vsphere.local", ""); err == nil {
+		t.Fatal("expected the login with an empty password to fail")
+	}
+	if vc.Client != client || vc.Config.Password != "pass" || handlerCalls != 0 {
+		t.Fatal("expected the current session and credentials to be kept")
+	}
+
+	// Unchanged credentials do not replace the session.
+	if err = vc.UpdateCredentials(ctx, "user@vsphere.local", "pass"); err != nil {
+		t.Fatal(err)
+	}
+	if vc.Client != client || handlerCalls != 0 {
+		t.Fatal("expected the session to be kept for unchanged credentials")
+	}
+
+	// New credentials replace the session and the dependent clients.
+	if err = vc.UpdateCredentials(ctx, "user@vsphere.local", "new-pass"); err != nil {
+		t.Fatal(err)
+	}
+	if vc.Client == client || vc.PbmClient == pbmClient {
+		t.Fatal("expected the clients to be recreated on a new session")
+	}
+	if vc.Config.Password != "new-pass" || handlerCalls != 1 {
+		t.Fatalf("expected the credentials to be updated and the handler to be called once, got %d calls",
+			handlerCalls)
+	}
+	if vc.CnsClient != nil || vc.VslmClient != nil || vc.VsanClient != nil {
+		t.Fatal("expected the clients which were not created to stay nil")
+	}
+}
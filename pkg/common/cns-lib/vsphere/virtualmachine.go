@@ -437,3 +437,56 @@ func findMissingCategories(topologyCategories map[string]string) []string {
 	}
 	return missing
 }
+
+// AttachableDiskLimits holds the number of disks which can be attached to
+// each controller of a virtual machine.
+type AttachableDiskLimits struct {
+	// PVSCSI is the number of disks per PVSCSI controller.
+	PVSCSI int64
+	// NVMe is the number of disks per NVMe controller.
+	NVMe int64
+}
+
+// GetAttachableVolumeCount returns the number of First Class Disks which can
+// be attached to the virtual machine. It is the number of units of its PVSCSI
+// and NVMe controllers, minus the units used by other devices. The units used
+// by First Class Disks are counted, as these disks are volumes.
+func (vm *VirtualMachine) GetAttachableVolumeCount(ctx context.Context, limits AttachableDiskLimits) (
+	int64, error) {
+	log := logger.GetLogger(ctx)
+	devices, err := vm.Device(ctx)
+	if err != nil {
+		return 0, logger.LogNewErrorf(log, "failed to get devices of VM %v. Error: %v", vm, err)
+	}
+	count := getAttachableVolumeCount(devices, limits)
+	log.Debugf("VM %v can attach %d volumes", vm, count)
+	return count, nil
+}
+
+// getAttachableVolumeCount returns the number of First Class Disks which can
+// be attached to the controllers in the given devices.
+func getAttachableVolumeCount(devices object.VirtualDeviceList, limits AttachableDiskLimits) int64 {
+	controllerLimits := make(map[int32]int64)
+	for _, device := range devices {
+		switch controller := device.(type) {
+		case *types.ParaVirtualSCSIController:
+			controllerLimits[controller.Key] = limits.PVSCSI
+		case *types.VirtualNVMEController:
+			controllerLimits[controller.Key] = limits.NVMe
+		}
+	}
+	var count int64
+	for _, limit := range controllerLimits {
+		count += limit
+	}
+	for _, device := range devices {
+		if _, ok := controllerLimits[device.GetVirtualDevice().ControllerKey]; !ok {
+			continue
+		}
+		if disk, ok := device.(*types.VirtualDisk); ok && disk.VDiskId != nil {
+			continue
+		}
+		count--
+	}
+	return max(count, 0)
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/vmware/govmomi/vapi/rest"
 	_ "github.com/vmware/govmomi/vapi/simulator"
 	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
 var (
@@ -180,3 +181,49 @@ func TestIsInZoneRegion(t *testing.T) {
 		t.Fatalf("VM should belong to specified zone and region")
 	}
 }
+
+// TestGetAttachableVolumeCount checks that the units used by devices other
+// than First Class Disks are not counted.
+func TestGetAttachableVolumeCount(t *testing.T) {
+	pvscsi := &types.ParaVirtualSCSIController{}
+	pvscsi.Key = 1000
+	nvme := &types.VirtualNVMEController{}
+	nvme.Key = 31000
+	lsiLogic := &types.VirtualLsiLogicController{}
+	lsiLogic.Key = 1001
+	osDisk := &types.VirtualDisk{}
+	osDisk.ControllerKey = pvscsi.Key
+	volume := &types.VirtualDisk{VDiskId: &types.ID{Id: "volume-1"}}
+	volume.ControllerKey = nvme.Key
+	otherDisk := &types.VirtualDisk{}
+	otherDisk.ControllerKey = lsiLogic.Key
+	limits := AttachableDiskLimits{PVSCSI: 15, NVMe: 15}
+
+	for _, test := range []struct {
+		name    string
+		devices object.VirtualDeviceList
+		want    int64
+	}{
+		{
+			name:    "no controller",
+			devices: object.VirtualDeviceList{lsiLogic, otherDisk},
+			want:    0,
+		},
+		{
+			name:    "PVSCSI controller with an OS disk",
+			devices: object.VirtualDeviceList{pvscsi, osDisk, lsiLogic, otherDisk},
+			want:    14,
+		},
+		{
+			name:    "PVSCSI and NVMe controllers with a volume",
+			devices: object.VirtualDeviceList{pvscsi, osDisk, nvme, volume},
+			want:    29,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := getAttachableVolumeCount(test.devices, limits); got != test.want {
+				t.Errorf("getAttachableVolumeCount() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -31,11 +31,15 @@ func (vc *VirtualCenter) ConnectVsan(ctx context.Context) error {
 		log.Errorf("failed to connect to Virtual Center %q with err: %v", vc.Config.Host, err)
 		return err
 	}
+	// UpdateCredentials replaces the clients under ClientMutex.
+	vc.ClientMutex.Lock()
+	defer vc.ClientMutex.Unlock()
 	if vc.VsanClient == nil {
 		if vc.VsanClient, err = vsan.NewClient(ctx, vc.Client.Client); err != nil {
 			log.Errorf("failed to create vsan client with err: %v", err)
 			return err
 		}
+		vc.VsanClient.RoundTripper = vc.newMetricRoundTripper("vsan", vc.VsanClient.RoundTripper)
 	}
 	return nil
 }
@@ -43,6 +47,8 @@ func (vc *VirtualCenter) ConnectVsan(ctx context.Context) error {
 // DisconnectVsan destroys the VSAN client for the virtual center.
 func (vc *VirtualCenter) DisconnectVsan(ctx context.Context) error {
 	log := logger.GetLogger(ctx)
+	vc.ClientMutex.Lock()
+	defer vc.ClientMutex.Unlock()
 	if vc.VsanClient == nil {
 		log.Debug("VsanClient wasn't connected, ignoring disconnect request")
 	} else {
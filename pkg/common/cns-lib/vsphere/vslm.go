@@ -43,6 +43,9 @@ func (vc *VirtualCenter) ConnectVslm(ctx context.Context) error {
 		log.Errorf("failed to connect to Virtual Center host %q with err: %v", vc.Config.Host, err)
 		return err
 	}
+	// UpdateCredentials replaces the clients under ClientMutex.
+	vc.ClientMutex.Lock()
+	defer vc.ClientMutex.Unlock()
 	if vc.VslmClient == nil {
 		if vc.VslmClient, err = NewVslmClient(ctx, vc.Client.Client); err != nil {
 			log.Errorf("failed to create Vslm client on vCenter host %q with err: %v", vc.Config.Host, err)
@@ -55,9 +58,28 @@ func (vc *VirtualCenter) ConnectVslm(ctx context.Context) error {
 // DisconnectVslm destroys the Vslm client for the virtual center.
 func (vc *VirtualCenter) DisconnectVslm(ctx context.Context) {
 	log := logger.GetLogger(ctx)
+	vc.ClientMutex.Lock()
+	defer vc.ClientMutex.Unlock()
 	if vc.VslmClient == nil {
 		log.Info("VslmClient wasn't connected, ignoring")
 	} else {
 		vc.VslmClient = nil
 	}
 }
+
+// ListVStorageObjectsAttachedToTag returns the IDs of the First Class Disks
+// with the given tag.
+func (vc *VirtualCenter) ListVStorageObjectsAttachedToTag(ctx context.Context, category string,
+	tag string) ([]string, error) {
+	log := logger.GetLogger(ctx)
+	ids, err := vslm.NewObjectManager(vc.Client.Client).ListAttachedObjects(ctx, category, tag)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to list the First Class Disks with tag %q of category %q. "+
+			"Error: %+v", tag, category, err)
+	}
+	volumeIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		volumeIDs = append(volumeIDs, id.Id)
+	}
+	return volumeIDs, nil
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"reflect"
 	"regexp"
@@ -86,8 +87,51 @@ const (
 	// interval after which stale CnsVSphereVolumeMigration CRs will be cleaned up.
 	// Current default value is set to 15 minutes.
 	DefaultCnsVolumeOperationRequestCleanupIntervalInMin = 15
+	// CnsVolumeOperationRequestStoreCRD is the CnsVolumeOperationRequest store
+	// persisting every operation in a CnsVolumeOperationRequest CR.
+	CnsVolumeOperationRequestStoreCRD = "crd"
+	// CnsVolumeOperationRequestStoreConfigMap is the CnsVolumeOperationRequest
+	// store persisting the operations in a fixed set of ConfigMaps.
+	CnsVolumeOperationRequestStoreConfigMap = "configmap"
+	// DefaultCnsVolumeOperationRequestStoreShards is the default number of
+	// ConfigMaps used by the "configmap" CnsVolumeOperationRequest store.
+	DefaultCnsVolumeOperationRequestStoreShards = 16
+	// FullSyncModeComplete is the full sync mode reconciling every volume on
+	// each cycle.
+	FullSyncModeComplete = "complete"
+	// FullSyncModeIncremental is the full sync mode reconciling the volumes
+	// changed since the previous cycle.
+	FullSyncModeIncremental = "incremental"
+	// DefaultCompleteFullSyncIntervalInMin is the default interval after which
+	// the incremental full sync reconciles every volume again.
+	DefaultCompleteFullSyncIntervalInMin = 1440
 	// DefaultGlobalMaxSnapshotsPerBlockVolume is the default maximum number of block volume snapshots per volume.
 	DefaultGlobalMaxSnapshotsPerBlockVolume = 3
+	// DefaultTracingSamplingRatio is the default ratio of the traces started by
+	// the driver which are sampled.
+	DefaultTracingSamplingRatio = 1.0
+	// DriftRemediationPolicyReport is the drift remediation policy reporting
+	// the drift of a category without correcting it.
+	DriftRemediationPolicyReport = "report"
+	// DriftRemediationPolicyRemediate is the drift remediation policy
+	// correcting the drift of a category.
+	DriftRemediationPolicyRemediate = "remediate"
+	// DefaultOrphanGCIntervalInMin is the default interval between the passes
+	// of the orphan garbage collector.
+	DefaultOrphanGCIntervalInMin = 60
+	// DefaultOrphanGCGracePeriodInMin is the default time during which an
+	// orphaned CNS object is kept after it was first found.
+	DefaultOrphanGCGracePeriodInMin = 1440
+	// DefaultFileVolumeACLResyncIntervalInMin is the default interval between
+	// the reconciles of the ACL of all the file volumes.
+	DefaultFileVolumeACLResyncIntervalInMin = 10
+	// DefaultFileVolumeACLGracePeriodInMin is the default time during which
+	// the IP of a node is kept in the ACL of a file volume it no longer
+	// consumes.
+	DefaultFileVolumeACLGracePeriodInMin = 5
+	// DefaultAPIMaxWaitSeconds is the default maximum time a vCenter API call
+	// waits for the rate limiter of the vCenter.
+	DefaultAPIMaxWaitSeconds = 60
 	// MaxNumberOfTopologyCategories is the max number of topology domains/categories allowed.
 	MaxNumberOfTopologyCategories = 5
 	// TopologyLabelsDomain is the domain name used to identify user-defined
@@ -125,6 +169,10 @@ var (
 	// ErrPasswordMissing is returned when the provided password is empty.
 	ErrPasswordMissing = errors.New("password is missing")
 
+	// ErrMultipleTokenFiles is returned when both a SAML token file and a
+	// service account token file are configured for a vCenter.
+	ErrMultipleTokenFiles = errors.New("saml-token-file and service-account-token-file are mutually exclusive")
+
 	// ErrInvalidVCenterIP is returned when the provided vCenter IP address is
 	// missing from the provided configuration.
 	ErrInvalidVCenterIP = errors.New("vsphere.conf does not have the VirtualCenter IP address specified")
@@ -384,9 +432,38 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 			return ErrInvalidVCenterIP
 		}
 
+		if vcConfig.SecretName == "" {
+			vcConfig.SecretName = cfg.Global.SecretName
+		}
+		if vcConfig.SecretName != "" {
+			// The credentials are read from the Secret by the credentials watcher,
+			// which may not have resolved them yet.
+			if vcConfig.SecretNamespace == "" {
+				vcConfig.SecretNamespace = cfg.Global.SecretNamespace
+			}
+			if vcConfig.SecretNamespace == "" {
+				vcConfig.SecretNamespace = GetCSINamespace()
+			}
+			if user, password, ok := GetVCenterCredentials(vcServer); ok {
+				vcConfig.User = user
+				vcConfig.Password = password
+			}
+		}
+
+		if vcConfig.SAMLTokenFile == "" && vcConfig.ServiceAccountTokenFile == "" {
+			vcConfig.SAMLTokenFile = cfg.Global.SAMLTokenFile
+			vcConfig.ServiceAccountTokenFile = cfg.Global.ServiceAccountTokenFile
+		}
+		if vcConfig.SAMLTokenFile != "" && vcConfig.ServiceAccountTokenFile != "" {
+			log.Errorf("saml-token-file and service-account-token-file are both set for vc %s", vcServer)
+			return ErrMultipleTokenFiles
+		}
+		// A token provider logs in to vCenter instead of the user and password.
+		usesToken := vcConfig.SAMLTokenFile != "" || vcConfig.ServiceAccountTokenFile != ""
+
 		if vcConfig.User == "" {
 			vcConfig.User = cfg.Global.User
-			if vcConfig.User == "" {
+			if vcConfig.User == "" && vcConfig.SecretName == "" && !usesToken {
 				log.Errorf("vcConfig.User is empty for vc %s!", vcServer)
 				return ErrUsernameMissing
 			}
@@ -394,7 +471,7 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 
 		// vCenter server username provided in vSphere config secret should contain domain name,
 		// CSI driver will crash if username doesn't contain domain name.
-		if !isValidvCenterUsernameWithDomain(vcConfig.User) {
+		if vcConfig.User != "" && !isValidvCenterUsernameWithDomain(vcConfig.User) {
 			log.Errorf("username %v specified in vSphere config secret is invalid, "+
 				"make sure that username is a fully qualified domain name.", vcConfig.User)
 			return ErrInvalidUsername
@@ -402,7 +479,7 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 
 		if vcConfig.Password == "" {
 			vcConfig.Password = cfg.Global.Password
-			if vcConfig.Password == "" {
+			if vcConfig.Password == "" && vcConfig.SecretName == "" && !usesToken {
 				log.Errorf("vcConfig.Password is empty for vc %s!", vcServer)
 				return ErrPasswordMissing
 			}
@@ -419,6 +496,9 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 		if !insecure {
 			vcConfig.InsecureFlag = cfg.Global.InsecureFlag
 		}
+		if err := validateAPIRateLimit(ctx, vcServer, vcConfig); err != nil {
+			return err
+		}
 		if setCfgGlobalvCenter && cfg.Global.VCenterIP == "" {
 			cfg.Global.VCenterIP = vcServer
 		}
@@ -438,16 +518,9 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 		}
 	} else {
 		for key, netPerm := range cfg.NetPermissions {
-			if netPerm.Permissions == "" {
-				netPerm.Permissions = vsanfstypes.VsanFileShareAccessTypeREAD_WRITE
-			} else if netPerm.Permissions != vsanfstypes.VsanFileShareAccessTypeNO_ACCESS &&
-				netPerm.Permissions != vsanfstypes.VsanFileShareAccessTypeREAD_ONLY &&
-				netPerm.Permissions != vsanfstypes.VsanFileShareAccessTypeREAD_WRITE {
+			if err := validateNetPermission(netPerm); err != nil {
 				log.Errorf("Invalid value %s for Permissions under NetPermission Config %s", netPerm.Permissions, key)
-				return ErrInvalidNetPermission
-			}
-			if netPerm.Ips == "" {
-				netPerm.Ips = "*"
+				return err
 			}
 		}
 	}
@@ -471,9 +544,52 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 		cfg.Global.CnsVolumeOperationRequestCleanupIntervalInMin =
 			DefaultCnsVolumeOperationRequestCleanupIntervalInMin
 	}
+	switch cfg.Global.CnsVolumeOperationRequestStore {
+	case "":
+		cfg.Global.CnsVolumeOperationRequestStore = CnsVolumeOperationRequestStoreCRD
+	case CnsVolumeOperationRequestStoreCRD, CnsVolumeOperationRequestStoreConfigMap:
+	default:
+		return logger.LogNewErrorf(log, "invalid cnsvolumeoperationrequest-store %q. Supported values are %q and %q",
+			cfg.Global.CnsVolumeOperationRequestStore, CnsVolumeOperationRequestStoreCRD,
+			CnsVolumeOperationRequestStoreConfigMap)
+	}
+	if cfg.Global.CnsVolumeOperationRequestStoreShards < 0 {
+		return logger.LogNewErrorf(log, "invalid cnsvolumeoperationrequest-store-shards %d",
+			cfg.Global.CnsVolumeOperationRequestStoreShards)
+	}
+	if cfg.Global.CnsVolumeOperationRequestStoreShards == 0 {
+		cfg.Global.CnsVolumeOperationRequestStoreShards = DefaultCnsVolumeOperationRequestStoreShards
+	}
+	switch cfg.Global.FullSyncMode {
+	case "":
+		cfg.Global.FullSyncMode = FullSyncModeComplete
+	case FullSyncModeComplete, FullSyncModeIncremental:
+	default:
+		return logger.LogNewErrorf(log, "invalid full-sync-mode %q. Supported values are %q and %q",
+			cfg.Global.FullSyncMode, FullSyncModeComplete, FullSyncModeIncremental)
+	}
+	if cfg.Global.CompleteFullSyncIntervalInMin < 0 {
+		return logger.LogNewErrorf(log, "invalid complete-full-sync-intervalinmin %d",
+			cfg.Global.CompleteFullSyncIntervalInMin)
+	}
+	if cfg.Global.CompleteFullSyncIntervalInMin == 0 {
+		cfg.Global.CompleteFullSyncIntervalInMin = DefaultCompleteFullSyncIntervalInMin
+	}
 	if cfg.Snapshot.GlobalMaxSnapshotsPerBlockVolume == 0 {
 		cfg.Snapshot.GlobalMaxSnapshotsPerBlockVolume = DefaultGlobalMaxSnapshotsPerBlockVolume
 	}
+	if err := validateTracingConfig(ctx, cfg); err != nil {
+		return err
+	}
+	if err := validateDriftReportConfig(ctx, cfg); err != nil {
+		return err
+	}
+	if err := validateOrphanGCConfig(ctx, cfg); err != nil {
+		return err
+	}
+	if err := validateFileVolumeACLConfig(ctx, cfg); err != nil {
+		return err
+	}
 
 	// Labels section validation - the customer can either provide topology
 	// domain info using zone,region parameters or by using the topologyCategories
@@ -673,6 +789,108 @@ func validateGCConfig(ctx context.Context, cfg *Config) error {
 	if cfg.GC.ClusterKind == "" {
 		cfg.GC.ClusterKind = TKCKind
 	}
+	if cfg.GC.SecretName != "" && cfg.GC.SecretNamespace == "" {
+		cfg.GC.SecretNamespace = GetCSINamespace()
+	}
+	return validateTracingConfig(ctx, cfg)
+}
+
+// validateTracingConfig validates the Tracing section of the config, and sets
+// its default values.
+func validateTracingConfig(ctx context.Context, cfg *Config) error {
+	log := logger.GetLogger(ctx)
+	if cfg.Tracing.SamplingRatio < 0 || cfg.Tracing.SamplingRatio > 1 {
+		return logger.LogNewErrorf(log, "invalid sampling-ratio %v, expecting a value between 0 and 1",
+			cfg.Tracing.SamplingRatio)
+	}
+	if cfg.Tracing.SamplingRatio == 0 {
+		cfg.Tracing.SamplingRatio = DefaultTracingSamplingRatio
+	}
+	return nil
+}
+
+// validateDriftReportConfig validates the remediation policies of the
+// DriftReport section of the config, and sets their default values.
+func validateDriftReportConfig(ctx context.Context, cfg *Config) error {
+	log := logger.GetLogger(ctx)
+	policies := []struct {
+		name          string
+		policy        *string
+		defaultPolicy string
+	}{
+		{"orphaned-volume", &cfg.DriftReport.OrphanedVolume, DriftRemediationPolicyRemediate},
+		{"pv-without-volume", &cfg.DriftReport.PVWithoutVolume, DriftRemediationPolicyRemediate},
+		{"metadata-mismatch", &cfg.DriftReport.MetadataMismatch, DriftRemediationPolicyRemediate},
+		{"stale-volume-operation-request", &cfg.DriftReport.StaleVolumeOperationRequest,
+			DriftRemediationPolicyReport},
+		{"stale-volume-attachment", &cfg.DriftReport.StaleVolumeAttachment, DriftRemediationPolicyReport},
+	}
+	for _, p := range policies {
+		switch *p.policy {
+		case "":
+			*p.policy = p.defaultPolicy
+		case DriftRemediationPolicyReport, DriftRemediationPolicyRemediate:
+		default:
+			return logger.LogNewErrorf(log, "invalid %s drift remediation policy %q. Supported values are %q and %q",
+				p.name, *p.policy, DriftRemediationPolicyReport, DriftRemediationPolicyRemediate)
+		}
+	}
+	return nil
+}
+
+// validateOrphanGCConfig validates the OrphanGC section of the config, and
+// sets its default values.
+func validateOrphanGCConfig(ctx context.Context, cfg *Config) error {
+	log := logger.GetLogger(ctx)
+	if cfg.OrphanGC.IntervalInMin < 0 || cfg.OrphanGC.GracePeriodInMin < 0 {
+		return logger.LogNewErrorf(log, "invalid orphan GC interval-minutes %d or grace-period-minutes %d, "+
+			"expecting non-negative values", cfg.OrphanGC.IntervalInMin, cfg.OrphanGC.GracePeriodInMin)
+	}
+	if cfg.OrphanGC.IntervalInMin == 0 {
+		cfg.OrphanGC.IntervalInMin = DefaultOrphanGCIntervalInMin
+	}
+	if cfg.OrphanGC.GracePeriodInMin == 0 {
+		cfg.OrphanGC.GracePeriodInMin = DefaultOrphanGCGracePeriodInMin
+	}
+	return nil
+}
+
+// validateFileVolumeACLConfig validates the FileVolumeACL section of the
+// config, and sets its default values.
+func validateFileVolumeACLConfig(ctx context.Context, cfg *Config) error {
+	log := logger.GetLogger(ctx)
+	if cfg.FileVolumeACL.ResyncIntervalInMin < 0 || cfg.FileVolumeACL.GracePeriodInMin < 0 {
+		return logger.LogNewErrorf(log, "invalid file volume ACL resync-interval-minutes %d or "+
+			"grace-period-minutes %d, expecting non-negative values",
+			cfg.FileVolumeACL.ResyncIntervalInMin, cfg.FileVolumeACL.GracePeriodInMin)
+	}
+	if cfg.FileVolumeACL.ResyncIntervalInMin == 0 {
+		cfg.FileVolumeACL.ResyncIntervalInMin = DefaultFileVolumeACLResyncIntervalInMin
+	}
+	if cfg.FileVolumeACL.GracePeriodInMin == 0 {
+		cfg.FileVolumeACL.GracePeriodInMin = DefaultFileVolumeACLGracePeriodInMin
+	}
+	return nil
+}
+
+// validateAPIRateLimit validates the vCenter API rate limit of vcConfig, and
+// sets the default burst and maximum wait if the rate is limited.
+func validateAPIRateLimit(ctx context.Context, vcServer string, vcConfig *VirtualCenterConfig) error {
+	log := logger.GetLogger(ctx)
+	if vcConfig.APIRateLimit < 0 || vcConfig.APIBurst < 0 || vcConfig.APIMaxWaitSeconds < 0 {
+		return logger.LogNewErrorf(log, "invalid api-rate-limit %v, api-burst %d or api-max-wait-seconds %d "+
+			"for vc %s, expecting non-negative values", vcConfig.APIRateLimit, vcConfig.APIBurst,
+			vcConfig.APIMaxWaitSeconds, vcServer)
+	}
+	if vcConfig.APIRateLimit == 0 {
+		return nil
+	}
+	if vcConfig.APIBurst == 0 {
+		vcConfig.APIBurst = int(math.Ceil(vcConfig.APIRateLimit))
+	}
+	if vcConfig.APIMaxWaitSeconds == 0 {
+		vcConfig.APIMaxWaitSeconds = DefaultAPIMaxWaitSeconds
+	}
 	return nil
 }
 
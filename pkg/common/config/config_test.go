@@ -18,7 +18,9 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"reflect"
 	"strings"
@@ -266,6 +268,271 @@ func TestSnapshotConfigWhenMaxSpecifiedAsEnv(t *testing.T) {
 	}
 }
 
+func TestCnsVolumeOperationRequestStoreConfig(t *testing.T) {
+	cfg := &Config{
+		VirtualCenter: idealVCConfig,
+	}
+	if err := validateConfig(ctx, cfg); err != nil {
+		t.Fatalf("Unexpected error during config validation: %v", err)
+	}
+	if cfg.Global.CnsVolumeOperationRequestStore != CnsVolumeOperationRequestStoreCRD ||
+		cfg.Global.CnsVolumeOperationRequestStoreShards != DefaultCnsVolumeOperationRequestStoreShards {
+		t.Errorf("Unexpected default CnsVolumeOperationRequest store %q with %d shards",
+			cfg.Global.CnsVolumeOperationRequestStore, cfg.Global.CnsVolumeOperationRequestStoreShards)
+	}
+
+	cfg = &Config{
+		VirtualCenter: idealVCConfig,
+	}
+	cfg.Global.CnsVolumeOperationRequestStore = "etcd"
+	if err := validateConfig(ctx, cfg); err == nil {
+		t.Errorf("Expected an error for an unknown CnsVolumeOperationRequest store")
+	}
+}
+
+func TestFullSyncModeConfig(t *testing.T) {
+	cfg := &Config{
+		VirtualCenter: idealVCConfig,
+	}
+	if err := validateConfig(ctx, cfg); err != nil {
+		t.Fatalf("Unexpected error during config validation: %v", err)
+	}
+	if cfg.Global.FullSyncMode != FullSyncModeComplete ||
+		cfg.Global.CompleteFullSyncIntervalInMin != DefaultCompleteFullSyncIntervalInMin {
+		t.Errorf("Unexpected default full sync mode %q with complete full sync interval %d",
+			cfg.Global.FullSyncMode, cfg.Global.CompleteFullSyncIntervalInMin)
+	}
+
+	cfg = &Config{
+		VirtualCenter: idealVCConfig,
+	}
+	cfg.Global.FullSyncMode = "partial"
+	if err := validateConfig(ctx, cfg); err == nil {
+		t.Errorf("Expected an error for an unknown full sync mode")
+	}
+
+	cfg = &Config{
+		VirtualCenter: idealVCConfig,
+	}
+	cfg.Global.FullSyncMode = FullSyncModeIncremental
+	cfg.Global.CompleteFullSyncIntervalInMin = -1
+	if err := validateConfig(ctx, cfg); err == nil {
+		t.Errorf("Expected an error for a negative complete full sync interval")
+	}
+}
+
+func TestValidateDriftReportConfig(t *testing.T) {
+	cfg := &Config{}
+	cfg.DriftReport.MetadataMismatch = DriftRemediationPolicyReport
+	if err := validateDriftReportConfig(ctx, cfg); err != nil {
+		t.Fatalf("Unexpected error during drift report validation: %v", err)
+	}
+	if cfg.DriftReport.OrphanedVolume != DriftRemediationPolicyRemediate ||
+		cfg.DriftReport.MetadataMismatch != DriftRemediationPolicyReport ||
+		cfg.DriftReport.StaleVolumeAttachment != DriftRemediationPolicyReport {
+		t.Errorf("Unexpected drift remediation policies %+v", cfg.DriftReport)
+	}
+	cfg.DriftReport.StaleVolumeOperationRequest = "delete"
+	if err := validateDriftReportConfig(ctx, cfg); err == nil {
+		t.Error("Expected an error for an unknown drift remediation policy")
+	}
+}
+
+func TestValidateOrphanGCConfig(t *testing.T) {
+	cfg := &Config{}
+	cfg.OrphanGC.GracePeriodInMin = 120
+	if err := validateOrphanGCConfig(ctx, cfg); err != nil {
+		t.Fatalf("Unexpected error during orphan GC validation: %v", err)
+	}
+	if cfg.OrphanGC.IntervalInMin != DefaultOrphanGCIntervalInMin || cfg.OrphanGC.GracePeriodInMin != 120 {
+		t.Errorf("Unexpected orphan GC config %+v", cfg.OrphanGC)
+	}
+	cfg.OrphanGC.IntervalInMin = -1
+	if err := validateOrphanGCConfig(ctx, cfg); err == nil {
+		t.Error("Expected an error for a negative orphan GC interval")
+	}
+}
+
+func TestParseNetPermissions(t *testing.T) {
+	configured := map[string]*NetPermissionConfig{
+		"A": {Ips: "10.0.0.0/24", Permissions: "READ_WRITE"},
+		"B": {Ips: "*", Permissions: "READ_ONLY", RootSquash: true},
+	}
+	netPerms, err := ParseNetPermissions("A, B", configured)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing net permissions: %v", err)
+	}
+	if len(netPerms) != 2 || !reflect.DeepEqual(*netPerms[1], *configured["B"]) {
+		t.Errorf("Unexpected net permissions %+v", netPerms)
+	}
+	netPerms[0].Ips = "*"
+	if configured["A"].Ips != "10.0.0.0/24" {
+		t.Error("The configured net permissions must not be modified")
+	}
+
+	netPerms, err = ParseNetPermissions(`[{"ips": "10.0.0.1-10.0.0.9", "rootsquash": true}]`, configured)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing net permissions: %v", err)
+	}
+	expected := NetPermissionConfig{Ips: "10.0.0.1-10.0.0.9", Permissions: "READ_WRITE", RootSquash: true}
+	if len(netPerms) != 1 || !reflect.DeepEqual(*netPerms[0], expected) {
+		t.Errorf("Unexpected net permissions %+v", netPerms)
+	}
+
+	for _, value := range []string{"", "C", "A,", "[]", "[null]", `[{"ips": "10.0.0.0/33"}]`,
+		`[{"permissions": "WRITE_ONLY"}]`, `[{"ips": "*"`} {
+		if _, err := ParseNetPermissions(value, configured); err == nil {
+			t.Errorf("Expected an error parsing net permissions %q", value)
+		}
+	}
+}
+
+func TestNetPermissionIpsContain(t *testing.T) {
+	for _, test := range []struct {
+		ips      string
+		ip       string
+		expected bool
+	}{
+		{"*", "10.0.0.1", true},
+		{"10.0.0.1", "10.0.0.1", true},
+		{"10.0.0.1", "10.0.0.2", false},
+		{"10.0.0.0/24", "10.0.0.200", true},
+		{"10.0.0.0/24", "10.0.1.1", false},
+		{"10.0.0.10-10.0.0.20", "10.0.0.15", true},
+		{"10.0.0.10-10.0.0.20", "10.0.0.21", false},
+		{"invalid", "10.0.0.1", false},
+	} {
+		if NetPermissionIpsContain(test.ips, net.ParseIP(test.ip)) != test.expected {
+			t.Errorf("Expected NetPermissionIpsContain(%q, %q) to be %v", test.ips, test.ip, test.expected)
+		}
+	}
+}
+
+func TestValidateConfigWithCredentialsSecret(t *testing.T) {
+	cfg := &Config{
+		VirtualCenter: map[string]*VirtualCenterConfig{
+			"2.2.2.2": {
+				VCenterPort:  "443",
+				Datacenters:  "dc1",
+				InsecureFlag: true,
+			},
+		},
+	}
+	cfg.Global.SecretName = "vcenter-credentials"
+	// The credentials are not resolved from the Secret yet.
+	if err := validateConfig(ctx, cfg); err != nil {
+		t.Fatalf("Unexpected error during config validation: %v", err)
+	}
+	vcConfig := cfg.VirtualCenter["2.2.2.2"]
+	if vcConfig.SecretName != "vcenter-credentials" || vcConfig.SecretNamespace != GetCSINamespace() {
+		t.Errorf("Unexpected Secret %s/%s", vcConfig.SecretNamespace, vcConfig.SecretName)
+	}
+	if vcConfig.User != "" || vcConfig.Password != "" {
+		t.Errorf("Unexpected credentials before the Secret is resolved")
+	}
+
+	SetVCenterCredentials("2.2.2.2", "Administrator@vsphere.local", "Password")
+	defer delete(secretVCenterCredentials, "2.2.2.2")
+	vcConfig.User = "stale@vsphere.local"
+	if err := validateConfig(ctx, cfg); err != nil {
+		t.Fatalf("Unexpected error during config validation: %v", err)
+	}
+	if vcConfig.User != "Administrator@vsphere.local" || vcConfig.Password != "Password" {
+		t.Errorf("Expected the credentials of the Secret, got user %q", vcConfig.User)
+	}
+}
+
+func TestValidateConfigWithTokenFile(t *testing.T) {
+	cfg := &Config{
+		VirtualCenter: map[string]*VirtualCenterConfig{
+			"2.2.2.2": {
+				VCenterPort:  "443",
+				Datacenters:  "dc1",
+				InsecureFlag: true,
+			},
+		},
+	}
+	cfg.Global.ServiceAccountTokenFile = "/var/run/secrets/vcenter/token"
+	if err := validateConfig(ctx, cfg); err != nil {
+		t.Fatalf("Unexpected error during config validation: %v", err)
+	}
+	if cfg.VirtualCenter["2.2.2.2"].ServiceAccountTokenFile != "/var/run/secrets/vcenter/token" {
+		t.Errorf("Expected the service account token file of the Global section")
+	}
+
+	cfg.VirtualCenter["2.2.2.2"].SAMLTokenFile = "/var/run/secrets/vcenter/saml"
+	if err := validateConfig(ctx, cfg); !errors.Is(err, ErrMultipleTokenFiles) {
+		t.Errorf("Expected error %v, got %v", ErrMultipleTokenFiles, err)
+	}
+}
+
+func TestValidateTracingConfig(t *testing.T) {
+	cfg := &Config{}
+	cfg.Tracing.Endpoint = "otel-collector.monitoring:4317"
+	if err := validateTracingConfig(ctx, cfg); err != nil {
+		t.Fatalf("Unexpected error during tracing config validation: %v", err)
+	}
+	if cfg.Tracing.SamplingRatio != DefaultTracingSamplingRatio {
+		t.Errorf("Expected the default sampling ratio, got %v", cfg.Tracing.SamplingRatio)
+	}
+	for _, ratio := range []float64{-0.1, 1.5} {
+		cfg.Tracing.SamplingRatio = ratio
+		if err := validateTracingConfig(ctx, cfg); err == nil {
+			t.Errorf("Expected sampling ratio %v to be rejected", ratio)
+		}
+	}
+}
+
+func TestValidateAPIRateLimit(t *testing.T) {
+	vcConfig := &VirtualCenterConfig{APIRateLimit: 2.5}
+	if err := validateAPIRateLimit(ctx, "1.1.1.1", vcConfig); err != nil {
+		t.Fatalf("Unexpected error during rate limit validation: %v", err)
+	}
+	if vcConfig.APIBurst != 3 || vcConfig.APIMaxWaitSeconds != DefaultAPIMaxWaitSeconds {
+		t.Errorf("Expected the default burst and maximum wait, got %d and %d",
+			vcConfig.APIBurst, vcConfig.APIMaxWaitSeconds)
+	}
+	vcConfig = &VirtualCenterConfig{APIRateLimit: -1}
+	if err := validateAPIRateLimit(ctx, "1.1.1.1", vcConfig); err == nil {
+		t.Error("Expected a negative rate limit to be rejected")
+	}
+}
+
+func TestGetVCenterCredentialsFromSecretData(t *testing.T) {
+	data := map[string][]byte{
+		"username":          []byte("Administrator@vsphere.local"),
+		"password":          []byte("Password"),
+		"2.2.2.2.username":  []byte("csi@vsphere.local"),
+		"2.2.2.2.password":  []byte("Password2"),
+		"3.3.3.3.username":  []byte("Administrator"),
+		"4.4.4.4.password":  []byte(""),
+		"4.4.4.4.username2": []byte("unused"),
+	}
+	tests := []struct {
+		host             string
+		expectedUser     string
+		expectedPassword string
+		expectedErr      error
+	}{
+		{host: "1.1.1.1", expectedUser: "Administrator@vsphere.local", expectedPassword: "Password"},
+		{host: "2.2.2.2", expectedUser: "csi@vsphere.local", expectedPassword: "Password2"},
+		{host: "3.3.3.3", expectedErr: ErrInvalidUsername},
+		{host: "4.4.4.4", expectedErr: ErrPasswordMissing},
+	}
+	for _, test := range tests {
+		user, password, err := GetVCenterCredentialsFromSecretData(test.host, data)
+		if !errors.Is(err, test.expectedErr) {
+			t.Errorf("host %s: expected error %v, got %v", test.host, test.expectedErr, err)
+		}
+		if user != test.expectedUser || password != test.expectedPassword {
+			t.Errorf("host %s: unexpected credentials for user %q", test.host, user)
+		}
+	}
+	if _, _, err := GetVCenterCredentialsFromSecretData("1.1.1.1", nil); !errors.Is(err, ErrUsernameMissing) {
+		t.Errorf("expected error %v, got %v", ErrUsernameMissing, err)
+	}
+}
+
 func isConfigEqual(actual *Config, expected *Config) bool {
 	// TODO: Compare Global struct
 	// Compare VC Config
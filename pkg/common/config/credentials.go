@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// SecretUsernameKey is the key of the vCenter username in a credentials
+	// Secret. The key "<vCenter host>.username" takes precedence over it.
+	SecretUsernameKey = "username"
+	// SecretPasswordKey is the key of the vCenter password in a credentials
+	// Secret. The key "<vCenter host>.password" takes precedence over it.
+	SecretPasswordKey = "password"
+	// SecretTokenKey is the key of the Supervisor Cluster token in the
+	// credentials Secret of a Guest Cluster.
+	SecretTokenKey = "token"
+	// SecretCACertKey is the key of the Supervisor Cluster CA certificate in
+	// the credentials Secret of a Guest Cluster.
+	SecretCACertKey = "ca.crt"
+)
+
+type vCenterCredentials struct {
+	user     string
+	password string
+}
+
+var (
+	// credentialsLock protects the credentials resolved from Secrets.
+	credentialsLock = &sync.RWMutex{}
+	// secretVCenterCredentials is a map of vCenter host to the credentials
+	// resolved from the Secret referenced by its configuration.
+	secretVCenterCredentials = make(map[string]vCenterCredentials)
+	// supervisorToken and supervisorCACert are resolved from the Secret
+	// referenced by the Guest Cluster configuration.
+	supervisorToken  string
+	supervisorCACert []byte
+)
+
+// SetVCenterCredentials records the credentials of vCenter host resolved from
+// its Secret. They override the user and password of the vSphere config file
+// each time the configuration is read.
+func SetVCenterCredentials(host string, user string, password string) {
+	credentialsLock.Lock()
+	defer credentialsLock.Unlock()
+	secretVCenterCredentials[host] = vCenterCredentials{user: user, password: password}
+}
+
+// GetVCenterCredentials returns the credentials of vCenter host resolved from
+// its Secret, if any.
+func GetVCenterCredentials(host string) (string, string, bool) {
+	credentialsLock.RLock()
+	defer credentialsLock.RUnlock()
+	creds, ok := secretVCenterCredentials[host]
+	return creds.user, creds.password, ok
+}
+
+// SetSupervisorCredentials records the token and CA certificate used to
+// access the Supervisor Cluster, resolved from the Secret referenced by the
+// Guest Cluster configuration.
+func SetSupervisorCredentials(token string, caCert []byte) {
+	credentialsLock.Lock()
+	defer credentialsLock.Unlock()
+	supervisorToken = token
+	supervisorCACert = caCert
+}
+
+// GetSupervisorCredentials returns the token and CA certificate used to
+// access the Supervisor Cluster, if they were resolved from a Secret.
+func GetSupervisorCredentials() (string, []byte, bool) {
+	credentialsLock.RLock()
+	defer credentialsLock.RUnlock()
+	return supervisorToken, supervisorCACert, supervisorToken != ""
+}
+
+// GetVCenterCredentialsFromSecretData returns the credentials of vCenter host
+// from the data of a Secret. The keys "<host>.username" and "<host>.password"
+// take precedence over the keys "username" and "password", so that a single
+// Secret can hold the credentials of several vCenters.
+func GetVCenterCredentialsFromSecretData(host string, data map[string][]byte) (string, string, error) {
+	user, ok := data[host+"."+SecretUsernameKey]
+	if !ok {
+		user = data[SecretUsernameKey]
+	}
+	password, ok := data[host+"."+SecretPasswordKey]
+	if !ok {
+		password = data[SecretPasswordKey]
+	}
+	if len(user) == 0 {
+		return "", "", fmt.Errorf("%w for vCenter %q", ErrUsernameMissing, host)
+	}
+	if !isValidvCenterUsernameWithDomain(string(user)) {
+		return "", "", fmt.Errorf("%w for vCenter %q", ErrInvalidUsername, host)
+	}
+	if len(password) == 0 {
+		return "", "", fmt.Errorf("%w for vCenter %q", ErrPasswordMissing, host)
+	}
+	return string(user), string(password), nil
+}
+
+// GetSupervisorCredentialsFromSecretData returns the token and CA certificate
+// used to access the Supervisor Cluster from the data of a Secret.
+func GetSupervisorCredentialsFromSecretData(data map[string][]byte) (string, []byte, error) {
+	token := data[SecretTokenKey]
+	if len(token) == 0 {
+		return "", nil, fmt.Errorf("key %q is missing from the Supervisor Cluster credentials", SecretTokenKey)
+	}
+	caCert := data[SecretCACertKey]
+	if len(caCert) == 0 {
+		return "", nil, fmt.Errorf("key %q is missing from the Supervisor Cluster credentials", SecretCACertKey)
+	}
+	return string(token), caCert, nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	vsanfstypes "github.com/vmware/govmomi/vsan/vsanfs/types"
+)
+
+// validateNetPermission sets the defaults of netPerm and validates its
+// Permissions.
+func validateNetPermission(netPerm *NetPermissionConfig) error {
+	switch netPerm.Permissions {
+	case "":
+		netPerm.Permissions = vsanfstypes.VsanFileShareAccessTypeREAD_WRITE
+	case vsanfstypes.VsanFileShareAccessTypeNO_ACCESS, vsanfstypes.VsanFileShareAccessTypeREAD_ONLY,
+		vsanfstypes.VsanFileShareAccessTypeREAD_WRITE:
+	default:
+		return ErrInvalidNetPermission
+	}
+	if netPerm.Ips == "" {
+		netPerm.Ips = "*"
+	}
+	return nil
+}
+
+// isValidNetPermissionIps returns true if ips is "*", an IP address, an IP
+// subnet or an IP range "<first IP>-<last IP>".
+func isValidNetPermissionIps(ips string) bool {
+	if ips == "*" || net.ParseIP(ips) != nil {
+		return true
+	}
+	if _, _, err := net.ParseCIDR(ips); err == nil {
+		return true
+	}
+	first, last, found := strings.Cut(ips, "-")
+	return found && net.ParseIP(first) != nil && net.ParseIP(last) != nil
+}
+
+// ParseNetPermissions parses the net permissions selected or defined by the
+// netpermissions parameter of a StorageClass or the net permissions annotation
+// of a PVC. The value is either a comma separated list of the names of the
+// NetPermissions sections in configured, or a JSON list of net permissions,
+// for example [{"ips": "10.20.30.0/24", "permissions": "READ_ONLY", "rootsquash": true}].
+func ParseNetPermissions(value string, configured map[string]*NetPermissionConfig) (
+	[]*NetPermissionConfig, error) {
+	value = strings.TrimSpace(value)
+	var netPerms []*NetPermissionConfig
+	if strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), &netPerms); err != nil {
+			return nil, fmt.Errorf("failed to parse net permissions %q. Error: %v", value, err)
+		}
+		for _, netPerm := range netPerms {
+			if netPerm == nil {
+				return nil, fmt.Errorf("invalid null net permission in %q", value)
+			}
+			if err := validateNetPermission(netPerm); err != nil {
+				return nil, fmt.Errorf("invalid permissions %q in %q. Supported values are %q, %q and %q",
+					netPerm.Permissions, value, vsanfstypes.VsanFileShareAccessTypeREAD_WRITE,
+					vsanfstypes.VsanFileShareAccessTypeREAD_ONLY, vsanfstypes.VsanFileShareAccessTypeNO_ACCESS)
+			}
+			if !isValidNetPermissionIps(netPerm.Ips) {
+				return nil, fmt.Errorf("invalid ips %q in %q. Expecting \"*\", an IP address, subnet or range",
+					netPerm.Ips, value)
+			}
+		}
+	} else {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			netPerm, ok := configured[name]
+			if !ok || netPerm == nil {
+				return nil, fmt.Errorf("net permissions %q are not defined in the NetPermissions sections "+
+					"of the vSphere config", name)
+			}
+			copied := *netPerm
+			netPerms = append(netPerms, &copied)
+		}
+	}
+	if len(netPerms) == 0 {
+		return nil, fmt.Errorf("no net permissions in %q", value)
+	}
+	return netPerms, nil
+}
+
+// NetPermissionIpsContain returns true if the ips of a net permission, that
+// is "*", an IP address, an IP subnet or an IP range, contain ip.
+func NetPermissionIpsContain(ips string, ip net.IP) bool {
+	if ips == "*" {
+		return true
+	}
+	if ipsIP := net.ParseIP(ips); ipsIP != nil {
+		return ipsIP.Equal(ip)
+	}
+	if _, subnet, err := net.ParseCIDR(ips); err == nil {
+		return subnet.Contains(ip)
+	}
+	first, last, found := strings.Cut(ips, "-")
+	firstIP, lastIP := net.ParseIP(first), net.ParseIP(last)
+	if !found || firstIP == nil || lastIP == nil || ip.To16() == nil {
+		return false
+	}
+	return bytes.Compare(ip.To16(), firstIP.To16()) >= 0 && bytes.Compare(ip.To16(), lastIP.To16()) <= 0
+}
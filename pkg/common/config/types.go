@@ -61,6 +61,20 @@ type Config struct {
 		User string `gcfg:"user"`
 		// vCenter password in clear text.
 		Password string `gcfg:"password"`
+		// SecretName is the name of the Secret holding the vCenter credentials.
+		// If specified, the credentials are read from the Secret instead of User
+		// and Password, and are updated when the Secret is updated.
+		SecretName string `gcfg:"secret-name"`
+		// SecretNamespace is the namespace of SecretName. Defaults to the
+		// namespace of the driver.
+		SecretNamespace string `gcfg:"secret-namespace"`
+		// SAMLTokenFile is the path of a file holding a SAML bearer token used
+		// to log in to vCenter instead of User and Password.
+		SAMLTokenFile string `gcfg:"saml-token-file"`
+		// ServiceAccountTokenFile is the path of a projected Kubernetes service
+		// account token, exchanged for a SAML bearer token by vCenter to log in
+		// instead of User and Password.
+		ServiceAccountTokenFile string `gcfg:"service-account-token-file"`
 		// vCenter port.
 		VCenterPort string `gcfg:"port"`
 		// Specifies whether to verify the server's certificate chain. Set to true to
@@ -92,6 +106,20 @@ type Config struct {
 		// CnsVolumeOperationRequestCleanupIntervalInMin specifies the interval after which
 		// stale CnsVolumeOperationRequest instances will be cleaned up.
 		CnsVolumeOperationRequestCleanupIntervalInMin int `gcfg:"cnsvolumeoperationrequest-cleanup-intervalinmin"`
+		// CnsVolumeOperationRequestStore specifies the backend persisting the details of
+		// the operations invoked on CNS. Either "crd" (default) or "configmap".
+		CnsVolumeOperationRequestStore string `gcfg:"cnsvolumeoperationrequest-store"`
+		// CnsVolumeOperationRequestStoreShards specifies the number of ConfigMaps
+		// used by the "configmap" CnsVolumeOperationRequest store.
+		CnsVolumeOperationRequestStoreShards int `gcfg:"cnsvolumeoperationrequest-store-shards"`
+		// FullSyncMode specifies how the full sync reconciles the volumes of the
+		// cluster. Either "complete" (default), reconciling every volume on each
+		// cycle, or "incremental", reconciling the volumes changed since the
+		// previous cycle. The incremental mode is supported on vanilla clusters.
+		FullSyncMode string `gcfg:"full-sync-mode"`
+		// CompleteFullSyncIntervalInMin specifies the interval after which the
+		// incremental full sync reconciles every volume again.
+		CompleteFullSyncIntervalInMin int `gcfg:"complete-full-sync-intervalinmin"`
 		// CSIFetchPreferredDatastoresIntervalInMin specifies the interval
 		// after which the preferred datastores cache is refreshed in the driver.
 		CSIFetchPreferredDatastoresIntervalInMin int `gcfg:"csi-fetch-preferred-datastores-intervalinmin"`
@@ -105,6 +133,18 @@ type Config struct {
 
 	// Snapshot configurations.
 	Snapshot SnapshotConfig
+
+	// Tracing configurations.
+	Tracing TracingConfig
+
+	// DriftReport configurations.
+	DriftReport DriftReportConfig
+
+	// OrphanGC configurations.
+	OrphanGC OrphanGCConfig
+
+	// FileVolumeACL configurations.
+	FileVolumeACL FileVolumeACLConfig
 }
 
 // ConfigurationInfo is a struct that used to capture config param details
@@ -127,11 +167,11 @@ type TopologyCategoryInfo struct {
 // network permissions set on file share volumes
 type NetPermissionConfig struct {
 	// Client IP address, IP range or IP subnet. Example: "10.20.30.0/24"; defaults to "*" if not specified
-	Ips string `gcfg:"ips"`
+	Ips string `gcfg:"ips" json:"ips"`
 	// Is it READ_ONLY, READ_WRITE or NO_ACCESS. Defaults to "READ_WRITE" if not specified
-	Permissions vsanfstypes.VsanFileShareAccessType `gcfg:"permissions"`
+	Permissions vsanfstypes.VsanFileShareAccessType `gcfg:"permissions" json:"permissions"`
 	// Disallow root access for this IP range. Defaults to "false" if not specified
-	RootSquash bool `gcfg:"rootsquash"`
+	RootSquash bool `gcfg:"rootsquash" json:"rootsquash"`
 }
 
 // VirtualCenterConfig contains information used to access a remote vCenter
@@ -141,6 +181,16 @@ type VirtualCenterConfig struct {
 	User string `gcfg:"user" sensitive:"true"`
 	// vCenter password in clear text.
 	Password string `gcfg:"password" sensitive:"true"`
+	// SecretName is the name of the Secret holding the vCenter credentials.
+	// Overrides the SecretName of the Global section.
+	SecretName string `gcfg:"secret-name"`
+	// SecretNamespace is the namespace of SecretName.
+	SecretNamespace string `gcfg:"secret-namespace"`
+	// SAMLTokenFile overrides the SAMLTokenFile of the Global section.
+	SAMLTokenFile string `gcfg:"saml-token-file"`
+	// ServiceAccountTokenFile overrides the ServiceAccountTokenFile of the
+	// Global section.
+	ServiceAccountTokenFile string `gcfg:"service-account-token-file"`
 	// vCenter port.
 	VCenterPort string `gcfg:"port"`
 	// Specifies the path to a CA certificate in PEM format. This has no effect if
@@ -161,6 +211,15 @@ type VirtualCenterConfig struct {
 	InsecureFlag bool `gcfg:"insecure-flag"`
 	// FileVolumeActivated indicates whether file service has been enabled on any vSAN cluster or not
 	FileVolumeActivated bool
+	// APIRateLimit is the maximum number of vCenter API calls per second of
+	// each container of the driver. The calls are not limited if 0.
+	APIRateLimit float64 `gcfg:"api-rate-limit"`
+	// APIBurst is the number of vCenter API calls allowed at once above
+	// APIRateLimit. Defaults to APIRateLimit rounded up.
+	APIBurst int `gcfg:"api-burst"`
+	// APIMaxWaitSeconds is the maximum time a vCenter API call waits for the
+	// rate limiter before it is rejected. Defaults to 60.
+	APIMaxWaitSeconds int `gcfg:"api-max-wait-seconds"`
 }
 
 // GCConfig contains information used by guest cluster to access a supervisor
@@ -184,6 +243,14 @@ type GCConfig struct {
 	// (stretched Supervisor or WorkloadDomainIsolation enabled).
 	// Populated from vspherePVCSI.zone via cns-csi.conf topology-enabled field.
 	TopologyEnabled bool `gcfg:"topology-enabled"`
+	// SecretName is the name of the Secret holding the token and the CA
+	// certificate used to access the Supervisor Cluster, under the keys
+	// "token" and "ca.crt". If specified, they are used instead of the pvCSI
+	// provider files, and the token is updated when the Secret is updated.
+	SecretName string `gcfg:"secret-name"`
+	// SecretNamespace is the namespace of SecretName. Defaults to the
+	// namespace of the driver.
+	SecretNamespace string `gcfg:"secret-namespace"`
 }
 
 // SnapshotConfig contains snapshot configuration.
@@ -198,5 +265,69 @@ type SnapshotConfig struct {
 	GranularMaxSnapshotsPerBlockVolumeInVVOL int `gcfg:"granular-max-snapshots-per-block-volume-vvol"`
 }
 
+// TracingConfig contains the configuration of the OpenTelemetry tracing of the
+// CSI RPCs, vCenter calls and syncer reconciles.
+type TracingConfig struct {
+	// Endpoint is the host:port of the OTLP gRPC collector receiving the spans.
+	// Tracing is disabled if neither Endpoint nor the environment variable
+	// OTEL_EXPORTER_OTLP_ENDPOINT is set.
+	Endpoint string `gcfg:"otlp-endpoint"`
+	// Insecure disables TLS for the connection to the collector.
+	Insecure bool `gcfg:"otlp-insecure"`
+	// SamplingRatio is the ratio of the traces started by the driver which are
+	// sampled, between 0 and 1. Defaults to 1 if unset. The traces propagated
+	// by the CSI sidecars follow their sampling decision.
+	SamplingRatio float64 `gcfg:"sampling-ratio"`
+}
+
+// DriftReportConfig contains the configuration of the CnsDriftReport produced
+// by each full sync, and the remediation policy of each drift category, either
+// DriftRemediationPolicyReport or DriftRemediationPolicyRemediate.
+type DriftReportConfig struct {
+	// DryRun reports the drift found by the full sync without correcting it,
+	// whatever the remediation policies.
+	DryRun bool `gcfg:"dry-run"`
+	// OrphanedVolume is the policy of the CNS volumes without a PV, labeled
+	// pv_missing when remediated. Defaults to remediate.
+	OrphanedVolume string `gcfg:"orphaned-volume"`
+	// PVWithoutVolume is the policy of the PVs whose volume is not registered
+	// in CNS, registered again when remediated. Defaults to remediate.
+	PVWithoutVolume string `gcfg:"pv-without-volume"`
+	// MetadataMismatch is the policy of the volumes whose metadata in CNS
+	// differs from Kubernetes, updated when remediated. Defaults to remediate.
+	MetadataMismatch string `gcfg:"metadata-mismatch"`
+	// StaleVolumeOperationRequest is the policy of the CnsVolumeOperationRequests
+	// stuck in progress, deleted when remediated. Defaults to report.
+	StaleVolumeOperationRequest string `gcfg:"stale-volume-operation-request"`
+	// StaleVolumeAttachment is the policy of the VolumeAttachments whose PV or
+	// node no longer exists, deleted when remediated. Defaults to report.
+	StaleVolumeAttachment string `gcfg:"stale-volume-attachment"`
+}
+
+// OrphanGCConfig contains the configuration of the garbage collection of the
+// CNS volumes and snapshots of the cluster without a Kubernetes counterpart.
+type OrphanGCConfig struct {
+	// IntervalInMin is the interval in minutes between the passes of the
+	// garbage collector. Defaults to DefaultOrphanGCIntervalInMin.
+	IntervalInMin int `gcfg:"interval-minutes"`
+	// GracePeriodInMin is the time in minutes during which an orphaned object
+	// is kept after it was first found. Defaults to
+	// DefaultOrphanGCGracePeriodInMin.
+	GracePeriodInMin int `gcfg:"grace-period-minutes"`
+}
+
+// FileVolumeACLConfig contains the configuration of the tightening of the ACL
+// of the file volumes to the IPs of the nodes consuming them.
+type FileVolumeACLConfig struct {
+	// ResyncIntervalInMin is the interval in minutes between the reconciles
+	// of the ACL of all the file volumes. Defaults to
+	// DefaultFileVolumeACLResyncIntervalInMin.
+	ResyncIntervalInMin int `gcfg:"resync-interval-minutes"`
+	// GracePeriodInMin is the time in minutes during which the IP of a node
+	// is kept in the ACL of a file volume after the node stopped consuming the
+	// volume or changed its IP. Defaults to DefaultFileVolumeACLGracePeriodInMin.
+	GracePeriodInMin int `gcfg:"grace-period-minutes"`
+}
+
 // EnvClusterFlavor is the k8s cluster type on which CSI Driver is being deployed
 const EnvClusterFlavor = "CLUSTER_FLAVOR"
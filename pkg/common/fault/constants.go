@@ -67,6 +67,12 @@ const (
 	CSIInvalidArgumentFault = "csi.fault.InvalidArgument"
 	// CSIUnimplementedFault is the fault type returned when the function is unimplemented.
 	CSIUnimplementedFault = "csi.fault.Unimplemented"
+	// CSIAlreadyExistsFault is the fault type returned when an object with the same name but different
+	// parameters already exists.
+	CSIAlreadyExistsFault = "csi.fault.AlreadyExists"
+	// CSIFailedPreconditionFault is the fault type returned when the request does not match the state
+	// of the object.
+	CSIFailedPreconditionFault = "csi.fault.FailedPrecondition"
 	// CSIInvalidStoragePolicyConfigurationFault is the fault type returned when the user provides invalid storage policy.
 	CSIInvalidStoragePolicyConfigurationFault = "csi.fault.invalidconfig.InvalidStoragePolicyConfiguration"
 
@@ -49,6 +49,18 @@ const (
 	PrometheusListSnapshotsOpType = "list-snapshot"
 	// PrometheusListVolumeOpType represents the ListVolumes operation.
 	PrometheusListVolumeOpType = "list-volume"
+	// PrometheusGetCapacityOpType represents the GetCapacity operation.
+	PrometheusGetCapacityOpType = "get-capacity"
+	// PrometheusModifyVolumeOpType represents the ControllerModifyVolume operation.
+	PrometheusModifyVolumeOpType = "modify-volume"
+	// PrometheusGetVolumeOpType represents the ControllerGetVolume operation.
+	PrometheusGetVolumeOpType = "get-volume"
+	// PrometheusCreateVolumeGroupSnapshotOpType represents the CreateVolumeGroupSnapshot operation.
+	PrometheusCreateVolumeGroupSnapshotOpType = "create-volume-group-snapshot"
+	// PrometheusDeleteVolumeGroupSnapshotOpType represents the DeleteVolumeGroupSnapshot operation.
+	PrometheusDeleteVolumeGroupSnapshotOpType = "delete-volume-group-snapshot"
+	// PrometheusGetVolumeGroupSnapshotOpType represents the GetVolumeGroupSnapshot operation.
+	PrometheusGetVolumeGroupSnapshotOpType = "get-volume-group-snapshot"
 
 	// CNS operation types
 
@@ -208,4 +220,63 @@ var (
 		Name: "vsphere_cns_volume_pv_retained",
 		Help: "Number of CNS volumes with ReclaimPolicy=Retain PVs in Released/Available phase, per vCenter.",
 	}, []string{"vc"})
+
+	// MetadataSyncQueueDepthGauge is a gauge metric to observe the number of
+	// volumes waiting for a metadata update in the syncer.
+	MetadataSyncQueueDepthGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vsphere_syncer_metadata_queue_depth",
+		Help: "Number of volumes waiting for a metadata update in the syncer.",
+	})
+
+	// MetadataSyncQueueLatencyHistVec is a histogram vector metric to observe the
+	// time from queueing a volume metadata update to its completion on CNS.
+	MetadataSyncQueueLatencyHistVec = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vsphere_syncer_metadata_queue_latency_seconds",
+		Help:    "Histogram vector for the latency of queued volume metadata updates.",
+		Buckets: []float64{0.5, 1, 2, 5, 10, 30, 60, 120, 300, 600},
+	},
+		// Possible status - "pass", "fail"
+		[]string{"status"})
+
+	// MetadataSyncQueueRetriesCounter is a counter metric to observe the number
+	// of retried volume metadata updates.
+	MetadataSyncQueueRetriesCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "vsphere_syncer_metadata_queue_retries_total",
+		Help: "Number of retried volume metadata updates in the syncer.",
+	})
+
+	// VCenterAPIRateLimitWaitHistVec is a histogram vector metric to observe the
+	// time the vCenter API calls wait for the rate limiter of the vCenter.
+	VCenterAPIRateLimitWaitHistVec = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vsphere_vcenter_api_rate_limit_wait_seconds",
+		Help:    "Histogram vector for the wait of the vCenter API calls for the rate limiter, per priority.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 2, 5, 10, 30, 60},
+	},
+		// Possible priority - "user-facing", "attach-detach", "background"
+		[]string{"vc", "priority"})
+
+	// VCenterAPIRateLimitRejectionsCounterVec is a counter metric to observe the
+	// number of vCenter API calls rejected by the rate limiter of the vCenter
+	// after waiting longer than the maximum wait.
+	VCenterAPIRateLimitRejectionsCounterVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vsphere_vcenter_api_rate_limit_rejections_total",
+		Help: "Number of vCenter API calls rejected by the rate limiter, per priority.",
+	}, []string{"vc", "priority"})
+
+	// FileVolumeACLDriftGauge is a gauge metric to observe the number of file
+	// volumes whose ACL differs from the IPs of the nodes consuming them and
+	// could not be reconciled yet.
+	FileVolumeACLDriftGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vsphere_file_volume_acl_drift",
+		Help: "Number of file volumes whose ACL differs from the IPs of the nodes consuming them.",
+	})
+
+	// FileVolumeACLReconcilesCounterVec is a counter vector metric to observe
+	// the reconciles of the ACL of the file volumes.
+	FileVolumeACLReconcilesCounterVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vsphere_file_volume_acl_reconciles_total",
+		Help: "Number of reconciles of the ACL of the file volumes, per result.",
+	},
+		// Possible result - "in-sync", "updated", "failed"
+		[]string{"result"})
 )
@@ -0,0 +1,197 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing exports OpenTelemetry spans of the CSI RPCs, vCenter calls
+// and syncer reconciles to an OTLP collector.
+package tracing
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+)
+
+const (
+	// tracerName is the name of the tracer of the driver.
+	tracerName = "sigs.k8s.io/vsphere-csi-driver"
+	// envOTLPEndpoint and envOTLPTracesEndpoint are the standard environment
+	// variables of the OTLP endpoint, which enable tracing when the config sets
+	// no endpoint, for example in the node plugin which reads no vSphere config.
+	envOTLPEndpoint       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envOTLPTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+)
+
+var (
+	// tracerProvider is the provider exporting the spans, nil if tracing is
+	// disabled.
+	tracerProvider     *sdktrace.TracerProvider
+	tracerProviderLock = &sync.Mutex{}
+	// newExporter is the function creating the OTLP exporter of the spans.
+	newExporter = func(ctx context.Context, cfg *cnsconfig.TracingConfig) (sdktrace.SpanExporter, error) {
+		var opts []otlptracegrpc.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	}
+)
+
+func init() {
+	// The trace context of the CSI sidecars is propagated in the gRPC metadata
+	// even if tracing is disabled, so that the logs can be correlated.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Init starts exporting the spans of serviceName to the OTLP collector of cfg.
+// It is a no-op if cfg is nil or sets no endpoint and the standard OTLP
+// environment variables are not set.
+func Init(ctx context.Context, serviceName string, version string, cfg *cnsconfig.TracingConfig) error {
+	log := logger.GetLogger(ctx)
+	if cfg == nil {
+		cfg = &cnsconfig.TracingConfig{}
+	}
+	if cfg.Endpoint == "" && os.Getenv(envOTLPEndpoint) == "" && os.Getenv(envOTLPTracesEndpoint) == "" {
+		log.Debugf("Tracing is disabled for %q, no OTLP endpoint is configured", serviceName)
+		return nil
+	}
+	samplingRatio := cfg.SamplingRatio
+	if samplingRatio == 0 {
+		samplingRatio = cnsconfig.DefaultTracingSamplingRatio
+	}
+
+	tracerProviderLock.Lock()
+	defer tracerProviderLock.Unlock()
+	if tracerProvider != nil {
+		return nil
+	}
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to create the OTLP trace exporter. Err: %v", err)
+	}
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(samplingRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(serviceName), semconv.ServiceVersion(version))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	log.Infof("Tracing is enabled for %q with sampling ratio %v", serviceName, samplingRatio)
+	return nil
+}
+
+// Shutdown exports the pending spans and stops the export.
+func Shutdown(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	tracerProviderLock.Lock()
+	defer tracerProviderLock.Unlock()
+	if tracerProvider == nil {
+		return
+	}
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		log.Warnf("failed to export the pending spans. Err: %v", err)
+	}
+	tracerProvider = nil
+}
+
+// StartSpan starts a span child of the span of ctx, and returns the context
+// holding it. The span must be ended with EndSpan.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartClientSpan starts a span of a call to a remote service, such as
+// vCenter, child of the span of ctx.
+func StartClientSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...),
+		trace.WithSpanKind(trace.SpanKindClient))
+}
+
+// EndSpan records err in span if not nil, and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// UnaryServerInterceptor returns the gRPC interceptor starting a span for each
+// CSI RPC. The span is a child of the trace context propagated by the caller
+// in the gRPC metadata, such as the one of the external-provisioner.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+		}
+		ctx, span := otel.Tracer(tracerName).Start(ctx, info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("rpc.system", "grpc"),
+				attribute.String("rpc.method", info.FullMethod)))
+		resp, err := handler(ctx, req)
+		if err != nil {
+			span.SetAttributes(attribute.String("rpc.grpc.status_code", status.Code(err).String()))
+		}
+		EndSpan(span, err)
+		return resp, err
+	}
+}
+
+// metadataCarrier adapts the gRPC metadata to a propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+// Get returns the first value of key.
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Set sets the value of key.
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys returns the keys of the metadata.
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
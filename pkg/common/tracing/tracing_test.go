@@ -0,0 +1,128 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+)
+
+func TestInitWithoutEndpoint(t *testing.T) {
+	t.Setenv(envOTLPEndpoint, "")
+	t.Setenv(envOTLPTracesEndpoint, "")
+	origNewExporter := newExporter
+	defer func() {
+		newExporter = origNewExporter
+	}()
+	newExporter = func(ctx context.Context, cfg *cnsconfig.TracingConfig) (sdktrace.SpanExporter, error) {
+		t.Fatal("expected no exporter to be created without an OTLP endpoint")
+		return nil, nil
+	}
+	if err := Init(context.Background(), "vsphere-csi-node", "test", nil); err != nil {
+		t.Fatal(err)
+	}
+	if tracerProvider != nil {
+		t.Fatal("expected tracing to be disabled without an OTLP endpoint")
+	}
+}
+
+func TestInitWithEndpoint(t *testing.T) {
+	ctx := context.Background()
+	exporter := tracetest.NewInMemoryExporter()
+	origNewExporter := newExporter
+	origProvider := otel.GetTracerProvider()
+	defer func() {
+		newExporter = origNewExporter
+		Shutdown(ctx)
+		otel.SetTracerProvider(origProvider)
+	}()
+	newExporter = func(ctx context.Context, cfg *cnsconfig.TracingConfig) (sdktrace.SpanExporter, error) {
+		if cfg.Endpoint != "otel-collector:4317" || !cfg.Insecure {
+			t.Errorf("unexpected exporter config %+v", cfg)
+		}
+		return exporter, nil
+	}
+	err := Init(ctx, "vsphere-csi-controller", "test",
+		&cnsconfig.TracingConfig{Endpoint: "otel-collector:4317", Insecure: true, SamplingRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := StartClientSpan(ctx, "vcenter.RetrieveProperties")
+	EndSpan(span, nil)
+	if err := tracerProvider.ForceFlush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "vcenter.RetrieveProperties" ||
+		spans[0].SpanKind != trace.SpanKindClient {
+		t.Fatalf("expected the client span to be exported, got %+v", spans)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	origProvider := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(origProvider)
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	// Trace context of the external-provisioner.
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-"+traceID+"-00f067aa0ba902b7-01"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/CreateVolume"}
+	var childTraceID string
+	_, err := UnaryServerInterceptor()(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, span := StartSpan(ctx, "cns.WaitOnTask")
+		childTraceID = span.SpanContext().TraceID().String()
+		EndSpan(span, nil)
+		return nil, status.Error(grpccodes.NotFound, "volume not found")
+	})
+	if status.Code(err) != grpccodes.NotFound {
+		t.Fatalf("expected the error of the handler, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	server := spans[1]
+	if server.Name() != info.FullMethod || server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected server span %q of kind %v", server.Name(), server.SpanKind())
+	}
+	if server.SpanContext().TraceID().String() != traceID || childTraceID != traceID {
+		t.Errorf("expected the trace context of the caller to be propagated, got trace %s",
+			server.SpanContext().TraceID())
+	}
+	if server.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the span of the caller as parent, got %s", server.Parent().SpanID())
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("expected the error status to be recorded, got %v", server.Status())
+	}
+}
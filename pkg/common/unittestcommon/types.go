@@ -82,6 +82,9 @@ type MockVolumeMigrationService interface {
 // fakeVolumeOperationRequestInterface implements the VolumeOperationRequest
 // interface by storing the operation details in an in-memory map.
 type fakeVolumeOperationRequestInterface struct {
+	// lock protects volumeOperationRequestMap, which is accessed concurrently
+	// by operations running in parallel.
+	lock                      sync.RWMutex
 	volumeOperationRequestMap map[string]*cnsvolumeoperationrequest.VolumeOperationRequestDetails
 }
 
@@ -76,6 +76,25 @@ func GetFakeContainerOrchestratorInterface(orchestratorType int) (commonco.COCom
 			"listview-tasks":                    "true",
 			"storage-quota-m2":                  "false",
 			"workload-domain-isolation":         "true",
+			"storage-capacity-tracking":         "true",
+			"block-volume-clone":                "true",
+			"changed-block-tracking":            "false",
+			"volume-attributes-class":           "false",
+			"volume-condition":                  "false",
+			"volume-group-snapshot":             "false",
+			"multi-writer-block-volume":         "false",
+			"attachable-volumes-from-inventory": "false",
+			"metadata-sync-queue":               "false",
+			"static-volume-registration":        "false",
+			"bulk-volume-import":                "false",
+			"drift-report":                      "false",
+			"orphan-gc":                         "false",
+			"file-volume-net-permissions":       "false",
+			"file-volume-acl-tightening":        "false",
+			"node-luks-encryption":              "false",
+			"block-volume-mkfs-options":         "false",
+			"btrfs-block-volume":                "false",
+			"block-volume-project-quota":        "false",
 			// From `wcp-cluster-capabilities` configmap in supervisor
 			"Workload_Domain_Isolation_Supported": "false",
 			"supports_CSI_Backup_API":             "false",
@@ -323,6 +342,13 @@ func (nodeTopology *mockNodeVolumeTopology) GetNodeTopologyLabels(ctx context.Co
 	return nil, logger.LogNewError(log, "GetNodeTopologyLabels is not yet implemented.")
 }
 
+// GetNodeAttachableVolumes fetches the number of attachable volumes of a node from the CSINodeTopology CR.
+func (nodeTopology *mockNodeVolumeTopology) GetNodeAttachableVolumes(ctx context.Context,
+	info *commoncotypes.NodeInfo) (int64, error) {
+	log := logger.GetLogger(ctx)
+	return 0, logger.LogNewError(log, "GetNodeAttachableVolumes is not yet implemented.")
+}
+
 // GetSharedDatastoresInTopology retrieves shared datastores of nodes which satisfy a given topology requirement.
 func (cntrlTopology *mockControllerVolumeTopology) GetSharedDatastoresInTopology(ctx context.Context,
 	reqParams interface{}) ([]*cnsvsphere.DatastoreInfo, error) {
@@ -431,6 +457,8 @@ func (f *fakeVolumeOperationRequestInterface) GetRequestDetails(
 	ctx context.Context,
 	name string,
 ) (*cnsvolumeoperationrequest.VolumeOperationRequestDetails, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
 	instance, ok := f.volumeOperationRequestMap[name]
 	if !ok {
 		return nil, apierrors.NewNotFound(cnsvolumeoprequestv1alpha1.Resource(
@@ -445,6 +473,8 @@ func (f *fakeVolumeOperationRequestInterface) StoreRequestDetails(
 	ctx context.Context,
 	instance *cnsvolumeoperationrequest.VolumeOperationRequestDetails,
 ) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
 	f.volumeOperationRequestMap[instance.Name] = instance
 	return nil
 }
@@ -455,6 +485,8 @@ func (f *fakeVolumeOperationRequestInterface) DeleteRequestDetails(
 	ctx context.Context,
 	name string,
 ) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
 	delete(f.volumeOperationRequestMap, name)
 	return nil
 }
@@ -465,6 +497,8 @@ func (f *fakeVolumeOperationRequestInterface) HasPriorSuccessfulCreate(
 	ctx context.Context,
 	name string,
 ) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
 	instance, ok := f.volumeOperationRequestMap[name]
 	if !ok {
 		return false
@@ -476,6 +510,20 @@ func (f *fakeVolumeOperationRequestInterface) HasPriorSuccessfulCreate(
 	return false
 }
 
+// ListRequestDetails returns all the VolumeOperationRequestDetails stored by
+// the fake VolumeOperationRequest interface.
+func (f *fakeVolumeOperationRequestInterface) ListRequestDetails(
+	ctx context.Context,
+) ([]*cnsvolumeoperationrequest.VolumeOperationRequestDetails, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	var instances []*cnsvolumeoperationrequest.VolumeOperationRequestDetails
+	for _, instance := range f.volumeOperationRequestMap {
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
 // GetNodesForVolumes returns nodeNames to which the given volumeIDs are attached
 func (c *FakeK8SOrchestrator) GetNodesForVolumes(ctx context.Context, volumeID []string) map[string][]string {
 	nodeNames := make(map[string][]string)
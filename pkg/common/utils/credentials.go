@@ -0,0 +1,216 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v3/pkg/kubernetes"
+)
+
+var (
+	// watchedSecrets is the set of credentials Secrets already watched.
+	watchedSecrets     = make(map[types.NamespacedName]bool)
+	watchedSecretsLock = &sync.Mutex{}
+	// newSecretListener is the function creating the listener on a Secret.
+	newSecretListener = k8s.NewSecretListener
+	// updateVirtualCenterCredentials is the function updating the credentials
+	// of the vCenter sessions.
+	updateVirtualCenterCredentials = cnsvsphere.UpdateVirtualCenterCredentials
+)
+
+// StartCredentialsWatcher resolves the credentials of the vCenters and of the
+// Supervisor Cluster from the Secrets referenced by cfg, and sets them in cfg
+// before the sessions are created from it. The Secrets are then watched, so
+// that the vCenter sessions are logged in again with the rotated credentials,
+// and the Supervisor Cluster clients use the rotated token, without restarting
+// the container. The Secrets are watched until ctx is done.
+// It is a no-op if cfg references no Secret.
+func StartCredentialsWatcher(ctx context.Context, cfg *cnsconfig.Config) error {
+	log := logger.GetLogger(ctx)
+	if cfg.GC.SecretName == "" && !hasVCenterCredentialsSecret(cfg) {
+		return nil
+	}
+	k8sClient, err := k8s.NewClient(ctx)
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to create Kubernetes client. Err: %v", err)
+	}
+	return startCredentialsWatcher(ctx, k8sClient, cfg)
+}
+
+func hasVCenterCredentialsSecret(cfg *cnsconfig.Config) bool {
+	for _, vcConfig := range cfg.VirtualCenter {
+		if vcConfig.SecretName != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func startCredentialsWatcher(ctx context.Context, k8sClient clientset.Interface, cfg *cnsconfig.Config) error {
+	log := logger.GetLogger(ctx)
+	// hostsBySecret is a map of Secret to the vCenter hosts whose credentials
+	// it holds.
+	hostsBySecret := make(map[types.NamespacedName][]string)
+	for host, vcConfig := range cfg.VirtualCenter {
+		if vcConfig.SecretName == "" {
+			continue
+		}
+		secretName := types.NamespacedName{Namespace: vcConfig.SecretNamespace, Name: vcConfig.SecretName}
+		hostsBySecret[secretName] = append(hostsBySecret[secretName], host)
+	}
+	for secretName, hosts := range hostsBySecret {
+		secret, err := k8sClient.CoreV1().Secrets(secretName.Namespace).Get(ctx, secretName.Name,
+			metav1.GetOptions{})
+		if err != nil {
+			return logger.LogNewErrorf(log, "failed to get the vCenter credentials Secret %s. Err: %v",
+				secretName, err)
+		}
+		for _, host := range hosts {
+			user, password, err := cnsconfig.GetVCenterCredentialsFromSecretData(host, secret.Data)
+			if err != nil {
+				return logger.LogNewErrorf(log, "failed to read the credentials of vCenter %q from Secret %s. "+
+					"Err: %v", host, secretName, err)
+			}
+			cnsconfig.SetVCenterCredentials(host, user, password)
+			cfg.VirtualCenter[host].User = user
+			cfg.VirtualCenter[host].Password = password
+		}
+		log.Infof("Resolved the credentials of vCenters %v from Secret %s", hosts, secretName)
+		err = watchSecret(ctx, k8sClient, secretName, func(secret *v1.Secret) {
+			onVCenterCredentialsSecretUpdate(ctx, hosts, secret)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.GC.SecretName != "" {
+		secretName := types.NamespacedName{Namespace: cfg.GC.SecretNamespace, Name: cfg.GC.SecretName}
+		secret, err := k8sClient.CoreV1().Secrets(secretName.Namespace).Get(ctx, secretName.Name,
+			metav1.GetOptions{})
+		if err != nil {
+			return logger.LogNewErrorf(log, "failed to get the Supervisor Cluster credentials Secret %s. Err: %v",
+				secretName, err)
+		}
+		token, caCert, err := cnsconfig.GetSupervisorCredentialsFromSecretData(secret.Data)
+		if err != nil {
+			return logger.LogNewErrorf(log, "failed to read the credentials of the Supervisor Cluster "+
+				"from Secret %s. Err: %v", secretName, err)
+		}
+		cnsconfig.SetSupervisorCredentials(token, caCert)
+		log.Infof("Resolved the credentials of the Supervisor Cluster from Secret %s", secretName)
+		err = watchSecret(ctx, k8sClient, secretName, func(secret *v1.Secret) {
+			onSupervisorCredentialsSecretUpdate(ctx, secret)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchSecret calls onUpdate when the Secret is added or updated, and on each
+// resync of the listener, until ctx is done. It is a no-op if the Secret is
+// already watched.
+func watchSecret(ctx context.Context, k8sClient clientset.Interface, secretName types.NamespacedName,
+	onUpdate func(secret *v1.Secret)) error {
+	log := logger.GetLogger(ctx)
+	watchedSecretsLock.Lock()
+	defer watchedSecretsLock.Unlock()
+	if watchedSecrets[secretName] {
+		return nil
+	}
+	err := newSecretListener(ctx, k8sClient, secretName.Namespace, secretName.Name,
+		func(obj interface{}) {
+			if secret, ok := obj.(*v1.Secret); ok {
+				onUpdate(secret)
+			}
+		},
+		func(oldObj interface{}, newObj interface{}) {
+			if secret, ok := newObj.(*v1.Secret); ok {
+				onUpdate(secret)
+			}
+		},
+		func(obj interface{}) {
+			log.Warnf("Credentials Secret %s was deleted. The current credentials are kept.", secretName)
+		})
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to watch the credentials Secret %s. Err: %v", secretName, err)
+	}
+	watchedSecrets[secretName] = true
+	go func() {
+		// The listener is stopped with ctx, so that the Secret can be watched
+		// again by the next call to StartCredentialsWatcher.
+		<-ctx.Done()
+		watchedSecretsLock.Lock()
+		defer watchedSecretsLock.Unlock()
+		delete(watchedSecrets, secretName)
+	}()
+	return nil
+}
+
+// onVCenterCredentialsSecretUpdate updates the credentials of the vCenter
+// hosts held by the Secret. The sessions of the vCenters whose credentials
+// changed are logged in again with the new credentials.
+func onVCenterCredentialsSecretUpdate(ctx context.Context, hosts []string, secret *v1.Secret) {
+	log := logger.GetLogger(ctx)
+	for _, host := range hosts {
+		user, password, err := cnsconfig.GetVCenterCredentialsFromSecretData(host, secret.Data)
+		if err != nil {
+			log.Errorf("failed to read the credentials of vCenter %q from Secret %s/%s. "+
+				"The current credentials are kept. Err: %v", host, secret.Namespace, secret.Name, err)
+			continue
+		}
+		if currentUser, currentPassword, ok := cnsconfig.GetVCenterCredentials(host); !ok ||
+			currentUser != user || currentPassword != password {
+			log.Infof("Credentials of vCenter %q were updated in Secret %s/%s", host, secret.Namespace, secret.Name)
+			cnsconfig.SetVCenterCredentials(host, user, password)
+		}
+		// The credentials of the session are compared by UpdateCredentials, so
+		// that a failed login is retried on the next resync of the listener.
+		if err = updateVirtualCenterCredentials(ctx, host, user, password); err != nil {
+			log.Errorf("failed to update the session of vCenter %q with the credentials of Secret %s/%s. Err: %v",
+				host, secret.Namespace, secret.Name, err)
+		}
+	}
+}
+
+// onSupervisorCredentialsSecretUpdate updates the token and CA certificate
+// used to access the Supervisor Cluster.
+func onSupervisorCredentialsSecretUpdate(ctx context.Context, secret *v1.Secret) {
+	log := logger.GetLogger(ctx)
+	token, caCert, err := cnsconfig.GetSupervisorCredentialsFromSecretData(secret.Data)
+	if err != nil {
+		log.Errorf("failed to read the credentials of the Supervisor Cluster from Secret %s/%s. "+
+			"The current credentials are kept. Err: %v", secret.Namespace, secret.Name, err)
+		return
+	}
+	if currentToken, _, _ := cnsconfig.GetSupervisorCredentials(); currentToken != token {
+		log.Infof("Token of the Supervisor Cluster was updated in Secret %s/%s", secret.Namespace, secret.Name)
+	}
+	cnsconfig.SetSupervisorCredentials(token, caCert)
+}
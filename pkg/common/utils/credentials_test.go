@@ -0,0 +1,165 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+)
+
+func TestStartCredentialsWatcher(t *testing.T) {
+	ctx := context.Background()
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vcenter-credentials", Namespace: "vmware-system-csi"},
+		Data: map[string][]byte{
+			"username":                 []byte("administrator@vsphere.local"),
+			"password":                 []byte("pass"),
+			"vc2.example.com.username": []byte("csi@vsphere.local"),
+			"vc2.example.com.password": []byte("pass2"),
+		},
+	}
+	k8sClient := k8sfake.NewSimpleClientset(secret)
+	cfg := &cnsconfig.Config{
+		VirtualCenter: map[string]*cnsconfig.VirtualCenterConfig{
+			"vc1.example.com": {SecretName: "vcenter-credentials", SecretNamespace: "vmware-system-csi"},
+			"vc2.example.com": {SecretName: "vcenter-credentials", SecretNamespace: "vmware-system-csi"},
+			"vc3.example.com": {User: "user@vsphere.local", Password: "pass3"},
+		},
+	}
+
+	origNewSecretListener, origUpdateVirtualCenterCredentials := newSecretListener, updateVirtualCenterCredentials
+	defer func() {
+		newSecretListener = origNewSecretListener
+		updateVirtualCenterCredentials = origUpdateVirtualCenterCredentials
+	}()
+	var onUpdate func(oldObj interface{}, newObj interface{})
+	listeners := 0
+	newSecretListener = func(ctx context.Context, client clientset.Interface, namespace string, name string,
+		add func(obj interface{}), update func(oldObj, newObj interface{}), remove func(obj interface{})) error {
+		listeners++
+		onUpdate = update
+		return nil
+	}
+	updated := make(map[string]string)
+	updateVirtualCenterCredentials = func(ctx context.Context, host string, username string, password string) error {
+		updated[host] = username + ":" + password
+		return nil
+	}
+
+	if err := startCredentialsWatcher(ctx, k8sClient, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if listeners != 1 {
+		t.Fatalf("expected a single listener on the Secret, got %d", listeners)
+	}
+	expected := map[string]string{
+		"vc1.example.com": "administrator@vsphere.local:pass",
+		"vc2.example.com": "csi@vsphere.local:pass2",
+		"vc3.example.com": "user@vsphere.local:pass3",
+	}
+	for host, creds := range expected {
+		vcConfig := cfg.VirtualCenter[host]
+		if vcConfig.User+":"+vcConfig.Password != creds {
+			t.Errorf("unexpected credentials for vCenter %s: user %q", host, vcConfig.User)
+		}
+	}
+	if user, _, ok := cnsconfig.GetVCenterCredentials("vc2.example.com"); !ok || user != "csi@vsphere.local" {
+		t.Errorf("expected the credentials of vCenter vc2.example.com to be recorded, got user %q", user)
+	}
+
+	// Rotate the password of vc2.example.com.
+	rotated := secret.DeepCopy()
+	rotated.Data["vc2.example.com.password"] = []byte("rotated")
+	onUpdate(secret, rotated)
+	if updated["vc2.example.com"] != "csi@vsphere.local:rotated" {
+		t.Errorf("expected the session of vCenter vc2.example.com to be updated, got %q",
+			updated["vc2.example.com"])
+	}
+	if _, password, _ := cnsconfig.GetVCenterCredentials("vc2.example.com"); password != "rotated" {
+		t.Errorf("expected the rotated password of vCenter vc2.example.com to be recorded")
+	}
+	if _, ok := updated["vc3.example.com"]; ok {
+		t.Errorf("expected the session of vCenter vc3.example.com not to be updated")
+	}
+
+	// Invalid credentials are ignored.
+	invalid := rotated.DeepCopy()
+	invalid.Data["vc2.example.com.username"] = []byte("csi")
+	delete(updated, "vc2.example.com")
+	onUpdate(rotated, invalid)
+	if _, ok := updated["vc2.example.com"]; ok {
+		t.Errorf("expected the invalid credentials of vCenter vc2.example.com to be ignored")
+	}
+}
+
+func TestWatchSecretStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	secretName := types.NamespacedName{Namespace: "vmware-system-csi", Name: "rotated-credentials"}
+
+	origNewSecretListener := newSecretListener
+	defer func() {
+		newSecretListener = origNewSecretListener
+	}()
+	listeners := 0
+	newSecretListener = func(ctx context.Context, client clientset.Interface, namespace string, name string,
+		add func(obj interface{}), update func(oldObj, newObj interface{}), remove func(obj interface{})) error {
+		listeners++
+		return nil
+	}
+
+	k8sClient := k8sfake.NewSimpleClientset()
+	for i := 0; i < 2; i++ {
+		if err := watchSecret(ctx, k8sClient, secretName, func(secret *v1.Secret) {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if listeners != 1 {
+		t.Fatalf("expected a single listener on the Secret, got %d", listeners)
+	}
+
+	// Once ctx is done, the Secret can be watched again.
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		watchedSecretsLock.Lock()
+		watched := watchedSecrets[secretName]
+		watchedSecretsLock.Unlock()
+		if !watched {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected Secret %s not to be watched after ctx is done", secretName)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := watchSecret(context.Background(), k8sClient, secretName, func(secret *v1.Secret) {}); err != nil {
+		t.Fatal(err)
+	}
+	if listeners != 2 {
+		t.Fatalf("expected the Secret to be watched again, got %d listeners", listeners)
+	}
+}
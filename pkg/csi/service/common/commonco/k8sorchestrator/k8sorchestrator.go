@@ -406,7 +406,8 @@ func Newk8sOrchestrator(ctx context.Context, controllerClusterFlavor cnstypes.Cn
 
 	if (controllerClusterFlavor == cnstypes.CnsClusterFlavorWorkload ||
 		(controllerClusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
-			k8sOrchestratorInstance.IsFSSEnabled(ctx, common.ListVolumes))) &&
+			(k8sOrchestratorInstance.IsFSSEnabled(ctx, common.ListVolumes) ||
+				k8sOrchestratorInstance.IsFSSEnabled(ctx, common.StaticVolumeRegistration)))) &&
 		(operationMode != operationModeWebHookServer) {
 		err := initVolumeHandleToPvcMap(ctx, controllerClusterFlavor)
 		if err != nil {
@@ -22,6 +22,7 @@ import (
 	"strings"
 	"time"
 
+	cnstypes "github.com/vmware/govmomi/cns/types"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 
@@ -111,7 +112,13 @@ func isFileVolume(ctx context.Context, pv *v1.PersistentVolume) bool {
 		return false
 	}
 
-	if k8sOrchestratorInstance.IsFSSEnabled(ctx, common.SharedDiskFss) &&
+	// Shared disks on the supervisor and multi-writer raw block volumes on
+	// vanilla clusters are block volumes.
+	isMultiNodeBlockVolumeEnabled := k8sOrchestratorInstance.IsFSSEnabled(ctx, common.SharedDiskFss)
+	if k8sOrchestratorInstance.clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		isMultiNodeBlockVolumeEnabled = k8sOrchestratorInstance.IsFSSEnabled(ctx, common.MultiWriterBlockVolume)
+	}
+	if isMultiNodeBlockVolumeEnabled &&
 		*pv.Spec.VolumeMode == v1.PersistentVolumeBlock {
 		// If volumeMode is block, then volume is Block volume.
 		return false
@@ -795,6 +795,26 @@ func (volTopology *nodeVolumeTopology) GetNodeTopologyLabels(ctx context.Context
 		nodeInfo.NodeName)
 }
 
+// GetNodeAttachableVolumes uses the CSINodeTopology CR to retrieve the number of
+// volumes which can be attached to a node. Call it once GetNodeTopologyLabels succeeded,
+// as the status of the CR is reset until the syncer reconciles it.
+func (volTopology *nodeVolumeTopology) GetNodeAttachableVolumes(ctx context.Context,
+	nodeInfo *commoncotypes.NodeInfo) (int64, error) {
+	log := logger.GetLogger(ctx)
+	csiNodeTopology := &csinodetopologyv1alpha1.CSINodeTopology{}
+	err := volTopology.csiNodeTopologyK8sClient.Get(ctx, types.NamespacedName{Name: nodeInfo.NodeName},
+		csiNodeTopology)
+	if err != nil {
+		return 0, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get CsiNodeTopology for the node: %q. Error: %+v", nodeInfo.NodeName, err)
+	}
+	if csiNodeTopology.Status.Status != csinodetopologyv1alpha1.CSINodeTopologySuccess {
+		return 0, logger.LogNewErrorCodef(log, codes.Internal,
+			"CsiNodeTopology for the node: %q is in %q state", nodeInfo.NodeName, csiNodeTopology.Status.Status)
+	}
+	return csiNodeTopology.Status.AttachableVolumes, nil
+}
+
 func (volTopology *nodeVolumeTopology) updateNodeIDForTopology(
 	ctx context.Context,
 	nodeInfo *commoncotypes.NodeInfo,
@@ -123,4 +123,7 @@ type ControllerTopologyService interface {
 type NodeTopologyService interface {
 	// GetNodeTopologyLabels fetches the topology labels of a NodeVM given the NodeInfo.
 	GetNodeTopologyLabels(ctx context.Context, info *NodeInfo) (map[string]string, error)
+	// GetNodeAttachableVolumes fetches the number of volumes which can be attached to a NodeVM
+	// given the NodeInfo. It returns 0 when the number is unknown.
+	GetNodeAttachableVolumes(ctx context.Context, info *NodeInfo) (int64, error)
 }
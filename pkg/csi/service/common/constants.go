@@ -53,6 +53,51 @@ const (
 	// For Example: StoragePolicy: "vSAN Default Storage Policy".
 	AttributeStoragePolicyName = "storagepolicyname"
 
+	// AttributeRequiredIopsLimit represents the IOPS limit the storage policy
+	// of a volume must already enforce, in the mutable parameters of a
+	// VolumeAttributesClass. It is only validated; the driver never sets it.
+	AttributeRequiredIopsLimit = "requirediopslimit"
+
+	// AttributeNetPermissions represents the net permissions of the file
+	// volumes in the StorageClass, either the comma separated names of
+	// NetPermissions sections of the vSphere config or a JSON list of
+	// net permissions.
+	AttributeNetPermissions = "netpermissions"
+
+	// AttributeNodeEncryption represents the encryption of the block volumes
+	// of the StorageClass by the node plugin. The only supported value is
+	// NodeEncryptionLUKS.
+	AttributeNodeEncryption = "nodeencryption"
+
+	// AttributeNodeEncryptionKeySource represents the source of the
+	// passphrase of the block volumes encrypted by the node plugin, either
+	// NodeEncryptionKeySourceSecret, the default, or NodeEncryptionKeySourceKMS.
+	AttributeNodeEncryptionKeySource = "nodeencryptionkeysource"
+
+	// AttributeMkfsOptions represents the options of mkfs used to format the
+	// block volumes of the StorageClass, validated by ParseMkfsOptions.
+	AttributeMkfsOptions = "mkfsoptions"
+
+	// AttributeProjectQuotaDirectories represents the directories of the
+	// block volumes of the StorageClass limited by a project quota, validated
+	// by ParseProjectQuotaDirectories.
+	AttributeProjectQuotaDirectories = "projectquotadirectories"
+
+	// NodeEncryptionLUKS encrypts the volume with LUKS2 on the node.
+	NodeEncryptionLUKS = "luks"
+
+	// NodeEncryptionKeySourceSecret reads the passphrase of the volume from
+	// the NodeEncryptionPassphraseKey key of its node-stage secret.
+	NodeEncryptionKeySourceSecret = "secret"
+
+	// NodeEncryptionKeySourceKMS generates a passphrase for the volume, and
+	// stores it in its LUKS header wrapped by a Kubernetes KMS v2 plugin.
+	NodeEncryptionKeySourceKMS = "kms"
+
+	// NodeEncryptionPassphraseKey is the key of the passphrase of the volume
+	// in its node-stage secret.
+	NodeEncryptionPassphraseKey = "passphrase"
+
 	// AttributeStoragePolicyID represents Storage Policy Id in the Storage Classs.
 	// For Example: StoragePolicyId: "251bce41-cb24-41df-b46b-7c75aed3c4ee".
 	AttributeStoragePolicyID = "storagepolicyid"
@@ -133,6 +178,9 @@ const (
 	// XFSType represents the xfs filesystem type for block volume.
 	XFSType = "xfs"
 
+	// BtrfsFsType represents the btrfs filesystem type for block volume.
+	BtrfsFsType = "btrfs"
+
 	// NfsV4FsType represents nfs4 mount type.
 	NfsV4FsType = "nfs4"
 
@@ -328,6 +376,17 @@ const (
 	// if inaccessible PV can be fake attached.
 	AnnIgnoreInaccessiblePV = "pv.attach.kubernetes.io/ignore-if-inaccessible"
 
+	// AnnNetPermissions is the annotation key on a file volume claim holding
+	// its net permissions, in the format of AttributeNetPermissions. It takes
+	// precedence over the net permissions of the StorageClass.
+	AnnNetPermissions = "csi.vsphere.vmware.com/net-permissions"
+
+	// AnnProjectQuotaDirectories is the annotation key on a block volume claim
+	// holding its project quota directories, in the format of
+	// AttributeProjectQuotaDirectories. It takes precedence over the project
+	// quota directories of the StorageClass.
+	AnnProjectQuotaDirectories = "csi.vsphere.vmware.com/project-quota-directories"
+
 	// TriggerCsiFullSyncCRName is the instance name of TriggerCsiFullSync
 	// All other names will be rejected by TriggerCsiFullSync controller.
 	TriggerCsiFullSyncCRName = "csifullsync"
@@ -701,6 +760,83 @@ const (
 	// operator. Requires both this flag in the guest PVCSI ConfigMap and the
 	// DataProtectionSnapshotService WCP capability on the Supervisor.
 	VKSRegisterVolume = "vks-register-volume"
+
+	// StorageCapacityTracking is the vanilla FSS that gates the GetCapacity
+	// controller RPC used by external-provisioner's storage capacity tracking.
+	StorageCapacityTracking = "storage-capacity-tracking"
+
+	// BlockVolumeClone is the vanilla FSS that enables creating block volumes
+	// from an existing volume (PVC dataSource) through an implicit snapshot.
+	BlockVolumeClone = "block-volume-clone"
+
+	// ChangedBlockTracking is the vanilla FSS that enables Changed Block Tracking
+	// on block volumes and serves the CSI SnapshotMetadata service
+	// (GetMetadataAllocated and GetMetadataDelta RPCs).
+	ChangedBlockTracking = "changed-block-tracking"
+
+	// VolumeAttributesClass is the vanilla FSS that enables the ControllerModifyVolume
+	// RPC, which applies the mutable parameters of a VolumeAttributesClass to a block volume.
+	VolumeAttributesClass = "volume-attributes-class"
+
+	// VolumeCondition is the vanilla FSS that enables the ControllerGetVolume RPC,
+	// which reports the published nodes and the condition of a volume.
+	VolumeCondition = "volume-condition"
+
+	// VolumeGroupSnapshot is the vanilla FSS that enables the CSI GroupController
+	// service, which takes crash-consistent snapshots of a group of block volumes.
+	VolumeGroupSnapshot = "volume-group-snapshot"
+
+	// MultiWriterBlockVolume is the vanilla FSS that enables raw block volumes with
+	// the MULTI_NODE_MULTI_WRITER access mode, attached to several node VMs at once.
+	MultiWriterBlockVolume = "multi-writer-block-volume"
+
+	// AttachableVolumesFromInventory is the vanilla FSS that computes MaxVolumesPerNode
+	// in NodeGetInfo from the PVSCSI and NVMe controllers of the node VM.
+	AttachableVolumesFromInventory = "attachable-volumes-from-inventory"
+
+	// MetadataSyncQueue is the vanilla FSS that queues the volume metadata updates
+	// of the syncer, instead of calling CNS from the informer callbacks.
+	MetadataSyncQueue = "metadata-sync-queue"
+
+	// StaticVolumeRegistration is the vanilla FSS that enables the CnsRegisterVolume
+	// and CnsUnregisterVolume controllers.
+	StaticVolumeRegistration = "static-volume-registration"
+
+	// BulkVolumeImport is the vanilla FSS that enables the CnsVolumeImport
+	// controller, which imports the disks of a datastore as PVs.
+	BulkVolumeImport = "bulk-volume-import"
+
+	// DriftReport is the vanilla FSS that enables the CnsDriftReport produced
+	// by each full sync, and the remediation policies of the drift categories.
+	DriftReport = "drift-report"
+
+	// OrphanGC is the vanilla FSS that enables the garbage collection of the
+	// CNS volumes and snapshots without a Kubernetes counterpart.
+	OrphanGC = "orphan-gc"
+
+	// FileVolumeNetPermissions is the vanilla FSS that enables the net
+	// permissions of file volumes set by the StorageClass and the PVC.
+	FileVolumeNetPermissions = "file-volume-net-permissions"
+
+	// FileVolumeACLTightening is the vanilla FSS that enables the tightening
+	// of the ACL of the file volumes to the IPs of the nodes consuming them.
+	FileVolumeACLTightening = "file-volume-acl-tightening"
+
+	// NodeLUKSEncryption is the vanilla FSS that enables the encryption of
+	// block volumes with LUKS by the node plugin.
+	NodeLUKSEncryption = "node-luks-encryption"
+
+	// BlockVolumeMkfsOptions is the vanilla FSS that enables the mkfs options
+	// of the block volumes set by the StorageClass.
+	BlockVolumeMkfsOptions = "block-volume-mkfs-options"
+
+	// BtrfsBlockVolume is the vanilla FSS that enables the btrfs filesystem
+	// type for block volumes.
+	BtrfsBlockVolume = "btrfs-block-volume"
+
+	// BlockVolumeProjectQuota is the vanilla FSS that enables the project
+	// quota directories of block volumes.
+	BlockVolumeProjectQuota = "block-volume-project-quota"
 )
 
 var WCPFeatureStates = map[string]struct{}{
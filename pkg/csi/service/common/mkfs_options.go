@@ -0,0 +1,205 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// mkfsOptionValidator validates the value of an option of mkfs.
+type mkfsOptionValidator func(value string) error
+
+// extMkfsOptions are the options of mkfs.ext3 and mkfs.ext4 allowed in the
+// mkfsoptions parameter of a StorageClass. The options destroying data or
+// weakening the file system, such as -S or -O ^has_journal, are left out.
+var extMkfsOptions = map[string]mkfsOptionValidator{
+	"-b": mkfsOneOf("1024", "2048", "4096"),
+	"-i": mkfsIntRange(1024, 67108864),
+	"-I": mkfsOneOf("128", "256", "512", "1024"),
+	"-m": mkfsPercentage(50),
+	"-N": mkfsIntRange(1, math.MaxUint32),
+	"-T": mkfsOneOf("small", "floppy", "news", "largefile", "largefile4", "big", "huge"),
+	"-E": mkfsSubOptions(map[string]mkfsOptionValidator{
+		"lazy_itable_init":  mkfsOneOf("0", "1"),
+		"lazy_journal_init": mkfsOneOf("0", "1"),
+		"stride":            mkfsIntRange(1, math.MaxUint32),
+		"stripe_width":      mkfsIntRange(1, math.MaxUint32),
+		"stripe-width":      mkfsIntRange(1, math.MaxUint32),
+		"discard":           nil,
+		"nodiscard":         nil,
+	}),
+}
+
+// xfsMkfsOptions are the options of mkfs.xfs allowed in the mkfsoptions
+// parameter of a StorageClass.
+var xfsMkfsOptions = map[string]mkfsOptionValidator{
+	"-b": mkfsSubOptions(map[string]mkfsOptionValidator{
+		"size": mkfsPowerOfTwo(512, 65536),
+	}),
+	"-m": mkfsSubOptions(map[string]mkfsOptionValidator{
+		"crc":        mkfsOneOf("0", "1"),
+		"reflink":    mkfsOneOf("0", "1"),
+		"finobt":     mkfsOneOf("0", "1"),
+		"rmapbt":     mkfsOneOf("0", "1"),
+		"bigtime":    mkfsOneOf("0", "1"),
+		"inobtcount": mkfsOneOf("0", "1"),
+	}),
+	"-i": mkfsSubOptions(map[string]mkfsOptionValidator{
+		"size":   mkfsPowerOfTwo(256, 2048),
+		"maxpct": mkfsIntRange(0, 100),
+		"sparse": mkfsOneOf("0", "1"),
+		"align":  mkfsOneOf("0", "1"),
+	}),
+	"-d": mkfsSubOptions(map[string]mkfsOptionValidator{
+		"su":      mkfsSize,
+		"sw":      mkfsIntRange(1, 256),
+		"agcount": mkfsIntRange(1, 1<<20),
+	}),
+	"-l": mkfsSubOptions(map[string]mkfsOptionValidator{
+		"size":       mkfsSize,
+		"lazy-count": mkfsOneOf("0", "1"),
+	}),
+	"-n": mkfsSubOptions(map[string]mkfsOptionValidator{
+		"size": mkfsPowerOfTwo(512, 65536),
+	}),
+}
+
+var mkfsSizeRegexp = regexp.MustCompile(`^[1-9][0-9]*[kmg]?$`)
+
+// ParseMkfsOptions validates the mkfsoptions parameter of a StorageClass,
+// whitespace-separated options of mkfs each followed by its value, for the
+// given file system type, and returns the arguments to add to mkfs. Only the
+// options known to be safe for the file system are allowed.
+func ParseMkfsOptions(fsType, options string) ([]string, error) {
+	var allowed map[string]mkfsOptionValidator
+	switch fsType {
+	case Ext4FsType, Ext3FsType:
+		allowed = extMkfsOptions
+	case XFSType:
+		allowed = xfsMkfsOptions
+	default:
+		return nil, fmt.Errorf("mkfs options are not supported for file system type %q", fsType)
+	}
+	args := strings.Fields(options)
+	if len(args)%2 != 0 {
+		return nil, fmt.Errorf("option %q has no value", args[len(args)-1])
+	}
+	xfsFeatures := make(map[string]string)
+	for i := 0; i < len(args); i += 2 {
+		option, value := args[i], args[i+1]
+		validate, ok := allowed[option]
+		if !ok {
+			return nil, fmt.Errorf("option %q is not allowed for file system type %q", option, fsType)
+		}
+		if err := validate(value); err != nil {
+			return nil, fmt.Errorf("invalid value %q of option %q: %v", value, option, err)
+		}
+		if fsType == XFSType && option == "-m" {
+			for _, subOption := range strings.Split(value, ",") {
+				name, subValue, _ := strings.Cut(subOption, "=")
+				xfsFeatures[name] = subValue
+			}
+		}
+	}
+	// The reflink and reverse mapping btrees of xfs require the metadata CRCs.
+	if xfsFeatures["crc"] == "0" && (xfsFeatures["reflink"] == "1" || xfsFeatures["rmapbt"] == "1") {
+		return nil, errors.New("reflink=1 and rmapbt=1 require crc=1")
+	}
+	return args, nil
+}
+
+// mkfsSubOptions returns a validator of comma-separated sub-options, with a
+// value validated by their validator, or without value if it is nil.
+func mkfsSubOptions(subOptions map[string]mkfsOptionValidator) mkfsOptionValidator {
+	return func(value string) error {
+		for _, subOption := range strings.Split(value, ",") {
+			name, subValue, hasValue := strings.Cut(subOption, "=")
+			validate, ok := subOptions[name]
+			if !ok {
+				return fmt.Errorf("sub-option %q is not allowed", name)
+			}
+			if validate == nil {
+				if hasValue {
+					return fmt.Errorf("sub-option %q takes no value", name)
+				}
+				continue
+			}
+			if err := validate(subValue); err != nil {
+				return fmt.Errorf("sub-option %q: %v", name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// mkfsOneOf returns a validator of values among the given ones.
+func mkfsOneOf(values ...string) mkfsOptionValidator {
+	return func(value string) error {
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected one of %v", values)
+	}
+}
+
+// mkfsIntRange returns a validator of integers between low and high.
+func mkfsIntRange(low, high int64) mkfsOptionValidator {
+	return func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < low || n > high {
+			return fmt.Errorf("expected an integer between %d and %d", low, high)
+		}
+		return nil
+	}
+}
+
+// mkfsPowerOfTwo returns a validator of powers of two between low and high.
+func mkfsPowerOfTwo(low, high int64) mkfsOptionValidator {
+	return func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < low || n > high || n&(n-1) != 0 {
+			return fmt.Errorf("expected a power of two between %d and %d", low, high)
+		}
+		return nil
+	}
+}
+
+// mkfsPercentage returns a validator of percentages up to high.
+func mkfsPercentage(high float64) mkfsOptionValidator {
+	return func(value string) error {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || n < 0 || n > high {
+			return fmt.Errorf("expected a percentage between 0 and %v", high)
+		}
+		return nil
+	}
+}
+
+// mkfsSize validates a size in bytes, with an optional k, m or g unit.
+func mkfsSize(value string) error {
+	if !mkfsSizeRegexp.MatchString(value) {
+		return errors.New("expected a size such as 64k")
+	}
+	return nil
+}
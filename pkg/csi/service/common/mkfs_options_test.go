@@ -0,0 +1,136 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMkfsOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		fsType   string
+		options  string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "ext4 inode ratio and reserved blocks",
+			fsType:   Ext4FsType,
+			options:  "-i 65536  -m 0.5",
+			expected: []string{"-i", "65536", "-m", "0.5"},
+		},
+		{
+			name:     "ext4 extended options",
+			fsType:   Ext4FsType,
+			options:  "-E lazy_itable_init=0,lazy_journal_init=0,nodiscard",
+			expected: []string{"-E", "lazy_itable_init=0,lazy_journal_init=0,nodiscard"},
+		},
+		{
+			name:     "xfs reflink and inode size",
+			fsType:   XFSType,
+			options:  "-m reflink=1,crc=1 -i size=512 -d su=64k,sw=4",
+			expected: []string{"-m", "reflink=1,crc=1", "-i", "size=512", "-d", "su=64k,sw=4"},
+		},
+		{
+			name:    "empty options",
+			fsType:  XFSType,
+			options: " ",
+		},
+		{
+			name:    "force is not allowed",
+			fsType:  XFSType,
+			options: "-f 1",
+			wantErr: true,
+		},
+		{
+			name:    "ext4 superblock only is not allowed",
+			fsType:  Ext4FsType,
+			options: "-S 1",
+			wantErr: true,
+		},
+		{
+			name:    "ext4 features are not allowed",
+			fsType:  Ext4FsType,
+			options: "-O ^has_journal",
+			wantErr: true,
+		},
+		{
+			name:    "option without value",
+			fsType:  Ext4FsType,
+			options: "-i",
+			wantErr: true,
+		},
+		{
+			name:    "reserved blocks out of range",
+			fsType:  Ext4FsType,
+			options: "-m 80",
+			wantErr: true,
+		},
+		{
+			name:    "unknown extended option",
+			fsType:  Ext4FsType,
+			options: "-E root_owner=0:0",
+			wantErr: true,
+		},
+		{
+			name:    "flag sub-option with a value",
+			fsType:  Ext4FsType,
+			options: "-E discard=1",
+			wantErr: true,
+		},
+		{
+			name:    "xfs inode size not a power of two",
+			fsType:  XFSType,
+			options: "-i size=384",
+			wantErr: true,
+		},
+		{
+			name:    "xfs reflink without crc",
+			fsType:  XFSType,
+			options: "-m crc=0 -m reflink=1",
+			wantErr: true,
+		},
+		{
+			name:    "ntfs",
+			fsType:  NTFSFsType,
+			options: "-i 65536",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := ParseMkfsOptions(tt.fsType, tt.options)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected %q to be refused for %s, got %v", tt.options, tt.fsType, args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.expected) == 0 && len(args) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(args, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, args)
+			}
+		})
+	}
+}
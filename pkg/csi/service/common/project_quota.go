@@ -0,0 +1,106 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// MaxProjectQuotaDirectories is the maximum number of project quota
+// directories of a volume.
+const MaxProjectQuotaDirectories = 64
+
+var projectQuotaDirectoryNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,62}$`)
+
+// ProjectQuotaDirectory is a directory at the root of a block volume whose
+// usage is limited by a project quota.
+type ProjectQuotaDirectory struct {
+	// Name is the name of the directory.
+	Name string
+	// ProjectID is the ID of the project of the directory, unique in the
+	// file system of the volume.
+	ProjectID uint32
+	// LimitBytes is the hard limit of the usage of the directory.
+	LimitBytes int64
+}
+
+// ParseProjectQuotaDirectories validates the projectquotadirectories
+// parameter of a StorageClass, comma-separated directories each followed by
+// the quantity of its limit, such as "data=10Gi,logs=1Gi", and returns the
+// directories sorted by name. The directories are given the project IDs 1 to
+// N in that order, so that the same value always gives the same projects.
+func ParseProjectQuotaDirectories(value string) ([]ProjectQuotaDirectory, error) {
+	var dirs []ProjectQuotaDirectory
+	names := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, limit, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("directory %q has no limit", entry)
+		}
+		name, limit = strings.TrimSpace(name), strings.TrimSpace(limit)
+		if !projectQuotaDirectoryNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid directory name %q: expected up to 63 letters, digits, '.', '_' "+
+				"or '-', starting with a letter or a digit", name)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate directory %q", name)
+		}
+		names[name] = true
+		quantity, err := resource.ParseQuantity(limit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit %q of directory %q: %v", limit, name, err)
+		}
+		limitBytes, ok := quantity.AsInt64()
+		if !ok || limitBytes <= 0 {
+			return nil, fmt.Errorf("invalid limit %q of directory %q: expected a positive number of bytes",
+				limit, name)
+		}
+		dirs = append(dirs, ProjectQuotaDirectory{Name: name, LimitBytes: limitBytes})
+	}
+	if len(dirs) == 0 {
+		return nil, errors.New("no directory")
+	}
+	if len(dirs) > MaxProjectQuotaDirectories {
+		return nil, fmt.Errorf("%d directories, expected at most %d", len(dirs), MaxProjectQuotaDirectories)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name < dirs[j].Name })
+	for i := range dirs {
+		dirs[i].ProjectID = uint32(i + 1)
+	}
+	return dirs, nil
+}
+
+// FormatProjectQuotaDirectories returns the projectquotadirectories volume
+// attribute of the given directories, with their limits in bytes.
+func FormatProjectQuotaDirectories(dirs []ProjectQuotaDirectory) string {
+	entries := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		entries = append(entries, dir.Name+"="+strconv.FormatInt(dir.LimitBytes, 10))
+	}
+	return strings.Join(entries, ",")
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProjectQuotaDirectories(t *testing.T) {
+	tooMany := make([]string, MaxProjectQuotaDirectories+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("dir%d=1Mi", i)
+	}
+	tests := []struct {
+		name     string
+		value    string
+		expected []ProjectQuotaDirectory
+		wantErr  bool
+	}{
+		{
+			name:  "directories are sorted by name",
+			value: "logs=1Gi, data=10Gi",
+			expected: []ProjectQuotaDirectory{
+				{Name: "data", ProjectID: 1, LimitBytes: 10 << 30},
+				{Name: "logs", ProjectID: 2, LimitBytes: 1 << 30},
+			},
+		},
+		{
+			name:  "limits in bytes",
+			value: "data=10737418240,logs=1073741824",
+			expected: []ProjectQuotaDirectory{
+				{Name: "data", ProjectID: 1, LimitBytes: 10 << 30},
+				{Name: "logs", ProjectID: 2, LimitBytes: 1 << 30},
+			},
+		},
+		{
+			name:    "no directory",
+			value:   " , ",
+			wantErr: true,
+		},
+		{
+			name:    "no limit",
+			value:   "data",
+			wantErr: true,
+		},
+		{
+			name:    "invalid limit",
+			value:   "data=ten",
+			wantErr: true,
+		},
+		{
+			name:    "zero limit",
+			value:   "data=0",
+			wantErr: true,
+		},
+		{
+			name:    "path is not allowed",
+			value:   "data/db=1Gi",
+			wantErr: true,
+		},
+		{
+			name:    "parent is not allowed",
+			value:   "..=1Gi",
+			wantErr: true,
+		},
+		{
+			name:    "lost+found is not allowed",
+			value:   "lost+found=1Gi",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate directory",
+			value:   "data=1Gi,data=2Gi",
+			wantErr: true,
+		},
+		{
+			name:    "too many directories",
+			value:   strings.Join(tooMany, ","),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dirs, err := ParseProjectQuotaDirectories(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected %q to be refused, got %v", tt.value, dirs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(dirs, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, dirs)
+			}
+			// The volume attribute gives the same directories.
+			attribute := FormatProjectQuotaDirectories(dirs)
+			parsed, err := ParseProjectQuotaDirectories(attribute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(parsed, dirs) {
+				t.Fatalf("expected %v from %q, got %v", dirs, attribute, parsed)
+			}
+		})
+	}
+}
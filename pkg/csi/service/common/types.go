@@ -46,6 +46,14 @@ var (
 		csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
 	}
 
+	// MultiWriterBlockVolumeCaps represents how the multi-writer raw block
+	// volume could be accessed. The volume is attached to every node with the
+	// multi-writer sharing mode, so that several nodes can write it at once.
+	MultiWriterBlockVolumeCaps = []csi.VolumeCapability_AccessMode_Mode{
+		csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+	}
+
 	// ErrNotFound represents not found error
 	ErrNotFound = errors.New("not found")
 )
@@ -90,6 +98,9 @@ type CreateVolumeSpec struct {
 	// policy provisioning. CNS selects the final host from this set and returns it in the
 	// placement result. Empty for non-host-local volumes.
 	Hosts []vim25types.ManagedObjectReference
+	// NetPermissions holds the net permissions of a file volume. The
+	// NetPermissions of the vSphere config are used when it is nil.
+	NetPermissions []*config.NetPermissionConfig
 }
 
 // StorageClassParams represents the storage class parameterss
@@ -98,6 +109,16 @@ type StorageClassParams struct {
 	StoragePolicyName string
 	CSIMigration      string
 	Datastore         string
+	NetPermissions    string
+	// NodeEncryption and NodeEncryptionKeySource hold the encryption of a
+	// block volume by the node plugin.
+	NodeEncryption          string
+	NodeEncryptionKeySource string
+	// MkfsOptions holds the options of mkfs used to format a block volume.
+	MkfsOptions string
+	// ProjectQuotaDirectories holds the directories of a block volume
+	// limited by a project quota.
+	ProjectQuotaDirectories string
 }
 
 type CryptoKeyID struct {
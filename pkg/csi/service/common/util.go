@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -174,6 +175,35 @@ func IsFileVolumeRequest(ctx context.Context, capabilities []*csi.VolumeCapabili
 	return false
 }
 
+// IsMultiWriterBlockVolumeRequest checks whether the request is for a raw
+// block volume written by multiple nodes.
+func IsMultiWriterBlockVolumeRequest(ctx context.Context, capabilities []*csi.VolumeCapability) bool {
+	for _, capability := range capabilities {
+		if capability.GetAccessMode().GetMode() == csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER &&
+			capability.GetBlock() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidMultiWriterBlockVolumeCapabilities validates the given volume
+// capabilities of a multi-writer raw block volume. File systems are refused,
+// as none of the supported file systems can be mounted by several nodes.
+func IsValidMultiWriterBlockVolumeCapabilities(ctx context.Context, volCaps []*csi.VolumeCapability) error {
+	for _, volCap := range volCaps {
+		if !slices.Contains(MultiWriterBlockVolumeCaps, volCap.GetAccessMode().GetMode()) {
+			return fmt.Errorf("%s access mode is not supported for multi-writer %q volumes",
+				csi.VolumeCapability_AccessMode_Mode_name[int32(volCap.GetAccessMode().GetMode())], BlockVolumeType)
+		}
+		if volCap.GetBlock() == nil {
+			return fmt.Errorf("filesystem volume mode is not supported for multi-writer %q volumes",
+				BlockVolumeType)
+		}
+	}
+	return nil
+}
+
 // IsVolumeReadOnly checks the access mode in Volume Capability and decides
 // if volume is readonly or not.
 func IsVolumeReadOnly(capability *csi.VolumeCapability) bool {
@@ -206,9 +236,10 @@ func validateVolumeCapabilities(volCaps []*csi.VolumeCapability,
 
 		if volCap.AccessMode.Mode == csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER {
 			// For ReadWriteOnce access mode we only support following filesystems:
-			// ext3, ext4, xfs for Linux and ntfs for Windows.
+			// ext3, ext4, xfs, btrfs for Linux and ntfs for Windows.
 			if volCap.GetMount() != nil && !(volCap.GetMount().FsType == Ext4FsType ||
 				volCap.GetMount().FsType == Ext3FsType || volCap.GetMount().FsType == XFSType ||
+				volCap.GetMount().FsType == BtrfsFsType ||
 				strings.ToLower(volCap.GetMount().FsType) == NTFSFsType || volCap.GetMount().FsType == "") {
 				return fmt.Errorf("fstype %s not supported for ReadWriteOnce volume creation",
 					volCap.GetMount().FsType)
@@ -262,6 +293,20 @@ func ParseStorageClassParams(ctx context.Context, params map[string]string) (*St
 			log.Warnf("param 'fstype' is deprecated, please use 'csi.storage.k8s.io/fstype' instead")
 		} else if param == CSIMigrationParams {
 			scParams.CSIMigration = value
+		} else if param == AttributeNetPermissions {
+			scParams.NetPermissions = value
+		} else if param == AttributeNodeEncryption {
+			scParams.NodeEncryption = value
+		} else if param == AttributeNodeEncryptionKeySource {
+			scParams.NodeEncryptionKeySource = value
+		} else if param == AttributeMkfsOptions {
+			scParams.MkfsOptions = value
+		} else if param == AttributeProjectQuotaDirectories {
+			scParams.ProjectQuotaDirectories = value
+		} else if param == AttributePvName || param == AttributePvcName || param == AttributePvcNamespace {
+			// The PV and PVC names added by the --extra-create-metadata flag of
+			// external-provisioner are not StorageClass parameters.
+			continue
 		} else {
 			otherParams[param] = value
 		}
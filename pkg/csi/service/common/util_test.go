@@ -193,6 +193,67 @@ func TestValidVolumeCapabilitiesForBlock(t *testing.T) {
 	}
 }
 
+func TestMultiWriterBlockVolumeCapabilities(t *testing.T) {
+	blockCap := func(mode csi.VolumeCapability_AccessMode_Mode) *csi.VolumeCapability {
+		return &csi.VolumeCapability{
+			AccessType: &csi.VolumeCapability_Block{
+				Block: &csi.VolumeCapability_BlockVolume{},
+			},
+			AccessMode: &csi.VolumeCapability_AccessMode{
+				Mode: mode,
+			},
+		}
+	}
+	mountCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{
+				FsType: "ext4",
+			},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+		},
+	}
+	multiWriterCap := blockCap(csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER)
+
+	if !IsMultiWriterBlockVolumeRequest(ctx, []*csi.VolumeCapability{multiWriterCap}) {
+		t.Errorf("VolCap = %+v should be a multi-writer block volume request", multiWriterCap)
+	}
+	if IsMultiWriterBlockVolumeRequest(ctx, []*csi.VolumeCapability{mountCap}) {
+		t.Errorf("VolCap = %+v should not be a multi-writer block volume request", mountCap)
+	}
+	if IsMultiWriterBlockVolumeRequest(ctx,
+		[]*csi.VolumeCapability{blockCap(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER)}) {
+		t.Errorf("single node writer block VolCap should not be a multi-writer block volume request")
+	}
+
+	validCaps := [][]*csi.VolumeCapability{
+		{multiWriterCap},
+		{multiWriterCap, blockCap(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER)},
+	}
+	for _, volCap := range validCaps {
+		if err := IsValidMultiWriterBlockVolumeCapabilities(ctx, volCap); err != nil {
+			t.Errorf("Multi-writer block VolCap = %+v failed validation: %v", volCap, err)
+		}
+	}
+	invalidCaps := [][]*csi.VolumeCapability{
+		// Filesystems cannot be shared by several nodes.
+		{multiWriterCap, mountCap},
+		{multiWriterCap, blockCap(csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY)},
+		{multiWriterCap, blockCap(csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER)},
+	}
+	for _, volCap := range invalidCaps {
+		if err := IsValidMultiWriterBlockVolumeCapabilities(ctx, volCap); err == nil {
+			t.Errorf("Invalid multi-writer block VolCap = %+v passed validation!", volCap)
+		}
+	}
+	// Multi-writer block volumes are still refused by the validation of
+	// regular volumes.
+	if err := IsValidVolumeCapabilities(ctx, []*csi.VolumeCapability{multiWriterCap}); err == nil {
+		t.Errorf("Multi-writer block VolCap = %+v passed validation of regular volumes!", multiWriterCap)
+	}
+}
+
 func TestInvalidVolumeCapabilitiesForBlock(t *testing.T) {
 	// Invalid case: fstype=nfs and mode=SINGLE_NODE_WRITER
 	volCap := []*csi.VolumeCapability{
@@ -641,16 +641,15 @@ func CreateFileVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluster
 		}
 	}
 
-	// Retrieve net permissions from CnsConfig of manager and convert to required
-	// format.
-	netPerms := make([]vsanfstypes.VsanFileShareNetPermission, 0)
-	for _, netPerm := range cnsConfig.NetPermissions {
-		netPerms = append(netPerms, vsanfstypes.VsanFileShareNetPermission{
-			Ips:         netPerm.Ips,
-			Permissions: netPerm.Permissions,
-			AllowRoot:   !netPerm.RootSquash,
-		})
+	// Retrieve net permissions from the spec, or else from CnsConfig of manager,
+	// and convert to required format.
+	netPermConfigs := spec.NetPermissions
+	if netPermConfigs == nil {
+		for _, netPerm := range cnsConfig.NetPermissions {
+			netPermConfigs = append(netPermConfigs, netPerm)
+		}
 	}
+	netPerms := GetVsanFileShareNetPermissions(netPermConfigs)
 
 	clusterID := cnsConfig.Global.ClusterID
 	if useSupervisorId {
@@ -724,6 +723,21 @@ func CreateFileVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluster
 	return volumeInfo, "", nil
 }
 
+// GetVsanFileShareNetPermissions converts the given net permissions to the
+// net permissions of a vSAN file share.
+func GetVsanFileShareNetPermissions(
+	netPermConfigs []*config.NetPermissionConfig) []vsanfstypes.VsanFileShareNetPermission {
+	netPerms := make([]vsanfstypes.VsanFileShareNetPermission, 0, len(netPermConfigs))
+	for _, netPerm := range netPermConfigs {
+		netPerms = append(netPerms, vsanfstypes.VsanFileShareNetPermission{
+			Ips:         netPerm.Ips,
+			Permissions: netPerm.Permissions,
+			AllowRoot:   !netPerm.RootSquash,
+		})
+	}
+	return netPerms
+}
+
 // getHostVsanUUID returns the config.clusterInfo.nodeUuid of the ESX host's
 // HostVsanSystem.
 func getHostVsanUUID(ctx context.Context, hostMoID string, vc *vsphere.VirtualCenter) (string, error) {
@@ -27,6 +27,8 @@ import (
 
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/node"
 	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/tracing"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/utils"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
@@ -140,6 +142,12 @@ func (driver *vsphereCSIDriver) BeforeServe(ctx context.Context) error {
 	}
 
 	if strings.EqualFold(driver.mode, "node") {
+		// The node plugin reads no vSphere config, its tracing is configured
+		// with the standard OTLP environment variables.
+		if err := tracing.Init(ctx, "vsphere-csi-node", Version, nil); err != nil {
+			log.Errorf("failed to initialize tracing. Error: %+v", err)
+			return err
+		}
 		return nil
 	}
 
@@ -149,6 +157,10 @@ func (driver *vsphereCSIDriver) BeforeServe(ctx context.Context) error {
 		log.Errorf("failed to read config. Error: %+v", err)
 		return err
 	}
+	if err := tracing.Init(ctx, "vsphere-csi-controller", Version, &cfg.Tracing); err != nil {
+		log.Errorf("failed to initialize tracing. Error: %+v", err)
+		return err
+	}
 
 	if clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
 		CSINamespace := common.GetCSINamespace()
@@ -194,6 +206,12 @@ func (driver *vsphereCSIDriver) BeforeServe(ctx context.Context) error {
 			}
 		}
 	}
+	// Resolve the credentials referenced by the config from their Secrets
+	// before the sessions are created from it.
+	if err := utils.StartCredentialsWatcher(ctx, cfg); err != nil {
+		log.Errorf("failed to start the credentials watcher. Error: %+v", err)
+		return err
+	}
 	if err := driver.cnscs.Init(cfg, Version); err != nil {
 		log.Errorf("failed to init controller. Error: %+v", err)
 		return err
@@ -214,20 +232,37 @@ func (driver *vsphereCSIDriver) Run(ctx context.Context, endpoint string) {
 	}
 
 	// Determine if SnapshotMetadata service should be registered
-	// The service is only registered in controller mode when CBT feature is enabled for both
-	// Supervisor and guest cluster CSI drivers.
+	// The service is only registered in controller mode when CBT feature is enabled for the
+	// Supervisor, guest or vanilla cluster CSI driver.
 	var snapshotMetadataServer csi.SnapshotMetadataServer
 	if driver.mode == "controller" && commonco.ContainerOrchestratorUtility != nil &&
 		((clusterFlavor == cnstypes.CnsClusterFlavorWorkload &&
 			commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSI_Backup_API)) ||
 			(clusterFlavor == cnstypes.CnsClusterFlavorGuest &&
-				commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSI_Backup_API_FSS))) {
+				commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSI_Backup_API_FSS)) ||
+			(clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+				commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.ChangedBlockTracking))) {
 		// Pass the controller server which implements the SnapshotMetadata RPCs
 		snapshotMetadataServer = controllerServer.(csi.SnapshotMetadataServer)
 		log.Info("SnapshotMetadata service will be registered (CBT support enabled)")
 	}
 
+	// The GroupController service is only registered in controller mode when the
+	// VolumeGroupSnapshot feature is enabled for the vanilla cluster CSI driver.
+	var groupControllerServer csi.GroupControllerServer
+	if driver.mode == "controller" && isGroupControllerServiceEnabled(ctx) {
+		groupControllerServer = controllerServer.(csi.GroupControllerServer)
+		log.Info("GroupController service will be registered (VolumeGroupSnapshot support enabled)")
+	}
+
 	//Start the nonblocking GRPC
 	grpc := NewNonBlockingGRPCServer()
-	grpc.Start(endpoint, driver, controllerServer, driver, snapshotMetadataServer)
+	grpc.Start(endpoint, driver, controllerServer, driver, snapshotMetadataServer, groupControllerServer)
+}
+
+// isGroupControllerServiceEnabled returns true if the GroupController service
+// is served, which is only the case for the vanilla cluster CSI driver.
+func isGroupControllerServiceEnabled(ctx context.Context) bool {
+	return commonco.ContainerOrchestratorUtility != nil && clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeGroupSnapshot)
 }
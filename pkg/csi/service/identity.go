@@ -70,15 +70,17 @@ func (driver *vsphereCSIDriver) GetPluginCapabilities(
 		},
 	}
 
-	// Advertise SnapshotMetadata service for CBT support if CBT feature is enabled for both
-	// Supervisor and guest cluster CSI drivers.
+	// Advertise SnapshotMetadata service for CBT support if CBT feature is enabled for the
+	// Supervisor, guest or vanilla cluster CSI driver.
 	// The SnapshotMetadata service provides GetMetadataAllocated and GetMetadataDelta RPCs
 	// for efficient backup and restore operations (CSI spec v1.10.0+).
 	if commonco.ContainerOrchestratorUtility != nil &&
 		((clusterFlavor == cnstypes.CnsClusterFlavorWorkload &&
 			commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSI_Backup_API)) ||
 			(clusterFlavor == cnstypes.CnsClusterFlavorGuest &&
-				commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSI_Backup_API_FSS))) {
+				commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSI_Backup_API_FSS)) ||
+			(clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+				commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.ChangedBlockTracking))) {
 		caps = append(caps, &csi.PluginCapability{
 			Type: &csi.PluginCapability_Service_{
 				Service: &csi.PluginCapability_Service{
@@ -88,6 +90,17 @@ func (driver *vsphereCSIDriver) GetPluginCapabilities(
 		})
 	}
 
+	// Advertise GroupController service for VolumeGroupSnapshot support.
+	if isGroupControllerServiceEnabled(ctx) {
+		caps = append(caps, &csi.PluginCapability{
+			Type: &csi.PluginCapability_Service_{
+				Service: &csi.PluginCapability_Service{
+					Type: csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE,
+				},
+			},
+		})
+	}
+
 	return &csi.GetPluginCapabilitiesResponse{
 		Capabilities: caps,
 	}, nil
@@ -18,12 +18,15 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	cnstypes "github.com/vmware/govmomi/cns/types"
 	"github.com/vmware/govmomi/units"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
 
 	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
@@ -44,17 +47,97 @@ const (
 	// defaultMaxVolumesPerNodeGuest is the legacy cap for guest cluster nodes.
 	// pvcsi.yaml sets MAX_VOLUMES_PER_NODE=59 for the vsphere-csi-node container.
 	defaultMaxVolumesPerNodeGuest = 59
+
+	// luksKMSEndpointEnv is the environment variable holding the unix socket
+	// of the KMS v2 plugin wrapping the passphrases of the volumes encrypted
+	// with the kms key source.
+	luksKMSEndpointEnv = "LUKS_KMS_ENDPOINT"
 )
 
 var topologyService commoncotypes.NodeTopologyService
 
+// validateNodeVolumeCapabilities validates the given volume capabilities,
+// including the ones of multi-writer raw block volumes.
+func validateNodeVolumeCapabilities(ctx context.Context, caps []*csi.VolumeCapability) error {
+	if common.IsMultiWriterBlockVolumeRequest(ctx, caps) {
+		return common.IsValidMultiWriterBlockVolumeCapabilities(ctx, caps)
+	}
+	return common.IsValidVolumeCapabilities(ctx, caps)
+}
+
+// isFileVolumeOnNode checks whether the volume published with the given
+// capabilities and publish context is a file volume. Multi-writer raw block
+// volumes use a multi-node access mode, but are attached to the node as disks,
+// and cannot be mounted with a file system.
+func isFileVolumeOnNode(ctx context.Context, caps []*csi.VolumeCapability,
+	publishContext map[string]string) (bool, error) {
+	if common.IsMultiWriterBlockVolumeRequest(ctx, caps) || !common.IsFileVolumeRequest(ctx, caps) {
+		return false, nil
+	}
+	if publishContext[common.AttributeDiskType] == common.DiskTypeBlockVolume {
+		return false, fmt.Errorf("filesystem volume mode is not supported for multi-writer %q volumes",
+			common.BlockVolumeType)
+	}
+	return true, nil
+}
+
+// withoutSecrets returns a copy of the request without its secrets, for the
+// request to be logged.
+func withoutSecrets(req proto.Message) proto.Message {
+	clone := proto.Clone(req)
+	switch r := clone.(type) {
+	case *csi.NodeStageVolumeRequest:
+		r.Secrets = nil
+	case *csi.NodeExpandVolumeRequest:
+		r.Secrets = nil
+	}
+	return clone
+}
+
+// getLUKSParams returns the params of the LUKS encryption of the volume
+// staged by req, from its volume context and node-stage secrets.
+func getLUKSParams(ctx context.Context, req *csi.NodeStageVolumeRequest) (*osutils.LUKSParams, error) {
+	log := logger.GetLogger(ctx)
+	volCtx := req.GetVolumeContext()
+	if volCtx[common.AttributeNodeEncryption] != common.NodeEncryptionLUKS {
+		return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"unsupported node encryption %q of volume %q", volCtx[common.AttributeNodeEncryption], req.GetVolumeId())
+	}
+	if req.GetVolumeCapability().GetBlock() != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"node encryption is not supported for raw block volume %q", req.GetVolumeId())
+	}
+	switch volCtx[common.AttributeNodeEncryptionKeySource] {
+	case common.NodeEncryptionKeySourceKMS:
+		endpoint := os.Getenv(luksKMSEndpointEnv)
+		if endpoint == "" {
+			return nil, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+				"volume %q requires a KMS plugin, but %s is not set on the node plugin",
+				req.GetVolumeId(), luksKMSEndpointEnv)
+		}
+		return &osutils.LUKSParams{KMSEndpoint: endpoint}, nil
+	case "", common.NodeEncryptionKeySourceSecret:
+		passphrase := req.GetSecrets()[common.NodeEncryptionPassphraseKey]
+		if passphrase == "" {
+			return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"the node-stage secret of volume %q has no %q key", req.GetVolumeId(),
+				common.NodeEncryptionPassphraseKey)
+		}
+		return &osutils.LUKSParams{Passphrase: []byte(passphrase)}, nil
+	default:
+		return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"unsupported node encryption key source %q of volume %q",
+			volCtx[common.AttributeNodeEncryptionKeySource], req.GetVolumeId())
+	}
+}
+
 func (driver *vsphereCSIDriver) NodeStageVolume(
 	ctx context.Context,
 	req *csi.NodeStageVolumeRequest) (
 	*csi.NodeStageVolumeResponse, error) {
 	ctx = logger.NewContextWithLogger(ctx)
 	log := logger.GetLogger(ctx)
-	log.Infof("NodeStageVolume: called with args %+v", req)
+	log.Infof("NodeStageVolume: called with args %+v", withoutSecrets(req))
 
 	volumeID := req.GetVolumeId()
 	volCap := req.GetVolumeCapability()
@@ -65,7 +148,13 @@ func (driver *vsphereCSIDriver) NodeStageVolume(
 	}
 
 	// Check for block volume or file share.
-	if common.IsFileVolumeRequest(ctx, []*csi.VolumeCapability{volCap}) {
+	caps := []*csi.VolumeCapability{volCap}
+	isFileVolume, err := isFileVolumeOnNode(ctx, caps, req.GetPublishContext())
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"NodeStageVolume failed: volume capability not supported. Err: %+v", err)
+	}
+	if isFileVolume {
 		log.Infof("NodeStageVolume: Volume %q detected as a file share volume. Ignoring staging for file volumes.",
 			volumeID)
 		return &csi.NodeStageVolumeResponse{}, nil
@@ -76,13 +165,11 @@ func (driver *vsphereCSIDriver) NodeStageVolume(
 	}
 	defer driver.volumeLocks.Release(volumeID)
 
-	caps := []*csi.VolumeCapability{volCap}
-	if err := common.IsValidVolumeCapabilities(ctx, caps); err != nil {
+	if err := validateNodeVolumeCapabilities(ctx, caps); err != nil {
 		return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
 			"NodeStageVolume failed: volume capability not supported. Err: %+v", err)
 	}
 
-	var err error
 	params := osutils.NodeStageParams{
 		VolID: volumeID,
 		// Retrieve accessmode - RO/RW.
@@ -100,6 +187,20 @@ func (driver *vsphereCSIDriver) NodeStageVolume(
 		if err != nil {
 			return nil, err
 		}
+		if mkfsOptions := req.GetVolumeContext()[common.AttributeMkfsOptions]; mkfsOptions != "" {
+			params.MkfsOptions, err = common.ParseMkfsOptions(params.FsType, mkfsOptions)
+			if err != nil {
+				return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+					"NodeStageVolume failed: invalid mkfs options of volume %q. Err: %v", volumeID, err)
+			}
+		}
+		if dirs := req.GetVolumeContext()[common.AttributeProjectQuotaDirectories]; dirs != "" {
+			params.ProjectQuotaDirectories, err = common.ParseProjectQuotaDirectories(dirs)
+			if err != nil {
+				return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+					"NodeStageVolume failed: invalid project quota directories of volume %q. Err: %v", volumeID, err)
+			}
+		}
 
 		// Check that staging path is created by CO and is a directory.
 		params.StagingTarget = req.GetStagingTargetPath()
@@ -107,6 +208,12 @@ func (driver *vsphereCSIDriver) NodeStageVolume(
 			return nil, err
 		}
 	}
+	if req.GetVolumeContext()[common.AttributeNodeEncryption] != "" {
+		params.LUKS, err = getLUKSParams(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return driver.osUtils.NodeStageBlockVolume(ctx, req, params)
 }
 
@@ -152,7 +259,7 @@ func (driver *vsphereCSIDriver) NodeUnstageVolume(
 
 	if !targetFound {
 		log.Infof("NodeUnstageVolume: Target path %q is not mounted. Skipping unstage.", stagingTarget)
-		return &csi.NodeUnstageVolumeResponse{}, nil
+		return driver.closeLUKSDevice(ctx, volumeID)
 	}
 
 	volID := req.GetVolumeId()
@@ -163,7 +270,7 @@ func (driver *vsphereCSIDriver) NodeUnstageVolume(
 	// This will take care of idempotent requests.
 	if !dirExists {
 		log.Infof("NodeUnstageVolume: Target path %q does not exist. Assuming unstage is complete.", stagingTarget)
-		return &csi.NodeUnstageVolumeResponse{}, nil
+		return driver.closeLUKSDevice(ctx, volID)
 	}
 
 	if err := driver.osUtils.CleanupStagePath(ctx, stagingTarget, volID); err != nil {
@@ -172,6 +279,17 @@ func (driver *vsphereCSIDriver) NodeUnstageVolume(
 	}
 
 	log.Infof("NodeUnstageVolume successful for target %q for volume %q", stagingTarget, volID)
+	return driver.closeLUKSDevice(ctx, volID)
+}
+
+// closeLUKSDevice closes the LUKS mapping of the unstaged volume, if any, for
+// its disk to be detached.
+func (driver *vsphereCSIDriver) closeLUKSDevice(ctx context.Context,
+	volID string) (*csi.NodeUnstageVolumeResponse, error) {
+	log := logger.GetLogger(ctx)
+	if err := driver.osUtils.CloseLUKSDevice(ctx, volID); err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal, "NodeUnstageVolume failed: %v", err)
+	}
 	return &csi.NodeUnstageVolumeResponse{}, nil
 }
 
@@ -217,13 +335,18 @@ func (driver *vsphereCSIDriver) NodePublishVolume(
 	}
 	defer driver.volumeLocks.Release(volumeID)
 	caps := []*csi.VolumeCapability{volCap}
-	if err := common.IsValidVolumeCapabilities(ctx, caps); err != nil {
+	if err := validateNodeVolumeCapabilities(ctx, caps); err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"NodePublishVolume failed: volume capability not supported. Err: %+v", err)
+	}
+	isFileVolume, err := isFileVolumeOnNode(ctx, caps, req.GetPublishContext())
+	if err != nil {
 		return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
 			"NodePublishVolume failed: volume capability not supported. Err: %+v", err)
 	}
 
 	// Check if this is a MountVolume or BlockVolume.
-	if !common.IsFileVolumeRequest(ctx, caps) {
+	if !isFileVolume {
 		var dev *osutils.Device
 		err = driver.osUtils.VerifyVolumeAttachedAndFillParams(ctx, req.GetPublishContext(), &params, &dev)
 		if err != nil {
@@ -236,7 +359,16 @@ func (driver *vsphereCSIDriver) NodePublishVolume(
 			// bind mount device to target.
 			return driver.osUtils.PublishBlockVol(ctx, req, dev, params)
 		}
-		// Volume must be a mount volume.
+		// Volume must be a mount volume. The file system of an encrypted
+		// volume is on the LUKS mapping of its disk.
+		luksDev, err := driver.osUtils.GetLUKSDevice(ctx, volumeID)
+		if err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"NodePublishVolume failed: error getting LUKS mapping of volume %q: %v", volumeID, err)
+		}
+		if luksDev != nil {
+			dev = luksDev.Mapping
+		}
 		return driver.osUtils.PublishMountVol(ctx, req, dev, params)
 	}
 	// Volume must be a file share.
@@ -329,24 +461,53 @@ func (driver *vsphereCSIDriver) NodeGetVolumeStats(
 	if !ok {
 		log.Warn("failed to fetch used inodes")
 	}
-	return &csi.NodeGetVolumeStatsResponse{
-		Usage: []*csi.VolumeUsage{
-			{
-				Available: available,
-				Total:     capacity,
-				Used:      used,
-				Unit:      csi.VolumeUsage_BYTES,
-			},
-			{
-				Available: inodesFree,
-				Total:     inodes,
-				Used:      inodesUsed,
-				Unit:      csi.VolumeUsage_INODES,
-			},
+	usage := []*csi.VolumeUsage{
+		{
+			Available: available,
+			Total:     capacity,
+			Used:      used,
+			Unit:      csi.VolumeUsage_BYTES,
 		},
+	}
+	// File systems allocating inodes dynamically, such as btrfs, have no
+	// number of inodes to report.
+	if inodes > 0 {
+		usage = append(usage, &csi.VolumeUsage{
+			Available: inodesFree,
+			Total:     inodes,
+			Used:      inodesUsed,
+			Unit:      csi.VolumeUsage_INODES,
+		})
+	}
+	return &csi.NodeGetVolumeStatsResponse{
+		Usage:           usage,
+		VolumeCondition: driver.getVolumeCondition(ctx, volumeID, targetPath),
 	}, nil
 }
 
+// getVolumeCondition returns the condition of the volume published at
+// targetPath. Its message reports the usage and the limit of each project
+// quota directory of the volume, as the usage returned by NodeGetVolumeStats
+// is the one of the whole file system.
+func (driver *vsphereCSIDriver) getVolumeCondition(ctx context.Context,
+	volumeID string, targetPath string) *csi.VolumeCondition {
+	log := logger.GetLogger(ctx)
+	usages, err := driver.osUtils.GetProjectQuotaUsage(ctx, targetPath)
+	if err != nil {
+		log.Warnf("NodeGetVolumeStats: failed to get the project quota usage of volume %q. Err: %v",
+			volumeID, err)
+		return &csi.VolumeCondition{
+			Message: fmt.Sprintf("failed to get the project quota usage: %v", err),
+		}
+	}
+	var projects []string
+	for _, usage := range usages {
+		projects = append(projects, fmt.Sprintf("project %d used %d of %d bytes",
+			usage.ProjectID, usage.UsedBytes, usage.LimitBytes))
+	}
+	return &csi.VolumeCondition{Message: strings.Join(projects, ", ")}
+}
+
 func (driver *vsphereCSIDriver) NodeGetCapabilities(
 	ctx context.Context,
 	req *csi.NodeGetCapabilitiesRequest) (
@@ -375,18 +536,25 @@ func (driver *vsphereCSIDriver) NodeGetCapabilities(
 					},
 				},
 			},
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{
+						Type: csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+					},
+				},
+			},
 		},
 	}, nil
 }
 
 // NodeGetInfo RPC returns the NodeGetInfoResponse with mandatory fields
-// `NodeId` and `AccessibleTopology`. However, for sending `MaxVolumesPerNode`
-// in the response, it is not straight forward since vSphere CSI driver
-// supports both block and file volume. For block volume, max volumes to be
-// attached is deterministic by inspecting SCSI controllers of the VM, but for
-// file volume, this is not deterministic. We can not set this limit on
-// MaxVolumesPerNode, since single driver is used for both block and file
-// volumes.
+// `NodeId` and `AccessibleTopology`. `MaxVolumesPerNode` is the number of
+// block volumes which can be attached to the node VM. On Vanilla clusters with
+// the attachable-volumes-from-inventory FSS enabled, it is computed from the
+// PVSCSI and NVMe controllers of the VM when the node plugin registers, so it
+// is not updated for controllers added to the VM until the plugin restarts.
+// Note that the scheduler also counts the file volumes against this limit,
+// although they are not attached to the VM.
 func (driver *vsphereCSIDriver) NodeGetInfo(
 	ctx context.Context,
 	req *csi.NodeGetInfoRequest) (
@@ -488,6 +656,10 @@ func (driver *vsphereCSIDriver) NodeGetInfo(
 			NodeID:   nodeID,
 		}
 		accessibleTopology, err = topologyService.GetNodeTopologyLabels(ctx, &nodeInfo)
+		if err == nil && commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx,
+			common.AttachableVolumesFromInventory) {
+			maxVolumesPerNode, err = getAttachableVolumesPerNode(ctx, &nodeInfo, maxVolumesPerNode)
+		}
 	}
 
 	if err != nil {
@@ -507,6 +679,25 @@ func (driver *vsphereCSIDriver) NodeGetInfo(
 	return nodeInfoResponse, nil
 }
 
+// getAttachableVolumesPerNode returns the number of volumes which can be attached
+// to the node VM, as computed by the syncer from its PVSCSI and NVMe controllers.
+// The number is capped to maxVolumesPerNode, which is returned if it is unknown.
+func getAttachableVolumesPerNode(ctx context.Context, nodeInfo *commoncotypes.NodeInfo,
+	maxVolumesPerNode int64) (int64, error) {
+	log := logger.GetLogger(ctx)
+	attachableVolumes, err := topologyService.GetNodeAttachableVolumes(ctx, nodeInfo)
+	if err != nil {
+		return 0, err
+	}
+	if attachableVolumes <= 0 {
+		log.Infof("NodeGetInfo: attachable volumes of node %q are unknown, using MaxVolumesPerNode of %d",
+			nodeInfo.NodeName, maxVolumesPerNode)
+		return maxVolumesPerNode, nil
+	}
+	log.Infof("NodeGetInfo: %d volumes can be attached to node %q", attachableVolumes, nodeInfo.NodeName)
+	return min(attachableVolumes, maxVolumesPerNode), nil
+}
+
 // initVolumeTopologyService is a helper method to initialize
 // TopologyService in node.
 func initVolumeTopologyService(ctx context.Context) error {
@@ -531,7 +722,7 @@ func (driver *vsphereCSIDriver) NodeExpandVolume(
 	*csi.NodeExpandVolumeResponse, error) {
 	ctx = logger.NewContextWithLogger(ctx)
 	log := logger.GetLogger(ctx)
-	log.Infof("NodeExpandVolume: called with args %+v", req)
+	log.Infof("NodeExpandVolume: called with args %+v", withoutSecrets(req))
 
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
@@ -569,12 +760,24 @@ func (driver *vsphereCSIDriver) NodeExpandVolume(
 	}
 	log.Debugf("NodeExpandVolume: staging target path %s, getDevFromMount %+v", volumePath, *dev)
 
+	// The file system of an encrypted volume is on the LUKS mapping of its
+	// disk.
+	luksDev, err := driver.osUtils.GetLUKSDevice(ctx, volumeID)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"error getting LUKS mapping of volume %q: %v", volumeID, err)
+	}
+	diskDev := dev
+	if luksDev != nil {
+		diskDev = luksDev.Disk
+	}
+
 	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.OnlineVolumeExtend) {
 		// Fetch the current block size.
-		currentBlockSizeBytes, err := driver.osUtils.GetBlockSizeBytes(ctx, dev.RealDev)
+		currentBlockSizeBytes, err := driver.osUtils.GetBlockSizeBytes(ctx, diskDev.RealDev)
 		if err != nil {
 			return nil, logger.LogNewErrorCodef(log, codes.Internal,
-				"error when getting size of block volume at path %s: %v", dev.RealDev, err)
+				"error when getting size of block volume at path %s: %v", diskDev.RealDev, err)
 		}
 		// Check if a rescan is required.
 		if currentBlockSizeBytes < reqVolSizeBytes {
@@ -582,7 +785,7 @@ func (driver *vsphereCSIDriver) NodeExpandVolume(
 			// rescan the device on the guest OS in order to see the modified size
 			// on the Guest OS.
 			// Refer to https://kb.vmware.com/s/article/1006371
-			err = driver.osUtils.RescanDevice(ctx, dev)
+			err = driver.osUtils.RescanDevice(ctx, diskDev)
 			if err != nil {
 				return nil, logger.LogNewErrorCode(log, codes.Internal, err.Error())
 			}
@@ -595,7 +798,7 @@ func (driver *vsphereCSIDriver) NodeExpandVolume(
 	volCap := req.GetVolumeCapability()
 	if volCap != nil {
 		caps := []*csi.VolumeCapability{volCap}
-		if err := common.IsValidVolumeCapabilities(ctx, caps); err != nil {
+		if err := validateNodeVolumeCapabilities(ctx, caps); err != nil {
 			return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
 				"volume capability not supported. Err: %+v", err)
 		}
@@ -620,8 +823,24 @@ func (driver *vsphereCSIDriver) NodeExpandVolume(
 		}
 	}
 
+	fsSizeBytes := reqVolSizeBytes
+	if luksDev != nil {
+		// The node-expand secret of a volume encrypted with the secret key
+		// source holds its passphrase.
+		luksParams := &osutils.LUKSParams{
+			Passphrase:  []byte(req.GetSecrets()[common.NodeEncryptionPassphraseKey]),
+			KMSEndpoint: os.Getenv(luksKMSEndpointEnv),
+		}
+		if err = driver.osUtils.ResizeLUKSDevice(ctx, volumeID, luksDev, luksParams); err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"error when resizing LUKS mapping of volume %q on node: %v", volumeID, err)
+		}
+		// The LUKS header takes the start of the disk.
+		fsSizeBytes -= luksDev.OffsetBytes
+	}
+
 	// Resize file system.
-	if err = driver.osUtils.ResizeVolume(ctx, dev.RealDev, volumePath, reqVolSizeBytes); err != nil {
+	if err = driver.osUtils.ResizeVolume(ctx, dev.RealDev, volumePath, fsSizeBytes); err != nil {
 		return nil, logger.LogNewErrorCodef(log, codes.Internal,
 			"error when resizing filesystem on volume %q on node: %v", volumeID, err)
 	}
@@ -27,7 +27,9 @@ import (
 	"google.golang.org/grpc/status"
 
 	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco/k8sorchestrator"
+	commoncotypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco/types"
 )
 
 func TestNodeStageVolume_FileVolume(t *testing.T) {
@@ -108,6 +110,25 @@ func TestNodeStageVolume_InvalidArguments(t *testing.T) {
 			},
 			expectedErr: codes.InvalidArgument,
 		},
+		{
+			name: "Filesystem on multi-writer block volume",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId: "test-volume-id",
+				PublishContext: map[string]string{
+					common.AttributeDiskType: common.DiskTypeBlockVolume,
+				},
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+					},
+				},
+				StagingTargetPath: "/tmp/staging",
+			},
+			expectedErr: codes.InvalidArgument,
+		},
 	}
 
 	for _, tt := range tests {
@@ -369,6 +390,7 @@ func TestNodeGetCapabilities(t *testing.T) {
 		csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
 		csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
 		csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
+		csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
 	}
 
 	actualCaps := make([]csi.NodeServiceCapability_RPC_Type, 0)
@@ -400,6 +422,48 @@ func TestNodeGetInfo(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to get system uuid for node VM")
 }
 
+// fakeNodeTopologyService returns a fixed number of attachable volumes.
+type fakeNodeTopologyService struct {
+	attachableVolumes int64
+}
+
+func (f *fakeNodeTopologyService) GetNodeTopologyLabels(ctx context.Context,
+	info *commoncotypes.NodeInfo) (map[string]string, error) {
+	return nil, nil
+}
+
+func (f *fakeNodeTopologyService) GetNodeAttachableVolumes(ctx context.Context,
+	info *commoncotypes.NodeInfo) (int64, error) {
+	return f.attachableVolumes, nil
+}
+
+func TestGetAttachableVolumesPerNode(t *testing.T) {
+	ctx := context.Background()
+	origTopologyService := topologyService
+	defer func() {
+		topologyService = origTopologyService
+	}()
+	nodeInfo := &commoncotypes.NodeInfo{NodeName: "test-node"}
+
+	tests := []struct {
+		name              string
+		attachableVolumes int64
+		expected          int64
+	}{
+		{name: "Unknown attachable volumes", attachableVolumes: 0, expected: defaultMaxVolumesPerNodeGuest},
+		{name: "Attachable volumes below the limit", attachableVolumes: 29, expected: 29},
+		{name: "Attachable volumes above the limit", attachableVolumes: 63, expected: defaultMaxVolumesPerNodeGuest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topologyService = &fakeNodeTopologyService{attachableVolumes: tt.attachableVolumes}
+			maxVolumesPerNode, err := getAttachableVolumesPerNode(ctx, nodeInfo, defaultMaxVolumesPerNodeGuest)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, maxVolumesPerNode)
+		})
+	}
+}
+
 func TestNodeConstants(t *testing.T) {
 	// Test that constants are properly defined
 	assert.Equal(t, 255, maxAllowedBlockVolumesPerNodeInvSphere8)
@@ -453,3 +517,39 @@ func TestOsUtilsInitialization(t *testing.T) {
 	// OsUtils is not initialized when CO initialization fails because it happens after CO init
 	assert.Nil(t, driver.osUtils, "OsUtils should not be initialized when CO initialization fails")
 }
+
+func TestGetLUKSParams(t *testing.T) {
+	ctx := context.Background()
+	req := &csi.NodeStageVolumeRequest{
+		VolumeId: "test-volume-id",
+		VolumeCapability: &csi.VolumeCapability{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+		},
+		VolumeContext: map[string]string{common.AttributeNodeEncryption: common.NodeEncryptionLUKS},
+		Secrets:       map[string]string{common.NodeEncryptionPassphraseKey: "secret-passphrase"},
+	}
+
+	params, err := getLUKSParams(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret-passphrase"), params.Passphrase)
+	assert.NotContains(t, withoutSecrets(req).(*csi.NodeStageVolumeRequest).String(), "secret-passphrase")
+	assert.Equal(t, "secret-passphrase", req.Secrets[common.NodeEncryptionPassphraseKey],
+		"the secrets of the request must be kept")
+
+	req.Secrets = nil
+	_, err = getLUKSParams(ctx, req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "a passphrase must be required")
+
+	req.VolumeContext[common.AttributeNodeEncryptionKeySource] = common.NodeEncryptionKeySourceKMS
+	t.Setenv(luksKMSEndpointEnv, "")
+	_, err = getLUKSParams(ctx, req)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "a KMS plugin endpoint must be required")
+	t.Setenv(luksKMSEndpointEnv, "/run/kms/socket")
+	params, err = getLUKSParams(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "/run/kms/socket", params.KMSEndpoint)
+
+	req.VolumeCapability.AccessType = &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}}
+	_, err = getLUKSParams(ctx, req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "raw block volumes must be refused")
+}
@@ -0,0 +1,334 @@
+//go:build darwin || linux
+// +build darwin linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+)
+
+const (
+	// luksMappingPrefix is the prefix of the device mapper names of the LUKS
+	// mappings of the volumes.
+	luksMappingPrefix = "luks-"
+	// luksDiskFormat is the format reported by blkid for a LUKS disk.
+	luksDiskFormat = "crypto_LUKS"
+	// luksKMSTokenType is the type of the LUKS2 token holding the passphrase
+	// of a volume wrapped by the KMS plugin.
+	luksKMSTokenType = "vsphere-csi-kms"
+	// luksKMSTokenID is the ID of the LUKS2 token holding the passphrase of a
+	// volume wrapped by the KMS plugin.
+	luksKMSTokenID = "0"
+	// luksPassphraseBytes is the number of random bytes of the passphrases
+	// generated for the volumes whose passphrase is wrapped by the KMS plugin.
+	luksPassphraseBytes = 32
+	// luksSectorBytes is the size of the sectors reported by cryptsetup status.
+	luksSectorBytes = 512
+)
+
+// devMapperDir is the directory of the device mapper devices.
+var devMapperDir = "/dev/mapper"
+
+// luksKMSToken is the LUKS2 token holding the passphrase of a volume wrapped
+// by the KMS plugin.
+type luksKMSToken struct {
+	Type        string            `json:"type"`
+	Keyslots    []string          `json:"keyslots"`
+	Ciphertext  []byte            `json:"ciphertext"`
+	KeyID       string            `json:"key_id"`
+	Annotations map[string][]byte `json:"annotations,omitempty"`
+}
+
+// getLUKSMappingName returns the device mapper name of the LUKS mapping of
+// the volume.
+func getLUKSMappingName(volID string) string {
+	return luksMappingPrefix + volID
+}
+
+// cryptsetup runs cryptsetup with the given args, and stdin on its standard
+// input when not nil, and returns its standard output.
+func (osUtils *OsUtils) cryptsetup(stdin []byte, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := osUtils.Mounter.Exec.Command("cryptsetup", args...)
+	if stdin != nil {
+		cmd.SetStdin(bytes.NewReader(stdin))
+	}
+	cmd.SetStdout(&stdout)
+	cmd.SetStderr(&stderr)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("cryptsetup %s failed: %v, output: %s", args[0], err,
+			strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// openLUKSDevice opens the LUKS mapping of the disk of the volume staged with
+// params, and returns the device mapper device holding its file system. A
+// blank disk is formatted with LUKS2 first. Disks with unencrypted data are
+// refused.
+func (osUtils *OsUtils) openLUKSDevice(ctx context.Context, dev *Device, params NodeStageParams) (*Device, error) {
+	log := logger.GetLogger(ctx)
+	name := getLUKSMappingName(params.VolID)
+	mapping, err := osUtils.GetDevice(ctx, filepath.Join(devMapperDir, name))
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"error getting LUKS mapping %q of volume %q: %v", name, params.VolID, err)
+	}
+	if mapping != nil {
+		log.Infof("openLUKSDevice: LUKS mapping %q of volume %q is already open", name, params.VolID)
+		return mapping, nil
+	}
+
+	format, err := osUtils.getDiskFormat(ctx, dev.FullPath)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"error getting the format of disk %q of volume %q: %v", dev.FullPath, params.VolID, err)
+	}
+	var passphrase []byte
+	switch format {
+	case luksDiskFormat:
+		passphrase, err = osUtils.getLUKSPassphrase(ctx, params.VolID, dev, params.LUKS)
+	case "":
+		if params.Ro {
+			return nil, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+				"cannot format read-only volume %q with LUKS", params.VolID)
+		}
+		passphrase, err = osUtils.formatLUKSDevice(ctx, params.VolID, dev, params.LUKS)
+	default:
+		return nil, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+			"disk %q of volume %q holds unencrypted %q data, refusing to format it with LUKS",
+			dev.FullPath, params.VolID, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"open", "--type", "luks", "--key-file", "-"}
+	if params.Ro {
+		args = append(args, "--readonly")
+	}
+	args = append(args, dev.FullPath, name)
+	if _, err := osUtils.cryptsetup(passphrase, args...); err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"error opening LUKS mapping %q of volume %q: %v", name, params.VolID, err)
+	}
+	mapping, err = osUtils.GetDevice(ctx, filepath.Join(devMapperDir, name))
+	if err != nil || mapping == nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"error getting LUKS mapping %q of volume %q after opening it: %v", name, params.VolID, err)
+	}
+	log.Infof("openLUKSDevice: Opened LUKS mapping %q of disk %q of volume %q", name, dev.FullPath, params.VolID)
+	return mapping, nil
+}
+
+// formatLUKSDevice formats the blank disk of the volume with LUKS2, and
+// returns its passphrase. With a KMS plugin, the passphrase is generated, and
+// stored in a LUKS2 token of the disk wrapped by the KMS plugin.
+func (osUtils *OsUtils) formatLUKSDevice(ctx context.Context, volID string, dev *Device,
+	luks *LUKSParams) ([]byte, error) {
+	log := logger.GetLogger(ctx)
+	passphrase := luks.Passphrase
+	var token *luksKMSToken
+	if luks.KMSEndpoint != "" {
+		key := make([]byte, luksPassphraseBytes)
+		if _, err := rand.Read(key); err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"error generating the passphrase of volume %q: %v", volID, err)
+		}
+		passphrase = []byte(base64.StdEncoding.EncodeToString(key))
+		var err error
+		token, err = kmsEncrypt(ctx, luks.KMSEndpoint, volID, passphrase)
+		if err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.Unavailable,
+				"error wrapping the passphrase of volume %q with the KMS plugin: %v", volID, err)
+		}
+	}
+
+	log.Infof("formatLUKSDevice: Formatting disk %q of volume %q with LUKS2", dev.FullPath, volID)
+	if _, err := osUtils.cryptsetup(passphrase, "luksFormat", "--type", "luks2", "--batch-mode",
+		"--key-file", "-", dev.FullPath); err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"error formatting disk %q of volume %q with LUKS2: %v", dev.FullPath, volID, err)
+	}
+	if token == nil {
+		return passphrase, nil
+	}
+	tokenJSON, err := json.Marshal(token)
+	if err == nil {
+		_, err = osUtils.cryptsetup(tokenJSON, "token", "import", "--token-id", luksKMSTokenID,
+			"--json-file", "-", dev.FullPath)
+	}
+	if err != nil {
+		// The passphrase is lost, wipe the LUKS header for the disk to be
+		// formatted again by the next stage.
+		wipe := osUtils.Mounter.Exec.Command("wipefs", "--all", dev.FullPath)
+		if out, wipeErr := wipe.CombinedOutput(); wipeErr != nil {
+			log.Errorf("formatLUKSDevice: error wiping disk %q of volume %q: %v, output: %s",
+				dev.FullPath, volID, wipeErr, string(out))
+		}
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"error storing the wrapped passphrase of volume %q on disk %q: %v", volID, dev.FullPath, err)
+	}
+	return passphrase, nil
+}
+
+// getLUKSPassphrase returns the passphrase of the LUKS disk of the volume,
+// unwrapped by the KMS plugin when luks has a KMS endpoint.
+func (osUtils *OsUtils) getLUKSPassphrase(ctx context.Context, volID string, dev *Device,
+	luks *LUKSParams) ([]byte, error) {
+	log := logger.GetLogger(ctx)
+	if luks.KMSEndpoint == "" {
+		return luks.Passphrase, nil
+	}
+	token, err := osUtils.getLUKSKMSToken(dev)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+			"error getting the wrapped passphrase of volume %q from disk %q: %v", volID, dev.FullPath, err)
+	}
+	passphrase, err := kmsDecrypt(ctx, luks.KMSEndpoint, volID, token)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Unavailable,
+			"error unwrapping the passphrase of volume %q with the KMS plugin: %v", volID, err)
+	}
+	return passphrase, nil
+}
+
+// getLUKSKMSToken returns the LUKS2 token of the disk holding its passphrase
+// wrapped by the KMS plugin.
+func (osUtils *OsUtils) getLUKSKMSToken(dev *Device) (*luksKMSToken, error) {
+	out, err := osUtils.cryptsetup(nil, "token", "export", "--token-id", luksKMSTokenID, dev.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	token := &luksKMSToken{}
+	if err := json.Unmarshal(out, token); err != nil {
+		return nil, fmt.Errorf("invalid LUKS2 token %s: %v", luksKMSTokenID, err)
+	}
+	if token.Type != luksKMSTokenType {
+		return nil, fmt.Errorf("LUKS2 token %s has type %q instead of %q", luksKMSTokenID, token.Type,
+			luksKMSTokenType)
+	}
+	return token, nil
+}
+
+// GetLUKSDevice returns the LUKS mapping of the disk of the volume, or nil when
+// the volume has no open LUKS mapping.
+func (osUtils *OsUtils) GetLUKSDevice(ctx context.Context, volID string) (*LUKSDevice, error) {
+	name := getLUKSMappingName(volID)
+	mapping, err := osUtils.GetDevice(ctx, filepath.Join(devMapperDir, name))
+	if err != nil || mapping == nil {
+		return nil, err
+	}
+	out, err := osUtils.cryptsetup(nil, "status", name)
+	if err != nil {
+		return nil, err
+	}
+	diskPath, offsetBytes, err := parseLUKSStatus(out)
+	if err != nil {
+		return nil, fmt.Errorf("invalid status of LUKS mapping %q: %v", name, err)
+	}
+	disk, err := osUtils.GetDevice(ctx, diskPath)
+	if err != nil || disk == nil {
+		return nil, fmt.Errorf("error getting disk %q of LUKS mapping %q: %v", diskPath, name, err)
+	}
+	return &LUKSDevice{
+		Mapping:     mapping,
+		Disk:        disk,
+		OffsetBytes: offsetBytes,
+	}, nil
+}
+
+// parseLUKSStatus returns the disk and the size of the header of a LUKS
+// mapping, from the output of cryptsetup status.
+func parseLUKSStatus(out []byte) (string, int64, error) {
+	var diskPath string
+	offsetBytes := int64(-1)
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "device":
+			diskPath = value
+		case "offset":
+			sectors, err := strconv.ParseInt(strings.TrimSuffix(value, " sectors"), 10, 64)
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid offset %q", value)
+			}
+			offsetBytes = sectors * luksSectorBytes
+		}
+	}
+	if diskPath == "" || offsetBytes < 0 {
+		return "", 0, fmt.Errorf("missing device or offset in %q", string(out))
+	}
+	return diskPath, offsetBytes, nil
+}
+
+// ResizeLUKSDevice grows the LUKS mapping of the volume to the size of its
+// disk. The passphrase of the volume is used when known, from params or from
+// the KMS plugin.
+func (osUtils *OsUtils) ResizeLUKSDevice(ctx context.Context, volID string, luksDev *LUKSDevice,
+	params *LUKSParams) error {
+	log := logger.GetLogger(ctx)
+	passphrase := params.Passphrase
+	if params.KMSEndpoint != "" {
+		if token, err := osUtils.getLUKSKMSToken(luksDev.Disk); err != nil {
+			log.Debugf("ResizeLUKSDevice: no wrapped passphrase on disk %q of volume %q: %v",
+				luksDev.Disk.FullPath, volID, err)
+		} else if passphrase, err = kmsDecrypt(ctx, params.KMSEndpoint, volID, token); err != nil {
+			return fmt.Errorf("error unwrapping the passphrase of volume %q with the KMS plugin: %v", volID, err)
+		}
+	}
+	args := []string{"resize"}
+	if len(passphrase) != 0 {
+		args = append(args, "--key-file", "-")
+	}
+	args = append(args, getLUKSMappingName(volID))
+	if _, err := osUtils.cryptsetup(passphrase, args...); err != nil {
+		return fmt.Errorf("error resizing LUKS mapping of volume %q: %v", volID, err)
+	}
+	log.Infof("ResizeLUKSDevice: Resized LUKS mapping of volume %q", volID)
+	return nil
+}
+
+// CloseLUKSDevice closes the LUKS mapping of the volume, if open.
+func (osUtils *OsUtils) CloseLUKSDevice(ctx context.Context, volID string) error {
+	log := logger.GetLogger(ctx)
+	name := getLUKSMappingName(volID)
+	mapping, err := osUtils.GetDevice(ctx, filepath.Join(devMapperDir, name))
+	if err != nil || mapping == nil {
+		return err
+	}
+	if _, err := osUtils.cryptsetup(nil, "close", name); err != nil {
+		return fmt.Errorf("error closing LUKS mapping %q of volume %q: %v", name, volID, err)
+	}
+	log.Infof("CloseLUKSDevice: Closed LUKS mapping %q of volume %q", name, volID)
+	return nil
+}
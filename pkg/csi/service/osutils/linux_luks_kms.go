@@ -0,0 +1,216 @@
+//go:build darwin || linux
+// +build darwin linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// kmsServicePrefix is the prefix of the methods of the KeyManagementService
+	// of the Kubernetes KMS v2 plugins.
+	kmsServicePrefix = "/v2.KeyManagementService/"
+	// kmsTimeout is the timeout of the calls to the KMS plugin.
+	kmsTimeout = 30 * time.Second
+)
+
+// kmsRawCodec passes the messages of the KMS plugin, encoded with protowire,
+// as they are. The KMS v2 API is small enough not to depend on its generated
+// code.
+type kmsRawCodec struct{}
+
+func (kmsRawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+func (kmsRawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (kmsRawCodec) Name() string {
+	return "proto"
+}
+
+// kmsInvoke calls the given method of the KMS plugin at endpoint, a unix
+// socket, with the encoded request, and returns the encoded response.
+func kmsInvoke(ctx context.Context, endpoint, method string, req []byte) ([]byte, error) {
+	target := endpoint
+	if !strings.HasPrefix(target, "unix://") {
+		target = "unix://" + target
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(ctx, kmsTimeout)
+	defer cancel()
+	var resp []byte
+	if err := conn.Invoke(ctx, kmsServicePrefix+method, &req, &resp, grpc.ForceCodec(kmsRawCodec{})); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// kmsEncrypt wraps the passphrase of the volume with the KMS plugin at
+// endpoint, and returns the LUKS2 token holding it.
+func kmsEncrypt(ctx context.Context, endpoint, volID string, passphrase []byte) (*luksKMSToken, error) {
+	// EncryptRequest: plaintext = 1, uid = 2.
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendBytes(req, passphrase)
+	req = protowire.AppendTag(req, 2, protowire.BytesType)
+	req = protowire.AppendString(req, volID)
+	resp, err := kmsInvoke(ctx, endpoint, "Encrypt", req)
+	if err != nil {
+		return nil, err
+	}
+	// EncryptResponse: ciphertext = 1, key_id = 2, annotations = 3.
+	token := &luksKMSToken{
+		Type:     luksKMSTokenType,
+		Keyslots: []string{"0"},
+	}
+	err = consumeKMSFields(resp, func(num protowire.Number, value []byte) error {
+		switch num {
+		case 1:
+			token.Ciphertext = append([]byte(nil), value...)
+		case 2:
+			token.KeyID = string(value)
+		case 3:
+			key, annotation, err := consumeKMSMapEntry(value)
+			if err != nil {
+				return err
+			}
+			if token.Annotations == nil {
+				token.Annotations = make(map[string][]byte)
+			}
+			token.Annotations[key] = annotation
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(token.Ciphertext) == 0 || token.KeyID == "" {
+		return nil, errors.New("the KMS plugin returned no ciphertext or key ID")
+	}
+	return token, nil
+}
+
+// kmsDecrypt unwraps the passphrase of the volume held by token with the KMS
+// plugin at endpoint.
+func kmsDecrypt(ctx context.Context, endpoint, volID string, token *luksKMSToken) ([]byte, error) {
+	// DecryptRequest: ciphertext = 1, uid = 2, key_id = 3, annotations = 4.
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendBytes(req, token.Ciphertext)
+	req = protowire.AppendTag(req, 2, protowire.BytesType)
+	req = protowire.AppendString(req, volID)
+	req = protowire.AppendTag(req, 3, protowire.BytesType)
+	req = protowire.AppendString(req, token.KeyID)
+	for key, annotation := range token.Annotations {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, key)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, annotation)
+		req = protowire.AppendTag(req, 4, protowire.BytesType)
+		req = protowire.AppendBytes(req, entry)
+	}
+	resp, err := kmsInvoke(ctx, endpoint, "Decrypt", req)
+	if err != nil {
+		return nil, err
+	}
+	// DecryptResponse: plaintext = 1.
+	var passphrase []byte
+	err = consumeKMSFields(resp, func(num protowire.Number, value []byte) error {
+		if num == 1 {
+			passphrase = append([]byte(nil), value...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("the KMS plugin returned no plaintext")
+	}
+	return passphrase, nil
+}
+
+// consumeKMSFields calls fn with each length-delimited field of the encoded
+// message b, and skips its other fields.
+func consumeKMSFields(b []byte, fn func(num protowire.Number, value []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		value, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(num, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consumeKMSMapEntry returns the key and value of an encoded entry of a
+// map<string, bytes> field.
+func consumeKMSMapEntry(b []byte) (string, []byte, error) {
+	var key string
+	var value []byte
+	err := consumeKMSFields(b, func(num protowire.Number, field []byte) error {
+		switch num {
+		case 1:
+			key = string(field)
+		case 2:
+			value = append([]byte(nil), field...)
+		}
+		return nil
+	})
+	return key, value, err
+}
@@ -0,0 +1,154 @@
+//go:build darwin || linux
+// +build darwin linux
+
+package osutils
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+	"k8s.io/mount-utils"
+	"k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+// startFakeKMSPlugin starts a KMS v2 plugin wrapping the plaintexts by
+// prefixing them, and returns its endpoint.
+func startFakeKMSPlugin(t *testing.T) string {
+	endpoint := filepath.Join(t.TempDir(), "kms.sock")
+	listener, err := net.Listen("unix", endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.ForceServerCodec(kmsRawCodec{}),
+		grpc.UnknownServiceHandler(func(srv any, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			var req, resp []byte
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			fields := make(map[protowire.Number][]byte)
+			if err := consumeKMSFields(req, func(num protowire.Number, value []byte) error {
+				fields[num] = value
+				return nil
+			}); err != nil {
+				return err
+			}
+			switch method {
+			case kmsServicePrefix + "Encrypt":
+				resp = protowire.AppendTag(resp, 1, protowire.BytesType)
+				resp = protowire.AppendBytes(resp, append([]byte("wrapped:"), fields[1]...))
+				resp = protowire.AppendTag(resp, 2, protowire.BytesType)
+				resp = protowire.AppendString(resp, "key-1")
+				var entry []byte
+				entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+				entry = protowire.AppendString(entry, "version")
+				entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+				entry = protowire.AppendBytes(entry, []byte("v1"))
+				resp = protowire.AppendTag(resp, 3, protowire.BytesType)
+				resp = protowire.AppendBytes(resp, entry)
+			case kmsServicePrefix + "Decrypt":
+				key, annotation, err := consumeKMSMapEntry(fields[4])
+				if err != nil || string(fields[3]) != "key-1" || key != "version" || string(annotation) != "v1" {
+					t.Errorf("unexpected DecryptRequest fields %q", fields)
+				}
+				resp = protowire.AppendTag(resp, 1, protowire.BytesType)
+				resp = protowire.AppendBytes(resp, bytes.TrimPrefix(fields[1], []byte("wrapped:")))
+			default:
+				t.Errorf("unexpected KMS method %q", method)
+			}
+			return stream.SendMsg(&resp)
+		}))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	return endpoint
+}
+
+func TestFormatLUKSDeviceWithKMS(t *testing.T) {
+	ctx := context.Background()
+	endpoint := startFakeKMSPlugin(t)
+	var formatPassphrase, tokenJSON []byte
+	// cryptsetupAction returns a cryptsetup command reading its standard
+	// input with read, and writing stdout on its standard output.
+	cryptsetupAction := func(read func([]byte), stdout func() []byte) testingexec.FakeCommandAction {
+		return func(cmd string, args ...string) exec.Cmd {
+			fakeCmd := &testingexec.FakeCmd{}
+			fakeCmd.RunScript = []testingexec.FakeAction{func() ([]byte, []byte, error) {
+				if read != nil {
+					in, err := io.ReadAll(fakeCmd.Stdin)
+					if err != nil {
+						return nil, nil, err
+					}
+					read(in)
+				}
+				if stdout != nil {
+					return stdout(), nil, nil
+				}
+				return nil, nil, nil
+			}}
+			return testingexec.InitFakeCmd(fakeCmd, cmd, args...)
+		}
+	}
+	fakeExec := &testingexec.FakeExec{CommandScript: []testingexec.FakeCommandAction{
+		cryptsetupAction(func(in []byte) { formatPassphrase = in }, nil),
+		cryptsetupAction(func(in []byte) { tokenJSON = in }, nil),
+		cryptsetupAction(nil, func() []byte { return tokenJSON }),
+	}}
+	osUtils := &OsUtils{Mounter: &mount.SafeFormatAndMount{Exec: fakeExec}}
+	dev := &Device{FullPath: "/dev/sdb", RealDev: "/dev/sdb"}
+	luks := &LUKSParams{KMSEndpoint: endpoint}
+
+	passphrase, err := osUtils.formatLUKSDevice(ctx, "volume-1", dev, luks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passphrase) == 0 || !bytes.Equal(passphrase, formatPassphrase) {
+		t.Fatalf("expected the disk to be formatted with the generated passphrase %q, got %q",
+			passphrase, formatPassphrase)
+	}
+	if bytes.Contains(tokenJSON, passphrase) {
+		t.Fatalf("the LUKS2 token %s must not hold the passphrase in clear", tokenJSON)
+	}
+	unwrapped, err := osUtils.getLUKSPassphrase(ctx, "volume-1", dev, luks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(passphrase, unwrapped) {
+		t.Fatalf("expected the passphrase %q to be unwrapped by the KMS plugin, got %q", passphrase, unwrapped)
+	}
+	if fakeExec.CommandCalls != 3 {
+		t.Fatalf("expected 3 cryptsetup calls, got %d", fakeExec.CommandCalls)
+	}
+}
+
+func TestParseLUKSStatus(t *testing.T) {
+	out := []byte(`/dev/mapper/luks-volume-1 is active and is in use.
+  type:    LUKS2
+  cipher:  aes-xts-plain64
+  keysize: 512 bits
+  key location: keyring
+  device:  /dev/sdb
+  sector size:  512
+  offset:  32768 sectors
+  size:    2064384 sectors
+  mode:    read/write
+`)
+	diskPath, offsetBytes, err := parseLUKSStatus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diskPath != "/dev/sdb" || offsetBytes != 32768*512 {
+		t.Fatalf("unexpected disk %q and offset %d", diskPath, offsetBytes)
+	}
+	if _, _, err := parseLUKSStatus([]byte("/dev/mapper/luks-volume-1 is inactive.\n")); err == nil {
+		t.Fatal("expected an error for the status of an inactive mapping")
+	}
+}
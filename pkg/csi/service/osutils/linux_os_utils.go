@@ -50,6 +50,10 @@ const (
 	UUIDPrefix  = "VMware-"
 )
 
+// mountInfoPath is the mountinfo file the project quotas of a mount point are
+// looked up in. It is overridden by the unit tests.
+var mountInfoPath = "/proc/self/mountinfo"
+
 // defaultFileMountOptions are the mount flag options used by default while publishing a file volume.
 var defaultFileMountOptions = []string{"hard", "sec=sys", "vers=4", "minorversion=1"}
 
@@ -149,9 +153,10 @@ func (osUtils *OsUtils) getDiskFormat(ctx context.Context, disk string) (string,
 	return fstype, nil
 }
 
-// xfsFormatAndMount mounts volume to the staging path for xfs fstype
+// xfsFormatAndMount mounts volume to the staging path for xfs fstype. The
+// volume is formatted with mkfsOptions if it is unformatted.
 func (osUtils *OsUtils) xfsFormatAndMount(ctx context.Context, source string, target string,
-	fstype string, opts ...string) error {
+	fstype string, mkfsOptions []string, opts ...string) error {
 	log := logger.GetLogger(ctx)
 	// Check if the disk is already formatted
 	existingFormat, err := osUtils.getDiskFormat(ctx, source)
@@ -175,19 +180,16 @@ func (osUtils *OsUtils) xfsFormatAndMount(ctx context.Context, source string, ta
 			return err
 		}
 		var args []string
-		if kernel >= 5 && major >= 10 {
-			args = []string{
-				source,
-			}
-		} else {
+		if !(kernel >= 5 && major >= 10) {
 			args = []string{
 				"-m",
 				"bigtime=0",
 				"-m",
 				"inobtcount=0",
-				source,
 			}
 		}
+		args = append(args, mkfsOptions...)
+		args = append(args, source)
 
 		log.Infof("xfsFormatAndMount: Disk %q appears to be unformatted, attempting to format as type: %q "+
 			"with options: %v", source, fstype, args)
@@ -213,6 +215,43 @@ func (osUtils *OsUtils) xfsFormatAndMount(ctx context.Context, source string, ta
 	return nil
 }
 
+// mkfsFormatAndMount mounts volume to the staging path for ext3, ext4 and
+// btrfs fstypes. The volume is formatted with mkfsOptions if it is
+// unformatted.
+func (osUtils *OsUtils) mkfsFormatAndMount(ctx context.Context, source string, target string,
+	fstype string, mkfsOptions []string, opts ...string) error {
+	log := logger.GetLogger(ctx)
+	existingFormat, err := osUtils.getDiskFormat(ctx, source)
+	if err != nil {
+		return fmt.Errorf("failed to get disk format of disk %s: %v", source, err)
+	}
+
+	if existingFormat == "" {
+		var args []string
+		if fstype != common.BtrfsFsType {
+			// -F formats the whole disk without asking for confirmation.
+			args = []string{"-F"}
+		}
+		args = append(args, mkfsOptions...)
+		args = append(args, source)
+		log.Infof("mkfsFormatAndMount: Disk %q appears to be unformatted, attempting to format as type: %q "+
+			"with options: %v", source, fstype, args)
+		output, err := osUtils.Mounter.Exec.Command("mkfs."+fstype, args...).CombinedOutput()
+		if err != nil {
+			return logger.LogNewErrorf(log, "format of disk %q failed: type:(%q) errcode:(%v) output:(%v)",
+				source, fstype, err, string(output))
+		}
+		log.Infof("mkfsFormatAndMount: Disk successfully formatted (mkfs): %s - %s %s", fstype, source, target)
+	}
+
+	log.Infof("mkfsFormatAndMount: Attempting to mount disk %s in %s format at %s", source, fstype, target)
+	if err := osUtils.Mounter.Mount(source, target, fstype, opts); err != nil {
+		return logger.LogNewErrorf(log, "mkfsFormatAndMount: mount of disk %s failed: type:(%q) target:(%q) "+
+			"errcode:(%v)", source, fstype, target, err)
+	}
+	return nil
+}
+
 // NodeStageBlockVolume mounts mount volume or file volume to staging target
 func (osUtils *OsUtils) NodeStageBlockVolume(
 	ctx context.Context,
@@ -253,6 +292,26 @@ func (osUtils *OsUtils) NodeStageBlockVolume(
 		log.Infof("nodeStageBlockVolume: Skipping staging for block volume ID %q", params.VolID)
 		return &csi.NodeStageVolumeResponse{}, nil
 	}
+	// A disk attached to several nodes would be corrupted by mounting a
+	// file system on each of them.
+	if req.GetVolumeCapability().GetAccessMode().GetMode() == csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER {
+		return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"filesystem volume mode is not supported for multi-writer block volume: %q", params.VolID)
+	}
+	if params.LUKS != nil {
+		// The file system of an encrypted volume is on the LUKS mapping of
+		// its disk.
+		dev, err = osUtils.openLUKSDevice(ctx, dev, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(params.ProjectQuotaDirectories) > 0 {
+		if err := enableProjectQuota(ctx, &params); err != nil {
+			return nil, err
+		}
+	}
 
 	// Mount Volume.
 	// Fetch dev mounts to check if the device is already staged.
@@ -284,7 +343,16 @@ func (osUtils *OsUtils) NodeStageBlockVolume(
 		if params.FsType == "xfs" {
 			// use internal function for XFS mount, as we want to provide few parameters for mkfs command
 			// which are specific to XFS filesystem
-			err := osUtils.xfsFormatAndMount(ctx, dev.FullPath, params.StagingTarget, params.FsType, params.MntFlags...)
+			err := osUtils.xfsFormatAndMount(ctx, dev.FullPath, params.StagingTarget, params.FsType,
+				params.MkfsOptions, params.MntFlags...)
+			if err != nil {
+				return nil, logger.LogNewErrorCodef(log, codes.Internal,
+					"error in formating and mounting volume. Parameters: %v err: %v", params, err)
+			}
+		} else if len(params.MkfsOptions) > 0 || params.FsType == common.BtrfsFsType {
+			// gofsutil does not take mkfs options, and ignores the errors of mkfs.
+			err := osUtils.mkfsFormatAndMount(ctx, dev.FullPath, params.StagingTarget, params.FsType,
+				params.MkfsOptions, params.MntFlags...)
 			if err != nil {
 				return nil, logger.LogNewErrorCodef(log, codes.Internal,
 					"error in formating and mounting volume. Parameters: %v err: %v", params, err)
@@ -312,6 +380,13 @@ func (osUtils *OsUtils) NodeStageBlockVolume(
 					// TODO make sure that all the mount options match.
 					log.Infof("nodeStageBlockVolume: Device already mounted at %q with mount option %q",
 						params.StagingTarget, rwo)
+					// The project quota directories of a previous failed stage
+					// may not be set up.
+					if !params.Ro {
+						if err := osUtils.setupProjectQuotaDirectories(ctx, params); err != nil {
+							return nil, err
+						}
+					}
 					return &csi.NodeStageVolumeResponse{}, nil
 				}
 				return nil, logger.LogNewErrorCodef(log, codes.AlreadyExists,
@@ -322,10 +397,73 @@ func (osUtils *OsUtils) NodeStageBlockVolume(
 			"device already in use and mounted elsewhere")
 	}
 	log.Infof("nodeStageBlockVolume: Device mounted successfully at %q", params.StagingTarget)
+	if err := osUtils.setupProjectQuotaDirectories(ctx, params); err != nil {
+		return nil, err
+	}
 
 	return &csi.NodeStageVolumeResponse{}, nil
 }
 
+// enableProjectQuota sets the options of mkfs and mount enabling the project
+// quotas of the file system of the volume to stage. Project quotas are
+// supported by ext4 and xfs.
+func enableProjectQuota(ctx context.Context, params *NodeStageParams) error {
+	log := logger.GetLogger(ctx)
+	switch params.FsType {
+	case common.Ext4FsType:
+		// The project quotas of ext4 need the quota and project features,
+		// which are only set when formatting the volume.
+		params.MkfsOptions = append([]string{"-O", "quota,project"}, params.MkfsOptions...)
+	case common.XFSType:
+	default:
+		return logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"project quota directories are not supported for fstype %s of volume %q", params.FsType, params.VolID)
+	}
+	params.MntFlags = append(params.MntFlags, "prjquota")
+	return nil
+}
+
+// setupProjectQuotaDirectories creates the project quota directories of the
+// volume staged at params.StagingTarget, and sets the project and the limit of
+// each of them with xfs_quota. The project of a directory is inherited by the
+// files created in it. It does nothing for the volumes without project quota
+// directories, and can be called again after a failed stage.
+func (osUtils *OsUtils) setupProjectQuotaDirectories(ctx context.Context, params NodeStageParams) error {
+	log := logger.GetLogger(ctx)
+	if len(params.ProjectQuotaDirectories) == 0 {
+		return nil
+	}
+	quotaArgs := []string{"-x"}
+	if params.FsType != common.XFSType {
+		// xfs_quota manages the project quotas of ext4 in foreign file system
+		// mode.
+		quotaArgs = append(quotaArgs, "-f")
+	}
+	for _, dir := range params.ProjectQuotaDirectories {
+		path := filepath.Join(params.StagingTarget, dir.Name)
+		if _, err := osUtils.Mkdir(ctx, path); err != nil {
+			return logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to create project quota directory %q of volume %q: %v", path, params.VolID, err)
+		}
+		commands := []string{
+			fmt.Sprintf("project -s -p %s %d", path, dir.ProjectID),
+			fmt.Sprintf("limit -p bhard=%d %d", dir.LimitBytes, dir.ProjectID),
+		}
+		for _, command := range commands {
+			args := append(append([]string{}, quotaArgs...), "-c", command, params.StagingTarget)
+			output, err := osUtils.Mounter.Exec.Command("xfs_quota", args...).CombinedOutput()
+			if err != nil {
+				return logger.LogNewErrorCodef(log, codes.Internal,
+					"failed to set project quota of directory %q of volume %q with %q: %v, output: %s",
+					path, params.VolID, command, err, string(output))
+			}
+		}
+		log.Infof("setupProjectQuotaDirectories: Directory %q limited to %d bytes by project %d",
+			path, dir.LimitBytes, dir.ProjectID)
+	}
+	return nil
+}
+
 // CleanupStagePath will unmount the volume from node and remove the stage directory
 func (osUtils *OsUtils) CleanupStagePath(ctx context.Context, stagingTarget string, volID string) error {
 	log := logger.GetLogger(ctx)
@@ -521,6 +659,99 @@ func (osUtils *OsUtils) GetMetrics(ctx context.Context, path string) (*k8svol.Me
 	return metrics, nil
 }
 
+// GetProjectQuotaUsage returns the usage and the limit of each project quota
+// of the volume mounted at path, as reported by xfs_quota. It returns nil for
+// the volumes which are not mounted with project quotas enabled. Project 0,
+// owning the files outside of the project quota directories, is not returned.
+func (osUtils *OsUtils) GetProjectQuotaUsage(ctx context.Context, path string) ([]ProjectQuotaUsage, error) {
+	log := logger.GetLogger(ctx)
+	fsType, superOpts, err := getMountSuperOptions(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if (fsType != common.Ext4FsType && fsType != common.XFSType) ||
+		!common.Contains(strings.Split(superOpts, ","), "prjquota") {
+		return nil, nil
+	}
+	args := []string{"-x"}
+	if fsType != common.XFSType {
+		args = append(args, "-f")
+	}
+	// Report the blocks of the projects by ID, without header.
+	args = append(args, "-c", "report -p -b -n -N", path)
+	output, err := osUtils.Mounter.Exec.Command("xfs_quota", args...).CombinedOutput()
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to report the project quotas of %q: %v, output: %s",
+			path, err, string(output))
+	}
+	var usages []ProjectQuotaUsage
+	for _, line := range strings.Split(string(output), "\n") {
+		// Each line is "#<project ID> <used> <soft> <hard> <warn/grace>",
+		// in KiB.
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		projectID, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "#"), 10, 32)
+		if err != nil {
+			return nil, logger.LogNewErrorf(log, "failed to parse the project quota report line %q of %q: %v",
+				line, path, err)
+		}
+		if projectID == 0 {
+			continue
+		}
+		used, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, logger.LogNewErrorf(log, "failed to parse the project quota report line %q of %q: %v",
+				line, path, err)
+		}
+		limit, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, logger.LogNewErrorf(log, "failed to parse the project quota report line %q of %q: %v",
+				line, path, err)
+		}
+		usages = append(usages, ProjectQuotaUsage{
+			ProjectID:  uint32(projectID),
+			UsedBytes:  used * 1024,
+			LimitBytes: limit * 1024,
+		})
+	}
+	return usages, nil
+}
+
+// getMountSuperOptions returns the file system type and the super block
+// options of the mount point at path, which are not part of the mount options
+// returned by gofsutil. Each line of mountinfo is "<id> <parent id>
+// <major:minor> <root> <mount point> <mount options> [optional fields] -
+// <fstype> <source> <super options>".
+func getMountSuperOptions(ctx context.Context, path string) (string, string, error) {
+	log := logger.GetLogger(ctx)
+	content, err := os.ReadFile(mountInfoPath)
+	if err != nil {
+		return "", "", logger.LogNewErrorf(log, "failed to read %q: %v", mountInfoPath, err)
+	}
+	path = filepath.Clean(path)
+	found := false
+	var fsType, superOpts string
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || unescape(ctx, fields[4]) != path {
+			continue
+		}
+		// The last mount at path hides the previous ones.
+		for i := 5; i < len(fields)-3; i++ {
+			if fields[i] == "-" {
+				found, fsType, superOpts = true, fields[i+1], fields[i+3]
+				break
+			}
+		}
+	}
+	if !found {
+		return "", "", logger.LogNewErrorf(log, "mount point %q not found in %q", path, mountInfoPath)
+	}
+	return fsType, superOpts, nil
+}
+
 // GetBlockSizeBytes returns the Block size in bytes
 func (osUtils *OsUtils) GetBlockSizeBytes(ctx context.Context, devicePath string) (int64, error) {
 	cmdArgs := []string{"--getsize64", devicePath}
@@ -656,8 +887,15 @@ func (osUtils *OsUtils) PublishBlockVol(
 	}
 	log.Debugf("publishBlockVol: device %+v, device mounts %q", *dev, devMnts)
 
+	// A multi-writer volume can be used by several pods on the node, each
+	// with its own target.
+	isMultiWriter := common.IsMultiWriterBlockVolumeRequest(ctx,
+		[]*csi.VolumeCapability{req.GetVolumeCapability()})
+
 	// Check if device is already mounted.
-	if len(devMnts) == 0 {
+	if isMultiWriter && isTargetInMounts(ctx, params.Target, devMnts) {
+		log.Debugf("Volume already published to target. Parameters: [%+v]", params)
+	} else if len(devMnts) == 0 || isMultiWriter {
 		// Do the bind mount.
 		mntFlags := make([]string, 0)
 		log.Debugf("PublishBlockVolume: Attempting to bind mount %q to %q with mount flags %v",
@@ -1140,12 +1378,13 @@ func (osUtils *OsUtils) GetVolumeCapabilityFsType(ctx context.Context,
 		}
 	} else {
 		// For Block volumes we only support following filesystems:
-		// ext3, ext4 and xfs for Linux.
+		// ext3, ext4, xfs and btrfs for Linux.
 		if fsType == "" {
 			log.Infof("empty string fstype observed for block volume. Defaulting to: %s",
 				common.Ext4FsType)
 			fsType = common.Ext4FsType
-		} else if !(fsType == common.Ext4FsType || fsType == common.Ext3FsType || fsType == common.XFSType) {
+		} else if !(fsType == common.Ext4FsType || fsType == common.Ext3FsType || fsType == common.XFSType ||
+			fsType == common.BtrfsFsType) {
 			return "", logger.LogNewErrorCodef(log, codes.FailedPrecondition,
 				"unsupported fsType %q observed for block volume", fsType)
 		}
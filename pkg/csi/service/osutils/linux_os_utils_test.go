@@ -5,8 +5,17 @@ package osutils
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
 	"testing"
+
+	"k8s.io/mount-utils"
+	"k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
 )
 
 func TestUnescape(t *testing.T) {
@@ -52,3 +61,192 @@ func TestUnescape(t *testing.T) {
 		})
 	}
 }
+
+func TestMkfsFormatAndMount(t *testing.T) {
+	tests := []struct {
+		name        string
+		fsType      string
+		mkfsOptions []string
+		expected    []string
+	}{
+		{
+			name:        "ext4 with mkfs options",
+			fsType:      "ext4",
+			mkfsOptions: []string{"-i", "65536", "-E", "lazy_itable_init=0"},
+			expected:    []string{"mkfs.ext4", "-F", "-i", "65536", "-E", "lazy_itable_init=0", "/dev/sdb"},
+		},
+		{
+			name:     "btrfs",
+			fsType:   "btrfs",
+			expected: []string{"mkfs.btrfs", "/dev/sdb"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var mkfsCmd []string
+			fakeExec := &testingexec.FakeExec{CommandScript: []testingexec.FakeCommandAction{
+				// blkid finds no file system on the disk.
+				func(cmd string, args ...string) exec.Cmd {
+					return testingexec.InitFakeCmd(&testingexec.FakeCmd{CombinedOutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) { return nil, nil, &testingexec.FakeExitError{Status: 2} },
+					}}, cmd, args...)
+				},
+				func(cmd string, args ...string) exec.Cmd {
+					mkfsCmd = append([]string{cmd}, args...)
+					return testingexec.InitFakeCmd(&testingexec.FakeCmd{CombinedOutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) { return nil, nil, nil },
+					}}, cmd, args...)
+				},
+			}}
+			mounter := mount.NewFakeMounter(nil)
+			osUtils := &OsUtils{Mounter: &mount.SafeFormatAndMount{Interface: mounter, Exec: fakeExec}}
+
+			err := osUtils.mkfsFormatAndMount(ctx, "/dev/sdb", "/staging", tt.fsType, tt.mkfsOptions, "noatime")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(mkfsCmd, tt.expected) {
+				t.Fatalf("expected the disk to be formatted with %v, got %v", tt.expected, mkfsCmd)
+			}
+			mountPoints, err := mounter.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(mountPoints) != 1 || mountPoints[0].Path != "/staging" || mountPoints[0].Type != tt.fsType {
+				t.Fatalf("expected the disk to be mounted at /staging, got %+v", mountPoints)
+			}
+		})
+	}
+}
+
+func TestEnableProjectQuota(t *testing.T) {
+	ctx := context.Background()
+	dirs := []common.ProjectQuotaDirectory{{Name: "data", ProjectID: 1, LimitBytes: 1 << 30}}
+
+	params := NodeStageParams{FsType: "ext4", MkfsOptions: []string{"-i", "65536"},
+		ProjectQuotaDirectories: dirs}
+	if err := enableProjectQuota(ctx, &params); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"-O", "quota,project", "-i", "65536"}
+	if !reflect.DeepEqual(params.MkfsOptions, expected) {
+		t.Fatalf("expected ext4 to be formatted with %v, got %v", expected, params.MkfsOptions)
+	}
+	if !reflect.DeepEqual(params.MntFlags, []string{"prjquota"}) {
+		t.Fatalf("expected ext4 to be mounted with prjquota, got %v", params.MntFlags)
+	}
+
+	params = NodeStageParams{FsType: "xfs", MntFlags: []string{"nouuid"}, ProjectQuotaDirectories: dirs}
+	if err := enableProjectQuota(ctx, &params); err != nil {
+		t.Fatal(err)
+	}
+	if len(params.MkfsOptions) != 0 || !reflect.DeepEqual(params.MntFlags, []string{"nouuid", "prjquota"}) {
+		t.Fatalf("expected xfs to be mounted with prjquota, got mkfs options %v and mount flags %v",
+			params.MkfsOptions, params.MntFlags)
+	}
+
+	params = NodeStageParams{FsType: "btrfs", ProjectQuotaDirectories: dirs}
+	if err := enableProjectQuota(ctx, &params); err == nil {
+		t.Fatal("expected project quota directories to be refused for btrfs")
+	}
+}
+
+func TestSetupProjectQuotaDirectories(t *testing.T) {
+	ctx := context.Background()
+	stagingTarget := t.TempDir()
+	var quotaCmds [][]string
+	quotaCmd := func(cmd string, args ...string) exec.Cmd {
+		quotaCmds = append(quotaCmds, append([]string{cmd}, args...))
+		return testingexec.InitFakeCmd(&testingexec.FakeCmd{CombinedOutputScript: []testingexec.FakeAction{
+			func() ([]byte, []byte, error) { return nil, nil, nil },
+		}}, cmd, args...)
+	}
+	fakeExec := &testingexec.FakeExec{CommandScript: []testingexec.FakeCommandAction{
+		quotaCmd, quotaCmd, quotaCmd, quotaCmd,
+	}}
+	osUtils := &OsUtils{Mounter: &mount.SafeFormatAndMount{Interface: mount.NewFakeMounter(nil), Exec: fakeExec}}
+	params := NodeStageParams{
+		VolID:         "volume",
+		FsType:        "ext4",
+		StagingTarget: stagingTarget,
+		ProjectQuotaDirectories: []common.ProjectQuotaDirectory{
+			{Name: "data", ProjectID: 1, LimitBytes: 10 << 30},
+			{Name: "logs", ProjectID: 2, LimitBytes: 1 << 30},
+		},
+	}
+
+	if err := osUtils.setupProjectQuotaDirectories(ctx, params); err != nil {
+		t.Fatal(err)
+	}
+	dataDir := filepath.Join(stagingTarget, "data")
+	logsDir := filepath.Join(stagingTarget, "logs")
+	expected := [][]string{
+		{"xfs_quota", "-x", "-f", "-c", "project -s -p " + dataDir + " 1", stagingTarget},
+		{"xfs_quota", "-x", "-f", "-c", "limit -p bhard=10737418240 1", stagingTarget},
+		{"xfs_quota", "-x", "-f", "-c", "project -s -p " + logsDir + " 2", stagingTarget},
+		{"xfs_quota", "-x", "-f", "-c", "limit -p bhard=1073741824 2", stagingTarget},
+	}
+	if !reflect.DeepEqual(quotaCmds, expected) {
+		t.Fatalf("expected the project quotas to be set with %v, got %v", expected, quotaCmds)
+	}
+	for _, dir := range []string{dataDir, logsDir} {
+		if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+			t.Fatalf("expected directory %q to be created, got %v", dir, err)
+		}
+	}
+}
+
+func TestGetProjectQuotaUsage(t *testing.T) {
+	ctx := context.Background()
+	mountInfo := filepath.Join(t.TempDir(), "mountinfo")
+	content := "22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n" +
+		"30 22 8:16 / /staging rw,relatime shared:2 - ext4 /dev/sdb rw,prjquota\n" +
+		"31 22 8:16 / /target\\040dir rw,relatime shared:2 - ext4 /dev/sdb rw,prjquota\n" +
+		"32 22 8:32 / /noquota rw,relatime - xfs /dev/sdc rw,attr2,noquota\n"
+	if err := os.WriteFile(mountInfo, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	originalMountInfoPath := mountInfoPath
+	mountInfoPath = mountInfo
+	defer func() { mountInfoPath = originalMountInfoPath }()
+
+	var quotaCmds [][]string
+	report := "#0                 20          0          0     00 [--------]\n" +
+		"#1               1024          0   10485760     00 [--------]\n" +
+		"#2                  0          0    1048576     00 [--------]\n"
+	fakeExec := &testingexec.FakeExec{CommandScript: []testingexec.FakeCommandAction{
+		func(cmd string, args ...string) exec.Cmd {
+			quotaCmds = append(quotaCmds, append([]string{cmd}, args...))
+			return testingexec.InitFakeCmd(&testingexec.FakeCmd{CombinedOutputScript: []testingexec.FakeAction{
+				func() ([]byte, []byte, error) { return []byte(report), nil, nil },
+			}}, cmd, args...)
+		},
+	}}
+	osUtils := &OsUtils{Mounter: &mount.SafeFormatAndMount{Interface: mount.NewFakeMounter(nil), Exec: fakeExec}}
+
+	usages, err := osUtils.GetProjectQuotaUsage(ctx, "/target dir/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ProjectQuotaUsage{
+		{ProjectID: 1, UsedBytes: 1 << 20, LimitBytes: 10 << 30},
+		{ProjectID: 2, UsedBytes: 0, LimitBytes: 1 << 30},
+	}
+	if !reflect.DeepEqual(usages, expected) {
+		t.Fatalf("expected the project quota usage %v, got %v", expected, usages)
+	}
+	expectedCmds := [][]string{{"xfs_quota", "-x", "-f", "-c", "report -p -b -n -N", "/target dir/"}}
+	if !reflect.DeepEqual(quotaCmds, expectedCmds) {
+		t.Fatalf("expected the project quotas to be reported with %v, got %v", expectedCmds, quotaCmds)
+	}
+
+	// The volumes mounted without project quotas have no project to report.
+	usages, err = osUtils.GetProjectQuotaUsage(ctx, "/noquota")
+	if err != nil || usages != nil {
+		t.Fatalf("expected no project quota usage for /noquota, got %v, err: %v", usages, err)
+	}
+	if _, err := osUtils.GetProjectQuotaUsage(ctx, "/unknown"); err == nil {
+		t.Fatal("expected an error for a path which is not a mount point")
+	}
+}
@@ -41,6 +41,32 @@ type NodeStageParams struct {
 	MntFlags []string
 	// Read-only flag.
 	Ro bool
+	// LUKS holds the LUKS encryption of the volume, nil when not encrypted.
+	LUKS *LUKSParams
+	// MkfsOptions are the options of mkfs used when formatting the volume.
+	MkfsOptions []string
+	// ProjectQuotaDirectories are the directories of the volume limited by a
+	// project quota.
+	ProjectQuotaDirectories []common.ProjectQuotaDirectory
+}
+
+// LUKSParams holds the params of the LUKS encryption of a volume on the node.
+type LUKSParams struct {
+	// Passphrase unlocks the volume when it comes from a secret.
+	Passphrase []byte
+	// KMSEndpoint is the endpoint of the KMS v2 plugin wrapping the passphrase
+	// of the volume, when the passphrase comes from a KMS.
+	KMSEndpoint string
+}
+
+// LUKSDevice holds the LUKS mapping of the disk of an encrypted volume.
+type LUKSDevice struct {
+	// Mapping is the device mapper device holding the file system.
+	Mapping *Device
+	// Disk is the encrypted disk.
+	Disk *Device
+	// OffsetBytes is the size of the LUKS header at the start of the disk.
+	OffsetBytes int64
 }
 
 // struct to hold params required for NodePublish operation
@@ -68,6 +94,14 @@ type Device struct {
 	RealDev  string // in windows it represents volumeID and in linux it represents device path
 }
 
+// ProjectQuotaUsage holds the usage and the limit of a project quota of a
+// volume on the node.
+type ProjectQuotaUsage struct {
+	ProjectID  uint32
+	UsedBytes  int64
+	LimitBytes int64
+}
+
 // GetDiskID returns the diskID of the disk attached
 func (osUtils *OsUtils) GetDiskID(pubCtx map[string]string, log *zap.SugaredLogger) (string, error) {
 	var diskID string
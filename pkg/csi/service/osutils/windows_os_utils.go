@@ -84,6 +84,18 @@ func (osUtils *OsUtils) NodeStageBlockVolume(
 		return nil, logger.LogNewErrorCodef(log, codes.Internal,
 			"Stage for raw block Volume access type is currently not supported for windows node")
 	}
+	if params.LUKS != nil {
+		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"LUKS encryption is currently not supported for windows node")
+	}
+	if len(params.MkfsOptions) > 0 {
+		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"mkfs options are currently not supported for windows node")
+	}
+	if len(params.ProjectQuotaDirectories) > 0 {
+		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"project quota directories are currently not supported for windows node")
+	}
 
 	// Block Volume with Mount access type.
 	pubCtx := req.GetPublishContext()
@@ -269,6 +281,12 @@ func (osUtils *OsUtils) GetMetrics(ctx context.Context, path string) (*k8svol.Me
 	return metrics, nil
 }
 
+// GetProjectQuotaUsage returns nil, as project quotas are not supported by
+// windows.
+func (osUtils *OsUtils) GetProjectQuotaUsage(ctx context.Context, path string) ([]ProjectQuotaUsage, error) {
+	return nil, nil
+}
+
 // GetBlockSizeBytes returns the Block size in bytes
 func (osUtils *OsUtils) GetBlockSizeBytes(ctx context.Context, devicePath string) (int64, error) {
 	mounter, err := GetMounter(ctx, osUtils)
@@ -312,6 +330,22 @@ func (osUtils *OsUtils) RescanDevice(ctx context.Context, dev *Device) error {
 	return mounter.Rescan(ctx)
 }
 
+// GetLUKSDevice returns nil as LUKS encryption is not supported on windows.
+func (osUtils *OsUtils) GetLUKSDevice(ctx context.Context, volID string) (*LUKSDevice, error) {
+	return nil, nil
+}
+
+// ResizeLUKSDevice is not supported on windows.
+func (osUtils *OsUtils) ResizeLUKSDevice(ctx context.Context, volID string, luksDev *LUKSDevice,
+	params *LUKSParams) error {
+	return status.Error(codes.Unimplemented, "LUKS encryption is currently not supported for windows node")
+}
+
+// CloseLUKSDevice is a no-op as LUKS encryption is not supported on windows.
+func (osUtils *OsUtils) CloseLUKSDevice(ctx context.Context, volID string) error {
+	return nil
+}
+
 // VerifyTargetDir checks if the target path is not empty, exists and is a
 // directory. If targetShouldExist is set to false, then verifyTargetDir
 // returns (false, nil) if the path does not exist. If targetShouldExist is
@@ -17,6 +17,7 @@ limitations under the License.
 package service
 
 import (
+	"context"
 	"net"
 	"os"
 	"strings"
@@ -24,6 +25,8 @@ import (
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
 
 	csitypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/types"
@@ -37,7 +40,7 @@ var (
 type NonBlockingGRPCServer interface {
 	// Start services at the endpoint.
 	Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer,
-		sms csi.SnapshotMetadataServer)
+		sms csi.SnapshotMetadataServer, gcs csi.GroupControllerServer)
 
 	// Stop stops the gRPC server. It immediately closes all open connections
 	// and listeners. It cancels all active RPCs on the server side and the
@@ -62,9 +65,10 @@ type nonBlockingGRPCServer struct {
 }
 
 func (s *nonBlockingGRPCServer) Start(endpoint string, ids csi.IdentityServer,
-	cs csi.ControllerServer, ns csi.NodeServer, sms csi.SnapshotMetadataServer) {
+	cs csi.ControllerServer, ns csi.NodeServer, sms csi.SnapshotMetadataServer,
+	gcs csi.GroupControllerServer) {
 	log := logger.GetLoggerWithNoContext()
-	if err := s.serve(endpoint, ids, cs, ns, sms); err != nil {
+	if err := s.serve(endpoint, ids, cs, ns, sms, gcs); err != nil {
 		log.Errorf("failed to start grpc server. Err: %v", err)
 	}
 }
@@ -90,7 +94,8 @@ func (s *nonBlockingGRPCServer) Stop() {
 }
 
 func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer,
-	cs csi.ControllerServer, ns csi.NodeServer, sms csi.SnapshotMetadataServer) error {
+	cs csi.ControllerServer, ns csi.NodeServer, sms csi.SnapshotMetadataServer,
+	gcs csi.GroupControllerServer) error {
 	log := logger.GetLoggerWithNoContext()
 
 	const (
@@ -114,7 +119,10 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer,
 		return logger.LogNewErrorf(log, "failed to listen: %v", err)
 	}
 
-	server := grpc.NewServer()
+	// Each RPC is traced as a child of the trace context of the sidecar, and
+	// makes its vCenter API calls with the priority of the RPC.
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(tracing.UnaryServerInterceptor(),
+		requestPriorityInterceptor))
 	s.server = server
 
 	// Register the CSI services.
@@ -147,6 +155,13 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer,
 			csi.RegisterSnapshotMetadataServer(s.server, sms)
 			log.Info("snapshot metadata service registered")
 		}
+
+		// Register GroupController service for controller mode if provided.
+		// This service provides the VolumeGroupSnapshot RPCs.
+		if gcs != nil {
+			csi.RegisterGroupControllerServer(s.server, gcs)
+			log.Info("group controller service registered")
+		}
 	} else if strings.EqualFold(mode, "node") {
 		if ns == nil {
 			return logger.LogNewError(log, "node service required when running in node mode")
@@ -165,3 +180,16 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer,
 	}
 	return nil
 }
+
+// requestPriorityInterceptor sets the priority of the vCenter API calls of the
+// CSI RPCs, above the priority of the background syncs. The attach and detach
+// RPCs are given a lower priority than the other user-facing RPCs.
+func requestPriorityInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	priority := cnsvsphere.PriorityUserFacing
+	switch info.FullMethod {
+	case csi.Controller_ControllerPublishVolume_FullMethodName, csi.Controller_ControllerUnpublishVolume_FullMethodName:
+		priority = cnsvsphere.PriorityAttachDetach
+	}
+	return handler(cnsvsphere.WithRequestPriority(ctx, priority), req)
+}
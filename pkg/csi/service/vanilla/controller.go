@@ -36,6 +36,7 @@ import (
 	"github.com/vmware/govmomi/vim25/types"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/migration"
@@ -145,6 +146,7 @@ type controller struct {
 	topologyMgr commoncotypes.ControllerTopologyService
 	csi.UnimplementedControllerServer
 	csi.UnimplementedSnapshotMetadataServer
+	csi.UnimplementedGroupControllerServer
 	topologyCalc TopologyCalculatorInterface
 }
 
@@ -168,10 +170,14 @@ var (
 	// filters out all the potential shared datastores in a volume provisioning call.
 	errAllDSFilteredOut = errors.New("auth service could not find datastore for block volume provisioning")
 
+	// errDatastoreNotFound is returned when no datacenter of the vCenter has a
+	// datastore with the requested URL.
+	errDatastoreNotFound = errors.New("datastore not found")
+
 	// variable for list snapshots
 	CNSSnapshotsForListSnapshots = make([]cnstypes.CnsSnapshotQueryResultEntry, 0)
 	CNSVolumeDetailsMap          = make([]map[string]*utils.CnsVolumeDetails, 0)
-	volumeIDToNodeUUIDMap        = make(map[string]string)
+	volumeIDToNodeUUIDMap        = make(map[string][]string)
 )
 
 // New creates a CNS controller.
@@ -193,7 +199,10 @@ func (c *controller) Init(config *cnsconfig.Config, version string) error {
 		func() bool {
 			return commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeSnapshot)
 		}, false,
-		false)
+		false, cnsvolumeoperationrequest.StoreConfig{
+			Type:   config.Global.CnsVolumeOperationRequestStore,
+			Shards: config.Global.CnsVolumeOperationRequestStoreShards,
+		})
 	if err != nil {
 		log.Errorf("failed to initialize VolumeOperationRequestInterface with error: %v", err)
 		return err
@@ -491,6 +500,21 @@ func (c *controller) createBlockVolumeWithPlacementEngineForMultiVC(ctx context.
 		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
 			"parsing storage class parameters failed with error: %+v", err)
 	}
+	encryptionAttributes, encryptionFaultType, err := getNodeEncryptionAttributes(ctx, req, scParams)
+	if err != nil {
+		return nil, encryptionFaultType, err
+	}
+	mkfsOptionsAttributes, mkfsOptionsFaultType, err := getMkfsOptionsAttributes(ctx, req, scParams)
+	if err != nil {
+		return nil, mkfsOptionsFaultType, err
+	}
+	if fsTypeFaultType, err := validateBlockVolumeFsType(ctx, req); err != nil {
+		return nil, fsTypeFaultType, err
+	}
+	projectQuotaAttributes, projectQuotaFaultType, err := getProjectQuotaAttributes(ctx, req, scParams)
+	if err != nil {
+		return nil, projectQuotaFaultType, err
+	}
 
 	if scParams.CSIMigration == "true" {
 		if len(c.managers.VcenterConfigs) > 1 {
@@ -547,7 +571,18 @@ func (c *controller) createBlockVolumeWithPlacementEngineForMultiVC(ctx context.
 	// Check if requested volume size and source snapshot size matches.
 	volumeSource := req.GetVolumeContentSource()
 	var contentSourceSnapshotID, snapshotDatastoreURL string
-	if volumeSource != nil {
+	var cloneSource *cloneSourceVolume
+	if volumeSource != nil && volumeSource.GetVolume() != nil {
+		var faultType string
+		cloneSource, faultType, err = c.getCloneSourceVolume(ctx, volumeSource.GetVolume().GetVolumeId(),
+			volSizeMB, scParams)
+		if err != nil {
+			return nil, faultType, err
+		}
+		// The clone is created from a snapshot of the source volume, which
+		// pins it to the datastore of the source volume.
+		snapshotDatastoreURL = cloneSource.DatastoreURL
+	} else if volumeSource != nil {
 		sourceSnapshot := volumeSource.GetSnapshot()
 		if sourceSnapshot == nil {
 			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
@@ -615,6 +650,11 @@ func (c *controller) createBlockVolumeWithPlacementEngineForMultiVC(ctx context.
 		VolumeType:              common.BlockVolumeType,
 		ContentSourceSnapshotID: contentSourceSnapshotID,
 	}
+	if cloneSource != nil {
+		// A volume can only be created from a snapshot of the same size, the
+		// clone is expanded to the requested size once created.
+		createVolumeSpec.CapacityMB = cloneSource.SizeInMB
+	}
 	// Check if vCenter task for this volume is already registered as part of
 	// improved idempotency CR.
 	log.Debugf("Checking if vCenter task for volume %s is already registered.", req.Name)
@@ -777,6 +817,46 @@ func (c *controller) createBlockVolumeWithPlacementEngineForMultiVC(ctx context.
 		}
 	}
 
+	var cloneSnapshotID string
+	if cloneSource != nil {
+		if multivCenterTopologyDeployment {
+			// The clone has to be created on the vCenter of the source volume.
+			topologySegmentsList, exists := vcTopologySegmentsMap[cloneSource.VCenterHost]
+			if !exists {
+				return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+					"accessibility requirements %+v do not include any topology segment of vCenter %q "+
+						"hosting source volume %q", topologyRequirement, cloneSource.VCenterHost, cloneSource.VolumeID)
+			}
+			vcTopologySegmentsMap = map[string][]map[string]string{cloneSource.VCenterHost: topologySegmentsList}
+		}
+		if !volTaskAlreadyRegistered {
+			cloneSnapshotID, _, err = common.CreateSnapshotUtil(ctx, cloneSource.VolumeManager,
+				cloneSource.VolumeID, getCloneSnapshotName(req.Name), &cnsvolume.CreateSnapshotExtraParams{
+					IsCSITransactionSupportEnabled: isCSITransactionSupportEnabled,
+				})
+			if err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+					"failed to create snapshot on volume %q to clone it. Error: %+v", cloneSource.VolumeID, err)
+			}
+			log.Infof("Creating volume %q from snapshot %q to clone volume %q", req.Name, cloneSnapshotID,
+				cloneSource.VolumeID)
+			createVolumeSpec.ContentSourceSnapshotID = cloneSnapshotID
+			// completeVolumeClone deletes the snapshot once the clone is
+			// created. Delete it when CreateVolume fails before that, so that
+			// it is not left on the source volume.
+			defer func() {
+				if cloneSnapshotID == "" {
+					return
+				}
+				if _, err := common.DeleteSnapshotUtil(ctx, cloneSource.VolumeManager, cloneSnapshotID,
+					nil); err != nil {
+					log.Errorf("failed to delete snapshot %q taken to clone volume %q. Error: %+v",
+						cloneSnapshotID, cloneSource.VolumeID, err)
+				}
+			}()
+		}
+	}
+
 	if !volTaskAlreadyRegistered {
 		// Iterate through each VC and its accessibility requirements to try and create a volume.
 		// If it fails for any reason, move to the next VC in list.
@@ -993,8 +1073,44 @@ func (c *controller) createBlockVolumeWithPlacementEngineForMultiVC(ctx context.
 			"failed to create volume. Errors encountered: %+v", combinedErrMssgs)
 	}
 
+	if cloneSource != nil {
+		if volumeMgr == nil {
+			volumeMgr, err = GetVolumeManagerFromVCHost(ctx, c.managers, vcHost)
+			if err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+			}
+		}
+		faultType, err = c.completeVolumeClone(ctx, cloneSource, req.Name, cloneSnapshotID, vcHost, volumeMgr,
+			volumeInfo.VolumeID.Id, volSizeMB)
+		if err != nil {
+			return nil, faultType, err
+		}
+		// The snapshot was deleted by completeVolumeClone.
+		cloneSnapshotID = ""
+	}
+
+	if scParams.CSIMigration != "true" &&
+		commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.ChangedBlockTracking) {
+		if volumeMgr == nil {
+			volumeMgr, err = GetVolumeManagerFromVCHost(ctx, c.managers, vcHost)
+			if err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+			}
+		}
+		enableVolumeCBT(ctx, volumeMgr, volumeInfo.VolumeID.Id)
+	}
+
 	attributes := make(map[string]string)
 	attributes[common.AttributeDiskType] = common.DiskTypeBlockVolume
+	for key, value := range encryptionAttributes {
+		attributes[key] = value
+	}
+	for key, value := range mkfsOptionsAttributes {
+		attributes[key] = value
+	}
+	for key, value := range projectQuotaAttributes {
+		attributes[key] = value
+	}
 
 	if scParams.CSIMigration == "true" {
 		volumePath, err := volumeMigrationService.GetVolumePath(ctx, volumeInfo.VolumeID.Id)
@@ -1057,6 +1173,14 @@ func (c *controller) createBlockVolumeWithPlacementEngineForMultiVC(ctx context.
 				},
 			},
 		}
+	} else if cloneSource != nil {
+		resp.Volume.ContentSource = &csi.VolumeContentSource{
+			Type: &csi.VolumeContentSource_Volume{
+				Volume: &csi.VolumeContentSource_VolumeSource{
+					VolumeId: cloneSource.VolumeID,
+				},
+			},
+		}
 	}
 	if len(c.managers.VcenterConfigs) > 1 {
 		// Create CNSVolumeInfo CR for the volume ID.
@@ -1147,7 +1271,24 @@ func (c *controller) createFileVolume(ctx context.Context, req *csi.CreateVolume
 		volumeID                 string
 		vcenter                  *cnsvsphere.VirtualCenter
 		vcHost                   string
+		netPermissions           []*cnsconfig.NetPermissionConfig
 	)
+	if scParams.NodeEncryption != "" || scParams.NodeEncryptionKeySource != "" {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"node encryption is not supported for file volumes")
+	}
+	if scParams.MkfsOptions != "" {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"mkfs options are not supported for file volumes")
+	}
+	if scParams.ProjectQuotaDirectories != "" {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"project quota directories are not supported for file volumes")
+	}
+	netPermissions, faultType, err = c.getFileVolumeNetPermissions(ctx, req, scParams)
+	if err != nil {
+		return nil, faultType, err
+	}
 	// Get operation store
 	var operationStore cnsvolumeoperationrequest.VolumeOperationRequest
 	for _, volMgr := range c.managers.VolumeManagers {
@@ -1259,10 +1400,11 @@ func (c *controller) createFileVolume(ctx context.Context, req *csi.CreateVolume
 			log.Debugf("Topology accessibility requirements per VC are %+v", vcTopologySegmentsMap)
 		}
 		var createVolumeSpec = common.CreateVolumeSpec{
-			CapacityMB: volSizeMB,
-			Name:       req.Name,
-			ScParams:   scParams,
-			VolumeType: common.FileVolumeType,
+			CapacityMB:     volSizeMB,
+			Name:           req.Name,
+			ScParams:       scParams,
+			VolumeType:     common.FileVolumeType,
+			NetPermissions: netPermissions,
 		}
 		var combinedErrMssgs []string
 		if topologyRequirement != nil {
@@ -1472,12 +1614,22 @@ func (c *controller) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequ
 		// For all other cases, the faultType will be set to "csi.fault.Internal" for now.
 		// Later we may need to define different csi faults.
 		volumeCapabilities := req.GetVolumeCapabilities()
-		if err := common.IsValidVolumeCapabilities(ctx, volumeCapabilities); err != nil {
+		isMultiWriterBlockVolume := isMultiWriterBlockVolumeRequest(ctx, volumeCapabilities)
+		if isMultiWriterBlockVolume {
+			if err := common.IsValidMultiWriterBlockVolumeCapabilities(ctx, volumeCapabilities); err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+					"volume capability not supported. Err: %+v", err)
+			}
+		} else if err := common.IsValidVolumeCapabilities(ctx, volumeCapabilities); err != nil {
 			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
 				"volume capability not supported. Err: %+v", err)
 		}
-		if common.IsFileVolumeRequest(ctx, volumeCapabilities) {
+		if !isMultiWriterBlockVolume && common.IsFileVolumeRequest(ctx, volumeCapabilities) {
 			volumeType = prometheus.PrometheusFileVolumeType
+			if req.GetVolumeContentSource() != nil {
+				return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+					"volume content source is not supported for file volumes")
+			}
 			if len(c.managers.VcenterConfigs) > 1 {
 				isvSANFileServicesDisabledInAllVCs := true
 				for _, vcconfig := range c.managers.VcenterConfigs {
@@ -1513,6 +1665,35 @@ func (c *controller) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequ
 			}
 		}
 		volumeType = prometheus.PrometheusBlockVolumeType
+		if len(req.GetMutableParameters()) != 0 {
+			if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeAttributesClass) {
+				return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+					"mutable parameters are not supported")
+			}
+			// The storage policy and datastore of the VolumeAttributesClass
+			// take precedence over the ones of the StorageClass.
+			modifyParams, err := parseModifyVolumeParams(req.GetMutableParameters())
+			if err != nil {
+				return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+					"invalid mutable parameters. Error: %v", err)
+			}
+			params := make(map[string]string)
+			for key, value := range req.Parameters {
+				lowerKey := strings.ToLower(key)
+				if (modifyParams.StoragePolicyName != "" && lowerKey == common.AttributeStoragePolicyName) ||
+					(modifyParams.DatastoreURL != "" && lowerKey == common.AttributeDatastoreURL) {
+					continue
+				}
+				params[key] = value
+			}
+			if modifyParams.StoragePolicyName != "" {
+				params[common.AttributeStoragePolicyName] = modifyParams.StoragePolicyName
+			}
+			if modifyParams.DatastoreURL != "" {
+				params[common.AttributeDatastoreURL] = modifyParams.DatastoreURL
+			}
+			req.Parameters = params
+		}
 		return c.createBlockVolumeWithPlacementEngineForMultiVC(ctx, req)
 	}
 	resp, faultType, err := createVolumeInternal()
@@ -1565,6 +1746,9 @@ func (c *controller) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequ
 		if err != nil {
 			return nil, csifault.CSIInvalidArgumentFault, err
 		}
+		if err := checkVolumeNotInGroupSnapshot(ctx, req.VolumeId); err != nil {
+			return nil, csifault.CSIInternalFault, err
+		}
 		if strings.Contains(req.VolumeId, ".vmdk") {
 			volumeType = prometheus.PrometheusBlockVolumeType
 			cnsVolumeType = common.BlockVolumeType
@@ -1695,7 +1879,9 @@ func (c *controller) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 				"failed to get volume manager for volume Id: %q. Error: %v", req.VolumeId, err)
 		}
 		// Check whether its a block or file volume.
-		if common.IsFileVolumeRequest(ctx, []*csi.VolumeCapability{req.GetVolumeCapability()}) {
+		caps := []*csi.VolumeCapability{req.GetVolumeCapability()}
+		isMultiWriterBlockVolume := isMultiWriterBlockVolumeRequest(ctx, caps)
+		if !isMultiWriterBlockVolume && common.IsFileVolumeRequest(ctx, caps) {
 			volumeType = prometheus.PrometheusFileVolumeType
 			// File Volume.
 			queryFilter := cnstypes.CnsQueryFilter{
@@ -1772,8 +1958,13 @@ func (c *controller) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 			}
 			log.Debugf("Found VirtualMachine for node:%q.", req.NodeId)
 			// faultType is returned from manager.AttachVolume.
-			diskUUID, faultType, err := common.AttachVolumeUtil(ctx, volumeManager, nodevm, req.VolumeId,
-				false)
+			var diskUUID, faultType string
+			if isMultiWriterBlockVolume {
+				diskUUID, faultType, err = attachMultiWriterVolume(ctx, volumeManager, nodevm, req.VolumeId)
+			} else {
+				diskUUID, faultType, err = common.AttachVolumeUtil(ctx, volumeManager, nodevm, req.VolumeId,
+					false)
+			}
 			if err != nil {
 				return nil, faultType, logger.LogNewErrorCodef(log, codes.Internal,
 					"failed to attach disk: %+q with node: %q err %+v", req.VolumeId, req.NodeId, err)
@@ -1838,8 +2029,7 @@ func (c *controller) ControllerUnpublishVolume(ctx context.Context, req *csi.Con
 		if !strings.Contains(req.VolumeId, ".vmdk") {
 			// Check if volume is file volume using volume ID prefix pattern.
 			// File volumes have "file:" prefix, so we can avoid expensive CNS QueryVolume call.
-			const FileVolumePrefix = "file:"
-			if strings.HasPrefix(req.VolumeId, FileVolumePrefix) {
+			if strings.HasPrefix(req.VolumeId, fileVolumePrefix) {
 				volumeType = prometheus.PrometheusFileVolumeType
 				log.Infof("Skipping ControllerUnpublish for file volume %q", req.VolumeId)
 				return &csi.ControllerUnpublishVolumeResponse{}, "", nil
@@ -1956,6 +2146,10 @@ func (c *controller) ControllerExpandVolume(ctx context.Context, req *csi.Contro
 			}
 		}
 
+		if err := checkVolumeNotInGroupSnapshot(ctx, req.VolumeId); err != nil {
+			return nil, csifault.CSIInternalFault, err
+		}
+
 		// Fetch vCenterHost, vCenterManager & volumeManager for given volume, based on VC configuration
 		vCenterManager = getVCenterManagerForVCenter(ctx, c)
 		vCenterHost, volumeManager, err = getVCenterAndVolumeManagerForVolumeID(ctx, c, req.VolumeId, volumeInfoService)
@@ -2061,11 +2255,15 @@ func (c *controller) ValidateVolumeCapabilities(ctx context.Context, req *csi.Va
 	log.Infof("ControllerGetCapabilities: called with args %+v", req)
 	volCaps := req.GetVolumeCapabilities()
 	var confirmed *csi.ValidateVolumeCapabilitiesResponse_Confirmed
-	if err := common.IsValidVolumeCapabilities(ctx, volCaps); err == nil {
+	var message string
+	if err := validateVolumeCapabilitiesForVolume(ctx, req.GetVolumeId(), volCaps); err == nil {
 		confirmed = &csi.ValidateVolumeCapabilitiesResponse_Confirmed{VolumeCapabilities: volCaps}
+	} else {
+		message = err.Error()
 	}
 	return &csi.ValidateVolumeCapabilitiesResponse{
 		Confirmed: confirmed,
+		Message:   message,
 	}, nil
 }
 
@@ -2137,11 +2335,12 @@ func (c *controller) ListVolumes(ctx context.Context, req *csi.ListVolumesReques
 			}
 
 			// Fetching below map once per resync cycle to be used later while processing the volumes
-			volumeIDToNodeUUIDMap, err = getBlockVolumeIDToNodeUUIDMap(ctx, c, allNodeVMs)
+			volumeIDNodeUUIDMap, err := getBlockVolumeIDToNodeUUIDMap(ctx, c, allNodeVMs)
 			if err != nil {
 				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
 					"get block volumeIDToNodeUUIDMap failed with err = %+v ", err)
 			}
+			setVolumeIDToNodeUUIDMap(volumeIDNodeUUIDMap)
 		}
 
 		// Step 3: If the difference between number of K8s volumes and CNS volumes is greater than threshold,
@@ -2200,6 +2399,7 @@ func (c *controller) processQueryResultsListVolumes(ctx context.Context, startin
 	string, string, error) {
 
 	volumeType := ""
+	volumeIDToNodeUUIDMap := getVolumeIDToNodeUUIDMap()
 	nextToken := ""
 	volCounter := 0
 	nextTokenCounter := 0
@@ -2248,7 +2448,7 @@ func (c *controller) processQueryResultsListVolumes(ctx context.Context, startin
 		} else {
 			volumeType = prometheus.PrometheusBlockVolumeType
 			blockVolID := cnsVolumes[i].VolumeId.Id
-			nodeVMUUID, found := volumeIDToNodeUUIDMap[blockVolID]
+			nodeVMUUIDs, found := volumeIDToNodeUUIDMap[blockVolID]
 			if found {
 				volCounter += 1
 				volumeId := blockVolID
@@ -2269,7 +2469,7 @@ func (c *controller) processQueryResultsListVolumes(ctx context.Context, startin
 				}
 				// Getting published nodes
 				volStatus := &csi.ListVolumesResponse_VolumeStatus{
-					PublishedNodeIds: []string{nodeVMUUID},
+					PublishedNodeIds: nodeVMUUIDs,
 				}
 				entry := &csi.ListVolumesResponse_Entry{
 					Volume: blockVolumeInfo,
@@ -2295,12 +2495,244 @@ func (c *controller) processQueryResultsListVolumes(ctx context.Context, startin
 	return entries, nextToken, volumeType, nil
 }
 
+// GetCapacity returns the capacity available for provisioning volumes with
+// the given StorageClass parameters in the requested topology segment.
+// AvailableCapacity is the free space summed across all candidate datastores,
+// while MaximumVolumeSize is the free space on the largest candidate, as a
+// single volume cannot span datastores.
 func (c *controller) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (
 	*csi.GetCapacityResponse, error) {
+	start := time.Now()
 	ctx = logger.NewContextWithLogger(ctx)
 	log := logger.GetLogger(ctx)
 	log.Infof("GetCapacity: called with args %+v", req)
-	return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "getCapacity")
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.StorageCapacityTracking) {
+		return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "getCapacity")
+	}
+	volumeType := prometheus.PrometheusUnknownVolumeType
+
+	getCapacityInternal := func() (*csi.GetCapacityResponse, string, error) {
+		volumeCapabilities := req.GetVolumeCapabilities()
+		isMultiWriterBlockVolume := isMultiWriterBlockVolumeRequest(ctx, volumeCapabilities)
+		if isMultiWriterBlockVolume {
+			if err := common.IsValidMultiWriterBlockVolumeCapabilities(ctx, volumeCapabilities); err != nil {
+				return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+					"volume capability not supported. Err: %+v", err)
+			}
+		} else if len(volumeCapabilities) != 0 {
+			if err := common.IsValidVolumeCapabilities(ctx, volumeCapabilities); err != nil {
+				return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+					"volume capability not supported. Err: %+v", err)
+			}
+		}
+		scParams, err := common.ParseStorageClassParams(ctx, getStorageClassParamsForCapacity(req.GetParameters()))
+		if err != nil {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"parsing storage class parameters failed with error: %+v", err)
+		}
+		isFileVolume := !isMultiWriterBlockVolume && len(volumeCapabilities) != 0 &&
+			common.IsFileVolumeRequest(ctx, volumeCapabilities)
+		if isFileVolume {
+			volumeType = prometheus.PrometheusFileVolumeType
+		} else {
+			volumeType = prometheus.PrometheusBlockVolumeType
+		}
+		candidateDatastores, faultType, err := c.getDatastoresForCapacity(ctx, req.GetAccessibleTopology(),
+			scParams, isFileVolume)
+		if err != nil {
+			return nil, faultType, err
+		}
+		if scParams.DatastoreURL != "" && len(candidateDatastores) != 0 &&
+			!isDatastoreURLInList(candidateDatastores, scParams.DatastoreURL) {
+			if len(req.GetAccessibleTopology().GetSegments()) == 0 {
+				// Same input fails CreateVolume, so don't report it as zero capacity.
+				return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+					"datastoreURL: %s specified in the storage class is not found in the list of "+
+						"compatible shared datastores", scParams.DatastoreURL)
+			}
+			log.Warnf("GetCapacity: datastoreURL: %s specified in the storage class is not accessible "+
+				"in topology segment %+v or is not compatible with it. Reporting no capacity.",
+				scParams.DatastoreURL, req.GetAccessibleTopology().GetSegments())
+		}
+		availableCapacity, maximumVolumeSize := getCapacityFromDatastores(ctx, candidateDatastores,
+			scParams.DatastoreURL)
+		log.Infof("GetCapacity: available capacity %d bytes, maximum volume size %d bytes for topology %+v",
+			availableCapacity, maximumVolumeSize, req.GetAccessibleTopology().GetSegments())
+		return &csi.GetCapacityResponse{
+			AvailableCapacity: availableCapacity,
+			MaximumVolumeSize: wrapperspb.Int64(maximumVolumeSize),
+		}, "", nil
+	}
+	resp, faultType, err := getCapacityInternal()
+	if err != nil {
+		if csifault.IsNonStorageFault(faultType) {
+			faultType = csifault.AddCsiNonStoragePrefix(ctx, faultType)
+		}
+		log.Errorf("Operation failed, reporting failure status to Prometheus."+
+			" Operation Type: %q, Volume Type: %q, Fault Type: %q",
+			prometheus.PrometheusGetCapacityOpType, volumeType, faultType)
+		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusGetCapacityOpType,
+			prometheus.PrometheusFailStatus, faultType).Observe(time.Since(start).Seconds())
+	} else {
+		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusGetCapacityOpType,
+			prometheus.PrometheusPassStatus, faultType).Observe(time.Since(start).Seconds())
+	}
+	return resp, err
+}
+
+// getDatastoresForCapacity returns the datastores on which a volume with the
+// given StorageClass parameters could be provisioned within the accessible
+// topology. Block volumes consider the datastores shared by all hosts in the
+// topology segment, file volumes consider the vSAN file service enabled
+// datastores accessible from it.
+func (c *controller) getDatastoresForCapacity(ctx context.Context, accessibleTopology *csi.Topology,
+	scParams *common.StorageClassParams, isFileVolume bool) ([]*cnsvsphere.DatastoreInfo, string, error) {
+	log := logger.GetLogger(ctx)
+	var (
+		vcHost               string
+		topologySegmentsList []map[string]string
+		err                  error
+	)
+	if len(accessibleTopology.GetSegments()) != 0 {
+		// NOTE: We do not support kubernetes.io/hostname as a topology label.
+		if c.managers.CnsConfig.Labels.TopologyCategories == "" && c.managers.CnsConfig.Labels.Zone == "" &&
+			c.managers.CnsConfig.Labels.Region == "" {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+				"topology category names not specified in the vsphere config secret")
+		}
+		if len(c.managers.VcenterConfigs) > 1 {
+			vcTopologySegmentsMap, err := common.GetAccessibilityRequirementsByVC(ctx,
+				&csi.TopologyRequirement{Preferred: []*csi.Topology{accessibleTopology}})
+			if err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+					"failed to get vCenter for topology segments %+v. Error: %+v",
+					accessibleTopology.GetSegments(), err)
+			}
+			if len(vcTopologySegmentsMap) != 1 {
+				return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+					"topology segments %+v resolve to %d vCenters. Expected exactly one.",
+					accessibleTopology.GetSegments(), len(vcTopologySegmentsMap))
+			}
+			for vcHost, topologySegmentsList = range vcTopologySegmentsMap {
+				// Only one entry, validated above.
+			}
+		} else {
+			vcHost = c.managers.CnsConfig.Global.VCenterIP
+			topologySegmentsList = []map[string]string{accessibleTopology.GetSegments()}
+		}
+	} else {
+		if len(c.managers.VcenterConfigs) > 1 {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+				"accessible topology cannot be nil for a multi-VC environment")
+		}
+		vcHost = c.managers.CnsConfig.Global.VCenterIP
+	}
+	vcenter, err := common.GetVCenterFromVCHost(ctx, c.managers.VcenterManager, vcHost)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get vCenter instance for host %q. Error: %+v", vcHost, err)
+	}
+	var storagePolicyID string
+	if scParams.StoragePolicyName != "" {
+		storagePolicyID, err = vcenter.GetStoragePolicyIDByName(ctx, scParams.StoragePolicyName)
+		if err != nil {
+			// A storage policy missing on this vCenter means no capacity can be
+			// offered in this topology segment, rather than a failure.
+			errMssgFromPBM := fmt.Sprintf("no pbm profile found with name: %q", scParams.StoragePolicyName)
+			if err.Error() == errMssgFromPBM {
+				log.Infof("Storage policy name %q not found in VC %q. Reporting no capacity.",
+					scParams.StoragePolicyName, vcHost)
+				return nil, "", nil
+			}
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get policy ID for storage policy name %q. Error: %+v",
+				scParams.StoragePolicyName, err)
+		}
+	}
+
+	var datastores []*cnsvsphere.DatastoreInfo
+	if isFileVolume {
+		if c.authMgrs[vcHost] == nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"authorization service not found for vCenter %q", vcHost)
+		}
+		var fsEnabledDatastores []*cnsvsphere.DatastoreInfo
+		for _, dsList := range c.authMgrs[vcHost].GetFsEnabledClusterToDsMap(ctx) {
+			fsEnabledDatastores = append(fsEnabledDatastores, dsList...)
+		}
+		if len(topologySegmentsList) != 0 {
+			accessibleDatastores, err := placementengine.GetAllAccessibleDSInTopology(ctx,
+				topologySegmentsList, vcenter)
+			if err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+					"failed to get accessible datastores in topology %+v for VC %q. Error: %+v",
+					topologySegmentsList, vcHost, err)
+			}
+			for _, ds := range accessibleDatastores {
+				for _, fsEnabledDatastore := range fsEnabledDatastores {
+					if ds.Info.Url == fsEnabledDatastore.Info.Url {
+						datastores = append(datastores, ds)
+						break
+					}
+				}
+			}
+		} else {
+			datastores = fsEnabledDatastores
+		}
+		if storagePolicyID != "" {
+			datastores, err = filterDatastoresByStoragePolicy(ctx, vcenter, datastores, storagePolicyID)
+			if err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+			}
+		}
+		// The file service enabled datastores come from the periodically
+		// refreshed auth manager cache, so their free space may be stale.
+		datastores, err = refreshDatastoreFreeSpace(ctx, vcenter, datastores)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+		}
+		return datastores, "", nil
+	}
+
+	if len(topologySegmentsList) != 0 {
+		// GetSharedDatastores takes care of the storage policy compatibility.
+		datastores, err = placementengine.GetSharedDatastores(ctx,
+			placementengine.VanillaSharedDatastoresParams{
+				Vcenter:              vcenter,
+				TopologySegmentsList: topologySegmentsList,
+				StoragePolicyID:      storagePolicyID,
+			})
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get shared datastores for topology segments %+v in vCenter %q. Error: %+v",
+				topologySegmentsList, vcHost, err)
+		}
+	} else {
+		datastores, err = c.nodeMgr.GetSharedDatastoresInK8SCluster(ctx)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get shared datastores in kubernetes cluster. Error: %+v", err)
+		}
+		if storagePolicyID != "" {
+			datastores, err = filterDatastoresByStoragePolicy(ctx, vcenter, datastores, storagePolicyID)
+			if err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+			}
+		}
+	}
+	if len(datastores) == 0 {
+		return nil, "", nil
+	}
+	// Only datastores the CSI user is authorized to provision on count towards capacity.
+	datastores, err = c.filterDatastores(ctx, datastores, vcHost)
+	if err != nil {
+		if err == errAllDSFilteredOut {
+			return nil, "", nil
+		}
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to filter datastores based on authorisation check in vCenter %q. Error: %+v", vcHost, err)
+	}
+	return datastores, "", nil
 }
 
 // initVolumeMigrationService is a helper method to initialize
@@ -2352,6 +2784,19 @@ func (c *controller) ControllerGetCapabilities(ctx context.Context, req *csi.Con
 		controllerCaps = append(controllerCaps, csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
 			csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES)
 	}
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.StorageCapacityTracking) {
+		controllerCaps = append(controllerCaps, csi.ControllerServiceCapability_RPC_GET_CAPACITY)
+	}
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeClone) {
+		controllerCaps = append(controllerCaps, csi.ControllerServiceCapability_RPC_CLONE_VOLUME)
+	}
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeAttributesClass) {
+		controllerCaps = append(controllerCaps, csi.ControllerServiceCapability_RPC_MODIFY_VOLUME)
+	}
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeCondition) {
+		controllerCaps = append(controllerCaps, csi.ControllerServiceCapability_RPC_GET_VOLUME,
+			csi.ControllerServiceCapability_RPC_VOLUME_CONDITION)
+	}
 	var caps []*csi.ControllerServiceCapability
 	for _, cap := range controllerCaps {
 		c := &csi.ControllerServiceCapability{
@@ -2478,6 +2923,13 @@ func (c *controller) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 				volumeID, maxSnapshotsPerBlockVolume)
 		}
 
+		isCBTEnabled := commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.ChangedBlockTracking)
+		if isCBTEnabled {
+			// Volumes created before CBT was turned on do not have it enabled yet.
+			// Enabling it here gives the snapshot a change-id that later deltas can be based on.
+			enableVolumeCBT(ctx, volumeManager, volumeID)
+		}
+
 		// the returned snapshotID below is a combination of CNS VolumeID and CNS SnapshotID concatenated by the "+"
 		// sign. That is, a string of "<UUID>+<UUID>". Because, all other CNS snapshot APIs still require both
 		// VolumeID and SnapshotID as the input, while corresponding snapshot APIs in upstream CSI require SnapshotID.
@@ -2507,6 +2959,21 @@ func (c *controller) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 			"on volume %s size %d Time proto %+v Timestamp %+v Response: %+v",
 			snapshotID, volumeID, snapshotSizeInMB*common.MbInBytes, snapshotCreateTimeInProto,
 			cnsSnapshotInfo.SnapshotLatestOperationCompleteTime, createSnapshotResponse)
+
+		// Record the change-id of the snapshot on the VolumeSnapshot. Backup software passes it
+		// back as base_snapshot_id of GetMetadataDelta to fetch the blocks changed since.
+		if isCBTEnabled && cnsSnapshotInfo.ChangedBlockTrackingId != "" {
+			volumeSnapshotName := req.Parameters[common.VolumeSnapshotNameKey]
+			volumeSnapshotNamespace := req.Parameters[common.VolumeSnapshotNamespaceKey]
+			annotations := map[string]string{common.VolumeSnapshotChangeIDKey: cnsSnapshotInfo.ChangedBlockTrackingId}
+			annotated, err := commonco.ContainerOrchestratorUtility.AnnotateVolumeSnapshot(ctx, volumeSnapshotName,
+				volumeSnapshotNamespace, annotations)
+			if err != nil || !annotated {
+				log.Warnf("The snapshot: %s was created successfully, but failed to annotate volumesnapshot %s/%s "+
+					"with annotations %v. Error: %v", snapshotID, volumeSnapshotNamespace, volumeSnapshotName,
+					annotations, err)
+			}
+		}
 		return createSnapshotResponse, nil
 	}
 
@@ -2870,18 +3337,107 @@ func processQueryResultsListSnapshots(ctx context.Context, startingToken int,
 	return snapEntries, nextToken, nil
 }
 
+// ControllerGetVolume returns the capacity, the published nodes and the
+// condition of a volume. The volume is abnormal when CNS reports it as
+// inaccessible or when its datastore is not accessible.
 func (c *controller) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (
 	*csi.ControllerGetVolumeResponse, error) {
+	start := time.Now()
 	ctx = logger.NewContextWithLogger(ctx)
 	log := logger.GetLogger(ctx)
 	log.Infof("ControllerGetVolume: called with args %+v", req)
-	return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "controllerGetVolume")
-}
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeCondition) {
+		return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "controllerGetVolume")
+	}
+	volumeType := prometheus.PrometheusUnknownVolumeType
 
-func (c *controller) ControllerModifyVolume(ctx context.Context, req *csi.ControllerModifyVolumeRequest) (
-	*csi.ControllerModifyVolumeResponse, error) {
-	ctx = logger.NewContextWithLogger(ctx)
-	log := logger.GetLogger(ctx)
-	log.Infof("ControllerModifyVolume: called with args %+v", req)
-	return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "ControllerModifyVolume")
+	controllerGetVolumeInternal := func() (*csi.ControllerGetVolumeResponse, string, error) {
+		if req.GetVolumeId() == "" {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+				"volume ID must be provided")
+		}
+		volumeID := req.GetVolumeId()
+		if strings.Contains(volumeID, ".vmdk") {
+			if err := initVolumeMigrationService(ctx, c); err != nil {
+				// Error is already wrapped in CSI error code.
+				return nil, csifault.CSIInternalFault, err
+			}
+			var err error
+			volumeID, err = volumeMigrationService.GetVolumeID(ctx,
+				&migration.VolumeSpec{VolumePath: req.GetVolumeId()}, false)
+			if err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+					"failed to get VolumeID from volumeMigrationService for volumePath: %q", req.GetVolumeId())
+			}
+		}
+		vCenterHost, volumeManager, err := getVCenterAndVolumeManagerForVolumeID(ctx, c, volumeID,
+			volumeInfoService)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get vCenter/volume manager for volume Id: %q. Error: %v", volumeID, err)
+		}
+		queryFilter := cnstypes.CnsQueryFilter{
+			VolumeIds: []cnstypes.CnsVolumeId{{Id: volumeID}},
+		}
+		queryResult, err := volumeManager.QueryVolume(ctx, queryFilter)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to query volume %q. Error: %+v", volumeID, err)
+		}
+		if len(queryResult.Volumes) == 0 {
+			return nil, csifault.CSINotFoundFault, logger.LogNewErrorCodef(log, codes.NotFound,
+				"volume %q not found", volumeID)
+		}
+		cnsVolume := queryResult.Volumes[0]
+		volumeType = convertCnsVolumeType(ctx, cnsVolume.VolumeType)
+
+		var capacityInMb int64
+		if cnsVolume.BackingObjectDetails != nil {
+			capacityInMb = cnsVolume.BackingObjectDetails.GetCnsBackingObjectDetails().CapacityInMb
+		}
+		var publishedNodeIDs []string
+		if cnsVolume.VolumeType == common.BlockVolumeType {
+			// The map is shared with ListVolumes and rebuilt at most once per
+			// volumeIDToNodeUUIDMapTTL, as building it reads every node VM.
+			volumeIDToNodeUUIDMap, err := getCachedBlockVolumeIDToNodeUUIDMap(ctx, c)
+			if err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+					"get block volumeIDToNodeUUIDMap failed with err = %+v ", err)
+			}
+			publishedNodeIDs = volumeIDToNodeUUIDMap[volumeID]
+		}
+		volumeCondition, err := c.getVolumeCondition(ctx, vCenterHost, &cnsVolume)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get condition of volume %q. Error: %+v", volumeID, err)
+		}
+		resp := &csi.ControllerGetVolumeResponse{
+			Volume: &csi.Volume{
+				VolumeId:      req.GetVolumeId(),
+				CapacityBytes: capacityInMb * common.MbInBytes,
+			},
+			Status: &csi.ControllerGetVolumeResponse_VolumeStatus{
+				PublishedNodeIds: publishedNodeIDs,
+				VolumeCondition:  volumeCondition,
+			},
+		}
+		return resp, "", nil
+	}
+
+	resp, faultType, err := controllerGetVolumeInternal()
+	if err != nil {
+		if csifault.IsNonStorageFault(faultType) {
+			faultType = csifault.AddCsiNonStoragePrefix(ctx, faultType)
+		}
+		log.Errorf("Operation failed, reporting failure status to Prometheus."+
+			" Operation Type: %q, Volume Type: %q, Fault Type: %q",
+			prometheus.PrometheusGetVolumeOpType, volumeType, faultType)
+		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusGetVolumeOpType,
+			prometheus.PrometheusFailStatus, faultType).Observe(time.Since(start).Seconds())
+	} else {
+		log.Debugf("ControllerGetVolume: returns %+v", resp)
+		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusGetVolumeOpType,
+			prometheus.PrometheusPassStatus, faultType).Observe(time.Since(start).Seconds())
+	}
+	return resp, err
 }
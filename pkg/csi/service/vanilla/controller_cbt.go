@@ -0,0 +1,367 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vanilla
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+)
+
+const (
+	// defaultMaxResults defines the default maximum number of blocks to return in a single gRPC stream
+	// if the CSI caller (e.g. external-snapshot-metadata) passes 0 (no limit).
+	defaultMaxResults = 10000
+)
+
+// vslmErrorToCSICode returns the gRPC status code from err if it is already a
+// gRPC status error (e.g. one produced by volume.TranslateVslmError). Errors
+// without a status default to codes.Internal.
+func vslmErrorToCSICode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	if c := status.Code(err); c != codes.Unknown {
+		return c
+	}
+	return codes.Internal
+}
+
+// enableVolumeCBT enables Changed Block Tracking on the given block volume.
+// It is best effort: a volume without CBT can still be provisioned and
+// snapshotted, the backup software then falls back to a full backup.
+func enableVolumeCBT(ctx context.Context, volumeManager cnsvolume.Manager, volumeID string) {
+	log := logger.GetLogger(ctx)
+	if err := common.SetVolumeCbtFlagsUtil(ctx, volumeManager, volumeID); err != nil {
+		log.Warnf("failed to enable CBT for volume %s: %v", volumeID, err)
+		return
+	}
+	log.Infof("Successfully enabled CBT for volume %s", volumeID)
+}
+
+// getBlockVolumeCapacityForCBT resolves the volume manager of the vCenter the
+// volume belongs to and returns it along with the volume capacity in bytes.
+// Only block volumes are supported by the SnapshotMetadata service.
+func (c *controller) getBlockVolumeCapacityForCBT(ctx context.Context, volumeID string, rpcName string) (
+	cnsvolume.Manager, int64, error) {
+	log := logger.GetLogger(ctx)
+	_, volumeManager, err := getVCenterAndVolumeManagerForVolumeID(ctx, c, volumeID, volumeInfoService)
+	if err != nil {
+		return nil, 0, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get vCenter/volume manager for volume Id: %q. Error: %v", volumeID, err)
+	}
+
+	queryResult, err := volumeManager.QueryVolume(ctx, cnstypes.CnsQueryFilter{
+		VolumeIds: []cnstypes.CnsVolumeId{{Id: volumeID}},
+	})
+	if err != nil {
+		return nil, 0, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to query volume %s: %v", volumeID, err)
+	}
+	if len(queryResult.Volumes) == 0 {
+		return nil, 0, logger.LogNewErrorCodef(log, codes.NotFound,
+			"volume %s not found", volumeID)
+	}
+
+	cnsVolumeType := queryResult.Volumes[0].VolumeType
+	if cnsVolumeType != common.BlockVolumeType {
+		return nil, 0, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"%s is only supported for block volumes, got volume type: %s", rpcName, cnsVolumeType)
+	}
+	blockBacking, ok := queryResult.Volumes[0].BackingObjectDetails.(*cnstypes.CnsBlockBackingDetails)
+	if !ok {
+		return nil, 0, logger.LogNewErrorCodef(log, codes.Internal,
+			"volume %s does not have block backing details", volumeID)
+	}
+	return volumeManager, blockBacking.CapacityInMb * common.MbInBytes, nil
+}
+
+// GetMetadataAllocated returns the allocated blocks of a snapshot using FCD VSLM APIs.
+func (c *controller) GetMetadataAllocated(req *csi.GetMetadataAllocatedRequest,
+	server csi.SnapshotMetadata_GetMetadataAllocatedServer) error {
+	ctx := server.Context()
+	ctx = logger.NewContextWithLogger(ctx)
+	log := logger.GetLogger(ctx)
+	log.Infof("GetMetadataAllocated: called with args %+v", req)
+
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.ChangedBlockTracking) {
+		return logger.LogNewErrorCode(log, codes.Unimplemented, "GetMetadataAllocated")
+	}
+
+	start := time.Now()
+	volumeType := prometheus.PrometheusBlockVolumeType
+
+	getMetadataAllocatedInternal := func() error {
+		if err := validateGetMetadataAllocatedRequest(ctx, req); err != nil {
+			return logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"validation for GetMetadataAllocated Request: %+v has failed. Error: %v", req, err)
+		}
+
+		snapshotID := req.GetSnapshotId()
+		maxResults := req.GetMaxResults()
+		if maxResults == 0 || maxResults > defaultMaxResults {
+			// CSI spec: If zero, the Plugin MUST choose a reasonable maximum number of results.
+			maxResults = defaultMaxResults
+		}
+
+		// CSI snapshot ID format: "volumeID+snapshotID"
+		volumeID, cnsSnapshotID, err := common.ParseCSISnapshotID(snapshotID)
+		if err != nil {
+			return logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"failed to parse snapshot ID %s: %v", snapshotID, err)
+		}
+		log.Infof("GetMetadataAllocated: querying allocated blocks for volume %s, snapshot %s, offset %d, max %d",
+			volumeID, cnsSnapshotID, req.GetStartingOffset(), maxResults)
+
+		volumeManager, volumeCapacityBytes, err := c.getBlockVolumeCapacityForCBT(ctx, volumeID,
+			"GetMetadataAllocated")
+		if err != nil {
+			return err
+		}
+
+		// Stream all allocated blocks from startingOffset, re-querying from
+		// nextOffset until QueryFCDAllocatedBlocks reports the end of the disk.
+		currentOffset := uint64(req.GetStartingOffset())
+		totalBlocks := 0
+		for {
+			allocatedAreas, nextOffset, err := volumeManager.QueryFCDAllocatedBlocks(
+				ctx, volumeID, cnsSnapshotID, currentOffset)
+			if err != nil {
+				return logger.LogNewErrorCodef(log, vslmErrorToCSICode(err),
+					"failed to query allocated blocks: %v", err)
+			}
+			for _, blockMetadata := range toBlockMetadataBatches(allocatedAreas, int(maxResults)) {
+				if err := server.Send(&csi.GetMetadataAllocatedResponse{
+					BlockMetadata:       blockMetadata,
+					VolumeCapacityBytes: volumeCapacityBytes,
+					BlockMetadataType:   csi.BlockMetadataType_VARIABLE_LENGTH,
+				}); err != nil {
+					return logger.LogNewErrorCodef(log, codes.Internal,
+						"failed to send allocated metadata to client: %v", err)
+				}
+			}
+			totalBlocks += len(allocatedAreas)
+			if nextOffset == uint64(volumeCapacityBytes) {
+				break
+			}
+			currentOffset = nextOffset
+		}
+
+		// If no blocks are found, send an empty response to indicate that the snapshot is empty.
+		if totalBlocks == 0 {
+			if err := server.Send(&csi.GetMetadataAllocatedResponse{
+				VolumeCapacityBytes: volumeCapacityBytes,
+				BlockMetadataType:   csi.BlockMetadataType_VARIABLE_LENGTH,
+			}); err != nil {
+				return logger.LogNewErrorCodef(log, codes.Internal,
+					"failed to send allocated metadata to client: %v", err)
+			}
+		}
+
+		log.Infof("GetMetadataAllocated succeeded for snapshot %s, streamed %d allocated blocks total",
+			snapshotID, totalBlocks)
+		return nil
+	}
+
+	err := getMetadataAllocatedInternal()
+	if err != nil {
+		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, "GetMetadataAllocated",
+			prometheus.PrometheusFailStatus, "NotComputed").Observe(time.Since(start).Seconds())
+		return err
+	}
+	prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, "GetMetadataAllocated",
+		prometheus.PrometheusPassStatus, "").Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// GetMetadataDelta returns the blocks changed between two snapshots using FCD VSLM APIs.
+//
+// base_snapshot_id is the vSphere CBT change-id of the base snapshot, i.e. the
+// `csi.vsphere.volume/change-id` annotation set on the VolumeSnapshot by CreateSnapshot.
+// target_snapshot_id is the CSI snapshot handle ("volID+snapID") of the target snapshot,
+// which must still exist. The volume ID is therefore taken from target_snapshot_id only.
+func (c *controller) GetMetadataDelta(req *csi.GetMetadataDeltaRequest,
+	server csi.SnapshotMetadata_GetMetadataDeltaServer) error {
+	ctx := server.Context()
+	ctx = logger.NewContextWithLogger(ctx)
+	log := logger.GetLogger(ctx)
+	log.Infof("GetMetadataDelta: called with args %+v", req)
+
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.ChangedBlockTracking) {
+		return logger.LogNewErrorCode(log, codes.Unimplemented, "GetMetadataDelta")
+	}
+
+	start := time.Now()
+	volumeType := prometheus.PrometheusBlockVolumeType
+
+	getMetadataDeltaInternal := func() error {
+		if err := validateGetMetadataDeltaRequest(ctx, req); err != nil {
+			return logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"validation for GetMetadataDelta Request: %+v has failed. Error: %v", req, err)
+		}
+
+		baseChangeID := req.GetBaseSnapshotId()
+		targetSnapshotID := req.GetTargetSnapshotId()
+		maxResults := req.GetMaxResults()
+		if maxResults == 0 || maxResults > defaultMaxResults {
+			// CSI spec: If zero, the Plugin MUST choose a reasonable maximum number of results.
+			maxResults = defaultMaxResults
+		}
+
+		volumeID, targetCnsSnapshotID, err := common.ParseCSISnapshotID(targetSnapshotID)
+		if err != nil {
+			return logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"failed to parse target snapshot ID %s: %v", targetSnapshotID, err)
+		}
+		log.Infof("GetMetadataDelta: querying changed blocks for volume %s, "+
+			"target snapshot %s, base change-id %s, offset %d, max %d",
+			volumeID, targetCnsSnapshotID, baseChangeID, req.GetStartingOffset(), maxResults)
+
+		volumeManager, volumeCapacityBytes, err := c.getBlockVolumeCapacityForCBT(ctx, volumeID,
+			"GetMetadataDelta")
+		if err != nil {
+			return err
+		}
+
+		// Stream all changed blocks from startingOffset, re-querying from
+		// nextOffset until QueryFCDChangedBlocks reports the end of the disk.
+		currentOffset := uint64(req.GetStartingOffset())
+		totalBlocks := 0
+		for {
+			changedAreas, nextOffset, err := volumeManager.QueryFCDChangedBlocks(
+				ctx, volumeID, targetCnsSnapshotID, baseChangeID, currentOffset)
+			if err != nil {
+				return logger.LogNewErrorCodef(log, vslmErrorToCSICode(err),
+					"failed to query changed blocks: %v", err)
+			}
+			for _, blockMetadata := range toBlockMetadataBatches(changedAreas, int(maxResults)) {
+				if err := server.Send(&csi.GetMetadataDeltaResponse{
+					BlockMetadata:       blockMetadata,
+					VolumeCapacityBytes: volumeCapacityBytes,
+					BlockMetadataType:   csi.BlockMetadataType_VARIABLE_LENGTH,
+				}); err != nil {
+					return logger.LogNewErrorCodef(log, codes.Internal,
+						"failed to send delta metadata to client: %v", err)
+				}
+			}
+			totalBlocks += len(changedAreas)
+			if nextOffset == uint64(volumeCapacityBytes) {
+				break
+			}
+			currentOffset = nextOffset
+		}
+
+		// If no blocks are found, send an empty response to indicate that the snapshot delta is empty.
+		if totalBlocks == 0 {
+			if err := server.Send(&csi.GetMetadataDeltaResponse{
+				VolumeCapacityBytes: volumeCapacityBytes,
+				BlockMetadataType:   csi.BlockMetadataType_VARIABLE_LENGTH,
+			}); err != nil {
+				return logger.LogNewErrorCodef(log, codes.Internal,
+					"failed to send delta metadata to client: %v", err)
+			}
+		}
+
+		log.Infof("GetMetadataDelta succeeded for target snapshot %s, streamed %d changed blocks total",
+			targetSnapshotID, totalBlocks)
+		return nil
+	}
+
+	err := getMetadataDeltaInternal()
+	if err != nil {
+		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, "GetMetadataDelta",
+			prometheus.PrometheusFailStatus, "NotComputed").Observe(time.Since(start).Seconds())
+		return err
+	}
+	prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, "GetMetadataDelta",
+		prometheus.PrometheusPassStatus, "").Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// toBlockMetadataBatches converts the areas returned by VSLM to CSI block
+// metadata, split into batches of at most maxResults entries.
+func toBlockMetadataBatches(areas []cnsvolume.DiskArea, maxResults int) [][]*csi.BlockMetadata {
+	var batches [][]*csi.BlockMetadata
+	for i := 0; i < len(areas); i += maxResults {
+		end := min(i+maxResults, len(areas))
+		blockMetadata := make([]*csi.BlockMetadata, 0, end-i)
+		for _, area := range areas[i:end] {
+			blockMetadata = append(blockMetadata, &csi.BlockMetadata{
+				ByteOffset: int64(area.Offset),
+				SizeBytes:  int64(area.Length),
+			})
+		}
+		batches = append(batches, blockMetadata)
+	}
+	return batches
+}
+
+// validateGetMetadataAllocatedRequest validates the GetMetadataAllocated request.
+func validateGetMetadataAllocatedRequest(ctx context.Context, req *csi.GetMetadataAllocatedRequest) error {
+	log := logger.GetLogger(ctx)
+	if req == nil {
+		return fmt.Errorf("GetMetadataAllocated request is nil")
+	}
+	if req.SnapshotId == "" {
+		return fmt.Errorf("snapshot ID is required")
+	}
+	if req.StartingOffset < 0 {
+		return fmt.Errorf("starting_offset must be non-negative value")
+	}
+	if req.MaxResults < 0 {
+		return fmt.Errorf("max_results must be non-negative value")
+	}
+	log.Debugf("GetMetadataAllocated request validation passed")
+	return nil
+}
+
+// validateGetMetadataDeltaRequest validates the GetMetadataDelta request.
+func validateGetMetadataDeltaRequest(ctx context.Context, req *csi.GetMetadataDeltaRequest) error {
+	log := logger.GetLogger(ctx)
+	if req == nil {
+		return fmt.Errorf("GetMetadataDelta request is nil")
+	}
+	if req.BaseSnapshotId == "" {
+		return fmt.Errorf("base snapshot ID (vSphere change-id) is required")
+	}
+	if !common.IsValidChangeId(req.BaseSnapshotId) {
+		return fmt.Errorf("base_snapshot_id %q has invalid format; provide a valid vSphere changeID",
+			req.BaseSnapshotId)
+	}
+	if req.TargetSnapshotId == "" {
+		return fmt.Errorf("target snapshot ID is required")
+	}
+	if req.StartingOffset < 0 {
+		return fmt.Errorf("starting_offset must be non-negative value")
+	}
+	if req.MaxResults < 0 {
+		return fmt.Errorf("max_results must be non-negative value")
+	}
+	log.Debugf("GetMetadataDelta request validation passed")
+	return nil
+}
@@ -0,0 +1,326 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vanilla
+
+import (
+	"context"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/google/uuid"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/unittestcommon"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+)
+
+const testValidChangeID = "52 21 4f 8a 5e 47 9c bd-3b ff e0 12 a3 4c 56 78/12"
+
+type mockAllocatedServer struct {
+	grpc.ServerStream
+	ctx       context.Context
+	sendCount int
+	allBlocks []*csi.BlockMetadata
+}
+
+func (m *mockAllocatedServer) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockAllocatedServer) Send(resp *csi.GetMetadataAllocatedResponse) error {
+	m.sendCount++
+	m.allBlocks = append(m.allBlocks, resp.BlockMetadata...)
+	return nil
+}
+
+type mockDeltaServer struct {
+	grpc.ServerStream
+	ctx       context.Context
+	sendCount int
+	allBlocks []*csi.BlockMetadata
+}
+
+func (m *mockDeltaServer) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockDeltaServer) Send(resp *csi.GetMetadataDeltaResponse) error {
+	m.sendCount++
+	m.allBlocks = append(m.allBlocks, resp.BlockMetadata...)
+	return nil
+}
+
+// cbtMockVolumeManager reports a fixed capacity for block volumes, as vcsim
+// does not fill in the backing details, and records CBT enablement.
+type cbtMockVolumeManager struct {
+	cnsvolume.Manager
+	cbtEnabledVolumes []string
+}
+
+func (m *cbtMockVolumeManager) QueryVolume(ctx context.Context,
+	queryFilter cnstypes.CnsQueryFilter) (*cnstypes.CnsQueryResult, error) {
+	res, err := m.Manager.QueryVolume(ctx, queryFilter)
+	if err == nil && res != nil {
+		for i := range res.Volumes {
+			res.Volumes[i].BackingObjectDetails = &cnstypes.CnsBlockBackingDetails{
+				CnsBackingObjectDetails: cnstypes.CnsBackingObjectDetails{CapacityInMb: 1024},
+			}
+		}
+	}
+	return res, err
+}
+
+func (m *cbtMockVolumeManager) SetVolumeControlFlags(ctx context.Context, volumeID string,
+	controlFlags []string) error {
+	m.cbtEnabledVolumes = append(m.cbtEnabledVolumes, volumeID)
+	return nil
+}
+
+// setupCBTTest enables the changed-block-tracking FSS and installs a
+// cbtMockVolumeManager for the test vCenter. The returned func restores both.
+func setupCBTTest(t *testing.T, ct *controllerTest) (*cbtMockVolumeManager, func()) {
+	fakeOrchestrator := commonco.ContainerOrchestratorUtility.(*unittestcommon.FakeK8SOrchestrator)
+	if err := fakeOrchestrator.EnableFSS(ctx, common.ChangedBlockTracking); err != nil {
+		t.Fatal(err)
+	}
+	host := ct.controller.managers.VcenterConfigs[ct.controller.managers.CnsConfig.Global.VCenterIP].Host
+	origVolumeManager := ct.controller.managers.VolumeManagers[host]
+	mockVolumeManager := &cbtMockVolumeManager{Manager: origVolumeManager}
+	ct.controller.managers.VolumeManagers[host] = mockVolumeManager
+	return mockVolumeManager, func() {
+		ct.controller.managers.VolumeManagers[host] = origVolumeManager
+		_ = fakeOrchestrator.DisableFSS(ctx, common.ChangedBlockTracking)
+	}
+}
+
+// createCBTTestVolume creates a 1 GiB block volume and returns its ID.
+func createCBTTestVolume(t *testing.T, ct *controllerTest) string {
+	reqCreate := &csi.CreateVolumeRequest{
+		Name: testVolumeName + "-" + uuid.New().String(),
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 1 * common.GbInBytes,
+		},
+		VolumeCapabilities: []*csi.VolumeCapability{
+			{
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+				},
+			},
+		},
+	}
+	respCreate, err := ct.controller.CreateVolume(ctx, reqCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volID := respCreate.Volume.VolumeId
+	t.Cleanup(func() {
+		_, _ = ct.controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volID})
+	})
+	return volID
+}
+
+// stubChangedDiskAreas returns two pages of areas: two areas in the first 8 KiB
+// and a single one starting at 8 KiB. The govmomi simulator does not serve the
+// /vslm/sdk endpoint, so connecting to VSLM is stubbed out as well.
+func stubChangedDiskAreas(t *testing.T) {
+	origConnectHook := cnsvolume.ConnectVslmHook
+	origHook := cnsvolume.QueryChangedDiskAreasHook
+	t.Cleanup(func() {
+		cnsvolume.ConnectVslmHook = origConnectHook
+		cnsvolume.QueryChangedDiskAreasHook = origHook
+	})
+	cnsvolume.ConnectVslmHook = func(ctx context.Context, vc *cnsvsphere.VirtualCenter) error { return nil }
+	volumeCapacityBytes := int64(1 * common.GbInBytes)
+	cnsvolume.QueryChangedDiskAreasHook = func(ctx context.Context, vcenter *cnsvsphere.VirtualCenter,
+		volumeID vim25types.ID, snapshotID vim25types.ID, startingOffset int64,
+		changeID string) (*vim25types.DiskChangeInfo, error) {
+		var area []vim25types.DiskChangeExtent
+		length := volumeCapacityBytes - startingOffset
+		switch startingOffset {
+		case 0:
+			area = []vim25types.DiskChangeExtent{
+				{Start: 0, Length: 4096},
+				{Start: 4096, Length: 4096},
+			}
+			length = 8192
+		case 8192:
+			area = []vim25types.DiskChangeExtent{
+				{Start: 8192, Length: 4096},
+			}
+		}
+		return &vim25types.DiskChangeInfo{
+			StartOffset: startingOffset,
+			Length:      length,
+			ChangedArea: area,
+		}, nil
+	}
+}
+
+func TestValidateGetMetadataDeltaRequest(t *testing.T) {
+	ctx := logger.NewContextWithLogger(context.Background())
+	tests := []struct {
+		name    string
+		req     *csi.GetMetadataDeltaRequest
+		wantErr bool
+	}{
+		{
+			name:    "nil request",
+			wantErr: true,
+		},
+		{
+			name: "base snapshot ID is a CSI snapshot handle",
+			req: &csi.GetMetadataDeltaRequest{
+				BaseSnapshotId:   "volume-123+snapshot-123",
+				TargetSnapshotId: "volume-123+snapshot-456",
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty target snapshot ID",
+			req: &csi.GetMetadataDeltaRequest{
+				BaseSnapshotId: testValidChangeID,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative starting_offset",
+			req: &csi.GetMetadataDeltaRequest{
+				BaseSnapshotId:   testValidChangeID,
+				TargetSnapshotId: "volume-123+snapshot-456",
+				StartingOffset:   -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid request",
+			req: &csi.GetMetadataDeltaRequest{
+				BaseSnapshotId:   testValidChangeID,
+				TargetSnapshotId: "volume-123+snapshot-456",
+				MaxResults:       100,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGetMetadataDeltaRequest(ctx, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateGetMetadataDeltaRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestGetMetadataFSSDisabled verifies both RPCs return Unimplemented when the
+// changed-block-tracking FSS is disabled.
+func TestGetMetadataFSSDisabled(t *testing.T) {
+	ct := getControllerTest(t)
+	err := ct.controller.GetMetadataAllocated(&csi.GetMetadataAllocatedRequest{
+		SnapshotId: "volume-123+snapshot-456",
+	}, &mockAllocatedServer{ctx: ctx})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("GetMetadataAllocated: expected Unimplemented, got: %v", err)
+	}
+	err = ct.controller.GetMetadataDelta(&csi.GetMetadataDeltaRequest{
+		BaseSnapshotId:   testValidChangeID,
+		TargetSnapshotId: "volume-123+snapshot-456",
+	}, &mockDeltaServer{ctx: ctx})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("GetMetadataDelta: expected Unimplemented, got: %v", err)
+	}
+}
+
+func TestGetMetadataAllocatedVolumeNotFound(t *testing.T) {
+	ct := getControllerTest(t)
+	_, restore := setupCBTTest(t, ct)
+	defer restore()
+
+	err := ct.controller.GetMetadataAllocated(&csi.GetMetadataAllocatedRequest{
+		SnapshotId: uuid.New().String() + "+snapshot-456",
+	}, &mockAllocatedServer{ctx: ctx})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got: %v", err)
+	}
+}
+
+// TestCreateVolumeEnablesCBT verifies CBT is enabled on new block volumes
+// when the changed-block-tracking FSS is enabled.
+func TestCreateVolumeEnablesCBT(t *testing.T) {
+	ct := getControllerTest(t)
+	mockVolumeManager, restore := setupCBTTest(t, ct)
+	defer restore()
+
+	volID := createCBTTestVolume(t, ct)
+	if len(mockVolumeManager.cbtEnabledVolumes) != 1 || mockVolumeManager.cbtEnabledVolumes[0] != volID {
+		t.Fatalf("expected CBT to be enabled for volume %s, got %v", volID, mockVolumeManager.cbtEnabledVolumes)
+	}
+}
+
+// TestGetMetadataAllocatedPagination verifies that all pages returned by VSLM
+// are streamed, split into batches of at most max_results entries.
+func TestGetMetadataAllocatedPagination(t *testing.T) {
+	ct := getControllerTest(t)
+	_, restore := setupCBTTest(t, ct)
+	defer restore()
+	volID := createCBTTestVolume(t, ct)
+	stubChangedDiskAreas(t)
+
+	srv := &mockAllocatedServer{ctx: ctx}
+	err := ct.controller.GetMetadataAllocated(&csi.GetMetadataAllocatedRequest{
+		SnapshotId: volID + "+snapshot-456",
+		MaxResults: 1,
+	}, srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.sendCount != 3 || len(srv.allBlocks) != 3 {
+		t.Errorf("expected 3 responses with 3 blocks, got %d responses with %d blocks",
+			srv.sendCount, len(srv.allBlocks))
+	}
+}
+
+func TestGetMetadataDeltaPagination(t *testing.T) {
+	ct := getControllerTest(t)
+	_, restore := setupCBTTest(t, ct)
+	defer restore()
+	volID := createCBTTestVolume(t, ct)
+	stubChangedDiskAreas(t)
+
+	srv := &mockDeltaServer{ctx: ctx}
+	err := ct.controller.GetMetadataDelta(&csi.GetMetadataDeltaRequest{
+		BaseSnapshotId:   testValidChangeID,
+		TargetSnapshotId: volID + "+snapshot-456",
+		MaxResults:       2,
+	}, srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.sendCount != 2 || len(srv.allBlocks) != 3 {
+		t.Errorf("expected 2 responses with 3 blocks, got %d responses with %d blocks",
+			srv.sendCount, len(srv.allBlocks))
+	}
+	if srv.allBlocks[2].ByteOffset != 8192 {
+		t.Errorf("expected last block at offset 8192, got %d", srv.allBlocks[2].ByteOffset)
+	}
+}
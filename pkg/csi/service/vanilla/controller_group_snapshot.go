@@ -0,0 +1,679 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vanilla
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	csifault "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/fault"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/utils"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsvolumeoperationrequest"
+)
+
+const (
+	// groupSnapshotInstancePrefix is the prefix of the CnsVolumeOperationRequest
+	// instance which tracks a group snapshot. The group snapshot ID is the name
+	// of the group snapshot given by the CO.
+	groupSnapshotInstancePrefix = "group-snapshot-"
+	// groupSnapshotIDSeparator separates the source volume IDs and the member
+	// snapshot IDs stored in the CnsVolumeOperationRequest of a group snapshot.
+	groupSnapshotIDSeparator = ","
+)
+
+var (
+	// groupSnapshotVolumesLock protects groupSnapshotVolumes.
+	groupSnapshotVolumesLock sync.Mutex
+	// groupSnapshotVolumes maps the ID of every volume with a group snapshot in
+	// progress to the name of the group snapshot. Operations changing the size,
+	// placement or existence of these volumes are rejected with Aborted until the
+	// group snapshot completes. This only guards the control plane operations
+	// served by this controller, which is the only one serving them as the
+	// leader of the csi-snapshotter and csi-resizer sidecars; it does not pause
+	// the I/O of the volumes.
+	groupSnapshotVolumes = make(map[string]string)
+)
+
+// claimGroupSnapshotVolumes marks the given volumes as being snapshotted by the
+// named group snapshot. Either all volumes are claimed or none is.
+func claimGroupSnapshotVolumes(ctx context.Context, name string, volumeIDs []string) error {
+	log := logger.GetLogger(ctx)
+	groupSnapshotVolumesLock.Lock()
+	defer groupSnapshotVolumesLock.Unlock()
+	for _, volumeID := range volumeIDs {
+		if group, ok := groupSnapshotVolumes[volumeID]; ok {
+			return logger.LogNewErrorCodef(log, codes.Aborted,
+				"group snapshot %q is already in progress for volume %q", group, volumeID)
+		}
+	}
+	for _, volumeID := range volumeIDs {
+		groupSnapshotVolumes[volumeID] = name
+	}
+	return nil
+}
+
+// releaseGroupSnapshotVolumes releases the volumes claimed by
+// claimGroupSnapshotVolumes.
+func releaseGroupSnapshotVolumes(volumeIDs []string) {
+	groupSnapshotVolumesLock.Lock()
+	defer groupSnapshotVolumesLock.Unlock()
+	for _, volumeID := range volumeIDs {
+		delete(groupSnapshotVolumes, volumeID)
+	}
+}
+
+// checkVolumeNotInGroupSnapshot returns an Aborted error if a group snapshot
+// of the given volume is in progress.
+func checkVolumeNotInGroupSnapshot(ctx context.Context, volumeID string) error {
+	log := logger.GetLogger(ctx)
+	groupSnapshotVolumesLock.Lock()
+	defer groupSnapshotVolumesLock.Unlock()
+	if group, ok := groupSnapshotVolumes[volumeID]; ok {
+		return logger.LogNewErrorCodef(log, codes.Aborted,
+			"group snapshot %q is in progress for volume %q", group, volumeID)
+	}
+	return nil
+}
+
+// GroupControllerGetCapabilities returns the capabilities of the
+// GroupController service.
+func (c *controller) GroupControllerGetCapabilities(ctx context.Context,
+	req *csi.GroupControllerGetCapabilitiesRequest) (*csi.GroupControllerGetCapabilitiesResponse, error) {
+	ctx = logger.NewContextWithLogger(ctx)
+	log := logger.GetLogger(ctx)
+	log.Infof("GroupControllerGetCapabilities: called with args %+v", req)
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeGroupSnapshot) {
+		return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "groupControllerGetCapabilities")
+	}
+	return &csi.GroupControllerGetCapabilitiesResponse{
+		Capabilities: []*csi.GroupControllerServiceCapability{
+			{
+				Type: &csi.GroupControllerServiceCapability_Rpc{
+					Rpc: &csi.GroupControllerServiceCapability_RPC{
+						Type: csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// CreateVolumeGroupSnapshot creates a CNS snapshot of every source volume.
+// The member snapshots are taken concurrently by separate CNS tasks, so they
+// are close in time but not taken at a single point in time. While they are
+// taken, operations changing the source volumes are rejected. If any snapshot
+// fails, the snapshots already taken are deleted in the same call, so that
+// either the whole group exists or none of it. The group is tracked in a
+// CnsVolumeOperationRequest, which makes retries with the same name return
+// the same group snapshot.
+func (c *controller) CreateVolumeGroupSnapshot(ctx context.Context, req *csi.CreateVolumeGroupSnapshotRequest) (
+	*csi.CreateVolumeGroupSnapshotResponse, error) {
+	start := time.Now()
+	ctx = logger.NewContextWithLogger(ctx)
+	log := logger.GetLogger(ctx)
+	log.Infof("CreateVolumeGroupSnapshot: called with args %+v", req)
+	if err := checkVolumeGroupSnapshotEnabled(ctx); err != nil {
+		return nil, err
+	}
+	groupSnapshot, faultType, err := c.createVolumeGroupSnapshot(ctx, req)
+	observeVolumeGroupSnapshotOp(ctx, prometheus.PrometheusCreateVolumeGroupSnapshotOpType, start, faultType, err)
+	if err != nil {
+		return nil, err
+	}
+	return &csi.CreateVolumeGroupSnapshotResponse{GroupSnapshot: groupSnapshot}, nil
+}
+
+// DeleteVolumeGroupSnapshot deletes the member snapshots of a group snapshot
+// and the CnsVolumeOperationRequest tracking it.
+func (c *controller) DeleteVolumeGroupSnapshot(ctx context.Context, req *csi.DeleteVolumeGroupSnapshotRequest) (
+	*csi.DeleteVolumeGroupSnapshotResponse, error) {
+	start := time.Now()
+	ctx = logger.NewContextWithLogger(ctx)
+	log := logger.GetLogger(ctx)
+	log.Infof("DeleteVolumeGroupSnapshot: called with args %+v", req)
+	if err := checkVolumeGroupSnapshotEnabled(ctx); err != nil {
+		return nil, err
+	}
+	faultType, err := c.deleteVolumeGroupSnapshot(ctx, req)
+	observeVolumeGroupSnapshotOp(ctx, prometheus.PrometheusDeleteVolumeGroupSnapshotOpType, start, faultType, err)
+	if err != nil {
+		return nil, err
+	}
+	return &csi.DeleteVolumeGroupSnapshotResponse{}, nil
+}
+
+// GetVolumeGroupSnapshot returns a group snapshot created by
+// CreateVolumeGroupSnapshot.
+func (c *controller) GetVolumeGroupSnapshot(ctx context.Context, req *csi.GetVolumeGroupSnapshotRequest) (
+	*csi.GetVolumeGroupSnapshotResponse, error) {
+	start := time.Now()
+	ctx = logger.NewContextWithLogger(ctx)
+	log := logger.GetLogger(ctx)
+	log.Infof("GetVolumeGroupSnapshot: called with args %+v", req)
+	if err := checkVolumeGroupSnapshotEnabled(ctx); err != nil {
+		return nil, err
+	}
+	groupSnapshot, faultType, err := c.getVolumeGroupSnapshot(ctx, req)
+	observeVolumeGroupSnapshotOp(ctx, prometheus.PrometheusGetVolumeGroupSnapshotOpType, start, faultType, err)
+	if err != nil {
+		return nil, err
+	}
+	return &csi.GetVolumeGroupSnapshotResponse{GroupSnapshot: groupSnapshot}, nil
+}
+
+// checkVolumeGroupSnapshotEnabled returns an Unimplemented error unless both
+// block volume snapshots and volume group snapshots are enabled.
+func checkVolumeGroupSnapshotEnabled(ctx context.Context) error {
+	log := logger.GetLogger(ctx)
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeGroupSnapshot) ||
+		!commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeSnapshot) {
+		return logger.LogNewErrorCode(log, codes.Unimplemented, "volume group snapshots are not enabled")
+	}
+	return nil
+}
+
+// observeVolumeGroupSnapshotOp reports the result of a group snapshot
+// operation to Prometheus.
+func observeVolumeGroupSnapshotOp(ctx context.Context, opType string, start time.Time,
+	faultType string, err error) {
+	log := logger.GetLogger(ctx)
+	volumeType := prometheus.PrometheusBlockVolumeType
+	if err != nil {
+		if csifault.IsNonStorageFault(faultType) {
+			faultType = csifault.AddCsiNonStoragePrefix(ctx, faultType)
+		}
+		log.Errorf("Operation failed, reporting failure status to Prometheus."+
+			" Operation Type: %q, Volume Type: %q, Fault Type: %q", opType, volumeType, faultType)
+		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, opType,
+			prometheus.PrometheusFailStatus, faultType).Observe(time.Since(start).Seconds())
+		return
+	}
+	prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, opType,
+		prometheus.PrometheusPassStatus, faultType).Observe(time.Since(start).Seconds())
+}
+
+func (c *controller) createVolumeGroupSnapshot(ctx context.Context, req *csi.CreateVolumeGroupSnapshotRequest) (
+	*csi.VolumeGroupSnapshot, string, error) {
+	log := logger.GetLogger(ctx)
+	name := req.GetName()
+	if name == "" {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"group snapshot name must be provided")
+	}
+	volumeIDs := req.GetSourceVolumeIds()
+	if len(volumeIDs) == 0 {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"source volume IDs must be provided")
+	}
+	seen := make(map[string]bool)
+	for _, volumeID := range volumeIDs {
+		if volumeID == "" || seen[volumeID] {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"source volume IDs %v must be unique and non-empty", volumeIDs)
+		}
+		seen[volumeID] = true
+		if strings.Contains(volumeID, ".vmdk") {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"cannot snapshot migrated vSphere volume %q", volumeID)
+		}
+		if common.GetCnsVolumeType(ctx, volumeID) == common.FileVolumeType {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"cannot snapshot file volume %q. Only block volumes are supported", volumeID)
+		}
+	}
+	volumeManager, faultType, err := c.getGroupSnapshotVolumeManager(ctx, volumeIDs)
+	if err != nil {
+		return nil, faultType, err
+	}
+	volumeDetails, faultType, err := queryGroupSnapshotVolumeDetails(ctx, volumeManager, volumeIDs)
+	if err != nil {
+		return nil, faultType, err
+	}
+	operationStore := volumeManager.GetOperationStore()
+	if operationStore == nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal,
+			"failed to get operation store for volume manager")
+	}
+
+	// Reject operations on the source volumes until the group is complete.
+	// This also serializes retries of the same group snapshot.
+	if err := claimGroupSnapshotVolumes(ctx, name, volumeIDs); err != nil {
+		return nil, csifault.CSIInternalFault, err
+	}
+	defer releaseGroupSnapshotVolumes(volumeIDs)
+
+	instanceName := groupSnapshotInstancePrefix + name
+	sourceVolumes := joinGroupSnapshotIDs(volumeIDs)
+	details, err := operationStore.GetRequestDetails(ctx, instanceName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get the operation details of group snapshot %q. Error: %v", name, err)
+	}
+	if err == nil && details != nil {
+		if details.VolumeID != sourceVolumes {
+			return nil, csifault.CSIAlreadyExistsFault, logger.LogNewErrorCodef(log, codes.AlreadyExists,
+				"group snapshot %q already exists with different source volumes %q", name, details.VolumeID)
+		}
+		if details.OperationDetails != nil &&
+			details.OperationDetails.TaskStatus == cnsvolumeoperationrequest.TaskInvocationStatusSuccess {
+			log.Infof("Group snapshot %q was already created", name)
+			return buildVolumeGroupSnapshot(name, splitGroupSnapshotIDs(details.SnapshotID), volumeDetails,
+				details.OperationDetails.TaskInvocationTimestamp.Time), "", nil
+		}
+		// A previous attempt failed, or the controller restarted while taking
+		// the group. Delete what is left of it before taking it again.
+		if details.SnapshotID != "" {
+			if faultType, err := c.rollbackVolumeGroupSnapshot(ctx, operationStore, volumeManager, name,
+				sourceVolumes, splitGroupSnapshotIDs(details.SnapshotID)); err != nil {
+				return nil, faultType, err
+			}
+		}
+	}
+	if err := operationStore.StoreRequestDetails(ctx, cnsvolumeoperationrequest.CreateVolumeOperationRequestDetails(
+		instanceName, sourceVolumes, "", 0, nil, metav1.Now(), "", "", "",
+		cnsvolumeoperationrequest.TaskInvocationStatusInProgress, "", "")); err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to store the operation details of group snapshot %q. Error: %v", name, err)
+	}
+
+	// Take the member snapshots concurrently, so that they are as close in time
+	// as possible. Each member snapshot has its own name within the group.
+	type memberResult struct {
+		snapshotID string
+		info       *cnsvolume.CnsSnapshotInfo
+		err        error
+	}
+	results := make([]memberResult, len(volumeIDs))
+	memberNames := groupSnapshotMemberNames(name, sourceVolumes)
+	var wg sync.WaitGroup
+	for i, volumeID := range volumeIDs {
+		wg.Add(1)
+		go func(i int, volumeID string) {
+			defer wg.Done()
+			snapshotID, info, err := common.CreateSnapshotUtil(ctx, volumeManager, volumeID, memberNames[volumeID],
+				&cnsvolume.CreateSnapshotExtraParams{
+					IsCSITransactionSupportEnabled: isCSITransactionSupportEnabled,
+				})
+			results[i] = memberResult{snapshotID: snapshotID, info: info, err: err}
+		}(i, volumeID)
+	}
+	wg.Wait()
+
+	var (
+		snapshotIDs  []string
+		creationTime time.Time
+		createErrs   []string
+	)
+	for i, result := range results {
+		if result.err != nil {
+			createErrs = append(createErrs, volumeIDs[i]+": "+result.err.Error())
+			continue
+		}
+		snapshotIDs = append(snapshotIDs, result.snapshotID)
+		if result.info.SnapshotLatestOperationCompleteTime.After(creationTime) {
+			creationTime = result.info.SnapshotLatestOperationCompleteTime
+		}
+	}
+	if len(createErrs) > 0 {
+		errStr := strings.Join(createErrs, "; ")
+		log.Errorf("failed to create group snapshot %q. Rolling back snapshots %v. Errors: %s",
+			name, snapshotIDs, errStr)
+		if faultType, err := c.rollbackVolumeGroupSnapshot(ctx, operationStore, volumeManager, name,
+			sourceVolumes, snapshotIDs); err != nil {
+			return nil, faultType, err
+		}
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to create group snapshot %q. Error: %s", name, errStr)
+	}
+
+	if err := operationStore.StoreRequestDetails(ctx, cnsvolumeoperationrequest.CreateVolumeOperationRequestDetails(
+		instanceName, sourceVolumes, joinGroupSnapshotIDs(snapshotIDs), 0, nil, metav1.NewTime(creationTime),
+		"", "", "", cnsvolumeoperationrequest.TaskInvocationStatusSuccess, "", "")); err != nil {
+		// The group can not be returned without its record, so do not leave
+		// its snapshots behind for the retry.
+		log.Errorf("failed to store the operation details of group snapshot %q. Rolling back snapshots %v. "+
+			"Error: %v", name, snapshotIDs, err)
+		if faultType, rollbackErr := c.rollbackVolumeGroupSnapshot(ctx, operationStore, volumeManager, name,
+			sourceVolumes, snapshotIDs); rollbackErr != nil {
+			return nil, faultType, rollbackErr
+		}
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to store the operation details of group snapshot %q. Error: %v", name, err)
+	}
+	log.Infof("CreateVolumeGroupSnapshot: created group snapshot %q with snapshots %v", name, snapshotIDs)
+	return buildVolumeGroupSnapshot(name, snapshotIDs, volumeDetails, creationTime), "", nil
+}
+
+// rollbackVolumeGroupSnapshot deletes the given member snapshots of a group
+// snapshot which could not be completed. The snapshots which could not be
+// deleted are recorded in the CnsVolumeOperationRequest of the group, so that
+// they are deleted by the next attempt.
+func (c *controller) rollbackVolumeGroupSnapshot(ctx context.Context,
+	operationStore cnsvolumeoperationrequest.VolumeOperationRequest, volumeManager cnsvolume.Manager,
+	name, sourceVolumes string, snapshotIDs []string) (string, error) {
+	log := logger.GetLogger(ctx)
+	instanceName := groupSnapshotInstancePrefix + name
+	// Record the snapshots before deleting them, so that a restart of the
+	// controller does not leak them.
+	if err := operationStore.StoreRequestDetails(ctx, cnsvolumeoperationrequest.CreateVolumeOperationRequestDetails(
+		instanceName, sourceVolumes, joinGroupSnapshotIDs(snapshotIDs), 0, nil, metav1.Now(), "", "", "",
+		cnsvolumeoperationrequest.TaskInvocationStatusInProgress, "", "")); err != nil {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to store the operation details of group snapshot %q. Error: %v", name, err)
+	}
+	leftover, deleteErrs := deleteGroupSnapshotMembers(ctx, operationStore, volumeManager, name, sourceVolumes,
+		snapshotIDs)
+	if err := operationStore.StoreRequestDetails(ctx, cnsvolumeoperationrequest.CreateVolumeOperationRequestDetails(
+		instanceName, sourceVolumes, joinGroupSnapshotIDs(leftover), 0, nil, metav1.Now(), "", "", "",
+		cnsvolumeoperationrequest.TaskInvocationStatusError, strings.Join(deleteErrs, "; "), "")); err != nil {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to store the operation details of group snapshot %q. Error: %v", name, err)
+	}
+	if len(leftover) > 0 {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to roll back snapshots %v of group snapshot %q. Errors: %s",
+			leftover, name, strings.Join(deleteErrs, "; "))
+	}
+	return "", nil
+}
+
+// deleteGroupSnapshotMembers deletes the given member snapshots of a group
+// snapshot of the given source volumes and the CnsVolumeOperationRequests
+// created for them by CreateSnapshot. It returns the snapshots which could
+// not be deleted.
+func deleteGroupSnapshotMembers(ctx context.Context, operationStore cnsvolumeoperationrequest.VolumeOperationRequest,
+	volumeManager cnsvolume.Manager, name, sourceVolumes string, snapshotIDs []string) ([]string, []string) {
+	log := logger.GetLogger(ctx)
+	var (
+		leftover   []string
+		deleteErrs []string
+	)
+	memberNames := groupSnapshotMemberNames(name, sourceVolumes)
+	for _, snapshotID := range snapshotIDs {
+		if _, err := common.DeleteSnapshotUtil(ctx, volumeManager, snapshotID, nil); err != nil {
+			leftover = append(leftover, snapshotID)
+			deleteErrs = append(deleteErrs, err.Error())
+			continue
+		}
+		// CreateSnapshot returns the recorded snapshot of a volume for the same
+		// name, so the record has to go along with the snapshot.
+		volumeID, _, err := common.ParseCSISnapshotID(snapshotID)
+		if err != nil || memberNames[volumeID] == "" {
+			continue
+		}
+		if err := operationStore.DeleteRequestDetails(ctx, memberNames[volumeID]+"-"+volumeID); err != nil {
+			log.Warnf("failed to delete the operation details of snapshot %q. Error: %v", snapshotID, err)
+		}
+	}
+	return leftover, deleteErrs
+}
+
+func (c *controller) deleteVolumeGroupSnapshot(ctx context.Context,
+	req *csi.DeleteVolumeGroupSnapshotRequest) (string, error) {
+	log := logger.GetLogger(ctx)
+	name := req.GetGroupSnapshotId()
+	if name == "" {
+		return csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"group snapshot ID must be provided")
+	}
+	snapshotIDs := req.GetSnapshotIds()
+	volumeIDs := make([]string, 0, len(snapshotIDs))
+	for _, snapshotID := range snapshotIDs {
+		volumeID, _, err := common.ParseCSISnapshotID(snapshotID)
+		if err != nil {
+			return csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument, err.Error())
+		}
+		volumeIDs = append(volumeIDs, volumeID)
+	}
+	if len(volumeIDs) == 0 {
+		return csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"snapshot IDs must be provided")
+	}
+	volumeManager, faultType, err := c.getGroupSnapshotVolumeManager(ctx, volumeIDs)
+	if err != nil {
+		return faultType, err
+	}
+	operationStore := volumeManager.GetOperationStore()
+	if operationStore == nil {
+		return csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal,
+			"failed to get operation store for volume manager")
+	}
+	instanceName := groupSnapshotInstancePrefix + name
+	details, err := operationStore.GetRequestDetails(ctx, instanceName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get the operation details of group snapshot %q. Error: %v", name, err)
+	}
+	if err == nil && details != nil && details.OperationDetails != nil &&
+		details.OperationDetails.TaskStatus == cnsvolumeoperationrequest.TaskInvocationStatusSuccess &&
+		details.SnapshotID != joinGroupSnapshotIDs(snapshotIDs) {
+		return csifault.CSIFailedPreconditionFault, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+			"snapshot IDs %v do not match the snapshots %q of group snapshot %q", snapshotIDs,
+			details.SnapshotID, name)
+	}
+	// The member names derive from all the source volumes of the group, which
+	// are the volumes of the snapshots unless the group is recorded.
+	sourceVolumes := joinGroupSnapshotIDs(volumeIDs)
+	if err == nil && details != nil && details.VolumeID != "" {
+		sourceVolumes = details.VolumeID
+	}
+	leftover, deleteErrs := deleteGroupSnapshotMembers(ctx, operationStore, volumeManager, name, sourceVolumes,
+		snapshotIDs)
+	if len(leftover) > 0 {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to delete snapshots %v of group snapshot %q. Errors: %s",
+			leftover, name, strings.Join(deleteErrs, "; "))
+	}
+	if err := operationStore.DeleteRequestDetails(ctx, instanceName); err != nil {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to delete the operation details of group snapshot %q. Error: %v", name, err)
+	}
+	log.Infof("DeleteVolumeGroupSnapshot: deleted group snapshot %q", name)
+	return "", nil
+}
+
+func (c *controller) getVolumeGroupSnapshot(ctx context.Context, req *csi.GetVolumeGroupSnapshotRequest) (
+	*csi.VolumeGroupSnapshot, string, error) {
+	log := logger.GetLogger(ctx)
+	name := req.GetGroupSnapshotId()
+	if name == "" {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"group snapshot ID must be provided")
+	}
+	snapshotIDs := req.GetSnapshotIds()
+	volumeIDs := make([]string, 0, len(snapshotIDs))
+	for _, snapshotID := range snapshotIDs {
+		volumeID, _, err := common.ParseCSISnapshotID(snapshotID)
+		if err != nil {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+				err.Error())
+		}
+		volumeIDs = append(volumeIDs, volumeID)
+	}
+	if len(volumeIDs) == 0 {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"snapshot IDs must be provided")
+	}
+	volumeManager, faultType, err := c.getGroupSnapshotVolumeManager(ctx, volumeIDs)
+	if err != nil {
+		return nil, faultType, err
+	}
+	operationStore := volumeManager.GetOperationStore()
+	if operationStore == nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal,
+			"failed to get operation store for volume manager")
+	}
+	details, err := operationStore.GetRequestDetails(ctx, groupSnapshotInstancePrefix+name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, csifault.CSINotFoundFault, logger.LogNewErrorCodef(log, codes.NotFound,
+				"group snapshot %q not found", name)
+		}
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get the operation details of group snapshot %q. Error: %v", name, err)
+	}
+	if details.OperationDetails == nil ||
+		details.OperationDetails.TaskStatus != cnsvolumeoperationrequest.TaskInvocationStatusSuccess {
+		return nil, csifault.CSINotFoundFault, logger.LogNewErrorCodef(log, codes.NotFound,
+			"group snapshot %q was not created", name)
+	}
+	if details.SnapshotID != joinGroupSnapshotIDs(snapshotIDs) {
+		return nil, csifault.CSIFailedPreconditionFault, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+			"snapshot IDs %v do not match the snapshots %q of group snapshot %q", snapshotIDs,
+			details.SnapshotID, name)
+	}
+	volumeDetails, faultType, err := queryGroupSnapshotVolumeDetails(ctx, volumeManager, volumeIDs)
+	if err != nil {
+		return nil, faultType, err
+	}
+	return buildVolumeGroupSnapshot(name, snapshotIDs, volumeDetails,
+		details.OperationDetails.TaskInvocationTimestamp.Time), "", nil
+}
+
+// getGroupSnapshotVolumeManager returns the volume manager of the vCenter
+// hosting all the given volumes. A group snapshot can not span vCenters.
+func (c *controller) getGroupSnapshotVolumeManager(ctx context.Context, volumeIDs []string) (
+	cnsvolume.Manager, string, error) {
+	log := logger.GetLogger(ctx)
+	var (
+		groupVCenterHost   string
+		groupVolumeManager cnsvolume.Manager
+	)
+	for _, volumeID := range volumeIDs {
+		vCenterHost, volumeManager, err := getVCenterAndVolumeManagerForVolumeID(ctx, c, volumeID, volumeInfoService)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get vCenter/volume manager for volume Id: %q. Error: %v", volumeID, err)
+		}
+		if groupVolumeManager != nil && vCenterHost != groupVCenterHost {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"volumes of a group snapshot must be on the same vCenter. Volume %q is on %q, not on %q",
+				volumeID, vCenterHost, groupVCenterHost)
+		}
+		groupVCenterHost, groupVolumeManager = vCenterHost, volumeManager
+	}
+	isCnsSnapshotSupported, err := getVCenterManagerForVCenter(ctx, c).IsCnsSnapshotSupported(ctx, groupVCenterHost)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to check if cns snapshot is supported on VC due to error: %v", err)
+	}
+	if !isCnsSnapshotSupported {
+		return nil, csifault.CSIUnimplementedFault, logger.LogNewErrorCode(log, codes.Unimplemented,
+			"VC version does not support snapshot operations")
+	}
+	return groupVolumeManager, "", nil
+}
+
+// queryGroupSnapshotVolumeDetails queries the given block volumes, which must
+// all exist.
+func queryGroupSnapshotVolumeDetails(ctx context.Context, volumeManager cnsvolume.Manager,
+	volumeIDs []string) (map[string]*utils.CnsVolumeDetails, string, error) {
+	log := logger.GetLogger(ctx)
+	cnsVolumeIDs := make([]cnstypes.CnsVolumeId, 0, len(volumeIDs))
+	for _, volumeID := range volumeIDs {
+		cnsVolumeIDs = append(cnsVolumeIDs, cnstypes.CnsVolumeId{Id: volumeID})
+	}
+	volumeDetails, err := utils.QueryVolumeDetailsUtil(ctx, volumeManager, cnsVolumeIDs)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to query volumes %v. Error: %v", volumeIDs, err)
+	}
+	for _, volumeID := range volumeIDs {
+		details, ok := volumeDetails[volumeID]
+		if !ok {
+			return nil, csifault.CSINotFoundFault, logger.LogNewErrorCodef(log, codes.NotFound,
+				"volume %q not found", volumeID)
+		}
+		if details.VolumeType != common.BlockVolumeType {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"volume %q is a %s volume. Only block volumes are supported", volumeID, details.VolumeType)
+		}
+	}
+	return volumeDetails, "", nil
+}
+
+// buildVolumeGroupSnapshot returns the CSI group snapshot with the given
+// member snapshots, all of which share the creation time of the group.
+func buildVolumeGroupSnapshot(name string, snapshotIDs []string, volumeDetails map[string]*utils.CnsVolumeDetails,
+	creationTime time.Time) *csi.VolumeGroupSnapshot {
+	creationTimeInProto := timestamppb.New(creationTime)
+	snapshots := make([]*csi.Snapshot, 0, len(snapshotIDs))
+	for _, snapshotID := range snapshotIDs {
+		volumeID, _, _ := common.ParseCSISnapshotID(snapshotID)
+		snapshot := &csi.Snapshot{
+			SnapshotId:      snapshotID,
+			SourceVolumeId:  volumeID,
+			CreationTime:    creationTimeInProto,
+			ReadyToUse:      true,
+			GroupSnapshotId: name,
+		}
+		if details, ok := volumeDetails[volumeID]; ok {
+			snapshot.SizeBytes = details.SizeInMB * common.MbInBytes
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return &csi.VolumeGroupSnapshot{
+		GroupSnapshotId: name,
+		Snapshots:       snapshots,
+		CreationTime:    creationTimeInProto,
+		ReadyToUse:      true,
+	}
+}
+
+// groupSnapshotMemberNames returns the name of the member snapshot of every
+// source volume of the given group snapshot. The members are numbered in the
+// order of their sorted source volume IDs, so that every attempt to take the
+// group names them alike.
+func groupSnapshotMemberNames(name, sourceVolumes string) map[string]string {
+	memberNames := make(map[string]string)
+	for i, volumeID := range splitGroupSnapshotIDs(sourceVolumes) {
+		memberNames[volumeID] = name + "-" + strconv.Itoa(i)
+	}
+	return memberNames
+}
+
+// joinGroupSnapshotIDs returns the sorted IDs joined by groupSnapshotIDSeparator,
+// so that the same set of IDs is always stored as the same string.
+func joinGroupSnapshotIDs(ids []string) string {
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, groupSnapshotIDSeparator)
+}
+
+// splitGroupSnapshotIDs reverses joinGroupSnapshotIDs.
+func splitGroupSnapshotIDs(ids string) []string {
+	if ids == "" {
+		return nil
+	}
+	return strings.Split(ids, groupSnapshotIDSeparator)
+}
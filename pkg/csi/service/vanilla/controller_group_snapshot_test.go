@@ -0,0 +1,378 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vanilla
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/google/uuid"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	csifault "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/fault"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/unittestcommon"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsvolumeoperationrequest"
+)
+
+// groupSnapshotMockVolumeManager fails the snapshots of the given volume and
+// serves the given operation store, if any.
+type groupSnapshotMockVolumeManager struct {
+	cnsvolume.Manager
+	failVolumeID   string
+	operationStore cnsvolumeoperationrequest.VolumeOperationRequest
+}
+
+func (m *groupSnapshotMockVolumeManager) GetOperationStore() cnsvolumeoperationrequest.VolumeOperationRequest {
+	if m.operationStore != nil {
+		return m.operationStore
+	}
+	return m.Manager.GetOperationStore()
+}
+
+// failSuccessOperationStore fails to store the successful completion of an
+// operation.
+type failSuccessOperationStore struct {
+	cnsvolumeoperationrequest.VolumeOperationRequest
+}
+
+func (s *failSuccessOperationStore) StoreRequestDetails(ctx context.Context,
+	instance *cnsvolumeoperationrequest.VolumeOperationRequestDetails) error {
+	if instance.OperationDetails != nil &&
+		instance.OperationDetails.TaskStatus == cnsvolumeoperationrequest.TaskInvocationStatusSuccess {
+		return errors.New("injected store failure")
+	}
+	return s.VolumeOperationRequest.StoreRequestDetails(ctx, instance)
+}
+
+func (m *groupSnapshotMockVolumeManager) CreateSnapshot(ctx context.Context, volumeID string, desc string,
+	extraParams interface{}) (*cnsvolume.CnsSnapshotInfo, error) {
+	if volumeID == m.failVolumeID {
+		return nil, errors.New("injected snapshot failure")
+	}
+	return m.Manager.CreateSnapshot(ctx, volumeID, desc, extraParams)
+}
+
+// enableVolumeGroupSnapshot enables the volume-group-snapshot FSS for the test.
+func enableVolumeGroupSnapshot(t *testing.T) {
+	fakeOrchestrator := commonco.ContainerOrchestratorUtility.(*unittestcommon.FakeK8SOrchestrator)
+	if err := fakeOrchestrator.EnableFSS(ctx, common.VolumeGroupSnapshot); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = fakeOrchestrator.DisableFSS(ctx, common.VolumeGroupSnapshot)
+	})
+}
+
+func TestVolumeGroupSnapshotFSSDisabled(t *testing.T) {
+	ct := getControllerTest(t)
+	_, err := ct.controller.GroupControllerGetCapabilities(ctx, &csi.GroupControllerGetCapabilitiesRequest{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("expected Unimplemented, got %v", err)
+	}
+	_, err = ct.controller.CreateVolumeGroupSnapshot(ctx, &csi.CreateVolumeGroupSnapshotRequest{
+		Name:            "group-1",
+		SourceVolumeIds: []string{"volume-1"},
+	})
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("expected Unimplemented, got %v", err)
+	}
+}
+
+func TestCreateVolumeGroupSnapshotInvalidArgument(t *testing.T) {
+	ct := getControllerTest(t)
+	enableVolumeGroupSnapshot(t)
+	tests := []struct {
+		name      string
+		groupName string
+		volumeIDs []string
+	}{
+		{
+			name:      "no name",
+			volumeIDs: []string{"volume-1"},
+		},
+		{
+			name:      "no source volumes",
+			groupName: "group-1",
+		},
+		{
+			name:      "duplicate source volumes",
+			groupName: "group-1",
+			volumeIDs: []string{"volume-1", "volume-1"},
+		},
+		{
+			name:      "file volume",
+			groupName: "group-1",
+			volumeIDs: []string{"file:volume-1"},
+		},
+		{
+			name:      "migrated volume",
+			groupName: "group-1",
+			volumeIDs: []string{"[vsanDatastore] kubevols/volume-1.vmdk"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ct.controller.CreateVolumeGroupSnapshot(ctx, &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            tt.groupName,
+				SourceVolumeIds: tt.volumeIDs,
+			})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVolumeGroupSnapshot(t *testing.T) {
+	ct := getControllerTest(t)
+	enableVolumeGroupSnapshot(t)
+	resp, err := ct.controller.GroupControllerGetCapabilities(ctx, &csi.GroupControllerGetCapabilitiesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Capabilities) != 1 || resp.Capabilities[0].GetRpc().GetType() !=
+		csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT {
+		t.Fatalf("unexpected capabilities %+v", resp.Capabilities)
+	}
+
+	volumeIDs := []string{createCBTTestVolume(t, ct), createCBTTestVolume(t, ct)}
+	name := "group-" + uuid.New().String()
+	createReq := &csi.CreateVolumeGroupSnapshotRequest{Name: name, SourceVolumeIds: volumeIDs}
+	createResp, err := ct.controller.CreateVolumeGroupSnapshot(ctx, createReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group := createResp.GroupSnapshot
+	if group.GroupSnapshotId != name || !group.ReadyToUse || len(group.Snapshots) != len(volumeIDs) {
+		t.Fatalf("unexpected group snapshot %+v", group)
+	}
+	var snapshotIDs []string
+	for _, snapshot := range group.Snapshots {
+		if snapshot.GroupSnapshotId != name || snapshot.SizeBytes != common.GbInBytes {
+			t.Fatalf("unexpected snapshot %+v", snapshot)
+		}
+		snapshotIDs = append(snapshotIDs, snapshot.SnapshotId)
+	}
+	// Every member snapshot has its own name.
+	memberNames := groupSnapshotMemberNames(name, joinGroupSnapshotIDs(volumeIDs))
+	for _, volumeID := range volumeIDs {
+		result, err := ct.controller.manager.VolumeManager.QuerySnapshots(ctx, cnstypes.CnsSnapshotQueryFilter{
+			SnapshotQuerySpecs: []cnstypes.CnsSnapshotQuerySpec{{VolumeId: cnstypes.CnsVolumeId{Id: volumeID}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Entries) != 1 ||
+			!strings.HasPrefix(result.Entries[0].Snapshot.Description, memberNames[volumeID]) {
+			t.Fatalf("expected snapshot %q on volume %q, got %+v", memberNames[volumeID], volumeID,
+				result.Entries)
+		}
+	}
+
+	// A retry returns the same group snapshot.
+	retryResp, err := ct.controller.CreateVolumeGroupSnapshot(ctx, createReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joinGroupSnapshotIDs(snapshotIDsOf(retryResp.GroupSnapshot)) != joinGroupSnapshotIDs(snapshotIDs) {
+		t.Fatalf("expected the retry to return snapshots %v, got %+v", snapshotIDs, retryResp.GroupSnapshot)
+	}
+	// The same name with other source volumes is rejected.
+	_, faultType, err := ct.controller.createVolumeGroupSnapshot(ctx, &csi.CreateVolumeGroupSnapshotRequest{
+		Name:            name,
+		SourceVolumeIds: volumeIDs[:1],
+	})
+	if status.Code(err) != codes.AlreadyExists || faultType != csifault.CSIAlreadyExistsFault {
+		t.Fatalf("expected AlreadyExists, got %v with fault %q", err, faultType)
+	}
+
+	getResp, err := ct.controller.GetVolumeGroupSnapshot(ctx, &csi.GetVolumeGroupSnapshotRequest{
+		GroupSnapshotId: name,
+		SnapshotIds:     snapshotIDs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !getResp.GroupSnapshot.CreationTime.AsTime().Equal(group.CreationTime.AsTime()) {
+		t.Fatalf("expected creation time %v, got %v", group.CreationTime, getResp.GroupSnapshot.CreationTime)
+	}
+	_, faultType, err = ct.controller.getVolumeGroupSnapshot(ctx, &csi.GetVolumeGroupSnapshotRequest{
+		GroupSnapshotId: name,
+		SnapshotIds:     snapshotIDs[:1],
+	})
+	if status.Code(err) != codes.FailedPrecondition || faultType != csifault.CSIFailedPreconditionFault {
+		t.Fatalf("expected FailedPrecondition, got %v with fault %q", err, faultType)
+	}
+
+	deleteReq := &csi.DeleteVolumeGroupSnapshotRequest{GroupSnapshotId: name, SnapshotIds: snapshotIDs}
+	if _, err := ct.controller.DeleteVolumeGroupSnapshot(ctx, deleteReq); err != nil {
+		t.Fatal(err)
+	}
+	for _, volumeID := range volumeIDs {
+		snapshots, _, err := common.QueryVolumeSnapshotsByVolumeID(ctx, ct.controller.manager.VolumeManager,
+			volumeID, common.QuerySnapshotLimit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshots) != 0 {
+			t.Fatalf("expected no snapshots on volume %q, got %+v", volumeID, snapshots)
+		}
+	}
+	_, err = ct.controller.GetVolumeGroupSnapshot(ctx, &csi.GetVolumeGroupSnapshotRequest{
+		GroupSnapshotId: name,
+		SnapshotIds:     snapshotIDs,
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+	// Deleting the group snapshot again succeeds.
+	if _, err := ct.controller.DeleteVolumeGroupSnapshot(ctx, deleteReq); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateVolumeGroupSnapshotRollback(t *testing.T) {
+	ct := getControllerTest(t)
+	enableVolumeGroupSnapshot(t)
+	volumeIDs := []string{createCBTTestVolume(t, ct), createCBTTestVolume(t, ct)}
+	host := ct.controller.managers.VcenterConfigs[ct.controller.managers.CnsConfig.Global.VCenterIP].Host
+	origVolumeManager := ct.controller.managers.VolumeManagers[host]
+	mockVolumeManager := &groupSnapshotMockVolumeManager{Manager: origVolumeManager, failVolumeID: volumeIDs[1]}
+	ct.controller.managers.VolumeManagers[host] = mockVolumeManager
+	t.Cleanup(func() {
+		ct.controller.managers.VolumeManagers[host] = origVolumeManager
+	})
+
+	name := "group-" + uuid.New().String()
+	req := &csi.CreateVolumeGroupSnapshotRequest{Name: name, SourceVolumeIds: volumeIDs}
+	_, err := ct.controller.CreateVolumeGroupSnapshot(ctx, req)
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal, got %v", err)
+	}
+	// The snapshot of the first volume is rolled back.
+	snapshots, _, err := common.QueryVolumeSnapshotsByVolumeID(ctx, origVolumeManager, volumeIDs[0],
+		common.QuerySnapshotLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 0 {
+		t.Fatalf("expected the snapshot of volume %q to be rolled back, got %+v", volumeIDs[0], snapshots)
+	}
+	details, err := ct.operationStore.GetRequestDetails(ctx, groupSnapshotInstancePrefix+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.OperationDetails.TaskStatus != cnsvolumeoperationrequest.TaskInvocationStatusError ||
+		details.SnapshotID != "" {
+		t.Fatalf("expected a failed group snapshot without snapshots, got %+v", details)
+	}
+
+	// Once the failure is gone, the retry takes the whole group.
+	mockVolumeManager.failVolumeID = ""
+	resp, err := ct.controller.CreateVolumeGroupSnapshot(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GroupSnapshot.Snapshots) != len(volumeIDs) {
+		t.Fatalf("unexpected group snapshot %+v", resp.GroupSnapshot)
+	}
+	if _, err := ct.controller.DeleteVolumeGroupSnapshot(ctx, &csi.DeleteVolumeGroupSnapshotRequest{
+		GroupSnapshotId: name,
+		SnapshotIds:     snapshotIDsOf(resp.GroupSnapshot),
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateVolumeGroupSnapshotRollbackOnStoreFailure(t *testing.T) {
+	ct := getControllerTest(t)
+	enableVolumeGroupSnapshot(t)
+	volumeIDs := []string{createCBTTestVolume(t, ct), createCBTTestVolume(t, ct)}
+	host := ct.controller.managers.VcenterConfigs[ct.controller.managers.CnsConfig.Global.VCenterIP].Host
+	origVolumeManager := ct.controller.managers.VolumeManagers[host]
+	ct.controller.managers.VolumeManagers[host] = &groupSnapshotMockVolumeManager{
+		Manager:        origVolumeManager,
+		operationStore: &failSuccessOperationStore{VolumeOperationRequest: ct.operationStore},
+	}
+	t.Cleanup(func() {
+		ct.controller.managers.VolumeManagers[host] = origVolumeManager
+	})
+
+	_, err := ct.controller.CreateVolumeGroupSnapshot(ctx, &csi.CreateVolumeGroupSnapshotRequest{
+		Name:            "group-" + uuid.New().String(),
+		SourceVolumeIds: volumeIDs,
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal, got %v", err)
+	}
+	// The group could not be recorded, so none of its snapshots is left.
+	for _, volumeID := range volumeIDs {
+		snapshots, _, err := common.QueryVolumeSnapshotsByVolumeID(ctx, origVolumeManager, volumeID,
+			common.QuerySnapshotLimit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshots) != 0 {
+			t.Fatalf("expected the snapshot of volume %q to be rolled back, got %+v", volumeID, snapshots)
+		}
+	}
+}
+
+func TestVolumeGroupSnapshotRejectsVolumeOperations(t *testing.T) {
+	ct := getControllerTest(t)
+	enableVolumeGroupSnapshot(t)
+	volumeID := createCBTTestVolume(t, ct)
+	if err := claimGroupSnapshotVolumes(ctx, "group-in-progress", []string{volumeID}); err != nil {
+		t.Fatal(err)
+	}
+	defer releaseGroupSnapshotVolumes([]string{volumeID})
+
+	_, err := ct.controller.ControllerExpandVolume(ctx, &csi.ControllerExpandVolumeRequest{
+		VolumeId:      volumeID,
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 2 * common.GbInBytes},
+	})
+	if status.Code(err) != codes.Aborted {
+		t.Fatalf("expected Aborted for expansion, got %v", err)
+	}
+	_, err = ct.controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeID})
+	if status.Code(err) != codes.Aborted {
+		t.Fatalf("expected Aborted for deletion, got %v", err)
+	}
+	_, err = ct.controller.CreateVolumeGroupSnapshot(ctx, &csi.CreateVolumeGroupSnapshotRequest{
+		Name:            "group-" + uuid.New().String(),
+		SourceVolumeIds: []string{volumeID},
+	})
+	if status.Code(err) != codes.Aborted {
+		t.Fatalf("expected Aborted for an overlapping group snapshot, got %v", err)
+	}
+}
+
+func snapshotIDsOf(group *csi.VolumeGroupSnapshot) []string {
+	var snapshotIDs []string
+	for _, snapshot := range group.Snapshots {
+		snapshotIDs = append(snapshotIDs, snapshot.SnapshotId)
+	}
+	return snapshotIDs
+}
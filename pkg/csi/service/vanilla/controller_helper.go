@@ -18,25 +18,45 @@ package vanilla
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	cnstypes "github.com/vmware/govmomi/cns/types"
 	"github.com/vmware/govmomi/object"
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
 	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/node"
 	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	csifault "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/fault"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/utils"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsvolumeinfo"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v3/pkg/kubernetes"
+)
+
+const (
+	// csiParameterPrefix is the prefix of the StorageClass parameters reserved
+	// for the CSI sidecars.
+	csiParameterPrefix = "csi.storage.k8s.io/"
+	// fileVolumePrefix is the prefix of the IDs of file volumes.
+	fileVolumePrefix = "file:"
 )
 
 // validateVanillaDeleteVolumeRequest is the helper function to validate
@@ -52,7 +72,55 @@ func validateVanillaDeleteVolumeRequest(ctx context.Context, req *csi.DeleteVolu
 // otherwise returns nil.
 func validateVanillaControllerPublishVolumeRequest(ctx context.Context,
 	req *csi.ControllerPublishVolumeRequest) error {
-	return common.ValidateControllerPublishVolumeRequest(ctx, req)
+	log := logger.GetLogger(ctx)
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.MultiWriterBlockVolume) {
+		return common.ValidateControllerPublishVolumeRequest(ctx, req)
+	}
+	// Check for required parameters.
+	if len(req.VolumeId) == 0 {
+		return logger.LogNewErrorCode(log, codes.InvalidArgument, "volume ID is a required parameter")
+	} else if len(req.NodeId) == 0 {
+		return logger.LogNewErrorCode(log, codes.InvalidArgument, "node ID is a required parameter")
+	}
+	volCap := req.GetVolumeCapability()
+	if volCap == nil {
+		return logger.LogNewErrorCode(log, codes.InvalidArgument, "volume capability not provided")
+	}
+	if err := validateVolumeCapabilitiesForVolume(ctx, req.VolumeId, []*csi.VolumeCapability{volCap}); err != nil {
+		return logger.LogNewErrorCodef(log, codes.InvalidArgument, "volume capability not supported. Err: %+v", err)
+	}
+	return nil
+}
+
+// validateVolumeCapabilitiesForVolume validates the given capabilities of the
+// volume with the given ID. Multi-node access modes are only supported with a
+// file system for file volumes, and as raw block for block volumes.
+func validateVolumeCapabilitiesForVolume(ctx context.Context, volumeID string,
+	caps []*csi.VolumeCapability) error {
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.MultiWriterBlockVolume) {
+		return common.IsValidVolumeCapabilities(ctx, caps)
+	}
+	isFileVolume := strings.HasPrefix(volumeID, fileVolumePrefix)
+	if common.IsMultiWriterBlockVolumeRequest(ctx, caps) {
+		if err := common.IsValidMultiWriterBlockVolumeCapabilities(ctx, caps); err != nil {
+			return err
+		}
+		if isFileVolume {
+			return fmt.Errorf("raw block volume mode is not supported for %q volumes", common.FileVolumeType)
+		}
+		if strings.Contains(volumeID, ".vmdk") {
+			return errors.New("multi-writer access is not supported for migrated in-tree vSphere volumes")
+		}
+		return nil
+	}
+	if err := common.IsValidVolumeCapabilities(ctx, caps); err != nil {
+		return err
+	}
+	if common.IsFileVolumeRequest(ctx, caps) && !isFileVolume {
+		return fmt.Errorf("filesystem volume mode is not supported for multi-writer %q volumes",
+			common.BlockVolumeType)
+	}
+	return nil
 }
 
 // validateControllerUnpublishVolumeRequest is the helper function to validate
@@ -145,14 +213,66 @@ func convertCnsVolumeType(ctx context.Context, cnsVolumeType string) string {
 	return volumeType
 }
 
+// volumeIDToNodeUUIDMapTTL is how long ControllerGetVolume reuses the volume
+// ID to node UUIDs map built by ListVolumes or by a previous call, instead of
+// retrieving the devices of every node VM on each call.
+const volumeIDToNodeUUIDMapTTL = time.Minute
+
+var (
+	// volumeIDToNodeUUIDMapLock guards volumeIDToNodeUUIDMap and its refresh
+	// time. The map is replaced on refresh and never modified in place.
+	volumeIDToNodeUUIDMapLock        sync.Mutex
+	volumeIDToNodeUUIDMapRefreshTime time.Time
+)
+
+// setVolumeIDToNodeUUIDMap replaces the cached volume ID to node UUIDs map.
+func setVolumeIDToNodeUUIDMap(volumeIDNodeUUIDMap map[string][]string) {
+	volumeIDToNodeUUIDMapLock.Lock()
+	defer volumeIDToNodeUUIDMapLock.Unlock()
+	volumeIDToNodeUUIDMap = volumeIDNodeUUIDMap
+	volumeIDToNodeUUIDMapRefreshTime = time.Now()
+}
+
+// getVolumeIDToNodeUUIDMap returns the cached volume ID to node UUIDs map.
+func getVolumeIDToNodeUUIDMap() map[string][]string {
+	volumeIDToNodeUUIDMapLock.Lock()
+	defer volumeIDToNodeUUIDMapLock.Unlock()
+	return volumeIDToNodeUUIDMap
+}
+
+// getCachedBlockVolumeIDToNodeUUIDMap returns the cached volume ID to node
+// UUIDs map, rebuilding it when it is older than volumeIDToNodeUUIDMapTTL.
+// Concurrent callers wait for a single rebuild.
+func getCachedBlockVolumeIDToNodeUUIDMap(ctx context.Context, c *controller) (map[string][]string, error) {
+	volumeIDToNodeUUIDMapLock.Lock()
+	defer volumeIDToNodeUUIDMapLock.Unlock()
+	if time.Since(volumeIDToNodeUUIDMapRefreshTime) < volumeIDToNodeUUIDMapTTL {
+		return volumeIDToNodeUUIDMap, nil
+	}
+	allNodeVMs, err := c.nodeMgr.GetAllNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes(node vms) in the vanilla cluster. Error: %v", err)
+	}
+	volumeIDNodeUUIDMap, err := getBlockVolumeIDToNodeUUIDMap(ctx, c, allNodeVMs)
+	if err != nil {
+		return nil, err
+	}
+	volumeIDToNodeUUIDMap = volumeIDNodeUUIDMap
+	volumeIDToNodeUUIDMapRefreshTime = time.Now()
+	return volumeIDNodeUUIDMap, nil
+}
+
+// getBlockVolumeIDToNodeUUIDMap returns the UUIDs of the node VMs each block
+// volume is attached to. A multi-writer raw block volume is attached to
+// several node VMs at once, so every one of them is reported.
 func getBlockVolumeIDToNodeUUIDMap(ctx context.Context, c *controller,
-	allnodeVMs []*vsphere.VirtualMachine) (map[string]string, error) {
+	allnodeVMs []*vsphere.VirtualMachine) (map[string][]string, error) {
 	var vCenters []*vsphere.VirtualCenter
 	var err error
 
 	log := logger.GetLogger(ctx)
 	log.Debugf("getBlockVolumeIDToNodeUUIDMap called for Node VMs: %+v", allnodeVMs)
-	volumeIDNodeUUIDMap := make(map[string]string)
+	volumeIDNodeUUIDMap := make(map[string][]string)
 	// Get VirtualCenter object(s)
 	// For multi-VC configuration, create map for volumes in all vCenters
 	vCenters, err = common.GetVCenters(ctx, c.managers)
@@ -197,7 +317,8 @@ func getBlockVolumeIDToNodeUUIDMap(ctx context.Context, c *controller,
 			for _, device := range vmDevices {
 				if virtualDisk, ok := device.(*types.VirtualDisk); ok {
 					if virtualDisk.VDiskId != nil {
-						volumeIDNodeUUIDMap[virtualDisk.VDiskId.Id] = info.Config.Uuid
+						volumeIDNodeUUIDMap[virtualDisk.VDiskId.Id] = append(
+							volumeIDNodeUUIDMap[virtualDisk.VDiskId.Id], info.Config.Uuid)
 					}
 				}
 
@@ -271,3 +392,627 @@ func GetVolumeManagerFromVCHost(ctx context.Context, managers *common.Managers,
 	}
 	return volumeMgr, nil
 }
+
+// getStorageClassParamsForCapacity drops the "csi.storage.k8s.io/" prefixed
+// keys from the StorageClass parameters. external-provisioner strips these
+// before CreateVolume but passes them through unchanged to GetCapacity.
+func getStorageClassParamsForCapacity(params map[string]string) map[string]string {
+	scParams := make(map[string]string)
+	for key, value := range params {
+		if strings.HasPrefix(strings.ToLower(key), csiParameterPrefix) {
+			continue
+		}
+		scParams[key] = value
+	}
+	return scParams
+}
+
+// getCapacityFromDatastores returns the free space summed across the given
+// datastores and the free space on the largest of them. When datastoreURL is
+// set, only the datastore with that URL is considered.
+func getCapacityFromDatastores(ctx context.Context, datastores []*vsphere.DatastoreInfo,
+	datastoreURL string) (int64, int64) {
+	log := logger.GetLogger(ctx)
+	var availableCapacity, maximumVolumeSize int64
+	for _, ds := range datastores {
+		if ds == nil || ds.Info == nil {
+			continue
+		}
+		if datastoreURL != "" && strings.TrimSpace(ds.Info.Url) != strings.TrimSpace(datastoreURL) {
+			continue
+		}
+		freeSpace := ds.Info.FreeSpace
+		if freeSpace < 0 {
+			freeSpace = 0
+		}
+		log.Debugf("Datastore %q has %d bytes of free space", ds.Info.Url, freeSpace)
+		availableCapacity += freeSpace
+		if freeSpace > maximumVolumeSize {
+			maximumVolumeSize = freeSpace
+		}
+	}
+	return availableCapacity, maximumVolumeSize
+}
+
+// filterDatastoresByStoragePolicy returns the datastores compatible with the
+// given storage policy ID.
+func filterDatastoresByStoragePolicy(ctx context.Context, vcenter *vsphere.VirtualCenter,
+	datastores []*vsphere.DatastoreInfo, storagePolicyID string) ([]*vsphere.DatastoreInfo, error) {
+	log := logger.GetLogger(ctx)
+	if len(datastores) == 0 {
+		return nil, nil
+	}
+	var dsMoRefs []types.ManagedObjectReference
+	for _, ds := range datastores {
+		dsMoRefs = append(dsMoRefs, ds.Reference())
+	}
+	compat, err := vcenter.PbmCheckCompatibility(ctx, dsMoRefs, storagePolicyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find datastore compatibility with storage policy ID %q. Error: %+v",
+			storagePolicyID, err)
+	}
+	compatibleDsMoids := make(map[string]struct{})
+	for _, ds := range compat.CompatibleDatastores() {
+		compatibleDsMoids[ds.HubId] = struct{}{}
+	}
+	var compatibleDatastores []*vsphere.DatastoreInfo
+	for _, ds := range datastores {
+		if _, exists := compatibleDsMoids[ds.Reference().Value]; exists {
+			compatibleDatastores = append(compatibleDatastores, ds)
+		}
+	}
+	log.Debugf("Datastores compatible with storage policy %q are %+v", storagePolicyID, compatibleDatastores)
+	return compatibleDatastores, nil
+}
+
+// refreshDatastoreFreeSpace returns copies of the given datastores with the
+// free space retrieved from the vCenter. The given datastores are not modified
+// as they may be shared with a cache.
+func refreshDatastoreFreeSpace(ctx context.Context, vcenter *vsphere.VirtualCenter,
+	datastores []*vsphere.DatastoreInfo) ([]*vsphere.DatastoreInfo, error) {
+	if len(datastores) == 0 {
+		return nil, nil
+	}
+	var dsMoRefs []types.ManagedObjectReference
+	for _, ds := range datastores {
+		dsMoRefs = append(dsMoRefs, ds.Reference())
+	}
+	var dsMoList []mo.Datastore
+	pc := property.DefaultCollector(vcenter.Client.Client)
+	err := pc.Retrieve(ctx, dsMoRefs, []string{"summary"}, &dsMoList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve summary of datastores %+v. Error: %+v", dsMoRefs, err)
+	}
+	freeSpaceByMoid := make(map[string]int64)
+	for _, dsMo := range dsMoList {
+		freeSpaceByMoid[dsMo.Reference().Value] = dsMo.Summary.FreeSpace
+	}
+	refreshedDatastores := make([]*vsphere.DatastoreInfo, 0, len(datastores))
+	for _, ds := range datastores {
+		freeSpace, exists := freeSpaceByMoid[ds.Reference().Value]
+		if !exists || ds.Info == nil {
+			// Skip datastores the vCenter did not return a summary for.
+			continue
+		}
+		info := *ds.Info
+		info.FreeSpace = freeSpace
+		refreshedDatastores = append(refreshedDatastores, &vsphere.DatastoreInfo{
+			Datastore:    ds.Datastore,
+			Info:         &info,
+			CustomValues: ds.CustomValues,
+		})
+	}
+	return refreshedDatastores, nil
+}
+
+// isDatastoreURLInList returns true if a datastore with the given URL is
+// present in the given list of datastores.
+func isDatastoreURLInList(datastores []*vsphere.DatastoreInfo, datastoreURL string) bool {
+	for _, ds := range datastores {
+		if ds != nil && ds.Info != nil && strings.TrimSpace(ds.Info.Url) == strings.TrimSpace(datastoreURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// getVolumeCondition returns the condition of the given CNS volume. The volume
+// is abnormal when CNS reports it as inaccessible, or when its datastore is
+// missing or not accessible.
+func (c *controller) getVolumeCondition(ctx context.Context, vCenterHost string,
+	volume *cnstypes.CnsVolume) (*csi.VolumeCondition, error) {
+	log := logger.GetLogger(ctx)
+	if volume.HealthStatus != "" && volume.HealthStatus != string(pbmtypes.PbmHealthStatusForEntityUnknown) {
+		volHealthStatus, err := common.ConvertVolumeHealthStatus(ctx, volume.VolumeId.Id, volume.HealthStatus)
+		if err != nil {
+			return nil, err
+		}
+		if volHealthStatus == common.VolHealthStatusInaccessible {
+			return &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  fmt.Sprintf("volume is inaccessible. CNS health status is %q", volume.HealthStatus),
+			}, nil
+		}
+	}
+	vCenter, err := common.GetVCenterFromVCHost(ctx, c.managers.VcenterManager, vCenterHost)
+	if err != nil {
+		return nil, err
+	}
+	datastore, err := getDatastoreInfoByURL(ctx, vCenter, volume.DatastoreUrl)
+	if err != nil {
+		if errors.Is(err, errDatastoreNotFound) {
+			return &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  fmt.Sprintf("datastore %q of the volume is not found", volume.DatastoreUrl),
+			}, nil
+		}
+		return nil, err
+	}
+	var datastoreMo mo.Datastore
+	pc := property.DefaultCollector(vCenter.Client.Client)
+	err = pc.RetrieveOne(ctx, datastore.Reference(), []string{"summary.accessible"}, &datastoreMo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accessibility of datastore %q. Error: %+v", volume.DatastoreUrl, err)
+	}
+	if !datastoreMo.Summary.Accessible {
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  fmt.Sprintf("datastore %q of the volume is not accessible", volume.DatastoreUrl),
+		}, nil
+	}
+	log.Debugf("Volume %q is healthy. CNS health status is %q", volume.VolumeId.Id, volume.HealthStatus)
+	return &csi.VolumeCondition{
+		Abnormal: false,
+		Message:  "volume is accessible",
+	}, nil
+}
+
+// cloneSourceVolume holds the details of the source volume of a clone request.
+type cloneSourceVolume struct {
+	VolumeID      string
+	VCenterHost   string
+	VolumeManager cnsvolume.Manager
+	DatastoreURL  string
+	SizeInMB      int64
+}
+
+// getCloneSnapshotName returns the name of the snapshot implicitly taken on
+// the source volume to create the clone with the given name.
+func getCloneSnapshotName(cloneName string) string {
+	return "clone-" + cloneName
+}
+
+// getCloneSourceVolume validates the source volume of a clone request and
+// returns its details. The clone is created from an implicit snapshot of the
+// source volume, so the source must be a CNS block volume on a vCenter which
+// supports snapshots, and the clone can not be smaller than the source. The
+// clone lands on the datastore of the source volume, which must be compatible
+// with the storage policy of the clone.
+func (c *controller) getCloneSourceVolume(ctx context.Context, sourceVolumeID string, volSizeMB int64,
+	scParams *common.StorageClassParams) (*cloneSourceVolume, string, error) {
+	log := logger.GetLogger(ctx)
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeClone) {
+		return nil, csifault.CSIUnimplementedFault, logger.LogNewErrorCode(log, codes.Unimplemented,
+			"cloning of block volumes is not enabled")
+	}
+	if sourceVolumeID == "" {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"source volume ID is required to clone a volume")
+	}
+	if strings.Contains(sourceVolumeID, ".vmdk") {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"cannot clone migrated vSphere volume %q", sourceVolumeID)
+	}
+	vCenterHost, volumeManager, err := getVCenterAndVolumeManagerForVolumeID(ctx, c, sourceVolumeID,
+		volumeInfoService)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get vCenter/volume manager for volumeID: %q. Error: %+v", sourceVolumeID, err)
+	}
+	isCnsSnapshotSupported, err := c.managers.VcenterManager.IsCnsSnapshotSupported(ctx, vCenterHost)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to check if cns snapshot operations are supported on VC %q due to error: %v",
+			vCenterHost, err)
+	}
+	if !isCnsSnapshotSupported {
+		return nil, csifault.CSIUnimplementedFault, logger.LogNewErrorCodef(log, codes.Unimplemented,
+			"VC %q does not support snapshot operations required to clone a volume", vCenterHost)
+	}
+	volumeIds := []cnstypes.CnsVolumeId{{Id: sourceVolumeID}}
+	cnsVolumeDetailsMap, err := utils.QueryVolumeDetailsUtil(ctx, volumeManager, volumeIds)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to retrieve volume details for ID %q. Error: %+v", sourceVolumeID, err)
+	}
+	sourceVolumeDetails, ok := cnsVolumeDetailsMap[sourceVolumeID]
+	if !ok {
+		return nil, csifault.CSINotFoundFault, logger.LogNewErrorCodef(log, codes.NotFound,
+			"source volume %q not found", sourceVolumeID)
+	}
+	if sourceVolumeDetails.VolumeType != common.BlockVolumeType {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"cannot clone volume %q of type %q. Only block volumes can be cloned",
+			sourceVolumeID, sourceVolumeDetails.VolumeType)
+	}
+	if volSizeMB < sourceVolumeDetails.SizeInMB {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"requested volume size %d MB is smaller than source volume %q size %d MB",
+			volSizeMB, sourceVolumeID, sourceVolumeDetails.SizeInMB)
+	}
+	// The clone is always placed on the datastore of the source volume.
+	if scParams.DatastoreURL != "" &&
+		strings.TrimSpace(sourceVolumeDetails.DatastoreUrl) != strings.TrimSpace(scParams.DatastoreURL) {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"datastore URL %q given in storage class does not match the source volume datastore URL %q",
+			scParams.DatastoreURL, sourceVolumeDetails.DatastoreUrl)
+	}
+	if scParams.StoragePolicyName != "" {
+		faultType, err := c.checkCloneStoragePolicy(ctx, vCenterHost, sourceVolumeID,
+			sourceVolumeDetails.DatastoreUrl, scParams.StoragePolicyName)
+		if err != nil {
+			return nil, faultType, err
+		}
+	}
+	return &cloneSourceVolume{
+		VolumeID:      sourceVolumeID,
+		VCenterHost:   vCenterHost,
+		VolumeManager: volumeManager,
+		DatastoreURL:  sourceVolumeDetails.DatastoreUrl,
+		SizeInMB:      sourceVolumeDetails.SizeInMB,
+	}, "", nil
+}
+
+// checkCloneStoragePolicy checks that the datastore of the source volume of a
+// clone is compatible with the storage policy of the clone, before the
+// snapshot of the source volume is taken.
+func (c *controller) checkCloneStoragePolicy(ctx context.Context, vCenterHost string, sourceVolumeID string,
+	datastoreURL string, storagePolicyName string) (string, error) {
+	log := logger.GetLogger(ctx)
+	vCenter, err := common.GetVCenterFromVCHost(ctx, c.managers.VcenterManager, vCenterHost)
+	if err != nil {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get vCenter instance for host %q. Error: %+v", vCenterHost, err)
+	}
+	storagePolicyID, err := vCenter.GetStoragePolicyIDByName(ctx, storagePolicyName)
+	if err != nil {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get policy ID for storage policy name %q. Error: %+v", storagePolicyName, err)
+	}
+	datastore, err := getDatastoreInfoByURL(ctx, vCenter, datastoreURL)
+	if err != nil {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to find datastore %q of source volume %q. Error: %+v", datastoreURL, sourceVolumeID, err)
+	}
+	compatibleDatastores, err := filterDatastoresByStoragePolicy(ctx, vCenter,
+		[]*vsphere.DatastoreInfo{datastore}, storagePolicyID)
+	if err != nil {
+		return csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+	}
+	if len(compatibleDatastores) == 0 {
+		return csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"storage policy %q is not compatible with datastore %q of source volume %q",
+			storagePolicyName, datastoreURL, sourceVolumeID)
+	}
+	return "", nil
+}
+
+// completeVolumeClone expands the newly created clone to the requested size
+// and deletes the snapshot implicitly taken on the source volume. It is safe
+// to call again when a previous CreateVolume call created the clone but
+// failed in here.
+func (c *controller) completeVolumeClone(ctx context.Context, source *cloneSourceVolume, cloneName string,
+	cloneSnapshotID string, vcHost string, volumeMgr cnsvolume.Manager, volumeID string, volSizeMB int64) (
+	string, error) {
+	log := logger.GetLogger(ctx)
+	if volSizeMB > source.SizeInMB {
+		volumeIds := []cnstypes.CnsVolumeId{{Id: volumeID}}
+		cnsVolumeDetailsMap, err := utils.QueryVolumeDetailsUtil(ctx, volumeMgr, volumeIds)
+		if err != nil {
+			return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to retrieve volume details for ID %q. Error: %+v", volumeID, err)
+		}
+		if volumeDetails, ok := cnsVolumeDetailsMap[volumeID]; !ok || volumeDetails.SizeInMB < volSizeMB {
+			log.Infof("Expanding clone %q of volume %q from %d MB to %d MB", volumeID, source.VolumeID,
+				source.SizeInMB, volSizeMB)
+			faultType, err := common.ExpandVolumeUtil(ctx, c.managers.VcenterManager, vcHost, volumeMgr,
+				volumeID, volSizeMB, nil)
+			if err != nil {
+				return faultType, logger.LogNewErrorCodef(log, codes.Internal,
+					"failed to expand clone %q of volume %q to %d MB. Error: %+v",
+					volumeID, source.VolumeID, volSizeMB, err)
+			}
+		}
+	}
+
+	if cloneSnapshotID == "" {
+		// The snapshot was taken by a previous CreateVolume call. Look it up
+		// in the operation store to clean it up.
+		operationStore := source.VolumeManager.GetOperationStore()
+		if operationStore == nil {
+			return csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal,
+				"failed to get operation store for volume manager")
+		}
+		instanceName := getCloneSnapshotName(cloneName) + "-" + source.VolumeID
+		snapshotDetails, err := operationStore.GetRequestDetails(ctx, instanceName)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				log.Debugf("No snapshot found on volume %q for clone %q", source.VolumeID, cloneName)
+				return "", nil
+			}
+			return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get details of snapshot %q. Error: %+v", instanceName, err)
+		}
+		if snapshotDetails.SnapshotID == "" {
+			return "", nil
+		}
+		cloneSnapshotID = source.VolumeID + common.VSphereCSISnapshotIdDelimiter + snapshotDetails.SnapshotID
+	}
+	// A snapshot left behind on the source volume blocks its deletion, so
+	// fail the request and let it be retried rather than ignoring the error.
+	_, err := common.DeleteSnapshotUtil(ctx, source.VolumeManager, cloneSnapshotID, nil)
+	if err != nil {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to delete snapshot %q taken to clone volume %q. Error: %+v",
+			cloneSnapshotID, source.VolumeID, err)
+	}
+	log.Infof("Deleted snapshot %q taken to clone volume %q", cloneSnapshotID, source.VolumeID)
+	return "", nil
+}
+
+// isMultiWriterBlockVolumeRequest checks whether the given capabilities
+// request a raw block volume written by multiple nodes, and such volumes are
+// enabled.
+func isMultiWriterBlockVolumeRequest(ctx context.Context, capabilities []*csi.VolumeCapability) bool {
+	return commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.MultiWriterBlockVolume) &&
+		common.IsMultiWriterBlockVolumeRequest(ctx, capabilities)
+}
+
+// attachMultiWriterVolume attaches the volume to the node VM with the
+// multi-writer sharing mode, so that it can be attached to other node VMs at
+// the same time. It returns the UUID of the disk on the node VM.
+func attachMultiWriterVolume(ctx context.Context, volumeManager cnsvolume.Manager,
+	vm *vsphere.VirtualMachine, volumeID string) (string, string, error) {
+	log := logger.GetLogger(ctx)
+	diskUUID, err := cnsvolume.IsDiskAttached(ctx, vm, volumeID, false)
+	if err != nil {
+		return "", csifault.CSIInternalFault, err
+	}
+	if diskUUID != "" {
+		log.Infof("Volume %q is already attached to node VM %q", volumeID, vm.String())
+		return diskUUID, "", nil
+	}
+	results, faultType, err := volumeManager.BatchAttachVolumes(ctx, vm, []cnsvolume.BatchAttachRequest{
+		{
+			VolumeID:    volumeID,
+			SharingMode: string(types.VirtualDiskSharingSharingMultiWriter),
+		},
+	})
+	for _, result := range results {
+		if result.Error != nil {
+			return "", result.FaultType, result.Error
+		}
+	}
+	if err != nil {
+		return "", faultType, err
+	}
+	if len(results) != 1 || results[0].DiskUUID == "" {
+		return "", csifault.CSIInternalFault, logger.LogNewErrorf(log,
+			"unexpected results %+v for the attach of volume %q to node VM %q", results, volumeID, vm.String())
+	}
+	return results[0].DiskUUID, "", nil
+}
+
+// getNodeEncryptionAttributes returns the volume context attributes making the
+// node plugin encrypt the block volume requested by req, from the nodeencryption
+// parameters of its StorageClass. It returns nil when the volume is not
+// encrypted.
+func getNodeEncryptionAttributes(ctx context.Context, req *csi.CreateVolumeRequest,
+	scParams *common.StorageClassParams) (map[string]string, string, error) {
+	log := logger.GetLogger(ctx)
+	if scParams.NodeEncryption == "" {
+		if scParams.NodeEncryptionKeySource != "" {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"parameter %q requires parameter %q", common.AttributeNodeEncryptionKeySource,
+				common.AttributeNodeEncryption)
+		}
+		return nil, "", nil
+	}
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.NodeLUKSEncryption) {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"parameter %q is not supported", common.AttributeNodeEncryption)
+	}
+	if scParams.NodeEncryption != common.NodeEncryptionLUKS {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"invalid value %q of parameter %q, only %q is supported", scParams.NodeEncryption,
+			common.AttributeNodeEncryption, common.NodeEncryptionLUKS)
+	}
+	keySource := scParams.NodeEncryptionKeySource
+	if keySource == "" {
+		keySource = common.NodeEncryptionKeySourceSecret
+	}
+	if keySource != common.NodeEncryptionKeySourceSecret && keySource != common.NodeEncryptionKeySourceKMS {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"invalid value %q of parameter %q, expected %q or %q", keySource,
+			common.AttributeNodeEncryptionKeySource, common.NodeEncryptionKeySourceSecret,
+			common.NodeEncryptionKeySourceKMS)
+	}
+	for _, volCap := range req.GetVolumeCapabilities() {
+		if volCap.GetBlock() != nil {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+				"node encryption is not supported for raw block volumes")
+		}
+	}
+	return map[string]string{
+		common.AttributeNodeEncryption:          common.NodeEncryptionLUKS,
+		common.AttributeNodeEncryptionKeySource: keySource,
+	}, "", nil
+}
+
+// getMkfsOptionsAttributes returns the volume context attributes making the
+// node plugin format the block volume requested by req with the mkfsoptions
+// parameter of its StorageClass. It returns nil when the parameter is not set.
+func getMkfsOptionsAttributes(ctx context.Context, req *csi.CreateVolumeRequest,
+	scParams *common.StorageClassParams) (map[string]string, string, error) {
+	log := logger.GetLogger(ctx)
+	if strings.TrimSpace(scParams.MkfsOptions) == "" {
+		return nil, "", nil
+	}
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeMkfsOptions) {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"parameter %q is not supported", common.AttributeMkfsOptions)
+	}
+	for _, volCap := range req.GetVolumeCapabilities() {
+		if volCap.GetBlock() != nil {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+				"mkfs options are not supported for raw block volumes")
+		}
+		// The node plugin formats the volumes without fstype with ext4.
+		fsType := strings.ToLower(volCap.GetMount().GetFsType())
+		if fsType == "" {
+			fsType = common.Ext4FsType
+		}
+		if _, err := common.ParseMkfsOptions(fsType, scParams.MkfsOptions); err != nil {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"invalid parameter %q: %v", common.AttributeMkfsOptions, err)
+		}
+	}
+	return map[string]string{
+		common.AttributeMkfsOptions: scParams.MkfsOptions,
+	}, "", nil
+}
+
+// validateBlockVolumeFsType refuses the btrfs file system for the volume
+// requested by req when the BtrfsBlockVolume FSS is disabled.
+func validateBlockVolumeFsType(ctx context.Context, req *csi.CreateVolumeRequest) (string, error) {
+	log := logger.GetLogger(ctx)
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.BtrfsBlockVolume) {
+		return "", nil
+	}
+	for _, volCap := range req.GetVolumeCapabilities() {
+		if strings.ToLower(volCap.GetMount().GetFsType()) == common.BtrfsFsType {
+			return csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"fstype %s not supported for volume creation", common.BtrfsFsType)
+		}
+	}
+	return "", nil
+}
+
+// getProjectQuotaAttributes returns the volume attributes of the project
+// quota directories of the block volume requested by req, from the project
+// quota directories annotation of its PVC, or else from the
+// projectquotadirectories parameter of its StorageClass. The node plugin
+// creates the directories when staging the volume. The PVC is only known when
+// external-provisioner runs with the --extra-create-metadata flag.
+func getProjectQuotaAttributes(ctx context.Context, req *csi.CreateVolumeRequest,
+	scParams *common.StorageClassParams) (map[string]string, string, error) {
+	log := logger.GetLogger(ctx)
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeProjectQuota) {
+		if scParams.ProjectQuotaDirectories != "" {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"parameter %q is not supported", common.AttributeProjectQuotaDirectories)
+		}
+		return nil, "", nil
+	}
+	value := scParams.ProjectQuotaDirectories
+	pvcName := req.Parameters[common.AttributePvcName]
+	pvcNamespace := req.Parameters[common.AttributePvcNamespace]
+	if pvcName != "" && pvcNamespace != "" {
+		k8sClient, err := newK8sClient(ctx)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to create Kubernetes client. Error: %v", err)
+		}
+		pvc, err := k8sClient.CoreV1().PersistentVolumeClaims(pvcNamespace).Get(ctx, pvcName, metav1.GetOptions{})
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get PVC %s/%s. Error: %v", pvcNamespace, pvcName, err)
+		}
+		if ann := pvc.Annotations[common.AnnProjectQuotaDirectories]; ann != "" {
+			log.Infof("Using the project quota directories %q of PVC %s/%s", ann, pvcNamespace, pvcName)
+			value = ann
+		}
+	}
+	if strings.TrimSpace(value) == "" {
+		return nil, "", nil
+	}
+	dirs, err := common.ParseProjectQuotaDirectories(value)
+	if err != nil {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"invalid project quota directories: %v", err)
+	}
+	for _, volCap := range req.GetVolumeCapabilities() {
+		if volCap.GetBlock() != nil {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+				"project quota directories are not supported for raw block volumes")
+		}
+		// The node plugin formats the volumes without fstype with ext4.
+		fsType := strings.ToLower(volCap.GetMount().GetFsType())
+		if fsType != "" && fsType != common.Ext4FsType && fsType != common.XFSType {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"project quota directories are not supported for fstype %s", fsType)
+		}
+	}
+	if volSizeBytes := req.GetCapacityRange().GetRequiredBytes(); volSizeBytes != 0 {
+		for _, dir := range dirs {
+			if dir.LimitBytes > volSizeBytes {
+				return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+					"limit %d of project quota directory %q exceeds the volume size %d",
+					dir.LimitBytes, dir.Name, volSizeBytes)
+			}
+		}
+	}
+	return map[string]string{
+		common.AttributeProjectQuotaDirectories: common.FormatProjectQuotaDirectories(dirs),
+	}, "", nil
+}
+
+// newK8sClient creates the Kubernetes client used to read the PVC of a file
+// volume. Tests replace it with a fake client.
+var newK8sClient = k8s.NewClient
+
+// getFileVolumeNetPermissions returns the net permissions of the file volume
+// requested by req, from the net permissions annotation of its PVC, or else
+// from the netpermissions parameter of its StorageClass. It returns nil when
+// neither is set, for the NetPermissions of the vSphere config to be used.
+// The PVC is only known when external-provisioner runs with the
+// --extra-create-metadata flag.
+func (c *controller) getFileVolumeNetPermissions(ctx context.Context, req *csi.CreateVolumeRequest,
+	scParams *common.StorageClassParams) ([]*cnsconfig.NetPermissionConfig, string, error) {
+	log := logger.GetLogger(ctx)
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.FileVolumeNetPermissions) {
+		if scParams.NetPermissions != "" {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"parameter %q is not supported", common.AttributeNetPermissions)
+		}
+		return nil, "", nil
+	}
+	value := scParams.NetPermissions
+	pvcName := req.Parameters[common.AttributePvcName]
+	pvcNamespace := req.Parameters[common.AttributePvcNamespace]
+	if pvcName != "" && pvcNamespace != "" {
+		k8sClient, err := newK8sClient(ctx)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to create Kubernetes client. Error: %v", err)
+		}
+		pvc, err := k8sClient.CoreV1().PersistentVolumeClaims(pvcNamespace).Get(ctx, pvcName, metav1.GetOptions{})
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get PVC %s/%s. Error: %v", pvcNamespace, pvcName, err)
+		}
+		if ann := pvc.Annotations[common.AnnNetPermissions]; ann != "" {
+			log.Infof("Using the net permissions %q of PVC %s/%s", ann, pvcNamespace, pvcName)
+			value = ann
+		}
+	}
+	if value == "" {
+		return nil, "", nil
+	}
+	netPermissions, err := cnsconfig.ParseNetPermissions(value, c.managers.CnsConfig.NetPermissions)
+	if err != nil {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"invalid net permissions. Error: %v", err)
+	}
+	return netPermissions, "", nil
+}
@@ -0,0 +1,569 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vanilla
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	"google.golang.org/grpc/codes"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
+	csifault "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/fault"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsvolumeoperationrequest"
+)
+
+const (
+	// vsanIopsLimitNs and vsanIopsLimitPropID identify the vSAN IOPS limit
+	// rule of a storage policy.
+	vsanIopsLimitNs     = "VSAN"
+	vsanIopsLimitPropID = "iopsLimit"
+
+	// modifyPolicyInstancePrefix and modifyRelocateInstancePrefix prefix the
+	// CnsVolumeOperationRequest instances which track an in place storage
+	// policy change and a relocation of the volume respectively.
+	modifyPolicyInstancePrefix   = "modify-"
+	modifyRelocateInstancePrefix = "modify-relocate-"
+)
+
+// modifyVolumeParams holds the mutable parameters of a ControllerModifyVolume
+// request.
+type modifyVolumeParams struct {
+	StoragePolicyName string
+	DatastoreURL      string
+	// RequiredIopsLimit is validated against the vSAN IOPS limit rule of the
+	// target storage policy. It does not change the limit.
+	RequiredIopsLimit *int64
+}
+
+// key returns a short stable digest of the parameters. It is part of the
+// CnsVolumeOperationRequest instance names so that a retry of the same
+// request rejoins the in-flight task, while a request for different
+// parameters does not.
+func (p *modifyVolumeParams) key() string {
+	var fields []string
+	if p.StoragePolicyName != "" {
+		fields = append(fields, common.AttributeStoragePolicyName+"="+p.StoragePolicyName)
+	}
+	if p.DatastoreURL != "" {
+		fields = append(fields, common.AttributeDatastoreURL+"="+p.DatastoreURL)
+	}
+	if p.RequiredIopsLimit != nil {
+		fields = append(fields, common.AttributeRequiredIopsLimit+"="+strconv.FormatInt(*p.RequiredIopsLimit, 10))
+	}
+	sort.Strings(fields)
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strings.Join(fields, ";")))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// parseModifyVolumeParams parses the mutable parameters of a
+// ControllerModifyVolume request. Parameter names are case insensitive, as
+// in the StorageClass.
+func parseModifyVolumeParams(params map[string]string) (*modifyVolumeParams, error) {
+	if len(params) == 0 {
+		return nil, errors.New("mutable parameters must be provided")
+	}
+	modifyParams := &modifyVolumeParams{}
+	for param, value := range params {
+		switch strings.ToLower(param) {
+		case common.AttributeStoragePolicyName:
+			modifyParams.StoragePolicyName = value
+		case common.AttributeDatastoreURL:
+			modifyParams.DatastoreURL = strings.TrimSpace(value)
+		case common.AttributeRequiredIopsLimit:
+			iopsLimit, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || iopsLimit <= 0 {
+				return nil, fmt.Errorf("invalid value %q for parameter %q. Must be a positive integer",
+					value, param)
+			}
+			modifyParams.RequiredIopsLimit = &iopsLimit
+		default:
+			return nil, fmt.Errorf("parameter %q is not supported", param)
+		}
+	}
+	if modifyParams.StoragePolicyName == "" && modifyParams.DatastoreURL == "" && modifyParams.RequiredIopsLimit == nil {
+		return nil, errors.New("at least one of storagepolicyname, datastoreurl or requirediopslimit must be set")
+	}
+	return modifyParams, nil
+}
+
+// getIopsLimitFromPolicyContent returns the vSAN IOPS limit rule of the given
+// storage policy content, or nil if the policy does not limit IOPS.
+func getIopsLimitFromPolicyContent(policyContent []cnsvsphere.SpbmPolicyContent) (*int64, error) {
+	for _, policy := range policyContent {
+		for _, subProfile := range policy.Profiles {
+			for _, rule := range subProfile.Rules {
+				if rule.Ns == vsanIopsLimitNs && rule.PropID == vsanIopsLimitPropID {
+					iopsLimit, err := strconv.ParseInt(rule.Value, 10, 64)
+					if err != nil {
+						return nil, fmt.Errorf("failed to parse IOPS limit %q: %v", rule.Value, err)
+					}
+					return &iopsLimit, nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// updateVolumePolicyHook starts a vCenter task which changes the storage
+// policy of the volume on the given datastore without moving it. Unit tests
+// may replace it, as vcsim does not implement UpdateVStorageObjectPolicy.
+var updateVolumePolicyHook = func(ctx context.Context, vc *cnsvsphere.VirtualCenter, volumeID string,
+	datastore vim25types.ManagedObjectReference, storagePolicyID string) (*object.Task, error) {
+	req := vim25types.UpdateVStorageObjectPolicy_Task{
+		This:      *vc.Client.ServiceContent.VStorageObjectManager,
+		Id:        vim25types.ID{Id: volumeID},
+		Datastore: datastore,
+		Profile: []vim25types.BaseVirtualMachineProfileSpec{
+			&vim25types.VirtualMachineDefinedProfileSpec{ProfileId: storagePolicyID},
+		},
+	}
+	res, err := methods.UpdateVStorageObjectPolicy_Task(ctx, vc.Client.Client, &req)
+	if err != nil {
+		return nil, err
+	}
+	return object.NewTask(vc.Client.Client, res.Returnval), nil
+}
+
+// waitForModifyTaskHook waits for a task started by ControllerModifyVolume to
+// complete. Unit tests may replace it.
+var waitForModifyTaskHook = func(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+	taskRef vim25types.ManagedObjectReference) error {
+	taskInfo, err := object.NewTask(vc.Client.Client, taskRef).WaitForResultEx(ctx)
+	if err != nil {
+		return err
+	}
+	// CNS RelocateVolume reports per volume faults in the task result.
+	if results, ok := taskInfo.Result.(cnstypes.CnsVolumeOperationBatchResult); ok {
+		for _, result := range results.VolumeResults {
+			if fault := result.GetCnsVolumeOperationResult().Fault; fault != nil {
+				return fmt.Errorf("fault: %+v", fault.LocalizedMessage)
+			}
+		}
+	}
+	return nil
+}
+
+// ControllerModifyVolume applies the mutable parameters of a
+// VolumeAttributesClass to a block volume. The storage policy of the volume is
+// changed in place when its datastore is compatible with the new policy, and
+// the volume is relocated along with the policy change otherwise. The tasks
+// are tracked in CnsVolumeOperationRequest instances, so a retry from
+// csi-resizer rejoins an in-flight task instead of starting a new one.
+//
+// gRPC error codes returned:
+//   - codes.Unimplemented    - the volume-attributes-class FSS is disabled.
+//   - codes.InvalidArgument  - the request is invalid or can not be satisfied,
+//     e.g. no compatible datastore is available, or the vCenter task
+//     failed. csi-resizer marks the PVC Infeasible so the user can roll back
+//     the VolumeAttributesClass.
+//   - codes.DeadlineExceeded - the gRPC deadline expired while the task is
+//     still running. csi-resizer retries and the retry rejoins the task.
+//   - codes.Internal         - unexpected vCenter or Kubernetes API failure.
+func (c *controller) ControllerModifyVolume(ctx context.Context, req *csi.ControllerModifyVolumeRequest) (
+	*csi.ControllerModifyVolumeResponse, error) {
+	start := time.Now()
+	ctx = logger.NewContextWithLogger(ctx)
+	log := logger.GetLogger(ctx)
+	log.Infof("ControllerModifyVolume: called with args %+v", req)
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeAttributesClass) {
+		return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "ControllerModifyVolume")
+	}
+	volumeType := prometheus.PrometheusUnknownVolumeType
+
+	controllerModifyVolumeInternal := func() (*csi.ControllerModifyVolumeResponse, string, error) {
+		volumeID := req.GetVolumeId()
+		if volumeID == "" {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
+				"volume ID must be provided")
+		}
+		if strings.Contains(volumeID, ".vmdk") {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"cannot modify migrated in-tree vSphere volume %q", volumeID)
+		}
+		if common.GetCnsVolumeType(ctx, volumeID) == common.FileVolumeType {
+			volumeType = prometheus.PrometheusFileVolumeType
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"cannot modify file volume %q. Only block volumes are supported", volumeID)
+		}
+		volumeType = prometheus.PrometheusBlockVolumeType
+		if err := checkVolumeNotInGroupSnapshot(ctx, volumeID); err != nil {
+			return nil, csifault.CSIInternalFault, err
+		}
+		params, err := parseModifyVolumeParams(req.GetMutableParameters())
+		if err != nil {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"invalid mutable parameters for volume %q. Error: %v", volumeID, err)
+		}
+		return c.modifyVolume(ctx, volumeID, params)
+	}
+
+	resp, faultType, err := controllerModifyVolumeInternal()
+	if err != nil {
+		if csifault.IsNonStorageFault(faultType) {
+			faultType = csifault.AddCsiNonStoragePrefix(ctx, faultType)
+		}
+		log.Errorf("Operation failed, reporting failure status to Prometheus."+
+			" Operation Type: %q, Volume Type: %q, Fault Type: %q",
+			prometheus.PrometheusModifyVolumeOpType, volumeType, faultType)
+		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusModifyVolumeOpType,
+			prometheus.PrometheusFailStatus, faultType).Observe(time.Since(start).Seconds())
+	} else {
+		log.Infof("Volume %q modified successfully.", req.GetVolumeId())
+		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusModifyVolumeOpType,
+			prometheus.PrometheusPassStatus, faultType).Observe(time.Since(start).Seconds())
+	}
+	return resp, err
+}
+
+// modifyVolume changes the storage policy and datastore of the given block
+// volume to match the given parameters.
+func (c *controller) modifyVolume(ctx context.Context, volumeID string, params *modifyVolumeParams) (
+	*csi.ControllerModifyVolumeResponse, string, error) {
+	log := logger.GetLogger(ctx)
+	vCenterHost, volumeManager, err := getVCenterAndVolumeManagerForVolumeID(ctx, c, volumeID, volumeInfoService)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get vCenter/volume manager for volume Id: %q. Error: %v", volumeID, err)
+	}
+	vCenter, err := common.GetVCenterFromVCHost(ctx, c.managers.VcenterManager, vCenterHost)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get vCenter instance for host %q. Error: %+v", vCenterHost, err)
+	}
+	operationStore := volumeManager.GetOperationStore()
+	if operationStore == nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal,
+			"failed to get operation store for volume manager")
+	}
+	policyInstanceName := modifyPolicyInstancePrefix + volumeID + "-" + params.key()
+	relocateInstanceName := modifyRelocateInstancePrefix + volumeID + "-" + params.key()
+
+	// Rejoin a task started by a previous call for the same parameters. The
+	// volume is re-evaluated once the task completes, as the task may have
+	// been started from a different state of the volume.
+	for _, instanceName := range []string{relocateInstanceName, policyInstanceName} {
+		details, err := operationStore.GetRequestDetails(ctx, instanceName)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get details of operation %q. Error: %+v", instanceName, err)
+		}
+		if details.OperationDetails == nil || !cnsvolume.IsTaskPending(details) {
+			continue
+		}
+		log.Infof("Volume %q has task %q pending for operation %q", volumeID,
+			details.OperationDetails.TaskID, instanceName)
+		taskRef := vim25types.ManagedObjectReference{Type: "Task", Value: details.OperationDetails.TaskID}
+		faultType, err := c.waitForModifyTask(ctx, operationStore, details, vCenter, taskRef)
+		if err != nil {
+			return nil, faultType, err
+		}
+	}
+
+	volume, err := queryBlockVolume(ctx, volumeManager, volumeID)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+	}
+	if volume == nil {
+		return nil, csifault.CSINotFoundFault, logger.LogNewErrorCodef(log, codes.NotFound,
+			"volume %q not found", volumeID)
+	}
+	targetPolicyID := volume.StoragePolicyId
+	if params.StoragePolicyName != "" {
+		targetPolicyID, err = vCenter.GetStoragePolicyIDByName(ctx, params.StoragePolicyName)
+		if err != nil {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"failed to get policy ID for storage policy name %q. Error: %+v", params.StoragePolicyName, err)
+		}
+	}
+	if params.RequiredIopsLimit != nil {
+		if targetPolicyID == "" {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"volume %q has no storage policy enforcing an IOPS limit of %d. Set storagepolicyname as well",
+				volumeID, *params.RequiredIopsLimit)
+		}
+		policyContent, err := vCenter.PbmRetrieveContent(ctx, []string{targetPolicyID})
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to retrieve content of storage policy %q. Error: %+v", targetPolicyID, err)
+		}
+		iopsLimit, err := getIopsLimitFromPolicyContent(policyContent)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"storage policy %q has an invalid IOPS limit. Error: %v", targetPolicyID, err)
+		}
+		if iopsLimit == nil || *iopsLimit != *params.RequiredIopsLimit {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"storage policy %q does not enforce an IOPS limit of %d", targetPolicyID, *params.RequiredIopsLimit)
+		}
+	}
+	sameDatastore := params.DatastoreURL == "" || params.DatastoreURL == volume.DatastoreUrl
+	if targetPolicyID == volume.StoragePolicyId && sameDatastore {
+		log.Infof("Volume %q already has storage policy %q on datastore %q", volumeID,
+			targetPolicyID, volume.DatastoreUrl)
+		return &csi.ControllerModifyVolumeResponse{}, "", nil
+	}
+
+	currentDatastore, err := getDatastoreInfoByURL(ctx, vCenter, volume.DatastoreUrl)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to find datastore %q of volume %q. Error: %+v", volume.DatastoreUrl, volumeID, err)
+	}
+	if sameDatastore {
+		compatibleDatastores := []*cnsvsphere.DatastoreInfo{currentDatastore}
+		if targetPolicyID != "" {
+			compatibleDatastores, err = filterDatastoresByStoragePolicy(ctx, vCenter,
+				compatibleDatastores, targetPolicyID)
+			if err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+			}
+		}
+		if len(compatibleDatastores) != 0 {
+			log.Infof("Changing storage policy of volume %q to %q on datastore %q", volumeID,
+				targetPolicyID, volume.DatastoreUrl)
+			task, err := updateVolumePolicyHook(ctx, vCenter, volumeID, currentDatastore.Reference(),
+				targetPolicyID)
+			if err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+					"failed to change storage policy of volume %q to %q. Error: %+v", volumeID, targetPolicyID, err)
+			}
+			details := cnsvolumeoperationrequest.CreateVolumeOperationRequestDetails(policyInstanceName,
+				volumeID, "", 0, nil, metav1.Now(), task.Reference().Value, vCenterHost, "",
+				cnsvolumeoperationrequest.TaskInvocationStatusInProgress, "", "")
+			if err := operationStore.StoreRequestDetails(ctx, details); err != nil {
+				log.Warnf("failed to store details of operation %q. Error: %+v", policyInstanceName, err)
+			}
+			faultType, err := c.waitForModifyTask(ctx, operationStore, details, vCenter, task.Reference())
+			if err != nil {
+				return nil, faultType, err
+			}
+			return &csi.ControllerModifyVolumeResponse{}, "", nil
+		}
+		log.Infof("Datastore %q of volume %q is not compatible with storage policy %q. Relocating the volume.",
+			volume.DatastoreUrl, volumeID, targetPolicyID)
+	}
+
+	var capacityInMb int64
+	if backingDetails, ok := volume.BackingObjectDetails.(*cnstypes.CnsBlockBackingDetails); ok {
+		capacityInMb = backingDetails.CapacityInMb
+	}
+	targetDatastore, faultType, err := c.getRelocationDatastore(ctx, vCenter, vCenterHost, currentDatastore,
+		targetPolicyID, params.DatastoreURL, capacityInMb)
+	if err != nil {
+		return nil, faultType, err
+	}
+	relocateSpec := cnstypes.NewCnsBlockVolumeRelocateSpec(volumeID, targetDatastore.Reference())
+	if targetPolicyID != "" {
+		relocateSpec.Profile = []vim25types.BaseVirtualMachineProfileSpec{
+			&vim25types.VirtualMachineDefinedProfileSpec{ProfileId: targetPolicyID},
+		}
+	}
+	log.Infof("Relocating volume %q to datastore %q with storage policy %q", volumeID,
+		targetDatastore.Info.Url, targetPolicyID)
+	task, err := volumeManager.RelocateVolume(ctx, relocateSpec)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to relocate volume %q to datastore %q. Error: %+v", volumeID, targetDatastore.Info.Url, err)
+	}
+	details := cnsvolumeoperationrequest.CreateVolumeOperationRequestDetails(relocateInstanceName,
+		volumeID, "", capacityInMb, nil, metav1.Now(), task.Reference().Value, vCenterHost, "",
+		cnsvolumeoperationrequest.TaskInvocationStatusInProgress, "", "")
+	if err := operationStore.StoreRequestDetails(ctx, details); err != nil {
+		log.Warnf("failed to store details of operation %q. Error: %+v", relocateInstanceName, err)
+	}
+	faultType, err = c.waitForModifyTask(ctx, operationStore, details, vCenter, task.Reference())
+	if err != nil {
+		return nil, faultType, err
+	}
+	return &csi.ControllerModifyVolumeResponse{}, "", nil
+}
+
+// waitForModifyTask waits for the given task and records its outcome in the
+// operation store. When the context expires first, the operation is left in
+// progress so that the next call rejoins the task.
+func (c *controller) waitForModifyTask(ctx context.Context,
+	operationStore cnsvolumeoperationrequest.VolumeOperationRequest,
+	details *cnsvolumeoperationrequest.VolumeOperationRequestDetails, vCenter *cnsvsphere.VirtualCenter,
+	taskRef vim25types.ManagedObjectReference) (string, error) {
+	log := logger.GetLogger(ctx)
+	err := waitForModifyTaskHook(ctx, vCenter, taskRef)
+	if err != nil && ctx.Err() != nil {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.DeadlineExceeded,
+			"timed out waiting for task %q of volume %q. The task continues in the background.",
+			taskRef.Value, details.VolumeID)
+	}
+	details.OperationDetails.TaskStatus = cnsvolumeoperationrequest.TaskInvocationStatusSuccess
+	if err != nil {
+		details.OperationDetails.TaskStatus = cnsvolumeoperationrequest.TaskInvocationStatusError
+		details.OperationDetails.Error = err.Error()
+	}
+	if storeErr := operationStore.StoreRequestDetails(ctx, details); storeErr != nil {
+		log.Warnf("failed to store details of operation %q. Error: %+v", details.Name, storeErr)
+	}
+	if err != nil {
+		return csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"task %q to modify volume %q failed. Error: %+v", taskRef.Value, details.VolumeID, err)
+	}
+	log.Infof("Task %q to modify volume %q completed", taskRef.Value, details.VolumeID)
+	return "", nil
+}
+
+// queryBlockVolume returns the CNS block volume with the given ID, or nil if
+// it does not exist.
+func queryBlockVolume(ctx context.Context, volumeManager cnsvolume.Manager, volumeID string) (
+	*cnstypes.CnsVolume, error) {
+	queryFilter := cnstypes.CnsQueryFilter{
+		VolumeIds: []cnstypes.CnsVolumeId{{Id: volumeID}},
+	}
+	queryResult, err := volumeManager.QueryVolume(ctx, queryFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query volume %q. Error: %+v", volumeID, err)
+	}
+	for i := range queryResult.Volumes {
+		volume := &queryResult.Volumes[i]
+		if volume.VolumeId.Id != volumeID {
+			continue
+		}
+		if volume.VolumeType != common.BlockVolumeType {
+			return nil, fmt.Errorf("volume %q is not a block volume", volumeID)
+		}
+		return volume, nil
+	}
+	return nil, nil
+}
+
+// getDatastoreInfoByURL returns the datastore with the given URL across all
+// datacenters of the vCenter.
+func getDatastoreInfoByURL(ctx context.Context, vCenter *cnsvsphere.VirtualCenter,
+	datastoreURL string) (*cnsvsphere.DatastoreInfo, error) {
+	datacenters, err := vCenter.GetDatacenters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, datacenter := range datacenters {
+		datastore, err := datacenter.GetDatastoreInfoByURL(ctx, datastoreURL)
+		if err == nil {
+			return datastore, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q on vCenter %q", errDatastoreNotFound, datastoreURL, vCenter.Config.Host)
+}
+
+// getRelocationDatastore returns the datastore to relocate a volume to for a
+// storage policy change. Candidates must be mounted on every host which mounts
+// the current datastore of the volume, so that the volume stays accessible to
+// the same nodes, be compatible with the storage policy and be authorized for
+// block volumes. The candidate with the most free space is picked unless
+// datastoreURL requests a specific one.
+func (c *controller) getRelocationDatastore(ctx context.Context, vCenter *cnsvsphere.VirtualCenter,
+	vCenterHost string, currentDatastore *cnsvsphere.DatastoreInfo, storagePolicyID string,
+	datastoreURL string, capacityInMb int64) (*cnsvsphere.DatastoreInfo, string, error) {
+	log := logger.GetLogger(ctx)
+	var datastoreMo mo.Datastore
+	pc := property.DefaultCollector(vCenter.Client.Client)
+	err := pc.RetrieveOne(ctx, currentDatastore.Reference(), []string{"host"}, &datastoreMo)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get hosts of datastore %q. Error: %+v", currentDatastore.Info.Url, err)
+	}
+	var hosts []*cnsvsphere.HostSystem
+	for _, hostMount := range datastoreMo.Host {
+		hosts = append(hosts, &cnsvsphere.HostSystem{
+			HostSystem: object.NewHostSystem(vCenter.Client.Client, hostMount.Key),
+		})
+	}
+	candidates, err := cnsvsphere.GetSharedDatastoresForHosts(ctx, hosts)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get datastores shared with datastore %q. Error: %+v", currentDatastore.Info.Url, err)
+	}
+	var otherDatastores []*cnsvsphere.DatastoreInfo
+	for _, candidate := range candidates {
+		if candidate.Info.Url != currentDatastore.Info.Url {
+			otherDatastores = append(otherDatastores, candidate)
+		}
+	}
+	candidates = otherDatastores
+	if storagePolicyID != "" {
+		candidates, err = filterDatastoresByStoragePolicy(ctx, vCenter, candidates, storagePolicyID)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+		}
+	}
+	if len(candidates) != 0 {
+		candidates, err = c.filterDatastores(ctx, candidates, vCenterHost)
+		if err != nil && err != errAllDSFilteredOut {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+		}
+	}
+	candidates, err = refreshDatastoreFreeSpace(ctx, vCenter, candidates)
+	if err != nil {
+		return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+	}
+	var targetDatastore *cnsvsphere.DatastoreInfo
+	for _, candidate := range candidates {
+		if candidate.Info.FreeSpace < capacityInMb*common.MbInBytes {
+			continue
+		}
+		if datastoreURL != "" {
+			if candidate.Info.Url == datastoreURL {
+				targetDatastore = candidate
+				break
+			}
+			continue
+		}
+		if targetDatastore == nil || candidate.Info.FreeSpace > targetDatastore.Info.FreeSpace {
+			targetDatastore = candidate
+		}
+	}
+	if targetDatastore == nil {
+		if datastoreURL != "" {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"datastore %q is not accessible to the nodes of the volume, not compatible with storage "+
+					"policy %q, or does not have %d MB of free space", datastoreURL, storagePolicyID, capacityInMb)
+		}
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"no datastore accessible to the nodes of the volume is compatible with storage policy %q "+
+				"and has %d MB of free space", storagePolicyID, capacityInMb)
+	}
+	return targetDatastore, "", nil
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vanilla
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/object"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/unittestcommon"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsvolumeoperationrequest"
+)
+
+const testModifyStoragePolicyName = "vSAN Default Storage Policy"
+
+// modifyMockVolumeManager reports the given storage policy for every volume,
+// as vcsim does not track the storage policy of volumes.
+type modifyMockVolumeManager struct {
+	cnsvolume.Manager
+	storagePolicyID string
+}
+
+func (m *modifyMockVolumeManager) QueryVolume(ctx context.Context,
+	queryFilter cnstypes.CnsQueryFilter) (*cnstypes.CnsQueryResult, error) {
+	res, err := m.Manager.QueryVolume(ctx, queryFilter)
+	if err == nil && res != nil {
+		for i := range res.Volumes {
+			res.Volumes[i].StoragePolicyId = m.storagePolicyID
+		}
+	}
+	return res, err
+}
+
+// setupModifyTest enables the volume-attributes-class FSS, installs a
+// modifyMockVolumeManager and stubs the vCenter tasks. It returns the mock
+// and a counter of the policy updates started.
+func setupModifyTest(t *testing.T, ct *controllerTest, storagePolicyID string,
+	waitErr func(ctx context.Context) error) (*modifyMockVolumeManager, *int) {
+	fakeOrchestrator := commonco.ContainerOrchestratorUtility.(*unittestcommon.FakeK8SOrchestrator)
+	if err := fakeOrchestrator.EnableFSS(ctx, common.VolumeAttributesClass); err != nil {
+		t.Fatal(err)
+	}
+	host := ct.controller.managers.VcenterConfigs[ct.controller.managers.CnsConfig.Global.VCenterIP].Host
+	origVolumeManager := ct.controller.managers.VolumeManagers[host]
+	mockVolumeManager := &modifyMockVolumeManager{Manager: origVolumeManager, storagePolicyID: storagePolicyID}
+	ct.controller.managers.VolumeManagers[host] = mockVolumeManager
+	origUpdateHook := updateVolumePolicyHook
+	origWaitHook := waitForModifyTaskHook
+	policyUpdates := 0
+	updateVolumePolicyHook = func(ctx context.Context, vc *cnsvsphere.VirtualCenter, volumeID string,
+		datastore vim25types.ManagedObjectReference, storagePolicyID string) (*object.Task, error) {
+		policyUpdates++
+		return object.NewTask(vc.Client.Client, vim25types.ManagedObjectReference{
+			Type: "Task", Value: "task-modify"}), nil
+	}
+	waitForModifyTaskHook = func(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+		taskRef vim25types.ManagedObjectReference) error {
+		if waitErr != nil {
+			return waitErr(ctx)
+		}
+		return nil
+	}
+	t.Cleanup(func() {
+		ct.controller.managers.VolumeManagers[host] = origVolumeManager
+		updateVolumePolicyHook = origUpdateHook
+		waitForModifyTaskHook = origWaitHook
+		_ = fakeOrchestrator.DisableFSS(ctx, common.VolumeAttributesClass)
+	})
+	return mockVolumeManager, &policyUpdates
+}
+
+func TestParseModifyVolumeParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "no parameters",
+			wantErr: true,
+		},
+		{
+			name:    "unknown parameter",
+			params:  map[string]string{"fstype": "ext4"},
+			wantErr: true,
+		},
+		{
+			name:    "negative IOPS limit",
+			params:  map[string]string{common.AttributeRequiredIopsLimit: "-1"},
+			wantErr: true,
+		},
+		{
+			name: "case insensitive parameter names",
+			params: map[string]string{
+				"StoragePolicyName": testModifyStoragePolicyName,
+				"RequiredIopsLimit": "500",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := parseModifyVolumeParams(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseModifyVolumeParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (params.StoragePolicyName != testModifyStoragePolicyName ||
+				params.RequiredIopsLimit == nil || *params.RequiredIopsLimit != 500) {
+				t.Fatalf("unexpected parameters %+v", params)
+			}
+		})
+	}
+}
+
+func TestModifyVolumeParamsKey(t *testing.T) {
+	first := &modifyVolumeParams{StoragePolicyName: "gold"}
+	second := &modifyVolumeParams{StoragePolicyName: "silver"}
+	if first.key() == second.key() {
+		t.Fatalf("expected different keys for different storage policies")
+	}
+	if first.key() != (&modifyVolumeParams{StoragePolicyName: "gold"}).key() {
+		t.Fatalf("expected the same key for the same parameters")
+	}
+}
+
+func TestControllerModifyVolumeFSSDisabled(t *testing.T) {
+	ct := getControllerTest(t)
+	_, err := ct.controller.ControllerModifyVolume(ctx, &csi.ControllerModifyVolumeRequest{
+		VolumeId:          "volume-1",
+		MutableParameters: map[string]string{common.AttributeStoragePolicyName: testModifyStoragePolicyName},
+	})
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("expected Unimplemented, got %v", err)
+	}
+}
+
+func TestControllerModifyVolumeInvalidArgument(t *testing.T) {
+	ct := getControllerTest(t)
+	setupModifyTest(t, ct, "", nil)
+	tests := []struct {
+		name   string
+		volID  string
+		params map[string]string
+	}{
+		{
+			name:   "file volume",
+			volID:  "file:volume-1",
+			params: map[string]string{common.AttributeStoragePolicyName: testModifyStoragePolicyName},
+		},
+		{
+			name:   "migrated volume",
+			volID:  "[vsanDatastore] kubevols/volume-1.vmdk",
+			params: map[string]string{common.AttributeStoragePolicyName: testModifyStoragePolicyName},
+		},
+		{
+			name:   "unknown parameter",
+			volID:  "volume-1",
+			params: map[string]string{"fstype": "ext4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ct.controller.ControllerModifyVolume(ctx, &csi.ControllerModifyVolumeRequest{
+				VolumeId:          tt.volID,
+				MutableParameters: tt.params,
+			})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestControllerModifyVolumeNoChange(t *testing.T) {
+	ct := getControllerTest(t)
+	policyID, err := ct.vcenter.GetStoragePolicyIDByName(ctx, testModifyStoragePolicyName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, policyUpdates := setupModifyTest(t, ct, policyID, nil)
+	volID := createCBTTestVolume(t, ct)
+	_, err = ct.controller.ControllerModifyVolume(ctx, &csi.ControllerModifyVolumeRequest{
+		VolumeId:          volID,
+		MutableParameters: map[string]string{common.AttributeStoragePolicyName: testModifyStoragePolicyName},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *policyUpdates != 0 {
+		t.Fatalf("expected no policy update, got %d", *policyUpdates)
+	}
+}
+
+func TestControllerModifyVolumeInPlace(t *testing.T) {
+	ct := getControllerTest(t)
+	_, policyUpdates := setupModifyTest(t, ct, "old-policy-id", nil)
+	volID := createCBTTestVolume(t, ct)
+	req := &csi.ControllerModifyVolumeRequest{
+		VolumeId:          volID,
+		MutableParameters: map[string]string{common.AttributeStoragePolicyName: testModifyStoragePolicyName},
+	}
+	if _, err := ct.controller.ControllerModifyVolume(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if *policyUpdates != 1 {
+		t.Fatalf("expected one policy update, got %d", *policyUpdates)
+	}
+	params, _ := parseModifyVolumeParams(req.MutableParameters)
+	details, err := ct.operationStore.GetRequestDetails(ctx, modifyPolicyInstancePrefix+volID+"-"+params.key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.OperationDetails.TaskStatus != cnsvolumeoperationrequest.TaskInvocationStatusSuccess {
+		t.Fatalf("expected the operation to be recorded as successful, got %+v", details.OperationDetails)
+	}
+}
+
+func TestControllerModifyVolumeRejoinsPendingTask(t *testing.T) {
+	ct := getControllerTest(t)
+	// The first call times out while the policy update is running.
+	taskRunning := true
+	mockVolumeManager, policyUpdates := setupModifyTest(t, ct, "old-policy-id", func(ctx context.Context) error {
+		if !taskRunning {
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	volID := createCBTTestVolume(t, ct)
+	req := &csi.ControllerModifyVolumeRequest{
+		VolumeId:          volID,
+		MutableParameters: map[string]string{common.AttributeStoragePolicyName: testModifyStoragePolicyName},
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err := ct.controller.ControllerModifyVolume(timeoutCtx, req)
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	params, _ := parseModifyVolumeParams(req.MutableParameters)
+	instanceName := modifyPolicyInstancePrefix + volID + "-" + params.key()
+	details, err := ct.operationStore.GetRequestDetails(ctx, instanceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cnsvolume.IsTaskPending(details) {
+		t.Fatalf("expected the task to be pending, got %+v", details.OperationDetails)
+	}
+
+	// The retry waits for the same task instead of starting a new one, and
+	// then finds the volume with the requested policy.
+	taskRunning = false
+	mockVolumeManager.storagePolicyID, err = ct.vcenter.GetStoragePolicyIDByName(ctx, testModifyStoragePolicyName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ct.controller.ControllerModifyVolume(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if *policyUpdates != 1 {
+		t.Fatalf("expected the pending task to be rejoined, got %d policy updates", *policyUpdates)
+	}
+	details, err = ct.operationStore.GetRequestDetails(ctx, instanceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.OperationDetails.TaskStatus != cnsvolumeoperationrequest.TaskInvocationStatusSuccess {
+		t.Fatalf("expected the operation to be recorded as successful, got %+v", details.OperationDetails)
+	}
+}
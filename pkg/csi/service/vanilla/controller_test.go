@@ -21,10 +21,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/vmware/govmomi/cns"
@@ -33,11 +35,13 @@ import (
 	"github.com/vmware/govmomi/pbm"
 	"github.com/vmware/govmomi/pbm/types"
 	"github.com/vmware/govmomi/vim25"
+	vimtypes "github.com/vmware/govmomi/vim25/types"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/prototext"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	v1 "k8s.io/api/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	testclient "k8s.io/client-go/kubernetes/fake"
 
@@ -2601,3 +2605,794 @@ func TestCreateSnapshotWithCnsSnapshotCreatedFault(t *testing.T) {
 		t.Fatal("expected error was not received for create snapshot operation.")
 	}
 }
+
+func TestGetCapacity(t *testing.T) {
+	ct := getControllerTest(t)
+
+	params := make(map[string]string)
+	if v := os.Getenv("VSPHERE_DATASTORE_URL"); v != "" {
+		params[common.AttributeDatastoreURL] = v
+	}
+	// PBM simulator defaults.
+	params[common.AttributeStoragePolicyName] = "vSAN Default Storage Policy"
+	if v := os.Getenv("VSPHERE_STORAGE_POLICY_NAME"); v != "" {
+		params[common.AttributeStoragePolicyName] = v
+	}
+	// Parameters reserved for the CSI sidecars must be ignored.
+	params["csi.storage.k8s.io/fstype"] = "ext4"
+	capabilities := []*csi.VolumeCapability{
+		{
+			AccessMode: &csi.VolumeCapability_AccessMode{
+				Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+			},
+		},
+	}
+
+	resp, err := ct.controller.GetCapacity(ctx, &csi.GetCapacityRequest{
+		VolumeCapabilities: capabilities,
+		Parameters:         params,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.AvailableCapacity <= 0 {
+		t.Fatalf("expected available capacity to be greater than 0, got %d", resp.AvailableCapacity)
+	}
+	if resp.MaximumVolumeSize == nil || resp.MaximumVolumeSize.Value > resp.AvailableCapacity {
+		t.Fatalf("unexpected maximum volume size %v for available capacity %d",
+			resp.MaximumVolumeSize, resp.AvailableCapacity)
+	}
+
+	// A datastore URL outside the compatible shared datastores is rejected, same as in CreateVolume.
+	_, err = ct.controller.GetCapacity(ctx, &csi.GetCapacityRequest{
+		VolumeCapabilities: capabilities,
+		Parameters: map[string]string{
+			common.AttributeStoragePolicyName: params[common.AttributeStoragePolicyName],
+			common.AttributeDatastoreURL:      "ds:///vmfs/volumes/non-existent-datastore/",
+		},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument error for unknown datastore URL, got %v", err)
+	}
+
+	// A storage policy which does not exist on the vCenter offers no capacity.
+	params[common.AttributeStoragePolicyName] = "non-existent-policy-" + uuid.New().String()
+	resp, err = ct.controller.GetCapacity(ctx, &csi.GetCapacityRequest{
+		VolumeCapabilities: capabilities,
+		Parameters:         params,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.AvailableCapacity != 0 || resp.MaximumVolumeSize.GetValue() != 0 {
+		t.Fatalf("expected no capacity for unknown storage policy, got %+v", resp)
+	}
+
+	// Invalid StorageClass parameters are rejected.
+	_, err = ct.controller.GetCapacity(ctx, &csi.GetCapacityRequest{
+		VolumeCapabilities: capabilities,
+		Parameters:         map[string]string{"invalid-param": "value"},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument error for invalid parameters, got %v", err)
+	}
+}
+
+func TestGetCapacityFromDatastores(t *testing.T) {
+	newDatastore := func(url string, freeSpace int64) *cnsvsphere.DatastoreInfo {
+		return &cnsvsphere.DatastoreInfo{
+			Info: &vimtypes.DatastoreInfo{Url: url, FreeSpace: freeSpace},
+		}
+	}
+	datastores := []*cnsvsphere.DatastoreInfo{
+		newDatastore("ds:///vmfs/volumes/ds1/", 10*common.GbInBytes),
+		newDatastore("ds:///vmfs/volumes/ds2/", 30*common.GbInBytes),
+		newDatastore("ds:///vmfs/volumes/ds3/", -1),
+		nil,
+	}
+	tests := []struct {
+		name              string
+		datastoreURL      string
+		expectedAvailable int64
+		expectedMaximum   int64
+	}{
+		{
+			name:              "all datastores",
+			expectedAvailable: 40 * common.GbInBytes,
+			expectedMaximum:   30 * common.GbInBytes,
+		},
+		{
+			name:              "datastore URL from storage class",
+			datastoreURL:      "ds:///vmfs/volumes/ds1/",
+			expectedAvailable: 10 * common.GbInBytes,
+			expectedMaximum:   10 * common.GbInBytes,
+		},
+		{
+			name:         "datastore URL not among candidates",
+			datastoreURL: "ds:///vmfs/volumes/ds4/",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			available, maximum := getCapacityFromDatastores(context.Background(), datastores, test.datastoreURL)
+			if available != test.expectedAvailable || maximum != test.expectedMaximum {
+				t.Errorf("expected (%d, %d), got (%d, %d)", test.expectedAvailable, test.expectedMaximum,
+					available, maximum)
+			}
+		})
+	}
+}
+
+func TestCreateVolumeFromVolume(t *testing.T) {
+	ct := getControllerTest(t)
+
+	params := make(map[string]string)
+	if v := os.Getenv("VSPHERE_DATASTORE_URL"); v != "" {
+		params[common.AttributeDatastoreURL] = v
+	}
+	capabilities := []*csi.VolumeCapability{
+		{
+			AccessMode: &csi.VolumeCapability_AccessMode{
+				Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+			},
+		},
+	}
+	deleteVolume := func(volumeID string) {
+		_, err := ct.controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Create the source volume.
+	respCreate, err := ct.controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: testVolumeName + "-" + uuid.New().String(),
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 1 * common.GbInBytes,
+		},
+		Parameters:         params,
+		VolumeCapabilities: capabilities,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceVolID := respCreate.Volume.VolumeId
+	defer deleteVolume(sourceVolID)
+
+	volumeContentSource := &csi.VolumeContentSource{
+		Type: &csi.VolumeContentSource_Volume{
+			Volume: &csi.VolumeContentSource_VolumeSource{
+				VolumeId: sourceVolID,
+			},
+		},
+	}
+
+	// Clone the volume into a bigger volume.
+	respClone, err := ct.controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: testVolumeName + "-" + uuid.New().String(),
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 2 * common.GbInBytes,
+		},
+		Parameters:          params,
+		VolumeCapabilities:  capabilities,
+		VolumeContentSource: volumeContentSource,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloneVolID := respClone.Volume.VolumeId
+	defer deleteVolume(cloneVolID)
+
+	if respClone.Volume.GetContentSource().GetVolume().GetVolumeId() != sourceVolID {
+		t.Fatalf("expected content source volume %q, got %+v", sourceVolID, respClone.Volume.GetContentSource())
+	}
+	queryResult, err := ct.vcenter.CnsClient.QueryVolume(ctx, &cnstypes.CnsQueryFilter{
+		VolumeIds: []cnstypes.CnsVolumeId{{Id: cloneVolID}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queryResult.Volumes) != 1 {
+		t.Fatalf("failed to find the cloned volume with ID: %s", cloneVolID)
+	}
+	capacityInMB := queryResult.Volumes[0].BackingObjectDetails.GetCnsBackingObjectDetails().CapacityInMb
+	if capacityInMB != 2*common.GbInBytes/common.MbInBytes {
+		t.Fatalf("expected cloned volume of 2 GiB, got %d MB", capacityInMB)
+	}
+
+	// The snapshot taken to clone the volume must not be left behind.
+	snapshots, _, err := common.QueryVolumeSnapshotsByVolumeID(ctx, ct.controller.manager.VolumeManager,
+		sourceVolID, common.QuerySnapshotLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 0 {
+		t.Fatalf("expected no snapshots on source volume %q, got %+v", sourceVolID, snapshots)
+	}
+
+	// A clone smaller than its source is rejected.
+	_, err = ct.controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: testVolumeName + "-" + uuid.New().String(),
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 512 * common.MbInBytes,
+		},
+		Parameters:          params,
+		VolumeCapabilities:  capabilities,
+		VolumeContentSource: volumeContentSource,
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument error for clone smaller than its source, got %v", err)
+	}
+
+	// The storage policy of a clone is checked against the datastore of its
+	// source before the snapshot is taken.
+	policyParams := map[string]string{common.AttributeStoragePolicyName: "vSAN Default Storage Policy"}
+	for k, v := range params {
+		policyParams[k] = v
+	}
+	respClone, err = ct.controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: testVolumeName + "-" + uuid.New().String(),
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 1 * common.GbInBytes,
+		},
+		Parameters:          policyParams,
+		VolumeCapabilities:  capabilities,
+		VolumeContentSource: volumeContentSource,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteVolume(respClone.Volume.VolumeId)
+	policyParams[common.AttributeStoragePolicyName] = "no such policy"
+	_, err = ct.controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: testVolumeName + "-" + uuid.New().String(),
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 1 * common.GbInBytes,
+		},
+		Parameters:          policyParams,
+		VolumeCapabilities:  capabilities,
+		VolumeContentSource: volumeContentSource,
+	})
+	if err == nil {
+		t.Fatal("expected the clone with an unknown storage policy to fail")
+	}
+	snapshots, _, err = common.QueryVolumeSnapshotsByVolumeID(ctx, ct.controller.manager.VolumeManager,
+		sourceVolID, common.QuerySnapshotLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 0 {
+		t.Fatalf("expected no snapshots on source volume %q, got %+v", sourceVolID, snapshots)
+	}
+}
+
+// healthMockVolumeManager reports the given CNS health status for every volume.
+type healthMockVolumeManager struct {
+	cnsvolume.Manager
+	healthStatus string
+}
+
+func (m *healthMockVolumeManager) QueryVolume(ctx context.Context,
+	queryFilter cnstypes.CnsQueryFilter) (*cnstypes.CnsQueryResult, error) {
+	res, err := m.Manager.QueryVolume(ctx, queryFilter)
+	if err == nil && res != nil {
+		for i := range res.Volumes {
+			res.Volumes[i].HealthStatus = m.healthStatus
+		}
+	}
+	return res, err
+}
+
+func TestControllerGetVolume(t *testing.T) {
+	ct := getControllerTest(t)
+	fakeOrchestrator := commonco.ContainerOrchestratorUtility.(*unittestcommon.FakeK8SOrchestrator)
+
+	_, err := ct.controller.ControllerGetVolume(ctx, &csi.ControllerGetVolumeRequest{VolumeId: "volume-1"})
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("expected Unimplemented with the FSS disabled, got %v", err)
+	}
+
+	if err := fakeOrchestrator.EnableFSS(ctx, common.VolumeCondition); err != nil {
+		t.Fatal(err)
+	}
+	host := ct.controller.managers.VcenterConfigs[ct.controller.managers.CnsConfig.Global.VCenterIP].Host
+	origVolumeManager := ct.controller.managers.VolumeManagers[host]
+	mockVolumeManager := &healthMockVolumeManager{
+		Manager:      origVolumeManager,
+		healthStatus: string(types.PbmHealthStatusForEntityGreen),
+	}
+	ct.controller.managers.VolumeManagers[host] = mockVolumeManager
+	defer func() {
+		ct.controller.managers.VolumeManagers[host] = origVolumeManager
+		_ = fakeOrchestrator.DisableFSS(ctx, common.VolumeCondition)
+	}()
+
+	caps, err := ct.controller.ControllerGetCapabilities(ctx, &csi.ControllerGetCapabilitiesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	advertised := make(map[csi.ControllerServiceCapability_RPC_Type]bool)
+	for _, capability := range caps.Capabilities {
+		advertised[capability.GetRpc().GetType()] = true
+	}
+	if !advertised[csi.ControllerServiceCapability_RPC_GET_VOLUME] ||
+		!advertised[csi.ControllerServiceCapability_RPC_VOLUME_CONDITION] {
+		t.Fatalf("expected GET_VOLUME and VOLUME_CONDITION capabilities, got %+v", caps.Capabilities)
+	}
+
+	_, err = ct.controller.ControllerGetVolume(ctx, &csi.ControllerGetVolumeRequest{VolumeId: uuid.New().String()})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound for an unknown volume, got %v", err)
+	}
+
+	respCreate, err := ct.controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: testVolumeName + "-" + uuid.New().String(),
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 1 * common.GbInBytes,
+		},
+		VolumeCapabilities: []*csi.VolumeCapability{
+			{
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	volID := respCreate.Volume.VolumeId
+	defer func() {
+		_, _ = ct.controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volID})
+	}()
+
+	resp, err := ct.controller.ControllerGetVolume(ctx, &csi.ControllerGetVolumeRequest{VolumeId: volID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Volume.VolumeId != volID {
+		t.Fatalf("expected volume %q, got %q", volID, resp.Volume.VolumeId)
+	}
+	if len(resp.Status.PublishedNodeIds) != 0 {
+		t.Fatalf("expected no published nodes, got %v", resp.Status.PublishedNodeIds)
+	}
+	if resp.Status.VolumeCondition.Abnormal {
+		t.Fatalf("expected a normal volume condition, got %+v", resp.Status.VolumeCondition)
+	}
+
+	mockVolumeManager.healthStatus = string(types.PbmHealthStatusForEntityRed)
+	resp, err = ct.controller.ControllerGetVolume(ctx, &csi.ControllerGetVolumeRequest{VolumeId: volID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Status.VolumeCondition.Abnormal {
+		t.Fatalf("expected an abnormal volume condition, got %+v", resp.Status.VolumeCondition)
+	}
+}
+
+func TestGetCachedBlockVolumeIDToNodeUUIDMap(t *testing.T) {
+	cached := map[string][]string{"volume-1": {"node-uuid-1", "node-uuid-2"}}
+	setVolumeIDToNodeUUIDMap(cached)
+	defer func() {
+		setVolumeIDToNodeUUIDMap(make(map[string][]string))
+		volumeIDToNodeUUIDMapRefreshTime = time.Time{}
+	}()
+
+	// A fresh map is served without reading the node VMs, so a controller
+	// without a node manager is enough.
+	volumeIDNodeUUIDMap, err := getCachedBlockVolumeIDToNodeUUIDMap(ctx, &controller{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(volumeIDNodeUUIDMap["volume-1"], cached["volume-1"]) {
+		t.Fatalf("expected the cached map %v, got %v", cached, volumeIDNodeUUIDMap)
+	}
+}
+
+func TestMultiWriterBlockVolume(t *testing.T) {
+	ct := getControllerTest(t)
+	fakeOrchestrator := commonco.ContainerOrchestratorUtility.(*unittestcommon.FakeK8SOrchestrator)
+
+	multiWriterCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Block{
+			Block: &csi.VolumeCapability_BlockVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+		},
+	}
+	mountCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+		},
+	}
+	reqCreate := &csi.CreateVolumeRequest{
+		Name: testVolumeName + "-" + uuid.New().String(),
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 1 * common.GbInBytes,
+		},
+		VolumeCapabilities: []*csi.VolumeCapability{multiWriterCap},
+	}
+
+	_, err := ct.controller.CreateVolume(ctx, reqCreate)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument with the FSS disabled, got %v", err)
+	}
+
+	if err := fakeOrchestrator.EnableFSS(ctx, common.MultiWriterBlockVolume); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = fakeOrchestrator.DisableFSS(ctx, common.MultiWriterBlockVolume)
+	}()
+
+	respCreate, err := ct.controller.CreateVolume(ctx, reqCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volID := respCreate.Volume.VolumeId
+	defer func() {
+		_, _ = ct.controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volID})
+	}()
+
+	respValidate, err := ct.controller.ValidateVolumeCapabilities(ctx, &csi.ValidateVolumeCapabilitiesRequest{
+		VolumeId:           volID,
+		VolumeCapabilities: []*csi.VolumeCapability{multiWriterCap},
+	})
+	if err != nil || respValidate.Confirmed == nil {
+		t.Fatalf("expected multi-writer raw block to be confirmed, got %+v, err %v", respValidate, err)
+	}
+	respValidate, err = ct.controller.ValidateVolumeCapabilities(ctx, &csi.ValidateVolumeCapabilitiesRequest{
+		VolumeId:           volID,
+		VolumeCapabilities: []*csi.VolumeCapability{mountCap},
+	})
+	if err != nil || respValidate.Confirmed != nil || respValidate.Message == "" {
+		t.Fatalf("expected filesystem on a block volume to be refused, got %+v, err %v", respValidate, err)
+	}
+
+	vms, err := find.NewFinder(ct.vcenter.Client.Client).VirtualMachineList(ctx, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vms) < 2 {
+		t.Skip("at least two VMs are required to attach a multi-writer volume")
+	}
+	nodeIDs := []string{vms[0].UUID(ctx), vms[1].UUID(ctx)}
+
+	_, err = ct.controller.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+		VolumeId:         volID,
+		NodeId:           nodeIDs[0],
+		VolumeCapability: mountCap,
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected filesystem publish of a block volume to fail, got %v", err)
+	}
+
+	// The CNS simulator attaches a volume to a single VM, so the attach and
+	// detach calls are recorded instead.
+	host := ct.controller.managers.VcenterConfigs[ct.controller.managers.CnsConfig.Global.VCenterIP].Host
+	origVolumeManager := ct.controller.managers.VolumeManagers[host]
+	mockVolumeManager := &multiWriterMockVolumeManager{
+		Manager:  origVolumeManager,
+		attached: make(map[string]cnsvolume.BatchAttachRequest),
+	}
+	ct.controller.managers.VolumeManagers[host] = mockVolumeManager
+	defer func() {
+		ct.controller.managers.VolumeManagers[host] = origVolumeManager
+	}()
+
+	for _, nodeID := range nodeIDs {
+		resp, err := ct.controller.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+			VolumeId:         volID,
+			NodeId:           nodeID,
+			VolumeCapability: multiWriterCap,
+		})
+		if err != nil {
+			t.Fatalf("failed to publish volume %q to node %q: %v", volID, nodeID, err)
+		}
+		if resp.PublishContext[common.AttributeFirstClassDiskUUID] == "" {
+			t.Fatalf("expected a disk UUID in publish context %+v", resp.PublishContext)
+		}
+	}
+	if len(mockVolumeManager.attached) != len(nodeIDs) {
+		t.Fatalf("expected volume to be attached to %d nodes, got %+v", len(nodeIDs), mockVolumeManager.attached)
+	}
+	for vmUUID, attachRequest := range mockVolumeManager.attached {
+		if attachRequest.SharingMode != string(vimtypes.VirtualDiskSharingSharingMultiWriter) {
+			t.Fatalf("expected volume to be attached to node %q in multi-writer mode, got %q",
+				vmUUID, attachRequest.SharingMode)
+		}
+	}
+	for _, nodeID := range nodeIDs {
+		_, err := ct.controller.ControllerUnpublishVolume(ctx, &csi.ControllerUnpublishVolumeRequest{
+			VolumeId: volID,
+			NodeId:   nodeID,
+		})
+		if err != nil {
+			t.Fatalf("failed to unpublish volume %q from node %q: %v", volID, nodeID, err)
+		}
+	}
+	if len(mockVolumeManager.attached) != 0 {
+		t.Fatalf("expected volume to be detached from all nodes, got %+v", mockVolumeManager.attached)
+	}
+}
+
+// multiWriterMockVolumeManager records the attachments of multi-writer volumes.
+type multiWriterMockVolumeManager struct {
+	cnsvolume.Manager
+	// attached maps the UUID of a node VM to its attach request.
+	attached map[string]cnsvolume.BatchAttachRequest
+}
+
+func (m *multiWriterMockVolumeManager) BatchAttachVolumes(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+	batchAttachRequest []cnsvolume.BatchAttachRequest) ([]cnsvolume.BatchAttachResult, string, error) {
+	var results []cnsvolume.BatchAttachResult
+	for _, request := range batchAttachRequest {
+		m.attached[vm.UUID] = request
+		results = append(results, cnsvolume.BatchAttachResult{
+			VolumeID: request.VolumeID,
+			DiskUUID: "6000c29" + strings.ReplaceAll(request.VolumeID, "-", ""),
+		})
+	}
+	return results, "", nil
+}
+
+func (m *multiWriterMockVolumeManager) DetachVolume(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+	volumeID string) (string, error) {
+	delete(m.attached, vm.UUID)
+	return "", nil
+}
+
+func TestGetFileVolumeNetPermissions(t *testing.T) {
+	ct := getControllerTest(t)
+	fakeOrchestrator := commonco.ContainerOrchestratorUtility.(*unittestcommon.FakeK8SOrchestrator)
+	origNetPermissions := ct.controller.managers.CnsConfig.NetPermissions
+	origNewK8sClient := newK8sClient
+	defer func() {
+		ct.controller.managers.CnsConfig.NetPermissions = origNetPermissions
+		newK8sClient = origNewK8sClient
+		_ = fakeOrchestrator.DisableFSS(ctx, common.FileVolumeNetPermissions)
+	}()
+	ct.controller.managers.CnsConfig.NetPermissions = map[string]*config.NetPermissionConfig{
+		"tenant-a": {Ips: "10.0.0.0/24", Permissions: "READ_WRITE"},
+		"tenant-b": {Ips: "10.0.1.0/24", Permissions: "READ_ONLY", RootSquash: true},
+	}
+	k8sClient := testclient.NewSimpleClientset(&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:        "pvc-1",
+		Namespace:   "default",
+		Annotations: map[string]string{common.AnnNetPermissions: "tenant-b"},
+	}})
+	newK8sClient = func(ctx context.Context) (clientset.Interface, error) {
+		return k8sClient, nil
+	}
+	scParams := &common.StorageClassParams{NetPermissions: "tenant-a"}
+	req := &csi.CreateVolumeRequest{Parameters: map[string]string{
+		common.AttributeNetPermissions: "tenant-a",
+		common.AttributePvcName:        "pvc-1",
+		common.AttributePvcNamespace:   "default",
+	}}
+
+	if _, _, err := ct.controller.getFileVolumeNetPermissions(ctx, req, scParams); err == nil {
+		t.Fatal("expected the net permissions to be refused with the FSS disabled")
+	}
+	if err := fakeOrchestrator.EnableFSS(ctx, common.FileVolumeNetPermissions); err != nil {
+		t.Fatal(err)
+	}
+	netPerms, _, err := ct.controller.getFileVolumeNetPermissions(ctx, req, scParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(netPerms) != 1 || netPerms[0].Ips != "10.0.1.0/24" {
+		t.Fatalf("expected the net permissions of the PVC annotation, got %+v", netPerms)
+	}
+
+	// Without the PVC, the net permissions of the StorageClass are used.
+	delete(req.Parameters, common.AttributePvcName)
+	netPerms, _, err = ct.controller.getFileVolumeNetPermissions(ctx, req, scParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(netPerms) != 1 || netPerms[0].Ips != "10.0.0.0/24" {
+		t.Fatalf("expected the net permissions of the StorageClass, got %+v", netPerms)
+	}
+
+	netPerms, _, err = ct.controller.getFileVolumeNetPermissions(ctx, req, &common.StorageClassParams{})
+	if err != nil || netPerms != nil {
+		t.Fatalf("expected no net permissions, got %+v, err: %v", netPerms, err)
+	}
+	if _, _, err = ct.controller.getFileVolumeNetPermissions(ctx, req,
+		&common.StorageClassParams{NetPermissions: "tenant-c"}); err == nil {
+		t.Fatal("expected unknown net permissions to be refused")
+	}
+}
+
+func TestGetNodeEncryptionAttributes(t *testing.T) {
+	getControllerTest(t)
+	fakeOrchestrator := commonco.ContainerOrchestratorUtility.(*unittestcommon.FakeK8SOrchestrator)
+	defer func() {
+		_ = fakeOrchestrator.DisableFSS(ctx, common.NodeLUKSEncryption)
+	}()
+	mountCap := &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Mount{
+		Mount: &csi.VolumeCapability_MountVolume{}}}
+	req := &csi.CreateVolumeRequest{VolumeCapabilities: []*csi.VolumeCapability{mountCap}}
+	scParams := &common.StorageClassParams{NodeEncryption: common.NodeEncryptionLUKS}
+
+	if attributes, _, err := getNodeEncryptionAttributes(ctx, req, &common.StorageClassParams{}); err != nil ||
+		attributes != nil {
+		t.Fatalf("expected no attributes for an unencrypted volume, got %v, err: %v", attributes, err)
+	}
+	if _, _, err := getNodeEncryptionAttributes(ctx, req, scParams); err == nil {
+		t.Fatal("expected the node encryption to be refused with the FSS disabled")
+	}
+	if err := fakeOrchestrator.EnableFSS(ctx, common.NodeLUKSEncryption); err != nil {
+		t.Fatal(err)
+	}
+	attributes, _, err := getNodeEncryptionAttributes(ctx, req, scParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attributes[common.AttributeNodeEncryption] != common.NodeEncryptionLUKS ||
+		attributes[common.AttributeNodeEncryptionKeySource] != common.NodeEncryptionKeySourceSecret {
+		t.Fatalf("expected LUKS encryption with the secret key source, got %v", attributes)
+	}
+
+	for _, invalid := range []*common.StorageClassParams{
+		{NodeEncryption: "dm-crypt"},
+		{NodeEncryption: common.NodeEncryptionLUKS, NodeEncryptionKeySource: "vault"},
+		{NodeEncryptionKeySource: common.NodeEncryptionKeySourceKMS},
+	} {
+		if _, _, err := getNodeEncryptionAttributes(ctx, req, invalid); err == nil {
+			t.Fatalf("expected parameters %+v to be refused", invalid)
+		}
+	}
+	req.VolumeCapabilities = []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Block{
+		Block: &csi.VolumeCapability_BlockVolume{}}}}
+	if _, _, err := getNodeEncryptionAttributes(ctx, req, scParams); err == nil {
+		t.Fatal("expected the node encryption of a raw block volume to be refused")
+	}
+}
+
+func TestGetMkfsOptionsAttributes(t *testing.T) {
+	getControllerTest(t)
+	fakeOrchestrator := commonco.ContainerOrchestratorUtility.(*unittestcommon.FakeK8SOrchestrator)
+	defer func() {
+		_ = fakeOrchestrator.DisableFSS(ctx, common.BlockVolumeMkfsOptions)
+	}()
+	xfsCap := &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Mount{
+		Mount: &csi.VolumeCapability_MountVolume{FsType: "xfs"}}}
+	req := &csi.CreateVolumeRequest{VolumeCapabilities: []*csi.VolumeCapability{xfsCap}}
+	scParams := &common.StorageClassParams{MkfsOptions: "-m reflink=1"}
+
+	if attributes, _, err := getMkfsOptionsAttributes(ctx, req, &common.StorageClassParams{}); err != nil ||
+		attributes != nil {
+		t.Fatalf("expected no attributes without mkfs options, got %v, err: %v", attributes, err)
+	}
+	if _, _, err := getMkfsOptionsAttributes(ctx, req, scParams); err == nil {
+		t.Fatal("expected the mkfs options to be refused with the FSS disabled")
+	}
+	if err := fakeOrchestrator.EnableFSS(ctx, common.BlockVolumeMkfsOptions); err != nil {
+		t.Fatal(err)
+	}
+	attributes, _, err := getMkfsOptionsAttributes(ctx, req, scParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attributes[common.AttributeMkfsOptions] != scParams.MkfsOptions {
+		t.Fatalf("expected the mkfs options in the volume context, got %v", attributes)
+	}
+
+	// The options of mkfs.xfs are refused for the default ext4 fstype.
+	req.VolumeCapabilities = []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Mount{
+		Mount: &csi.VolumeCapability_MountVolume{}}}}
+	if _, _, err := getMkfsOptionsAttributes(ctx, req, scParams); err == nil {
+		t.Fatal("expected xfs mkfs options to be refused for an ext4 volume")
+	}
+	req.VolumeCapabilities = []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Block{
+		Block: &csi.VolumeCapability_BlockVolume{}}}}
+	if _, _, err := getMkfsOptionsAttributes(ctx, req, scParams); err == nil {
+		t.Fatal("expected the mkfs options of a raw block volume to be refused")
+	}
+}
+
+func TestValidateBlockVolumeFsType(t *testing.T) {
+	getControllerTest(t)
+	fakeOrchestrator := commonco.ContainerOrchestratorUtility.(*unittestcommon.FakeK8SOrchestrator)
+	defer func() {
+		_ = fakeOrchestrator.DisableFSS(ctx, common.BtrfsBlockVolume)
+	}()
+	btrfsReq := &csi.CreateVolumeRequest{VolumeCapabilities: []*csi.VolumeCapability{{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{FsType: "btrfs"}}}}}
+	xfsReq := &csi.CreateVolumeRequest{VolumeCapabilities: []*csi.VolumeCapability{{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{FsType: "xfs"}}}}}
+
+	if _, err := validateBlockVolumeFsType(ctx, xfsReq); err != nil {
+		t.Fatalf("expected xfs to be supported, got %v", err)
+	}
+	if _, err := validateBlockVolumeFsType(ctx, btrfsReq); err == nil {
+		t.Fatal("expected btrfs to be refused with the FSS disabled")
+	}
+	if err := fakeOrchestrator.EnableFSS(ctx, common.BtrfsBlockVolume); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := validateBlockVolumeFsType(ctx, btrfsReq); err != nil {
+		t.Fatalf("expected btrfs to be supported with the FSS enabled, got %v", err)
+	}
+}
+
+func TestGetProjectQuotaAttributes(t *testing.T) {
+	getControllerTest(t)
+	fakeOrchestrator := commonco.ContainerOrchestratorUtility.(*unittestcommon.FakeK8SOrchestrator)
+	origNewK8sClient := newK8sClient
+	defer func() {
+		newK8sClient = origNewK8sClient
+		_ = fakeOrchestrator.DisableFSS(ctx, common.BlockVolumeProjectQuota)
+	}()
+	k8sClient := testclient.NewSimpleClientset(&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:        "pvc-1",
+		Namespace:   "default",
+		Annotations: map[string]string{common.AnnProjectQuotaDirectories: "logs=1Gi,data=4Gi"},
+	}})
+	newK8sClient = func(ctx context.Context) (clientset.Interface, error) {
+		return k8sClient, nil
+	}
+	xfsCap := &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Mount{
+		Mount: &csi.VolumeCapability_MountVolume{FsType: "xfs"}}}
+	scParams := &common.StorageClassParams{ProjectQuotaDirectories: "data=2Gi"}
+	req := &csi.CreateVolumeRequest{
+		VolumeCapabilities: []*csi.VolumeCapability{xfsCap},
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: 8 << 30},
+		Parameters: map[string]string{
+			common.AttributePvcName:      "pvc-1",
+			common.AttributePvcNamespace: "default",
+		},
+	}
+
+	if attributes, _, err := getProjectQuotaAttributes(ctx, req, &common.StorageClassParams{}); err != nil ||
+		attributes != nil {
+		t.Fatalf("expected no attributes without project quota directories, got %v, err: %v", attributes, err)
+	}
+	if _, _, err := getProjectQuotaAttributes(ctx, req, scParams); err == nil {
+		t.Fatal("expected the project quota directories to be refused with the FSS disabled")
+	}
+	if err := fakeOrchestrator.EnableFSS(ctx, common.BlockVolumeProjectQuota); err != nil {
+		t.Fatal(err)
+	}
+	attributes, _, err := getProjectQuotaAttributes(ctx, req, scParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attributes[common.AttributeProjectQuotaDirectories] != "data=4294967296,logs=1073741824" {
+		t.Fatalf("expected the project quota directories of the PVC annotation, got %v", attributes)
+	}
+
+	// Without the PVC, the project quota directories of the StorageClass are
+	// used.
+	req.Parameters = nil
+	attributes, _, err = getProjectQuotaAttributes(ctx, req, scParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attributes[common.AttributeProjectQuotaDirectories] != "data=2147483648" {
+		t.Fatalf("expected the project quota directories of the StorageClass, got %v", attributes)
+	}
+
+	// A directory cannot be larger than the volume.
+	req.CapacityRange.RequiredBytes = 1 << 30
+	if _, _, err := getProjectQuotaAttributes(ctx, req, scParams); err == nil {
+		t.Fatal("expected a limit larger than the volume to be refused")
+	}
+	req.CapacityRange.RequiredBytes = 8 << 30
+	req.VolumeCapabilities = []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Mount{
+		Mount: &csi.VolumeCapability_MountVolume{FsType: "btrfs"}}}}
+	if _, _, err := getProjectQuotaAttributes(ctx, req, scParams); err == nil {
+		t.Fatal("expected the project quota directories of a btrfs volume to be refused")
+	}
+	req.VolumeCapabilities = []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Block{
+		Block: &csi.VolumeCapability_BlockVolume{}}}}
+	if _, _, err := getProjectQuotaAttributes(ctx, req, scParams); err == nil {
+		t.Fatal("expected the project quota directories of a raw block volume to be refused")
+	}
+}
@@ -289,7 +289,10 @@ func (c *controller) Init(config *cnsconfig.Config, version string) error {
 			func() bool {
 				return commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeSnapshot)
 			}, isPodVMOnStretchSupervisorFSSEnabled,
-			isCSITransactionSupportEnabled)
+			isCSITransactionSupportEnabled, cnsvolumeoperationrequest.StoreConfig{
+				Type:   config.Global.CnsVolumeOperationRequestStore,
+				Shards: config.Global.CnsVolumeOperationRequestStoreShards,
+			})
 		if err != nil {
 			log.Errorf("failed to initialize VolumeOperationRequestInterface with error: %v", err)
 			return err
@@ -593,7 +596,10 @@ func (c *controller) ReloadConfiguration(reconnectToVCFromNewConfig bool) error
 				func() bool {
 					return commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeSnapshot)
 				}, isPodVMOnStretchSupervisorFSSEnabled,
-				isCSITransactionSupportEnabled)
+				isCSITransactionSupportEnabled, cnsvolumeoperationrequest.StoreConfig{
+					Type:   c.manager.CnsConfig.Global.CnsVolumeOperationRequestStore,
+					Shards: c.manager.CnsConfig.Global.CnsVolumeOperationRequestStoreShards,
+				})
 			if err != nil {
 				log.Errorf("failed to initialize VolumeOperationRequestInterface with error: %v", err)
 				return err
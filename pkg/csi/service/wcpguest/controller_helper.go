@@ -110,6 +110,13 @@ func validateGuestClusterCreateVolumeRequest(ctx context.Context, req *csi.Creat
 		common.IsFileVolumeRequest(ctx, req.GetVolumeCapabilities()) {
 		return logger.LogNewErrorCode(log, codes.InvalidArgument, "File volume provisioning is not supported.")
 	}
+	// The btrfs file system is only supported in vanilla clusters.
+	for _, volCap := range req.GetVolumeCapabilities() {
+		if strings.ToLower(volCap.GetMount().GetFsType()) == common.BtrfsFsType {
+			return logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"fstype %s not supported for volume creation", common.BtrfsFsType)
+		}
+	}
 	return common.ValidateCreateVolumeRequest(ctx, req)
 }
 
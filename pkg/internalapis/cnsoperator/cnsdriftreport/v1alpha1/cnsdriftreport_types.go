@@ -0,0 +1,135 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DriftCategory is a kind of inconsistency between CNS and Kubernetes.
+type DriftCategory string
+
+const (
+	// DriftCategoryOrphanedVolume is a CNS volume of the cluster without a PV.
+	DriftCategoryOrphanedVolume DriftCategory = "OrphanedVolume"
+	// DriftCategoryPVWithoutVolume is a PV whose volume is not registered in
+	// CNS.
+	DriftCategoryPVWithoutVolume DriftCategory = "PVWithoutVolume"
+	// DriftCategoryMetadataMismatch is a volume whose metadata in CNS differs
+	// from its PV, PVC and Pods.
+	DriftCategoryMetadataMismatch DriftCategory = "MetadataMismatch"
+	// DriftCategoryStaleVolumeOperationRequest is a CnsVolumeOperationRequest
+	// whose operation is in progress for longer than an operation can take.
+	DriftCategoryStaleVolumeOperationRequest DriftCategory = "StaleVolumeOperationRequest"
+	// DriftCategoryStaleVolumeAttachment is a VolumeAttachment of the driver
+	// whose PV or node no longer exists.
+	DriftCategoryStaleVolumeAttachment DriftCategory = "StaleVolumeAttachment"
+)
+
+// DriftCategories lists the categories of the drift report, in the order of
+// the report.
+var DriftCategories = []DriftCategory{
+	DriftCategoryOrphanedVolume,
+	DriftCategoryPVWithoutVolume,
+	DriftCategoryMetadataMismatch,
+	DriftCategoryStaleVolumeOperationRequest,
+	DriftCategoryStaleVolumeAttachment,
+}
+
+// MaxDriftItems is the maximum number of items listed per category. Count
+// holds the number of items found.
+const MaxDriftItems = 100
+
+// DriftItem is an inconsistency found by the full sync.
+type DriftItem struct {
+	// VolumeID is the CNS volume of the item, if any.
+	VolumeID string `json:"volumeID,omitempty"`
+	// Kind is the kind of the Kubernetes object of the item, if any.
+	Kind string `json:"kind,omitempty"`
+	// Name is the name of the Kubernetes object of the item, if any.
+	Name string `json:"name,omitempty"`
+	// Namespace is the namespace of the Kubernetes object of the item, if any.
+	Namespace string `json:"namespace,omitempty"`
+	// Reason describes the inconsistency.
+	Reason string `json:"reason,omitempty"`
+	// Error is the error of the remediation of the item, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// DriftCategoryStatus is the drift found by the full sync in a category.
+type DriftCategoryStatus struct {
+	// Category is the kind of inconsistency.
+	Category DriftCategory `json:"category"`
+	// Policy is the remediation policy of the category, "report" or
+	// "remediate".
+	Policy string `json:"policy"`
+	// Remediated is true if the full sync corrected the items of the category.
+	Remediated bool `json:"remediated"`
+	// Count is the number of items found.
+	Count int `json:"count"`
+	// Items lists the first MaxDriftItems items found.
+	Items []DriftItem `json:"items,omitempty"`
+}
+
+// CnsDriftReportStatus is the drift found by the last full sync of a vCenter.
+type CnsDriftReportStatus struct {
+	// VCenter is the vCenter of the full sync.
+	VCenter string `json:"vCenter"`
+	// DryRun is true if the full sync reported the drift without correcting
+	// it.
+	DryRun bool `json:"dryRun"`
+	// StartTime is the start time of the full sync.
+	StartTime metav1.Time `json:"startTime"`
+	// CompletionTime is the time at which the report was produced.
+	CompletionTime metav1.Time `json:"completionTime"`
+	// Categories is the drift found in each category.
+	Categories []DriftCategoryStatus `json:"categories,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsDriftReport is the Schema for the CnsDriftReport API. It reports the
+// inconsistencies between CNS and Kubernetes found by the last full sync of a
+// vCenter.
+type CnsDriftReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Status is the drift found by the last full sync of the vCenter.
+	Status CnsDriftReportStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsDriftReportList contains a list of CnsDriftReport
+type CnsDriftReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CnsDriftReport `json:"items"`
+}
+
+// GetCnsDriftReportName returns the name of the CnsDriftReport of vCenter vc.
+func GetCnsDriftReportName(vc string) string {
+	return "drift-" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(vc))
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+)
+
+// TestDeepCopyStatus verifies CnsDriftReportStatus.DeepCopy copies the items instead of sharing them.
+func TestDeepCopyStatus(t *testing.T) {
+	orig := &CnsDriftReportStatus{
+		VCenter: "vc",
+		Categories: []DriftCategoryStatus{
+			{Category: DriftCategoryOrphanedVolume, Count: 1, Items: []DriftItem{{VolumeID: "fcd-1"}}},
+		},
+	}
+	cp := orig.DeepCopy()
+	if cp.VCenter != orig.VCenter || len(cp.Categories) != 1 || cp.Categories[0].Items[0] != orig.Categories[0].Items[0] {
+		t.Fatalf("DeepCopy content mismatch: got %+v, want %+v", *cp, *orig)
+	}
+	cp.Categories[0].Items[0].VolumeID = "fcd-2"
+	if orig.Categories[0].Items[0].VolumeID != "fcd-1" {
+		t.Errorf("mutation of copy affected original: VolumeID = %q", orig.Categories[0].Items[0].VolumeID)
+	}
+}
+
+func TestGetCnsDriftReportName(t *testing.T) {
+	tests := map[string]string{
+		"vc.example.com": "drift-vc.example.com",
+		"10.1.2.3":       "drift-10.1.2.3",
+		"VC_1":           "drift-vc-1",
+		"fd00::1":        "drift-fd00--1",
+	}
+	for vc, expected := range tests {
+		if name := GetCnsDriftReportName(vc); name != expected {
+			t.Errorf("vc %q: expected name %q, got %q", vc, expected, name)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OrphanObjectKind is the kind of a CNS object without a Kubernetes
+// counterpart.
+type OrphanObjectKind string
+
+const (
+	// OrphanObjectKindVolume is a CNS volume of the cluster without a PV.
+	OrphanObjectKindVolume OrphanObjectKind = "Volume"
+	// OrphanObjectKindSnapshot is a CNS snapshot of a volume of the cluster
+	// without a VolumeSnapshotContent.
+	OrphanObjectKindSnapshot OrphanObjectKind = "Snapshot"
+)
+
+// HoldLabel is the label which, set to "true" on a CnsOrphanObject, prevents
+// the deletion of its CNS object.
+const HoldLabel = "cns.vmware.com/orphan-gc-hold"
+
+// CnsOrphanObjectSpec identifies the orphaned CNS object.
+type CnsOrphanObjectSpec struct {
+	// VCenter is the vCenter of the CNS object.
+	VCenter string `json:"vCenter"`
+	// Kind is the kind of the CNS object.
+	Kind OrphanObjectKind `json:"kind"`
+	// VolumeID is the CNS volume, or the volume of the CNS snapshot.
+	VolumeID string `json:"volumeID"`
+	// SnapshotID is the CNS snapshot, if Kind is Snapshot.
+	SnapshotID string `json:"snapshotID,omitempty"`
+}
+
+// CnsOrphanObjectStatus tracks the garbage collection of the orphaned CNS
+// object.
+type CnsOrphanObjectStatus struct {
+	// FirstSeen is the time at which the object was first found orphaned.
+	FirstSeen metav1.Time `json:"firstSeen"`
+	// LastSeen is the time of the last pass which found the object orphaned.
+	LastSeen metav1.Time `json:"lastSeen"`
+	// Passes is the number of consecutive passes which found the object
+	// orphaned.
+	Passes int `json:"passes"`
+	// DeleteAfter is the end of the grace period, after which the object is
+	// deleted unless held.
+	DeleteAfter metav1.Time `json:"deleteAfter"`
+	// Error is the error of the last deletion of the object, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsOrphanObject is the Schema for the CnsOrphanObject API. It marks a CNS
+// volume or snapshot of the cluster without a Kubernetes counterpart, which
+// the orphan garbage collector deletes after a grace period.
+type CnsOrphanObject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CnsOrphanObjectSpec   `json:"spec,omitempty"`
+	Status CnsOrphanObjectStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsOrphanObjectList contains a list of CnsOrphanObject
+type CnsOrphanObjectList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CnsOrphanObject `json:"items"`
+}
+
+// IsHeld returns true if the deletion of the CNS object is held.
+func (in *CnsOrphanObject) IsHeld() bool {
+	return in.Labels[HoldLabel] == "true"
+}
+
+// GetCnsOrphanObjectName returns the name of the CnsOrphanObject of the CNS
+// object of kind with the given ID.
+func GetCnsOrphanObjectName(kind OrphanObjectKind, id string) string {
+	return strings.ToLower(string(kind)) + "-" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(id))
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestGetCnsOrphanObjectName verifies the names are valid object names for any CNS ID.
+func TestGetCnsOrphanObjectName(t *testing.T) {
+	tests := []struct {
+		kind OrphanObjectKind
+		id   string
+		want string
+	}{
+		{OrphanObjectKindVolume, "3B6B4A8E-1C2D-4E5F-8A9B-0C1D2E3F4A5B", "volume-3b6b4a8e-1c2d-4e5f-8a9b-0c1d2e3f4a5b"},
+		{OrphanObjectKindVolume, "file:1234", "volume-file-1234"},
+		{OrphanObjectKindSnapshot, "snap-1", "snapshot-snap-1"},
+	}
+	for _, tc := range tests {
+		if got := GetCnsOrphanObjectName(tc.kind, tc.id); got != tc.want {
+			t.Errorf("GetCnsOrphanObjectName(%q, %q) = %q, want %q", tc.kind, tc.id, got, tc.want)
+		}
+	}
+}
+
+// TestIsHeld verifies only the hold label set to "true" holds the deletion.
+func TestIsHeld(t *testing.T) {
+	for value, want := range map[string]bool{"true": true, "false": false, "": false} {
+		obj := &CnsOrphanObject{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{HoldLabel: value}}}
+		if got := obj.IsHeld(); got != want {
+			t.Errorf("IsHeld() with %s=%q = %v, want %v", HoldLabel, value, got, want)
+		}
+	}
+	if (&CnsOrphanObject{}).IsHeld() {
+		t.Error("IsHeld() without labels = true, want false")
+	}
+}
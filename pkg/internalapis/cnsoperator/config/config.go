@@ -11,3 +11,13 @@ const EmbedCnsFileVolumeClientFileName = "cnsfilevolumeclient_crd.yaml"
 var EmbedTriggerCsiFullSync embed.FS
 
 const EmbedTriggerCsiFullSyncName = "triggercsifullsync_crd.yaml"
+
+//go:embed cnsdriftreport_crd.yaml
+var EmbedCnsDriftReportFile embed.FS
+
+const EmbedCnsDriftReportFileName = "cnsdriftreport_crd.yaml"
+
+//go:embed cnsorphanobject_crd.yaml
+var EmbedCnsOrphanObjectFile embed.FS
+
+const EmbedCnsOrphanObjectFileName = "cnsorphanobject_crd.yaml"
@@ -18,7 +18,6 @@ package cnsvolumeoperationrequest
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"strings"
 	"sync"
@@ -71,6 +70,9 @@ type VolumeOperationRequest interface {
 	// StoragePolicyUsage.used to be incremented more than once for the
 	// same volume.
 	HasPriorSuccessfulCreate(ctx context.Context, name string) bool
+	// ListRequestDetails returns the details of the last operation of every
+	// instance persisted by the VolumeOperationRequest interface.
+	ListRequestDetails(ctx context.Context) ([]*VolumeOperationRequestDetails, error)
 }
 
 // operationRequestStore implements the VolumeOperationsRequest interface.
@@ -81,21 +83,40 @@ type operationRequestStore struct {
 	k8sclient client.Client
 }
 
+// StoreConfig selects the backend of the VolumeOperationRequest interface.
+type StoreConfig struct {
+	// Type is the backend persisting the operation details, either
+	// csiconfig.CnsVolumeOperationRequestStoreCRD or
+	// csiconfig.CnsVolumeOperationRequestStoreConfigMap.
+	// Defaults to csiconfig.CnsVolumeOperationRequestStoreCRD.
+	Type string
+	// Shards is the number of ConfigMaps used by the ConfigMap backend.
+	Shards int
+}
+
+// volumeOperationRequestStore is implemented by every backend of the
+// VolumeOperationRequest interface.
+type volumeOperationRequestStore interface {
+	VolumeOperationRequest
+	// cleanupStaleInstances periodically removes the stale operation details.
+	cleanupStaleInstances(cleanupInterval int)
+}
+
 var (
 	csiNamespace                         string
-	operationRequestStoreInstance        *operationRequestStore
+	operationRequestStoreInstance        volumeOperationRequestStore
 	operationStoreInitLock               = &sync.Mutex{}
 	isPodVMOnStretchSupervisorFSSEnabled bool
 	isCSITransactionSupportEnabled       bool
 )
 
-// InitVolumeOperationRequestInterface creates the CnsVolumeOperationRequest
-// definition on the API server and returns an implementation of
-// VolumeOperationRequest interface. Clients are unaware of the implementation
-// details to read and persist volume operation details.
+// InitVolumeOperationRequestInterface initializes the backend selected by
+// storeConfig and returns an implementation of VolumeOperationRequest
+// interface. Clients are unaware of the implementation details to read and
+// persist volume operation details.
 func InitVolumeOperationRequestInterface(ctx context.Context, cleanupInterval int,
 	isBlockVolumeSnapshotEnabled func() bool, isPodVMOnStretchSupervisorEnabled bool,
-	csiTransactionSupportEnabled bool) (
+	csiTransactionSupportEnabled bool, storeConfig StoreConfig) (
 	VolumeOperationRequest, error) {
 	log := logger.GetLogger(ctx)
 	csiNamespace = getCSINamespace()
@@ -103,47 +124,128 @@ func InitVolumeOperationRequestInterface(ctx context.Context, cleanupInterval in
 	operationStoreInitLock.Lock()
 	defer operationStoreInitLock.Unlock()
 	if operationRequestStoreInstance == nil {
-		// Create CnsVolumeOperationRequest definition on API server.
-		log.Info(
-			"Creating CnsVolumeOperationRequest definition on API server and initializing VolumeOperationRequest instance",
+		var (
+			store volumeOperationRequestStore
+			err   error
 		)
-		err := k8s.CreateCustomResourceDefinitionFromManifest(ctx,
-			cnsvolumeoperationrequestconfig.EmbedCnsVolumeOperationRequestFile,
-			cnsvolumeoperationrequestconfig.EmbedCnsVolumeOperationRequestFileName)
+		switch storeConfig.Type {
+		case "", csiconfig.CnsVolumeOperationRequestStoreCRD:
+			store, err = initCRDOperationRequestStore(ctx)
+		case csiconfig.CnsVolumeOperationRequestStoreConfigMap:
+			store, err = initConfigMapOperationRequestStore(ctx, storeConfig.Shards)
+		default:
+			return nil, logger.LogNewErrorf(log, "unknown CnsVolumeOperationRequest store %q", storeConfig.Type)
+		}
 		if err != nil {
-			log.Errorf("failed to create CnsVolumeOperationRequest CRD with error: %v", err)
 			return nil, err
 		}
+		operationRequestStoreInstance = store
+		go operationRequestStoreInstance.cleanupStaleInstances(cleanupInterval)
+	}
+	// Store PodVMOnStretchedSupervisor FSS value for later use.
+	isPodVMOnStretchSupervisorFSSEnabled = isPodVMOnStretchSupervisorEnabled
+	// Store CSI Transaction Support FSS value for later use.
+	isCSITransactionSupportEnabled = csiTransactionSupportEnabled
 
-		// Get in cluster config for client to API server.
-		config, err := k8s.GetKubeConfig(ctx)
+	return operationRequestStoreInstance, nil
+}
+
+// NewVolumeOperationRequestStore returns the backend selected by storeConfig
+// for components other than the CSI controller, such as the syncer. Unlike
+// InitVolumeOperationRequestInterface, it neither creates the CRD nor
+// migrates the CnsVolumeOperationRequest instances, and does not clean up
+// the stale instances.
+func NewVolumeOperationRequestStore(ctx context.Context, storeConfig StoreConfig) (VolumeOperationRequest, error) {
+	log := logger.GetLogger(ctx)
+	csiNamespace = getCSINamespace()
+	switch storeConfig.Type {
+	case "", csiconfig.CnsVolumeOperationRequestStoreCRD:
+		k8sclient, err := newCnsVolumeOperationRequestClient(ctx)
 		if err != nil {
-			log.Errorf("failed to get kubeconfig with error: %v", err)
 			return nil, err
 		}
-
-		// Create client to API server.
-		k8sclient, err := k8s.NewClientForGroup(ctx, config, cnsvolumeoprequestv1alpha1.SchemeGroupVersion.Group)
+		return &operationRequestStore{k8sclient: k8sclient}, nil
+	case csiconfig.CnsVolumeOperationRequestStoreConfigMap:
+		shards := storeConfig.Shards
+		if shards <= 0 {
+			shards = csiconfig.DefaultCnsVolumeOperationRequestStoreShards
+		}
+		k8sclient, err := k8s.NewClient(ctx)
 		if err != nil {
-			log.Errorf("failed to create k8sClient with error: %v", err)
 			return nil, err
 		}
+		return newConfigMapOperationRequestStore(k8sclient, shards), nil
+	default:
+		return nil, logger.LogNewErrorf(log, "unknown CnsVolumeOperationRequest store %q", storeConfig.Type)
+	}
+}
 
-		// Initialize the operationRequestStoreOnETCD implementation of
-		// VolumeOperationRequest interface.
-		// NOTE: Currently there is only a single implementation of this
-		// interface. Future implementations will need modify this step.
-		operationRequestStoreInstance = &operationRequestStore{
-			k8sclient: k8sclient,
-		}
-		go operationRequestStoreInstance.cleanupStaleInstances(cleanupInterval)
+// initCRDOperationRequestStore creates the CnsVolumeOperationRequest
+// definition on the API server and returns the CRD backend.
+func initCRDOperationRequestStore(ctx context.Context) (*operationRequestStore, error) {
+	log := logger.GetLogger(ctx)
+	// Create CnsVolumeOperationRequest definition on API server.
+	log.Info(
+		"Creating CnsVolumeOperationRequest definition on API server and initializing VolumeOperationRequest instance",
+	)
+	err := k8s.CreateCustomResourceDefinitionFromManifest(ctx,
+		cnsvolumeoperationrequestconfig.EmbedCnsVolumeOperationRequestFile,
+		cnsvolumeoperationrequestconfig.EmbedCnsVolumeOperationRequestFileName)
+	if err != nil {
+		log.Errorf("failed to create CnsVolumeOperationRequest CRD with error: %v", err)
+		return nil, err
 	}
-	// Store PodVMOnStretchedSupervisor FSS value for later use.
-	isPodVMOnStretchSupervisorFSSEnabled = isPodVMOnStretchSupervisorEnabled
-	// Store CSI Transaction Support FSS value for later use.
-	isCSITransactionSupportEnabled = csiTransactionSupportEnabled
+	k8sclient, err := newCnsVolumeOperationRequestClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &operationRequestStore{
+		k8sclient: k8sclient,
+	}, nil
+}
 
-	return operationRequestStoreInstance, nil
+// initConfigMapOperationRequestStore returns the ConfigMap backend, after
+// migrating the existing CnsVolumeOperationRequest instances into it.
+func initConfigMapOperationRequestStore(ctx context.Context, shards int) (*configMapOperationRequestStore, error) {
+	log := logger.GetLogger(ctx)
+	if shards <= 0 {
+		shards = csiconfig.DefaultCnsVolumeOperationRequestStoreShards
+	}
+	log.Infof("Initializing VolumeOperationRequest instance backed by %d ConfigMaps in namespace %q",
+		shards, csiNamespace)
+	k8sclient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("failed to create k8sClient with error: %v", err)
+		return nil, err
+	}
+	store := newConfigMapOperationRequestStore(k8sclient, shards)
+	crClient, err := newCnsVolumeOperationRequestClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.migrateCnsVolumeOperationRequests(ctx, crClient); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// newCnsVolumeOperationRequestClient returns a client to the API server for
+// CnsVolumeOperationRequest instances.
+func newCnsVolumeOperationRequestClient(ctx context.Context) (client.Client, error) {
+	log := logger.GetLogger(ctx)
+	// Get in cluster config for client to API server.
+	config, err := k8s.GetKubeConfig(ctx)
+	if err != nil {
+		log.Errorf("failed to get kubeconfig with error: %v", err)
+		return nil, err
+	}
+	// Create client to API server.
+	k8sclient, err := k8s.NewClientForGroup(ctx, config, cnsvolumeoprequestv1alpha1.SchemeGroupVersion.Group)
+	if err != nil {
+		log.Errorf("failed to create k8sClient with error: %v", err)
+		return nil, err
+	}
+	return k8sclient, nil
 }
 
 // GetRequestDetails returns the details of the operation on the volume
@@ -168,28 +270,7 @@ func (or *operationRequestStore) GetRequestDetails(
 	}
 	log.Debugf("Found CnsVolumeOperationRequest instance %v", spew.Sdump(instance))
 
-	if len(instance.Status.LatestOperationDetails) == 0 {
-		return nil, fmt.Errorf("length of LatestOperationDetails expected to be greater than 1 if the instance exists")
-	}
-
-	// Callers only need to know about the last operation that was invoked on a volume.
-	operationDetailsToReturn := instance.Status.LatestOperationDetails[len(instance.Status.LatestOperationDetails)-1]
-
-	var quotaDetails *QuotaDetails
-	if isPodVMOnStretchSupervisorFSSEnabled && instance.Status.StorageQuotaDetails != nil {
-		quotaDetails = &QuotaDetails{
-			Reserved:         instance.Status.StorageQuotaDetails.Reserved,
-			StorageClassName: instance.Status.StorageQuotaDetails.StorageClassName,
-			StoragePolicyId:  instance.Status.StorageQuotaDetails.StoragePolicyId,
-			Namespace:        instance.Status.StorageQuotaDetails.Namespace,
-		}
-	}
-
-	return CreateVolumeOperationRequestDetails(instance.Spec.Name, instance.Status.VolumeID, instance.Status.SnapshotID,
-			instance.Status.Capacity, quotaDetails, operationDetailsToReturn.TaskInvocationTimestamp,
-			operationDetailsToReturn.TaskID, operationDetailsToReturn.VCenterServer, operationDetailsToReturn.OpID,
-			operationDetailsToReturn.TaskStatus, operationDetailsToReturn.Error, instance.Status.ChangedBlockTrackingId),
-		nil
+	return requestDetailsFromStatus(instance.Spec.Name, &instance.Status)
 }
 
 // StoreRequestDetails persists the details of the operation taking
@@ -223,26 +304,7 @@ func (or *operationRequestStore) StoreRequestDetails(
 				Spec: cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestSpec{
 					Name: instanceKey.Name,
 				},
-				Status: cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus{
-					VolumeID:               operationToStore.VolumeID,
-					SnapshotID:             operationToStore.SnapshotID,
-					Capacity:               operationToStore.Capacity,
-					ChangedBlockTrackingId: operationToStore.ChangedBlockTrackingId,
-					FirstOperationDetails:  *operationDetailsToStore,
-					LatestOperationDetails: []cnsvolumeoprequestv1alpha1.OperationDetails{
-						*operationDetailsToStore,
-					},
-				},
-			}
-			if isPodVMOnStretchSupervisorFSSEnabled && operationToStore.QuotaDetails != nil {
-				newInstance.Status.StorageQuotaDetails = &cnsvolumeoprequestv1alpha1.QuotaDetails{
-					Reserved:                            operationToStore.QuotaDetails.Reserved,
-					StoragePolicyId:                     operationToStore.QuotaDetails.StoragePolicyId,
-					StorageClassName:                    operationToStore.QuotaDetails.StorageClassName,
-					Namespace:                           operationToStore.QuotaDetails.Namespace,
-					AggregatedSnapshotSize:              operationToStore.QuotaDetails.AggregatedSnapshotSize,
-					SnapshotLatestOperationCompleteTime: operationToStore.QuotaDetails.SnapshotLatestOperationCompleteTime,
-				}
+				Status: newOperationRequestStatus(operationToStore),
 			}
 			err = or.k8sclient.Create(ctx, newInstance)
 			if err != nil {
@@ -273,81 +335,7 @@ func (or *operationRequestStore) StoreRequestDetails(
 
 	// Create a deep copy since we modify the object.
 	updatedInstance := instance.DeepCopy()
-
-	// If CSI Transaction Support is enabled and we're storing a new InProgress operation with empty TaskID,
-	// mark any existing InProgress entries as TrackingAborted since this indicates a retry scenario.
-	if isCSITransactionSupportEnabled && operationDetailsToStore.TaskStatus == TaskInvocationStatusInProgress &&
-		operationDetailsToStore.TaskID == "" {
-		// This is a new operation attempt (Phase 1: Intent Registration)
-		// Mark any existing InProgress entries as TrackingAborted since this is clearly a retry
-		for index := range updatedInstance.Status.LatestOperationDetails {
-			existingOp := &updatedInstance.Status.LatestOperationDetails[index]
-			if existingOp.TaskStatus == TaskInvocationStatusInProgress {
-				// This is a retry - mark the previous attempt as aborted
-				existingOp.TaskStatus = TaskInvocationStatusTrackingAborted
-				existingOp.Error = "Operation tracking aborted due to retry attempt"
-				log.Infof("Marked previous InProgress operation as TrackingAborted due to retry detection. Instance: %s",
-					operationToStore.Name)
-			}
-		}
-
-		// Also check FirstOperationDetails
-		if updatedInstance.Status.FirstOperationDetails.TaskStatus == TaskInvocationStatusInProgress {
-			updatedInstance.Status.FirstOperationDetails.TaskStatus = TaskInvocationStatusTrackingAborted
-			updatedInstance.Status.FirstOperationDetails.Error = "Operation tracking aborted due to retry attempt"
-			log.Infof("Marked FirstOperationDetails as TrackingAborted due to retry detection. Instance: %s",
-				operationToStore.Name)
-		}
-	}
-
-	// Modify VolumeID, SnapshotID, Capacity, and ChangedBlockTrackingId
-	updatedInstance.Status.VolumeID = operationToStore.VolumeID
-	updatedInstance.Status.SnapshotID = operationToStore.SnapshotID
-	updatedInstance.Status.Capacity = operationToStore.Capacity
-	updatedInstance.Status.ChangedBlockTrackingId = operationToStore.ChangedBlockTrackingId
-	if isPodVMOnStretchSupervisorFSSEnabled && operationToStore.QuotaDetails != nil {
-		updatedInstance.Status.StorageQuotaDetails = &cnsvolumeoprequestv1alpha1.QuotaDetails{
-			Reserved:                            operationToStore.QuotaDetails.Reserved,
-			StoragePolicyId:                     operationToStore.QuotaDetails.StoragePolicyId,
-			StorageClassName:                    operationToStore.QuotaDetails.StorageClassName,
-			Namespace:                           operationToStore.QuotaDetails.Namespace,
-			AggregatedSnapshotSize:              operationToStore.QuotaDetails.AggregatedSnapshotSize,
-			SnapshotLatestOperationCompleteTime: operationToStore.QuotaDetails.SnapshotLatestOperationCompleteTime,
-		}
-	}
-
-	// Modify FirstOperationDetails only if TaskID's match or the initial TaskID is empty.
-	firstOp := instance.Status.FirstOperationDetails
-	if firstOp.TaskStatus == TaskInvocationStatusInProgress &&
-		(firstOp.TaskID == operationToStore.OperationDetails.TaskID || firstOp.TaskID == "") {
-		updatedInstance.Status.FirstOperationDetails = *operationDetailsToStore
-	}
-
-	operationExistsInList := false
-	// If the task details already exist in the status, update it with the
-	// latest information.
-	for index := len(instance.Status.LatestOperationDetails) - 1; index >= 0; index-- {
-		operationDetail := instance.Status.LatestOperationDetails[index]
-		if operationDetail.TaskStatus == TaskInvocationStatusInProgress &&
-			(operationDetailsToStore.TaskID == operationDetail.TaskID || operationDetail.TaskID == "") {
-			updatedInstance.Status.LatestOperationDetails[index] = *operationDetailsToStore
-			operationExistsInList = true
-			break
-		}
-	}
-
-	if !operationExistsInList {
-		// Append the latest task details to the local instance and
-		// ensure length of LatestOperationDetails is not greater
-		// than 10.
-		updatedInstance.Status.LatestOperationDetails = append(
-			updatedInstance.Status.LatestOperationDetails,
-			*operationDetailsToStore,
-		)
-		if len(updatedInstance.Status.LatestOperationDetails) > maxEntriesInLatestOperationDetails {
-			updatedInstance.Status.LatestOperationDetails = updatedInstance.Status.LatestOperationDetails[1:]
-		}
-	}
+	updatedInstance.Status = updateOperationRequestStatus(ctx, &instance.Status, operationToStore)
 
 	// Store the local instance on the API server.
 	err := or.k8sclient.Update(ctx, updatedInstance)
@@ -407,14 +395,33 @@ func (or *operationRequestStore) HasPriorSuccessfulCreate(ctx context.Context, n
 			instanceKey.Namespace, instanceKey.Name, err)
 		return false
 	}
-	for _, op := range instance.Status.LatestOperationDetails {
-		if op.TaskStatus == TaskInvocationStatusSuccess {
-			log.Infof("HasPriorSuccessfulCreate: found prior successful task %s in CR %s",
-				op.TaskID, name)
-			return true
+	return hasPriorSuccessfulOperation(ctx, name, &instance.Status)
+}
+
+// ListRequestDetails returns the details of the last operation of every
+// CnsVolumeOperationRequest instance in the namespace of the driver.
+func (or *operationRequestStore) ListRequestDetails(ctx context.Context) ([]*VolumeOperationRequestDetails, error) {
+	var requests []*VolumeOperationRequestDetails
+	continueToken := ""
+	for {
+		list := &cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestList{}
+		err := or.k8sclient.List(ctx, list, client.InNamespace(csiNamespace), client.Limit(5000),
+			client.Continue(continueToken))
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			details, err := requestDetailsFromStatus(list.Items[i].Name, &list.Items[i].Status)
+			if err != nil {
+				continue
+			}
+			requests = append(requests, details)
+		}
+		continueToken = list.GetContinue()
+		if continueToken == "" {
+			return requests, nil
 		}
 	}
-	return false
 }
 
 // cleanupStaleInstances cleans up CnsVolumeOperationRequest instances
@@ -440,17 +447,7 @@ func (or *operationRequestStore) cleanupStaleInstances(cleanupInterval int) {
 				break
 			}
 			for _, instance := range cnsVolumeOperationRequestList.Items {
-				latestOperationDetailsLength := len(instance.Status.LatestOperationDetails)
-				// Skip if task is still in progress
-				if latestOperationDetailsLength != 0 &&
-					instance.Status.LatestOperationDetails[latestOperationDetailsLength-1].TaskStatus ==
-						TaskInvocationStatusInProgress {
-					continue
-				}
-				// Delete instance if TaskInvocationTimestamp is older than 15 minutes
-				if latestOperationDetailsLength != 0 &&
-					instance.Status.LatestOperationDetails[latestOperationDetailsLength-1].
-						TaskInvocationTimestamp.Time.After(cutoffTime) {
+				if !isStaleOperationRequest(&instance.Status, cutoffTime) {
 					log.Debugf("CnsVolumeOperationRequest instance %q is skipped for deletion", instance.Name)
 					continue
 				}
@@ -0,0 +1,398 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumeoperationrequest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	cnsvolumeoprequestv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsvolumeoperationrequest/v1alpha1"
+)
+
+const (
+	// configMapStoreNamePrefix is the prefix of the names of the ConfigMaps
+	// holding the operation details in the ConfigMap store.
+	configMapStoreNamePrefix = "cnsvolumeoperationrequests-"
+	// configMapStoreLabel labels the ConfigMaps of the ConfigMap store.
+	configMapStoreLabel = "cns.vmware.com/cnsvolumeoperationrequest-store"
+	// migrationPageSize is the number of CnsVolumeOperationRequest instances
+	// migrated to the ConfigMap store at a time.
+	migrationPageSize = 500
+	// configMapStoreMaxDataSize is the maximum total size of the keys and
+	// values of a ConfigMap accepted by the API server.
+	configMapStoreMaxDataSize = 1024 * 1024
+)
+
+// configMapOperationRequestStore implements the VolumeOperationRequest
+// interface by persisting the status of every CnsVolumeOperationRequest
+// instance as JSON in one of a fixed set of ConfigMaps, sharded by instance
+// name. Every read and update gets the ConfigMap from the API server, so that
+// a new leader of the CSI controller never acts on the details known to the
+// previous one. Updates use optimistic concurrency, so concurrent writers
+// only cause a retry.
+type configMapOperationRequestStore struct {
+	k8sclient clientset.Interface
+	shards    []*configMapShard
+}
+
+// configMapShard is a ConfigMap of the ConfigMap store.
+type configMapShard struct {
+	name string
+	// lock serializes the updates of the shard within the process.
+	lock sync.Mutex
+}
+
+// newConfigMapOperationRequestStore returns a ConfigMap store with the given
+// number of shards.
+func newConfigMapOperationRequestStore(k8sclient clientset.Interface, shards int) *configMapOperationRequestStore {
+	store := &configMapOperationRequestStore{
+		k8sclient: k8sclient,
+	}
+	for i := 0; i < shards; i++ {
+		store.shards = append(store.shards, &configMapShard{
+			name: fmt.Sprintf("%s%d", configMapStoreNamePrefix, i),
+		})
+	}
+	return store
+}
+
+// shardFor returns the shard holding the instance with the given name.
+func (or *configMapOperationRequestStore) shardFor(name string) *configMapShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return or.shards[h.Sum32()%uint32(len(or.shards))]
+}
+
+// load returns the ConfigMap of the shard read from the API server, or nil
+// if it does not exist yet.
+func (or *configMapOperationRequestStore) load(ctx context.Context, shard *configMapShard) (*v1.ConfigMap, error) {
+	configMap, err := or.k8sclient.CoreV1().ConfigMaps(csiNamespace).Get(ctx, shard.name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return configMap, nil
+}
+
+// configMapDataSize returns the size of the data of the ConfigMap, as
+// counted by the API server against configMapStoreMaxDataSize.
+func configMapDataSize(configMap *v1.ConfigMap) int {
+	size := 0
+	for key, value := range configMap.Data {
+		size += len(key) + len(value)
+	}
+	for key, value := range configMap.BinaryData {
+		size += len(key) + len(value)
+	}
+	return size
+}
+
+// update applies mutate to the data of the shard and persists the result if
+// mutate reports a change. Conflicting updates are retried on a fresh copy of
+// the ConfigMap. An update which would make the ConfigMap larger than the API
+// server accepts fails without being sent.
+func (or *configMapOperationRequestStore) update(ctx context.Context, shard *configMapShard,
+	mutate func(data map[string]string) (bool, error)) error {
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		configMap, err := or.load(ctx, shard)
+		if err != nil {
+			return err
+		}
+		exists := configMap != nil
+		if !exists {
+			configMap = &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      shard.name,
+					Namespace: csiNamespace,
+					Labels:    map[string]string{configMapStoreLabel: "true"},
+				},
+			}
+		}
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		changed, err := mutate(configMap.Data)
+		if err != nil || !changed {
+			return err
+		}
+		if size := configMapDataSize(configMap); size > configMapStoreMaxDataSize {
+			return fmt.Errorf("ConfigMap %s/%s would hold %d bytes, more than the %d bytes accepted by the "+
+				"API server. Increase cnsvolumeoperationrequest-store-shards", csiNamespace, shard.name, size,
+				configMapStoreMaxDataSize)
+		}
+		if !exists {
+			_, err = or.k8sclient.CoreV1().ConfigMaps(csiNamespace).Create(ctx, configMap, metav1.CreateOptions{})
+		} else {
+			_, err = or.k8sclient.CoreV1().ConfigMaps(csiNamespace).Update(ctx, configMap, metav1.UpdateOptions{})
+		}
+		return err
+	})
+}
+
+// getStatus returns the status stored for the instance with the given name.
+func (or *configMapOperationRequestStore) getStatus(ctx context.Context,
+	name string) (*cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus, error) {
+	shard := or.shardFor(name)
+	configMap, err := or.load(ctx, shard)
+	if err != nil {
+		return nil, err
+	}
+	if configMap == nil || configMap.Data[name] == "" {
+		return nil, apierrors.NewNotFound(cnsvolumeoprequestv1alpha1.Resource(CRDPlural), name)
+	}
+	status := &cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus{}
+	if err := json.Unmarshal([]byte(configMap.Data[name]), status); err != nil {
+		return nil, fmt.Errorf("failed to decode the details of operation %q from ConfigMap %s/%s: %v",
+			name, csiNamespace, shard.name, err)
+	}
+	return status, nil
+}
+
+// GetRequestDetails returns the details of the last operation stored for
+// the instance with the given name. A NotFound error is returned if there
+// is none, as for CnsVolumeOperationRequest CRs.
+func (or *configMapOperationRequestStore) GetRequestDetails(ctx context.Context,
+	name string) (*VolumeOperationRequestDetails, error) {
+	log := logger.GetLogger(ctx)
+	log.Debugf("Getting CnsVolumeOperationRequest %q from the ConfigMap store", name)
+	status, err := or.getStatus(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return requestDetailsFromStatus(name, status)
+}
+
+// StoreRequestDetails records the given operation in the status of its
+// instance, in the same way as for CnsVolumeOperationRequest CRs.
+func (or *configMapOperationRequestStore) StoreRequestDetails(ctx context.Context,
+	operationToStore *VolumeOperationRequestDetails) error {
+	log := logger.GetLogger(ctx)
+	if operationToStore == nil {
+		return logger.LogNewError(log, "cannot store empty operation")
+	}
+	name := operationToStore.Name
+	shard := or.shardFor(name)
+	err := or.update(ctx, shard, func(data map[string]string) (bool, error) {
+		var status cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus
+		if data[name] == "" {
+			status = newOperationRequestStatus(operationToStore)
+		} else {
+			existing := &cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus{}
+			if err := json.Unmarshal([]byte(data[name]), existing); err != nil {
+				return false, err
+			}
+			status = updateOperationRequestStatus(ctx, existing, operationToStore)
+		}
+		encoded, err := json.Marshal(status)
+		if err != nil {
+			return false, err
+		}
+		data[name] = string(encoded)
+		return true, nil
+	})
+	if err != nil {
+		log.Errorf("failed to store CnsVolumeOperationRequest %q in ConfigMap %s/%s with error: %v",
+			name, csiNamespace, shard.name, err)
+		return err
+	}
+	log.Debugf("Stored CnsVolumeOperationRequest %q in ConfigMap %s/%s for task with ID: %s",
+		name, csiNamespace, shard.name, operationToStore.OperationDetails.TaskID)
+	return nil
+}
+
+// DeleteRequestDetails deletes the details stored for the instance with the
+// given name, if any.
+func (or *configMapOperationRequestStore) DeleteRequestDetails(ctx context.Context, name string) error {
+	log := logger.GetLogger(ctx)
+	log.Debugf("Deleting CnsVolumeOperationRequest %q from the ConfigMap store", name)
+	shard := or.shardFor(name)
+	err := or.update(ctx, shard, func(data map[string]string) (bool, error) {
+		if _, ok := data[name]; !ok {
+			return false, nil
+		}
+		delete(data, name)
+		return true, nil
+	})
+	if err != nil {
+		log.Errorf("failed to delete CnsVolumeOperationRequest %q from ConfigMap %s/%s with error: %v",
+			name, csiNamespace, shard.name, err)
+	}
+	return err
+}
+
+// ListRequestDetails returns the details of the last operation stored for
+// every instance of every shard.
+func (or *configMapOperationRequestStore) ListRequestDetails(
+	ctx context.Context) ([]*VolumeOperationRequestDetails, error) {
+	log := logger.GetLogger(ctx)
+	var requests []*VolumeOperationRequestDetails
+	for _, shard := range or.shards {
+		configMap, err := or.load(ctx, shard)
+		if err != nil {
+			return nil, err
+		}
+		if configMap == nil {
+			continue
+		}
+		for name, value := range configMap.Data {
+			status := &cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus{}
+			if err := json.Unmarshal([]byte(value), status); err != nil {
+				log.Warnf("skipping undecodable CnsVolumeOperationRequest %q in ConfigMap %s/%s. Error: %v",
+					name, csiNamespace, shard.name, err)
+				continue
+			}
+			details, err := requestDetailsFromStatus(name, status)
+			if err != nil {
+				continue
+			}
+			requests = append(requests, details)
+		}
+	}
+	return requests, nil
+}
+
+// HasPriorSuccessfulCreate returns true if any operation stored for the
+// instance with the given name has TaskStatus == Success.
+func (or *configMapOperationRequestStore) HasPriorSuccessfulCreate(ctx context.Context, name string) bool {
+	log := logger.GetLogger(ctx)
+	status, err := or.getStatus(ctx, name)
+	if err != nil {
+		log.Debugf("HasPriorSuccessfulCreate: could not fetch CnsVolumeOperationRequest %q: %v", name, err)
+		return false
+	}
+	return hasPriorSuccessfulOperation(ctx, name, status)
+}
+
+// cleanupStaleInstances removes the instances with latest
+// TaskInvocationTimestamp older than 15 minutes from every shard.
+func (or *configMapOperationRequestStore) cleanupStaleInstances(cleanupInterval int) {
+	ticker := time.NewTicker(time.Duration(cleanupInterval) * time.Minute)
+	ctx, log := logger.GetNewContextWithLogger()
+	log.Infof("CnsVolumeOperationRequest clean up interval is set to %d minutes", cleanupInterval)
+	for ; true; <-ticker.C {
+		log.Infof("Cleaning up stale CnsVolumeOperationRequest instances from the ConfigMap store.")
+		or.removeStaleInstances(ctx, time.Now().Add(-15*time.Minute))
+		log.Infof("Clean up of stale CnsVolumeOperationRequest complete.")
+	}
+}
+
+// removeStaleInstances removes the instances which are stale at cutoffTime.
+func (or *configMapOperationRequestStore) removeStaleInstances(ctx context.Context, cutoffTime time.Time) {
+	log := logger.GetLogger(ctx)
+	for _, shard := range or.shards {
+		err := or.update(ctx, shard, func(data map[string]string) (bool, error) {
+			changed := false
+			for name, value := range data {
+				status := &cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus{}
+				if err := json.Unmarshal([]byte(value), status); err != nil {
+					log.Warnf("removing undecodable CnsVolumeOperationRequest %q from ConfigMap %s/%s. Error: %v",
+						name, csiNamespace, shard.name, err)
+				} else if !isStaleOperationRequest(status, cutoffTime) {
+					continue
+				}
+				delete(data, name)
+				changed = true
+			}
+			return changed, nil
+		})
+		if err != nil {
+			log.Errorf("failed to clean up ConfigMap %s/%s with error %v", csiNamespace, shard.name, err)
+		}
+	}
+}
+
+// migrateCnsVolumeOperationRequests moves the existing CnsVolumeOperationRequest
+// CRs into the ConfigMap store. A CR is only deleted once its status is
+// stored, and the status of an instance already in the store is kept, so
+// that an interrupted migration can simply be run again.
+func (or *configMapOperationRequestStore) migrateCnsVolumeOperationRequests(ctx context.Context,
+	crClient client.Client) error {
+	log := logger.GetLogger(ctx)
+	migrated := 0
+	continueToken := ""
+	for {
+		list := &cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestList{}
+		err := crClient.List(ctx, list, client.InNamespace(csiNamespace), client.Limit(migrationPageSize),
+			client.Continue(continueToken))
+		if err != nil {
+			if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+				log.Info("CnsVolumeOperationRequest CRD not found. Nothing to migrate to the ConfigMap store.")
+				return nil
+			}
+			return logger.LogNewErrorf(log, "failed to list CnsVolumeOperationRequests with error: %v", err)
+		}
+		// Store the instances of the page with a single update per shard.
+		instancesByShard := make(map[*configMapShard][]cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequest)
+		for _, instance := range list.Items {
+			shard := or.shardFor(instance.Name)
+			instancesByShard[shard] = append(instancesByShard[shard], instance)
+		}
+		for shard, instances := range instancesByShard {
+			err := or.update(ctx, shard, func(data map[string]string) (bool, error) {
+				changed := false
+				for _, instance := range instances {
+					if data[instance.Name] != "" {
+						continue
+					}
+					encoded, err := json.Marshal(instance.Status)
+					if err != nil {
+						return false, err
+					}
+					data[instance.Name] = string(encoded)
+					changed = true
+				}
+				return changed, nil
+			})
+			if err != nil {
+				return logger.LogNewErrorf(log, "failed to migrate CnsVolumeOperationRequests to ConfigMap %s/%s "+
+					"with error: %v", csiNamespace, shard.name, err)
+			}
+		}
+		for i := range list.Items {
+			if err := crClient.Delete(ctx, &list.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+				return logger.LogNewErrorf(log, "failed to delete migrated CnsVolumeOperationRequest %s/%s "+
+					"with error: %v", list.Items[i].Namespace, list.Items[i].Name, err)
+			}
+			migrated++
+		}
+		continueToken = list.GetContinue()
+		if continueToken == "" {
+			break
+		}
+	}
+	log.Infof("Migrated %d CnsVolumeOperationRequests to the ConfigMap store", migrated)
+	return nil
+}
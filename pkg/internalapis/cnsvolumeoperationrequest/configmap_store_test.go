@@ -0,0 +1,254 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumeoperationrequest
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	cnsvolumeoprequestv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsvolumeoperationrequest/v1alpha1"
+)
+
+// setupConfigMapStore creates a ConfigMap store with fake clients.
+func setupConfigMapStore(t *testing.T, shards int) (*configMapOperationRequestStore, *fake.Clientset,
+	context.Context) {
+	ctx := context.Background()
+	k8sclient := fake.NewSimpleClientset()
+	csiNamespace = "vmware-system-csi"
+	isCSITransactionSupportEnabled = false
+	return newConfigMapOperationRequestStore(k8sclient, shards), k8sclient, ctx
+}
+
+// TestConfigMapStoreMatchesCRDStore stores the same sequence of operations
+// in both backends and checks that they return the same details.
+func TestConfigMapStoreMatchesCRDStore(t *testing.T) {
+	crdStore, ctx := setupTestEnvironment(t, false)
+	cmStore, _, _ := setupConfigMapStore(t, 4)
+
+	operations := []*VolumeOperationRequestDetails{
+		createTestVolumeOperationDetails("pvc-1", "", "", "task-1", TaskInvocationStatusInProgress, "",
+			createTestQuotaDetails(1024)),
+		createTestVolumeOperationDetails("pvc-1", "", "", "task-1", TaskInvocationStatusError, "failed", nil),
+		createTestVolumeOperationDetails("pvc-1", "vol-1", "", "task-2", TaskInvocationStatusSuccess, "", nil),
+		createTestVolumeOperationDetails("snap-1-vol-1", "vol-1", "snap-1", "task-3",
+			TaskInvocationStatusSuccess, "", nil),
+	}
+	for _, op := range operations {
+		if err := crdStore.StoreRequestDetails(ctx, op); err != nil {
+			t.Fatalf("failed to store %q in the CRD store: %v", op.Name, err)
+		}
+		if err := cmStore.StoreRequestDetails(ctx, op); err != nil {
+			t.Fatalf("failed to store %q in the ConfigMap store: %v", op.Name, err)
+		}
+	}
+	for _, name := range []string{"pvc-1", "snap-1-vol-1"} {
+		want, err := crdStore.GetRequestDetails(ctx, name)
+		if err != nil {
+			t.Fatalf("failed to get %q from the CRD store: %v", name, err)
+		}
+		got, err := cmStore.GetRequestDetails(ctx, name)
+		if err != nil {
+			t.Fatalf("failed to get %q from the ConfigMap store: %v", name, err)
+		}
+		// Both backends round-trip the status through JSON, so the timestamps match.
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("details of %q differ: ConfigMap store %+v, CRD store %+v", name, got, want)
+		}
+		if got, want := cmStore.HasPriorSuccessfulCreate(ctx, name),
+			crdStore.HasPriorSuccessfulCreate(ctx, name); got != want {
+			t.Errorf("HasPriorSuccessfulCreate(%q) = %v, CRD store returns %v", name, got, want)
+		}
+	}
+
+	_, err := cmStore.GetRequestDetails(ctx, "missing")
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected NotFound for a missing instance, got %v", err)
+	}
+	if cmStore.HasPriorSuccessfulCreate(ctx, "missing") {
+		t.Errorf("expected false for a missing instance")
+	}
+
+	if err := cmStore.DeleteRequestDetails(ctx, "pvc-1"); err != nil {
+		t.Fatalf("failed to delete pvc-1: %v", err)
+	}
+	if _, err := cmStore.GetRequestDetails(ctx, "pvc-1"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected NotFound after delete, got %v", err)
+	}
+	if err := cmStore.DeleteRequestDetails(ctx, "pvc-1"); err != nil {
+		t.Errorf("expected deleting a missing instance to succeed, got %v", err)
+	}
+}
+
+// TestConfigMapStoreRetriesConflicts checks that an update conflicting with
+// another writer is retried on a fresh copy of the ConfigMap.
+func TestConfigMapStoreRetriesConflicts(t *testing.T) {
+	store, k8sclient, ctx := setupConfigMapStore(t, 1)
+	if err := store.StoreRequestDetails(ctx, createTestVolumeOperationDetails("pvc-1", "", "", "task-1",
+		TaskInvocationStatusInProgress, "", nil)); err != nil {
+		t.Fatalf("failed to store pvc-1: %v", err)
+	}
+	conflicts := 0
+	k8sclient.PrependReactor("update", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			return false, nil, nil
+		}
+		conflicts++
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"},
+			store.shards[0].name, nil)
+	})
+	if err := store.StoreRequestDetails(ctx, createTestVolumeOperationDetails("pvc-2", "", "", "task-2",
+		TaskInvocationStatusInProgress, "", nil)); err != nil {
+		t.Fatalf("failed to store pvc-2: %v", err)
+	}
+	if conflicts != 1 {
+		t.Errorf("expected one conflict, got %d", conflicts)
+	}
+	for _, name := range []string{"pvc-1", "pvc-2"} {
+		if _, err := store.GetRequestDetails(ctx, name); err != nil {
+			t.Errorf("failed to get %q: %v", name, err)
+		}
+	}
+}
+
+// TestConfigMapStoreRemoveStaleInstances checks that only the stale instances
+// are removed.
+func TestConfigMapStoreRemoveStaleInstances(t *testing.T) {
+	store, _, ctx := setupConfigMapStore(t, 2)
+	stale := createTestVolumeOperationDetails("stale", "vol-1", "", "task-1", TaskInvocationStatusSuccess, "", nil)
+	stale.OperationDetails.TaskInvocationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	fresh := createTestVolumeOperationDetails("fresh", "vol-2", "", "task-2", TaskInvocationStatusSuccess, "", nil)
+	for _, op := range []*VolumeOperationRequestDetails{stale, fresh} {
+		if err := store.StoreRequestDetails(ctx, op); err != nil {
+			t.Fatalf("failed to store %q: %v", op.Name, err)
+		}
+	}
+	store.removeStaleInstances(ctx, time.Now().Add(-15*time.Minute))
+	if _, err := store.GetRequestDetails(ctx, "stale"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected stale instance to be removed, got %v", err)
+	}
+	if _, err := store.GetRequestDetails(ctx, "fresh"); err != nil {
+		t.Errorf("expected fresh instance to be kept, got %v", err)
+	}
+}
+
+// TestMigrateCnsVolumeOperationRequests checks that the existing CRs are moved
+// to the ConfigMap store, without overwriting instances already in it.
+func TestMigrateCnsVolumeOperationRequests(t *testing.T) {
+	crdStore, ctx := setupTestEnvironment(t, false)
+	cmStore, _, _ := setupConfigMapStore(t, 4)
+
+	seedCR(t, crdStore, "pvc-1", []cnsvolumeoprequestv1alpha1.OperationDetails{
+		mkOp("task-1", TaskInvocationStatusSuccess),
+	})
+	seedCR(t, crdStore, "pvc-2", []cnsvolumeoprequestv1alpha1.OperationDetails{
+		mkOp("task-2", TaskInvocationStatusError),
+	})
+	if err := cmStore.StoreRequestDetails(ctx, createTestVolumeOperationDetails("pvc-2", "", "", "task-3",
+		TaskInvocationStatusInProgress, "", nil)); err != nil {
+		t.Fatalf("failed to store pvc-2: %v", err)
+	}
+
+	if err := cmStore.migrateCnsVolumeOperationRequests(ctx, crdStore.k8sclient); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	details, err := cmStore.GetRequestDetails(ctx, "pvc-1")
+	if err != nil {
+		t.Fatalf("failed to get migrated pvc-1: %v", err)
+	}
+	if details.OperationDetails.TaskID != "task-1" || !cmStore.HasPriorSuccessfulCreate(ctx, "pvc-1") {
+		t.Errorf("unexpected details for migrated pvc-1: %+v", details.OperationDetails)
+	}
+	details, err = cmStore.GetRequestDetails(ctx, "pvc-2")
+	if err != nil {
+		t.Fatalf("failed to get pvc-2: %v", err)
+	}
+	if details.OperationDetails.TaskID != "task-3" {
+		t.Errorf("expected pvc-2 in the ConfigMap store to be kept, got task %q", details.OperationDetails.TaskID)
+	}
+	list := &cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestList{}
+	if err := crdStore.k8sclient.List(ctx, list); err != nil {
+		t.Fatalf("failed to list CRs: %v", err)
+	}
+	if len(list.Items) != 0 {
+		t.Errorf("expected migrated CRs to be deleted, %d left", len(list.Items))
+	}
+}
+
+// TestConfigMapStoreReadsAfterFailover checks that a store reads the details
+// stored by another leader of the CSI controller.
+func TestConfigMapStoreReadsAfterFailover(t *testing.T) {
+	previousLeader, k8sclient, ctx := setupConfigMapStore(t, 1)
+	leader := newConfigMapOperationRequestStore(k8sclient, 1)
+	if err := previousLeader.StoreRequestDetails(ctx, createTestVolumeOperationDetails("pvc-1", "", "", "task-1",
+		TaskInvocationStatusInProgress, "", nil)); err != nil {
+		t.Fatalf("failed to store pvc-1: %v", err)
+	}
+	if _, err := leader.GetRequestDetails(ctx, "pvc-1"); err != nil {
+		t.Fatalf("failed to get pvc-1: %v", err)
+	}
+	if err := leader.StoreRequestDetails(ctx, createTestVolumeOperationDetails("pvc-1", "vol-1", "", "task-1",
+		TaskInvocationStatusSuccess, "", nil)); err != nil {
+		t.Fatalf("failed to store pvc-1: %v", err)
+	}
+	details, err := previousLeader.GetRequestDetails(ctx, "pvc-1")
+	if err != nil {
+		t.Fatalf("failed to get pvc-1: %v", err)
+	}
+	if details.OperationDetails.TaskStatus != TaskInvocationStatusSuccess {
+		t.Errorf("expected the details stored by the new leader, got %+v", details.OperationDetails)
+	}
+	requests, err := previousLeader.ListRequestDetails(ctx)
+	if err != nil {
+		t.Fatalf("failed to list the details: %v", err)
+	}
+	if len(requests) != 1 || requests[0].Name != "pvc-1" || requests[0].VolumeID != "vol-1" {
+		t.Errorf("unexpected details %+v", requests)
+	}
+}
+
+// TestConfigMapStoreRejectsFullShard checks that an update which would make a
+// shard larger than the API server accepts fails, while deletes still succeed.
+func TestConfigMapStoreRejectsFullShard(t *testing.T) {
+	store, _, ctx := setupConfigMapStore(t, 1)
+	op := createTestVolumeOperationDetails("pvc-1", "", "", "task-1", TaskInvocationStatusError, "", nil)
+	// The error is recorded in both the first and the latest operation details.
+	op.OperationDetails.Error = strings.Repeat("x", configMapStoreMaxDataSize/3)
+	if err := store.StoreRequestDetails(ctx, op); err != nil {
+		t.Fatalf("failed to store pvc-1: %v", err)
+	}
+	op.Name = "pvc-2"
+	err := store.StoreRequestDetails(ctx, op)
+	if err == nil || !strings.Contains(err.Error(), "cnsvolumeoperationrequest-store-shards") {
+		t.Fatalf("expected the store of pvc-2 to fail, got %v", err)
+	}
+	if _, err := store.GetRequestDetails(ctx, "pvc-2"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected pvc-2 not to be stored, got %v", err)
+	}
+	if err := store.DeleteRequestDetails(ctx, "pvc-1"); err != nil {
+		t.Errorf("failed to delete pvc-1: %v", err)
+	}
+}
@@ -17,9 +17,14 @@ limitations under the License.
 package cnsvolumeoperationrequest
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
 	cnsvolumeoprequestv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsvolumeoperationrequest/v1alpha1"
 )
 
@@ -113,3 +118,179 @@ func convertToCnsVolumeOperationRequestDetails(
 		Error:                   details.Error,
 	}
 }
+
+// requestDetailsFromStatus returns the details of the last operation recorded
+// in the given CnsVolumeOperationRequest status.
+func requestDetailsFromStatus(name string,
+	status *cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus) (*VolumeOperationRequestDetails, error) {
+	if len(status.LatestOperationDetails) == 0 {
+		return nil, fmt.Errorf("length of LatestOperationDetails expected to be greater than 1 if the instance exists")
+	}
+
+	// Callers only need to know about the last operation that was invoked on a volume.
+	operationDetailsToReturn := status.LatestOperationDetails[len(status.LatestOperationDetails)-1]
+
+	var quotaDetails *QuotaDetails
+	if isPodVMOnStretchSupervisorFSSEnabled && status.StorageQuotaDetails != nil {
+		quotaDetails = &QuotaDetails{
+			Reserved:         status.StorageQuotaDetails.Reserved,
+			StorageClassName: status.StorageQuotaDetails.StorageClassName,
+			StoragePolicyId:  status.StorageQuotaDetails.StoragePolicyId,
+			Namespace:        status.StorageQuotaDetails.Namespace,
+		}
+	}
+
+	return CreateVolumeOperationRequestDetails(name, status.VolumeID, status.SnapshotID,
+			status.Capacity, quotaDetails, operationDetailsToReturn.TaskInvocationTimestamp,
+			operationDetailsToReturn.TaskID, operationDetailsToReturn.VCenterServer, operationDetailsToReturn.OpID,
+			operationDetailsToReturn.TaskStatus, operationDetailsToReturn.Error, status.ChangedBlockTrackingId),
+		nil
+}
+
+// newOperationRequestStatus returns the CnsVolumeOperationRequest status
+// recording the first operation stored for an instance.
+func newOperationRequestStatus(
+	operationToStore *VolumeOperationRequestDetails) cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus {
+	operationDetailsToStore := convertToCnsVolumeOperationRequestDetails(*operationToStore.OperationDetails)
+	status := cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus{
+		VolumeID:               operationToStore.VolumeID,
+		SnapshotID:             operationToStore.SnapshotID,
+		Capacity:               operationToStore.Capacity,
+		ChangedBlockTrackingId: operationToStore.ChangedBlockTrackingId,
+		FirstOperationDetails:  *operationDetailsToStore,
+		LatestOperationDetails: []cnsvolumeoprequestv1alpha1.OperationDetails{
+			*operationDetailsToStore,
+		},
+	}
+	if isPodVMOnStretchSupervisorFSSEnabled && operationToStore.QuotaDetails != nil {
+		status.StorageQuotaDetails = convertToCnsVolumeOperationRequestQuotaDetails(*operationToStore.QuotaDetails)
+	}
+	return status
+}
+
+// updateOperationRequestStatus returns a copy of the given
+// CnsVolumeOperationRequest status with operationToStore recorded in it.
+func updateOperationRequestStatus(ctx context.Context,
+	status *cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus,
+	operationToStore *VolumeOperationRequestDetails) cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus {
+	log := logger.GetLogger(ctx)
+	operationDetailsToStore := convertToCnsVolumeOperationRequestDetails(*operationToStore.OperationDetails)
+	updatedStatus := *status.DeepCopy()
+
+	// If CSI Transaction Support is enabled and we're storing a new InProgress operation with empty TaskID,
+	// mark any existing InProgress entries as TrackingAborted since this indicates a retry scenario.
+	if isCSITransactionSupportEnabled && operationDetailsToStore.TaskStatus == TaskInvocationStatusInProgress &&
+		operationDetailsToStore.TaskID == "" {
+		// This is a new operation attempt (Phase 1: Intent Registration)
+		// Mark any existing InProgress entries as TrackingAborted since this is clearly a retry
+		for index := range updatedStatus.LatestOperationDetails {
+			existingOp := &updatedStatus.LatestOperationDetails[index]
+			if existingOp.TaskStatus == TaskInvocationStatusInProgress {
+				// This is a retry - mark the previous attempt as aborted
+				existingOp.TaskStatus = TaskInvocationStatusTrackingAborted
+				existingOp.Error = "Operation tracking aborted due to retry attempt"
+				log.Infof("Marked previous InProgress operation as TrackingAborted due to retry detection. Instance: %s",
+					operationToStore.Name)
+			}
+		}
+
+		// Also check FirstOperationDetails
+		if updatedStatus.FirstOperationDetails.TaskStatus == TaskInvocationStatusInProgress {
+			updatedStatus.FirstOperationDetails.TaskStatus = TaskInvocationStatusTrackingAborted
+			updatedStatus.FirstOperationDetails.Error = "Operation tracking aborted due to retry attempt"
+			log.Infof("Marked FirstOperationDetails as TrackingAborted due to retry detection. Instance: %s",
+				operationToStore.Name)
+		}
+	}
+
+	// Modify VolumeID, SnapshotID, Capacity, and ChangedBlockTrackingId
+	updatedStatus.VolumeID = operationToStore.VolumeID
+	updatedStatus.SnapshotID = operationToStore.SnapshotID
+	updatedStatus.Capacity = operationToStore.Capacity
+	updatedStatus.ChangedBlockTrackingId = operationToStore.ChangedBlockTrackingId
+	if isPodVMOnStretchSupervisorFSSEnabled && operationToStore.QuotaDetails != nil {
+		updatedStatus.StorageQuotaDetails = convertToCnsVolumeOperationRequestQuotaDetails(
+			*operationToStore.QuotaDetails)
+	}
+
+	// Modify FirstOperationDetails only if TaskID's match or the initial TaskID is empty.
+	firstOp := status.FirstOperationDetails
+	if firstOp.TaskStatus == TaskInvocationStatusInProgress &&
+		(firstOp.TaskID == operationToStore.OperationDetails.TaskID || firstOp.TaskID == "") {
+		updatedStatus.FirstOperationDetails = *operationDetailsToStore
+	}
+
+	operationExistsInList := false
+	// If the task details already exist in the status, update it with the
+	// latest information.
+	for index := len(status.LatestOperationDetails) - 1; index >= 0; index-- {
+		operationDetail := status.LatestOperationDetails[index]
+		if operationDetail.TaskStatus == TaskInvocationStatusInProgress &&
+			(operationDetailsToStore.TaskID == operationDetail.TaskID || operationDetail.TaskID == "") {
+			updatedStatus.LatestOperationDetails[index] = *operationDetailsToStore
+			operationExistsInList = true
+			break
+		}
+	}
+
+	if !operationExistsInList {
+		// Append the latest task details to the local instance and
+		// ensure length of LatestOperationDetails is not greater
+		// than 10.
+		updatedStatus.LatestOperationDetails = append(
+			updatedStatus.LatestOperationDetails,
+			*operationDetailsToStore,
+		)
+		if len(updatedStatus.LatestOperationDetails) > maxEntriesInLatestOperationDetails {
+			updatedStatus.LatestOperationDetails = updatedStatus.LatestOperationDetails[1:]
+		}
+	}
+	return updatedStatus
+}
+
+// hasPriorSuccessfulOperation returns true if any entry in the
+// LatestOperationDetails of the given status has TaskStatus == Success.
+func hasPriorSuccessfulOperation(ctx context.Context, name string,
+	status *cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus) bool {
+	log := logger.GetLogger(ctx)
+	for _, op := range status.LatestOperationDetails {
+		if op.TaskStatus == TaskInvocationStatusSuccess {
+			log.Infof("HasPriorSuccessfulCreate: found prior successful task %s in CR %s",
+				op.TaskID, name)
+			return true
+		}
+	}
+	return false
+}
+
+// isStaleOperationRequest returns true if the last operation recorded in the
+// given status is complete and was invoked before cutoffTime.
+func isStaleOperationRequest(status *cnsvolumeoprequestv1alpha1.CnsVolumeOperationRequestStatus,
+	cutoffTime time.Time) bool {
+	latestOperationDetailsLength := len(status.LatestOperationDetails)
+	if latestOperationDetailsLength == 0 {
+		return true
+	}
+	latestOperation := status.LatestOperationDetails[latestOperationDetailsLength-1]
+	// Skip if task is still in progress
+	if latestOperation.TaskStatus == TaskInvocationStatusInProgress {
+		return false
+	}
+	// Stale if TaskInvocationTimestamp is older than cutoffTime
+	return !latestOperation.TaskInvocationTimestamp.Time.After(cutoffTime)
+}
+
+// convertToCnsVolumeOperationRequestQuotaDetails converts an object of type
+// QuotaDetails to the QuotaDetails type defined by the
+// CnsVolumeOperationRequest Custom Resource.
+func convertToCnsVolumeOperationRequestQuotaDetails(
+	details QuotaDetails) *cnsvolumeoprequestv1alpha1.QuotaDetails {
+	return &cnsvolumeoprequestv1alpha1.QuotaDetails{
+		Reserved:                            details.Reserved,
+		StoragePolicyId:                     details.StoragePolicyId,
+		StorageClassName:                    details.StorageClassName,
+		Namespace:                           details.Namespace,
+		AggregatedSnapshotSize:              details.AggregatedSnapshotSize,
+		SnapshotLatestOperationCompleteTime: details.SnapshotLatestOperationCompleteTime,
+	}
+}
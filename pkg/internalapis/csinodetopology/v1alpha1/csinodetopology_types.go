@@ -54,6 +54,12 @@ type CSINodeTopologyStatus struct {
 	// ErrorMessage will contain the error string when `Status` field is set to "Error".
 	// It will be empty when the `Status` field is set to "Success".
 	ErrorMessage string `json:"errorMessage,omitempty"`
+
+	// AttachableVolumes is the number of block volumes which can be attached to the NodeVM,
+	// computed from its PVSCSI and NVMe controllers and the disks which are not volumes.
+	// It is only set on Vanilla clusters with the attachable-volumes-from-inventory FSS enabled.
+	//+optional
+	AttachableVolumes int64 `json:"attachableVolumes,omitempty"`
 }
 
 // TopologyLabel will consist of a key-value pair.
@@ -26,7 +26,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	cnsdriftreportv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsoperator/cnsdriftreport/v1alpha1"
 	cnsfilevolclientv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsoperator/cnsfilevolumeclient/v1alpha1"
+	cnsorphanobjectv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsoperator/cnsorphanobject/v1alpha1"
 	triggercsifullsyncv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsoperator/triggercsifullsync/v1alpha1"
 	cnscsisvfeaturestatesv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/featurestates/v1alpha1"
 )
@@ -46,6 +48,12 @@ var (
 
 	// TriggerCsiFullSyncPlural is plural of TriggerCsiFullSyncPlural
 	TriggerCsiFullSyncPlural = "triggercsifullsyncs"
+
+	// CnsDriftReportPlural is plural of CnsDriftReport
+	CnsDriftReportPlural = "cnsdriftreports"
+
+	// CnsOrphanObjectPlural is plural of CnsOrphanObject
+	CnsOrphanObjectPlural = "cnsorphanobjects"
 )
 
 var (
@@ -80,6 +88,18 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&triggercsifullsyncv1alpha1.TriggerCsiFullSyncList{},
 	)
 
+	scheme.AddKnownTypes(
+		SchemeGroupVersion,
+		&cnsdriftreportv1alpha1.CnsDriftReport{},
+		&cnsdriftreportv1alpha1.CnsDriftReportList{},
+	)
+
+	scheme.AddKnownTypes(
+		SchemeGroupVersion,
+		&cnsorphanobjectv1alpha1.CnsOrphanObject{},
+		&cnsorphanobjectv1alpha1.CnsOrphanObjectList{},
+	)
+
 	scheme.AddKnownTypes(
 		SchemeGroupVersion,
 		&cnscsisvfeaturestatesv1alpha1.CnsCsiSvFeatureStates{},
@@ -23,6 +23,8 @@ import (
 
 	snapclientset "github.com/kubernetes-csi/external-snapshotter/client/v8/clientset/versioned"
 	"github.com/kubernetes-csi/external-snapshotter/client/v8/informers/externalversions"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/informers"
 	v1 "k8s.io/client-go/informers/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
@@ -44,6 +46,10 @@ const (
 	// as part of NewFilteredConfigMapInformer(). Since we do not anticipate
 	// frequent changes to the configmaps, the resync interval is set to 30 min.
 	resyncPeriodConfigMapInformer = 30 * time.Minute
+	// resyncPeriodSecretInformer is the time interval between each resync
+	// operation for the secret informer. The resync retries the handling of
+	// a credentials rotation which failed, such as a login to vCenter.
+	resyncPeriodSecretInformer = 5 * time.Minute
 )
 
 var (
@@ -104,6 +110,7 @@ func (im *InformerManager) AddNodeListener(ctx context.Context, add func(obj int
 	if im.nodeInformer == nil {
 		im.nodeInformer = im.informerFactory.Core().V1().Nodes().Informer()
 	}
+	im.nodeSynced = im.nodeInformer.HasSynced
 
 	_, err := im.nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    add,
@@ -120,11 +127,11 @@ func (im *InformerManager) AddNodeListener(ctx context.Context, add func(obj int
 func (im *InformerManager) AddCSINodeListener(ctx context.Context, add func(obj interface{}),
 	update func(oldObj, newObj interface{}), remove func(obj interface{})) error {
 	log := logger.GetLogger(ctx)
-	if im.nodeInformer == nil {
-		im.nodeInformer = im.informerFactory.Storage().V1().CSINodes().Informer()
+	if im.csiNodeInformer == nil {
+		im.csiNodeInformer = im.informerFactory.Storage().V1().CSINodes().Informer()
 	}
 
-	_, err := im.nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	_, err := im.csiNodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    add,
 		UpdateFunc: update,
 		DeleteFunc: remove,
@@ -336,6 +343,16 @@ func (im *InformerManager) GetPodLister() corelisters.PodLister {
 	return im.informerFactory.Core().V1().Pods().Lister()
 }
 
+// GetNodeLister returns Node Lister for the calling informer manager.
+func (im *InformerManager) GetNodeLister() corelisters.NodeLister {
+	return im.informerFactory.Core().V1().Nodes().Lister()
+}
+
+// NodeInformerSynced returns the node informer's HasSynced func, or nil if not registered.
+func (im *InformerManager) NodeInformerSynced() cache.InformerSynced {
+	return im.nodeSynced
+}
+
 // GetVolumeAttachmentLister returns the VolumeAttachment lister backed by the shared informer
 // factory. The caller must register the underlying informer first via
 // InitVolumeAttachmentInformer (or AddVolumeAttachmentListener) before Listen() so the cache
@@ -418,3 +435,27 @@ func NewConfigMapListener(ctx context.Context, client clientset.Interface, names
 	go configMapInformer.Run(stopCh)
 	return nil
 }
+
+// NewSecretListener creates a new listener on the secret with the given name
+// in the given namespace. The listener is stopped when ctx is done.
+// NOTE: This creates a NewSharedIndexInformer everytime and does not use the
+// informer factory, so that only the given secret is watched.
+func NewSecretListener(ctx context.Context, client clientset.Interface, namespace string, name string,
+	add func(obj interface{}), update func(oldObj, newObj interface{}), remove func(obj interface{})) error {
+	log := logger.GetLogger(ctx)
+	secretInformer := v1.NewFilteredSecretInformer(client, namespace, resyncPeriodSecretInformer,
+		cache.Indexers{}, func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		})
+
+	_, err := secretInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    add,
+		UpdateFunc: update,
+		DeleteFunc: remove,
+	})
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to add event handler on secret listener. Error: %v", err)
+	}
+	go secretInformer.Run(ctx.Done())
+	return nil
+}
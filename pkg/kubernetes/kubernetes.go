@@ -23,6 +23,7 @@ import (
 	"flag"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"time"
@@ -170,6 +171,22 @@ func GetRestClientConfigForSupervisor(ctx context.Context, endpoint string, port
 		tokenFile  = cnsconfig.DefaultpvCSIProviderPath + "/token"
 		rootCAFile = cnsconfig.DefaultpvCSIProviderPath + "/ca.crt"
 	)
+	if _, caCert, ok := cnsconfig.GetSupervisorCredentials(); ok {
+		// The token and CA certificate were resolved from the Secret referenced
+		// by the Guest Cluster config. The token is set on each request, so that
+		// the clients use the rotated token without being recreated.
+		config = &restclient.Config{
+			Host: "https://" + net.JoinHostPort(endpoint, port),
+			TLSClientConfig: restclient.TLSClientConfig{
+				CAData: caCert,
+			},
+			WrapTransport: func(rt http.RoundTripper) http.RoundTripper {
+				return &supervisorTokenRoundTripper{rt: rt}
+			},
+		}
+		config.QPS, config.Burst = getClientThroughput(ctx, true)
+		return config
+	}
 	token, err := os.ReadFile(tokenFile)
 	if err != nil {
 		return nil
@@ -189,6 +206,20 @@ func GetRestClientConfigForSupervisor(ctx context.Context, endpoint string, port
 	return config
 }
 
+// supervisorTokenRoundTripper sets the Supervisor Cluster token resolved from
+// the Secret referenced by the Guest Cluster config on each request.
+type supervisorTokenRoundTripper struct {
+	rt http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *supervisorTokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, _, _ := cnsconfig.GetSupervisorCredentials()
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.rt.RoundTrip(req)
+}
+
 // NewSupervisorClient creates a new supervisor client for given restClient config.
 func NewSupervisorClient(ctx context.Context, config *restclient.Config) (clientset.Interface, error) {
 	log := logger.GetLogger(ctx)
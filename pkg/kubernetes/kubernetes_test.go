@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"net/http"
 	"os"
 	"testing"
 
@@ -28,6 +29,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/tools/clientcmd"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
 )
 
 func setupFlags() {
@@ -304,3 +307,39 @@ func TestGetPreferredVersionFromDiscovery_ResourceNotInMatchingGroup(t *testing.
 		t.Errorf("expected empty string on error, got %q", got)
 	}
 }
+
+type fakeRoundTripper struct {
+	req *http.Request
+}
+
+func (f *fakeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.req = req
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func TestGetRestClientConfigForSupervisorWithCredentialsSecret(t *testing.T) {
+	ctx := context.Background()
+	cnsconfig.SetSupervisorCredentials("token-1", []byte("ca"))
+	defer cnsconfig.SetSupervisorCredentials("", nil)
+
+	config := GetRestClientConfigForSupervisor(ctx, "10.0.0.1", "6443")
+	assert.NotNil(t, config)
+	assert.Equal(t, "https://10.0.0.1:6443", config.Host)
+	assert.Equal(t, []byte("ca"), config.TLSClientConfig.CAData)
+	assert.Empty(t, config.BearerToken)
+
+	fake := &fakeRoundTripper{}
+	rt := config.WrapTransport(fake)
+	req, err := http.NewRequest(http.MethodGet, config.Host, nil)
+	assert.NoError(t, err)
+	_, err = rt.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token-1", fake.req.Header.Get("Authorization"))
+
+	// The rotated token is used by the existing clients.
+	cnsconfig.SetSupervisorCredentials("token-2", []byte("ca"))
+	_, err = rt.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token-2", fake.req.Header.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
@@ -59,6 +59,11 @@ type InformerManager struct {
 
 	// node informer
 	nodeInformer cache.SharedInformer
+	// Function to determine if nodeInformer has been synced
+	nodeSynced cache.InformerSynced
+
+	// CSINode informer
+	csiNodeInformer cache.SharedInformer
 
 	// ConfigMap informer
 	configMapInformer cache.SharedInformer
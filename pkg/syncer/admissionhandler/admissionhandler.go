@@ -62,7 +62,11 @@ var (
 	featureGateByokEnabled                 bool
 	featureFileVolumesWithVmServiceEnabled bool
 	featureIsSharedDiskEnabled             bool
+	featureMultiWriterBlockVolumeEnabled   bool
 	featureIsLinkedCloneSupportEnabled     bool
+	featureNetPermissionsEnabled           bool
+	featureMkfsOptionsEnabled              bool
+	featureProjectQuotaEnabled             bool
 )
 
 // watchConfigChange watches on the webhook configuration directory for changes
@@ -238,8 +242,24 @@ func StartWebhookServer(ctx context.Context, enableWebhookClientCertVerification
 		featureGateBlockVolumeSnapshotEnabled = containerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeSnapshot)
 		featureFileVolumesWithVmServiceEnabled = containerOrchestratorUtility.IsFSSEnabled(ctx,
 			common.FileVolumesWithVmService)
+		// Multi-writer raw block volumes are validated as block volumes.
+		featureMultiWriterBlockVolumeEnabled = containerOrchestratorUtility.IsFSSEnabled(ctx,
+			common.MultiWriterBlockVolume)
+		featureNetPermissionsEnabled = containerOrchestratorUtility.IsFSSEnabled(ctx, common.FileVolumeNetPermissions)
+		if featureNetPermissionsEnabled {
+			vsphereCfg, err := cnsconfig.GetConfig(ctx)
+			if err != nil {
+				log.Errorf("failed to read the vSphere config. err: %v", err)
+				return err
+			}
+			configuredNetPermissions = vsphereCfg.NetPermissions
+		}
+
+		featureMkfsOptionsEnabled = containerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeMkfsOptions)
+		featureProjectQuotaEnabled = containerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeProjectQuota)
 
-		if featureGateBlockVolumeSnapshotEnabled {
+		if featureGateBlockVolumeSnapshotEnabled || featureNetPermissionsEnabled || featureMkfsOptionsEnabled ||
+			featureProjectQuotaEnabled {
 			certs, err := tls.LoadX509KeyPair(cfg.WebHookConfig.CertFile, cfg.WebHookConfig.KeyFile)
 			if err != nil {
 				log.Errorf("failed to load key pair. certFile: %q, keyFile: %q err: %v",
@@ -0,0 +1,83 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admissionhandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+)
+
+// configuredNetPermissions holds the NetPermissions sections of the vSphere
+// config, which the net permissions of the StorageClasses and PVCs may select.
+var configuredNetPermissions map[string]*cnsconfig.NetPermissionConfig
+
+// validateStorageClassNetPermissions validates the netpermissions parameter
+// of a StorageClass.
+func validateStorageClassNetPermissions(parameters map[string]string) error {
+	for param, value := range parameters {
+		if strings.ToLower(param) != common.AttributeNetPermissions {
+			continue
+		}
+		if _, err := cnsconfig.ParseNetPermissions(value, configuredNetPermissions); err != nil {
+			return fmt.Errorf("invalid StorageClass parameter %q: %v", param, err)
+		}
+	}
+	return nil
+}
+
+// validatePVCNetPermissions validates the net permissions annotation of the
+// PVC created or updated by req. The annotation is only validated when it is
+// added or changed.
+func validatePVCNetPermissions(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	log := logger.GetLogger(ctx)
+	newPVC := corev1.PersistentVolumeClaim{}
+	if err := json.Unmarshal(req.Object.Raw, &newPVC); err != nil {
+		log.Errorf("error deserializing pvc: %v. skipping net permissions validation.", err)
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	value, found := newPVC.Annotations[common.AnnNetPermissions]
+	if !found {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	if req.Operation == admissionv1.Update {
+		oldPVC := corev1.PersistentVolumeClaim{}
+		if err := json.Unmarshal(req.OldObject.Raw, &oldPVC); err == nil &&
+			oldPVC.Annotations[common.AnnNetPermissions] == value {
+			return &admissionv1.AdmissionResponse{Allowed: true}
+		}
+	}
+	if _, err := cnsconfig.ParseNetPermissions(value, configuredNetPermissions); err != nil {
+		log.Errorf("validation of the net permissions of PVC %s/%s failed: %v", newPVC.Namespace, newPVC.Name, err)
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Reason: metav1.StatusReason(fmt.Sprintf("invalid annotation %q: %v", common.AnnNetPermissions, err)),
+			},
+		}
+	}
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admissionhandler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+)
+
+func TestValidateNetPermissions(t *testing.T) {
+	ctx := context.Background()
+	origEnabled, origConfigured := featureNetPermissionsEnabled, configuredNetPermissions
+	defer func() { featureNetPermissionsEnabled, configuredNetPermissions = origEnabled, origConfigured }()
+	featureNetPermissionsEnabled = true
+	configuredNetPermissions = map[string]*cnsconfig.NetPermissionConfig{
+		"tenant-a": {Ips: "10.0.0.0/24", Permissions: "READ_WRITE"},
+	}
+
+	newStorageClassReview := func(netPermissions string) *admissionv1.AdmissionReview {
+		sc := storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "sc"},
+			Provisioner: "csi.vsphere.vmware.com",
+			Parameters:  map[string]string{"netPermissions": netPermissions},
+		}
+		raw, err := json.Marshal(sc)
+		require.NoError(t, err)
+		return &admissionv1.AdmissionReview{Request: &admissionv1.AdmissionRequest{
+			Kind:   metav1.GroupVersionKind{Kind: "StorageClass"},
+			Object: runtime.RawExtension{Raw: raw},
+		}}
+	}
+	assert.True(t, validateStorageClass(ctx, newStorageClassReview("tenant-a")).Allowed)
+	assert.True(t, validateStorageClass(ctx, newStorageClassReview(`[{"ips": "10.0.1.0/24"}]`)).Allowed)
+	response := validateStorageClass(ctx, newStorageClassReview("tenant-b"))
+	assert.False(t, response.Allowed, "unknown net permissions must be rejected")
+	assert.Contains(t, string(response.Result.Reason), "tenant-b")
+
+	newPVC := func(netPermissions string) runtime.RawExtension {
+		pvc := corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+			Name:        "pvc",
+			Namespace:   "default",
+			Annotations: map[string]string{common.AnnNetPermissions: netPermissions},
+		}}
+		raw, err := json.Marshal(pvc)
+		require.NoError(t, err)
+		return runtime.RawExtension{Raw: raw}
+	}
+	newPVCRequest := func(operation admissionv1.Operation, oldValue, newValue string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Kind: "PersistentVolumeClaim"},
+			Operation: operation,
+			Object:    newPVC(newValue),
+			OldObject: newPVC(oldValue),
+		}
+	}
+	assert.True(t, validatePVC(ctx, newPVCRequest(admissionv1.Create, "", "tenant-a")).Allowed)
+	assert.False(t, validatePVC(ctx, newPVCRequest(admissionv1.Create, "", `[{"permissions": "ALL"}]`)).Allowed,
+		"invalid net permissions must be rejected")
+	assert.False(t, validatePVC(ctx, newPVCRequest(admissionv1.Update, "tenant-a", "tenant-b")).Allowed,
+		"a change to unknown net permissions must be rejected")
+	assert.True(t, validatePVC(ctx, newPVCRequest(admissionv1.Update, "tenant-b", "tenant-b")).Allowed,
+		"unchanged net permissions must not be validated again")
+}
@@ -72,7 +72,7 @@ func validatePv(ctx context.Context, req *admissionv1.AdmissionRequest) *admissi
 func isFileVolume(accessModes []corev1.PersistentVolumeAccessMode, volumeMode corev1.PersistentVolumeMode) bool {
 	for _, accessMode := range accessModes {
 		if accessMode == corev1.ReadWriteMany || accessMode == corev1.ReadOnlyMany {
-			if featureIsSharedDiskEnabled {
+			if featureIsSharedDiskEnabled || featureMultiWriterBlockVolumeEnabled {
 				if volumeMode != corev1.PersistentVolumeBlock {
 					return true
 				}
@@ -40,6 +40,12 @@ const (
 
 // validatePVC helps validate AdmissionReview requests for PersistentVolumeClaim.
 func validatePVC(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if featureNetPermissionsEnabled &&
+		(req.Operation == admissionv1.Create || req.Operation == admissionv1.Update) {
+		if response := validatePVCNetPermissions(ctx, req); !response.Allowed {
+			return response
+		}
+	}
 	if !featureGateBlockVolumeSnapshotEnabled {
 		// If CSI block volume snapshot is disabled and webhook is running,
 		// skip validation for PersistentVolumeClaim.
@@ -19,6 +19,8 @@ package admissionhandler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	stroagev1 "k8s.io/api/storage/v1"
@@ -45,6 +47,9 @@ var (
 const (
 	migrationParamErrorMessage = "Invalid StorageClass Parameters. " +
 		"Migration specific parameters should not be used in the StorageClass"
+	// csiFsTypeParam is the StorageClass parameter setting the fstype of
+	// the volumes passed by external-provisioner to the driver.
+	csiFsTypeParam = "csi.storage.k8s.io/fstype"
 )
 
 // validateStorageClass helps validate AdmissionReview requests for StroageClass.
@@ -78,6 +83,30 @@ func validateStorageClass(ctx context.Context, ar *admissionv1.AdmissionReview)
 					break
 				}
 			}
+			if allowed && featureNetPermissionsEnabled {
+				if err := validateStorageClassNetPermissions(sc.Parameters); err != nil {
+					allowed = false
+					result = &metav1.Status{
+						Reason: metav1.StatusReason(err.Error()),
+					}
+				}
+			}
+			if allowed && featureMkfsOptionsEnabled {
+				if err := validateStorageClassMkfsOptions(sc.Parameters); err != nil {
+					allowed = false
+					result = &metav1.Status{
+						Reason: metav1.StatusReason(err.Error()),
+					}
+				}
+			}
+			if allowed && featureProjectQuotaEnabled {
+				if err := validateStorageClassProjectQuotaDirectories(sc.Parameters); err != nil {
+					allowed = false
+					result = &metav1.Status{
+						Reason: metav1.StatusReason(err.Error()),
+					}
+				}
+			}
 		}
 		if allowed {
 			log.Infof("Validation of StorageClass: %q Passed", sc.Name)
@@ -94,3 +123,51 @@ func validateStorageClass(ctx context.Context, ar *admissionv1.AdmissionReview)
 		Result:  result,
 	}
 }
+
+// validateStorageClassMkfsOptions validates the mkfsoptions parameter of a
+// StorageClass for the fstype of the StorageClass, ext4 by default.
+func validateStorageClassMkfsOptions(parameters map[string]string) error {
+	fsType := common.Ext4FsType
+	var mkfsOptions, mkfsOptionsParam string
+	for param, value := range parameters {
+		switch strings.ToLower(param) {
+		case common.AttributeMkfsOptions:
+			mkfsOptions, mkfsOptionsParam = value, param
+		case common.AttributeFsType, csiFsTypeParam:
+			fsType = strings.ToLower(value)
+		}
+	}
+	if mkfsOptionsParam == "" {
+		return nil
+	}
+	if _, err := common.ParseMkfsOptions(fsType, mkfsOptions); err != nil {
+		return fmt.Errorf("invalid StorageClass parameter %q: %v", mkfsOptionsParam, err)
+	}
+	return nil
+}
+
+// validateStorageClassProjectQuotaDirectories validates the
+// projectquotadirectories parameter of a StorageClass, for the file system
+// type of the StorageClass, ext4 by default.
+func validateStorageClassProjectQuotaDirectories(parameters map[string]string) error {
+	fsType := common.Ext4FsType
+	var dirs, dirsParam string
+	for param, value := range parameters {
+		switch strings.ToLower(param) {
+		case common.AttributeProjectQuotaDirectories:
+			dirs, dirsParam = value, param
+		case common.AttributeFsType, csiFsTypeParam:
+			fsType = strings.ToLower(value)
+		}
+	}
+	if dirsParam == "" {
+		return nil
+	}
+	if fsType != common.Ext4FsType && fsType != common.XFSType {
+		return fmt.Errorf("StorageClass parameter %q is not supported for fstype %s", dirsParam, fsType)
+	}
+	if _, err := common.ParseProjectQuotaDirectories(dirs); err != nil {
+		return fmt.Errorf("invalid StorageClass parameter %q: %v", dirsParam, err)
+	}
+	return nil
+}
@@ -18,10 +18,12 @@ package admissionhandler
 
 import (
 	"context"
+	"encoding/json"
 	"strings"
 	"testing"
 
 	v1 "k8s.io/api/admission/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -116,3 +118,87 @@ func TestValidateStorageClassForValidStorageClass(t *testing.T) {
 	}
 	t.Log("TestValidateStorageClassForValidStorageClass Passed")
 }
+
+// TestValidateStorageClassForMkfsOptions is the unit test for validating
+// admissionReview request containing StorageClass with mkfs options.
+func TestValidateStorageClassForMkfsOptions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	origEnabled := featureMkfsOptionsEnabled
+	defer func() { featureMkfsOptionsEnabled = origEnabled }()
+	featureMkfsOptionsEnabled = true
+
+	newStorageClassReview := func(parameters map[string]string) *v1.AdmissionReview {
+		raw, err := json.Marshal(storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "sc"},
+			Provisioner: "csi.vsphere.vmware.com",
+			Parameters:  parameters,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &v1.AdmissionReview{Request: &v1.AdmissionRequest{
+			Kind:   metav1.GroupVersionKind{Kind: "StorageClass"},
+			Object: runtime.RawExtension{Raw: raw},
+		}}
+	}
+	for _, parameters := range []map[string]string{
+		{"mkfsOptions": "-i 65536 -m 1 -E lazy_itable_init=0"},
+		{"mkfsOptions": "-m reflink=1,crc=1", "csi.storage.k8s.io/fstype": "xfs"},
+	} {
+		if response := validateStorageClass(ctx, newStorageClassReview(parameters)); !response.Allowed {
+			t.Fatalf("expected StorageClass parameters %v to be allowed, got %v", parameters, response.Result)
+		}
+	}
+	for _, parameters := range []map[string]string{
+		{"mkfsOptions": "-S 1"},
+		{"mkfsOptions": "-m reflink=1,crc=1"},
+		{"mkfsOptions": "-f 1", "csi.storage.k8s.io/fstype": "xfs"},
+	} {
+		response := validateStorageClass(ctx, newStorageClassReview(parameters))
+		if response.Allowed || !strings.Contains(string(response.Result.Reason), "mkfsOptions") {
+			t.Fatalf("expected StorageClass parameters %v to be refused, got %v", parameters, response.Result)
+		}
+	}
+}
+
+func TestValidateStorageClassForProjectQuotaDirectories(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	origEnabled := featureProjectQuotaEnabled
+	defer func() { featureProjectQuotaEnabled = origEnabled }()
+	featureProjectQuotaEnabled = true
+
+	newStorageClassReview := func(parameters map[string]string) *v1.AdmissionReview {
+		raw, err := json.Marshal(storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "sc"},
+			Provisioner: "csi.vsphere.vmware.com",
+			Parameters:  parameters,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &v1.AdmissionReview{Request: &v1.AdmissionRequest{
+			Kind:   metav1.GroupVersionKind{Kind: "StorageClass"},
+			Object: runtime.RawExtension{Raw: raw},
+		}}
+	}
+	for _, parameters := range []map[string]string{
+		{"projectQuotaDirectories": "data=10Gi,logs=1Gi"},
+		{"projectQuotaDirectories": "data=10Gi", "csi.storage.k8s.io/fstype": "xfs"},
+	} {
+		if response := validateStorageClass(ctx, newStorageClassReview(parameters)); !response.Allowed {
+			t.Fatalf("expected StorageClass parameters %v to be allowed, got %v", parameters, response.Result)
+		}
+	}
+	for _, parameters := range []map[string]string{
+		{"projectQuotaDirectories": "data"},
+		{"projectQuotaDirectories": "../data=1Gi"},
+		{"projectQuotaDirectories": "data=10Gi", "csi.storage.k8s.io/fstype": "btrfs"},
+	} {
+		response := validateStorageClass(ctx, newStorageClassReview(parameters))
+		if response.Allowed || !strings.Contains(string(response.Result.Reason), "projectQuotaDirectories") {
+			t.Fatalf("expected StorageClass parameters %v to be refused, got %v", parameters, response.Result)
+		}
+	}
+}
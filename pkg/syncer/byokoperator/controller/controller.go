@@ -33,6 +33,7 @@ import (
 var addToManagerFuncs = []func(ctx context.Context, mgr manager.Manager, opts common.Options) error{
 	storageclass.AddToManager,
 	persistentvolumeclaim.AddToManager,
+	persistentvolumeclaim.AddRekeyToManager,
 }
 
 func AddToManager(ctx context.Context, mgr manager.Manager, opts common.Options) error {
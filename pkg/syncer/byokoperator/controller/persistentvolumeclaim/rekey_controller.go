@@ -0,0 +1,382 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package persistentvolumeclaim
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	byokv1 "github.com/vmware-tanzu/vm-operator/external/byok/api/v1alpha1"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vimtypes "github.com/vmware/govmomi/vim25/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/crypto"
+	csicommon "sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	ctrlcommoon "sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer/byokoperator/controller/common"
+)
+
+const (
+	// defaultRekeyMaxConcurrency is the maximum number of volumes of an
+	// EncryptionClass rekeyed at the same time, unless set by the class.
+	defaultRekeyMaxConcurrency = 2
+	// rekeyBatchInterval is the delay between two batches of rekeys of the
+	// volumes of an EncryptionClass.
+	rekeyBatchInterval = 10 * time.Second
+	// rekeyRetryInterval is the delay before retrying the rekey of a volume
+	// which failed or could not be rekeyed.
+	rekeyRetryInterval = 10 * time.Minute
+
+	// RekeyConditionType is the type of the PVC condition reporting the
+	// rekeys of its volume.
+	RekeyConditionType corev1.PersistentVolumeClaimConditionType = "VolumeRekeyed"
+	// RekeyReasonSucceeded is the reason of the condition once the volume is
+	// rekeyed.
+	RekeyReasonSucceeded = "RekeySucceeded"
+	// RekeyReasonInProgress is the reason of the condition while the volume is
+	// rekeyed.
+	RekeyReasonInProgress = "RekeyInProgress"
+	// RekeyReasonFailed is the reason of the condition when the rekey of the
+	// volume failed.
+	RekeyReasonFailed = "RekeyFailed"
+	// RekeyReasonDeferred is the reason of the condition when the volume is
+	// attached to a VirtualMachine, whose disks are rekeyed by VM Operator.
+	RekeyReasonDeferred = "RekeyDeferred"
+	// RekeyReasonPending is the reason of the condition when the volume is
+	// not encrypted with the key of its EncryptionClass yet.
+	RekeyReasonPending = "RekeyPending"
+)
+
+// AddRekeyToManager adds the controller rekeying the volumes of the
+// EncryptionClasses with a rotation policy to the manager.
+func AddRekeyToManager(ctx context.Context, mgr manager.Manager, opts ctrlcommoon.Options) error {
+	r := &rekeyReconciler{
+		reconciler: reconciler{
+			Client:        mgr.GetClient(),
+			logger:        logger.GetLoggerWithNoContext().Named("controllers").Named("EncryptionClassRekey"),
+			cryptoClient:  opts.CryptoClient,
+			volumeManager: opts.VolumeManager,
+		},
+		now: time.Now,
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("encryptionclass-rekey").
+		For(&byokv1.EncryptionClass{}).
+		Complete(r)
+}
+
+// rekeyReconciler periodically rekeys the volumes of the PVCs of an
+// EncryptionClass with a shallow recrypt, as set by the rotation policy
+// annotations of the class.
+type rekeyReconciler struct {
+	reconciler
+	now func() time.Time
+}
+
+func (r *rekeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	encClass := &byokv1.EncryptionClass{}
+	if err := r.Get(ctx, req.NamespacedName, encClass); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !encClass.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	interval, maxConcurrency, err := getRekeyPolicy(encClass)
+	if err != nil {
+		// The class is reconciled again once its annotations are fixed.
+		r.logger.Errorf("Invalid rotation policy of EncryptionClass %s/%s: %v",
+			encClass.Namespace, encClass.Name, err)
+		return ctrl.Result{}, nil
+	} else if interval == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := r.List(ctx, pvcs, client.InNamespace(encClass.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	now := r.now()
+	nextDue := now.Add(interval)
+	var due []*corev1.PersistentVolumeClaim
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		if crypto.GetEncryptionClassNameForPVC(pvc) != encClass.Name ||
+			pvc.Spec.VolumeName == "" || !pvc.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if dueAt := rekeyDueTime(pvc, interval); dueAt.After(now) {
+			if dueAt.Before(nextDue) {
+				nextDue = dueAt
+			}
+			continue
+		}
+		due = append(due, pvc)
+	}
+	if len(due) == 0 {
+		return ctrl.Result{RequeueAfter: nextDue.Sub(now)}, nil
+	}
+
+	// The volumes overdue the longest are rekeyed first.
+	sort.SliceStable(due, func(i, j int) bool {
+		return rekeyDueTime(due[i], interval).Before(rekeyDueTime(due[j], interval))
+	})
+	batch := due
+	if len(batch) > maxConcurrency {
+		batch = batch[:maxConcurrency]
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		rekeyed int
+	)
+	for _, pvc := range batch {
+		wg.Add(1)
+		go func(pvc *corev1.PersistentVolumeClaim) {
+			defer wg.Done()
+			if r.rekeyVolume(ctx, encClass, pvc) {
+				mutex.Lock()
+				rekeyed++
+				mutex.Unlock()
+			}
+		}(pvc)
+	}
+	wg.Wait()
+
+	r.logger.Infof("Rekeyed %d of %d volumes due for rekey in EncryptionClass %s/%s, %d left",
+		rekeyed, len(batch), encClass.Namespace, encClass.Name, len(due)-len(batch))
+	if len(due) > len(batch) {
+		return ctrl.Result{RequeueAfter: rekeyBatchInterval}, nil
+	}
+	if retryAt := now.Add(rekeyRetryInterval); rekeyed < len(batch) && retryAt.Before(nextDue) {
+		nextDue = retryAt
+	}
+	return ctrl.Result{RequeueAfter: nextDue.Sub(now)}, nil
+}
+
+// rekeyVolume rekeys the volume of the PVC with the key of the EncryptionClass,
+// records the rekey in the annotations of the PVC, and reports its progress in
+// the RekeyConditionType condition of the PVC. It returns whether the volume
+// was rekeyed.
+func (r *rekeyReconciler) rekeyVolume(ctx context.Context, encClass *byokv1.EncryptionClass,
+	pvc *corev1.PersistentVolumeClaim) bool {
+	if isAttached, detail := r.isPVCAttachedToVM(pvc); isAttached {
+		r.setRekeyCondition(ctx, pvc, corev1.ConditionFalse, RekeyReasonDeferred,
+			fmt.Sprintf("The volume is attached to a VirtualMachine %s and is rekeyed by VM Operator", detail))
+		return false
+	}
+
+	if pvc.Spec.StorageClassName == nil {
+		r.setRekeyCondition(ctx, pvc, corev1.ConditionFalse, RekeyReasonPending,
+			"The PVC has no StorageClass")
+		return false
+	}
+	encrypted, profileID, err := r.isEncryptedStoragePolicyForPVC(ctx, pvc)
+	if err != nil {
+		r.setRekeyCondition(ctx, pvc, corev1.ConditionFalse, RekeyReasonFailed,
+			fmt.Sprintf("Failed to get the storage policy of the volume: %v", err))
+		return false
+	} else if !encrypted {
+		r.setRekeyCondition(ctx, pvc, corev1.ConditionFalse, RekeyReasonPending,
+			"The storage policy of the volume does not support encryption")
+		return false
+	}
+
+	volumeID, err := r.findVolume(ctx, pvc)
+	if err != nil {
+		r.setRekeyCondition(ctx, pvc, corev1.ConditionFalse, RekeyReasonFailed,
+			fmt.Sprintf("Failed to find the volume: %v", err))
+		return false
+	} else if volumeID == "" {
+		r.setRekeyCondition(ctx, pvc, corev1.ConditionFalse, RekeyReasonPending,
+			"The volume is not an encryptable block volume")
+		return false
+	}
+
+	existingKeyID, err := csicommon.QueryVolumeCryptoKeyByID(ctx, r.volumeManager, volumeID)
+	if err != nil {
+		r.setRekeyCondition(ctx, pvc, corev1.ConditionFalse, RekeyReasonFailed,
+			fmt.Sprintf("Failed to query the key of the volume: %v", err))
+		return false
+	}
+	// The PVC controller encrypts the volume with the key of the class first.
+	if !isKeyOfEncryptionClass(existingKeyID, encClass) {
+		r.setRekeyCondition(ctx, pvc, corev1.ConditionFalse, RekeyReasonPending,
+			fmt.Sprintf("The volume is not encrypted with the key of EncryptionClass %s yet", encClass.Name))
+		return false
+	}
+
+	r.setRekeyCondition(ctx, pvc, corev1.ConditionFalse, RekeyReasonInProgress,
+		fmt.Sprintf("Rekeying the volume with the key of EncryptionClass %s", encClass.Name))
+	updateSpec := &cnstypes.CnsVolumeCryptoUpdateSpec{
+		VolumeId: cnstypes.CnsVolumeId{
+			Id: volumeID,
+		},
+		Profile: []vimtypes.BaseVirtualMachineProfileSpec{
+			&vimtypes.VirtualMachineDefinedProfileSpec{
+				ProfileId: profileID,
+			},
+		},
+		DisksCrypto: &vimtypes.DiskCryptoSpec{
+			Crypto: &vimtypes.CryptoSpecShallowRecrypt{
+				NewKeyId: vimtypes.CryptoKeyId{
+					KeyId: encClass.Spec.KeyID,
+					ProviderId: &vimtypes.KeyProviderId{
+						Id: encClass.Spec.KeyProvider,
+					},
+				},
+			},
+		},
+	}
+	if err := r.volumeManager.UpdateVolumeCrypto(ctx, updateSpec); err != nil {
+		r.logger.Errorf("Failed to rekey volume %s of PVC %s/%s: %v", volumeID, pvc.Namespace, pvc.Name, err)
+		r.setRekeyCondition(ctx, pvc, corev1.ConditionFalse, RekeyReasonFailed,
+			fmt.Sprintf("Failed to rekey the volume: %v", err))
+		return false
+	}
+
+	// The key is generated by the key provider when the class has no key ID.
+	keyID := encClass.Spec.KeyID
+	if newKeyID, err := csicommon.QueryVolumeCryptoKeyByID(ctx, r.volumeManager, volumeID); err != nil {
+		r.logger.Warnf("Failed to query the key of volume %s after its rekey: %v", volumeID, err)
+	} else if newKeyID != nil {
+		keyID = newKeyID.KeyId
+	}
+
+	rekeyTime := r.now()
+	patch := client.MergeFrom(pvc.DeepCopy())
+	metav1.SetMetaDataAnnotation(&pvc.ObjectMeta, crypto.PVCLastRekeyTimeAnnotationName,
+		rekeyTime.UTC().Format(time.RFC3339))
+	metav1.SetMetaDataAnnotation(&pvc.ObjectMeta, crypto.PVCLastRekeyKeyIDAnnotationName, keyID)
+	if err := r.Patch(ctx, pvc, patch); err != nil {
+		// The volume is rekeyed again once due according to its previous
+		// rekey time.
+		r.logger.Errorf("Failed to record the rekey of volume %s in PVC %s/%s: %v",
+			volumeID, pvc.Namespace, pvc.Name, err)
+	}
+	r.logger.Infof("Rekeyed volume %s of PVC %s/%s with key %q of provider %q", volumeID,
+		pvc.Namespace, pvc.Name, keyID, encClass.Spec.KeyProvider)
+	r.setRekeyCondition(ctx, pvc, corev1.ConditionTrue, RekeyReasonSucceeded,
+		fmt.Sprintf("The volume was rekeyed with key %q", keyID))
+	return true
+}
+
+// setRekeyCondition sets the RekeyConditionType condition of the PVC. The
+// probe time of the condition is the time of the last rekey attempt.
+func (r *rekeyReconciler) setRekeyCondition(ctx context.Context, pvc *corev1.PersistentVolumeClaim,
+	status corev1.ConditionStatus, reason, message string) {
+	now := metav1.NewTime(r.now())
+	patch := client.MergeFromWithOptions(pvc.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	condition := getRekeyCondition(pvc)
+	if condition == nil {
+		pvc.Status.Conditions = append(pvc.Status.Conditions, corev1.PersistentVolumeClaimCondition{
+			Type: RekeyConditionType,
+		})
+		condition = &pvc.Status.Conditions[len(pvc.Status.Conditions)-1]
+	}
+	if condition.Status != status {
+		condition.LastTransitionTime = now
+	}
+	condition.Status = status
+	condition.Reason = reason
+	condition.Message = message
+	condition.LastProbeTime = now
+	if err := r.Status().Patch(ctx, pvc, patch); err != nil {
+		r.logger.Errorf("Failed to set the %s condition of PVC %s/%s to %s: %v",
+			RekeyConditionType, pvc.Namespace, pvc.Name, reason, err)
+	}
+}
+
+// getRekeyPolicy returns the interval between the rekeys of the volumes of
+// the EncryptionClass, zero if they are not rekeyed, and the maximum number of
+// volumes rekeyed at the same time.
+func getRekeyPolicy(encClass *byokv1.EncryptionClass) (time.Duration, int, error) {
+	value, ok := encClass.Annotations[crypto.EncryptionClassRekeyIntervalAnnotationName]
+	if !ok {
+		return 0, 0, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days <= 0 {
+		return 0, 0, fmt.Errorf("annotation %s must be a positive number of days, got %q",
+			crypto.EncryptionClassRekeyIntervalAnnotationName, value)
+	}
+
+	maxConcurrency := defaultRekeyMaxConcurrency
+	if value, ok := encClass.Annotations[crypto.EncryptionClassRekeyMaxConcurrencyAnnotationName]; ok {
+		maxConcurrency, err = strconv.Atoi(value)
+		if err != nil || maxConcurrency <= 0 {
+			return 0, 0, fmt.Errorf("annotation %s must be a positive number, got %q",
+				crypto.EncryptionClassRekeyMaxConcurrencyAnnotationName, value)
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour, maxConcurrency, nil
+}
+
+// rekeyDueTime returns the time at which the volume of the PVC is due for
+// rekey: interval after its last rekey, or after the creation of the PVC if it
+// was never rekeyed. A volume which failed or could not be rekeyed is retried
+// after rekeyRetryInterval.
+func rekeyDueTime(pvc *corev1.PersistentVolumeClaim, interval time.Duration) time.Time {
+	lastRekey := pvc.CreationTimestamp.Time
+	if value, ok := pvc.Annotations[crypto.PVCLastRekeyTimeAnnotationName]; ok {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			lastRekey = t
+		}
+	}
+	dueAt := lastRekey.Add(interval)
+
+	// A rekey left in progress was interrupted, and is retried right away.
+	if condition := getRekeyCondition(pvc); condition != nil &&
+		condition.Status == corev1.ConditionFalse && condition.Reason != RekeyReasonInProgress {
+		if retryAt := condition.LastProbeTime.Add(rekeyRetryInterval); retryAt.After(dueAt) {
+			dueAt = retryAt
+		}
+	}
+	return dueAt
+}
+
+// getRekeyCondition returns the RekeyConditionType condition of the PVC, or
+// nil if it has none.
+func getRekeyCondition(pvc *corev1.PersistentVolumeClaim) *corev1.PersistentVolumeClaimCondition {
+	for i := range pvc.Status.Conditions {
+		if pvc.Status.Conditions[i].Type == RekeyConditionType {
+			return &pvc.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// isKeyOfEncryptionClass reports whether the key of a volume is the key of the
+// EncryptionClass, or a key of its key provider if the class has no key ID.
+func isKeyOfEncryptionClass(keyID *vimtypes.CryptoKeyId, encClass *byokv1.EncryptionClass) bool {
+	if keyID == nil || keyID.ProviderId == nil || keyID.ProviderId.Id != encClass.Spec.KeyProvider {
+		return false
+	}
+	return encClass.Spec.KeyID == "" || keyID.KeyId == encClass.Spec.KeyID
+}
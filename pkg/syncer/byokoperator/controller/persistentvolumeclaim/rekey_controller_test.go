@@ -0,0 +1,331 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package persistentvolumeclaim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	byokv1 "github.com/vmware-tanzu/vm-operator/external/byok/api/v1alpha1"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vimtypes "github.com/vmware/govmomi/vim25/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/crypto"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	csicommon "sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	cnsoperatortypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer/cnsoperator/types"
+)
+
+// fakeCryptoVolumeManager is a volume.Manager holding the keys of the volumes,
+// scoped to what the rekey controller needs (QueryVolumeInfo /
+// UpdateVolumeCrypto). The key provider generates a new key on each rekey
+// without a key ID.
+type fakeCryptoVolumeManager struct {
+	volume.Manager
+
+	mutex     sync.Mutex
+	keys      map[string]vimtypes.CryptoKeyId
+	updates   []string
+	updateErr error
+}
+
+func (m *fakeCryptoVolumeManager) QueryVolumeInfo(_ context.Context,
+	volumeIDs []cnstypes.CnsVolumeId) (*cnstypes.CnsQueryVolumeInfoResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	backing := &vimtypes.BaseConfigInfoDiskFileBackingInfo{}
+	if key, ok := m.keys[volumeIDs[0].Id]; ok {
+		backing.KeyId = &key
+	}
+	return &cnstypes.CnsQueryVolumeInfoResult{
+		VolumeInfo: &cnstypes.CnsBlockVolumeInfo{
+			VStorageObject: vimtypes.VStorageObject{
+				Config: vimtypes.VStorageObjectConfigInfo{
+					BaseConfigInfo: vimtypes.BaseConfigInfo{Backing: backing},
+				},
+			},
+		},
+	}, nil
+}
+
+func (m *fakeCryptoVolumeManager) UpdateVolumeCrypto(_ context.Context,
+	spec *cnstypes.CnsVolumeCryptoUpdateSpec) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.updateErr != nil {
+		return m.updateErr
+	}
+	recrypt, ok := spec.DisksCrypto.Crypto.(*vimtypes.CryptoSpecShallowRecrypt)
+	if !ok {
+		return fmt.Errorf("unexpected crypto spec %T", spec.DisksCrypto.Crypto)
+	}
+	m.updates = append(m.updates, spec.VolumeId.Id)
+	newKeyID := recrypt.NewKeyId
+	if newKeyID.KeyId == "" {
+		newKeyID.KeyId = fmt.Sprintf("generated-%d", len(m.updates))
+	}
+	m.keys[spec.VolumeId.Id] = newKeyID
+	return nil
+}
+
+// newRekeyTestPVC returns a bound PVC of the EncryptionClass created at
+// creationTime, and its PV.
+func newRekeyTestPVC(name, encClassName string, creationTime time.Time) (*corev1.PersistentVolumeClaim,
+	*corev1.PersistentVolume) {
+	storageClassName := "encrypted"
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(creationTime),
+			Annotations: map[string]string{
+				crypto.PVCEncryptionClassAnnotationName: encClassName,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClassName,
+			VolumeName:       "pv-" + name,
+		},
+	}
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "pv-" + name,
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{
+					Driver:       csicommon.VSphereCSIDriverName,
+					VolumeHandle: "volume-" + name,
+				},
+			},
+		},
+	}
+	return pvc, pv
+}
+
+func newRekeyReconciler(t *testing.T, volumeManager volume.Manager, now time.Time,
+	objs ...client.Object) (*rekeyReconciler, client.Client) {
+	scheme := createTestScheme()
+	require.NoError(t, byokv1.AddToScheme(scheme))
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithStatusSubresource(&corev1.PersistentVolumeClaim{}).Build()
+	r := &rekeyReconciler{
+		reconciler: reconciler{
+			Client: fakeClient,
+			logger: logger.GetLoggerWithNoContext().Named("test"),
+			cryptoClient: &fakeCryptoClient{
+				isEncryptedStorageClassResult:  true,
+				isEncryptedStorageClassProfile: "profile-1",
+			},
+			volumeManager: volumeManager,
+		},
+		now: func() time.Time { return now },
+	}
+	return r, fakeClient
+}
+
+func TestGetRekeyPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		interval       time.Duration
+		maxConcurrency int
+		wantErr        bool
+	}{
+		{
+			name: "no rotation policy",
+		},
+		{
+			name: "interval with default concurrency",
+			annotations: map[string]string{
+				crypto.EncryptionClassRekeyIntervalAnnotationName: "90",
+			},
+			interval:       90 * 24 * time.Hour,
+			maxConcurrency: defaultRekeyMaxConcurrency,
+		},
+		{
+			name: "interval and concurrency",
+			annotations: map[string]string{
+				crypto.EncryptionClassRekeyIntervalAnnotationName:       "30",
+				crypto.EncryptionClassRekeyMaxConcurrencyAnnotationName: "5",
+			},
+			interval:       30 * 24 * time.Hour,
+			maxConcurrency: 5,
+		},
+		{
+			name: "invalid interval",
+			annotations: map[string]string{
+				crypto.EncryptionClassRekeyIntervalAnnotationName: "0",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid concurrency",
+			annotations: map[string]string{
+				crypto.EncryptionClassRekeyIntervalAnnotationName:       "30",
+				crypto.EncryptionClassRekeyMaxConcurrencyAnnotationName: "many",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encClass := &byokv1.EncryptionClass{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+			}
+			interval, maxConcurrency, err := getRekeyPolicy(encClass)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.interval, interval)
+			assert.Equal(t, tt.maxConcurrency, maxConcurrency)
+		})
+	}
+}
+
+// TestRekeyReconcile verifies that the volumes due for rekey are rekeyed in
+// batches of the maximum concurrency of the class, and that the volumes not
+// due, attached to a VM, or not encrypted with the key of the class yet are
+// not rekeyed.
+func TestRekeyReconcile(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	encClass := &byokv1.EncryptionClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-encryption-class",
+			Namespace: "default",
+			Annotations: map[string]string{
+				crypto.EncryptionClassRekeyIntervalAnnotationName:       "30",
+				crypto.EncryptionClassRekeyMaxConcurrencyAnnotationName: "1",
+			},
+		},
+		Spec: byokv1.EncryptionClassSpec{KeyProvider: "provider-1"},
+	}
+	oldest, oldestPV := newRekeyTestPVC("oldest", encClass.Name, now.AddDate(0, 0, -60))
+	old, oldPV := newRekeyTestPVC("old", encClass.Name, now.AddDate(0, 0, -90))
+	old.Annotations[crypto.PVCLastRekeyTimeAnnotationName] = now.AddDate(0, 0, -40).Format(time.RFC3339)
+	recent, recentPV := newRekeyTestPVC("recent", encClass.Name, now.AddDate(0, 0, -90))
+	recent.Annotations[crypto.PVCLastRekeyTimeAnnotationName] = now.AddDate(0, 0, -20).Format(time.RFC3339)
+	attached, attachedPV := newRekeyTestPVC("attached", encClass.Name, now.AddDate(0, 0, -90))
+	attached.Annotations[cnsoperatortypes.UsedByVMAnnotationPrefix+"vm-uuid-1"] = ""
+	otherKey, otherKeyPV := newRekeyTestPVC("other-key", encClass.Name, now.AddDate(0, 0, -90))
+
+	volumeManager := &fakeCryptoVolumeManager{keys: map[string]vimtypes.CryptoKeyId{
+		"volume-oldest":    {KeyId: "key-1", ProviderId: &vimtypes.KeyProviderId{Id: "provider-1"}},
+		"volume-old":       {KeyId: "key-2", ProviderId: &vimtypes.KeyProviderId{Id: "provider-1"}},
+		"volume-recent":    {KeyId: "key-3", ProviderId: &vimtypes.KeyProviderId{Id: "provider-1"}},
+		"volume-attached":  {KeyId: "key-4", ProviderId: &vimtypes.KeyProviderId{Id: "provider-1"}},
+		"volume-other-key": {KeyId: "key-5", ProviderId: &vimtypes.KeyProviderId{Id: "provider-2"}},
+	}}
+	r, fakeClient := newRekeyReconciler(t, volumeManager, now, encClass, oldest, oldestPV, old, oldPV,
+		recent, recentPV, attached, attachedPV, otherKey, otherKeyPV)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: encClass.Name}}
+
+	// One volume is rekeyed per reconcile, the most overdue first.
+	var results []ctrl.Result
+	for i := 0; i < 4; i++ {
+		result, err := r.Reconcile(ctx, req)
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+	assert.Equal(t, []string{"volume-oldest", "volume-old"}, volumeManager.updates)
+	for _, result := range results[:3] {
+		assert.Equal(t, rekeyBatchInterval, result.RequeueAfter)
+	}
+	// The attached volume and the volume with another key are retried first.
+	assert.Equal(t, rekeyRetryInterval, results[3].RequeueAfter)
+
+	getPVC := func(name string) *corev1.PersistentVolumeClaim {
+		pvc := &corev1.PersistentVolumeClaim{}
+		require.NoError(t, fakeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, pvc))
+		return pvc
+	}
+	pvc := getPVC("oldest")
+	assert.Equal(t, now.Format(time.RFC3339), pvc.Annotations[crypto.PVCLastRekeyTimeAnnotationName])
+	assert.Equal(t, "generated-1", pvc.Annotations[crypto.PVCLastRekeyKeyIDAnnotationName])
+	condition := getRekeyCondition(pvc)
+	require.NotNil(t, condition)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.Equal(t, RekeyReasonSucceeded, condition.Reason)
+
+	assert.Equal(t, "generated-2", getPVC("old").Annotations[crypto.PVCLastRekeyKeyIDAnnotationName])
+	assert.Nil(t, getRekeyCondition(getPVC("recent")))
+	assert.Equal(t, RekeyReasonDeferred, getRekeyCondition(getPVC("attached")).Reason)
+	assert.Equal(t, RekeyReasonPending, getRekeyCondition(getPVC("other-key")).Reason)
+}
+
+// TestRekeyReconcileFailure verifies that a failed rekey is reported in the
+// condition of the PVC, and retried after rekeyRetryInterval.
+func TestRekeyReconcileFailure(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	encClass := &byokv1.EncryptionClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-encryption-class",
+			Namespace: "default",
+			Annotations: map[string]string{
+				crypto.EncryptionClassRekeyIntervalAnnotationName: "30",
+			},
+		},
+		Spec: byokv1.EncryptionClassSpec{KeyProvider: "provider-1", KeyID: "key-1"},
+	}
+	pvc, pv := newRekeyTestPVC("failing", encClass.Name, now.AddDate(0, 0, -60))
+	volumeManager := &fakeCryptoVolumeManager{
+		keys: map[string]vimtypes.CryptoKeyId{
+			"volume-failing": {KeyId: "key-1", ProviderId: &vimtypes.KeyProviderId{Id: "provider-1"}},
+		},
+		updateErr: errors.New("key provider unavailable"),
+	}
+	r, fakeClient := newRekeyReconciler(t, volumeManager, now, encClass, pvc, pv)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: encClass.Name}}
+
+	result, err := r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, rekeyRetryInterval, result.RequeueAfter)
+
+	require.NoError(t, fakeClient.Get(ctx, client.ObjectKeyFromObject(pvc), pvc))
+	condition := getRekeyCondition(pvc)
+	require.NotNil(t, condition)
+	assert.Equal(t, corev1.ConditionFalse, condition.Status)
+	assert.Equal(t, RekeyReasonFailed, condition.Reason)
+	assert.Contains(t, condition.Message, "key provider unavailable")
+	assert.NotContains(t, pvc.Annotations, crypto.PVCLastRekeyTimeAnnotationName)
+
+	// The volume is rekeyed once the retry interval elapsed.
+	volumeManager.updateErr = nil
+	r.now = func() time.Time { return now.Add(rekeyRetryInterval) }
+	_, err = r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"volume-failing"}, volumeManager.updates)
+	require.NoError(t, fakeClient.Get(ctx, client.ObjectKeyFromObject(pvc), pvc))
+	assert.Equal(t, "key-1", pvc.Annotations[crypto.PVCLastRekeyKeyIDAnnotationName])
+	assert.Equal(t, RekeyReasonSucceeded, getRekeyCondition(pvc).Reason)
+}
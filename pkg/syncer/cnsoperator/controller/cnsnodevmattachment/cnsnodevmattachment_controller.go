@@ -232,6 +232,7 @@ func (r *ReconcileCnsNodeVMAttachment) Reconcile(ctx context.Context,
 	request reconcile.Request) (reconcile.Result, error) {
 	start := time.Now()
 	ctx = logger.NewContextWithLogger(ctx)
+	ctx = cnsvsphere.WithRequestPriority(ctx, cnsvsphere.PriorityAttachDetach)
 	reconcileLog := logger.GetLogger(ctx)
 	reconcileLog.Infof("Received Reconcile for request: %q", request.NamespacedName)
 	// Start a goroutine to listen for context cancellation
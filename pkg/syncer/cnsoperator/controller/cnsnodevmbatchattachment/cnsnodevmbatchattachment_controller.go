@@ -242,6 +242,7 @@ func (r *Reconciler) Reconcile(ctx context.Context,
 	defer cancel()
 
 	batchAttachCtx = logger.NewContextWithLogger(batchAttachCtx)
+	batchAttachCtx = cnsvsphere.WithRequestPriority(batchAttachCtx, cnsvsphere.PriorityAttachDetach)
 	log := logger.GetLogger(batchAttachCtx)
 
 	// Initialize backOffDuration for the instance, if required.
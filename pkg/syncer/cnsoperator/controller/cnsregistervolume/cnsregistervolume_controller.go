@@ -104,6 +104,13 @@ var (
 func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
 	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager) error {
 	ctx, log := logger.GetNewContextWithLogger()
+	if clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.StaticVolumeRegistration) {
+			log.Debug("Not initializing the CnsRegisterVolume Controller as static volume registration is disabled")
+			return nil
+		}
+		return addForVanilla(ctx, mgr, configInfo, volumeManager)
+	}
 	if clusterFlavor != cnstypes.CnsClusterFlavorWorkload {
 		log.Debug("Not initializing the CnsRegisterVolume Controller as its a non-WCP CSI deployment")
 		return nil
@@ -167,7 +174,7 @@ func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
 		},
 	)
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: apis.GroupName})
-	reconciler, err := newReconciler(mgr, configInfo, volumeManager, recorder, volumeInfoService)
+	reconciler, err := newReconciler(mgr, clusterFlavor, configInfo, volumeManager, recorder, volumeInfoService)
 	if err != nil {
 		log.Errorf("Failed to create reconciler. Err: %v", err)
 		return err
@@ -176,8 +183,8 @@ func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
 }
 
 // newReconciler returns a new reconcile.Reconciler.
-func newReconciler(mgr manager.Manager, configInfo *commonconfig.ConfigurationInfo,
-	volumeManager volumes.Manager, recorder record.EventRecorder,
+func newReconciler(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
+	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager, recorder record.EventRecorder,
 	volumeInfoService cnsvolumeinfo.VolumeInfoService) (reconcile.Reconciler, error) {
 	ctx, log := logger.GetNewContextWithLogger()
 	k8sclient, err := k8s.NewClient(ctx)
@@ -186,7 +193,7 @@ func newReconciler(mgr manager.Manager, configInfo *commonconfig.ConfigurationIn
 		return nil, err
 	}
 	return &ReconcileCnsRegisterVolume{client: mgr.GetClient(), scheme: mgr.GetScheme(),
-		configInfo: configInfo, volumeManager: volumeManager, recorder: recorder,
+		clusterFlavor: clusterFlavor, configInfo: configInfo, volumeManager: volumeManager, recorder: recorder,
 		volumeInfoService: volumeInfoService, k8sclient: k8sclient}, nil
 }
 
@@ -221,6 +228,7 @@ type ReconcileCnsRegisterVolume struct {
 	// that reads objects from the cache and writes to the apiserver.
 	client            client.Client
 	scheme            *runtime.Scheme
+	clusterFlavor     cnstypes.CnsClusterFlavor
 	configInfo        *commonconfig.ConfigurationInfo
 	volumeManager     volumes.Manager
 	recorder          record.EventRecorder
@@ -309,6 +317,9 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 		setInstanceError(ctx, r, instance, msg)
 		return reconcile.Result{RequeueAfter: timeout}, nil
 	}
+	if r.clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		return r.reconcileForVanilla(ctx, instance, request, timeout)
+	}
 
 	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, r.configInfo, false)
 	if err != nil {
@@ -575,7 +586,7 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 	// resolver, so build the PV node affinity from it directly (covering all configurations,
 	// including non-stretched supervisors). Otherwise fall back to the shared-datastore topology.
 	if isHostLocal {
-		pvNodeAffinity = buildNodeAffinityFromSegments(datastoreAccessibleTopology)
+		pvNodeAffinity = util.BuildNodeAffinityFromSegments(datastoreAccessibleTopology)
 	} else if syncer.IsPodVMOnStretchSupervisorFSSEnabled {
 		if workloadDomainIsolationEnabled {
 			datastoreAccessibleTopology, err = topologyMgr.GetTopologyInfoFromNodes(ctx,
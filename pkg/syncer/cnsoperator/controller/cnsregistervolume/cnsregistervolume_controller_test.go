@@ -67,6 +67,7 @@ import (
 	k8s "sigs.k8s.io/vsphere-csi-driver/v3/pkg/kubernetes"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer"
 	cnsoperatortypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer/cnsoperator/types"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer/cnsoperator/util"
 )
 
 type mockVolumeManager struct {
@@ -4088,35 +4089,6 @@ func TestClearKeepAfterDeleteVm_AnnotationUpdateFailure_ReturnsError(t *testing.
 
 // --- Host-local volume registration tests ---
 
-// TestBuildNodeAffinityFromSegments verifies that PV node affinity is built with both the
-// zone and hostname keys as required node-selector terms, and that an empty input yields nil.
-func TestBuildNodeAffinityFromSegments(t *testing.T) {
-	// Empty input -> nil affinity.
-	assert.Nil(t, buildNodeAffinityFromSegments(nil))
-	assert.Nil(t, buildNodeAffinityFromSegments([]map[string]string{}))
-
-	segments := []map[string]string{
-		{
-			corev1.LabelTopologyZone: "zone-a",
-			corev1.LabelHostname:     "esx-node-1",
-		},
-	}
-	affinity := buildNodeAffinityFromSegments(segments)
-	assert.NotNil(t, affinity)
-	assert.NotNil(t, affinity.Required)
-	assert.Len(t, affinity.Required.NodeSelectorTerms, 1)
-
-	// Collect the match expressions into a key->value map for order-independent assertions.
-	got := map[string]string{}
-	for _, expr := range affinity.Required.NodeSelectorTerms[0].MatchExpressions {
-		assert.Equal(t, corev1.NodeSelectorOpIn, expr.Operator)
-		assert.Len(t, expr.Values, 1)
-		got[expr.Key] = expr.Values[0]
-	}
-	assert.Equal(t, "zone-a", got[corev1.LabelTopologyZone])
-	assert.Equal(t, "esx-node-1", got[corev1.LabelHostname])
-}
-
 // newFakeVCWithClient returns a VirtualCenter with a non-nil client so that object.NewHostSystem
 // can be constructed in tests (the host method itself is patched, so no network call is made).
 func newFakeVCWithClient() *cnsvsphere.VirtualCenter {
@@ -4422,3 +4394,81 @@ func TestReconcileHostLocalTopologyError(t *testing.T) {
 	assert.True(t, *setErrCalled, "setInstanceError should be called when host-local topology cannot be derived")
 	assert.True(t, *cleanupCalled, "cleanupCNSVolume should be called when host-local topology cannot be derived")
 }
+
+// patchVanillaVirtualCenter patches the lookup of the vCenter of the volume manager on a vanilla cluster.
+func patchVanillaVirtualCenter(patches *gomonkey.Patches) {
+	patches.ApplyFunc(cnsvsphere.GetVirtualCenterConfig, func(_ context.Context,
+		_ *config.Config) (*cnsvsphere.VirtualCenterConfig, error) {
+		return &cnsvsphere.VirtualCenterConfig{Host: "dummy-vcenter"}, nil
+	})
+	patches.ApplyFunc(cnsvsphere.GetVirtualCenterInstanceForVCenterConfig, func(_ context.Context,
+		_ *cnsvsphere.VirtualCenterConfig, _ bool) (*cnsvsphere.VirtualCenter, error) {
+		return newFakeVCWithClient(), nil
+	})
+}
+
+// TestReconcileVanillaVolumeNotAccessibleToNodes verifies that on a vanilla cluster, a volume on a
+// datastore which no node can access fails registration permanently (no requeue) and is cleaned up.
+func TestReconcileVanillaVolumeNotAccessibleToNodes(t *testing.T) {
+	ctx := context.Background()
+	patches := gomonkey.NewPatches()
+	defer patches.Reset()
+
+	r, setErrCalled, cleanupCalled := newHostLocalReconcileFixture(patches)
+	defer func() { fssEnabledOverride = nil }()
+	r.clusterFlavor = cnstypes.CnsClusterFlavorVanilla
+	patchVanillaVirtualCenter(patches)
+	r.k8sclient = k8sfake.NewClientset()
+
+	getVanillaAccessibleTopologyFn = func(_ context.Context, _ *cnsvsphere.VirtualCenter,
+		_ string) ([]map[string]string, error) {
+		return nil, util.ErrDatastoreNotAccessibleToNodes
+	}
+	defer func() { getVanillaAccessibleTopologyFn = getVanillaAccessibleTopology }()
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-volume", Namespace: "test-ns"}}
+	result, err := r.Reconcile(ctx, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, result)
+	assert.True(t, *setErrCalled, "setInstanceError should be called when no node can access the volume")
+	assert.True(t, *cleanupCalled, "cleanupCNSVolume should be called when no node can access the volume")
+}
+
+// TestReconcileVanillaStorageClassOfAnotherProvisioner verifies that on a vanilla cluster, a
+// StorageClass which is not provisioned by the vSphere CSI driver is rejected before the volume
+// is registered with CNS.
+func TestReconcileVanillaStorageClassOfAnotherProvisioner(t *testing.T) {
+	ctx := context.Background()
+	patches := gomonkey.NewPatches()
+	defer patches.Reset()
+
+	r, setErrCalled, _ := newHostLocalReconcileFixture(patches)
+	defer func() { fssEnabledOverride = nil }()
+	r.clusterFlavor = cnstypes.CnsClusterFlavorVanilla
+	patchVanillaVirtualCenter(patches)
+	r.k8sclient = k8sfake.NewClientset(&storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "other-sc"},
+		Provisioner: "other.csi.example.com",
+	})
+	createVolumeCalled := false
+	r.volumeManager = &mockVolumeManager{
+		createVolumeFunc: func(_ context.Context, _ *cnstypes.CnsVolumeCreateSpec,
+			_ interface{}) (*cnsvolume.CnsVolumeInfo, string, error) {
+			createVolumeCalled = true
+			return &cnsvolume.CnsVolumeInfo{VolumeID: cnstypes.CnsVolumeId{Id: "dummy-volume-id"}}, "", nil
+		},
+	}
+	instance := &cnsregistervolumev1alpha1.CnsRegisterVolume{}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-volume", Namespace: "test-ns"}}
+	assert.NoError(t, r.client.Get(ctx, req.NamespacedName, instance))
+	instance.Spec.StorageClassName = "other-sc"
+	assert.NoError(t, r.client.Update(ctx, instance))
+
+	result, err := r.Reconcile(ctx, req)
+
+	assert.NoError(t, err)
+	assert.NotZero(t, result.RequeueAfter)
+	assert.True(t, *setErrCalled, "setInstanceError should be called for a StorageClass of another provisioner")
+	assert.False(t, createVolumeCalled, "the volume should not be registered with CNS")
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsregistervolume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apis "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator"
+	cnsregistervolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/cnsregistervolume/v1alpha1"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v3/pkg/kubernetes"
+	cnsoperatortypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer/cnsoperator/types"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer/cnsoperator/util"
+)
+
+// getVanillaAccessibleTopologyFn is a function variable so unit tests can
+// stub the node VM and topology lookups.
+var getVanillaAccessibleTopologyFn = getVanillaAccessibleTopology
+
+// addForVanilla creates a new CnsRegisterVolume Controller for vanilla
+// clusters and adds it to the Manager.
+func addForVanilla(ctx context.Context, mgr manager.Manager, configInfo *commonconfig.ConfigurationInfo,
+	volumeManager volumes.Manager) error {
+	log := logger.GetLogger(ctx)
+	// Multi-writer raw block volumes are the shared block volumes of vanilla
+	// clusters.
+	isSharedDiskEnabled = commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.MultiWriterBlockVolume)
+	if configInfo.Cfg.Labels.TopologyCategories != "" || configInfo.Cfg.Labels.Zone != "" {
+		var err error
+		topologyMgr, err = commonco.ContainerOrchestratorUtility.InitTopologyServiceInController(ctx)
+		if err != nil {
+			log.Errorf("failed to init topology manager. err: %v", err)
+			return err
+		}
+	}
+	k8sclient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("Creating Kubernetes client failed. Err: %v", err)
+		return err
+	}
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sclient.CoreV1().Events(""),
+		},
+	)
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: apis.GroupName})
+	reconciler, err := newReconciler(mgr, cnstypes.CnsClusterFlavorVanilla, configInfo, volumeManager,
+		recorder, nil)
+	if err != nil {
+		log.Errorf("Failed to create reconciler. Err: %v", err)
+		return err
+	}
+	return add(mgr, reconciler)
+}
+
+// reconcileForVanilla registers the volume of the CnsRegisterVolume instance
+// with CNS on a vanilla cluster, and creates the PV and PVC bound to it. The
+// node affinity of the PV is computed from the nodes which can access the
+// datastore of the volume.
+func (r *ReconcileCnsRegisterVolume) reconcileForVanilla(ctx context.Context,
+	instance *cnsregistervolumev1alpha1.CnsRegisterVolume, request reconcile.Request,
+	timeout time.Duration) (reconcile.Result, error) {
+	log := logger.GetLogger(ctx)
+	// Use the vCenter of the volume manager, without re-registering the
+	// vCenters of a multi vCenter deployment.
+	vcConfig, err := cnsvsphere.GetVirtualCenterConfig(ctx, r.configInfo.Cfg)
+	if err != nil {
+		log.Errorf("Failed to get VirtualCenterConfig with error: %+v", err)
+		setInstanceError(ctx, r, instance, "Unable to connect to VC for volume registration")
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	vc, err := cnsvsphere.GetVirtualCenterInstanceForVCenterConfig(ctx, vcConfig, false)
+	if err != nil {
+		log.Errorf("Failed to get virtual center instance with error: %+v", err)
+		setInstanceError(ctx, r, instance, "Unable to connect to VC for volume registration")
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	k8sclient := r.k8sclient
+
+	// Validate the StorageClass before registering the volume, so that a
+	// typo in the spec does not leave a CNS volume behind.
+	storageClassName := instance.Spec.StorageClassName
+	if storageClassName != "" {
+		sc, err := k8sclient.StorageV1().StorageClasses().Get(ctx, storageClassName, metav1.GetOptions{})
+		if err != nil {
+			msg := fmt.Sprintf("Failed to fetch StorageClass: %q with error: %+v", storageClassName, err)
+			log.Error(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		if sc.Provisioner != cnsoperatortypes.VSphereCSIDriverName {
+			msg := fmt.Sprintf("StorageClass: %q is not provisioned by %s", storageClassName,
+				cnsoperatortypes.VSphereCSIDriverName)
+			log.Error(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+	}
+
+	createSpec := constructCreateSpecForInstance(ctx, r, instance, vc.Config.Host, false)
+	log.Infof("Creating CNS volume: %+v for CnsRegisterVolume request with name: %q on namespace: %q",
+		instance, instance.Name, instance.Namespace)
+	volInfo, _, err := r.volumeManager.CreateVolume(ctx, createSpec, nil)
+	if err != nil {
+		msg := fmt.Sprintf("failed to create CNS volume. Error: %v", err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	volumeID := volInfo.VolumeID.Id
+	pvName := staticPvNamePrefix + volumeID
+	if instance.Spec.DiskURLPath != "" {
+		// The volume ID of a disk registered with its path is only known now.
+		if existingPVName, found := commonco.ContainerOrchestratorUtility.GetPVNameFromCSIVolumeID(
+			volumeID); found && existingPVName != pvName {
+			msg := fmt.Sprintf("PV: %q with the volume ID: %q for volume path: %q "+
+				"is already present. Can not create multiple PV with same disk.", existingPVName, volumeID,
+				instance.Spec.DiskURLPath)
+			log.Error(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+	}
+	log.Infof("Created CNS volume with volumeID: %s", volumeID)
+
+	querySelection := cnstypes.CnsQuerySelection{
+		Names: []string{
+			string(cnstypes.QuerySelectionNameTypeVolumeType),
+			string(cnstypes.QuerySelectionNameTypeDataStoreUrl),
+			string(cnstypes.QuerySelectionNameTypeBackingObjectDetails),
+		},
+	}
+	volume, err := common.QueryVolumeByID(ctx, r.volumeManager, volumeID, &querySelection)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to query CNS volume: %s with error: %+v", volumeID, err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+
+	datastoreAccessibleTopology, err := getVanillaAccessibleTopologyFn(ctx, vc, volume.DatastoreUrl)
+	if err != nil {
+		msg := fmt.Sprintf("failed to find the topology of volume %s on datastore %s. Error: %v",
+			volumeID, volume.DatastoreUrl, err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		if errors.Is(err, util.ErrDatastoreNotAccessibleToNodes) {
+			if err = r.cleanupCNSVolume(ctx, instance, volumeID); err != nil {
+				log.Errorf("Failed to cleanup CNS volume: %s with error: %+v", volumeID, err)
+				return reconcile.Result{RequeueAfter: timeout}, nil
+			}
+			// permanent failure and not requeue.
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	pvNodeAffinity := util.BuildNodeAffinityFromSegments(datastoreAccessibleTopology)
+
+	// Do this check before creating a PV. Otherwise, PVC will be bound to PV
+	// after PV is created even if validation fails.
+	pvc, err := k8sclient.CoreV1().PersistentVolumeClaims(instance.Namespace).Get(ctx,
+		instance.Spec.PvcName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			msg := fmt.Sprintf("Failed to get PVC: %s with error: %+v", instance.Spec.PvcName, err)
+			log.Error(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		pvc = nil
+	} else if pvc.Spec.VolumeName != pvName {
+		msg := fmt.Sprintf("Another PVC: %s already exists in namespace: %s",
+			instance.Spec.PvcName, instance.Namespace)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		if err = r.cleanupCNSVolume(ctx, instance, volumeID); err != nil {
+			log.Errorf("Failed to cleanup CNS volume: %s with error: %+v", volumeID, err)
+		}
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+
+	capacityInMb := volume.BackingObjectDetails.GetCnsBackingObjectDetails().CapacityInMb
+	pvCapacity := *resource.NewQuantity(capacityInMb*common.MbInBytes, resource.BinarySI)
+	accessMode := instance.Spec.AccessMode
+	// Set accessMode to ReadWriteOnce if DiskURLPath is used for import.
+	if accessMode == "" && instance.Spec.DiskURLPath != "" {
+		accessMode = v1.ReadWriteOnce
+	}
+	pv, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			msg := fmt.Sprintf("Failed to get PV: %s with error: %+v", pvName, err)
+			log.Error(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		log.Infof("PV: %s not found. Creating a new PV", pvName)
+		claimRef := &v1.ObjectReference{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+			Namespace:  instance.Namespace,
+			Name:       instance.Spec.PvcName,
+		}
+		pvSpec := getPersistentVolumeSpec(pvName, volumeID, pvCapacity, accessMode, instance.Spec.VolumeMode,
+			storageClassName, claimRef, instance.Namespace, instance.Name)
+		pvSpec.Spec.NodeAffinity = pvNodeAffinity
+		log.Debugf("PV spec is: %+v", pvSpec)
+		pv, err = k8sclient.CoreV1().PersistentVolumes().Create(ctx, pvSpec, metav1.CreateOptions{})
+		if err != nil {
+			log.Errorf("Failed to create PV with spec: %+v. Error: %+v", pvSpec, err)
+			setInstanceError(ctx, r, instance,
+				fmt.Sprintf("Failed to create PV: %s for volume with err: %+v", pvName, err))
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		log.Infof("PV: %s is created successfully", pvName)
+	}
+	// If PV is already bound to a different PVC at this point, then its a
+	// duplicate request.
+	if pv.Status.Phase == v1.VolumeBound && pv.Spec.ClaimRef.Name != instance.Spec.PvcName {
+		log.Errorf("Duplicate Request. There already exists a PV: %s which is bound", pvName)
+		setInstanceError(ctx, r, instance, "Duplicate Request")
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+
+	if pvc == nil {
+		log.Infof("Creating PVC: %s", instance.Spec.PvcName)
+		pvcSpec, err := getPersistentVolumeClaimSpec(ctx, instance.Spec.PvcName, instance.Namespace, capacityInMb,
+			storageClassName, accessMode, *pv.Spec.VolumeMode, pvName, nil, instance)
+		if err != nil {
+			msg := fmt.Sprintf("Failed to create spec for PVC: %q. Error: %v", instance.Spec.PvcName, err)
+			log.Error(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		// The volume mode of the PVC must match the volume mode of the PV for
+		// them to bind.
+		pvcSpec.Spec.VolumeMode = pv.Spec.VolumeMode
+		log.Debugf("PVC spec is: %+v", pvcSpec)
+		pvc, err = k8sclient.CoreV1().PersistentVolumeClaims(instance.Namespace).Create(ctx,
+			pvcSpec, metav1.CreateOptions{})
+		if err != nil {
+			log.Errorf("Failed to create PVC with spec: %+v. Error: %+v", pvcSpec, err)
+			setInstanceError(ctx, r, instance,
+				fmt.Sprintf("Failed to create PVC: %s for volume with err: %+v", instance.Spec.PvcName, err))
+			// Delete PV created above.
+			err = k8sclient.CoreV1().PersistentVolumes().Delete(ctx, pvName, *metav1.NewDeleteOptions(0))
+			if err != nil {
+				log.Errorf("Delete PV %s failed with error: %+v", pvName, err)
+			}
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		log.Infof("PVC: %s is created successfully", instance.Spec.PvcName)
+	}
+	// Watch for PVC to be bound.
+	isBound, err := isPVCBound(ctx, k8sclient, pvc, time.Duration(1*time.Minute))
+	if !isBound {
+		log.Errorf("PVC: %s is not bound. Error: %+v", instance.Spec.PvcName, err)
+		setInstanceError(ctx, r, instance, fmt.Sprintf("PVC: %s is not bound", instance.Spec.PvcName))
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	log.Infof("PVC: %s is bound", instance.Spec.PvcName)
+
+	// Update the instance to indicate the volume registration is successful.
+	msg := fmt.Sprintf("Successfully registered the volume on namespace: %s", instance.Namespace)
+	err = setInstanceSuccess(ctx, r, instance, instance.Spec.PvcName, pvc.UID, msg)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to update CnsRegistered instance with error: %+v", err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	backOffDurationMapMutex.Lock()
+	delete(backOffDuration, request.NamespacedName)
+	backOffDurationMapMutex.Unlock()
+	log.Info(msg)
+	return reconcile.Result{}, nil
+}
+
+// getVanillaAccessibleTopology returns the topology segments from which the
+// given datastore is accessible.
+func getVanillaAccessibleTopology(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+	datastoreURL string) ([]map[string]string, error) {
+	return util.GetVanillaDatastoreAccessibleTopology(ctx, vc, topologyMgr, datastoreURL)
+}
@@ -227,33 +227,6 @@ func getHostLocalAccessibleTopology(ctx context.Context, vc *vsphere.VirtualCent
 	return []map[string]string{segment}, nil
 }
 
-// buildNodeAffinityFromSegments builds a PV VolumeNodeAffinity from topology segments.
-// Each segment contributes one NodeSelectorTerm whose match expressions require every
-// segment key to equal its value (In operator), so both the zone and hostname keys of a
-// host-local segment become required node-selector terms on the PV.
-func buildNodeAffinityFromSegments(segments []map[string]string) *v1.VolumeNodeAffinity {
-	if len(segments) == 0 {
-		return nil
-	}
-	var terms []v1.NodeSelectorTerm
-	for _, segment := range segments {
-		expressions := make([]v1.NodeSelectorRequirement, 0, len(segment))
-		for key, value := range segment {
-			expressions = append(expressions, v1.NodeSelectorRequirement{
-				Key:      key,
-				Operator: v1.NodeSelectorOpIn,
-				Values:   []string{value},
-			})
-		}
-		terms = append(terms, v1.NodeSelectorTerm{MatchExpressions: expressions})
-	}
-	return &v1.VolumeNodeAffinity{
-		Required: &v1.NodeSelector{
-			NodeSelectorTerms: terms,
-		},
-	}
-}
-
 // clearKeepAfterDeleteVmIfNonRemovable clears the keepAfterDeleteVm control flag
 // on the just-registered FCD when the PVC was created by VM Operator with a
 // VirtualMachine DataSourceRef and the matching volume entry on the VM has
@@ -354,9 +327,13 @@ func constructCreateSpecForInstance(ctx context.Context, r *ReconcileCnsRegister
 	} else {
 		clusterIDForVolumeMetadata = r.configInfo.Cfg.Global.ClusterID
 	}
+	clusterFlavor := cnstypes.CnsClusterFlavorWorkload
+	if r.clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		clusterFlavor = cnstypes.CnsClusterFlavorVanilla
+	}
 	containerCluster := vsphere.GetContainerCluster(clusterIDForVolumeMetadata,
 		r.configInfo.Cfg.VirtualCenter[host].User,
-		cnstypes.CnsClusterFlavorWorkload, r.configInfo.Cfg.Global.ClusterDistribution)
+		clusterFlavor, r.configInfo.Cfg.Global.ClusterDistribution)
 	createSpec := &cnstypes.CnsVolumeCreateSpec{
 		Name:       volumeName,
 		VolumeType: common.BlockVolumeType,
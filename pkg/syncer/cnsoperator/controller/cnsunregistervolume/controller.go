@@ -66,6 +66,8 @@ var (
 	// If the reconcile fails, backoff is incremented exponentially.
 	backOffDuration         map[types.NamespacedName]time.Duration
 	backOffDurationMapMutex = sync.Mutex{}
+	// controllerClusterFlavor is the flavor of the cluster the controller runs on.
+	controllerClusterFlavor = cnstypes.CnsClusterFlavorWorkload
 )
 
 // Add creates a new CnsUnregisterVolume Controller and adds it to the Manager,
@@ -74,8 +76,8 @@ var (
 func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
 	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager) error {
 	ctx, log := logger.GetNewContextWithLogger()
-	if clusterFlavor != cnstypes.CnsClusterFlavorWorkload {
-		log.Debug("Not initializing the CnsUnregisterVolume Controller as its a non-WCP CSI deployment")
+	if clusterFlavor != cnstypes.CnsClusterFlavorWorkload && clusterFlavor != cnstypes.CnsClusterFlavorVanilla {
+		log.Debug("Not initializing the CnsUnregisterVolume Controller as its a TKGS CSI deployment")
 		return nil
 	}
 
@@ -86,10 +88,15 @@ func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
 		return err
 	}
 
-	if !coCommonInterface.IsFSSEnabled(ctx, common.WCPMobilityNonDisruptiveImport) {
+	fss := common.WCPMobilityNonDisruptiveImport
+	if clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		fss = common.StaticVolumeRegistration
+	}
+	if !coCommonInterface.IsFSSEnabled(ctx, fss) {
 		log.Infof("Not initializing the CnsUnregisterVolume Controller as this feature is disabled on the cluster")
 		return nil
 	}
+	controllerClusterFlavor = clusterFlavor
 
 	// Initializes kubernetes client.
 	k8sclient, err := k8s.NewClient(ctx)
@@ -23,6 +23,7 @@ import (
 
 	snapshotclient "github.com/kubernetes-csi/external-snapshotter/client/v8/clientset/versioned"
 	vmoperatortypes "github.com/vmware-tanzu/vm-operator/api/v1alpha2"
+	cnstypes "github.com/vmware/govmomi/cns/types"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -70,6 +71,7 @@ var getVolumeUsageInfo = _getVolumeUsageInfo
 // getVolumeUsageInfo checks if the PVC is in use by any resources in the specified namespace.
 // For the sake of efficiency, the function returns as soon as it finds that the volume is in use by any resource.
 // If ignoreVMUsage is set to true, the function skips checking if the volume is in use by any virtual machines.
+// On vanilla clusters, only pods and snapshots are checked.
 func _getVolumeUsageInfo(ctx context.Context, pvcName string, pvcNamespace string,
 	ignoreVMUsage bool) (*volumeUsageInfo, error) {
 	log := logger.GetLogger(ctx)
@@ -101,6 +103,15 @@ func _getVolumeUsageInfo(ctx context.Context, pvcName string, pvcNamespace strin
 		return nil, err
 	}
 
+	// Guest clusters and virtual machines only use volumes of a supervisor cluster.
+	if controllerClusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		volumeUsageInfo.snapshots, volumeUsageInfo.isInUse, err = getSnapshotsForPVC(ctx, pvcName, pvcNamespace, *cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &volumeUsageInfo, nil
+	}
+
 	volumeUsageInfo.guestClusters, volumeUsageInfo.isInUse, err = getGuestClustersForPVC(
 		ctx, pvcName, pvcNamespace, *cfg)
 	if err != nil {
@@ -0,0 +1,552 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumeimport
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	apis "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator"
+	v1a1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/cnsvolumeimport/v1alpha1"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
+	commoncotypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco/types"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v3/pkg/kubernetes"
+	cnsoptypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer/cnsoperator/types"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer/cnsoperator/util"
+)
+
+const (
+	workerThreadEnvVar      = "WORKER_THREADS_VOLUME_IMPORT"
+	defaultMaxWorkerThreads = 1
+	// staticPvNamePrefix is the prefix of the name of the PVs created for the
+	// disks, as for the PVs created by CnsRegisterVolume.
+	staticPvNamePrefix = "static-pv-"
+	// labelVolumeImportName is the label of the PVs created by a CnsVolumeImport
+	// instance, set to the name of the instance.
+	labelVolumeImportName = "cns.vmware.com/volume-import"
+	// statusUpdateInterval is the number of disks processed between two
+	// updates of the status of the instance.
+	statusUpdateInterval = 10
+	// queryVolumeBatchSize is the number of volume IDs queried from CNS at once.
+	queryVolumeBatchSize = 100
+)
+
+var (
+	// backOffDuration is a map of cnsvolumeimport name's to the time after which
+	// a request for this instance will be requeued.
+	// Initialized to 1 second for new instances and for instances whose latest
+	// reconcile operation succeeded.
+	// If the reconcile fails, backoff is incremented exponentially.
+	backOffDuration         map[types.NamespacedName]time.Duration
+	backOffDurationMapMutex = sync.Mutex{}
+	// topologyMgr is the topology service of the cluster. It is nil when the
+	// cluster is not topology aware.
+	topologyMgr commoncotypes.ControllerTopologyService
+)
+
+// Add creates a new CnsVolumeImport Controller and adds it to the Manager,
+// ConfigurationInfo and VirtualCenterTypes. The Manager will set fields on
+// the Controller and Start it when the Manager is Started.
+func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
+	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager) error {
+	ctx, log := logger.GetNewContextWithLogger()
+	if clusterFlavor != cnstypes.CnsClusterFlavorVanilla {
+		log.Debug("Not initializing the CnsVolumeImport Controller as its not a vanilla CSI deployment")
+		return nil
+	}
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.BulkVolumeImport) {
+		log.Infof("Not initializing the CnsVolumeImport Controller as %s FSS is disabled",
+			common.BulkVolumeImport)
+		return nil
+	}
+	if configInfo.Cfg.Labels.TopologyCategories != "" || configInfo.Cfg.Labels.Zone != "" {
+		var err error
+		topologyMgr, err = commonco.ContainerOrchestratorUtility.InitTopologyServiceInController(ctx)
+		if err != nil {
+			log.Errorf("failed to init topology manager. err: %v", err)
+			return err
+		}
+	}
+
+	// Initializes kubernetes client.
+	k8sclient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("Creating Kubernetes client failed. Err: %v", err)
+		return err
+	}
+
+	// eventBroadcaster broadcasts events on CnsVolumeImport instances to the event sink.
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sclient.CoreV1().Events(""),
+		},
+	)
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: apis.GroupName})
+	return add(mgr, newReconciler(mgr, configInfo, volumeManager, recorder, k8sclient))
+}
+
+// newReconciler returns a new reconcile.Reconciler.
+func newReconciler(mgr manager.Manager, configInfo *commonconfig.ConfigurationInfo,
+	volumeManager volumes.Manager, recorder record.EventRecorder, k8sclient clientset.Interface) reconcile.Reconciler {
+	return &Reconciler{
+		client:        mgr.GetClient(),
+		configInfo:    configInfo,
+		volumeManager: volumeManager,
+		recorder:      recorder,
+		k8sclient:     k8sclient,
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler.
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	ctx, log := logger.GetNewContextWithLogger()
+
+	maxWorkerThreads := util.GetMaxWorkerThreads(ctx,
+		workerThreadEnvVar, defaultMaxWorkerThreads)
+	// Create a new controller.
+	err := ctrl.NewControllerManagedBy(mgr).Named("cnsvolumeimport-controller").
+		For(&v1a1.CnsVolumeImport{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxWorkerThreads}).
+		Complete(r)
+	if err != nil {
+		log.Errorf("Failed to build application controller. Err: %v", err)
+		return err
+	}
+
+	backOffDuration = make(map[types.NamespacedName]time.Duration)
+	return nil
+}
+
+// blank assignment to verify that Reconciler implements
+// reconcile.Reconciler.
+var _ reconcile.Reconciler = &Reconciler{}
+
+// Reconciler reconciles a CnsVolumeImport object.
+type Reconciler struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver.
+	client        client.Client
+	configInfo    *commonconfig.ConfigurationInfo
+	volumeManager volumes.Manager
+	recorder      record.EventRecorder
+	k8sclient     clientset.Interface
+}
+
+// Reconcile imports the disks selected by a CnsVolumeImport instance.
+// The disks which already have a PV, or which are registered with CNS by
+// anything else than a previous import, are skipped, so that the import of an
+// instance can be retried, and an instance can be re-created, safely. The
+// import of the disks which failed is retried with an exponential backoff.
+// Note:
+// The Controller will requeue the Request to be processed again if the
+// returned error is non-nil or Result.Requeue is true. Otherwise, upon
+// completion it will remove the work from the queue.
+func (r *Reconciler) Reconcile(ctx context.Context,
+	request reconcile.Request) (reconcile.Result, error) {
+	ctx = logger.NewContextWithLogger(ctx)
+	log := logger.GetLogger(ctx).With("name", request.NamespacedName)
+
+	// Fetch the CnsVolumeImport instance.
+	instance := &v1a1.CnsVolumeImport{}
+	err := r.client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("instance not found. Ignoring since it must be deleted.")
+			deleteBackoffEntry(ctx, request.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		log.Error("Error reading the instance. ", err)
+		return reconcile.Result{}, err
+	}
+	if instance.DeletionTimestamp != nil {
+		log.Info("instance is marked for deletion")
+		deleteBackoffEntry(ctx, request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+	if instance.Status.Phase == v1a1.CnsVolumeImportPhaseCompleted &&
+		instance.Status.ObservedGeneration == instance.Generation {
+		log.Debug("instance is already completed")
+		deleteBackoffEntry(ctx, request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	log.Info("reconciling instance")
+	defer func() {
+		log.Info("finished reconciling instance")
+	}()
+	backoff := getBackoffDuration(ctx, request.NamespacedName)
+	log.Info("backoff duration is ", backoff)
+
+	if err = validateSpec(ctx, r.k8sclient, instance.Spec); err != nil {
+		setInstanceError(ctx, r, instance, err.Error())
+		return reconcile.Result{RequeueAfter: backoff}, nil
+	}
+	vc, err := getVirtualCenter(ctx, r.configInfo)
+	if err != nil {
+		log.Errorf("Failed to get virtual center instance with error: %+v", err)
+		setInstanceError(ctx, r, instance, "Unable to connect to VC for volume import")
+		return reconcile.Result{RequeueAfter: backoff}, nil
+	}
+	disks, err := listDisks(ctx, vc, instance.Spec)
+	if err != nil {
+		setInstanceError(ctx, r, instance, fmt.Sprintf("failed to list the disks of datastore %q. Error: %v",
+			instance.Spec.DatastoreURL, err))
+		return reconcile.Result{RequeueAfter: backoff}, nil
+	}
+	log.Infof("Found %d disks to import from datastore %q", len(disks), instance.Spec.DatastoreURL)
+	// The node affinity of the PVs only depends on the datastore of the disks.
+	topology, err := getAccessibleTopology(ctx, vc, instance.Spec.DatastoreURL)
+	if err != nil {
+		setInstanceError(ctx, r, instance, fmt.Sprintf("failed to find the topology of datastore %q. Error: %v",
+			instance.Spec.DatastoreURL, err))
+		return reconcile.Result{RequeueAfter: backoff}, nil
+	}
+	pvNames, err := getPVNamesByVolumeID(ctx, r.k8sclient)
+	if err != nil {
+		setInstanceError(ctx, r, instance, fmt.Sprintf("failed to list PVs. Error: %v", err))
+		return reconcile.Result{RequeueAfter: backoff}, nil
+	}
+
+	// FCDs registered with CNS are looked up once, instead of once per disk.
+	var volumeIDs []string
+	for _, disk := range disks {
+		if _, ok := pvNames[disk.volumeID]; disk.volumeID != "" && !ok {
+			volumeIDs = append(volumeIDs, disk.volumeID)
+		}
+	}
+	registeredVolumes, err := r.queryRegisteredVolumes(ctx, volumeIDs)
+	if err != nil {
+		setInstanceError(ctx, r, instance, fmt.Sprintf("failed to query the volumes registered with CNS. Error: %v",
+			err))
+		return reconcile.Result{RequeueAfter: backoff}, nil
+	}
+
+	r.importDisks(ctx, vc, instance, disks, pvNames, registeredVolumes,
+		util.BuildNodeAffinityFromSegments(topology))
+	if instance.Status.Failed != 0 {
+		setInstanceError(ctx, r, instance, fmt.Sprintf("failed to import %d of the %d disks",
+			instance.Status.Failed, instance.Status.Total))
+		return reconcile.Result{RequeueAfter: backoff}, nil
+	}
+	msg := fmt.Sprintf("imported %d disks, skipped %d disks", instance.Status.Imported, instance.Status.Skipped)
+	if err = setInstanceSuccess(ctx, r, instance, msg); err != nil {
+		log.Warn("failed to update status to success with error ", err)
+		setInstanceError(ctx, r, instance, "failed to update status to success")
+		return reconcile.Result{RequeueAfter: backoff}, nil
+	}
+	deleteBackoffEntry(ctx, request.NamespacedName)
+	log.Info(msg)
+	return reconcile.Result{}, nil
+}
+
+// validateSpec validates the selector and the StorageClass of the spec.
+func validateSpec(ctx context.Context, k8sclient clientset.Interface, spec v1a1.CnsVolumeImportSpec) error {
+	log := logger.GetLogger(ctx)
+	if spec.DatastoreURL == "" {
+		return logger.LogNewErrorf(log, "datastoreURL must be specified")
+	}
+	if spec.FolderPath != "" && spec.Tag != "" {
+		return logger.LogNewErrorf(log, "folderPath and tag cannot be specified together")
+	}
+	if spec.Tag != "" && spec.TagCategory == "" {
+		return logger.LogNewErrorf(log, "tagCategory must be specified with tag")
+	}
+	if spec.StorageClassName != "" {
+		sc, err := k8sclient.StorageV1().StorageClasses().Get(ctx, spec.StorageClassName, metav1.GetOptions{})
+		if err != nil {
+			return logger.LogNewErrorf(log, "failed to fetch StorageClass %q. Error: %v",
+				spec.StorageClassName, err)
+		}
+		if sc.Provisioner != cnsoptypes.VSphereCSIDriverName {
+			return logger.LogNewErrorf(log, "StorageClass %q is not provisioned by %s",
+				spec.StorageClassName, cnsoptypes.VSphereCSIDriverName)
+		}
+	}
+	return nil
+}
+
+// getPVNamesByVolumeID returns the names of the PVs of the vSphere CSI
+// driver, by volume handle.
+func getPVNamesByVolumeID(ctx context.Context, k8sclient clientset.Interface) (map[string]string, error) {
+	pvs, err := k8sclient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pvNames := make(map[string]string)
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == cnsoptypes.VSphereCSIDriverName {
+			pvNames[pv.Spec.CSI.VolumeHandle] = pv.Name
+		}
+	}
+	return pvNames, nil
+}
+
+// queryRegisteredVolumes returns the volumes registered with CNS among the
+// given FCDs, by volume ID.
+func (r *Reconciler) queryRegisteredVolumes(ctx context.Context,
+	volumeIDs []string) (map[string]cnstypes.CnsVolume, error) {
+	registeredVolumes := make(map[string]cnstypes.CnsVolume)
+	for start := 0; start < len(volumeIDs); start += queryVolumeBatchSize {
+		queryFilter := cnstypes.CnsQueryFilter{}
+		for _, volumeID := range volumeIDs[start:min(start+queryVolumeBatchSize, len(volumeIDs))] {
+			queryFilter.VolumeIds = append(queryFilter.VolumeIds, cnstypes.CnsVolumeId{Id: volumeID})
+		}
+		queryResult, err := r.volumeManager.QueryVolume(ctx, queryFilter)
+		if err != nil {
+			return nil, err
+		}
+		for _, volume := range queryResult.Volumes {
+			registeredVolumes[volume.VolumeId.Id] = volume
+		}
+	}
+	return registeredVolumes, nil
+}
+
+// isImportedVolume returns true if the volume was registered with CNS by a
+// previous import in this cluster, which failed to create its PV.
+func (r *Reconciler) isImportedVolume(volume cnstypes.CnsVolume) bool {
+	return volume.Name == staticPvNamePrefix+volume.VolumeId.Id &&
+		volume.Metadata.ContainerCluster.ClusterId == r.configInfo.Cfg.Global.ClusterID
+}
+
+// importDisks imports the disks, and records the result of each disk in the
+// status of the instance. The status is updated every statusUpdateInterval
+// disks, to report the progress of the import. The disks imported by a
+// previous reconcile are not imported again.
+func (r *Reconciler) importDisks(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+	instance *v1a1.CnsVolumeImport, disks []importDisk, pvNames map[string]string,
+	registeredVolumes map[string]cnstypes.CnsVolume, nodeAffinity *v1.VolumeNodeAffinity) {
+	log := logger.GetLogger(ctx)
+	previous := make(map[string]v1a1.CnsVolumeImportDiskStatus)
+	if instance.Status.ObservedGeneration == instance.Generation {
+		for _, diskStatus := range instance.Status.Disks {
+			key := diskStatus.Path
+			if key == "" {
+				key = diskStatus.VolumeID
+			}
+			previous[key] = diskStatus
+		}
+	}
+	instance.Status = v1a1.CnsVolumeImportStatus{
+		Phase:              v1a1.CnsVolumeImportPhaseInProgress,
+		ObservedGeneration: instance.Generation,
+		Total:              len(disks),
+		Error:              instance.Status.Error,
+	}
+	for i, disk := range disks {
+		diskStatus, ok := previous[disk.key()]
+		if !ok || diskStatus.State == v1a1.CnsVolumeImportDiskStateFailed {
+			if disk.volumeID == "" && diskStatus.VolumeID != "" {
+				// The virtual disk is already registered as an FCD.
+				disk.volumeID = diskStatus.VolumeID
+			}
+			diskStatus = r.importDisk(ctx, vc, instance, disk, pvNames, registeredVolumes, nodeAffinity)
+		}
+		instance.Status.Disks = append(instance.Status.Disks, diskStatus)
+		switch diskStatus.State {
+		case v1a1.CnsVolumeImportDiskStateImported:
+			instance.Status.Imported++
+		case v1a1.CnsVolumeImportDiskStateSkipped:
+			instance.Status.Skipped++
+		case v1a1.CnsVolumeImportDiskStateFailed:
+			instance.Status.Failed++
+		}
+		if (i+1)%statusUpdateInterval == 0 && i+1 < len(disks) {
+			if err := k8s.UpdateStatus(ctx, r.client, instance); err != nil {
+				log.Warnf("failed to update the progress of the import. Err: %v", err)
+			}
+		}
+	}
+}
+
+// importDisk registers the disk as an FCD if needed, registers the FCD with
+// CNS and creates its PV. A virtual disk already registered as an FCD, for
+// example by an instance which was deleted since, is imported or skipped as
+// the FCD it is registered as.
+func (r *Reconciler) importDisk(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+	instance *v1a1.CnsVolumeImport, disk importDisk, pvNames map[string]string,
+	registeredVolumes map[string]cnstypes.CnsVolume, nodeAffinity *v1.VolumeNodeAffinity) v1a1.CnsVolumeImportDiskStatus {
+	log := logger.GetLogger(ctx)
+	diskStatus := v1a1.CnsVolumeImportDiskStatus{Path: disk.path, VolumeID: disk.volumeID}
+	setFailed := func(err error) v1a1.CnsVolumeImportDiskStatus {
+		log.Errorf("failed to import disk %q. Err: %v", disk.key(), err)
+		diskStatus.State = v1a1.CnsVolumeImportDiskStateFailed
+		diskStatus.Message = err.Error()
+		return diskStatus
+	}
+
+	queryRegisteredVolume := false
+	if diskStatus.VolumeID == "" {
+		// RegisterDisk returns the ID of the FCD the virtual disk is already
+		// registered as, if any.
+		volumeID, err := r.volumeManager.RegisterDisk(ctx, disk.diskURLPath, getDiskName(disk.path))
+		if err != nil {
+			return setFailed(err)
+		}
+		diskStatus.VolumeID = volumeID
+		queryRegisteredVolume = true
+	}
+	if pvName, ok := pvNames[diskStatus.VolumeID]; ok {
+		diskStatus.PVName = pvName
+		diskStatus.State = v1a1.CnsVolumeImportDiskStateSkipped
+		diskStatus.Message = fmt.Sprintf("volume already has PV %q", pvName)
+		return diskStatus
+	}
+	if queryRegisteredVolume {
+		queried, err := r.queryRegisteredVolumes(ctx, []string{diskStatus.VolumeID})
+		if err != nil {
+			return setFailed(err)
+		}
+		maps.Copy(registeredVolumes, queried)
+	}
+	// CreateVolume succeeds for an FCD which is already registered with CNS,
+	// so such an FCD must be skipped, as it belongs to another cluster or to
+	// a volume whose PV was deleted.
+	if volume, ok := registeredVolumes[diskStatus.VolumeID]; ok && !r.isImportedVolume(volume) {
+		diskStatus.State = v1a1.CnsVolumeImportDiskStateSkipped
+		diskStatus.Message = fmt.Sprintf("volume is already registered with CNS as %q by cluster %q",
+			volume.Name, volume.Metadata.ContainerCluster.ClusterId)
+		return diskStatus
+	}
+
+	vStorageObject, err := r.volumeManager.RetrieveVStorageObject(ctx, diskStatus.VolumeID)
+	if err != nil {
+		return setFailed(err)
+	}
+	pvName := staticPvNamePrefix + diskStatus.VolumeID
+	createSpec := getCreateSpec(r.configInfo, vc.Config.Host, pvName, diskStatus.VolumeID)
+	if _, _, err = r.volumeManager.CreateVolume(ctx, createSpec, nil); err != nil {
+		return setFailed(err)
+	}
+	pvSpec := getPersistentVolumeSpec(instance, pvName, diskStatus.VolumeID,
+		vStorageObject.Config.CapacityInMB, nodeAffinity)
+	_, err = r.k8sclient.CoreV1().PersistentVolumes().Create(ctx, pvSpec, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return setFailed(err)
+	}
+	log.Infof("Imported disk %q as PV %q", disk.key(), pvName)
+	pvNames[diskStatus.VolumeID] = pvName
+	diskStatus.PVName = pvName
+	diskStatus.State = v1a1.CnsVolumeImportDiskStateImported
+	return diskStatus
+}
+
+// setInstanceError sets error and records an event on the CnsVolumeImport
+// instance.
+func setInstanceError(ctx context.Context, r *Reconciler,
+	instance *v1a1.CnsVolumeImport, errMsg string) {
+	instance.Status.Phase = v1a1.CnsVolumeImportPhaseFailed
+	instance.Status.ObservedGeneration = instance.Generation
+	instance.Status.Error = errMsg
+	_ = k8s.UpdateStatus(ctx, r.client, instance)
+	recordEvent(ctx, r, instance, v1.EventTypeWarning, errMsg)
+}
+
+// setInstanceSuccess sets instance to success and records an event on the
+// CnsVolumeImport instance.
+func setInstanceSuccess(ctx context.Context, r *Reconciler,
+	instance *v1a1.CnsVolumeImport, msg string) error {
+	instance.Status.Phase = v1a1.CnsVolumeImportPhaseCompleted
+	instance.Status.Error = ""
+	err := k8s.UpdateStatus(ctx, r.client, instance)
+	if err != nil {
+		return err
+	}
+	recordEvent(ctx, r, instance, v1.EventTypeNormal, msg)
+	return nil
+}
+
+// recordEvent records the event, sets the backOffDuration for the instance
+// appropriately and logs the message.
+// backOffDuration is reset to 1 second on success and doubled on failure
+// until it reaches a maximum of 5 minutes.
+func recordEvent(ctx context.Context, r *Reconciler,
+	instance *v1a1.CnsVolumeImport, eventtype string, msg string) {
+	log := logger.GetLogger(ctx)
+	log.Debugf("Event type is %s", eventtype)
+	namespacedName := types.NamespacedName{
+		Name:      instance.Name,
+		Namespace: instance.Namespace,
+	}
+	switch eventtype {
+	case v1.EventTypeWarning:
+		// Double backOff duration.
+		doubleBackoffDuration(ctx, namespacedName)
+		r.recorder.Event(instance, v1.EventTypeWarning, "CnsVolumeImportFailed", msg)
+	case v1.EventTypeNormal:
+		// Reset backOff duration to one second.
+		updateBackoffEntry(ctx, namespacedName, time.Second)
+		r.recorder.Event(instance, v1.EventTypeNormal, "CnsVolumeImportSucceeded", msg)
+	}
+}
+
+// getBackoffDuration returns the backoff duration for the instance.
+func getBackoffDuration(ctx context.Context, name types.NamespacedName) time.Duration {
+	backOffDurationMapMutex.Lock()
+	defer backOffDurationMapMutex.Unlock()
+	if _, exists := backOffDuration[name]; !exists {
+		backOffDuration[name] = time.Second
+	}
+	return backOffDuration[name]
+}
+
+// doubleBackoffDuration doubles the backoff duration for the instance
+// until it reaches a maximum of 5 minutes.
+func doubleBackoffDuration(ctx context.Context, name types.NamespacedName) {
+	d := getBackoffDuration(ctx, name)
+	d = min(d*2, cnsoptypes.MaxBackOffDurationForReconciler)
+	updateBackoffEntry(ctx, name, d)
+}
+
+// updateBackoffEntry updates the backoff duration for the instance.
+func updateBackoffEntry(ctx context.Context, name types.NamespacedName, duration time.Duration) {
+	backOffDurationMapMutex.Lock()
+	defer backOffDurationMapMutex.Unlock()
+	backOffDuration[name] = duration
+}
+
+// deleteBackoffEntry deletes the backoff entry for the instance.
+func deleteBackoffEntry(ctx context.Context, name types.NamespacedName) {
+	backOffDurationMapMutex.Lock()
+	defer backOffDurationMapMutex.Unlock()
+	delete(backOffDuration, name)
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumeimport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	apis "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator"
+	v1a1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/cnsvolumeimport/v1alpha1"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	cnsoptypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer/cnsoperator/types"
+)
+
+const testDatastoreURL = "ds:///vmfs/volumes/datastore-1/"
+
+// fakeVolumeManager implements the volume manager calls of the import.
+type fakeVolumeManager struct {
+	volumes.Manager
+	registeredPaths []string
+	createdVolumes  []string
+	failCreate      map[string]bool
+	// cnsVolumes are the volumes registered with CNS, by volume ID.
+	cnsVolumes map[string]cnstypes.CnsVolume
+}
+
+func (m *fakeVolumeManager) QueryVolume(ctx context.Context,
+	queryFilter cnstypes.CnsQueryFilter) (*cnstypes.CnsQueryResult, error) {
+	queryResult := &cnstypes.CnsQueryResult{}
+	for _, volumeID := range queryFilter.VolumeIds {
+		if volume, ok := m.cnsVolumes[volumeID.Id]; ok {
+			queryResult.Volumes = append(queryResult.Volumes, volume)
+		}
+	}
+	return queryResult, nil
+}
+
+func (m *fakeVolumeManager) RegisterDisk(ctx context.Context, path string, name string) (string, error) {
+	m.registeredPaths = append(m.registeredPaths, path)
+	return "fcd-" + name, nil
+}
+
+func (m *fakeVolumeManager) RetrieveVStorageObject(ctx context.Context,
+	volumeID string) (*vim25types.VStorageObject, error) {
+	return &vim25types.VStorageObject{
+		Config: vim25types.VStorageObjectConfigInfo{
+			BaseConfigInfo: vim25types.BaseConfigInfo{Id: vim25types.ID{Id: volumeID}},
+			CapacityInMB:   1024,
+		},
+	}, nil
+}
+
+func (m *fakeVolumeManager) CreateVolume(ctx context.Context, spec *cnstypes.CnsVolumeCreateSpec,
+	extraParams interface{}) (*volumes.CnsVolumeInfo, string, error) {
+	volumeID := spec.BackingObjectDetails.(*cnstypes.CnsBlockBackingDetails).BackingDiskId
+	if m.failCreate[volumeID] {
+		return nil, "", errors.New("CreateVolume failed")
+	}
+	m.createdVolumes = append(m.createdVolumes, volumeID)
+	return &volumes.CnsVolumeInfo{VolumeID: cnstypes.CnsVolumeId{Id: volumeID}}, "", nil
+}
+
+func newTestReconciler(t *testing.T, volumeManager volumes.Manager, instance *v1a1.CnsVolumeImport,
+	k8sObjects ...runtime.Object) *Reconciler {
+	t.Helper()
+	s := runtime.NewScheme()
+	require.NoError(t, apis.AddToScheme(s))
+	backOffDuration = make(map[types.NamespacedName]time.Duration)
+	return &Reconciler{
+		client: fake.NewClientBuilder().WithScheme(s).WithObjects(instance).
+			WithStatusSubresource(&v1a1.CnsVolumeImport{}).Build(),
+		configInfo:    &commonconfig.ConfigurationInfo{Cfg: &commonconfig.Config{}},
+		volumeManager: volumeManager,
+		recorder:      record.NewFakeRecorder(10),
+		k8sclient:     k8sfake.NewClientset(k8sObjects...),
+	}
+}
+
+// stubVCenter stubs the vCenter, disk and topology lookups of the import.
+func stubVCenter(t *testing.T, disks []importDisk) {
+	t.Helper()
+	getVirtualCenterOrig, listDisksOrig, getAccessibleTopologyOrig := getVirtualCenter, listDisks,
+		getAccessibleTopology
+	t.Cleanup(func() {
+		getVirtualCenter, listDisks, getAccessibleTopology = getVirtualCenterOrig, listDisksOrig,
+			getAccessibleTopologyOrig
+	})
+	getVirtualCenter = func(ctx context.Context,
+		configInfo *commonconfig.ConfigurationInfo) (*cnsvsphere.VirtualCenter, error) {
+		return &cnsvsphere.VirtualCenter{Config: &cnsvsphere.VirtualCenterConfig{Host: "vc-1"}}, nil
+	}
+	listDisks = func(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+		spec v1a1.CnsVolumeImportSpec) ([]importDisk, error) {
+		return disks, nil
+	}
+	getAccessibleTopology = func(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+		datastoreURL string) ([]map[string]string, error) {
+		return []map[string]string{{v1.LabelTopologyZone: "zone-a"}}, nil
+	}
+}
+
+func newInstance(spec v1a1.CnsVolumeImportSpec) *v1a1.CnsVolumeImport {
+	spec.DatastoreURL = testDatastoreURL
+	return &v1a1.CnsVolumeImport{
+		ObjectMeta: metav1.ObjectMeta{Name: "import-1", Generation: 1},
+		Spec:       spec,
+	}
+}
+
+func reconcileInstance(t *testing.T, r *Reconciler) (reconcile.Result, *v1a1.CnsVolumeImport) {
+	t.Helper()
+	ctx := context.Background()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "import-1"}}
+	res, err := r.Reconcile(ctx, request)
+	require.NoError(t, err)
+	instance := &v1a1.CnsVolumeImport{}
+	require.NoError(t, r.client.Get(ctx, request.NamespacedName, instance))
+	return res, instance
+}
+
+// TestReconcileImportsDisks verifies that the selected disks are imported as
+// PVs, and that the disks which already have a PV are skipped.
+func TestReconcileImportsDisks(t *testing.T) {
+	stubVCenter(t, []importDisk{
+		{path: "import/disk-1.vmdk", diskURLPath: "https://vc-1/folder/import/disk-1.vmdk"},
+		{volumeID: "fcd-2"},
+		{volumeID: "fcd-3"},
+	})
+	existingPV := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-2"},
+		Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+			CSI: &v1.CSIPersistentVolumeSource{Driver: cnsoptypes.VSphereCSIDriverName, VolumeHandle: "fcd-2"},
+		}},
+	}
+	volumeManager := &fakeVolumeManager{}
+	r := newTestReconciler(t, volumeManager, newInstance(v1a1.CnsVolumeImportSpec{}), existingPV)
+
+	res, instance := reconcileInstance(t, r)
+	assert.Equal(t, reconcile.Result{}, res)
+	assert.Equal(t, v1a1.CnsVolumeImportPhaseCompleted, instance.Status.Phase)
+	assert.Equal(t, int64(1), instance.Status.ObservedGeneration)
+	assert.Equal(t, 3, instance.Status.Total)
+	assert.Equal(t, 2, instance.Status.Imported)
+	assert.Equal(t, 1, instance.Status.Skipped)
+	assert.Equal(t, 0, instance.Status.Failed)
+	require.Len(t, instance.Status.Disks, 3)
+	assert.Equal(t, v1a1.CnsVolumeImportDiskStatus{Path: "import/disk-1.vmdk", VolumeID: "fcd-disk-1",
+		PVName: "static-pv-fcd-disk-1", State: v1a1.CnsVolumeImportDiskStateImported}, instance.Status.Disks[0])
+	assert.Equal(t, v1a1.CnsVolumeImportDiskStateSkipped, instance.Status.Disks[1].State)
+	assert.Equal(t, "pv-2", instance.Status.Disks[1].PVName)
+	assert.Equal(t, []string{"https://vc-1/folder/import/disk-1.vmdk"}, volumeManager.registeredPaths)
+	assert.Equal(t, []string{"fcd-disk-1", "fcd-3"}, volumeManager.createdVolumes)
+
+	pv, err := r.k8sclient.CoreV1().PersistentVolumes().Get(context.Background(), "static-pv-fcd-3",
+		metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1.PersistentVolumeReclaimRetain, pv.Spec.PersistentVolumeReclaimPolicy)
+	assert.Equal(t, []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce}, pv.Spec.AccessModes)
+	assert.Equal(t, "ext4", pv.Spec.CSI.FSType)
+	assert.Equal(t, "1Gi", pv.Spec.Capacity.Storage().String())
+	assert.Equal(t, "import-1", pv.Labels[labelVolumeImportName])
+	require.NotNil(t, pv.Spec.NodeAffinity)
+	assert.Equal(t, "zone-a",
+		pv.Spec.NodeAffinity.Required.NodeSelectorTerms[0].MatchExpressions[0].Values[0])
+}
+
+// TestReconcileRetriesFailedDisks verifies that only the disks whose import
+// failed are imported again.
+func TestReconcileRetriesFailedDisks(t *testing.T) {
+	stubVCenter(t, []importDisk{
+		{path: "import/disk-1.vmdk", diskURLPath: "https://vc-1/folder/import/disk-1.vmdk"},
+		{volumeID: "fcd-2"},
+	})
+	volumeManager := &fakeVolumeManager{failCreate: map[string]bool{"fcd-2": true}}
+	r := newTestReconciler(t, volumeManager, newInstance(v1a1.CnsVolumeImportSpec{
+		ReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+		VolumeMode:    v1.PersistentVolumeBlock,
+	}))
+
+	res, instance := reconcileInstance(t, r)
+	assert.NotZero(t, res.RequeueAfter)
+	assert.Equal(t, v1a1.CnsVolumeImportPhaseFailed, instance.Status.Phase)
+	assert.Equal(t, 1, instance.Status.Imported)
+	assert.Equal(t, 1, instance.Status.Failed)
+	assert.Equal(t, "CreateVolume failed", instance.Status.Disks[1].Message)
+
+	volumeManager.failCreate = nil
+	res, instance = reconcileInstance(t, r)
+	assert.Equal(t, reconcile.Result{}, res)
+	assert.Equal(t, v1a1.CnsVolumeImportPhaseCompleted, instance.Status.Phase)
+	assert.Equal(t, 2, instance.Status.Imported)
+	assert.Empty(t, instance.Status.Error)
+	assert.Len(t, volumeManager.registeredPaths, 1)
+	assert.Equal(t, []string{"fcd-disk-1", "fcd-2"}, volumeManager.createdVolumes)
+
+	pv, err := r.k8sclient.CoreV1().PersistentVolumes().Get(context.Background(), "static-pv-fcd-2",
+		metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1.PersistentVolumeReclaimDelete, pv.Spec.PersistentVolumeReclaimPolicy)
+	assert.Equal(t, v1.PersistentVolumeBlock, *pv.Spec.VolumeMode)
+	assert.Empty(t, pv.Spec.CSI.FSType)
+}
+
+// TestReconcileRejectsInvalidSpec verifies that the selector and the
+// StorageClass of the spec are validated before listing the disks.
+func TestReconcileRejectsInvalidSpec(t *testing.T) {
+	stubVCenter(t, nil)
+	listDisks = func(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+		spec v1a1.CnsVolumeImportSpec) ([]importDisk, error) {
+		t.Fatal("disks must not be listed for an invalid spec")
+		return nil, nil
+	}
+	for name, spec := range map[string]v1a1.CnsVolumeImportSpec{
+		"folder and tag":       {FolderPath: "import", TagCategory: "backup", Tag: "daily"},
+		"tag without category": {Tag: "daily"},
+		"missing StorageClass": {StorageClassName: "vsphere-sc"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := newTestReconciler(t, &fakeVolumeManager{}, newInstance(spec))
+			res, instance := reconcileInstance(t, r)
+			assert.NotZero(t, res.RequeueAfter)
+			assert.Equal(t, v1a1.CnsVolumeImportPhaseFailed, instance.Status.Phase)
+			assert.NotEmpty(t, instance.Status.Error)
+		})
+	}
+}
+
+// TestReconcileSkipsRegisteredVolumes verifies that the FCDs registered with
+// CNS by another cluster are skipped, and that the FCDs registered by a
+// previous import in this cluster, including virtual disks registered by a
+// deleted instance, get their PV.
+func TestReconcileSkipsRegisteredVolumes(t *testing.T) {
+	stubVCenter(t, []importDisk{
+		{path: "import/disk-1.vmdk", diskURLPath: "https://vc-1/folder/import/disk-1.vmdk"},
+		{volumeID: "fcd-2"},
+		{volumeID: "fcd-3"},
+	})
+	newCnsVolume := func(volumeID string, name string, clusterID string) cnstypes.CnsVolume {
+		return cnstypes.CnsVolume{
+			VolumeId: cnstypes.CnsVolumeId{Id: volumeID},
+			Name:     name,
+			Metadata: cnstypes.CnsVolumeMetadata{
+				ContainerCluster: cnstypes.CnsContainerCluster{ClusterId: clusterID},
+			},
+		}
+	}
+	volumeManager := &fakeVolumeManager{cnsVolumes: map[string]cnstypes.CnsVolume{
+		"fcd-disk-1": newCnsVolume("fcd-disk-1", "static-pv-fcd-disk-1", "cluster-1"),
+		"fcd-2":      newCnsVolume("fcd-2", "pvc-2", "cluster-2"),
+	}}
+	r := newTestReconciler(t, volumeManager, newInstance(v1a1.CnsVolumeImportSpec{}))
+	r.configInfo.Cfg.Global.ClusterID = "cluster-1"
+
+	res, instance := reconcileInstance(t, r)
+	assert.Equal(t, reconcile.Result{}, res)
+	assert.Equal(t, v1a1.CnsVolumeImportPhaseCompleted, instance.Status.Phase)
+	assert.Equal(t, 2, instance.Status.Imported)
+	assert.Equal(t, 1, instance.Status.Skipped)
+	require.Len(t, instance.Status.Disks, 3)
+	assert.Equal(t, v1a1.CnsVolumeImportDiskStateImported, instance.Status.Disks[0].State)
+	assert.Equal(t, "static-pv-fcd-disk-1", instance.Status.Disks[0].PVName)
+	assert.Equal(t, v1a1.CnsVolumeImportDiskStateSkipped, instance.Status.Disks[1].State)
+	assert.Equal(t, `volume is already registered with CNS as "pvc-2" by cluster "cluster-2"`,
+		instance.Status.Disks[1].Message)
+	assert.Empty(t, instance.Status.Disks[1].PVName)
+	assert.Equal(t, []string{"fcd-disk-1", "fcd-3"}, volumeManager.createdVolumes)
+	_, err := r.k8sclient.CoreV1().PersistentVolumes().Get(context.Background(), "static-pv-fcd-2",
+		metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumeimport
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1a1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/cnsvolumeimport/v1alpha1"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	cnsoptypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer/cnsoperator/types"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer/cnsoperator/util"
+)
+
+// importDisk is a disk selected by a CnsVolumeImport instance.
+type importDisk struct {
+	// path is the datastore path of a virtual disk to register as an FCD.
+	// It is empty for FCDs.
+	path string
+	// diskURLPath is the URL of the virtual disk used to register it.
+	diskURLPath string
+	// volumeID is the ID of the FCD. It is only known once a virtual disk is
+	// registered.
+	volumeID string
+}
+
+// key returns the key of the disk in the status of the instance.
+func (d importDisk) key() string {
+	if d.path != "" {
+		return d.path
+	}
+	return d.volumeID
+}
+
+var (
+	getVirtualCenter      = _getVirtualCenter
+	listDisks             = _listDisks
+	getAccessibleTopology = _getAccessibleTopology
+)
+
+// _getVirtualCenter returns the vCenter of the volume manager, without
+// re-registering the vCenters of a multi vCenter deployment.
+func _getVirtualCenter(ctx context.Context,
+	configInfo *commonconfig.ConfigurationInfo) (*cnsvsphere.VirtualCenter, error) {
+	vcConfig, err := cnsvsphere.GetVirtualCenterConfig(ctx, configInfo.Cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cnsvsphere.GetVirtualCenterInstanceForVCenterConfig(ctx, vcConfig, false)
+}
+
+// _listDisks returns the disks selected by the spec, sorted by key.
+func _listDisks(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+	spec v1a1.CnsVolumeImportSpec) ([]importDisk, error) {
+	log := logger.GetLogger(ctx)
+	dsInfo, err := getDatastoreInfoByURL(ctx, vc, spec.DatastoreURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var disks []importDisk
+	if spec.FolderPath != "" {
+		diskPaths, err := dsInfo.ListVirtualDisks(ctx, spec.FolderPath)
+		if err != nil {
+			return nil, err
+		}
+		dcPath := strings.TrimPrefix(dsInfo.Datacenter.InventoryPath, "/")
+		for _, diskPath := range diskPaths {
+			// Format:
+			// https://<vc_ip>/folder/<vm_vmdk_path>?dcPath=<datacenter-path>&dsName=<datastoreName>
+			diskURLPath := "https://" + vc.Config.Host + "/folder/" + diskPath +
+				"?dcPath=" + url.PathEscape(dcPath) + "&dsName=" + url.PathEscape(dsInfo.Info.Name)
+			disks = append(disks, importDisk{path: diskPath, diskURLPath: diskURLPath})
+		}
+		return disks, nil
+	}
+
+	volumeIDs, err := dsInfo.ListVStorageObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Tag != "" {
+		taggedVolumeIDs, err := vc.ListVStorageObjectsAttachedToTag(ctx, spec.TagCategory, spec.Tag)
+		if err != nil {
+			return nil, err
+		}
+		tagged := make(map[string]bool, len(taggedVolumeIDs))
+		for _, volumeID := range taggedVolumeIDs {
+			tagged[volumeID] = true
+		}
+		var filtered []string
+		for _, volumeID := range volumeIDs {
+			if tagged[volumeID] {
+				filtered = append(filtered, volumeID)
+			}
+		}
+		log.Debugf("%d of the %d FCDs of datastore %q are attached to tag %q", len(filtered),
+			len(volumeIDs), spec.DatastoreURL, spec.Tag)
+		volumeIDs = filtered
+	}
+	for _, volumeID := range volumeIDs {
+		disks = append(disks, importDisk{volumeID: volumeID})
+	}
+	return disks, nil
+}
+
+// getDatastoreInfoByURL returns the datastore with the given URL in the
+// datacenters of the vCenter.
+func getDatastoreInfoByURL(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+	datastoreURL string) (*cnsvsphere.DatastoreInfo, error) {
+	log := logger.GetLogger(ctx)
+	datacenters, err := vc.GetDatacenters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, dc := range datacenters {
+		dsInfo, err := dc.GetDatastoreInfoByURL(ctx, datastoreURL)
+		if err == nil {
+			return dsInfo, nil
+		}
+		log.Debugf("datastore %q not found in datacenter %q. Err: %v", datastoreURL, dc.InventoryPath, err)
+	}
+	return nil, logger.LogNewErrorf(log, "datastore %q not found on vCenter %q", datastoreURL, vc.Config.Host)
+}
+
+// _getAccessibleTopology returns the topology segments from which the
+// datastore is accessible.
+func _getAccessibleTopology(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+	datastoreURL string) ([]map[string]string, error) {
+	return util.GetVanillaDatastoreAccessibleTopology(ctx, vc, topologyMgr, datastoreURL)
+}
+
+// getCreateSpec returns the spec registering the FCD with CNS.
+func getCreateSpec(configInfo *commonconfig.ConfigurationInfo, host string, pvName string,
+	volumeID string) *cnstypes.CnsVolumeCreateSpec {
+	user := ""
+	if vcConfig, ok := configInfo.Cfg.VirtualCenter[host]; ok {
+		user = vcConfig.User
+	}
+	containerCluster := cnsvsphere.GetContainerCluster(configInfo.Cfg.Global.ClusterID, user,
+		cnstypes.CnsClusterFlavorVanilla, configInfo.Cfg.Global.ClusterDistribution)
+	return &cnstypes.CnsVolumeCreateSpec{
+		Name:       pvName,
+		VolumeType: common.BlockVolumeType,
+		Metadata: cnstypes.CnsVolumeMetadata{
+			ContainerCluster: containerCluster,
+		},
+		BackingObjectDetails: &cnstypes.CnsBlockBackingDetails{
+			BackingDiskId: volumeID,
+		},
+	}
+}
+
+// getPersistentVolumeSpec returns the spec of the PV of the FCD, which is not
+// bound to any PVC.
+func getPersistentVolumeSpec(instance *v1a1.CnsVolumeImport, pvName string, volumeID string,
+	capacityInMb int64, nodeAffinity *v1.VolumeNodeAffinity) *v1.PersistentVolume {
+	reclaimPolicy := instance.Spec.ReclaimPolicy
+	if reclaimPolicy == "" {
+		reclaimPolicy = v1.PersistentVolumeReclaimRetain
+	}
+	accessMode := instance.Spec.AccessMode
+	if accessMode == "" {
+		accessMode = v1.ReadWriteOnce
+	}
+	volumeMode := instance.Spec.VolumeMode
+	if volumeMode == "" {
+		volumeMode = v1.PersistentVolumeFilesystem
+	}
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: pvName,
+			Labels: map[string]string{
+				labelVolumeImportName: instance.Name,
+			},
+			Annotations: map[string]string{
+				"pv.kubernetes.io/provisioned-by": cnsoptypes.VSphereCSIDriverName,
+			},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: reclaimPolicy,
+			Capacity: v1.ResourceList{
+				v1.ResourceStorage: *resource.NewQuantity(capacityInMb*common.MbInBytes, resource.BinarySI),
+			},
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{
+					Driver:       cnsoptypes.VSphereCSIDriverName,
+					VolumeHandle: volumeID,
+				},
+			},
+			AccessModes:      []v1.PersistentVolumeAccessMode{accessMode},
+			StorageClassName: instance.Spec.StorageClassName,
+			VolumeMode:       &volumeMode,
+			NodeAffinity:     nodeAffinity,
+		},
+	}
+	// FsType should be set only when the volumeMode is Filesystem.
+	if volumeMode == v1.PersistentVolumeFilesystem {
+		fsType := instance.Spec.FsType
+		if fsType == "" {
+			fsType = common.Ext4FsType
+		}
+		pv.Spec.CSI.FSType = fsType
+	}
+	return pv
+}
+
+// getDiskName returns the name of the FCD registered for a virtual disk.
+func getDiskName(diskPath string) string {
+	return strings.TrimSuffix(path.Base(diskPath), path.Ext(diskPath))
+}
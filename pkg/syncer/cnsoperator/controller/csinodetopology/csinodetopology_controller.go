@@ -60,7 +60,20 @@ import (
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/syncer"
 )
 
-const defaultMaxWorkerThreads = 1
+const (
+	defaultMaxWorkerThreads = 1
+
+	// maxDisksPerPVSCSIController is the number of disks which can be attached
+	// to a PVSCSI controller, the unit number 7 being reserved for the controller.
+	maxDisksPerPVSCSIController = 15
+	// maxDisksPerNVMeController is the number of disks which can be attached
+	// to an NVMe controller.
+	maxDisksPerNVMeController = 15
+	// vSphere 8.0 supports 64 targets per PVSCSI controller and 64 namespaces
+	// per NVMe controller.
+	maxDisksPerPVSCSIControllerInvSphere8 = 63
+	maxDisksPerNVMeControllerInvSphere8   = 64
+)
 
 // backOffDuration is a map of csinodetopology instance name to the time after
 // which a request for this instance will be requeued. Initialized to 1 second
@@ -319,6 +332,23 @@ func (r *ReconcileCSINodeTopology) reconcileForVanilla(ctx context.Context, requ
 		return reconcile.Result{RequeueAfter: timeout}, nil
 	}
 
+	// Compute the number of volumes which can be attached to the nodeVM. The
+	// instance is only reconciled again when NodeGetInfo resets its status, so
+	// controllers added to the nodeVM later are only counted once the node
+	// plugin registers again, i.e. after the vsphere-csi-node pod restarts.
+	instance.Status.AttachableVolumes = 0
+	if clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.AttachableVolumesFromInventory) {
+		instance.Status.AttachableVolumes, err = nodeVM.GetAttachableVolumeCount(ctx, getAttachableDiskLimits(ctx))
+		if err != nil {
+			msg := fmt.Sprintf("failed to get the number of attachable volumes for the nodeVM %q. Error: %v",
+				instance.Name, err)
+			log.Error(msg)
+			_ = updateCRStatus(ctx, r, instance, csinodetopologyv1alpha1.CSINodeTopologyError, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+	}
+
 	if !r.isTopologyEnabled() {
 		// Not a topology aware setup.
 		// Set the Status to Success and return.
@@ -374,6 +404,21 @@ func (r *ReconcileCSINodeTopology) reconcileForVanilla(ctx context.Context, requ
 	return reconcile.Result{}, nil
 }
 
+// getAttachableDiskLimits returns the number of disks which can be attached to
+// each PVSCSI and NVMe controller of a nodeVM.
+func getAttachableDiskLimits(ctx context.Context) cnsvsphere.AttachableDiskLimits {
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.HighPVNodeDensity) {
+		return cnsvsphere.AttachableDiskLimits{
+			PVSCSI: maxDisksPerPVSCSIControllerInvSphere8,
+			NVMe:   maxDisksPerNVMeControllerInvSphere8,
+		}
+	}
+	return cnsvsphere.AttachableDiskLimits{
+		PVSCSI: maxDisksPerPVSCSIController,
+		NVMe:   maxDisksPerNVMeController,
+	}
+}
+
 // isTopologyEnabled checks if topology of cluster should be updated.
 // if cluster is not topology aware return false.
 func (r *ReconcileCSINodeTopology) isTopologyEnabled() bool {
@@ -359,6 +359,86 @@ func InitCnsOperator(ctx context.Context, clusterFlavor cnstypes.CnsClusterFlavo
 			log.Errorf("Failed to create %q CRD. Error: %+v", csinodetopology.CRDSingular, err)
 			return err
 		}
+
+		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.StaticVolumeRegistration) {
+			// Create CnsRegisterVolume and CnsUnregisterVolume CRDs from manifest.
+			log.Infof("Creating %q CRD", cnsoperatorv1alpha1.CnsRegisterVolumePlural)
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, cnsoperatorconfig.EmbedCnsRegisterVolumeCRFile,
+				cnsoperatorconfig.EmbedCnsRegisterVolumeCRFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", cnsoperatorv1alpha1.CnsRegisterVolumePlural, err)
+				return err
+			}
+			log.Infof("%q CRD is created successfully", cnsoperatorv1alpha1.CnsRegisterVolumePlural)
+			log.Infof("Creating %q CRD", cnsoperatorv1alpha1.CnsUnregisterVolumePlural)
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, cnsoperatorconfig.EmbedCnsUnregisterVolumeCRFile,
+				cnsoperatorconfig.EmbedCnsUnregisterVolumeCRFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", cnsoperatorv1alpha1.CnsUnregisterVolumePlural, err)
+				return err
+			}
+			log.Infof("%q CRD is created successfully", cnsoperatorv1alpha1.CnsUnregisterVolumePlural)
+
+			// Clean up routine to cleanup successful CnsRegisterVolume and
+			// CnsUnregisterVolume instances.
+			log.Info("Starting go routine to cleanup successful CnsRegisterVolume and CnsUnregisterVolume instances.")
+			err = watcher(ctx, cnsOperator)
+			if err != nil {
+				log.Error("Failed to watch on config file for changes to "+
+					"CnsRegisterVolumesCleanupIntervalInMin. Error: %+v", err)
+				return err
+			}
+			go func() {
+				for {
+					ctx, log := logger.GetNewContextWithLogger()
+					log.Infof("Triggering CnsRegisterVolume and CnsUnregisterVolume cleanup routine")
+					cleanUpCnsRegisterVolumeInstances(ctx, restConfig,
+						cnsOperator.configInfo.Cfg.Global.CnsRegisterVolumesCleanupIntervalInMin)
+					cleanUpCnsUnregisterVolumeInstances(ctx, restConfig,
+						cnsOperator.configInfo.Cfg.Global.CnsRegisterVolumesCleanupIntervalInMin)
+					log.Infof("Completed CnsRegisterVolume and CnsUnregisterVolume cleanup")
+					for i := 1; i <= cnsOperator.configInfo.Cfg.Global.CnsRegisterVolumesCleanupIntervalInMin; i++ {
+						time.Sleep(time.Duration(1 * time.Minute))
+					}
+				}
+			}()
+		}
+
+		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.BulkVolumeImport) {
+			// Create CnsVolumeImport CRD from manifest.
+			log.Infof("Creating %q CRD", cnsoperatorv1alpha1.CnsVolumeImportPlural)
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, cnsoperatorconfig.EmbedCnsVolumeImportCRFile,
+				cnsoperatorconfig.EmbedCnsVolumeImportCRFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", cnsoperatorv1alpha1.CnsVolumeImportPlural, err)
+				return err
+			}
+			log.Infof("%q CRD is created successfully", cnsoperatorv1alpha1.CnsVolumeImportPlural)
+		}
+
+		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.DriftReport) {
+			// Create CnsDriftReport CRD from manifest.
+			log.Infof("Creating %q CRD", internalapis.CnsDriftReportPlural)
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, internalapiscnsoperatorconfig.EmbedCnsDriftReportFile,
+				internalapiscnsoperatorconfig.EmbedCnsDriftReportFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", internalapis.CnsDriftReportPlural, err)
+				return err
+			}
+			log.Infof("%q CRD is created successfully", internalapis.CnsDriftReportPlural)
+		}
+
+		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.OrphanGC) {
+			// Create CnsOrphanObject CRD from manifest.
+			log.Infof("Creating %q CRD", internalapis.CnsOrphanObjectPlural)
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, internalapiscnsoperatorconfig.EmbedCnsOrphanObjectFile,
+				internalapiscnsoperatorconfig.EmbedCnsOrphanObjectFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", internalapis.CnsOrphanObjectPlural, err)
+				return err
+			}
+			log.Infof("%q CRD is created successfully", internalapis.CnsOrphanObjectPlural)
+		}
 	} else if clusterFlavor == cnstypes.CnsClusterFlavorGuest {
 		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.TKGsHA) {
 			// Create CSINodeTopology CRD.
@@ -35,6 +35,7 @@ import (
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/node"
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
 
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/utils"
@@ -650,3 +651,76 @@ func GetZoneCompatibleDatastoresForPolicy(ctx context.Context, topologyMgr commo
 
 	return zones, zoneCompatibleDS, dsIDs, nil
 }
+
+// ErrDatastoreNotAccessibleToNodes is returned by GetVanillaDatastoreAccessibleTopology
+// when no node VM of the cluster can access the datastore.
+var ErrDatastoreNotAccessibleToNodes = errors.New("datastore is not accessible to any node in the cluster")
+
+// GetVanillaDatastoreAccessibleTopology returns the topology segments from which the
+// given datastore is accessible on a vanilla cluster, computed from the node VMs of
+// the cluster which can access the datastore. It returns no segment when topologyMgr
+// is nil, i.e. when the cluster is not topology aware, and ErrDatastoreNotAccessibleToNodes
+// when no node VM can access the datastore.
+func GetVanillaDatastoreAccessibleTopology(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+	topologyMgr commoncotypes.ControllerTopologyService, datastoreURL string) ([]map[string]string, error) {
+	log := logger.GetLogger(ctx)
+	nodeManager := node.GetManager(ctx)
+	allNodeVMs, err := nodeManager.GetAllNodesByVC(ctx, vc.Config.Host)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to get the node VMs of vCenter %q. Error: %+v",
+			vc.Config.Host, err)
+	}
+	accessibleNodes, err := common.GetNodeVMsWithAccessToDatastore(ctx, vc, datastoreURL, allNodeVMs)
+	if err != nil {
+		return nil, err
+	}
+	if len(accessibleNodes) == 0 {
+		return nil, ErrDatastoreNotAccessibleToNodes
+	}
+	if topologyMgr == nil {
+		return nil, nil
+	}
+	var accessibleNodeNames []string
+	for _, vmRef := range accessibleNodes {
+		vmUUID, err := cnsvsphere.GetUUIDFromVMReference(ctx, vc, vmRef.Reference())
+		if err != nil {
+			return nil, err
+		}
+		nodeName, err := nodeManager.GetNodeNameByUUID(ctx, vmUUID)
+		if err != nil {
+			return nil, err
+		}
+		accessibleNodeNames = append(accessibleNodeNames, nodeName)
+	}
+	return topologyMgr.GetTopologyInfoFromNodes(ctx, commoncotypes.VanillaRetrieveTopologyInfoParams{
+		NodeNames:    accessibleNodeNames,
+		DatastoreURL: datastoreURL,
+	})
+}
+
+// BuildNodeAffinityFromSegments builds a PV VolumeNodeAffinity from topology segments.
+// Each segment contributes one NodeSelectorTerm whose match expressions require every
+// segment key to equal its value (In operator), so both the zone and hostname keys of a
+// host-local segment become required node-selector terms on the PV.
+func BuildNodeAffinityFromSegments(segments []map[string]string) *v1.VolumeNodeAffinity {
+	if len(segments) == 0 {
+		return nil
+	}
+	var terms []v1.NodeSelectorTerm
+	for _, segment := range segments {
+		expressions := make([]v1.NodeSelectorRequirement, 0, len(segment))
+		for key, value := range segment {
+			expressions = append(expressions, v1.NodeSelectorRequirement{
+				Key:      key,
+				Operator: v1.NodeSelectorOpIn,
+				Values:   []string{value},
+			})
+		}
+		terms = append(terms, v1.NodeSelectorTerm{MatchExpressions: expressions})
+	}
+	return &v1.VolumeNodeAffinity{
+		Required: &v1.NodeSelector{
+			NodeSelectorTerms: terms,
+		},
+	}
+}
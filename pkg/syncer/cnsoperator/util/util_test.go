@@ -31,6 +31,7 @@ import (
 	"github.com/vmware/govmomi/pbm"
 	pbmtypes "github.com/vmware/govmomi/pbm/types"
 	vimtypes "github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -982,3 +983,32 @@ func TestGetZoneCompatibleDatastoresForPolicy_ClusterInMultipleZones(t *testing.
 		assert.Equal(t, []string{"ds-1"}, dsIDs)
 	})
 }
+
+// TestBuildNodeAffinityFromSegments verifies that PV node affinity is built with both the
+// zone and hostname keys as required node-selector terms, and that an empty input yields nil.
+func TestBuildNodeAffinityFromSegments(t *testing.T) {
+	// Empty input -> nil affinity.
+	assert.Nil(t, BuildNodeAffinityFromSegments(nil))
+	assert.Nil(t, BuildNodeAffinityFromSegments([]map[string]string{}))
+
+	segments := []map[string]string{
+		{
+			v1.LabelTopologyZone: "zone-a",
+			v1.LabelHostname:     "esx-node-1",
+		},
+	}
+	affinity := BuildNodeAffinityFromSegments(segments)
+	assert.NotNil(t, affinity)
+	assert.NotNil(t, affinity.Required)
+	assert.Len(t, affinity.Required.NodeSelectorTerms, 1)
+
+	// Collect the match expressions into a key->value map for order-independent assertions.
+	got := map[string]string{}
+	for _, expr := range affinity.Required.NodeSelectorTerms[0].MatchExpressions {
+		assert.Equal(t, v1.NodeSelectorOpIn, expr.Operator)
+		assert.Len(t, expr.Values, 1)
+		got[expr.Key] = expr.Values[0]
+	}
+	assert.Equal(t, "zone-a", got[v1.LabelTopologyZone])
+	assert.Equal(t, "esx-node-1", got[v1.LabelHostname])
+}
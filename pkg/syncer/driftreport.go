@@ -0,0 +1,435 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator"
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/types"
+	driftreportv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsoperator/cnsdriftreport/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsvolumeoperationrequest"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v3/pkg/kubernetes"
+)
+
+// staleVolumeOperationRequestAge is the age after which a
+// CnsVolumeOperationRequest whose operation is still in progress is stale.
+const staleVolumeOperationRequestAge = time.Hour
+
+var (
+	// staleVolumeAttachmentsMap holds, per vCenter, the UIDs of the
+	// VolumeAttachments found stale by the previous full sync. A stale
+	// VolumeAttachment is only deleted once found stale by two consecutive
+	// full syncs, so that a node being recreated, or a lagging PV informer, do
+	// not cause its deletion.
+	staleVolumeAttachmentsMap  = make(map[string]map[apitypes.UID]bool)
+	staleVolumeAttachmentsLock sync.Mutex
+)
+
+// newDriftReportClient returns a client of the CnsDriftReports.
+var newDriftReportClient = func(ctx context.Context) (client.Client, error) {
+	restConfig, err := k8s.GetKubeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return k8s.NewClientForGroup(ctx, restConfig, cnsoperatorv1alpha1.GroupName)
+}
+
+// newDriftReportOperationStore returns the store of the CnsVolumeOperationRequests
+// configured for the CSI controller.
+var newDriftReportOperationStore = func(ctx context.Context,
+	cfg *cnsconfig.Config) (cnsvolumeoperationrequest.VolumeOperationRequest, error) {
+	return cnsvolumeoperationrequest.NewVolumeOperationRequestStore(ctx, cnsvolumeoperationrequest.StoreConfig{
+		Type:   cfg.Global.CnsVolumeOperationRequestStore,
+		Shards: cfg.Global.CnsVolumeOperationRequestStoreShards,
+	})
+}
+
+// driftReport collects the drift between CNS and Kubernetes found by a full
+// sync of a vCenter. A nil driftReport reports nothing and remediates every
+// category, which is the behaviour of the full sync without the DriftReport
+// feature.
+type driftReport struct {
+	vc        string
+	dryRun    bool
+	startTime time.Time
+	policies  map[driftreportv1alpha1.DriftCategory]string
+	// singleVC is true if the cluster has a single vCenter, to which the
+	// objects not backed by a volume of the vCenter are attributed.
+	singleVC   bool
+	categories map[driftreportv1alpha1.DriftCategory]*driftreportv1alpha1.DriftCategoryStatus
+}
+
+// isDriftReportEnabled returns true if the full sync of the cluster produces a
+// CnsDriftReport.
+func isDriftReportEnabled(ctx context.Context, metadataSyncer *metadataSyncInformer) bool {
+	return metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.DriftReport)
+}
+
+// newDriftReport returns the drift report of the full sync of vc started at
+// startTime, nil if the DriftReport feature is disabled.
+func newDriftReport(ctx context.Context, metadataSyncer *metadataSyncInformer, vc string,
+	startTime time.Time) *driftReport {
+	if !isDriftReportEnabled(ctx, metadataSyncer) {
+		return nil
+	}
+	cfg := metadataSyncer.configInfo.Cfg.DriftReport
+	report := &driftReport{
+		vc:        vc,
+		dryRun:    cfg.DryRun,
+		startTime: startTime,
+		policies: map[driftreportv1alpha1.DriftCategory]string{
+			driftreportv1alpha1.DriftCategoryOrphanedVolume:              cfg.OrphanedVolume,
+			driftreportv1alpha1.DriftCategoryPVWithoutVolume:             cfg.PVWithoutVolume,
+			driftreportv1alpha1.DriftCategoryMetadataMismatch:            cfg.MetadataMismatch,
+			driftreportv1alpha1.DriftCategoryStaleVolumeOperationRequest: cfg.StaleVolumeOperationRequest,
+			driftreportv1alpha1.DriftCategoryStaleVolumeAttachment:       cfg.StaleVolumeAttachment,
+		},
+		singleVC:   len(metadataSyncer.configInfo.Cfg.VirtualCenter) <= 1,
+		categories: make(map[driftreportv1alpha1.DriftCategory]*driftreportv1alpha1.DriftCategoryStatus),
+	}
+	for _, category := range driftreportv1alpha1.DriftCategories {
+		report.categories[category] = &driftreportv1alpha1.DriftCategoryStatus{
+			Category: category,
+			Policy:   report.policies[category],
+		}
+	}
+	return report
+}
+
+// remediate returns true if the full sync corrects the drift of category.
+func (r *driftReport) remediate(category driftreportv1alpha1.DriftCategory) bool {
+	if r == nil {
+		return true
+	}
+	return !r.dryRun && r.policies[category] == cnsconfig.DriftRemediationPolicyRemediate
+}
+
+// add records item in the drift of category, and returns a pointer to the
+// recorded item if it is listed in the report.
+func (r *driftReport) add(category driftreportv1alpha1.DriftCategory,
+	item driftreportv1alpha1.DriftItem) *driftreportv1alpha1.DriftItem {
+	if r == nil {
+		return nil
+	}
+	status := r.categories[category]
+	status.Count++
+	if len(status.Items) >= driftreportv1alpha1.MaxDriftItems {
+		return nil
+	}
+	status.Items = append(status.Items, item)
+	return &status.Items[len(status.Items)-1]
+}
+
+// reportVolumeSpecs records the PVs without volume of createSpecArray and the
+// metadata mismatches of updateSpecArray, and returns the specs of the
+// categories to remediate. The volumes whose metadata mismatch is not
+// remediated are reconciled again by the next incremental full sync.
+func (r *driftReport) reportVolumeSpecs(createSpecArray []cnstypes.CnsVolumeCreateSpec,
+	updateSpecArray []cnstypes.CnsVolumeMetadataUpdateSpec, pvToVolumeID map[string]string) (
+	[]cnstypes.CnsVolumeCreateSpec, []cnstypes.CnsVolumeMetadataUpdateSpec) {
+	if r == nil {
+		return createSpecArray, updateSpecArray
+	}
+	volumeToPVName := make(map[string]string)
+	for pvName, volumeID := range pvToVolumeID {
+		volumeToPVName[volumeID] = pvName
+	}
+	for _, createSpec := range createSpecArray {
+		item := driftreportv1alpha1.DriftItem{
+			Kind:   "PersistentVolume",
+			Name:   createSpec.Name,
+			Reason: "volume not registered in CNS",
+		}
+		switch backing := createSpec.BackingObjectDetails.(type) {
+		case *cnstypes.CnsBlockBackingDetails:
+			item.VolumeID = backing.BackingDiskId
+		case *cnstypes.CnsVsanFileShareBackingDetails:
+			item.VolumeID = backing.BackingFileId
+		}
+		r.add(driftreportv1alpha1.DriftCategoryPVWithoutVolume, item)
+	}
+	reported := make(map[string]bool)
+	for _, updateSpec := range updateSpecArray {
+		volumeID := updateSpec.VolumeId.Id
+		if reported[volumeID] {
+			continue
+		}
+		reported[volumeID] = true
+		r.add(driftreportv1alpha1.DriftCategoryMetadataMismatch, driftreportv1alpha1.DriftItem{
+			VolumeID: volumeID,
+			Kind:     "PersistentVolume",
+			Name:     volumeToPVName[volumeID],
+			Reason:   "volume metadata in CNS differs from Kubernetes",
+		})
+	}
+	if !r.remediate(driftreportv1alpha1.DriftCategoryPVWithoutVolume) {
+		createSpecArray = nil
+	}
+	if !r.remediate(driftreportv1alpha1.DriftCategoryMetadataMismatch) {
+		for volumeID := range reported {
+			markFullSyncVolumePending(r.vc, volumeID)
+		}
+		updateSpecArray = nil
+	}
+	return createSpecArray, updateSpecArray
+}
+
+// reportOrphanedVolumes records the CNS volumes without a PV which were
+// already found without a PV by a previous full sync.
+func (r *driftReport) reportOrphanedVolumes(cnsVolumes []cnstypes.CnsVolume, k8sPVMap map[string]string) {
+	if r == nil {
+		return
+	}
+	for _, volume := range cnsVolumes {
+		volumeID := volume.VolumeId.Id
+		if _, existsInK8s := k8sPVMap[volumeID]; existsInK8s {
+			continue
+		}
+		if cnsDeletionMap[r.vc][volumeID] || pvMissingLabeledMap[r.vc][volumeID] {
+			r.add(driftreportv1alpha1.DriftCategoryOrphanedVolume, driftreportv1alpha1.DriftItem{
+				VolumeID: volumeID,
+				Name:     volume.Name,
+				Reason:   "no PV of the volume in Kubernetes",
+			})
+		}
+	}
+}
+
+// filterOrphanedVolumeSpecs returns the pv_missing label update specs to
+// apply, none if the orphaned volumes are not remediated.
+func (r *driftReport) filterOrphanedVolumeSpecs(
+	missingPVUpdateSpecs []cnstypes.CnsVolumeMetadataUpdateSpec) []cnstypes.CnsVolumeMetadataUpdateSpec {
+	if r.remediate(driftreportv1alpha1.DriftCategoryOrphanedVolume) {
+		return missingPVUpdateSpecs
+	}
+	// Forget the volumes which are not labeled, so that they are labeled once
+	// the orphaned volumes are remediated.
+	for _, updateSpec := range missingPVUpdateSpecs {
+		delete(pvMissingLabeledMap[r.vc], updateSpec.VolumeId.Id)
+	}
+	return nil
+}
+
+// reportStaleVolumeOperationRequests records the CnsVolumeOperationRequests of
+// the vCenter whose operation is in progress for longer than
+// staleVolumeOperationRequestAge, and deletes them if remediated. They are
+// read through the store of the CSI controller, which may keep them in
+// ConfigMaps instead of CRs.
+func (r *driftReport) reportStaleVolumeOperationRequests(ctx context.Context,
+	store cnsvolumeoperationrequest.VolumeOperationRequest) error {
+	log := logger.GetLogger(ctx)
+	category := driftreportv1alpha1.DriftCategoryStaleVolumeOperationRequest
+	requests, err := store.ListRequestDetails(ctx)
+	if err != nil {
+		return err
+	}
+	cutoffTime := time.Now().Add(-staleVolumeOperationRequestAge)
+	for _, request := range requests {
+		latestOperation := request.OperationDetails
+		if latestOperation == nil {
+			continue
+		}
+		if latestOperation.TaskStatus != cnsvolumeoperationrequest.TaskInvocationStatusInProgress ||
+			latestOperation.TaskInvocationTimestamp.Time.After(cutoffTime) {
+			continue
+		}
+		if latestOperation.VCenterServer != r.vc && (latestOperation.VCenterServer != "" || !r.singleVC) {
+			continue
+		}
+		item := r.add(category, driftreportv1alpha1.DriftItem{
+			VolumeID:  request.VolumeID,
+			Kind:      "CnsVolumeOperationRequest",
+			Name:      request.Name,
+			Namespace: cnsconfig.GetCSINamespace(),
+			Reason: "task " + latestOperation.TaskID + " in progress since " +
+				latestOperation.TaskInvocationTimestamp.UTC().Format(time.RFC3339),
+		})
+		if !r.remediate(category) {
+			continue
+		}
+		if err := store.DeleteRequestDetails(ctx, request.Name); err != nil {
+			log.Warnf("FullSync for VC %s: failed to delete the stale CnsVolumeOperationRequest %q. Err: %v",
+				r.vc, request.Name, err)
+			if item != nil {
+				item.Error = err.Error()
+			}
+			continue
+		}
+		log.Infof("FullSync for VC %s: deleted the stale CnsVolumeOperationRequest %q", r.vc, request.Name)
+	}
+	return nil
+}
+
+// reportStaleVolumeAttachments records the VolumeAttachments of the driver
+// whose node no longer exists for a PV of the vCenter, or whose PV no longer
+// exists, and deletes them if remediated and already found stale by the
+// previous full sync.
+func (r *driftReport) reportStaleVolumeAttachments(ctx context.Context, k8sClient clientset.Interface,
+	metadataSyncer *metadataSyncInformer, pvToVolumeID map[string]string) error {
+	log := logger.GetLogger(ctx)
+	category := driftreportv1alpha1.DriftCategoryStaleVolumeAttachment
+	staleVolumeAttachmentsLock.Lock()
+	defer staleVolumeAttachmentsLock.Unlock()
+	previouslyStale := staleVolumeAttachmentsMap[r.vc]
+	stale := make(map[apitypes.UID]bool)
+	nodes, err := k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	nodeNames := make(map[string]bool)
+	for _, node := range nodes.Items {
+		nodeNames[node.Name] = true
+	}
+	volumeAttachments, err := k8sClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		staleVolumeAttachmentsMap[r.vc] = stale
+	}()
+	for i := range volumeAttachments.Items {
+		va := &volumeAttachments.Items[i]
+		if va.Spec.Attacher != csitypes.Name || va.Spec.Source.PersistentVolumeName == nil {
+			continue
+		}
+		pvName := *va.Spec.Source.PersistentVolumeName
+		var reason string
+		_, err := metadataSyncer.pvLister.Get(pvName)
+		switch {
+		case apierrors.IsNotFound(err):
+			if !r.singleVC {
+				continue
+			}
+			reason = "PV " + pvName + " not found"
+		case err != nil:
+			return err
+		case pvToVolumeID[pvName] != "" && !nodeNames[va.Spec.NodeName]:
+			reason = "node " + va.Spec.NodeName + " not found"
+		default:
+			continue
+		}
+		item := r.add(category, driftreportv1alpha1.DriftItem{
+			VolumeID: pvToVolumeID[pvName],
+			Kind:     "VolumeAttachment",
+			Name:     va.Name,
+			Reason:   reason,
+		})
+		stale[va.UID] = true
+		if !r.remediate(category) || va.DeletionTimestamp != nil {
+			continue
+		}
+		if !previouslyStale[va.UID] {
+			log.Infof("FullSync for VC %s: VolumeAttachment %q is stale, %s. It is deleted if still stale "+
+				"in the next full sync", r.vc, va.Name, reason)
+			continue
+		}
+		err = k8sClient.StorageV1().VolumeAttachments().Delete(ctx, va.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Warnf("FullSync for VC %s: failed to delete the stale VolumeAttachment %q. Err: %v",
+				r.vc, va.Name, err)
+			if item != nil {
+				item.Error = err.Error()
+			}
+			continue
+		}
+		log.Infof("FullSync for VC %s: deleted the stale VolumeAttachment %q", r.vc, va.Name)
+	}
+	return nil
+}
+
+// save creates or updates the CnsDriftReport of the vCenter with the drift
+// found by the full sync.
+func (r *driftReport) save(ctx context.Context, c client.Client) error {
+	log := logger.GetLogger(ctx)
+	status := driftreportv1alpha1.CnsDriftReportStatus{
+		VCenter:        r.vc,
+		DryRun:         r.dryRun,
+		StartTime:      metav1.NewTime(r.startTime),
+		CompletionTime: metav1.Now(),
+	}
+	for _, category := range driftreportv1alpha1.DriftCategories {
+		categoryStatus := *r.categories[category]
+		categoryStatus.Remediated = categoryStatus.Count > 0 && r.remediate(category)
+		status.Categories = append(status.Categories, categoryStatus)
+	}
+	name := driftreportv1alpha1.GetCnsDriftReportName(r.vc)
+	report := &driftreportv1alpha1.CnsDriftReport{}
+	err := c.Get(ctx, apitypes.NamespacedName{Name: name}, report)
+	if apierrors.IsNotFound(err) {
+		report = &driftreportv1alpha1.CnsDriftReport{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     status,
+		}
+		if err := c.Create(ctx, report); err != nil {
+			return err
+		}
+		log.Infof("FullSync for VC %s: created the CnsDriftReport %q", r.vc, name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	report.Status = status
+	return c.Update(ctx, report)
+}
+
+// reportStaleObjectsAndSave records the stale CnsVolumeOperationRequests and
+// VolumeAttachments of the full sync, and saves the drift report. Failures
+// are logged, as the report does not change the outcome of the full sync.
+func (r *driftReport) reportStaleObjectsAndSave(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	pvToVolumeID map[string]string) {
+	if r == nil {
+		return
+	}
+	log := logger.GetLogger(ctx)
+	c, err := newDriftReportClient(ctx)
+	if err != nil {
+		log.Warnf("FullSync for VC %s: failed to create the client of the CnsDriftReports. Err: %v", r.vc, err)
+		return
+	}
+	store, err := newDriftReportOperationStore(ctx, metadataSyncer.configInfo.Cfg)
+	if err == nil {
+		err = r.reportStaleVolumeOperationRequests(ctx, store)
+	}
+	if err != nil {
+		log.Warnf("FullSync for VC %s: failed to find the stale CnsVolumeOperationRequests. Err: %v", r.vc, err)
+	}
+	k8sClient, err := k8sNewClient(ctx)
+	if err == nil {
+		err = r.reportStaleVolumeAttachments(ctx, k8sClient, metadataSyncer, pvToVolumeID)
+	}
+	if err != nil {
+		log.Warnf("FullSync for VC %s: failed to find the stale VolumeAttachments. Err: %v", r.vc, err)
+	}
+	if err := r.save(ctx, c); err != nil {
+		log.Warnf("FullSync for VC %s: failed to save the CnsDriftReport. Err: %v", r.vc, err)
+	}
+}
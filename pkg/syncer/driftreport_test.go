@@ -0,0 +1,244 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/unittestcommon"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/types"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis"
+	driftreportv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsoperator/cnsdriftreport/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsvolumeoperationrequest"
+)
+
+// newTestDriftReport returns a drift report of vc-1 whose categories have
+// policy, except the PVs without volume which are remediated.
+func newTestDriftReport(dryRun bool, policy string) *driftReport {
+	report := &driftReport{
+		vc:        "vc-1",
+		dryRun:    dryRun,
+		startTime: time.Now(),
+		policies: map[driftreportv1alpha1.DriftCategory]string{
+			driftreportv1alpha1.DriftCategoryOrphanedVolume:              policy,
+			driftreportv1alpha1.DriftCategoryPVWithoutVolume:             cnsconfig.DriftRemediationPolicyRemediate,
+			driftreportv1alpha1.DriftCategoryMetadataMismatch:            policy,
+			driftreportv1alpha1.DriftCategoryStaleVolumeOperationRequest: policy,
+			driftreportv1alpha1.DriftCategoryStaleVolumeAttachment:       policy,
+		},
+		singleVC:   true,
+		categories: make(map[driftreportv1alpha1.DriftCategory]*driftreportv1alpha1.DriftCategoryStatus),
+	}
+	for _, category := range driftreportv1alpha1.DriftCategories {
+		report.categories[category] = &driftreportv1alpha1.DriftCategoryStatus{
+			Category: category,
+			Policy:   report.policies[category],
+		}
+	}
+	return report
+}
+
+func TestDriftReportVolumeSpecs(t *testing.T) {
+	createSpecs := []cnstypes.CnsVolumeCreateSpec{{
+		Name: "pv-1",
+		BackingObjectDetails: &cnstypes.CnsBlockBackingDetails{
+			BackingDiskId: "volume-1",
+		},
+	}}
+	updateSpecs := []cnstypes.CnsVolumeMetadataUpdateSpec{
+		{VolumeId: cnstypes.CnsVolumeId{Id: "volume-2"}},
+		// A block volume with two Pods has an update spec per Pod.
+		{VolumeId: cnstypes.CnsVolumeId{Id: "volume-2"}},
+	}
+	pvToVolumeID := map[string]string{"pv-1": "volume-1", "pv-2": "volume-2"}
+
+	var report *driftReport
+	create, update := report.reportVolumeSpecs(createSpecs, updateSpecs, pvToVolumeID)
+	assert.Len(t, create, 1, "the full sync without drift report must remediate every category")
+	assert.Len(t, update, 2)
+
+	defer takeFullSyncPendingVolumes("vc-1")
+	report = newTestDriftReport(false, cnsconfig.DriftRemediationPolicyReport)
+	create, update = report.reportVolumeSpecs(createSpecs, updateSpecs, pvToVolumeID)
+	assert.Len(t, create, 1, "the PVs without volume must be remediated")
+	assert.Empty(t, update, "the metadata mismatches must only be reported")
+	mismatches := report.categories[driftreportv1alpha1.DriftCategoryMetadataMismatch]
+	require.Equal(t, 1, mismatches.Count)
+	assert.Equal(t, "pv-2", mismatches.Items[0].Name)
+	assert.Equal(t, "volume-1",
+		report.categories[driftreportv1alpha1.DriftCategoryPVWithoutVolume].Items[0].VolumeID)
+	assert.True(t, takeFullSyncPendingVolumes("vc-1")["volume-2"],
+		"the reported metadata mismatches must be reconciled again by the next full sync")
+
+	report = newTestDriftReport(true, cnsconfig.DriftRemediationPolicyRemediate)
+	create, update = report.reportVolumeSpecs(createSpecs, updateSpecs, pvToVolumeID)
+	assert.Empty(t, create, "nothing must be remediated in dry-run")
+	assert.Empty(t, update, "nothing must be remediated in dry-run")
+}
+
+func TestDriftReportOrphanedVolumes(t *testing.T) {
+	origDeletionMap, origLabeledMap := cnsDeletionMap, pvMissingLabeledMap
+	defer func() { cnsDeletionMap, pvMissingLabeledMap = origDeletionMap, origLabeledMap }()
+	cnsDeletionMap = map[string]map[string]bool{"vc-1": {"volume-2": true}}
+	pvMissingLabeledMap = map[string]map[string]bool{"vc-1": {"volume-3": true}}
+
+	report := newTestDriftReport(false, cnsconfig.DriftRemediationPolicyReport)
+	report.reportOrphanedVolumes([]cnstypes.CnsVolume{
+		{VolumeId: cnstypes.CnsVolumeId{Id: "volume-1"}},
+		{VolumeId: cnstypes.CnsVolumeId{Id: "volume-2"}},
+		{VolumeId: cnstypes.CnsVolumeId{Id: "volume-3"}},
+		{VolumeId: cnstypes.CnsVolumeId{Id: "volume-4"}},
+	}, map[string]string{"volume-1": ""})
+	orphaned := report.categories[driftreportv1alpha1.DriftCategoryOrphanedVolume]
+	assert.Equal(t, 2, orphaned.Count, "only the volumes past the grace period must be reported")
+
+	specs := report.filterOrphanedVolumeSpecs([]cnstypes.CnsVolumeMetadataUpdateSpec{
+		{VolumeId: cnstypes.CnsVolumeId{Id: "volume-3"}},
+	})
+	assert.Empty(t, specs)
+	assert.False(t, pvMissingLabeledMap["vc-1"]["volume-3"],
+		"the volumes not labeled must be labeled once the orphaned volumes are remediated")
+}
+
+func TestDriftReportStaleObjectsAndSave(t *testing.T) {
+	ctx := logger.NewContextWithLogger(context.Background())
+	scheme := runtime.NewScheme()
+	require.NoError(t, internalapis.AddToScheme(scheme))
+	store, err := unittestcommon.InitFakeVolumeOperationRequestInterface()
+	require.NoError(t, err)
+	for _, request := range []struct {
+		name   string
+		status string
+		age    time.Duration
+	}{
+		{"stale", cnsvolumeoperationrequest.TaskInvocationStatusInProgress, 2 * time.Hour},
+		{"recent", cnsvolumeoperationrequest.TaskInvocationStatusInProgress, time.Minute},
+		{"done", cnsvolumeoperationrequest.TaskInvocationStatusSuccess, 2 * time.Hour},
+	} {
+		require.NoError(t, store.StoreRequestDetails(ctx, cnsvolumeoperationrequest.CreateVolumeOperationRequestDetails(
+			request.name, "", "", 0, nil, metav1.NewTime(time.Now().Add(-request.age)), "", "", "",
+			request.status, "", "")))
+	}
+	origStore := newDriftReportOperationStore
+	defer func() { newDriftReportOperationStore = origStore }()
+	newDriftReportOperationStore = func(ctx context.Context,
+		cfg *cnsconfig.Config) (cnsvolumeoperationrequest.VolumeOperationRequest, error) {
+		return store, nil
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	origClient := newDriftReportClient
+	defer func() { newDriftReportClient = origClient }()
+	newDriftReportClient = func(ctx context.Context) (client.Client, error) {
+		return c, nil
+	}
+
+	newVA := func(name, pvName, nodeName string) *storagev1.VolumeAttachment {
+		return &storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: apitypes.UID(name + "-uid")},
+			Spec: storagev1.VolumeAttachmentSpec{
+				Attacher: csitypes.Name,
+				NodeName: nodeName,
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+			},
+		}
+	}
+	k8sClient := k8sfake.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		newVA("va-1", "pv-1", "node-1"),
+		newVA("va-deleted-node", "pv-1", "node-2"),
+		newVA("va-deleted-pv", "pv-2", "node-1"),
+		newVA("va-recreated-node", "pv-1", "node-3"),
+	)
+	staleVolumeAttachmentsMap = make(map[string]map[apitypes.UID]bool)
+	origK8sClient := k8sNewClient
+	defer func() { k8sNewClient = origK8sClient }()
+	k8sNewClient = func(ctx context.Context) (clientset.Interface, error) {
+		return k8sClient, nil
+	}
+	pvLister, _, _ := newTestListers(t, &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-1"}})
+	metadataSyncer := &metadataSyncInformer{
+		pvLister:   pvLister,
+		configInfo: &cnsconfig.ConfigurationInfo{Cfg: &cnsconfig.Config{}},
+	}
+
+	report := newTestDriftReport(false, cnsconfig.DriftRemediationPolicyRemediate)
+	report.reportStaleObjectsAndSave(ctx, metadataSyncer, map[string]string{"pv-1": "volume-1"})
+
+	requests := report.categories[driftreportv1alpha1.DriftCategoryStaleVolumeOperationRequest]
+	require.Equal(t, 1, requests.Count)
+	assert.Equal(t, "stale", requests.Items[0].Name)
+	_, err = store.GetRequestDetails(ctx, "stale")
+	assert.True(t, apierrors.IsNotFound(err), "the stale CnsVolumeOperationRequest must be deleted")
+
+	attachments := report.categories[driftreportv1alpha1.DriftCategoryStaleVolumeAttachment]
+	assert.Equal(t, 3, attachments.Count)
+	vas, err := k8sClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, vas.Items, 4, "the VolumeAttachments found stale once must not be deleted")
+
+	// node-3 is recreated before the next full sync, which deletes the
+	// VolumeAttachments still stale.
+	_, err = k8sClient.CoreV1().Nodes().Create(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-3"}},
+		metav1.CreateOptions{})
+	require.NoError(t, err)
+	report = newTestDriftReport(false, cnsconfig.DriftRemediationPolicyRemediate)
+	report.reportStaleObjectsAndSave(ctx, metadataSyncer, map[string]string{"pv-1": "volume-1"})
+	attachments = report.categories[driftreportv1alpha1.DriftCategoryStaleVolumeAttachment]
+	assert.Equal(t, 2, attachments.Count)
+	vas, err = k8sClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for _, va := range vas.Items {
+		names = append(names, va.Name)
+	}
+	assert.ElementsMatch(t, []string{"va-1", "va-recreated-node"}, names,
+		"the VolumeAttachments found stale twice must be deleted")
+
+	saved := &driftreportv1alpha1.CnsDriftReport{}
+	require.NoError(t, c.Get(ctx, apitypes.NamespacedName{
+		Name: driftreportv1alpha1.GetCnsDriftReportName("vc-1")}, saved))
+	assert.Equal(t, "vc-1", saved.Status.VCenter)
+	require.Len(t, saved.Status.Categories, len(driftreportv1alpha1.DriftCategories))
+	for _, category := range saved.Status.Categories {
+		assert.Equal(t, category.Count > 0, category.Remediated, "category %s", category.Category)
+	}
+
+	// The report of the next full sync updates the CnsDriftReport.
+	report = newTestDriftReport(true, cnsconfig.DriftRemediationPolicyRemediate)
+	report.reportStaleObjectsAndSave(ctx, metadataSyncer, map[string]string{"pv-1": "volume-1"})
+	require.NoError(t, c.Get(ctx, apitypes.NamespacedName{
+		Name: driftreportv1alpha1.GetCnsDriftReportName("vc-1")}, saved))
+	assert.True(t, saved.Status.DryRun)
+}
@@ -0,0 +1,596 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sort"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vsanfstypes "github.com/vmware/govmomi/vsan/vsanfs/types"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelistersv1 "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/types"
+)
+
+const (
+	// annFileVolumeACL is the annotation of a file PV holding the net
+	// permissions its ACL was last tightened to, in the JSON format of the
+	// net permissions annotation of the PVCs.
+	annFileVolumeACL = "csi.vsphere.vmware.com/file-volume-acl"
+	// fileVolumeACLMaxRetries is the number of retries of a failed reconcile
+	// of the ACL of a file volume, before it is left to the next resync.
+	fileVolumeACLMaxRetries = 8
+	// fileVolumeACLRetryIntervalStart is the first retry interval of a failed
+	// reconcile of the ACL of a file volume.
+	fileVolumeACLRetryIntervalStart = time.Second
+	// fileVolumeACLRetryIntervalMax is the maximum retry interval of a failed
+	// reconcile of the ACL of a file volume.
+	fileVolumeACLRetryIntervalMax = 5 * time.Minute
+	// fileVolumeACLResultInSync is the result of a reconcile which found the
+	// ACL of the file volume matching the nodes consuming it.
+	fileVolumeACLResultInSync = "in-sync"
+	// fileVolumeACLResultUpdated is the result of a reconcile which updated
+	// the ACL of the file volume.
+	fileVolumeACLResultUpdated = "updated"
+	// fileVolumeACLResultFailed is the result of a failed reconcile.
+	fileVolumeACLResultFailed = "failed"
+)
+
+// fileVolumeACLReconciler tightens the ACL of the file volumes to the
+// internal IPs of the nodes consuming them, that is the nodes running a pod
+// using the volume or with a VolumeAttachment of the volume. The ACL never
+// grants more than the net permissions of the volume. The IP of a node is kept
+// in the ACL for a grace period after the node stopped consuming the volume
+// or changed its IP. A single worker reconciles the volumes queued by the
+// events of the Nodes, Pods and VolumeAttachments, and by periodic resyncs.
+type fileVolumeACLReconciler struct {
+	metadataSyncer *metadataSyncInformer
+	nodeLister     corelisters.NodeLister
+	nodeSynced     cache.InformerSynced
+	vaLister       storagelistersv1.VolumeAttachmentLister
+	k8sClient      clientset.Interface
+	// queue holds the names of the PVs to reconcile.
+	queue        workqueue.TypedRateLimitingInterface[string]
+	gracePeriod  time.Duration
+	resyncPeriod time.Duration
+	// lastConsumed maps the names of the PVs to the last time each IP
+	// consumed the volume. It is only accessed by the worker.
+	lastConsumed map[string]map[string]time.Time
+	// drifted holds the names of the PVs whose ACL could not be reconciled.
+	// It is only accessed by the worker.
+	drifted map[string]bool
+}
+
+// isFileVolumeACLTighteningEnabled returns true if the ACL of the file
+// volumes is tightened to the nodes consuming them.
+func isFileVolumeACLTighteningEnabled(ctx context.Context, metadataSyncer *metadataSyncInformer) bool {
+	return metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.FileVolumeACLTightening)
+}
+
+// newFileVolumeACLReconciler returns the reconciler of the ACL of the file
+// volumes, and registers its listeners on the Nodes, Pods and
+// VolumeAttachments. It must be called before the informers are started.
+func newFileVolumeACLReconciler(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	k8sClient clientset.Interface) (*fileVolumeACLReconciler, error) {
+	log := logger.GetLogger(ctx)
+	informerManager := metadataSyncer.k8sInformerManager
+	informerManager.InitVolumeAttachmentInformer()
+	cfg := metadataSyncer.configInfo.Cfg.FileVolumeACL
+	r := &fileVolumeACLReconciler{
+		metadataSyncer: metadataSyncer,
+		nodeLister:     informerManager.GetNodeLister(),
+		vaLister:       informerManager.GetVolumeAttachmentLister(),
+		k8sClient:      k8sClient,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.NewTypedItemExponentialFailureRateLimiter[string](fileVolumeACLRetryIntervalStart,
+				fileVolumeACLRetryIntervalMax),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "file-volume-acl"}),
+		gracePeriod:  time.Duration(cfg.GracePeriodInMin) * time.Minute,
+		resyncPeriod: time.Duration(cfg.ResyncIntervalInMin) * time.Minute,
+		lastConsumed: make(map[string]map[string]time.Time),
+		drifted:      make(map[string]bool),
+	}
+	err := informerManager.AddNodeListener(ctx,
+		func(obj interface{}) { r.enqueueAll(ctx) },
+		func(oldObj, newObj interface{}) {
+			oldNode, ok := oldObj.(*v1.Node)
+			newNode, ok2 := newObj.(*v1.Node)
+			if !ok || !ok2 || !equalStringSets(getNodeInternalIPs(oldNode), getNodeInternalIPs(newNode)) {
+				r.enqueueAll(ctx)
+			}
+		},
+		func(obj interface{}) { r.enqueueAll(ctx) })
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to listen on nodes. Error: %v", err)
+	}
+	r.nodeSynced = informerManager.NodeInformerSynced()
+	err = informerManager.AddPodListener(ctx,
+		func(obj interface{}) { r.enqueuePodVolumes(obj) },
+		func(oldObj, newObj interface{}) {
+			oldPod, ok := oldObj.(*v1.Pod)
+			newPod, ok2 := newObj.(*v1.Pod)
+			if !ok || !ok2 || oldPod.Spec.NodeName != newPod.Spec.NodeName ||
+				oldPod.Status.Phase != newPod.Status.Phase {
+				r.enqueuePodVolumes(newObj)
+			}
+		},
+		func(obj interface{}) { r.enqueuePodVolumes(obj) })
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to listen on pods. Error: %v", err)
+	}
+	err = informerManager.AddVolumeAttachmentListener(ctx,
+		func(obj interface{}) { r.enqueueVolumeAttachmentVolume(obj) },
+		func(oldObj, newObj interface{}) { r.enqueueVolumeAttachmentVolume(newObj) },
+		func(obj interface{}) { r.enqueueVolumeAttachmentVolume(obj) })
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to listen on volume attachments. Error: %v", err)
+	}
+	return r, nil
+}
+
+// run waits for the node cache, then reconciles the queued volumes and
+// queues all the file volumes every resync period until ctx is done.
+func (r *fileVolumeACLReconciler) run(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	if r.nodeSynced != nil && !cache.WaitForCacheSync(ctx.Done(), r.nodeSynced) {
+		log.Errorf("FileVolumeACL: failed to sync the node cache")
+		return
+	}
+	log.Infof("Starting the file volume ACL reconciler")
+	go wait.UntilWithContext(ctx, r.runWorker, time.Second)
+	go wait.UntilWithContext(ctx, r.enqueueAll, r.resyncPeriod)
+	go func() {
+		<-ctx.Done()
+		log.Info("Shutting down the file volume ACL reconciler")
+		r.queue.ShutDown()
+	}()
+}
+
+func (r *fileVolumeACLReconciler) runWorker(ctx context.Context) {
+	for r.processNextItem(ctx) {
+	}
+}
+
+// processNextItem reconciles the ACL of the next volume in the queue. It
+// returns false when the queue is shut down.
+func (r *fileVolumeACLReconciler) processNextItem(ctx context.Context) bool {
+	pvName, shutdown := r.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer r.queue.Done(pvName)
+	ctx = logger.NewContextWithLogger(ctx)
+	log := logger.GetLogger(ctx)
+	requeueAfter, err := r.reconcile(ctx, pvName)
+	if err == nil {
+		r.queue.Forget(pvName)
+		if requeueAfter > 0 {
+			r.queue.AddAfter(pvName, requeueAfter)
+		}
+		return true
+	}
+	prometheus.FileVolumeACLReconcilesCounterVec.WithLabelValues(fileVolumeACLResultFailed).Inc()
+	if r.queue.NumRequeues(pvName) < fileVolumeACLMaxRetries {
+		log.Warnf("FileVolumeACL: failed to reconcile the ACL of PV %q, retrying. Error: %v", pvName, err)
+		r.queue.AddRateLimited(pvName)
+		return true
+	}
+	log.Errorf("FileVolumeACL: failed to reconcile the ACL of PV %q after %d retries, leaving it to the "+
+		"next resync. Error: %v", pvName, fileVolumeACLMaxRetries, err)
+	r.queue.Forget(pvName)
+	return true
+}
+
+// enqueueAll queues all the file volumes of the cluster.
+func (r *fileVolumeACLReconciler) enqueueAll(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	pvs, err := r.metadataSyncer.pvLister.List(labels.Everything())
+	if err != nil {
+		log.Errorf("FileVolumeACL: failed to list the PVs. Error: %v", err)
+		return
+	}
+	for _, pv := range pvs {
+		if isCSIFileVolume(pv) {
+			r.queue.Add(pv.Name)
+		}
+	}
+}
+
+// enqueuePodVolumes queues the volumes of the PVCs used by the given pod.
+func (r *fileVolumeACLReconciler) enqueuePodVolumes(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	for _, volume := range pod.Spec.Volumes {
+		claimName := getPodVolumeClaimName(pod, volume)
+		if claimName == "" {
+			continue
+		}
+		pvc, err := r.metadataSyncer.pvcLister.PersistentVolumeClaims(pod.Namespace).Get(claimName)
+		if err != nil || pvc.Spec.VolumeName == "" {
+			continue
+		}
+		r.queue.Add(pvc.Spec.VolumeName)
+	}
+}
+
+// enqueueVolumeAttachmentVolume queues the volume of the given
+// VolumeAttachment.
+func (r *fileVolumeACLReconciler) enqueueVolumeAttachmentVolume(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	va, ok := obj.(*storagev1.VolumeAttachment)
+	if !ok || va.Spec.Source.PersistentVolumeName == nil {
+		return
+	}
+	r.queue.Add(*va.Spec.Source.PersistentVolumeName)
+}
+
+// reconcile tightens the ACL of the file volume of PV pvName to the nodes
+// consuming it. It returns the time after which the volume must be reconciled
+// again to remove the IPs whose grace period is not over.
+func (r *fileVolumeACLReconciler) reconcile(ctx context.Context, pvName string) (time.Duration, error) {
+	log := logger.GetLogger(ctx)
+	pv, err := r.metadataSyncer.pvLister.Get(pvName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			delete(r.lastConsumed, pvName)
+			r.setDrifted(pvName, false)
+			return 0, nil
+		}
+		return 0, err
+	}
+	if !isCSIFileVolume(pv) || pv.Spec.ClaimRef == nil || pv.Status.Phase != v1.VolumeBound {
+		return 0, nil
+	}
+	volumeID := pv.Spec.CSI.VolumeHandle
+	netPerms, err := r.getNetPermissions(ctx, pv)
+	if err != nil {
+		r.setDrifted(pvName, true)
+		return 0, err
+	}
+	consumerIPs, err := r.getConsumerIPs(pv)
+	if err != nil {
+		return 0, err
+	}
+	current, tightened, err := getFileVolumeACL(pv)
+	if err != nil {
+		log.Warnf("FileVolumeACL: ignoring the invalid annotation %q of PV %q. Error: %v",
+			annFileVolumeACL, pvName, err)
+	}
+	if !tightened {
+		current = netPerms
+	}
+
+	now := time.Now()
+	lastConsumed, found := r.lastConsumed[pvName]
+	if !found {
+		// The IPs granted before the restart of the syncer start their grace
+		// period now.
+		lastConsumed = make(map[string]time.Time)
+		for _, netPerm := range current {
+			if tightened && net.ParseIP(netPerm.Ips) != nil {
+				lastConsumed[netPerm.Ips] = now
+			}
+		}
+		r.lastConsumed[pvName] = lastConsumed
+	}
+	for ip := range consumerIPs {
+		lastConsumed[ip] = now
+	}
+	var requeueAfter time.Duration
+	var ips []string
+	for ip, consumedAt := range lastConsumed {
+		if !consumerIPs[ip] {
+			remaining := r.gracePeriod - now.Sub(consumedAt)
+			if remaining <= 0 {
+				delete(lastConsumed, ip)
+				continue
+			}
+			if requeueAfter == 0 || remaining < requeueAfter {
+				requeueAfter = remaining
+			}
+		}
+		ips = append(ips, ip)
+	}
+	target := getTightenedNetPermissions(ips, netPerms)
+
+	if tightened && equalNetPermissions(current, target) {
+		r.setDrifted(pvName, false)
+		prometheus.FileVolumeACLReconcilesCounterVec.WithLabelValues(fileVolumeACLResultInSync).Inc()
+		return requeueAfter, nil
+	}
+	r.setDrifted(pvName, true)
+	log.Infof("FileVolumeACL: updating the ACL of volume %q of PV %q from %s to %s", volumeID, pvName,
+		formatNetPermissions(current), formatNetPermissions(target))
+	_, volumeManager, err := getVcHostAndVolumeManagerForVolumeID(ctx, r.metadataSyncer, volumeID)
+	if err != nil {
+		return 0, err
+	}
+	if err := volumeManager.ConfigureVolumeACLs(ctx,
+		getNetPermissionsACLConfigureSpec(volumeID, current, target)); err != nil {
+		return 0, err
+	}
+	if err := r.patchFileVolumeACL(ctx, pv, target); err != nil {
+		return 0, err
+	}
+	r.setDrifted(pvName, false)
+	prometheus.FileVolumeACLReconcilesCounterVec.WithLabelValues(fileVolumeACLResultUpdated).Inc()
+	return requeueAfter, nil
+}
+
+// getNetPermissions returns the net permissions of the file volume of pv,
+// which bound its ACL.
+func (r *fileVolumeACLReconciler) getNetPermissions(ctx context.Context,
+	pv *v1.PersistentVolume) ([]*cnsconfig.NetPermissionConfig, error) {
+	var annotation, scParameter string
+	if r.metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.FileVolumeNetPermissions) {
+		pvc, err := r.metadataSyncer.pvcLister.PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Get(
+			pv.Spec.ClaimRef.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if pvc != nil {
+			annotation = pvc.Annotations[common.AnnNetPermissions]
+		}
+		scParameter, err = getStorageClassNetPermissions(ctx, r.k8sClient, pv.Spec.StorageClassName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	netPerms, err := getEffectiveNetPermissions(annotation, scParameter,
+		r.metadataSyncer.configInfo.Cfg.NetPermissions)
+	if err != nil {
+		return nil, err
+	}
+	if len(netPerms) == 0 {
+		// The file volumes created without net permissions are accessible
+		// from all IPs.
+		netPerms = []*cnsconfig.NetPermissionConfig{{
+			Ips:         "*",
+			Permissions: vsanfstypes.VsanFileShareAccessTypeREAD_WRITE,
+		}}
+	}
+	return netPerms, nil
+}
+
+// getConsumerIPs returns the internal IPs of the nodes running a pod using
+// the volume of pv, or with a VolumeAttachment of pv.
+func (r *fileVolumeACLReconciler) getConsumerIPs(pv *v1.PersistentVolume) (map[string]bool, error) {
+	nodeNames := make(map[string]bool)
+	pods, err := r.metadataSyncer.podLister.Pods(pv.Spec.ClaimRef.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if getPodVolumeClaimName(pod, volume) == pv.Spec.ClaimRef.Name {
+				nodeNames[pod.Spec.NodeName] = true
+			}
+		}
+	}
+	vas, err := r.vaLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, va := range vas {
+		if va.Spec.Source.PersistentVolumeName != nil && *va.Spec.Source.PersistentVolumeName == pv.Name &&
+			va.DeletionTimestamp == nil {
+			nodeNames[va.Spec.NodeName] = true
+		}
+	}
+	ips := make(map[string]bool)
+	for nodeName := range nodeNames {
+		node, err := r.nodeLister.Get(nodeName)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, ip := range getNodeInternalIPs(node) {
+			ips[ip] = true
+		}
+	}
+	return ips, nil
+}
+
+// patchFileVolumeACL records the net permissions the ACL of the volume of pv
+// was tightened to in its annotation.
+func (r *fileVolumeACLReconciler) patchFileVolumeACL(ctx context.Context, pv *v1.PersistentVolume,
+	netPerms []*cnsconfig.NetPermissionConfig) error {
+	value, err := json.Marshal(netPerms)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{annFileVolumeACL: string(value)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.k8sClient.CoreV1().PersistentVolumes().Patch(ctx, pv.Name, types.MergePatchType, patch,
+		metav1.PatchOptions{})
+	return err
+}
+
+// setDrifted records whether the ACL of the volume of PV pvName could not be
+// reconciled, and updates the drift metric.
+func (r *fileVolumeACLReconciler) setDrifted(pvName string, drifted bool) {
+	if drifted {
+		r.drifted[pvName] = true
+	} else {
+		delete(r.drifted, pvName)
+	}
+	prometheus.FileVolumeACLDriftGauge.Set(float64(len(r.drifted)))
+}
+
+// getFileVolumeACL returns the net permissions recorded in the annotation of
+// pv, and false if the ACL of its volume was never tightened.
+func getFileVolumeACL(pv *v1.PersistentVolume) ([]*cnsconfig.NetPermissionConfig, bool, error) {
+	value, found := pv.Annotations[annFileVolumeACL]
+	if !found {
+		return nil, false, nil
+	}
+	var netPerms []*cnsconfig.NetPermissionConfig
+	if err := json.Unmarshal([]byte(value), &netPerms); err != nil {
+		return nil, false, err
+	}
+	return netPerms, true, nil
+}
+
+// getTightenedNetPermissions returns the net permissions granting each of the
+// given IPs the most restrictive of the net permissions containing it. The IPs
+// which are not granted access by netPerms are left out. All the IPs are
+// denied access if none is granted access.
+func getTightenedNetPermissions(ips []string,
+	netPerms []*cnsconfig.NetPermissionConfig) []*cnsconfig.NetPermissionConfig {
+	rank := map[vsanfstypes.VsanFileShareAccessType]int{
+		vsanfstypes.VsanFileShareAccessTypeNO_ACCESS:  0,
+		vsanfstypes.VsanFileShareAccessTypeREAD_ONLY:  1,
+		vsanfstypes.VsanFileShareAccessTypeREAD_WRITE: 2,
+	}
+	sort.Strings(ips)
+	var tightened []*cnsconfig.NetPermissionConfig
+	for _, ip := range ips {
+		var granted *cnsconfig.NetPermissionConfig
+		for _, netPerm := range netPerms {
+			if !cnsconfig.NetPermissionIpsContain(netPerm.Ips, net.ParseIP(ip)) {
+				continue
+			}
+			if granted == nil {
+				granted = &cnsconfig.NetPermissionConfig{Ips: ip, Permissions: netPerm.Permissions}
+			} else if rank[netPerm.Permissions] < rank[granted.Permissions] {
+				granted.Permissions = netPerm.Permissions
+			}
+			granted.RootSquash = granted.RootSquash || netPerm.RootSquash
+		}
+		if granted != nil && granted.Permissions != vsanfstypes.VsanFileShareAccessTypeNO_ACCESS {
+			tightened = append(tightened, granted)
+		}
+	}
+	if len(tightened) == 0 {
+		return []*cnsconfig.NetPermissionConfig{{
+			Ips:         "*",
+			Permissions: vsanfstypes.VsanFileShareAccessTypeNO_ACCESS,
+		}}
+	}
+	return tightened
+}
+
+// equalNetPermissions returns true if a and b hold the same net permissions,
+// regardless of their order.
+func equalNetPermissions(a, b []*cnsconfig.NetPermissionConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	netPerms := make(map[cnsconfig.NetPermissionConfig]bool)
+	for _, netPerm := range a {
+		netPerms[*netPerm] = true
+	}
+	for _, netPerm := range b {
+		if !netPerms[*netPerm] {
+			return false
+		}
+	}
+	return true
+}
+
+// formatNetPermissions returns the JSON format of netPerms for logging.
+func formatNetPermissions(netPerms []*cnsconfig.NetPermissionConfig) string {
+	value, err := json.Marshal(netPerms)
+	if err != nil {
+		return err.Error()
+	}
+	return string(value)
+}
+
+// isCSIFileVolume returns true if pv is a file volume of the driver.
+func isCSIFileVolume(pv *v1.PersistentVolume) bool {
+	return pv.Spec.CSI != nil && pv.Spec.CSI.Driver == csitypes.Name && IsFileVolume(pv)
+}
+
+// getPodVolumeClaimName returns the name of the PVC of the given volume of
+// pod, or "" if the volume is not backed by a PVC.
+func getPodVolumeClaimName(pod *v1.Pod, volume v1.Volume) string {
+	if volume.PersistentVolumeClaim != nil {
+		return volume.PersistentVolumeClaim.ClaimName
+	}
+	if volume.Ephemeral != nil {
+		return pod.Name + "-" + volume.Name
+	}
+	return ""
+}
+
+// getNodeInternalIPs returns the internal IPs of node.
+func getNodeInternalIPs(node *v1.Node) []string {
+	var ips []string
+	for _, address := range node.Status.Addresses {
+		if address.Type == v1.NodeInternalIP {
+			ips = append(ips, address.Address)
+		}
+	}
+	return ips
+}
+
+// equalStringSets returns true if a and b hold the same strings, regardless
+// of their order.
+func equalStringSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, s := range a {
+		set[s] = true
+	}
+	for _, s := range b {
+		if !set[s] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/unittestcommon"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/types"
+)
+
+func TestFileVolumeACLReconcile(t *testing.T) {
+	ctx := logger.NewContextWithLogger(context.Background())
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+		Spec: v1.PersistentVolumeSpec{
+			AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+			StorageClassName: "sc-1",
+			ClaimRef:         &v1.ObjectReference{Namespace: "default", Name: "pvc-1"},
+			PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{
+				Driver:       csitypes.Name,
+				VolumeHandle: "file:volume-1",
+			}},
+		},
+		Status: v1.PersistentVolumeStatus{Phase: v1.VolumeBound},
+	}
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-1", Namespace: "default"},
+		Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv-1"},
+	}
+	pvName := "pv-1"
+	va := &storagev1.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{Name: "va-1"},
+		Spec: storagev1.VolumeAttachmentSpec{
+			NodeName: "node-3",
+			Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+		},
+	}
+	k8sClient := k8sfake.NewSimpleClientset(pv, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "sc-1"}})
+	_, pvcLister, vaLister := newTestListers(t, pvc, va)
+	pvIdx := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, pvIdx.Add(pv))
+
+	nodeIdx := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for name, ip := range map[string]string{"node-1": "10.0.0.1", "node-2": "10.0.0.2", "node-3": "192.168.0.3"} {
+		require.NoError(t, nodeIdx.Add(&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.NodeStatus{Addresses: []v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: ip},
+				{Type: v1.NodeHostName, Address: name},
+			}},
+		}))
+	}
+	podIdx := cache.NewIndexer(cache.MetaNamespaceKeyFunc,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+		Spec: v1.PodSpec{
+			NodeName: "node-1",
+			Volumes: []v1.Volume{{Name: "data", VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-1"},
+			}}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+	require.NoError(t, podIdx.Add(pod))
+
+	coCommonInterface, err := unittestcommon.GetFakeContainerOrchestratorInterface(common.Kubernetes)
+	require.NoError(t, err)
+	configInfo := &cnsconfig.ConfigurationInfo{Cfg: &cnsconfig.Config{
+		NetPermissions: map[string]*cnsconfig.NetPermissionConfig{
+			"nodes": {Ips: "10.0.0.0/24", Permissions: "READ_WRITE", RootSquash: true},
+		},
+	}}
+	configInfo.Cfg.Global.VCenterIP = "vc-1"
+	configInfo.Cfg.VirtualCenter = map[string]*cnsconfig.VirtualCenterConfig{"vc-1": {}}
+	volumeManager := &netPermissionsFakeManager{}
+	r := &fileVolumeACLReconciler{
+		metadataSyncer: &metadataSyncInformer{
+			clusterFlavor:     cnstypes.CnsClusterFlavorVanilla,
+			configInfo:        configInfo,
+			coCommonInterface: coCommonInterface,
+			pvLister:          corelisters.NewPersistentVolumeLister(pvIdx),
+			pvcLister:         pvcLister,
+			podLister:         corelisters.NewPodLister(podIdx),
+			volumeManagers:    map[string]volumes.Manager{"vc-1": volumeManager},
+		},
+		nodeLister:   corelisters.NewNodeLister(nodeIdx),
+		vaLister:     vaLister,
+		k8sClient:    k8sClient,
+		gracePeriod:  5 * time.Minute,
+		lastConsumed: make(map[string]map[string]time.Time),
+		drifted:      make(map[string]bool),
+	}
+	// reconcile reconciles the PV, and refreshes the lister with the PV
+	// patched by the reconcile.
+	reconcile := func() time.Duration {
+		requeueAfter, err := r.reconcile(ctx, "pv-1")
+		require.NoError(t, err)
+		patched, err := k8sClient.CoreV1().PersistentVolumes().Get(ctx, "pv-1", metav1.GetOptions{})
+		require.NoError(t, err)
+		require.NoError(t, pvIdx.Update(patched))
+		return requeueAfter
+	}
+	lastSpecIps := func(deleted bool) []string {
+		require.NotEmpty(t, volumeManager.specs)
+		var ips []string
+		for _, spec := range volumeManager.specs[len(volumeManager.specs)-1].AccessControlSpecList {
+			if spec.Delete == deleted {
+				for _, permission := range spec.Permission {
+					ips = append(ips, permission.Ips)
+				}
+			}
+		}
+		return ips
+	}
+
+	// The ACL is tightened to the IP of the node of the pod. The node of the
+	// VolumeAttachment is outside the net permissions of the volume.
+	assert.Zero(t, reconcile())
+	require.Len(t, volumeManager.specs, 1)
+	assert.Equal(t, []string{"10.0.0.0/24"}, lastSpecIps(true))
+	assert.Equal(t, []string{"10.0.0.1"}, lastSpecIps(false))
+	permission := volumeManager.specs[0].AccessControlSpecList[1].Permission[0]
+	assert.False(t, permission.AllowRoot, "the root squash of the net permissions must be kept")
+	assert.Empty(t, r.drifted)
+
+	assert.Zero(t, reconcile())
+	assert.Len(t, volumeManager.specs, 1, "an ACL in sync must not be updated")
+
+	// The IP of the previous node of the pod is kept during the grace period.
+	pod.Spec.NodeName = "node-2"
+	require.NoError(t, podIdx.Update(pod))
+	assert.InDelta(t, r.gracePeriod, reconcile(), float64(time.Second))
+	require.Len(t, volumeManager.specs, 2)
+	assert.Empty(t, lastSpecIps(true))
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, lastSpecIps(false))
+
+	r.lastConsumed["pv-1"]["10.0.0.1"] = time.Now().Add(-r.gracePeriod)
+	assert.Zero(t, reconcile())
+	require.Len(t, volumeManager.specs, 3)
+	assert.Equal(t, []string{"10.0.0.1"}, lastSpecIps(true))
+	assert.Equal(t, []string{"10.0.0.2"}, lastSpecIps(false))
+
+	// A volume without consumers is not accessible.
+	require.NoError(t, podIdx.Delete(pod))
+	r.lastConsumed["pv-1"]["10.0.0.2"] = time.Now().Add(-r.gracePeriod)
+	assert.Zero(t, reconcile())
+	require.Len(t, volumeManager.specs, 4)
+	assert.Equal(t, []string{"10.0.0.2"}, lastSpecIps(true))
+	assert.Equal(t, []string{"*"}, lastSpecIps(false))
+	patched, err := r.metadataSyncer.pvLister.Get("pv-1")
+	require.NoError(t, err)
+	netPerms, tightened, err := getFileVolumeACL(patched)
+	require.NoError(t, err)
+	assert.True(t, tightened)
+	assert.Equal(t, []*cnsconfig.NetPermissionConfig{{Ips: "*", Permissions: "NO_ACCESS"}}, netPerms)
+}
+
+func TestGetTightenedNetPermissions(t *testing.T) {
+	netPerms := []*cnsconfig.NetPermissionConfig{
+		{Ips: "10.0.0.0/16", Permissions: "READ_WRITE"},
+		{Ips: "10.0.1.0/24", Permissions: "READ_ONLY", RootSquash: true},
+		{Ips: "10.0.2.1", Permissions: "NO_ACCESS"},
+	}
+	tightened := getTightenedNetPermissions([]string{"10.0.1.5", "10.0.0.5", "10.0.2.1", "192.168.0.1"}, netPerms)
+	assert.Equal(t, []*cnsconfig.NetPermissionConfig{
+		{Ips: "10.0.0.5", Permissions: "READ_WRITE"},
+		{Ips: "10.0.1.5", Permissions: "READ_ONLY", RootSquash: true},
+	}, tightened, "each IP must be granted the most restrictive net permissions containing it")
+	assert.Equal(t, []*cnsconfig.NetPermissionConfig{{Ips: "*", Permissions: "NO_ACCESS"}},
+		getTightenedNetPermissions(nil, netPerms))
+}
@@ -32,6 +32,7 @@ import (
 	versioned "github.com/kubernetes-csi/external-snapshotter/client/v8/clientset/versioned"
 	"github.com/vmware/govmomi/cns"
 	cnstypes "github.com/vmware/govmomi/cns/types"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc/codes"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -50,6 +51,7 @@ import (
 	volumes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/vsphere"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/utils"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common/commonco"
@@ -84,6 +86,10 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer, vc s
 	fullSyncStartTime := time.Now()
 	var migrationFeatureStateForFullSync bool
 	var err error
+	ctx, span := tracing.StartSpan(ctx, "syncer.CsiFullSync", attribute.String("vsphere.vcenter", vc))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	// Fetch CSI migration feature state, before performing full sync operations.
 	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
 		if len(metadataSyncer.configInfo.Cfg.VirtualCenter) == 1 {
@@ -194,6 +200,8 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer, vc s
 
 	// k8sPVMap is useful for clean and quicker look up.
 	k8sPVMap := make(map[string]string)
+	// pvToVolumeID maps the name of the PVs to their volume ID.
+	pvToVolumeID := make(map[string]string)
 	// Instantiate volumeMigrationService when migration feature state is True.
 	if migrationFeatureStateForFullSync {
 		// Instantiate volumeMigrationService when migration feature state is True.
@@ -209,6 +217,7 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer, vc s
 		// k8sPVs contains valid CSI volumes or migrated vSphere volumes
 		if pv.Spec.CSI != nil {
 			k8sPVMap[pv.Spec.CSI.VolumeHandle] = ""
+			pvToVolumeID[pv.Name] = pv.Spec.CSI.VolumeHandle
 		} else if migrationFeatureStateForFullSync && pv.Spec.VsphereVolume != nil {
 			// For vSphere volumes, migration service will register volumes in CNS.
 			// Note that we can never reach here in case of a multi VC setup
@@ -224,6 +233,7 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer, vc s
 				return err
 			}
 			k8sPVMap[volumeHandle] = ""
+			pvToVolumeID[pv.Name] = volumeHandle
 		}
 	}
 	// pvToPVCMap maps pv name to corresponding PVC.
@@ -319,7 +329,28 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer, vc s
 	}
 
 	var queryAllResult *cnstypes.CnsQueryResult
-	if metadataSyncer.configInfo.Cfg.Global.ClusterID != "" {
+	// In the incremental mode, only the PVs changed since the watermark of the
+	// previous full sync, and the CNS volumes which do not match a PV, are
+	// reconciled.
+	k8sPVsToSync := k8sPVs
+	lastCompleteSync := fullSyncStartTime
+	watermark := getIncrementalFullSyncWatermark(ctx, metadataSyncer, vc)
+	if watermark != nil {
+		k8sPVsToSync, queryAllResult, err = incrementalFullSyncQuery(ctx, metadataSyncer, volManager, watermark,
+			k8sPVs, pvToVolumeID, pvToPVCMap, pvcToPodMap, vc)
+		if errors.Is(err, errInvalidFullSyncWatermark) {
+			log.Infof("FullSync for VC %s: watermark of the previous full sync is invalid, "+
+				"reconciling every volume", vc)
+			k8sPVsToSync, watermark = k8sPVs, nil
+		} else if err != nil {
+			log.Errorf("FullSync for VC %s: failed to query the volumes changed since the previous full sync. "+
+				"Err: %v", vc, err)
+			return err
+		} else {
+			lastCompleteSync = watermark.LastCompleteSync
+		}
+	}
+	if watermark == nil && metadataSyncer.configInfo.Cfg.Global.ClusterID != "" {
 		// Cluster ID is removed from vSphere Config Secret post 9.0 release in Supervisor
 		queryAllResult, err = utils.QueryAllVolumesForCluster(ctx, volManager,
 			metadataSyncer.configInfo.Cfg.Global.ClusterID, cnstypes.CnsQuerySelection{})
@@ -327,7 +358,7 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer, vc s
 			log.Errorf("FullSync for VC %s: QueryVolume failed with err=%+v", vc, err.Error())
 			return err
 		}
-	} else {
+	} else if watermark == nil {
 		log.Infof("observed emptry string cluster-id in the vSphere Config secret. " +
 			"Skipping to replace volume metadata with older cluster-id to new supervisor-id")
 	}
@@ -465,7 +496,7 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer, vc s
 	}
 
 	volumeToCnsEntityMetadataMap, volumeToK8sEntityMetadataMap, volumeClusterDistributionMap, err :=
-		fullSyncConstructVolumeMaps(ctx, k8sPVs, queryAllResult.Volumes, pvToPVCMap,
+		fullSyncConstructVolumeMaps(ctx, k8sPVsToSync, queryAllResult.Volumes, pvToPVCMap,
 			pvcToPodMap, metadataSyncer, migrationFeatureStateForFullSync, volManager, vc)
 	if err != nil {
 		log.Errorf("FullSync for VC %s: fullSyncGetEntityMetadata failed with err %+v", vc, err)
@@ -478,9 +509,13 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer, vc s
 	containerCluster := cnsvsphere.GetContainerCluster(clusterIDforVolumeMetadata,
 		vcHostObj.User, metadataSyncer.clusterFlavor,
 		metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
-	createSpecArray, updateSpecArray := fullSyncGetVolumeSpecs(ctx, vcenter.Client.Version, k8sPVs,
+	createSpecArray, updateSpecArray := fullSyncGetVolumeSpecs(ctx, vcenter.Client.Version, k8sPVsToSync,
 		volumeToCnsEntityMetadataMap, volumeToK8sEntityMetadataMap, volumeClusterDistributionMap,
 		containerCluster, migrationFeatureStateForFullSync, vc)
+	// With the DriftReport feature, the drift is reported, and only corrected
+	// for the categories to remediate.
+	report := newDriftReport(ctx, metadataSyncer, vc, fullSyncStartTime)
+	createSpecArray, updateSpecArray = report.reportVolumeSpecs(createSpecArray, updateSpecArray, pvToVolumeID)
 
 	// Re-query the same volume set with VOLUME_METADATA included so that
 	// getMissingPVVolumeUpdateSpecs and getRetainedPVVolumeUpdateSpecs can
@@ -517,12 +552,14 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer, vc s
 	// Instead we label them with `pv_missing=true` on their existing PV-type
 	// CnsKubernetesEntityMetadata, after a two-cycle grace period to absorb
 	// transient races between PV deletion and full-sync execution.
+	report.reportOrphanedVolumes(volumesWithMetadata, k8sPVMap)
 	missingPVUpdateSpecs, missingPVCount, err := getMissingPVVolumeUpdateSpecs(ctx, volumesWithMetadata,
 		k8sPVMap, metadataSyncer, migrationFeatureStateForFullSync, containerCluster, vc)
 	if err != nil {
 		log.Errorf("FullSync for VC %s: failed to compute pv_missing update specs with err %+v", vc, err)
 		return err
 	}
+	missingPVUpdateSpecs = report.filterOrphanedVolumeSpecs(missingPVUpdateSpecs)
 	prometheus.CnsVolumePVMissingGaugeVec.WithLabelValues(vc).Set(float64(missingPVCount))
 	if len(missingPVUpdateSpecs) > 0 {
 		log.Infof("FullSync for VC %s: applying pv_missing label to %d volume(s)",
@@ -557,6 +594,19 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer, vc s
 	wg.Wait()
 
 	cleanupCnsMaps(k8sPVMap, vc)
+	pendingVolumes := takeFullSyncPendingVolumes(vc)
+	if isIncrementalFullSyncEnabled(metadataSyncer) {
+		nextWatermark := newFullSyncWatermark(k8sPVs, pvToVolumeID, pvToPVCMap, pvcToPodMap, pendingVolumes,
+			lastCompleteSync)
+		if err := saveFullSyncWatermark(ctx, vc, nextWatermark); err != nil {
+			log.Warnf("FullSync for VC %s: failed to save the watermark of the full sync. Err: %v", vc, err)
+			// The previous watermark may hold the PVs of the pending volumes.
+			for volumeID := range pendingVolumes {
+				markFullSyncVolumePending(vc, volumeID)
+			}
+		}
+	}
+	report.reportStaleObjectsAndSave(ctx, metadataSyncer, pvToVolumeID)
 	log.Debugf("FullSync for VC %s: cnsDeletionMap at end of cycle: %v", vc, cnsDeletionMap)
 	log.Debugf("FullSync for VC %s: cnsCreationMap at end of cycle: %v", vc, cnsCreationMap)
 	log.Infof("FullSync for VC %s: end", vc)
@@ -1210,6 +1260,7 @@ func fullSyncUpdateVolumes(ctx context.Context, updateSpecArray []cnstypes.CnsVo
 			vc, updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
 		if err := volManager.UpdateVolumeMetadata(ctx, &updateSpec); err != nil {
 			log.Warnf("FullSync for VC %s: UpdateVolumeMetadata failed with err %v", vc, err)
+			markFullSyncVolumePending(vc, updateSpec.VolumeId.Id)
 		}
 	}
 }
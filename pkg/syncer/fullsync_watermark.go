@@ -0,0 +1,369 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/utils"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+)
+
+const (
+	// fullSyncWatermarkConfigMapName is the name of the ConfigMap holding the
+	// watermarks of the incremental full sync, keyed by vCenter.
+	fullSyncWatermarkConfigMapName = "vsphere-csi-fullsync-watermark"
+	// maxFullSyncWatermarkSize is the maximum size of the watermark of a
+	// vCenter, which takes 8 bytes, base64 encoded, per PV. It keeps the
+	// ConfigMap under the 1 MiB limit of the Kubernetes objects.
+	maxFullSyncWatermarkSize = 512 * 1024
+)
+
+// errInvalidFullSyncWatermark is returned when the watermark of the previous
+// full sync cannot be used to reconcile the volumes changed since.
+var errInvalidFullSyncWatermark = errors.New("invalid full sync watermark")
+
+var (
+	// fullSyncPendingVolumes holds, per vCenter, the volumes whose metadata
+	// update failed in a full sync, left out of its watermark so that they are
+	// reconciled again by the next incremental full sync.
+	fullSyncPendingVolumes     = make(map[string]map[string]bool)
+	fullSyncPendingVolumesLock sync.Mutex
+	// getFullSyncWatermark returns the watermark of the previous full sync of
+	// vc, nil if none was saved.
+	getFullSyncWatermark = getFullSyncWatermarkFromConfigMap
+	// saveFullSyncWatermark saves the watermark of the full sync of vc.
+	saveFullSyncWatermark = saveFullSyncWatermarkToConfigMap
+)
+
+// fullSyncWatermark is the state of the volumes reconciled by the full sync
+// of a vCenter.
+type fullSyncWatermark struct {
+	// PVDigests are the sorted digests of the PVs reconciled by the full sync,
+	// each computed from the resourceVersions of the PV, of its PVC and of the
+	// Pods mounting it. A PV whose digest is missing changed since.
+	PVDigests []byte `json:"pvDigests"`
+	// LastCompleteSync is the start time of the last full sync which
+	// reconciled every volume.
+	LastCompleteSync time.Time `json:"lastCompleteSync"`
+}
+
+// isIncrementalFullSyncEnabled returns true if the full sync of the cluster
+// reconciles the volumes changed since the previous cycle. The CNS volumes
+// created since are queried by cluster ID, so it must be set.
+func isIncrementalFullSyncEnabled(metadataSyncer *metadataSyncInformer) bool {
+	return metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.configInfo.Cfg.Global.FullSyncMode == cnsconfig.FullSyncModeIncremental &&
+		metadataSyncer.configInfo.Cfg.Global.ClusterID != ""
+}
+
+// getIncrementalFullSyncWatermark returns the watermark from which the full
+// sync of vc reconciles the changed volumes, or nil if it must reconcile every
+// volume: in the complete mode, without a watermark, or once the complete
+// full sync interval elapsed since the last complete full sync.
+func getIncrementalFullSyncWatermark(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	vc string) *fullSyncWatermark {
+	log := logger.GetLogger(ctx)
+	if !isIncrementalFullSyncEnabled(metadataSyncer) {
+		return nil
+	}
+	watermark, err := getFullSyncWatermark(ctx, vc)
+	if err != nil {
+		log.Warnf("FullSync for VC %s: failed to get the watermark of the previous full sync, "+
+			"reconciling every volume. Err: %v", vc, err)
+		return nil
+	}
+	if watermark == nil {
+		log.Infof("FullSync for VC %s: no watermark of a previous full sync, reconciling every volume", vc)
+		return nil
+	}
+	interval := time.Duration(metadataSyncer.configInfo.Cfg.Global.CompleteFullSyncIntervalInMin) * time.Minute
+	if time.Since(watermark.LastCompleteSync) >= interval {
+		log.Infof("FullSync for VC %s: last complete full sync at %v, reconciling every volume",
+			vc, watermark.LastCompleteSync)
+		return nil
+	}
+	return watermark
+}
+
+// markFullSyncVolumePending marks volumeID of vc to be reconciled again by the
+// next incremental full sync.
+func markFullSyncVolumePending(vc string, volumeID string) {
+	fullSyncPendingVolumesLock.Lock()
+	defer fullSyncPendingVolumesLock.Unlock()
+	if fullSyncPendingVolumes[vc] == nil {
+		fullSyncPendingVolumes[vc] = make(map[string]bool)
+	}
+	fullSyncPendingVolumes[vc][volumeID] = true
+}
+
+// takeFullSyncPendingVolumes returns the volumes of vc to be reconciled again,
+// and clears them.
+func takeFullSyncPendingVolumes(vc string) map[string]bool {
+	fullSyncPendingVolumesLock.Lock()
+	defer fullSyncPendingVolumesLock.Unlock()
+	pending := fullSyncPendingVolumes[vc]
+	delete(fullSyncPendingVolumes, vc)
+	return pending
+}
+
+// getPVDigest returns the digest of the resourceVersions of pv, of its PVC and
+// of the Pods mounting it. The resourceVersions are only compared for equality,
+// and the Pods are identified by UID so that the deletion or the replacement of
+// a Pod changes the digest.
+func getPVDigest(pv *v1.PersistentVolume, pvToPVCMap pvcMap, pvcToPodMap podMap) uint64 {
+	hash := fnv.New64a()
+	write := func(values ...string) {
+		for _, value := range values {
+			_, _ = hash.Write([]byte(value))
+			_, _ = hash.Write([]byte{0})
+		}
+	}
+	write(pv.Name, pv.ResourceVersion)
+	if pvc, ok := pvToPVCMap[pv.Name]; ok {
+		write(pvc.Namespace, pvc.Name, pvc.ResourceVersion)
+		pods := append([]*v1.Pod(nil), pvcToPodMap[pvc.Namespace+"/"+pvc.Name]...)
+		sort.Slice(pods, func(i, j int) bool {
+			return pods[i].UID < pods[j].UID
+		})
+		for _, pod := range pods {
+			write(string(pod.UID), pod.ResourceVersion)
+		}
+	}
+	return hash.Sum64()
+}
+
+// newFullSyncWatermark returns the watermark of the PVs reconciled by a full
+// sync. The PVs whose volume is in skipVolumes, such as the volumes whose
+// metadata update failed, are left out so that the next incremental full sync
+// reconciles them again.
+func newFullSyncWatermark(pvs []*v1.PersistentVolume, pvToVolumeID map[string]string, pvToPVCMap pvcMap,
+	pvcToPodMap podMap, skipVolumes map[string]bool, lastCompleteSync time.Time) *fullSyncWatermark {
+	digests := make([]uint64, 0, len(pvs))
+	for _, pv := range pvs {
+		if !skipVolumes[pvToVolumeID[pv.Name]] {
+			digests = append(digests, getPVDigest(pv, pvToPVCMap, pvcToPodMap))
+		}
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		return digests[i] < digests[j]
+	})
+	pvDigests := make([]byte, 8*len(digests))
+	for i, digest := range digests {
+		binary.BigEndian.PutUint64(pvDigests[8*i:], digest)
+	}
+	return &fullSyncWatermark{PVDigests: pvDigests, LastCompleteSync: lastCompleteSync}
+}
+
+// getReconciledPVDigests returns the set of digests of the PVs reconciled by
+// the full sync of watermark. It returns errInvalidFullSyncWatermark if the
+// digests cannot be decoded, or if there are none, as an empty watermark
+// cannot tell a changed PV from a PV left out.
+func getReconciledPVDigests(watermark *fullSyncWatermark) (map[uint64]bool, error) {
+	if len(watermark.PVDigests) == 0 || len(watermark.PVDigests)%8 != 0 {
+		return nil, errInvalidFullSyncWatermark
+	}
+	digests := make(map[uint64]bool, len(watermark.PVDigests)/8)
+	for i := 0; i < len(watermark.PVDigests); i += 8 {
+		digests[binary.BigEndian.Uint64(watermark.PVDigests[i:])] = true
+	}
+	return digests, nil
+}
+
+// getPVsChangedSince returns the PVs which, or whose PVC or Pods, changed
+// since the full sync of the reconciled digests.
+func getPVsChangedSince(pvs []*v1.PersistentVolume, pvToPVCMap pvcMap, pvcToPodMap podMap,
+	reconciled map[uint64]bool) []*v1.PersistentVolume {
+	changedPVs := make([]*v1.PersistentVolume, 0)
+	for _, pv := range pvs {
+		if !reconciled[getPVDigest(pv, pvToPVCMap, pvcToPodMap)] {
+			changedPVs = append(changedPVs, pv)
+		}
+	}
+	return changedPVs
+}
+
+// incrementalFullSyncQuery returns the PVs to reconcile since watermark, and
+// the CNS volumes to reconcile with them. The volume IDs of the cluster are
+// listed from CNS on each cycle, and compared with the volumes of the PVs, so
+// that the PVs missing their volume in CNS, and the CNS volumes without a PV,
+// are reconciled as by a complete full sync. The details of the CNS volumes
+// are only queried for the volumes of the changed PVs and the volumes without
+// a PV.
+func incrementalFullSyncQuery(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	volManager volumes.Manager, watermark *fullSyncWatermark, k8sPVs []*v1.PersistentVolume,
+	pvToVolumeID map[string]string, pvToPVCMap pvcMap, pvcToPodMap podMap,
+	vc string) ([]*v1.PersistentVolume, *cnstypes.CnsQueryResult, error) {
+	log := logger.GetLogger(ctx)
+	reconciled, err := getReconciledPVDigests(watermark)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The volume IDs are listed with the query filter of
+	// QueryAllVolumesForCluster used by the complete full sync.
+	clusterID := metadataSyncer.configInfo.Cfg.Global.ClusterID
+	volumeIDsResult, err := volManager.QueryAllVolume(ctx,
+		cnstypes.CnsQueryFilter{ContainerClusterIds: []string{clusterID}}, cnstypes.CnsQuerySelection{})
+	if err != nil {
+		return nil, nil, logger.LogNewErrorf(log, "failed to query the volume IDs of cluster %q. Err: %v",
+			clusterID, err)
+	}
+	cnsVolumeIDs := make(map[string]bool, len(volumeIDsResult.Volumes))
+	for _, volume := range volumeIDsResult.Volumes {
+		cnsVolumeIDs[volume.VolumeId.Id] = true
+	}
+
+	changedPVs := getPVsChangedSince(k8sPVs, pvToPVCMap, pvcToPodMap, reconciled)
+	isChanged := make(map[string]bool, len(changedPVs))
+	for _, pv := range changedPVs {
+		isChanged[pv.Name] = true
+	}
+	k8sVolumeIDs := make(map[string]bool, len(k8sPVs))
+	var missingVolumes int
+	for _, pv := range k8sPVs {
+		volumeID := pvToVolumeID[pv.Name]
+		k8sVolumeIDs[volumeID] = true
+		if !cnsVolumeIDs[volumeID] && !isChanged[pv.Name] {
+			isChanged[pv.Name] = true
+			changedPVs = append(changedPVs, pv)
+			missingVolumes++
+		}
+	}
+
+	var volumesToQuery []cnstypes.CnsVolume
+	var orphanVolumes int
+	for _, volume := range volumeIDsResult.Volumes {
+		if !k8sVolumeIDs[volume.VolumeId.Id] {
+			volumesToQuery = append(volumesToQuery, volume)
+			orphanVolumes++
+		}
+	}
+	for _, pv := range changedPVs {
+		if volumeID := pvToVolumeID[pv.Name]; cnsVolumeIDs[volumeID] {
+			volumesToQuery = append(volumesToQuery, cnstypes.CnsVolume{VolumeId: cnstypes.CnsVolumeId{Id: volumeID}})
+		}
+	}
+	queryResult := &cnstypes.CnsQueryResult{}
+	if len(volumesToQuery) > 0 {
+		queryResult, err = utils.QueryVolumeDetailsBatched(ctx, volManager, volumesToQuery,
+			cnstypes.CnsQuerySelection{})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	log.Infof("FullSync for VC %s: reconciling %d of %d PVs, of which %d miss their volume in CNS, and %d of "+
+		"%d CNS volumes, of which %d have no PV", vc, len(changedPVs), len(k8sPVs), missingVolumes,
+		len(queryResult.Volumes), len(volumeIDsResult.Volumes), orphanVolumes)
+	return changedPVs, queryResult, nil
+}
+
+// getFullSyncWatermarkFromConfigMap returns the watermark of vc saved in the
+// full sync watermark ConfigMap, nil if none was saved. It returns
+// errInvalidFullSyncWatermark if the watermark cannot be decoded.
+func getFullSyncWatermarkFromConfigMap(ctx context.Context, vc string) (*fullSyncWatermark, error) {
+	k8sClient, err := k8sNewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := k8sClient.CoreV1().ConfigMaps(cnsconfig.GetCSINamespace()).Get(ctx,
+		fullSyncWatermarkConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := configMap.Data[vc]
+	if !ok {
+		return nil, nil
+	}
+	watermark := &fullSyncWatermark{}
+	if err := json.Unmarshal([]byte(data), watermark); err != nil {
+		return nil, errInvalidFullSyncWatermark
+	}
+	return watermark, nil
+}
+
+// saveFullSyncWatermarkToConfigMap saves the watermark of vc in the full sync
+// watermark ConfigMap, creating it if needed. A watermark larger than
+// maxFullSyncWatermarkSize is not saved, and the previous watermark of vc is
+// removed so that the next full sync reconciles every volume.
+func saveFullSyncWatermarkToConfigMap(ctx context.Context, vc string, watermark *fullSyncWatermark) error {
+	k8sClient, err := k8sNewClient(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(watermark)
+	if err != nil {
+		return err
+	}
+	var errTooLarge error
+	if len(data) > maxFullSyncWatermarkSize {
+		errTooLarge = fmt.Errorf("watermark of %d bytes exceeds the limit of %d bytes", len(data),
+			maxFullSyncWatermarkSize)
+	}
+	configMaps := k8sClient.CoreV1().ConfigMaps(cnsconfig.GetCSINamespace())
+	configMap, err := configMaps.Get(ctx, fullSyncWatermarkConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if errTooLarge != nil {
+			return errTooLarge
+		}
+		configMap = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fullSyncWatermarkConfigMapName,
+				Namespace: cnsconfig.GetCSINamespace(),
+			},
+			Data: map[string]string{vc: string(data)},
+		}
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if errTooLarge != nil {
+		if _, ok := configMap.Data[vc]; !ok {
+			return errTooLarge
+		}
+		delete(configMap.Data, vc)
+		if _, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		return errTooLarge
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[vc] = string(data)
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
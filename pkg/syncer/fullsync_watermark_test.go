@@ -0,0 +1,216 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+)
+
+func newWatermarkTestObjects() ([]*v1.PersistentVolume, map[string]string, pvcMap, podMap) {
+	pvs := []*v1.PersistentVolume{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pv-1", ResourceVersion: "10"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pv-2", ResourceVersion: "11"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pv-3", ResourceVersion: "12"}},
+	}
+	pvToVolumeID := map[string]string{"pv-1": "volume-1", "pv-2": "volume-2", "pv-3": "volume-3"}
+	pvToPVCMap := pvcMap{
+		"pv-2": {ObjectMeta: metav1.ObjectMeta{Name: "pvc-2", Namespace: "ns", ResourceVersion: "20"}},
+		"pv-3": {ObjectMeta: metav1.ObjectMeta{Name: "pvc-3", Namespace: "ns", ResourceVersion: "13"}},
+	}
+	pvcToPodMap := podMap{
+		"ns/pvc-3": {{ObjectMeta: metav1.ObjectMeta{Name: "pod-3", Namespace: "ns", UID: "pod-3-uid",
+			ResourceVersion: "30"}}},
+	}
+	return pvs, pvToVolumeID, pvToPVCMap, pvcToPodMap
+}
+
+func getPVNames(pvs []*v1.PersistentVolume) []string {
+	names := make([]string, 0, len(pvs))
+	for _, pv := range pvs {
+		names = append(names, pv.Name)
+	}
+	return names
+}
+
+func TestGetPVsChangedSince(t *testing.T) {
+	pvs, pvToVolumeID, pvToPVCMap, pvcToPodMap := newWatermarkTestObjects()
+	watermark := newFullSyncWatermark(pvs, pvToVolumeID, pvToPVCMap, pvcToPodMap,
+		map[string]bool{"volume-1": true}, time.Now())
+	reconciled, err := getReconciledPVDigests(watermark)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pv-1 is left out of the watermark as its volume is pending.
+	if changed := getPVNames(getPVsChangedSince(pvs, pvToPVCMap, pvcToPodMap, reconciled)); !slices.Equal(changed,
+		[]string{"pv-1"}) {
+		t.Errorf("expected the PV of the pending volume to be changed, got %v", changed)
+	}
+
+	// The resourceVersions are compared for equality: a lower resourceVersion
+	// of the PVC is a change.
+	pvToPVCMap["pv-2"].ResourceVersion = "19"
+	// The Pod of pvc-3 is deleted, which does not change the PV or the PVC.
+	delete(pvcToPodMap, "ns/pvc-3")
+	changed := getPVNames(getPVsChangedSince(pvs, pvToPVCMap, pvcToPodMap, reconciled))
+	if !slices.Equal(changed, []string{"pv-1", "pv-2", "pv-3"}) {
+		t.Errorf("expected the PVs pv-1, pv-2 and pv-3 to be changed, got %v", changed)
+	}
+
+	// A Pod replaced by a Pod of the same name is a change.
+	pvs, pvToVolumeID, pvToPVCMap, pvcToPodMap = newWatermarkTestObjects()
+	reconciled, err = getReconciledPVDigests(newFullSyncWatermark(pvs, pvToVolumeID, pvToPVCMap, pvcToPodMap,
+		nil, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pvcToPodMap["ns/pvc-3"][0].UID = "pod-3-new-uid"
+	if changed := getPVNames(getPVsChangedSince(pvs, pvToPVCMap, pvcToPodMap, reconciled)); !slices.Equal(changed,
+		[]string{"pv-3"}) {
+		t.Errorf("expected the PV of the replaced Pod to be changed, got %v", changed)
+	}
+
+	for _, digests := range [][]byte{nil, make([]byte, 7)} {
+		if _, err := getReconciledPVDigests(&fullSyncWatermark{PVDigests: digests}); !errors.Is(err,
+			errInvalidFullSyncWatermark) {
+			t.Errorf("expected error %v for digests %v, got %v", errInvalidFullSyncWatermark, digests, err)
+		}
+	}
+}
+
+func TestIncrementalFullSyncQuery(t *testing.T) {
+	ctx := context.Background()
+	pvs, pvToVolumeID, pvToPVCMap, pvcToPodMap := newWatermarkTestObjects()
+	watermark := newFullSyncWatermark(pvs, pvToVolumeID, pvToPVCMap, pvcToPodMap, nil, time.Now())
+	// volume-2 is deleted and volume-4 is created out of band, which leaves
+	// the number of volumes of the cluster unchanged.
+	volumeManager := &orphanGCFakeManager{
+		volumes: map[string]cnstypes.CnsVolume{
+			"volume-1": {VolumeId: cnstypes.CnsVolumeId{Id: "volume-1"}},
+			"volume-3": {VolumeId: cnstypes.CnsVolumeId{Id: "volume-3"}},
+			"volume-4": {VolumeId: cnstypes.CnsVolumeId{Id: "volume-4"}},
+		},
+	}
+	cfg := &cnsconfig.Config{}
+	cfg.Global.ClusterID = "cluster-1"
+	metadataSyncer := &metadataSyncInformer{
+		clusterFlavor: cnstypes.CnsClusterFlavorVanilla,
+		configInfo:    &cnsconfig.ConfigurationInfo{Cfg: cfg},
+	}
+	// The Pod of pvc-3 is deleted.
+	delete(pvcToPodMap, "ns/pvc-3")
+
+	changedPVs, queryResult, err := incrementalFullSyncQuery(ctx, metadataSyncer, volumeManager, watermark, pvs,
+		pvToVolumeID, pvToPVCMap, pvcToPodMap, "vc-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed := getPVNames(changedPVs); !slices.Equal(changed, []string{"pv-3", "pv-2"}) {
+		t.Errorf("expected the PV of the deleted Pod and the PV missing its volume, got %v", changed)
+	}
+	var volumeIDs []string
+	for _, volume := range queryResult.Volumes {
+		volumeIDs = append(volumeIDs, volume.VolumeId.Id)
+	}
+	slices.Sort(volumeIDs)
+	if !slices.Equal(volumeIDs, []string{"volume-3", "volume-4"}) {
+		t.Errorf("expected the volume of the changed PV and the volume without a PV, got %v", volumeIDs)
+	}
+
+	if _, _, err := incrementalFullSyncQuery(ctx, metadataSyncer, volumeManager, &fullSyncWatermark{}, pvs,
+		pvToVolumeID, pvToPVCMap, pvcToPodMap, "vc-1"); !errors.Is(err, errInvalidFullSyncWatermark) {
+		t.Errorf("expected error %v for an empty watermark, got %v", errInvalidFullSyncWatermark, err)
+	}
+}
+
+func TestFullSyncWatermarkConfigMap(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := k8sfake.NewSimpleClientset()
+	origK8sClient := k8sNewClient
+	defer func() { k8sNewClient = origK8sClient }()
+	k8sNewClient = func(ctx context.Context) (clientset.Interface, error) {
+		return k8sClient, nil
+	}
+
+	watermark, err := getFullSyncWatermarkFromConfigMap(ctx, "vc-1")
+	if err != nil || watermark != nil {
+		t.Fatalf("expected no watermark before the first full sync, got %+v, err %v", watermark, err)
+	}
+	pvs, pvToVolumeID, pvToPVCMap, pvcToPodMap := newWatermarkTestObjects()
+	saved := newFullSyncWatermark(pvs, pvToVolumeID, pvToPVCMap, pvcToPodMap, nil,
+		time.Now().UTC().Truncate(time.Second))
+	for _, vc := range []string{"vc-1", "vc-2"} {
+		if err := saveFullSyncWatermarkToConfigMap(ctx, vc, saved); err != nil {
+			t.Fatal(err)
+		}
+	}
+	watermark, err = getFullSyncWatermarkFromConfigMap(ctx, "vc-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if watermark == nil || !bytes.Equal(watermark.PVDigests, saved.PVDigests) ||
+		!watermark.LastCompleteSync.Equal(saved.LastCompleteSync) {
+		t.Errorf("expected the saved watermark %+v, got %+v", saved, watermark)
+	}
+
+	configMap, err := k8sClient.CoreV1().ConfigMaps(cnsconfig.GetCSINamespace()).Get(ctx,
+		fullSyncWatermarkConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configMap.Data["vc-1"] = "{"
+	if _, err := k8sClient.CoreV1().ConfigMaps(cnsconfig.GetCSINamespace()).Update(ctx, configMap,
+		metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getFullSyncWatermarkFromConfigMap(ctx, "vc-1"); !errors.Is(err, errInvalidFullSyncWatermark) {
+		t.Errorf("expected error %v, got %v", errInvalidFullSyncWatermark, err)
+	}
+
+	// A watermark too large for the ConfigMap removes the previous watermark.
+	tooLarge := &fullSyncWatermark{PVDigests: make([]byte, maxFullSyncWatermarkSize)}
+	if err := saveFullSyncWatermarkToConfigMap(ctx, "vc-2", tooLarge); err == nil {
+		t.Error("expected a watermark too large to fail")
+	}
+	if watermark, err := getFullSyncWatermarkFromConfigMap(ctx, "vc-2"); err != nil || watermark != nil {
+		t.Errorf("expected the previous watermark to be removed, got %+v, err %v", watermark, err)
+	}
+}
+
+func TestFullSyncPendingVolumes(t *testing.T) {
+	markFullSyncVolumePending("vc-1", "volume-1")
+	markFullSyncVolumePending("vc-1", "volume-2")
+	pending := takeFullSyncPendingVolumes("vc-1")
+	if len(pending) != 2 || !pending["volume-1"] || !pending["volume-2"] {
+		t.Errorf("expected the pending volumes volume-1 and volume-2, got %v", pending)
+	}
+	if pending := takeFullSyncPendingVolumes("vc-1"); len(pending) != 0 {
+		t.Errorf("expected the pending volumes to be cleared, got %v", pending)
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/tracing"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+)
+
+const (
+	// defaultMetadataSyncWorkers is the default number of workers updating
+	// the volume metadata on CNS.
+	defaultMetadataSyncWorkers = 4
+	// maxMetadataSyncWorkers is the maximum number of workers updating the
+	// volume metadata on CNS.
+	maxMetadataSyncWorkers = 32
+	// defaultMetadataSyncMaxRetries is the default number of retries of a
+	// failed volume metadata update, before it is left to full sync.
+	defaultMetadataSyncMaxRetries = 8
+	// metadataSyncRetryIntervalStart is the first retry interval of a failed
+	// volume metadata update.
+	metadataSyncRetryIntervalStart = time.Second
+	// metadataSyncRetryIntervalMax is the maximum retry interval of a failed
+	// volume metadata update.
+	metadataSyncRetryIntervalMax = 5 * time.Minute
+)
+
+// volumeMetadataUpdate is a pending update of the metadata of a volume.
+type volumeMetadataUpdate struct {
+	// volumeManager is the volume manager of the vCenter of the volume.
+	volumeManager volumes.Manager
+	// updateSpec holds the coalesced metadata of the volume.
+	updateSpec *cnstypes.CnsVolumeMetadataUpdateSpec
+	// queuedAt is the time the first coalesced update was queued.
+	queuedAt time.Time
+}
+
+// metadataUpdateQueue is a rate limited queue of volume metadata updates,
+// keyed by volume ID. Successive updates of a volume are coalesced into a
+// single CnsVolumeMetadataUpdateSpec, so that a burst of events on the same
+// volume results in a single UpdateVolumeMetadata call.
+type metadataUpdateQueue struct {
+	queue      workqueue.TypedRateLimitingInterface[string]
+	maxRetries int
+	// lock protects pending, inFlight and dropped.
+	lock sync.Mutex
+	// pending maps volume IDs to their pending update.
+	pending map[string]*volumeMetadataUpdate
+	// inFlight holds the IDs of the volumes being updated by a worker.
+	inFlight map[string]struct{}
+	// dropped holds the IDs of the volumes dropped while being updated by a
+	// worker, whose failed update must not be retried.
+	dropped map[string]struct{}
+}
+
+// newMetadataUpdateQueue returns a metadataUpdateQueue retrying failed
+// updates at most maxRetries times.
+func newMetadataUpdateQueue(rateLimiter workqueue.TypedRateLimiter[string], maxRetries int) *metadataUpdateQueue {
+	return &metadataUpdateQueue{
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(rateLimiter,
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "volume-metadata-update"}),
+		maxRetries: maxRetries,
+		pending:    make(map[string]*volumeMetadataUpdate),
+		inFlight:   make(map[string]struct{}),
+		dropped:    make(map[string]struct{}),
+	}
+}
+
+// add queues the given update, coalescing it with the pending update of the
+// same volume.
+func (q *metadataUpdateQueue) add(ctx context.Context, volumeManager volumes.Manager,
+	updateSpec *cnstypes.CnsVolumeMetadataUpdateSpec) {
+	log := logger.GetLogger(ctx)
+	volumeID := updateSpec.VolumeId.Id
+	q.lock.Lock()
+	if update, ok := q.pending[volumeID]; ok {
+		log.Debugf("Coalescing metadata update of volume %q with the pending update", volumeID)
+		mergeVolumeMetadataUpdateSpec(update.updateSpec, updateSpec)
+		update.volumeManager = volumeManager
+	} else {
+		q.pending[volumeID] = &volumeMetadataUpdate{
+			volumeManager: volumeManager,
+			updateSpec:    updateSpec,
+			queuedAt:      time.Now(),
+		}
+	}
+	// The volume may be registered again after it was dropped.
+	delete(q.dropped, volumeID)
+	q.lock.Unlock()
+	q.queue.Add(volumeID)
+	prometheus.MetadataSyncQueueDepthGauge.Set(float64(q.queue.Len()))
+	log.Infof("Queued UpdateVolumeMetadata for volume %q", volumeID)
+}
+
+// drop discards the pending update of the given volume, and prevents the
+// update being run by a worker from being retried. It is called before the
+// volume is deleted from CNS, so that a queued update does not run against
+// the deleted volume.
+func (q *metadataUpdateQueue) drop(ctx context.Context, volumeID string) {
+	log := logger.GetLogger(ctx)
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if _, ok := q.pending[volumeID]; ok {
+		log.Infof("Dropping the pending metadata update of volume %q", volumeID)
+		delete(q.pending, volumeID)
+	}
+	if _, ok := q.inFlight[volumeID]; ok {
+		q.dropped[volumeID] = struct{}{}
+	}
+}
+
+// run starts the given number of workers, and shuts the queue down when ctx
+// is done.
+func (q *metadataUpdateQueue) run(ctx context.Context, workers int) {
+	log := logger.GetLogger(ctx)
+	log.Infof("Starting %d volume metadata update workers", workers)
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, q.runWorker, time.Second)
+	}
+	go func() {
+		<-ctx.Done()
+		log.Info("Shutting down the volume metadata update queue")
+		q.queue.ShutDown()
+	}()
+}
+
+func (q *metadataUpdateQueue) runWorker(ctx context.Context) {
+	for q.processNextItem(ctx) {
+	}
+}
+
+// processNextItem updates the metadata of the next volume in the queue. It
+// returns false when the queue is shut down.
+func (q *metadataUpdateQueue) processNextItem(ctx context.Context) bool {
+	volumeID, shutdown := q.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer q.queue.Done(volumeID)
+	prometheus.MetadataSyncQueueDepthGauge.Set(float64(q.queue.Len()))
+
+	q.lock.Lock()
+	update, ok := q.pending[volumeID]
+	delete(q.pending, volumeID)
+	if ok {
+		q.inFlight[volumeID] = struct{}{}
+	}
+	q.lock.Unlock()
+	if !ok {
+		q.queue.Forget(volumeID)
+		return true
+	}
+	defer func() {
+		q.lock.Lock()
+		delete(q.inFlight, volumeID)
+		delete(q.dropped, volumeID)
+		q.lock.Unlock()
+	}()
+
+	ctx = logger.NewContextWithLogger(ctx)
+	log := logger.GetLogger(ctx)
+	log.Debugf("Calling UpdateVolumeMetadata for volume %q with updateSpec: %+v",
+		volumeID, spew.Sdump(update.updateSpec))
+	ctx, span := tracing.StartSpan(ctx, "syncer.UpdateVolumeMetadata", attribute.String("vsphere.volume", volumeID))
+	err := update.volumeManager.UpdateVolumeMetadata(ctx, update.updateSpec)
+	tracing.EndSpan(span, err)
+	if err == nil {
+		log.Infof("UpdateVolumeMetadata succeeded for volume %q", volumeID)
+		q.queue.Forget(volumeID)
+		prometheus.MetadataSyncQueueLatencyHistVec.WithLabelValues(prometheus.PrometheusPassStatus).Observe(
+			time.Since(update.queuedAt).Seconds())
+		return true
+	}
+
+	q.lock.Lock()
+	_, dropped := q.dropped[volumeID]
+	q.lock.Unlock()
+	if dropped {
+		log.Infof("UpdateVolumeMetadata failed for volume %q, which was deleted. Not retrying. Error: %v",
+			volumeID, err)
+		q.queue.Forget(volumeID)
+		return true
+	}
+	if q.queue.NumRequeues(volumeID) < q.maxRetries {
+		log.Warnf("UpdateVolumeMetadata failed for volume %q, retrying. Error: %v", volumeID, err)
+		q.lock.Lock()
+		if newer, ok := q.pending[volumeID]; ok {
+			// The updates queued while the failed one was running take precedence.
+			mergeVolumeMetadataUpdateSpec(update.updateSpec, newer.updateSpec)
+			update.volumeManager = newer.volumeManager
+		}
+		q.pending[volumeID] = update
+		q.lock.Unlock()
+		prometheus.MetadataSyncQueueRetriesCounter.Inc()
+		q.queue.AddRateLimited(volumeID)
+		return true
+	}
+	log.Errorf("UpdateVolumeMetadata failed for volume %q after %d retries, leaving it to full sync. Error: %v",
+		volumeID, q.maxRetries, err)
+	q.queue.Forget(volumeID)
+	prometheus.MetadataSyncQueueLatencyHistVec.WithLabelValues(prometheus.PrometheusFailStatus).Observe(
+		time.Since(update.queuedAt).Seconds())
+	return true
+}
+
+// mergeVolumeMetadataUpdateSpec merges src into dst. The entity metadata of
+// src replaces the entity metadata of dst for the same entity.
+func mergeVolumeMetadataUpdateSpec(dst, src *cnstypes.CnsVolumeMetadataUpdateSpec) {
+	dst.Metadata.ContainerCluster = src.Metadata.ContainerCluster
+	dst.Metadata.ContainerClusterArray = src.Metadata.ContainerClusterArray
+	for _, srcMetadata := range src.Metadata.EntityMetadata {
+		key := getEntityMetadataKey(srcMetadata)
+		replaced := false
+		for i, dstMetadata := range dst.Metadata.EntityMetadata {
+			if getEntityMetadataKey(dstMetadata) == key {
+				dst.Metadata.EntityMetadata[i] = srcMetadata
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			dst.Metadata.EntityMetadata = append(dst.Metadata.EntityMetadata, srcMetadata)
+		}
+	}
+}
+
+// getEntityMetadataKey returns a key identifying the entity of the given
+// metadata.
+func getEntityMetadataKey(metadata cnstypes.BaseCnsEntityMetadata) string {
+	if k8sMetadata, ok := metadata.(*cnstypes.CnsKubernetesEntityMetadata); ok {
+		return k8sMetadata.EntityType + "/" + k8sMetadata.Namespace + "/" + k8sMetadata.EntityName + "/" +
+			k8sMetadata.ClusterID
+	}
+	entityMetadata := metadata.GetCnsEntityMetadata()
+	return entityMetadata.EntityName + "/" + entityMetadata.ClusterID
+}
+
+// updateVolumeMetadata updates the metadata of a volume on CNS. When the
+// metadata update queue is enabled, the update is queued instead and nil is
+// returned. The worker running the update then logs its result.
+func updateVolumeMetadata(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	volumeManager volumes.Manager, updateSpec *cnstypes.CnsVolumeMetadataUpdateSpec) error {
+	if metadataSyncer.metadataUpdateQueue != nil {
+		metadataSyncer.metadataUpdateQueue.add(ctx, volumeManager, updateSpec)
+		return nil
+	}
+	return volumeManager.UpdateVolumeMetadata(ctx, updateSpec)
+}
+
+// dropVolumeMetadataUpdates discards the queued metadata updates of a volume
+// which is about to be deleted from CNS.
+func dropVolumeMetadataUpdates(ctx context.Context, metadataSyncer *metadataSyncInformer, volumeID string) {
+	if metadataSyncer.metadataUpdateQueue != nil {
+		metadataSyncer.metadataUpdateQueue.drop(ctx, volumeID)
+	}
+}
+
+// getMetadataSyncWorkers returns the number of workers updating the volume
+// metadata on CNS. If environment variable METADATA_SYNC_WORKERS is set and
+// valid, return the value read from environment variable. Otherwise, use the
+// default value 4.
+func getMetadataSyncWorkers(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	workers := defaultMetadataSyncWorkers
+	if v := os.Getenv("METADATA_SYNC_WORKERS"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value > 0 && value <= maxMetadataSyncWorkers {
+			workers = value
+			log.Infof("MetadataSync: number of workers is set to %d", workers)
+		} else {
+			log.Warnf("MetadataSync: number of workers set in env variable METADATA_SYNC_WORKERS %s "+
+				"is not between 1 and %d, will use the default value %d", v, maxMetadataSyncWorkers, workers)
+		}
+	}
+	return workers
+}
+
+// getMetadataSyncMaxRetries returns the number of retries of a failed volume
+// metadata update. If environment variable METADATA_SYNC_MAX_RETRIES is set
+// and valid, return the value read from environment variable. Otherwise, use
+// the default value 8.
+func getMetadataSyncMaxRetries(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	maxRetries := defaultMetadataSyncMaxRetries
+	if v := os.Getenv("METADATA_SYNC_MAX_RETRIES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value >= 0 {
+			maxRetries = value
+			log.Infof("MetadataSync: max retries is set to %d", maxRetries)
+		} else {
+			log.Warnf("MetadataSync: max retries set in env variable METADATA_SYNC_MAX_RETRIES %s "+
+				"is invalid, will use the default value %d", v, maxRetries)
+		}
+	}
+	return maxRetries
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vimtypes "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/client-go/util/workqueue"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+)
+
+// recordingVolumeManager records the UpdateVolumeMetadata calls, failing
+// the first failures calls. onUpdate, if set, is run during each call.
+type recordingVolumeManager struct {
+	volumes.Manager
+	failures int
+	calls    []*cnstypes.CnsVolumeMetadataUpdateSpec
+	onUpdate func()
+}
+
+func (m *recordingVolumeManager) UpdateVolumeMetadata(ctx context.Context,
+	spec *cnstypes.CnsVolumeMetadataUpdateSpec) error {
+	m.calls = append(m.calls, spec)
+	if m.onUpdate != nil {
+		m.onUpdate()
+	}
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("fake error")
+	}
+	return nil
+}
+
+func newTestMetadataUpdateSpec(volumeID string, entityType string, name string,
+	labels map[string]string) *cnstypes.CnsVolumeMetadataUpdateSpec {
+	var keyValues []vimtypes.KeyValue
+	for k, v := range labels {
+		keyValues = append(keyValues, vimtypes.KeyValue{Key: k, Value: v})
+	}
+	return &cnstypes.CnsVolumeMetadataUpdateSpec{
+		VolumeId: cnstypes.CnsVolumeId{Id: volumeID},
+		Metadata: cnstypes.CnsVolumeMetadata{
+			EntityMetadata: []cnstypes.BaseCnsEntityMetadata{
+				&cnstypes.CnsKubernetesEntityMetadata{
+					CnsEntityMetadata: cnstypes.CnsEntityMetadata{
+						EntityName: name,
+						Labels:     keyValues,
+						ClusterID:  "cluster1",
+					},
+					EntityType: entityType,
+					Namespace:  "ns1",
+				},
+			},
+		},
+	}
+}
+
+func newTestMetadataUpdateQueue(maxRetries int) *metadataUpdateQueue {
+	return newMetadataUpdateQueue(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[string](time.Millisecond, time.Millisecond), maxRetries)
+}
+
+func TestMetadataUpdateQueueCoalescesUpdates(t *testing.T) {
+	ctx := context.Background()
+	q := newTestMetadataUpdateQueue(defaultMetadataSyncMaxRetries)
+	defer q.queue.ShutDown()
+	volumeManager := &recordingVolumeManager{}
+
+	q.add(ctx, volumeManager, newTestMetadataUpdateSpec("vol1", "PERSISTENT_VOLUME_CLAIM", "pvc1",
+		map[string]string{"app": "a"}))
+	q.add(ctx, volumeManager, newTestMetadataUpdateSpec("vol1", "POD", "pod1", nil))
+	q.add(ctx, volumeManager, newTestMetadataUpdateSpec("vol1", "PERSISTENT_VOLUME_CLAIM", "pvc1",
+		map[string]string{"app": "b"}))
+	assert.Equal(t, 1, q.queue.Len())
+
+	assert.True(t, q.processNextItem(ctx))
+	assert.Len(t, volumeManager.calls, 1)
+	entityMetadata := volumeManager.calls[0].Metadata.EntityMetadata
+	assert.Len(t, entityMetadata, 2)
+	pvcMetadata := entityMetadata[0].(*cnstypes.CnsKubernetesEntityMetadata)
+	assert.Equal(t, "pvc1", pvcMetadata.EntityName)
+	assert.Equal(t, []vimtypes.KeyValue{{Key: "app", Value: "b"}}, pvcMetadata.Labels)
+	assert.Equal(t, "pod1", entityMetadata[1].GetCnsEntityMetadata().EntityName)
+	assert.Empty(t, q.pending)
+}
+
+func TestMetadataUpdateQueueRetriesFailedUpdates(t *testing.T) {
+	ctx := context.Background()
+	q := newTestMetadataUpdateQueue(1)
+	defer q.queue.ShutDown()
+	volumeManager := &recordingVolumeManager{failures: 1}
+
+	q.add(ctx, volumeManager, newTestMetadataUpdateSpec("vol1", "PERSISTENT_VOLUME_CLAIM", "pvc1", nil))
+	assert.True(t, q.processNextItem(ctx))
+	assert.Len(t, volumeManager.calls, 1)
+	assert.Contains(t, q.pending, "vol1")
+
+	// The failed update is retried with the updates queued in the meantime.
+	q.add(ctx, volumeManager, newTestMetadataUpdateSpec("vol1", "POD", "pod1", nil))
+	assert.True(t, q.processNextItem(ctx))
+	assert.Len(t, volumeManager.calls, 2)
+	assert.Len(t, volumeManager.calls[1].Metadata.EntityMetadata, 2)
+	assert.Empty(t, q.pending)
+	assert.Equal(t, 0, q.queue.NumRequeues("vol1"))
+}
+
+func TestMetadataUpdateQueueDropsUpdatesAfterMaxRetries(t *testing.T) {
+	ctx := context.Background()
+	q := newTestMetadataUpdateQueue(1)
+	defer q.queue.ShutDown()
+	volumeManager := &recordingVolumeManager{failures: 2}
+
+	q.add(ctx, volumeManager, newTestMetadataUpdateSpec("vol1", "PERSISTENT_VOLUME_CLAIM", "pvc1", nil))
+	assert.True(t, q.processNextItem(ctx))
+	assert.True(t, q.processNextItem(ctx))
+	assert.Len(t, volumeManager.calls, 2)
+	assert.Empty(t, q.pending)
+	assert.Equal(t, 0, q.queue.NumRequeues("vol1"))
+}
+
+func TestMetadataUpdateQueueDropsPendingUpdates(t *testing.T) {
+	ctx := context.Background()
+	q := newTestMetadataUpdateQueue(defaultMetadataSyncMaxRetries)
+	defer q.queue.ShutDown()
+	volumeManager := &recordingVolumeManager{}
+
+	q.add(ctx, volumeManager, newTestMetadataUpdateSpec("vol1", "PERSISTENT_VOLUME_CLAIM", "pvc1", nil))
+	q.drop(ctx, "vol1")
+	assert.True(t, q.processNextItem(ctx))
+	assert.Empty(t, volumeManager.calls)
+	assert.Empty(t, q.pending)
+	assert.Empty(t, q.dropped)
+}
+
+func TestMetadataUpdateQueueDoesNotRetryDroppedUpdates(t *testing.T) {
+	ctx := context.Background()
+	q := newTestMetadataUpdateQueue(defaultMetadataSyncMaxRetries)
+	defer q.queue.ShutDown()
+	volumeManager := &recordingVolumeManager{failures: 1}
+	// The volume is deleted while its update is running.
+	volumeManager.onUpdate = func() { q.drop(ctx, "vol1") }
+
+	q.add(ctx, volumeManager, newTestMetadataUpdateSpec("vol1", "PERSISTENT_VOLUME_CLAIM", "pvc1", nil))
+	assert.True(t, q.processNextItem(ctx))
+	assert.Len(t, volumeManager.calls, 1)
+	assert.Empty(t, q.pending)
+	assert.Empty(t, q.inFlight)
+	assert.Empty(t, q.dropped)
+	assert.Equal(t, 0, q.queue.NumRequeues("vol1"))
+}
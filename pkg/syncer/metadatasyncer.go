@@ -109,6 +109,10 @@ var (
 	// isSharedDiskEabled is true if shared disks are supported on the supervisor cluster
 	isSharedDiskEabled bool
 
+	// isMultiWriterBlockVolumeEnabled is true if multi-writer raw block volumes
+	// are supported on the vanilla cluster
+	isMultiWriterBlockVolumeEnabled bool
+
 	// cnsvolumeoperationrequestInitialSyncComplete tracks whether the initial cache sync
 	// for CnsVolumeOperationRequest informer is complete. This prevents quota double-counting
 	// during syncer restarts when existing CRs trigger AddFunc events. int32 is used to avoid
@@ -295,6 +299,12 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 	}
 	metadataSyncer.clusterFlavor = clusterFlavor
 	clusterIDforVolumeMetadata = configInfo.Cfg.Global.ClusterID
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		// Multi-writer raw block volumes have a multi-node access mode, but
+		// are synced as block volumes.
+		isMultiWriterBlockVolumeEnabled = commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx,
+			common.MultiWriterBlockVolume)
+	}
 	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorWorkload {
 		isSharedDiskEabled = commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.SharedDiskFss)
 		if !configInfo.Cfg.Global.InsecureFlag && configInfo.Cfg.Global.CAFile != cnsconfig.SupervisorCAFilePath {
@@ -753,6 +763,16 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 		}
 	}
 
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.MetadataSyncQueue) {
+		// Queue the volume metadata updates of the informer callbacks, so that
+		// a burst of events does not block the informers on CNS calls.
+		metadataSyncer.metadataUpdateQueue = newMetadataUpdateQueue(
+			workqueue.NewTypedItemExponentialFailureRateLimiter[string](metadataSyncRetryIntervalStart,
+				metadataSyncRetryIntervalMax), getMetadataSyncMaxRetries(ctx))
+		metadataSyncer.metadataUpdateQueue.run(ctx, getMetadataSyncWorkers(ctx))
+	}
+
 	// Set up kubernetes resource listeners for metadata syncer.
 	metadataSyncer.k8sInformerManager = k8s.NewInformer(ctx, k8sClient)
 
@@ -833,6 +853,15 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 		}
 	}
 
+	if isFileVolumeACLTighteningEnabled(ctx, metadataSyncer) {
+		// Register the listeners of the file volume ACL reconciler before the
+		// informers are started.
+		metadataSyncer.fileVolumeACLReconciler, err = newFileVolumeACLReconciler(ctx, metadataSyncer, k8sClient)
+		if err != nil {
+			return err
+		}
+	}
+
 	stopCh := metadataSyncer.k8sInformerManager.Listen()
 	if stopCh == nil {
 		return logger.LogNewError(log, "Failed to sync informer caches")
@@ -957,6 +986,30 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 		}()
 	}
 
+	// Trigger the garbage collection of the orphaned CNS volumes and snapshots
+	// on vanilla cluster.
+	if isOrphanGCEnabled(ctx, metadataSyncer) {
+		gc, err := newOrphanGC(ctx, metadataSyncer)
+		if err != nil {
+			return err
+		}
+		orphanGCTicker := time.NewTicker(time.Duration(configInfo.Cfg.OrphanGC.IntervalInMin) * time.Minute)
+		defer orphanGCTicker.Stop()
+		go func() {
+			for range orphanGCTicker.C {
+				ctx, log := logger.GetNewContextWithLogger()
+				log.Infof("periodic OrphanGC is triggered")
+				gc.run(ctx)
+			}
+		}()
+	}
+
+	// Tighten the ACL of the file volumes to the nodes consuming them on
+	// vanilla cluster.
+	if metadataSyncer.fileVolumeACLReconciler != nil {
+		metadataSyncer.fileVolumeACLReconciler.run(ctx)
+	}
+
 	// Trigger get pv to backingDiskObjectId mapping on vanilla cluster
 	pvToBackingDiskObjectIdFSSEnabled := metadataSyncer.coCommonInterface.IsFSSEnabled(ctx,
 		common.PVtoBackingDiskObjectIdMapping)
@@ -2915,6 +2968,7 @@ func pvcUpdated(oldObj, newObj interface{}, metadataSyncer *metadataSyncInformer
 	// processing a VAC change for it. We start / stop the per-PVC migration
 	// watcher here. Internally gated on the VM_PVC_STORAGE_POLICY_MUTABILITY FSS.
 	handlePvcMigrationAnnotations(ctx, oldPvc, newPvc, metadataSyncer)
+	handlePvcNetPermissionsAnnotation(ctx, oldPvc, newPvc, metadataSyncer)
 
 	if newPvc.Status.Phase != v1.ClaimBound {
 		log.Debugf("PVCUpdated: New PVC not in Bound phase")
@@ -3474,36 +3528,41 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 		// Following wait poll is required to avoid race condition between
 		// pvcUpdated and pvUpdated. This helps avoid race condition between
 		// pvUpdated and pvcUpdated handlers when static PV and PVC is created
-		// almost at the same time using single YAML file.
-		err = wait.PollUntilContextTimeout(ctx, 5*time.Second, time.Minute, false,
-			func(ctx context.Context) (bool, error) {
-				queryFilter := cnstypes.CnsQueryFilter{
-					VolumeIds: []cnstypes.CnsVolumeId{{Id: volumeHandle}},
-				}
-				// Query with empty selection. CNS returns only the volume ID from
-				// its cache.
-				queryResult, err := cnsVolumeMgr.QueryAllVolume(ctx, queryFilter, cnstypes.CnsQuerySelection{})
-				if err != nil {
-					log.Errorf("PVCUpdated: QueryVolume failed for volume %q with err=%+v", volumeHandle, err.Error())
-					return false, err
-				}
-				if queryResult != nil && len(queryResult.Volumes) == 1 && queryResult.Volumes[0].VolumeId.Id == volumeHandle {
-					log.Infof("PVCUpdated: volume %q found", volumeHandle)
-					volumeFound = true
-				}
-				return volumeFound, nil
-			})
-		if err != nil {
-			log.Errorf("PVCUpdated: Error occurred while polling to check if volume is marked as container volume. "+
-				"err: %+v", err)
-			return
-		}
+		// almost at the same time using single YAML file. Queued updates are
+		// retried with backoff until the volume is registered, instead.
+		if metadataSyncer.metadataUpdateQueue == nil {
+			err = wait.PollUntilContextTimeout(ctx, 5*time.Second, time.Minute, false,
+				func(ctx context.Context) (bool, error) {
+					queryFilter := cnstypes.CnsQueryFilter{
+						VolumeIds: []cnstypes.CnsVolumeId{{Id: volumeHandle}},
+					}
+					// Query with empty selection. CNS returns only the volume ID from
+					// its cache.
+					queryResult, err := cnsVolumeMgr.QueryAllVolume(ctx, queryFilter, cnstypes.CnsQuerySelection{})
+					if err != nil {
+						log.Errorf("PVCUpdated: QueryVolume failed for volume %q with err=%+v",
+							volumeHandle, err.Error())
+						return false, err
+					}
+					if queryResult != nil && len(queryResult.Volumes) == 1 &&
+						queryResult.Volumes[0].VolumeId.Id == volumeHandle {
+						log.Infof("PVCUpdated: volume %q found", volumeHandle)
+						volumeFound = true
+					}
+					return volumeFound, nil
+				})
+			if err != nil {
+				log.Errorf("PVCUpdated: Error occurred while polling to check if volume is marked as container volume. "+
+					"err: %+v", err)
+				return
+			}
 
-		if !volumeFound {
-			// volumeFound will be false when wait poll times out.
-			log.Errorf("PVCUpdated: volume: %q is not marked as the container volume. Skipping PVC entity metadata update",
-				volumeHandle)
-			return
+			if !volumeFound {
+				// volumeFound will be false when wait poll times out.
+				log.Errorf("PVCUpdated: volume: %q is not marked as the container volume. "+
+					"Skipping PVC entity metadata update", volumeHandle)
+				return
+			}
 		}
 	}
 
@@ -3538,7 +3597,7 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 	}
 
 	log.Debugf("PVCUpdated: Calling UpdateVolumeMetadata with updateSpec: %+v", spew.Sdump(updateSpec))
-	if err := cnsVolumeMgr.UpdateVolumeMetadata(ctx, updateSpec); err != nil {
+	if err := updateVolumeMetadata(ctx, metadataSyncer, cnsVolumeMgr, updateSpec); err != nil {
 		log.Errorf("PVCUpdated: UpdateVolumeMetadata failed with err %v", err)
 	}
 }
@@ -3611,7 +3670,7 @@ func csiPVCDeleted(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 	log.Debugf("PVCDeleted: Calling UpdateVolumeMetadata for volume %s with updateSpec: %+v",
 		updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
 
-	if err := cnsVolumeMgr.UpdateVolumeMetadata(ctx, updateSpec); err != nil {
+	if err := updateVolumeMetadata(ctx, metadataSyncer, cnsVolumeMgr, updateSpec); err != nil {
 		log.Errorf("PVCDeleted: UpdateVolumeMetadata failed with err %v", err)
 	}
 }
@@ -3806,12 +3865,14 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 
 	log.Debugf("PVUpdated: Calling UpdateVolumeMetadata for volume %q with updateSpec: %+v",
 		updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
-	if err := cnsVolumeMgr.UpdateVolumeMetadata(ctx, updateSpec); err != nil {
+	if err := updateVolumeMetadata(ctx, metadataSyncer, cnsVolumeMgr, updateSpec); err != nil {
 		log.Errorf("PVUpdated: UpdateVolumeMetadata failed with err %v", err)
 		return
 	}
-	log.Debugf("PVUpdated: UpdateVolumeMetadata succeed for the volume %q with updateSpec: %+v",
-		updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
+	if metadataSyncer.metadataUpdateQueue == nil {
+		log.Debugf("PVUpdated: UpdateVolumeMetadata succeed for the volume %q with updateSpec: %+v",
+			updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
+	}
 }
 
 // csiPVDeleted deletes volume metadata on VC when volume has been deleted on
@@ -3895,6 +3956,9 @@ func csiPVDeleted(ctx context.Context, pv *v1.PersistentVolume, metadataSyncer *
 
 		volumeOperationsLock[vcHost].Lock()
 		defer volumeOperationsLock[vcHost].Unlock()
+		// A queued update of the PV or PVC entity must not add its metadata
+		// back once the references are removed.
+		dropVolumeMetadataUpdates(ctx, metadataSyncer, pv.Spec.CSI.VolumeHandle)
 
 		vcHostObj, vcHostObjFound := metadataSyncer.configInfo.Cfg.VirtualCenter[vcHost]
 		if !vcHostObjFound {
@@ -3989,6 +4053,8 @@ func csiPVDeleted(ctx context.Context, pv *v1.PersistentVolume, metadataSyncer *
 		defer volumeOperationsLock[vcHost].Unlock()
 
 		log.Debugf("PVDeleted: vSphere CSI Driver is deleting volume %v", pv)
+		// A queued update must not run against the deleted volume.
+		dropVolumeMetadataUpdates(ctx, metadataSyncer, volumeHandle)
 
 		if _, err := cnsVolumeMgr.DeleteVolume(ctx, volumeHandle, false); err != nil {
 			log.Errorf("PVDeleted: Failed to delete disk %s with error %+v", volumeHandle, err)
@@ -4158,7 +4224,7 @@ func csiUpdatePod(ctx context.Context, pod *v1.Pod, metadataSyncer *metadataSync
 
 		log.Debugf("Calling UpdateVolumeMetadata for volume %s with updateSpec: %+v",
 			updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
-		if err := cnsVolumeMgr.UpdateVolumeMetadata(ctx, updateSpec); err != nil {
+		if err := updateVolumeMetadata(ctx, metadataSyncer, cnsVolumeMgr, updateSpec); err != nil {
 			log.Errorf("UpdateVolumeMetadata failed for volume %s with err: %v", volume.Name, err)
 		}
 
@@ -0,0 +1,179 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"strings"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/types"
+)
+
+// handlePvcNetPermissionsAnnotation is invoked from pvcUpdated. When the net
+// permissions annotation of a bound file volume claim changes, it replaces
+// the net permissions of the file volume through ConfigureVolumeACLs. The net
+// permissions of the StorageClass, or else of the vSphere config, are applied
+// back when the annotation is removed.
+func handlePvcNetPermissionsAnnotation(ctx context.Context, oldPvc, newPvc *v1.PersistentVolumeClaim,
+	metadataSyncer *metadataSyncInformer) {
+	if metadataSyncer.clusterFlavor != cnstypes.CnsClusterFlavorVanilla ||
+		!metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.FileVolumeNetPermissions) {
+		return
+	}
+	oldValue := oldPvc.Annotations[common.AnnNetPermissions]
+	newValue := newPvc.Annotations[common.AnnNetPermissions]
+	if oldValue == newValue || newPvc.Status.Phase != v1.ClaimBound {
+		return
+	}
+	log := logger.GetLogger(ctx)
+	pv, err := metadataSyncer.pvLister.Get(newPvc.Spec.VolumeName)
+	if err != nil {
+		log.Errorf("PVCUpdated: failed to get PV %q of PVC %s/%s to update its net permissions. Error: %v",
+			newPvc.Spec.VolumeName, newPvc.Namespace, newPvc.Name, err)
+		return
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name || !IsFileVolume(pv) {
+		return
+	}
+	if metadataSyncer.fileVolumeACLReconciler != nil {
+		// The ACL of the volume is tightened to the nodes consuming it, within
+		// its new net permissions.
+		metadataSyncer.fileVolumeACLReconciler.queue.Add(pv.Name)
+		return
+	}
+	volumeID := pv.Spec.CSI.VolumeHandle
+	k8sClient, err := k8sNewClient(ctx)
+	if err != nil {
+		log.Errorf("PVCUpdated: failed to create the Kubernetes client. Error: %v", err)
+		return
+	}
+	scNetPermissions, err := getStorageClassNetPermissions(ctx, k8sClient, pv.Spec.StorageClassName)
+	if err != nil {
+		log.Errorf("PVCUpdated: failed to get the net permissions of StorageClass %q. Error: %v",
+			pv.Spec.StorageClassName, err)
+		return
+	}
+	configured := metadataSyncer.configInfo.Cfg.NetPermissions
+	newNetPerms, err := getEffectiveNetPermissions(newValue, scNetPermissions, configured)
+	if err != nil {
+		log.Errorf("PVCUpdated: invalid net permissions of PVC %s/%s. Not updating volume %q. Error: %v",
+			newPvc.Namespace, newPvc.Name, volumeID, err)
+		return
+	}
+	oldNetPerms, err := getEffectiveNetPermissions(oldValue, scNetPermissions, configured)
+	if err != nil {
+		// The new net permissions are still applied, but the old ones cannot
+		// be removed.
+		log.Warnf("PVCUpdated: invalid previous net permissions of PVC %s/%s. Error: %v",
+			newPvc.Namespace, newPvc.Name, err)
+		oldNetPerms = nil
+	}
+	_, volumeManager, err := getVcHostAndVolumeManagerForVolumeID(ctx, metadataSyncer, volumeID)
+	if err != nil {
+		log.Errorf("PVCUpdated: failed to get the volume manager of volume %q. Error: %v", volumeID, err)
+		return
+	}
+	spec := getNetPermissionsACLConfigureSpec(volumeID, oldNetPerms, newNetPerms)
+	if err := volumeManager.ConfigureVolumeACLs(ctx, spec); err != nil {
+		log.Errorf("PVCUpdated: failed to update the net permissions of volume %q of PVC %s/%s. Error: %v",
+			volumeID, newPvc.Namespace, newPvc.Name, err)
+		return
+	}
+	log.Infof("PVCUpdated: updated the net permissions of volume %q of PVC %s/%s",
+		volumeID, newPvc.Namespace, newPvc.Name)
+}
+
+// getStorageClassNetPermissions returns the netpermissions parameter of the
+// StorageClass scName, or "" if the StorageClass no longer exists.
+func getStorageClassNetPermissions(ctx context.Context, k8sClient clientset.Interface,
+	scName string) (string, error) {
+	if scName == "" {
+		return "", nil
+	}
+	sc, err := k8sClient.StorageV1().StorageClasses().Get(ctx, scName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	for param, value := range sc.Parameters {
+		if strings.ToLower(param) == common.AttributeNetPermissions {
+			return value, nil
+		}
+	}
+	return "", nil
+}
+
+// getEffectiveNetPermissions returns the net permissions of a file volume,
+// selected or defined by the annotation of its PVC, or else by the parameter
+// of its StorageClass, or else the NetPermissions of the vSphere config.
+func getEffectiveNetPermissions(annotation, scParameter string,
+	configured map[string]*cnsconfig.NetPermissionConfig) ([]*cnsconfig.NetPermissionConfig, error) {
+	if annotation != "" {
+		return cnsconfig.ParseNetPermissions(annotation, configured)
+	}
+	if scParameter != "" {
+		return cnsconfig.ParseNetPermissions(scParameter, configured)
+	}
+	var netPerms []*cnsconfig.NetPermissionConfig
+	for _, netPerm := range configured {
+		netPerms = append(netPerms, netPerm)
+	}
+	return netPerms, nil
+}
+
+// getNetPermissionsACLConfigureSpec returns the spec replacing the net
+// permissions oldNetPerms of volume volumeID by newNetPerms. The clients of
+// oldNetPerms missing from newNetPerms are removed.
+func getNetPermissionsACLConfigureSpec(volumeID string,
+	oldNetPerms, newNetPerms []*cnsconfig.NetPermissionConfig) cnstypes.CnsVolumeACLConfigureSpec {
+	newIps := make(map[string]bool)
+	for _, netPerm := range newNetPerms {
+		newIps[netPerm.Ips] = true
+	}
+	var removed []*cnsconfig.NetPermissionConfig
+	for _, netPerm := range oldNetPerms {
+		if !newIps[netPerm.Ips] {
+			removed = append(removed, netPerm)
+		}
+	}
+	spec := cnstypes.CnsVolumeACLConfigureSpec{
+		VolumeId: cnstypes.CnsVolumeId{Id: volumeID},
+	}
+	if len(removed) != 0 {
+		spec.AccessControlSpecList = append(spec.AccessControlSpecList, cnstypes.CnsNFSAccessControlSpec{
+			Permission: common.GetVsanFileShareNetPermissions(removed),
+			Delete:     true,
+		})
+	}
+	if len(newNetPerms) != 0 {
+		spec.AccessControlSpecList = append(spec.AccessControlSpecList, cnstypes.CnsNFSAccessControlSpec{
+			Permission: common.GetVsanFileShareNetPermissions(newNetPerms),
+		})
+	}
+	return spec
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/unittestcommon"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/types"
+)
+
+// netPermissionsFakeManager is a volume manager recording the ACL specs.
+type netPermissionsFakeManager struct {
+	volumes.Manager
+	specs []cnstypes.CnsVolumeACLConfigureSpec
+}
+
+func (m *netPermissionsFakeManager) ConfigureVolumeACLs(ctx context.Context,
+	spec cnstypes.CnsVolumeACLConfigureSpec) error {
+	m.specs = append(m.specs, spec)
+	return nil
+}
+
+func TestHandlePvcNetPermissionsAnnotation(t *testing.T) {
+	ctx := logger.NewContextWithLogger(context.Background())
+	k8sClient := k8sfake.NewSimpleClientset(&storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "sc-1"},
+		Parameters: map[string]string{"netPermissions": "tenant-a"},
+	})
+	origK8sClient := k8sNewClient
+	defer func() { k8sNewClient = origK8sClient }()
+	k8sNewClient = func(ctx context.Context) (clientset.Interface, error) {
+		return k8sClient, nil
+	}
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+		Spec: v1.PersistentVolumeSpec{
+			AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+			StorageClassName: "sc-1",
+			PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{
+				Driver:       csitypes.Name,
+				VolumeHandle: "file:volume-1",
+			}},
+		},
+	}
+	pvLister, _, _ := newTestListers(t, pv)
+	coCommonInterface, err := unittestcommon.GetFakeContainerOrchestratorInterface(common.Kubernetes)
+	require.NoError(t, err)
+	require.NoError(t, coCommonInterface.EnableFSS(ctx, common.FileVolumeNetPermissions))
+	defer func() { _ = coCommonInterface.DisableFSS(ctx, common.FileVolumeNetPermissions) }()
+	configInfo := &cnsconfig.ConfigurationInfo{Cfg: &cnsconfig.Config{
+		NetPermissions: map[string]*cnsconfig.NetPermissionConfig{
+			"tenant-a": {Ips: "10.0.0.0/24", Permissions: "READ_WRITE"},
+			"tenant-b": {Ips: "10.0.1.0/24", Permissions: "READ_ONLY"},
+		},
+	}}
+	configInfo.Cfg.Global.VCenterIP = "vc-1"
+	configInfo.Cfg.VirtualCenter = map[string]*cnsconfig.VirtualCenterConfig{"vc-1": {}}
+	volumeManager := &netPermissionsFakeManager{}
+	metadataSyncer := &metadataSyncInformer{
+		clusterFlavor:     cnstypes.CnsClusterFlavorVanilla,
+		configInfo:        configInfo,
+		coCommonInterface: coCommonInterface,
+		pvLister:          pvLister,
+		volumeManagers:    map[string]volumes.Manager{"vc-1": volumeManager},
+	}
+	newPVC := func(netPermissions string) *v1.PersistentVolumeClaim {
+		pvc := &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-1", Namespace: "default"},
+			Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv-1"},
+			Status:     v1.PersistentVolumeClaimStatus{Phase: v1.ClaimBound},
+		}
+		if netPermissions != "" {
+			pvc.Annotations = map[string]string{common.AnnNetPermissions: netPermissions}
+		}
+		return pvc
+	}
+
+	handlePvcNetPermissionsAnnotation(ctx, newPVC(""), newPVC(""), metadataSyncer)
+	assert.Empty(t, volumeManager.specs, "the net permissions must only be updated when the annotation changes")
+
+	handlePvcNetPermissionsAnnotation(ctx, newPVC(""), newPVC("tenant-b"), metadataSyncer)
+	require.Len(t, volumeManager.specs, 1)
+	spec := volumeManager.specs[0]
+	assert.Equal(t, "file:volume-1", spec.VolumeId.Id)
+	require.Len(t, spec.AccessControlSpecList, 2)
+	assert.True(t, spec.AccessControlSpecList[0].Delete, "the net permissions of the StorageClass must be removed")
+	assert.Equal(t, "10.0.0.0/24", spec.AccessControlSpecList[0].Permission[0].Ips)
+	assert.False(t, spec.AccessControlSpecList[1].Delete)
+	assert.Equal(t, "10.0.1.0/24", spec.AccessControlSpecList[1].Permission[0].Ips)
+
+	handlePvcNetPermissionsAnnotation(ctx, newPVC("tenant-b"), newPVC("tenant-c"), metadataSyncer)
+	assert.Len(t, volumeManager.specs, 1, "invalid net permissions must not be applied")
+
+	// Removing the annotation applies the net permissions of the StorageClass
+	// back.
+	handlePvcNetPermissionsAnnotation(ctx, newPVC("tenant-b"), newPVC(""), metadataSyncer)
+	require.Len(t, volumeManager.specs, 2)
+	spec = volumeManager.specs[1]
+	require.Len(t, spec.AccessControlSpecList, 2)
+	assert.Equal(t, "10.0.1.0/24", spec.AccessControlSpecList[0].Permission[0].Ips)
+	assert.Equal(t, "10.0.0.0/24", spec.AccessControlSpecList[1].Permission[0].Ips)
+}
+
+func TestGetNetPermissionsACLConfigureSpecWithoutNewNetPermissions(t *testing.T) {
+	oldNetPerms := []*cnsconfig.NetPermissionConfig{{Ips: "10.0.0.0/24", Permissions: "READ_WRITE"}}
+
+	spec := getNetPermissionsACLConfigureSpec("volume-1", oldNetPerms, nil)
+	require.Len(t, spec.AccessControlSpecList, 1, "no access control spec must be added without net permissions")
+	assert.True(t, spec.AccessControlSpecList[0].Delete)
+	assert.Equal(t, "10.0.0.0/24", spec.AccessControlSpecList[0].Permission[0].Ips)
+}
@@ -0,0 +1,538 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	snapshotterClientSet "github.com/kubernetes-csi/external-snapshotter/client/v8/clientset/versioned"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apiMeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientset "k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/migration"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/utils"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis"
+	orphanobjectv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsoperator/cnsorphanobject/v1alpha1"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v3/pkg/kubernetes"
+)
+
+const (
+	// orphanGCReasonDetected is the reason of the event of an object found
+	// orphaned for the first time.
+	orphanGCReasonDetected = "OrphanDetected"
+	// orphanGCReasonHeld is the reason of the event of an orphaned object
+	// whose deletion is due but held.
+	orphanGCReasonHeld = "OrphanHeld"
+	// orphanGCReasonDeleted is the reason of the event of a deleted orphaned
+	// object.
+	orphanGCReasonDeleted = "OrphanDeleted"
+	// orphanGCReasonDeleteFailed is the reason of the event of a failed
+	// deletion of an orphaned object.
+	orphanGCReasonDeleteFailed = "OrphanDeleteFailed"
+	// orphanGCMinPasses is the number of consecutive passes which must find an
+	// object orphaned before it is deleted.
+	orphanGCMinPasses = 2
+)
+
+// newOrphanGCClients returns a client of the CnsOrphanObjects, a Kubernetes
+// client and a client of the VolumeSnapshotContents.
+var newOrphanGCClients = func(ctx context.Context) (client.Client, clientset.Interface,
+	snapshotterClientSet.Interface, error) {
+	restConfig, err := k8s.GetKubeConfig(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	c, err := k8s.NewClientForGroup(ctx, restConfig, cnsoperatorv1alpha1.GroupName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	k8sClient, err := k8s.NewClient(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	snapshotClient, err := k8s.NewSnapshotterClient(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return c, k8sClient, snapshotClient, nil
+}
+
+// orphanGC deletes the CNS volumes and snapshots of the cluster without a
+// Kubernetes counterpart. Each pass marks the orphaned objects with a
+// CnsOrphanObject, and deletes the objects found orphaned by at least
+// orphanGCMinPasses consecutive passes once their grace period elapsed, unless
+// their CnsOrphanObject is held.
+type orphanGC struct {
+	metadataSyncer *metadataSyncInformer
+	client         client.Client
+	k8sClient      clientset.Interface
+	snapshotClient snapshotterClientSet.Interface
+	recorder       record.EventRecorder
+	gracePeriod    time.Duration
+}
+
+// orphanGCPass holds the objects found by a pass of the garbage collector on a
+// vCenter.
+type orphanGCPass struct {
+	vc string
+	// orphans are the specs of the orphaned objects, by CnsOrphanObject name.
+	orphans map[string]orphanobjectv1alpha1.CnsOrphanObjectSpec
+	// volumesWithSnapshots are the volumes of the cluster with snapshots in
+	// CNS, which cannot be deleted.
+	volumesWithSnapshots map[string]bool
+	// snapshotsChecked is false if the VolumeSnapshotContents cannot be
+	// listed, in which case no snapshot is found orphaned.
+	snapshotsChecked bool
+}
+
+// isOrphanGCEnabled returns true if the orphan garbage collector runs in the
+// cluster.
+func isOrphanGCEnabled(ctx context.Context, metadataSyncer *metadataSyncInformer) bool {
+	return metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.OrphanGC)
+}
+
+// newOrphanGC returns the orphan garbage collector of the cluster.
+func newOrphanGC(ctx context.Context, metadataSyncer *metadataSyncInformer) (*orphanGC, error) {
+	log := logger.GetLogger(ctx)
+	c, k8sClient, snapshotClient, err := newOrphanGCClients(ctx)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to create the clients of the orphan GC. Err: %v", err)
+	}
+	scheme := runtime.NewScheme()
+	if err := internalapis.AddToScheme(scheme); err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to add the CnsOrphanObject scheme. Err: %v", err)
+	}
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: k8sClient.CoreV1().Events(""),
+	})
+	return &orphanGC{
+		metadataSyncer: metadataSyncer,
+		client:         c,
+		k8sClient:      k8sClient,
+		snapshotClient: snapshotClient,
+		recorder:       eventBroadcaster.NewRecorder(scheme, v1.EventSource{Component: "vsphere-csi-orphan-gc"}),
+		gracePeriod:    time.Duration(metadataSyncer.configInfo.Cfg.OrphanGC.GracePeriodInMin) * time.Minute,
+	}, nil
+}
+
+// run makes a pass of the garbage collector on every vCenter of the cluster.
+func (gc *orphanGC) run(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	if clusterIDforVolumeMetadata == "" {
+		log.Warnf("OrphanGC: cluster-id is not set, skipping the garbage collection")
+		return
+	}
+	for vc := range gc.metadataSyncer.volumeManagers {
+		if err := gc.runForVC(ctx, vc); err != nil {
+			log.Errorf("OrphanGC for VC %s: pass failed. Err: %v", vc, err)
+		}
+	}
+}
+
+// runForVC makes a pass of the garbage collector on vc.
+func (gc *orphanGC) runForVC(ctx context.Context, vc string) error {
+	log := logger.GetLogger(ctx)
+	volumeManager, err := getVolManagerForVcHost(ctx, vc, gc.metadataSyncer)
+	if err != nil {
+		return err
+	}
+	pass, err := gc.findOrphans(ctx, vc)
+	if err != nil {
+		return err
+	}
+	markers, err := gc.markOrphans(ctx, pass)
+	if err != nil {
+		return err
+	}
+	var due []*orphanobjectv1alpha1.CnsOrphanObject
+	var snapshotsDue bool
+	now := time.Now()
+	for _, marker := range markers {
+		if marker.Status.Passes < orphanGCMinPasses || now.Before(marker.Status.DeleteAfter.Time) {
+			continue
+		}
+		if marker.IsHeld() {
+			log.Infof("OrphanGC for VC %s: deletion of %s %q is held", vc, marker.Spec.Kind, marker.Name)
+			gc.recorder.Eventf(marker, v1.EventTypeNormal, orphanGCReasonHeld,
+				"Deletion of the orphaned %s held by the %s label", marker.Spec.Kind, orphanobjectv1alpha1.HoldLabel)
+			continue
+		}
+		if marker.Spec.Kind == orphanobjectv1alpha1.OrphanObjectKindVolume &&
+			pass.volumesWithSnapshots[marker.Spec.VolumeID] {
+			log.Infof("OrphanGC for VC %s: volume %q has snapshots, deferring its deletion",
+				vc, marker.Spec.VolumeID)
+			continue
+		}
+		due = append(due, marker)
+		snapshotsDue = snapshotsDue || marker.Spec.Kind == orphanobjectv1alpha1.OrphanObjectKindSnapshot
+	}
+	if len(due) == 0 {
+		return nil
+	}
+	// Confirm against the API server, rather than the informer caches, that
+	// the objects due for deletion still have no Kubernetes counterpart.
+	liveVolumeIDs, liveSnapshotIDs, err := gc.getLiveCounterparts(ctx, snapshotsDue)
+	if err != nil {
+		return err
+	}
+	// Snapshots are deleted first, as a volume with snapshots cannot be
+	// deleted.
+	for _, kind := range []orphanobjectv1alpha1.OrphanObjectKind{orphanobjectv1alpha1.OrphanObjectKindSnapshot,
+		orphanobjectv1alpha1.OrphanObjectKindVolume} {
+		for _, marker := range due {
+			if marker.Spec.Kind != kind {
+				continue
+			}
+			if liveVolumeIDs[marker.Spec.VolumeID] && kind == orphanobjectv1alpha1.OrphanObjectKindVolume ||
+				liveSnapshotIDs[marker.Spec.SnapshotID] && kind == orphanobjectv1alpha1.OrphanObjectKindSnapshot {
+				log.Infof("OrphanGC for VC %s: %s %q is no longer orphaned", vc, kind, marker.Name)
+				continue
+			}
+			gc.deleteOrphan(ctx, volumeManager, marker)
+		}
+	}
+	return nil
+}
+
+// findOrphans returns the CNS volumes and snapshots of the cluster on vc
+// without a Kubernetes counterpart.
+func (gc *orphanGC) findOrphans(ctx context.Context, vc string) (*orphanGCPass, error) {
+	log := logger.GetLogger(ctx)
+	volumeManager, err := getVolManagerForVcHost(ctx, vc, gc.metadataSyncer)
+	if err != nil {
+		return nil, err
+	}
+	k8sVolumeIDs, err := gc.getK8sVolumeIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	k8sSnapshotIDs, err := gc.getK8sSnapshotIDs(ctx)
+	snapshotsChecked := err == nil
+	if err != nil {
+		if !apiMeta.IsNoMatchError(err) && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		log.Infof("OrphanGC for VC %s: VolumeSnapshotContent CRD not found, skipping the snapshots", vc)
+	}
+	pass := &orphanGCPass{
+		vc:                   vc,
+		orphans:              make(map[string]orphanobjectv1alpha1.CnsOrphanObjectSpec),
+		volumesWithSnapshots: make(map[string]bool),
+		snapshotsChecked:     snapshotsChecked,
+	}
+
+	queryAllResult, err := utils.QueryAllVolumesForCluster(ctx, volumeManager, clusterIDforVolumeMetadata,
+		cnstypes.CnsQuerySelection{})
+	if err != nil {
+		return nil, err
+	}
+	clusterVolumeIDs := make(map[string]bool)
+	var candidates []cnstypes.CnsVolumeId
+	for _, volume := range queryAllResult.Volumes {
+		volumeID := volume.VolumeId.Id
+		clusterVolumeIDs[volumeID] = true
+		if k8sVolumeIDs[volumeID] {
+			continue
+		}
+		// The PVC is still cached: the PV may not be in the informer cache yet.
+		if _, ok := gc.metadataSyncer.coCommonInterface.GetPVCNamespacedNameByUID(volumeID); ok {
+			continue
+		}
+		candidates = append(candidates, volume.VolumeId)
+	}
+	if len(candidates) > 0 {
+		// Query the metadata of the candidates, to keep the volumes of the
+		// PVs deleted with the Retain reclaim policy.
+		querySelection := &cnstypes.CnsQuerySelection{
+			Names: []string{
+				string(cnstypes.QuerySelectionNameTypeVolumeName),
+				string(querySelectionNameTypeVolumeMetadata),
+			},
+		}
+		queryResults, err := fullSyncGetQueryResults(ctx, candidates, clusterIDforVolumeMetadata, volumeManager,
+			gc.metadataSyncer, querySelection)
+		if err != nil {
+			return nil, err
+		}
+		for _, queryResult := range queryResults {
+			for _, volume := range queryResult.Volumes {
+				if isPVEntityLabeled(volume, prometheus.PrometheusPVRetainedLabelKey,
+					prometheus.PrometheusPVRetainedLabelValue) {
+					log.Debugf("OrphanGC for VC %s: skipping the retained volume %q", vc, volume.VolumeId.Id)
+					continue
+				}
+				pass.orphans[orphanobjectv1alpha1.GetCnsOrphanObjectName(orphanobjectv1alpha1.OrphanObjectKindVolume,
+					volume.VolumeId.Id)] = orphanobjectv1alpha1.CnsOrphanObjectSpec{
+					VCenter:  vc,
+					Kind:     orphanobjectv1alpha1.OrphanObjectKindVolume,
+					VolumeID: volume.VolumeId.Id,
+				}
+			}
+		}
+	}
+
+	snapshots, _, err := utils.QuerySnapshotsUtil(ctx, volumeManager, cnstypes.CnsSnapshotQueryFilter{},
+		math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range snapshots {
+		if entry.Error != nil {
+			continue
+		}
+		volumeID := entry.Snapshot.VolumeId.Id
+		snapshotID := entry.Snapshot.SnapshotId.Id
+		if !clusterVolumeIDs[volumeID] {
+			continue
+		}
+		pass.volumesWithSnapshots[volumeID] = true
+		if !snapshotsChecked || k8sSnapshotIDs[snapshotID] {
+			continue
+		}
+		pass.orphans[orphanobjectv1alpha1.GetCnsOrphanObjectName(orphanobjectv1alpha1.OrphanObjectKindSnapshot,
+			snapshotID)] = orphanobjectv1alpha1.CnsOrphanObjectSpec{
+			VCenter:    vc,
+			Kind:       orphanobjectv1alpha1.OrphanObjectKindSnapshot,
+			VolumeID:   volumeID,
+			SnapshotID: snapshotID,
+		}
+	}
+	log.Infof("OrphanGC for VC %s: found %d orphaned objects", vc, len(pass.orphans))
+	return pass, nil
+}
+
+// markOrphans creates or updates the CnsOrphanObjects of the orphans of pass,
+// deletes the CnsOrphanObjects of the objects of the vCenter which are no
+// longer orphaned, and returns the CnsOrphanObjects of the orphans.
+func (gc *orphanGC) markOrphans(ctx context.Context,
+	pass *orphanGCPass) ([]*orphanobjectv1alpha1.CnsOrphanObject, error) {
+	log := logger.GetLogger(ctx)
+	list := &orphanobjectv1alpha1.CnsOrphanObjectList{}
+	if err := gc.client.List(ctx, list); err != nil {
+		return nil, err
+	}
+	now := metav1.Now()
+	existing := make(map[string]bool)
+	var markers []*orphanobjectv1alpha1.CnsOrphanObject
+	for i := range list.Items {
+		marker := &list.Items[i]
+		if marker.Spec.VCenter != pass.vc {
+			continue
+		}
+		existing[marker.Name] = true
+		if _, orphaned := pass.orphans[marker.Name]; !orphaned {
+			if marker.Spec.Kind == orphanobjectv1alpha1.OrphanObjectKindSnapshot && !pass.snapshotsChecked {
+				continue
+			}
+			log.Infof("OrphanGC for VC %s: %s %q is no longer orphaned", pass.vc, marker.Spec.Kind, marker.Name)
+			if err := gc.client.Delete(ctx, marker); err != nil && !apierrors.IsNotFound(err) {
+				log.Warnf("OrphanGC for VC %s: failed to delete the CnsOrphanObject %q. Err: %v",
+					pass.vc, marker.Name, err)
+			}
+			continue
+		}
+		marker.Status.Passes++
+		marker.Status.LastSeen = now
+		if err := gc.client.Update(ctx, marker); err != nil {
+			log.Warnf("OrphanGC for VC %s: failed to update the CnsOrphanObject %q. Err: %v",
+				pass.vc, marker.Name, err)
+			continue
+		}
+		markers = append(markers, marker)
+	}
+	for name, spec := range pass.orphans {
+		if existing[name] {
+			continue
+		}
+		marker := &orphanobjectv1alpha1.CnsOrphanObject{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       spec,
+			Status: orphanobjectv1alpha1.CnsOrphanObjectStatus{
+				FirstSeen:   now,
+				LastSeen:    now,
+				Passes:      1,
+				DeleteAfter: metav1.NewTime(now.Add(gc.gracePeriod)),
+			},
+		}
+		if err := gc.client.Create(ctx, marker); err != nil {
+			log.Warnf("OrphanGC for VC %s: failed to create the CnsOrphanObject %q. Err: %v",
+				pass.vc, name, err)
+			continue
+		}
+		log.Infof("OrphanGC for VC %s: found the orphaned %s %q, to be deleted after %s",
+			pass.vc, spec.Kind, name, marker.Status.DeleteAfter.UTC().Format(time.RFC3339))
+		gc.recorder.Eventf(marker, v1.EventTypeNormal, orphanGCReasonDetected,
+			"Orphaned %s found, to be deleted after %s unless labeled %s=true", spec.Kind,
+			marker.Status.DeleteAfter.UTC().Format(time.RFC3339), orphanobjectv1alpha1.HoldLabel)
+	}
+	return markers, nil
+}
+
+// deleteOrphan deletes the CNS object of marker, and then marker.
+func (gc *orphanGC) deleteOrphan(ctx context.Context, volumeManager volumes.Manager,
+	marker *orphanobjectv1alpha1.CnsOrphanObject) {
+	log := logger.GetLogger(ctx)
+	var err error
+	switch marker.Spec.Kind {
+	case orphanobjectv1alpha1.OrphanObjectKindVolume:
+		_, err = volumeManager.DeleteVolume(ctx, marker.Spec.VolumeID, true)
+	case orphanobjectv1alpha1.OrphanObjectKindSnapshot:
+		_, err = volumeManager.DeleteSnapshot(ctx, marker.Spec.VolumeID, marker.Spec.SnapshotID, nil)
+	default:
+		err = fmt.Errorf("unknown kind %q", marker.Spec.Kind)
+	}
+	if err != nil {
+		log.Errorf("OrphanGC for VC %s: failed to delete the orphaned %s %q. Err: %v",
+			marker.Spec.VCenter, marker.Spec.Kind, marker.Name, err)
+		gc.recorder.Eventf(marker, v1.EventTypeWarning, orphanGCReasonDeleteFailed,
+			"Failed to delete the orphaned %s: %v", marker.Spec.Kind, err)
+		marker.Status.Error = err.Error()
+		if err := gc.client.Update(ctx, marker); err != nil {
+			log.Warnf("OrphanGC for VC %s: failed to update the CnsOrphanObject %q. Err: %v",
+				marker.Spec.VCenter, marker.Name, err)
+		}
+		return
+	}
+	log.Infof("OrphanGC for VC %s: deleted the orphaned %s %q", marker.Spec.VCenter, marker.Spec.Kind, marker.Name)
+	gc.recorder.Eventf(marker, v1.EventTypeNormal, orphanGCReasonDeleted, "Deleted the orphaned %s",
+		marker.Spec.Kind)
+	if err := gc.client.Delete(ctx, marker); err != nil && !apierrors.IsNotFound(err) {
+		log.Warnf("OrphanGC for VC %s: failed to delete the CnsOrphanObject %q. Err: %v",
+			marker.Spec.VCenter, marker.Name, err)
+	}
+}
+
+// getK8sVolumeIDs returns the volume IDs of the PVs in the informer cache.
+func (gc *orphanGC) getK8sVolumeIDs(ctx context.Context) (map[string]bool, error) {
+	pvs, err := gc.metadataSyncer.pvLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return gc.getVolumeIDs(ctx, pvs)
+}
+
+// getVolumeIDs returns the volume IDs of pvs. It fails if the volume ID of an
+// in-tree vSphere PV cannot be found, so that its volume is not deleted.
+func (gc *orphanGC) getVolumeIDs(ctx context.Context, pvs []*v1.PersistentVolume) (map[string]bool, error) {
+	volumeIDs := make(map[string]bool)
+	for _, pv := range pvs {
+		if pv.Spec.CSI != nil {
+			volumeIDs[pv.Spec.CSI.VolumeHandle] = true
+			continue
+		}
+		if pv.Spec.VsphereVolume == nil || len(gc.metadataSyncer.configInfo.Cfg.VirtualCenter) > 1 {
+			continue
+		}
+		if volumeMigrationService == nil {
+			if err := initVolumeMigrationService(ctx, gc.metadataSyncer); err != nil {
+				return nil, err
+			}
+		}
+		volumeID, err := volumeMigrationService.GetVolumeID(ctx, &migration.VolumeSpec{
+			VolumePath:        pv.Spec.VsphereVolume.VolumePath,
+			StoragePolicyName: pv.Spec.VsphereVolume.StoragePolicyName,
+		}, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the volume ID of PV %q: %w", pv.Name, err)
+		}
+		volumeIDs[volumeID] = true
+	}
+	if len(gc.metadataSyncer.configInfo.Cfg.VirtualCenter) <= 1 {
+		inlineVolumes, err := fullSyncGetInlineMigratedVolumesInfo(ctx, gc.metadataSyncer, true)
+		if err != nil {
+			return nil, err
+		}
+		for volumeID := range inlineVolumes {
+			volumeIDs[volumeID] = true
+		}
+	}
+	return volumeIDs, nil
+}
+
+// getK8sSnapshotIDs returns the snapshot IDs of the VolumeSnapshotContents of
+// the driver.
+func (gc *orphanGC) getK8sSnapshotIDs(ctx context.Context) (map[string]bool, error) {
+	contents, err := gc.snapshotClient.SnapshotV1().VolumeSnapshotContents().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	snapshotIDs := make(map[string]bool)
+	for _, content := range contents.Items {
+		var handles []*string
+		if content.Status != nil {
+			handles = append(handles, content.Status.SnapshotHandle)
+		}
+		handles = append(handles, content.Spec.Source.SnapshotHandle)
+		for _, handle := range handles {
+			if handle == nil {
+				continue
+			}
+			// The snapshot handle is the volume ID and the snapshot ID, joined by
+			// the snapshot ID delimiter.
+			parts := strings.Split(*handle, common.VSphereCSISnapshotIdDelimiter)
+			snapshotIDs[parts[len(parts)-1]] = true
+		}
+	}
+	return snapshotIDs, nil
+}
+
+// getLiveCounterparts returns the volume IDs of the PVs and, if listSnapshots is
+// true, the snapshot IDs of the VolumeSnapshotContents listed from the API
+// server.
+func (gc *orphanGC) getLiveCounterparts(ctx context.Context, listSnapshots bool) (map[string]bool,
+	map[string]bool, error) {
+	pvList, err := gc.k8sClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	pvs := make([]*v1.PersistentVolume, 0, len(pvList.Items))
+	for i := range pvList.Items {
+		pvs = append(pvs, &pvList.Items[i])
+	}
+	volumeIDs, err := gc.getVolumeIDs(ctx, pvs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !listSnapshots {
+		return volumeIDs, nil, nil
+	}
+	snapshotIDs, err := gc.getK8sSnapshotIDs(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return volumeIDs, snapshotIDs, nil
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	snapshotfake "github.com/kubernetes-csi/external-snapshotter/client/v8/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vimtypes "github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/cns-lib/volume"
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/unittestcommon"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis"
+	orphanobjectv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/internalapis/cnsoperator/cnsorphanobject/v1alpha1"
+)
+
+// orphanGCFakeManager is a volume manager holding the volumes and snapshots of
+// a vCenter.
+type orphanGCFakeManager struct {
+	volumes.Manager
+	volumes   map[string]cnstypes.CnsVolume
+	snapshots map[string]string
+}
+
+func (m *orphanGCFakeManager) QueryAllVolume(ctx context.Context, queryFilter cnstypes.CnsQueryFilter,
+	querySelection cnstypes.CnsQuerySelection) (*cnstypes.CnsQueryResult, error) {
+	return m.QueryVolume(ctx, queryFilter)
+}
+
+func (m *orphanGCFakeManager) QueryVolumeAsync(ctx context.Context, queryFilter cnstypes.CnsQueryFilter,
+	querySelection *cnstypes.CnsQuerySelection) (*cnstypes.CnsQueryResult, error) {
+	return m.QueryVolume(ctx, queryFilter)
+}
+
+func (m *orphanGCFakeManager) QueryVolume(ctx context.Context,
+	queryFilter cnstypes.CnsQueryFilter) (*cnstypes.CnsQueryResult, error) {
+	result := &cnstypes.CnsQueryResult{}
+	if len(queryFilter.VolumeIds) == 0 {
+		for _, volume := range m.volumes {
+			result.Volumes = append(result.Volumes, volume)
+		}
+		return result, nil
+	}
+	for _, volumeID := range queryFilter.VolumeIds {
+		if volume, ok := m.volumes[volumeID.Id]; ok {
+			result.Volumes = append(result.Volumes, volume)
+		}
+	}
+	return result, nil
+}
+
+func (m *orphanGCFakeManager) QuerySnapshots(ctx context.Context,
+	snapshotQueryFilter cnstypes.CnsSnapshotQueryFilter) (*cnstypes.CnsSnapshotQueryResult, error) {
+	result := &cnstypes.CnsSnapshotQueryResult{}
+	for snapshotID, volumeID := range m.snapshots {
+		result.Entries = append(result.Entries, cnstypes.CnsSnapshotQueryResultEntry{
+			Snapshot: cnstypes.CnsSnapshot{
+				SnapshotId: cnstypes.CnsSnapshotId{Id: snapshotID},
+				VolumeId:   cnstypes.CnsVolumeId{Id: volumeID},
+			},
+		})
+	}
+	result.Cursor.Offset = int64(len(result.Entries))
+	result.Cursor.TotalRecords = int64(len(result.Entries))
+	return result, nil
+}
+
+func (m *orphanGCFakeManager) DeleteVolume(ctx context.Context, volumeID string, deleteDisk bool) (string, error) {
+	delete(m.volumes, volumeID)
+	return "", nil
+}
+
+func (m *orphanGCFakeManager) DeleteSnapshot(ctx context.Context, volumeID string, snapshotID string,
+	extraParams interface{}) (*volumes.CnsSnapshotInfo, error) {
+	delete(m.snapshots, snapshotID)
+	return &volumes.CnsSnapshotInfo{SnapshotID: snapshotID, SourceVolumeID: volumeID}, nil
+}
+
+func TestOrphanGC(t *testing.T) {
+	ctx := logger.NewContextWithLogger(context.Background())
+	origClusterID := clusterIDforVolumeMetadata
+	defer func() { clusterIDforVolumeMetadata = origClusterID }()
+	clusterIDforVolumeMetadata = "cluster-1"
+
+	retainedVolume := cnstypes.CnsVolume{
+		VolumeId: cnstypes.CnsVolumeId{Id: "volume-3"},
+		Metadata: cnstypes.CnsVolumeMetadata{
+			EntityMetadata: []cnstypes.BaseCnsEntityMetadata{&cnstypes.CnsKubernetesEntityMetadata{
+				CnsEntityMetadata: cnstypes.CnsEntityMetadata{
+					EntityName: "pv-3",
+					Labels: []vimtypes.KeyValue{{
+						Key:   prometheus.PrometheusPVRetainedLabelKey,
+						Value: prometheus.PrometheusPVRetainedLabelValue,
+					}},
+					ClusterID: "cluster-1",
+				},
+				EntityType: string(cnstypes.CnsKubernetesEntityTypePV),
+			}},
+		},
+	}
+	volumeManager := &orphanGCFakeManager{
+		volumes: map[string]cnstypes.CnsVolume{
+			"volume-1": {VolumeId: cnstypes.CnsVolumeId{Id: "volume-1"}},
+			"volume-2": {VolumeId: cnstypes.CnsVolumeId{Id: "volume-2"}},
+			"volume-3": retainedVolume,
+			"volume-4": {VolumeId: cnstypes.CnsVolumeId{Id: "volume-4"}},
+		},
+		snapshots: map[string]string{"snapshot-1": "volume-1", "snapshot-2": "volume-4"},
+	}
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+		Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+			CSI: &v1.CSIPersistentVolumeSource{VolumeHandle: "volume-1"},
+		}},
+	}
+	snapshotHandle := "volume-1" + common.VSphereCSISnapshotIdDelimiter + "snapshot-1"
+	content := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{Name: "content-1"},
+		Status:     &snapshotv1.VolumeSnapshotContentStatus{SnapshotHandle: &snapshotHandle},
+	}
+	pvLister, _, _ := newTestListers(t, pv)
+	coCommonInterface, err := unittestcommon.GetFakeContainerOrchestratorInterface(common.Kubernetes)
+	require.NoError(t, err)
+	configInfo := &cnsconfig.ConfigurationInfo{Cfg: &cnsconfig.Config{}}
+	// The volumes of the in-tree vSphere PVs are not looked up with several
+	// vCenters.
+	configInfo.Cfg.VirtualCenter = map[string]*cnsconfig.VirtualCenterConfig{"vc-1": {}, "vc-2": {}}
+	metadataSyncer := &metadataSyncInformer{
+		clusterFlavor:     cnstypes.CnsClusterFlavorVanilla,
+		configInfo:        configInfo,
+		coCommonInterface: coCommonInterface,
+		pvLister:          pvLister,
+		volumeManagers:    map[string]volumes.Manager{"vc-1": volumeManager},
+	}
+	scheme := runtime.NewScheme()
+	require.NoError(t, internalapis.AddToScheme(scheme))
+	recorder := record.NewFakeRecorder(100)
+	gc := &orphanGC{
+		gracePeriod:    time.Hour,
+		metadataSyncer: metadataSyncer,
+		client:         fake.NewClientBuilder().WithScheme(scheme).Build(),
+		k8sClient:      k8sfake.NewSimpleClientset(pv),
+		snapshotClient: snapshotfake.NewSimpleClientset(content),
+		recorder:       recorder,
+	}
+	getMarker := func(kind orphanobjectv1alpha1.OrphanObjectKind, id string) (
+		*orphanobjectv1alpha1.CnsOrphanObject, error) {
+		marker := &orphanobjectv1alpha1.CnsOrphanObject{}
+		err := gc.client.Get(ctx, apitypes.NamespacedName{
+			Name: orphanobjectv1alpha1.GetCnsOrphanObjectName(kind, id)}, marker)
+		return marker, err
+	}
+
+	// The first pass only marks the orphans.
+	gc.run(ctx)
+	markers := &orphanobjectv1alpha1.CnsOrphanObjectList{}
+	require.NoError(t, gc.client.List(ctx, markers))
+	assert.Len(t, markers.Items, 3, "volume-2, volume-4 and snapshot-2 must be marked")
+	_, err = getMarker(orphanobjectv1alpha1.OrphanObjectKindVolume, "volume-3")
+	assert.Error(t, err, "the volumes of the retained PVs must not be marked")
+	assert.Len(t, volumeManager.volumes, 4, "nothing must be deleted by the first pass")
+	assert.Len(t, recorder.Events, 3)
+
+	// The second pass does not delete the orphans within the grace period.
+	gc.run(ctx)
+	assert.Len(t, volumeManager.volumes, 4, "nothing must be deleted within the grace period")
+
+	// Past the grace period, the held orphans are kept, and the volumes with
+	// snapshots are deleted once their snapshots are.
+	require.NoError(t, gc.client.List(ctx, markers))
+	for i := range markers.Items {
+		assert.Equal(t, 2, markers.Items[i].Status.Passes)
+		markers.Items[i].Status.DeleteAfter = metav1.NewTime(time.Now().Add(-time.Minute))
+		require.NoError(t, gc.client.Update(ctx, &markers.Items[i]))
+	}
+	held, err := getMarker(orphanobjectv1alpha1.OrphanObjectKindVolume, "volume-2")
+	require.NoError(t, err)
+	held.Labels = map[string]string{orphanobjectv1alpha1.HoldLabel: "true"}
+	require.NoError(t, gc.client.Update(ctx, held))
+	gc.run(ctx)
+	assert.Contains(t, volumeManager.volumes, "volume-2", "the held volume must be kept")
+	assert.Contains(t, volumeManager.volumes, "volume-4", "the volume must be kept until its snapshots are deleted")
+	assert.NotContains(t, volumeManager.snapshots, "snapshot-2")
+	assert.Contains(t, volumeManager.snapshots, "snapshot-1")
+	_, err = getMarker(orphanobjectv1alpha1.OrphanObjectKindSnapshot, "snapshot-2")
+	assert.Error(t, err, "the marker of the deleted snapshot must be deleted")
+
+	gc.run(ctx)
+	assert.NotContains(t, volumeManager.volumes, "volume-4")
+	assert.Contains(t, volumeManager.volumes, "volume-2")
+	assert.Contains(t, volumeManager.volumes, "volume-3")
+	assert.Contains(t, volumeManager.volumes, "volume-1")
+}
+
+func TestOrphanGCConfirmation(t *testing.T) {
+	ctx := logger.NewContextWithLogger(context.Background())
+	origClusterID := clusterIDforVolumeMetadata
+	defer func() { clusterIDforVolumeMetadata = origClusterID }()
+	clusterIDforVolumeMetadata = "cluster-1"
+
+	volumeManager := &orphanGCFakeManager{
+		volumes: map[string]cnstypes.CnsVolume{
+			"volume-1": {VolumeId: cnstypes.CnsVolumeId{Id: "volume-1"}},
+		},
+	}
+	// The PV is created after the informer cache was synced.
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+		Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+			CSI: &v1.CSIPersistentVolumeSource{VolumeHandle: "volume-1"},
+		}},
+	}
+	pvLister, _, _ := newTestListers(t)
+	coCommonInterface, err := unittestcommon.GetFakeContainerOrchestratorInterface(common.Kubernetes)
+	require.NoError(t, err)
+	configInfo := &cnsconfig.ConfigurationInfo{Cfg: &cnsconfig.Config{}}
+	configInfo.Cfg.VirtualCenter = map[string]*cnsconfig.VirtualCenterConfig{"vc-1": {}, "vc-2": {}}
+	scheme := runtime.NewScheme()
+	require.NoError(t, internalapis.AddToScheme(scheme))
+	marker := &orphanobjectv1alpha1.CnsOrphanObject{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: orphanobjectv1alpha1.GetCnsOrphanObjectName(orphanobjectv1alpha1.OrphanObjectKindVolume, "volume-1"),
+		},
+		Spec: orphanobjectv1alpha1.CnsOrphanObjectSpec{
+			VCenter:  "vc-1",
+			Kind:     orphanobjectv1alpha1.OrphanObjectKindVolume,
+			VolumeID: "volume-1",
+		},
+		Status: orphanobjectv1alpha1.CnsOrphanObjectStatus{
+			Passes:      1,
+			DeleteAfter: metav1.NewTime(time.Now().Add(-time.Minute)),
+		},
+	}
+	gc := &orphanGC{
+		metadataSyncer: &metadataSyncInformer{
+			clusterFlavor:     cnstypes.CnsClusterFlavorVanilla,
+			configInfo:        configInfo,
+			coCommonInterface: coCommonInterface,
+			pvLister:          pvLister,
+			volumeManagers:    map[string]volumes.Manager{"vc-1": volumeManager},
+		},
+		client:         fake.NewClientBuilder().WithScheme(scheme).WithObjects(marker).Build(),
+		k8sClient:      k8sfake.NewSimpleClientset(pv),
+		snapshotClient: snapshotfake.NewSimpleClientset(),
+		recorder:       record.NewFakeRecorder(100),
+	}
+	gc.run(ctx)
+	assert.Contains(t, volumeManager.volumes, "volume-1",
+		"the volume of a PV found by the confirmation must not be deleted")
+}
@@ -45,6 +45,7 @@ import (
 	cnsvolumemetadatav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v3/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
 	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/config"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v3/pkg/csi/service/logger"
 )
 
@@ -60,6 +61,10 @@ func PvcsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) er
 	log := logger.GetLogger(ctx)
 	log.Infof("FullSync: Start")
 	var err error
+	ctx, span := tracing.StartSpan(ctx, "syncer.PvcsiFullSync")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	fullSyncStartTime := time.Now()
 	defer func() {
 		fullSyncStatus := prometheus.PrometheusPassStatus
@@ -158,6 +158,12 @@ type metadataSyncInformer struct {
 	supervisorSnapClient        snapshotterClientSet.Interface
 	supervisorSnapRestConf      *restclient.Config
 	supervisorSnapRuntimeClient client.Client
+	// metadataUpdateQueue queues the volume metadata updates of the informer
+	// callbacks. It is nil when the updates are made inline.
+	metadataUpdateQueue *metadataUpdateQueue
+	// fileVolumeACLReconciler tightens the ACL of the file volumes to the
+	// nodes consuming them. It is nil when the feature is disabled.
+	fileVolumeACLReconciler *fileVolumeACLReconciler
 }
 
 const (
@@ -361,7 +361,7 @@ func IsFileVolume(pv *v1.PersistentVolume) bool {
 	}
 	for _, accessMode := range pv.Spec.AccessModes {
 		if accessMode == v1.ReadWriteMany || accessMode == v1.ReadOnlyMany {
-			if isSharedDiskEabled {
+			if isSharedDiskEabled || isMultiWriterBlockVolumeEnabled {
 				if *pv.Spec.VolumeMode != v1.PersistentVolumeBlock {
 					return true
 				}